@@ -73,6 +73,9 @@ type APIVersion struct {
 
 	// Seconds
 	BlockDelay uint64
+
+	// Consensus run by the node.
+	Consensus ConsensusInfo
 }
 
 func (v APIVersion) String() string {
@@ -9,6 +9,7 @@ import (
 	"github.com/consensus-shipyard/go-ipc-types/gateway"
 	"github.com/consensus-shipyard/go-ipc-types/sdk"
 	"github.com/consensus-shipyard/go-ipc-types/subnetactor"
+	"github.com/consensus-shipyard/go-ipc-types/validator"
 	"github.com/google/uuid"
 	"github.com/ipfs/go-cid"
 	blocks "github.com/ipfs/go-libipfs/blocks"
@@ -897,6 +898,9 @@ type FullNode interface {
 	IPCGetCheckpoint(ctx context.Context, sn sdk.SubnetID, epoch abi.ChainEpoch) (*gateway.BottomUpCheckpoint, error)                                    //perm:read
 	IPCGetTopDownMsgs(ctx context.Context, gatewayAddr address.Address, sn sdk.SubnetID, tsk types.TipSetKey, nonce uint64) ([]*gateway.CrossMsg, error) //perm:read
 	IPCGetGenesisEpochForSubnet(ctx context.Context, gatewayAddr address.Address, sn sdk.SubnetID) (abi.ChainEpoch, error)                               //perm:read
+	// IPCGetGatewayMembership returns the membership in the gateway actor of the current subnet at a tipset,
+	// i.e. the validator set of the latest SetMembership message executed before the tipset.
+	IPCGetGatewayMembership(ctx context.Context, gatewayAddr address.Address, tsk types.TipSetKey) (*validator.Set, error) //perm:read
 
 	// Serialized representation of IPC calls.
 	// This calls are serialized version of some of the IPC calls. They return directly the CBOR IPCGetCheckpointSerialized
@@ -907,6 +911,21 @@ type FullNode interface {
 	IPCGetCheckpointTemplateSerialized(ctx context.Context, gatewayAddr address.Address, epoch abi.ChainEpoch) ([]byte, error)                          //perm:read
 	IPCGetTopDownMsgsSerialized(ctx context.Context, gatewayAddr address.Address, sn sdk.SubnetID, tsk types.TipSetKey, nonce uint64) ([][]byte, error) //perm:read
 
+	// Mir-specific methods //
+
+	// MirPushEncryptedTx adds an encrypted message or a key reveal to the pool of encrypted
+	// transactions of the node. The transaction must be encoded with mir.MessageBytes.
+	MirPushEncryptedTx(ctx context.Context, tx []byte) error //perm:write
+	// MirPendingEncryptedTxs returns up to max transactions from the pool of encrypted
+	// transactions, without removing them. It is used by Mir validators to propose encrypted transactions.
+	MirPendingEncryptedTxs(ctx context.Context, max int) ([][]byte, error) //perm:read
+	// MirPublishVersionAttestation verifies a version attestation signed by a validator of the
+	// current Mir membership and broadcasts it to the rest of nodes of the subnet.
+	MirPublishVersionAttestation(ctx context.Context, att *MirVersionAttestation) error //perm:write
+	// MirMembershipVersions returns the validators of the current Mir membership, each one with
+	// the latest version attestation received from it. Validators that have not published an
+	// attestation recently are returned without attestation.
+	MirMembershipVersions(ctx context.Context) ([]MirMembershipEntry, error) //perm:read
 }
 
 // reverse interface to the client, called after EthSubscribe
@@ -1394,6 +1413,29 @@ type MsigTransaction struct {
 	Approved []address.Address
 }
 
+// MirVersionAttestation is a statement signed by a Mir validator with the version
+// of the software it is running.
+type MirVersionAttestation struct {
+	Validator address.Address
+	Version   string
+	Commit    string
+	// ParamsHash is the hash of the subnet parameters used by the validator.
+	ParamsHash string
+	// Timestamp is the time the attestation was signed, in Unix seconds.
+	Timestamp int64
+	Signature *crypto.Signature
+}
+
+// MirMembershipEntry is a validator of the current Mir membership.
+type MirMembershipEntry struct {
+	Validator address.Address
+	// Attestation is the latest version attestation of the validator, if any.
+	Attestation *MirVersionAttestation
+	// ParamsMismatch is set if the attestation commits to subnet parameters different
+	// from the ones of the node.
+	ParamsMismatch bool
+}
+
 type PruneOpts struct {
 	MovingGC    bool
 	RetainState int64
@@ -13,6 +13,7 @@ import (
 	gateway "github.com/consensus-shipyard/go-ipc-types/gateway"
 	sdk "github.com/consensus-shipyard/go-ipc-types/sdk"
 	subnetactor "github.com/consensus-shipyard/go-ipc-types/subnetactor"
+	validator "github.com/consensus-shipyard/go-ipc-types/validator"
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
 	cid "github.com/ipfs/go-cid"
@@ -1674,6 +1675,21 @@ func (mr *MockFullNodeMockRecorder) IPCGetCheckpointTemplateSerialized(arg0, arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IPCGetCheckpointTemplateSerialized", reflect.TypeOf((*MockFullNode)(nil).IPCGetCheckpointTemplateSerialized), arg0, arg1, arg2)
 }
 
+// IPCGetGatewayMembership mocks base method.
+func (m *MockFullNode) IPCGetGatewayMembership(arg0 context.Context, arg1 address.Address, arg2 types.TipSetKey) (*validator.Set, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IPCGetGatewayMembership", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*validator.Set)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IPCGetGatewayMembership indicates an expected call of IPCGetGatewayMembership.
+func (mr *MockFullNodeMockRecorder) IPCGetGatewayMembership(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IPCGetGatewayMembership", reflect.TypeOf((*MockFullNode)(nil).IPCGetGatewayMembership), arg0, arg1, arg2)
+}
+
 // IPCGetGenesisEpochForSubnet mocks base method.
 func (m *MockFullNode) IPCGetGenesisEpochForSubnet(arg0 context.Context, arg1 address.Address, arg2 sdk.SubnetID) (abi.ChainEpoch, error) {
 	m.ctrl.T.Helper()
@@ -1987,6 +2003,64 @@ func (mr *MockFullNodeMockRecorder) MinerGetBaseInfo(arg0, arg1, arg2, arg3 inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MinerGetBaseInfo", reflect.TypeOf((*MockFullNode)(nil).MinerGetBaseInfo), arg0, arg1, arg2, arg3)
 }
 
+// MirMembershipVersions mocks base method.
+func (m *MockFullNode) MirMembershipVersions(arg0 context.Context) ([]api.MirMembershipEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MirMembershipVersions", arg0)
+	ret0, _ := ret[0].([]api.MirMembershipEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MirMembershipVersions indicates an expected call of MirMembershipVersions.
+func (mr *MockFullNodeMockRecorder) MirMembershipVersions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MirMembershipVersions", reflect.TypeOf((*MockFullNode)(nil).MirMembershipVersions), arg0)
+}
+
+// MirPendingEncryptedTxs mocks base method.
+func (m *MockFullNode) MirPendingEncryptedTxs(arg0 context.Context, arg1 int) ([][]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MirPendingEncryptedTxs", arg0, arg1)
+	ret0, _ := ret[0].([][]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MirPendingEncryptedTxs indicates an expected call of MirPendingEncryptedTxs.
+func (mr *MockFullNodeMockRecorder) MirPendingEncryptedTxs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MirPendingEncryptedTxs", reflect.TypeOf((*MockFullNode)(nil).MirPendingEncryptedTxs), arg0, arg1)
+}
+
+// MirPublishVersionAttestation mocks base method.
+func (m *MockFullNode) MirPublishVersionAttestation(arg0 context.Context, arg1 *api.MirVersionAttestation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MirPublishVersionAttestation", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MirPublishVersionAttestation indicates an expected call of MirPublishVersionAttestation.
+func (mr *MockFullNodeMockRecorder) MirPublishVersionAttestation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MirPublishVersionAttestation", reflect.TypeOf((*MockFullNode)(nil).MirPublishVersionAttestation), arg0, arg1)
+}
+
+// MirPushEncryptedTx mocks base method.
+func (m *MockFullNode) MirPushEncryptedTx(arg0 context.Context, arg1 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MirPushEncryptedTx", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MirPushEncryptedTx indicates an expected call of MirPushEncryptedTx.
+func (mr *MockFullNodeMockRecorder) MirPushEncryptedTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MirPushEncryptedTx", reflect.TypeOf((*MockFullNode)(nil).MirPushEncryptedTx), arg0, arg1)
+}
+
 // MpoolBatchPush mocks base method.
 func (m *MockFullNode) MpoolBatchPush(arg0 context.Context, arg1 []*types.SignedMessage) ([]cid.Cid, error) {
 	m.ctrl.T.Helper()
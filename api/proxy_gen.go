@@ -10,6 +10,7 @@ import (
 	"github.com/consensus-shipyard/go-ipc-types/gateway"
 	"github.com/consensus-shipyard/go-ipc-types/sdk"
 	"github.com/consensus-shipyard/go-ipc-types/subnetactor"
+	"github.com/consensus-shipyard/go-ipc-types/validator"
 	"github.com/google/uuid"
 	"github.com/ipfs/go-cid"
 	blocks "github.com/ipfs/go-libipfs/blocks"
@@ -341,6 +342,8 @@ type FullNodeMethods struct {
 
 	IPCGetCheckpointTemplateSerialized func(p0 context.Context, p1 address.Address, p2 abi.ChainEpoch) ([]byte, error) `perm:"read"`
 
+	IPCGetGatewayMembership func(p0 context.Context, p1 address.Address, p2 types.TipSetKey) (*validator.Set, error) `perm:"read"`
+
 	IPCGetGenesisEpochForSubnet func(p0 context.Context, p1 address.Address, p2 sdk.SubnetID) (abi.ChainEpoch, error) `perm:"read"`
 
 	IPCGetPrevCheckpointForChild func(p0 context.Context, p1 address.Address, p2 sdk.SubnetID) (cid.Cid, error) `perm:"read"`
@@ -377,6 +380,14 @@ type FullNodeMethods struct {
 
 	MinerGetBaseInfo func(p0 context.Context, p1 address.Address, p2 abi.ChainEpoch, p3 types.TipSetKey) (*MiningBaseInfo, error) `perm:"read"`
 
+	MirMembershipVersions func(p0 context.Context) ([]MirMembershipEntry, error) `perm:"read"`
+
+	MirPendingEncryptedTxs func(p0 context.Context, p1 int) ([][]byte, error) `perm:"read"`
+
+	MirPublishVersionAttestation func(p0 context.Context, p1 *MirVersionAttestation) error `perm:"write"`
+
+	MirPushEncryptedTx func(p0 context.Context, p1 []byte) error `perm:"write"`
+
 	MpoolBatchPush func(p0 context.Context, p1 []*types.SignedMessage) ([]cid.Cid, error) `perm:"write"`
 
 	MpoolBatchPushMessage func(p0 context.Context, p1 []*types.Message, p2 *MessageSendSpec) ([]*types.SignedMessage, error) `perm:"sign"`
@@ -2619,6 +2630,17 @@ func (s *FullNodeStub) IPCGetCheckpointTemplateSerialized(p0 context.Context, p1
 	return *new([]byte), ErrNotSupported
 }
 
+func (s *FullNodeStruct) IPCGetGatewayMembership(p0 context.Context, p1 address.Address, p2 types.TipSetKey) (*validator.Set, error) {
+	if s.Internal.IPCGetGatewayMembership == nil {
+		return nil, ErrNotSupported
+	}
+	return s.Internal.IPCGetGatewayMembership(p0, p1, p2)
+}
+
+func (s *FullNodeStub) IPCGetGatewayMembership(p0 context.Context, p1 address.Address, p2 types.TipSetKey) (*validator.Set, error) {
+	return nil, ErrNotSupported
+}
+
 func (s *FullNodeStruct) IPCGetGenesisEpochForSubnet(p0 context.Context, p1 address.Address, p2 sdk.SubnetID) (abi.ChainEpoch, error) {
 	if s.Internal.IPCGetGenesisEpochForSubnet == nil {
 		return *new(abi.ChainEpoch), ErrNotSupported
@@ -2817,6 +2839,50 @@ func (s *FullNodeStub) MinerGetBaseInfo(p0 context.Context, p1 address.Address,
 	return nil, ErrNotSupported
 }
 
+func (s *FullNodeStruct) MirMembershipVersions(p0 context.Context) ([]MirMembershipEntry, error) {
+	if s.Internal.MirMembershipVersions == nil {
+		return *new([]MirMembershipEntry), ErrNotSupported
+	}
+	return s.Internal.MirMembershipVersions(p0)
+}
+
+func (s *FullNodeStub) MirMembershipVersions(p0 context.Context) ([]MirMembershipEntry, error) {
+	return *new([]MirMembershipEntry), ErrNotSupported
+}
+
+func (s *FullNodeStruct) MirPendingEncryptedTxs(p0 context.Context, p1 int) ([][]byte, error) {
+	if s.Internal.MirPendingEncryptedTxs == nil {
+		return *new([][]byte), ErrNotSupported
+	}
+	return s.Internal.MirPendingEncryptedTxs(p0, p1)
+}
+
+func (s *FullNodeStub) MirPendingEncryptedTxs(p0 context.Context, p1 int) ([][]byte, error) {
+	return *new([][]byte), ErrNotSupported
+}
+
+func (s *FullNodeStruct) MirPublishVersionAttestation(p0 context.Context, p1 *MirVersionAttestation) error {
+	if s.Internal.MirPublishVersionAttestation == nil {
+		return ErrNotSupported
+	}
+	return s.Internal.MirPublishVersionAttestation(p0, p1)
+}
+
+func (s *FullNodeStub) MirPublishVersionAttestation(p0 context.Context, p1 *MirVersionAttestation) error {
+	return ErrNotSupported
+}
+
+func (s *FullNodeStruct) MirPushEncryptedTx(p0 context.Context, p1 []byte) error {
+	if s.Internal.MirPushEncryptedTx == nil {
+		return ErrNotSupported
+	}
+	return s.Internal.MirPushEncryptedTx(p0, p1)
+}
+
+func (s *FullNodeStub) MirPushEncryptedTx(p0 context.Context, p1 []byte) error {
+	return ErrNotSupported
+}
+
 func (s *FullNodeStruct) MpoolBatchPush(p0 context.Context, p1 []*types.SignedMessage) ([]cid.Cid, error) {
 	if s.Internal.MpoolBatchPush == nil {
 		return *new([]cid.Cid), ErrNotSupported
@@ -169,9 +169,10 @@ type ConnMgrInfo struct {
 }
 
 type NodeStatus struct {
-	SyncStatus  NodeSyncStatus
-	PeerStatus  NodePeerStatus
-	ChainStatus NodeChainStatus
+	SyncStatus      NodeSyncStatus
+	PeerStatus      NodePeerStatus
+	ChainStatus     NodeChainStatus
+	ConsensusStatus NodeConsensusStatus
 }
 
 type NodeSyncStatus struct {
@@ -189,6 +190,24 @@ type NodeChainStatus struct {
 	BlocksPerTipsetLastFinality float64
 }
 
+// ConsensusInfo describes the consensus run by a node, so clients can adapt to it
+// without being configured out-of-band.
+type ConsensusInfo struct {
+	// Consensus algorithm: filcns, mir or tspow.
+	Type string
+	// Blocks are final as soon as they are included in the chain.
+	InstantFinality bool
+	// Tipsets always have a single block.
+	SingleBlockTipSets bool
+}
+
+type NodeConsensusStatus struct {
+	Info ConsensusInfo
+	// Number of blocks committed by the latest checkpoint verified by the node.
+	// It is zero if the consensus has no checkpoints or none has been verified yet.
+	CheckpointPeriod abi.ChainEpoch
+}
+
 type CheckStatusCode int
 
 //go:generate go run golang.org/x/tools/cmd/stringer -type=CheckStatusCode -trimprefix=CheckStatus
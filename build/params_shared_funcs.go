@@ -24,6 +24,11 @@ func IndexerIngestTopic(netName dtypes.NetworkName) string {
 
 	return "/indexer/ingest/" + nn
 }
+
+// MirAttestationsTopic is the topic where the version attestations of Mir validators are gossiped.
+func MirAttestationsTopic(netName dtypes.NetworkName) string {
+	return "/eudico/mir/attestations/" + string(netName)
+}
 func DhtProtocolName(netName dtypes.NetworkName) protocol.ID {
 	return protocol.ID("/fil/kad/" + string(netName))
 }
@@ -26,6 +26,8 @@ import (
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/actors/builtin"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetcron"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetparams"
 	"github.com/filecoin-project/lotus/chain/state"
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/store"
@@ -206,8 +208,14 @@ func checkBlockMessages(ctx context.Context, sm *stmgr.StateManager, cs *store.C
 		return xerrors.Errorf("failed to load base state tree: %w", err)
 	}
 
+	netName, err := stmgr.GetNetworkName(ctx, sm, stateroot)
+	if err != nil {
+		return xerrors.Errorf("failed to get network name: %w", err)
+	}
+
 	nv := sm.GetNetworkVersion(ctx, b.Header.Height)
 	pl := vm.PricelistByEpoch(b.Header.Height)
+	blockGasLimit := subnetparams.BlockGasLimit(string(netName))
 	var sumGasLimit int64
 	checkMsg := func(msg types.ChainMsg) error {
 		m := msg.VMMessage()
@@ -221,7 +229,7 @@ func checkBlockMessages(ctx context.Context, sm *stmgr.StateManager, cs *store.C
 		// ValidForBlockInclusion checks if any single message does not exceed BlockGasLimit
 		// So below is overflow safe
 		sumGasLimit += m.GasLimit
-		if sumGasLimit > build.BlockGasLimit {
+		if sumGasLimit > blockGasLimit {
 			return xerrors.Errorf("block gas limit exceeded")
 		}
 
@@ -278,14 +286,25 @@ func checkBlockMessages(ctx context.Context, sm *stmgr.StateManager, cs *store.C
 		}
 	}
 
+	secpkMsgs := make([]*types.Message, 0, len(b.SecpkMessages))
+	for _, m := range b.SecpkMessages {
+		secpkMsgs = append(secpkMsgs, &m.Message)
+	}
+	// Blocks below the upgrade have the legacy nonces, which aren't checked.
+	if subnetparams.Active(subnetparams.GetUpgrades(string(netName)).ConfigMsgNonces, b.Header.Height) {
+		if err := membership.CheckConfigMsgNonces(DefaultGatewayAddr, b.Header.Height, secpkMsgs); err != nil {
+			return xerrors.Errorf("block had invalid config message: %w", err)
+		}
+	}
+
 	smArr := blockadt.MakeEmptyArray(tmpstore)
 	for i, m := range b.SecpkMessages {
 		if nv >= network.Version14 && !IsValidSecpkSigType(nv, m.Signature.Type) {
 			return xerrors.Errorf("block had invalid signed message at index %d: %w", i, err)
 		}
 
-		// if this is a config message no need to check the message
-		if !membership.IsConfigMsg(DefaultGatewayAddr, &m.Message) {
+		// if this is a config or subnet cron message no need to check the message
+		if !membership.IsConfigMsg(DefaultGatewayAddr, &m.Message) && !subnetcron.IsCronMsg(string(netName), b.Header, &m.Message) {
 
 			if err := checkMsg(m); err != nil {
 				return xerrors.Errorf("block had invalid secpk message at index %d: %w", i, err)
@@ -31,6 +31,7 @@ import (
 	"github.com/filecoin-project/lotus/chain/actors/builtin/cron"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/reward"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetcron"
 	"github.com/filecoin-project/lotus/chain/rand"
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/store"
@@ -58,10 +59,19 @@ func NewActorRegistry() *vm.ActorRegistry {
 }
 
 type TipSetExecutor struct {
-	reward RewardFunc
+	reward StateRewardFunc
 }
 
 func NewTipSetExecutor(r RewardFunc) *TipSetExecutor {
+	return NewTipSetExecutorWithState(func(ctx context.Context, _ *stmgr.StateManager, _ cid.Cid, vmi vm.Interface,
+		em stmgr.ExecMonitor, epoch abi.ChainEpoch, ts *types.TipSet, params *reward.AwardBlockRewardParams) error {
+		return r(ctx, vmi, em, epoch, ts, params)
+	})
+}
+
+// NewTipSetExecutorWithState returns an executor whose reward function reads the state
+// the tipset is executed on.
+func NewTipSetExecutorWithState(r StateRewardFunc) *TipSetExecutor {
 	return &TipSetExecutor{reward: r}
 }
 
@@ -207,7 +217,24 @@ func (t *TipSetExecutor) ApplyBlocks(ctx context.Context,
 
 	var msgGas int64
 
-	for _, b := range bms {
+	// Subnet cron messages are bound to the subnet and to the header of the block including them.
+	var netName string
+	isCronMsg := func(i int, m *types.Message) bool {
+		if m.From != builtin.SystemActorAddr || ts == nil || i >= len(ts.Blocks()) {
+			return false
+		}
+		if netName == "" {
+			nn, err := stmgr.GetNetworkName(ctx, sm, pstate)
+			if err != nil {
+				log.Warnf("failed to get network name to check subnet cron messages: %s", err)
+				return false
+			}
+			netName = string(nn)
+		}
+		return subnetcron.IsCronMsg(netName, ts.Blocks()[i], m)
+	}
+
+	for i, b := range bms {
 		penalty := types.NewInt(0)
 		gasReward := big.Zero()
 
@@ -241,6 +268,27 @@ func (t *TipSetExecutor) ApplyBlocks(ctx context.Context,
 				continue
 			}
 
+			// Subnet cron messages are executed implicitly too, but a failing job
+			// must not prevent the chain from making progress.
+			if isCronMsg(i, m) {
+				r, err := vmi.ApplyImplicitMessage(ctx, m)
+				if err != nil {
+					return cid.Undef, cid.Undef, xerrors.Errorf("applying subnet cron message: %w", err)
+				}
+
+				if em != nil {
+					if err := em.MessageApplied(ctx, ts, cm.Cid(), m, r, true); err != nil {
+						return cid.Undef, cid.Undef, xerrors.Errorf("callback failed on subnet cron message: %w", err)
+					}
+				}
+
+				if r.ExitCode != 0 {
+					log.Warnf("subnet cron message to %s (method %d) exited with %d", m.To, m.Method, r.ExitCode)
+				}
+				processedMsgs[m.Cid()] = struct{}{}
+				continue
+			}
+
 			r, err := vmi.ApplyMessage(ctx, cm)
 			if err != nil {
 				return cid.Undef, cid.Undef, err
@@ -271,7 +319,7 @@ func (t *TipSetExecutor) ApplyBlocks(ctx context.Context,
 			GasReward: gasReward,
 			WinCount:  b.WinCount,
 		}
-		rErr := t.reward(ctx, vmi, em, epoch, ts, params)
+		rErr := t.reward(ctx, sm, pstate, vmi, em, epoch, ts, params)
 		if rErr != nil {
 			return cid.Undef, cid.Undef, xerrors.Errorf("error applying reward: %w", rErr)
 		}
@@ -3,6 +3,7 @@ package consensus
 import (
 	"context"
 
+	"github.com/ipfs/go-cid"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"go.opencensus.io/stats"
 
@@ -50,8 +51,14 @@ type Consensus interface {
 type RewardFunc func(ctx context.Context, vmi vm.Interface, em stmgr.ExecMonitor,
 	epoch abi.ChainEpoch, ts *types.TipSet, params *reward.AwardBlockRewardParams) error
 
+// StateRewardFunc is a RewardFunc that also gets the state the tipset is executed on,
+// for consensus implementations whose rewards depend on it, e.g. on the validator set.
+type StateRewardFunc func(ctx context.Context, sm *stmgr.StateManager, pstate cid.Cid, vmi vm.Interface,
+	em stmgr.ExecMonitor, epoch abi.ChainEpoch, ts *types.TipSet, params *reward.AwardBlockRewardParams) error
+
 // ValidateBlockPubsub implements the common checks performed by all consensus implementations
-// when a block is received through the pubsub channel.
+// when a block is received through the pubsub channel. The peers that sent a rejected block are
+// flagged by the caller.
 func ValidateBlockPubsub(ctx context.Context, cns Consensus, self bool, msg *pubsub.Message) (pubsub.ValidationResult, string) {
 	if self {
 		return validateLocalBlock(ctx, msg)
@@ -65,15 +72,9 @@ func ValidateBlockPubsub(ctx context.Context, cns Consensus, self bool, msg *pub
 
 	stats.Record(ctx, metrics.BlockReceived.M(1))
 
-	recordFailureFlagPeer := func(what string) {
-		// bv.Validate will flag the peer in that case
-		panic(what)
-	}
-
 	blk, what, err := decodeAndCheckBlock(msg)
 	if err != nil {
 		log.Error("got invalid block over pubsub: ", err)
-		recordFailureFlagPeer(what)
 		return pubsub.ValidationReject, what
 	}
 
@@ -81,7 +82,6 @@ func ValidateBlockPubsub(ctx context.Context, cns Consensus, self bool, msg *pub
 	err = validateMsgMeta(ctx, blk)
 	if err != nil {
 		log.Warnf("error validating message metadata: %s", err)
-		recordFailureFlagPeer("invalid_block_meta")
 		return pubsub.ValidationReject, "invalid_block_meta"
 	}
 
@@ -92,7 +92,6 @@ func ValidateBlockPubsub(ctx context.Context, cns Consensus, self bool, msg *pub
 			return pubsub.ValidationIgnore, reject
 		}
 		log.Warn("rejecting block msg: ", err)
-		recordFailureFlagPeer(reject)
 		return pubsub.ValidationReject, reject
 	}
 
@@ -0,0 +1,265 @@
+// Package attestation implements the version attestations of Mir validators.
+//
+// Every validator periodically signs a statement with the version of the software it runs
+// and publishes it through its full node. Full nodes gossip the attestations to the rest of
+// nodes of the subnet and attach them to the entries of the current membership, so subnet
+// coordinators can check from any node that the whole membership runs compatible versions
+// before scheduling an upgrade.
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/lib/sigs"
+	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
+)
+
+const (
+	// MaxClockDrift is how far in the future the timestamp of an attestation can be.
+	MaxClockDrift = 5 * time.Minute
+	// PublishInterval is the interval at which validators publish their attestation.
+	PublishInterval = 10 * time.Minute
+	// MaxAge is the age after which an attestation is stale. A validator that stopped
+	// publishing attestations is reported without version once its last one is stale.
+	MaxAge = 3 * PublishInterval
+)
+
+var log = logging.Logger("mir-attestation")
+
+// Attestation is the version attestation of a validator as sent through the network.
+type Attestation struct {
+	Validator address.Address
+	Version   string
+	Commit    string
+	// ParamsHash is the hash of the subnet parameters used by the validator.
+	ParamsHash string
+	// Timestamp is the time the attestation was signed, in Unix seconds.
+	Timestamp int64
+	Signature crypto.Signature
+}
+
+// FromAPI converts the attestation received through the API.
+func FromAPI(a *api.MirVersionAttestation) (*Attestation, error) {
+	if a.Signature == nil {
+		return nil, fmt.Errorf("version attestation of %s is not signed", a.Validator)
+	}
+	return &Attestation{
+		Validator:  a.Validator,
+		Version:    a.Version,
+		Commit:     a.Commit,
+		ParamsHash: a.ParamsHash,
+		Timestamp:  a.Timestamp,
+		Signature:  *a.Signature,
+	}, nil
+}
+
+// ToAPI converts the attestation to the type used in the API.
+func (a *Attestation) ToAPI() *api.MirVersionAttestation {
+	sig := a.Signature
+	return &api.MirVersionAttestation{
+		Validator:  a.Validator,
+		Version:    a.Version,
+		Commit:     a.Commit,
+		ParamsHash: a.ParamsHash,
+		Timestamp:  a.Timestamp,
+		Signature:  &sig,
+	}
+}
+
+func (a *Attestation) Bytes() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := a.MarshalCBOR(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (a *Attestation) FromBytes(b []byte) error {
+	return a.UnmarshalCBOR(bytes.NewReader(b))
+}
+
+// signingBytes returns the bytes of the attestation covered by the signature,
+// i.e. the attestation with an empty signature.
+func (a *Attestation) signingBytes() ([]byte, error) {
+	unsigned := *a
+	unsigned.Signature = crypto.Signature{}
+	return unsigned.Bytes()
+}
+
+// Signer signs data with the key of an address.
+type Signer interface {
+	WalletSign(ctx context.Context, k address.Address, msg []byte) (*crypto.Signature, error)
+}
+
+// New creates an attestation of the version of this binary and the hash of the subnet parameters
+// signed by the validator.
+func New(ctx context.Context, s Signer, validator address.Address, paramsHash string) (*Attestation, error) {
+	a := Attestation{
+		Validator:  validator,
+		Version:    build.UserVersion(),
+		Commit:     build.CurrentCommit,
+		ParamsHash: paramsHash,
+		Timestamp:  time.Now().Unix(),
+	}
+	b, err := a.signingBytes()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := s.WalletSign(ctx, validator, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign version attestation: %w", err)
+	}
+	a.Signature = *sig
+	return &a, nil
+}
+
+// Verify checks that the attestation was signed by the validator and is neither
+// from the future nor stale.
+func Verify(a *Attestation) error {
+	ts := time.Unix(a.Timestamp, 0)
+	if ts.After(time.Now().Add(MaxClockDrift)) {
+		return fmt.Errorf("version attestation of %s is from the future", a.Validator)
+	}
+	if time.Since(ts) > MaxAge {
+		return fmt.Errorf("version attestation of %s is stale", a.Validator)
+	}
+	b, err := a.signingBytes()
+	if err != nil {
+		return err
+	}
+	if err := sigs.Verify(&a.Signature, a.Validator, b); err != nil {
+		return fmt.Errorf("invalid signature in version attestation of %s: %w", a.Validator, err)
+	}
+	return nil
+}
+
+// Store keeps the latest attestation of every validator of the current membership.
+//
+// Attestations are only accepted from members, and are dropped when the validator leaves
+// the membership or the attestation becomes stale, so the size of the store is bounded
+// by the size of the membership.
+type Store struct {
+	lk      sync.RWMutex
+	members map[address.Address]struct{}
+	atts    map[address.Address]*Attestation
+}
+
+// NewStore creates an empty attestation store. No attestation is accepted
+// until the membership is set.
+func NewStore() *Store {
+	return &Store{
+		members: make(map[address.Address]struct{}),
+		atts:    make(map[address.Address]*Attestation),
+	}
+}
+
+// SetMembership sets the validators of the current membership and drops the
+// attestations of validators that are not members anymore.
+func (s *Store) SetMembership(validators []address.Address) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.members = make(map[address.Address]struct{}, len(validators))
+	for _, v := range validators {
+		s.members[v] = struct{}{}
+	}
+	for v := range s.atts {
+		if _, ok := s.members[v]; !ok {
+			delete(s.atts, v)
+		}
+	}
+}
+
+// Validate checks that the attestation is valid and was signed by a member.
+func (s *Store) Validate(a *Attestation) error {
+	s.lk.RLock()
+	_, ok := s.members[a.Validator]
+	s.lk.RUnlock()
+	if !ok {
+		return fmt.Errorf("version attestation of %s, which is not in the membership", a.Validator)
+	}
+	return Verify(a)
+}
+
+// Add validates the attestation and stores it if it is newer than the stored one for the validator.
+// It returns true if the attestation was stored.
+func (s *Store) Add(a *Attestation) (bool, error) {
+	if err := s.Validate(a); err != nil {
+		return false, err
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	// The membership may have changed since the attestation was validated.
+	if _, ok := s.members[a.Validator]; !ok {
+		return false, nil
+	}
+	if prev, ok := s.atts[a.Validator]; ok && prev.Timestamp >= a.Timestamp {
+		return false, nil
+	}
+	s.atts[a.Validator] = a
+	return true, nil
+}
+
+// Membership returns the entries of the current membership sorted by validator,
+// with the latest attestation of each validator if it is not stale.
+func (s *Store) Membership() []api.MirMembershipEntry {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	entries := make([]api.MirMembershipEntry, 0, len(s.members))
+	for v := range s.members {
+		e := api.MirMembershipEntry{Validator: v}
+		if a, ok := s.atts[v]; ok {
+			if time.Since(time.Unix(a.Timestamp, 0)) > MaxAge {
+				delete(s.atts, v)
+			} else {
+				e.Attestation = a.ToAPI()
+			}
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Validator.String() < entries[j].Validator.String() })
+	return entries
+}
+
+// Publisher is the API used by validators to publish their attestations.
+type Publisher interface {
+	Signer
+	MirPublishVersionAttestation(ctx context.Context, att *api.MirVersionAttestation) error
+}
+
+// Run publishes the attestation of the validator through the node every PublishInterval,
+// until the context is closed.
+func Run(ctx context.Context, node Publisher, validator address.Address, paramsHash string) {
+	ticker := time.NewTicker(PublishInterval)
+	defer ticker.Stop()
+	for {
+		a, err := New(ctx, node, validator, paramsHash)
+		if err == nil {
+			err = node.MirPublishVersionAttestation(ctx, a.ToAPI())
+		}
+		if err != nil {
+			log.Warnf("failed to publish version attestation: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package attestation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/lib/sigs"
+)
+
+type testSigner struct {
+	addr address.Address
+	pk   []byte
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	pk, err := sigs.Generate(crypto.SigTypeSecp256k1)
+	require.NoError(t, err)
+	pub, err := sigs.ToPublic(crypto.SigTypeSecp256k1, pk)
+	require.NoError(t, err)
+	addr, err := address.NewSecp256k1Address(pub)
+	require.NoError(t, err)
+	return &testSigner{addr: addr, pk: pk}
+}
+
+func (s *testSigner) WalletSign(_ context.Context, _ address.Address, msg []byte) (*crypto.Signature, error) {
+	return sigs.Sign(crypto.SigTypeSecp256k1, s.pk, msg)
+}
+
+// signedAt returns an attestation of the signer signed with the given timestamp.
+func (s *testSigner) signedAt(t *testing.T, ts time.Time) *Attestation {
+	a := &Attestation{Validator: s.addr, Version: "v1.0.0", Commit: "abcdef", Timestamp: ts.Unix()}
+	b, err := a.signingBytes()
+	require.NoError(t, err)
+	sig, err := s.WalletSign(context.Background(), s.addr, b)
+	require.NoError(t, err)
+	a.Signature = *sig
+	return a
+}
+
+func TestAttestationVerify(t *testing.T) {
+	s := newTestSigner(t)
+
+	a, err := New(context.Background(), s, s.addr, "params")
+	require.NoError(t, err)
+	require.NoError(t, Verify(a))
+
+	b, err := a.Bytes()
+	require.NoError(t, err)
+	var decoded Attestation
+	require.NoError(t, decoded.FromBytes(b))
+	require.Equal(t, *a, decoded)
+	require.NoError(t, Verify(&decoded))
+
+	tampered := *a
+	tampered.Commit = "deadbeef"
+	require.Error(t, Verify(&tampered))
+
+	tampered = *a
+	tampered.ParamsHash = "other params"
+	require.Error(t, Verify(&tampered))
+
+	impersonated := *a
+	impersonated.Validator = newTestSigner(t).addr
+	require.Error(t, Verify(&impersonated))
+
+	require.Error(t, Verify(s.signedAt(t, time.Now().Add(2*MaxClockDrift))))
+	require.Error(t, Verify(s.signedAt(t, time.Now().Add(-2*MaxAge))))
+}
+
+func TestStoreMembership(t *testing.T) {
+	s1, s2, outsider := newTestSigner(t), newTestSigner(t), newTestSigner(t)
+	st := NewStore()
+
+	// No attestation is accepted before the membership is known.
+	_, err := st.Add(s1.signedAt(t, time.Now()))
+	require.Error(t, err)
+
+	st.SetMembership([]address.Address{s1.addr, s2.addr})
+
+	latest := s1.signedAt(t, time.Now())
+	ok, err := st.Add(latest)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = st.Add(s1.signedAt(t, time.Now().Add(-time.Minute)))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = st.Add(outsider.signedAt(t, time.Now()))
+	require.Error(t, err)
+
+	entries := st.Membership()
+	require.Len(t, entries, 2)
+	require.True(t, entries[0].Validator.String() < entries[1].Validator.String())
+	for _, e := range entries {
+		if e.Validator == s1.addr {
+			require.NotNil(t, e.Attestation)
+			require.Equal(t, latest.Timestamp, e.Attestation.Timestamp)
+		} else {
+			require.Nil(t, e.Attestation)
+		}
+	}
+
+	// Attestations of validators leaving the membership are dropped.
+	st.SetMembership([]address.Address{s2.addr})
+	entries = st.Membership()
+	require.Len(t, entries, 1)
+	require.Equal(t, s2.addr, entries[0].Validator)
+	require.Len(t, st.atts, 0)
+}
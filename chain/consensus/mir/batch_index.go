@@ -0,0 +1,123 @@
+package mir
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+)
+
+// BatchIndexPrefix is the prefix of the keys of the batch index, one per Mir epoch.
+const BatchIndexPrefix = "mir/batches/"
+
+// ErrBatchNotIndexed is returned when the epoch of a batch is not in the batch index of the validator.
+var ErrBatchNotIndexed = errors.New("batch not indexed")
+
+func BatchIndexKey(epoch uint64) datastore.Key {
+	return datastore.NewKey(BatchIndexPrefix + strconv.FormatUint(epoch, 10))
+}
+
+// batchIndexEntry is the sequence number of the first batch of an epoch and the height of the block
+// created from it. Mir delivers every sequence number as a batch, padding ones included, and every
+// batch becomes the block at the next height, so the block of any batch of the epoch follows.
+type batchIndexEntry struct {
+	Epoch  uint64
+	SeqNr  uint64
+	Height abi.ChainEpoch
+}
+
+// BatchBlock is the block created from a batch ordered by Mir.
+type BatchBlock struct {
+	SeqNr  uint64
+	Epoch  uint64
+	Height abi.ChainEpoch
+	Block  cid.Cid
+}
+
+// putBatchIndexEntry indexes the epoch that starts with the checkpoint, whose first batch becomes
+// the block at height h.
+func putBatchIndexEntry(ctx context.Context, ds db.DB, ch *checkpoint.StableCheckpoint, h abi.ChainEpoch) error {
+	// The genesis checkpoint has no snapshot: its first batch becomes the block after the genesis.
+	if h == 0 {
+		h = 1
+	}
+	b, err := json.Marshal(&batchIndexEntry{Epoch: uint64(ch.Epoch()), SeqNr: uint64(ch.SeqNr()), Height: h})
+	if err != nil {
+		return err
+	}
+	if err := ds.Put(ctx, BatchIndexKey(uint64(ch.Epoch())), b); err != nil {
+		return xerrors.Errorf("error indexing batches of epoch %d: %w", ch.Epoch(), err)
+	}
+	return nil
+}
+
+func getBatchIndexEntry(ctx context.Context, ds db.DB, epoch uint64) (*batchIndexEntry, error) {
+	b, err := ds.Get(ctx, BatchIndexKey(epoch))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil, xerrors.Errorf("epoch %d: %w", epoch, ErrBatchNotIndexed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry batchIndexEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, xerrors.Errorf("error decoding batch index of epoch %d: %w", epoch, err)
+	}
+	return &entry, nil
+}
+
+// batchHeight returns the epoch of the batch with sequence number sn and the height of the block
+// created from it, looking back from the epoch latest, or ErrBatchNotIndexed if the index has gaps
+// down to the epoch of the batch.
+func batchHeight(ctx context.Context, ds db.DB, latest uint64, sn uint64) (uint64, abi.ChainEpoch, error) {
+	for e := latest; ; e-- {
+		entry, err := getBatchIndexEntry(ctx, ds, e)
+		if err != nil {
+			return 0, 0, xerrors.Errorf("sequence number %d: %w", sn, err)
+		}
+		if entry.SeqNr <= sn {
+			return e, entry.Height + abi.ChainEpoch(sn-entry.SeqNr), nil
+		}
+		if e == 0 {
+			return 0, 0, xerrors.Errorf("sequence number %d: %w", sn, ErrBatchNotIndexed)
+		}
+	}
+}
+
+// BlockByBatch returns the block the batch with sequence number sn became on this validator,
+// which helps tracing a batch delivered by Mir to the chain.
+func (m *Manager) BlockByBatch(ctx context.Context, sn uint64) (*BatchBlock, error) {
+	st := m.stateManager.status
+	st.lk.Lock()
+	current := st.currentEpoch
+	st.lk.Unlock()
+
+	epoch, h, err := batchHeight(ctx, m.ds, uint64(current), sn)
+	if err != nil {
+		return nil, err
+	}
+	if freeze := m.stateManager.FreezeHeight(); freeze > 0 && h > freeze {
+		return nil, xerrors.Errorf("batch %d was dropped: the subnet is frozen at height %d", sn, freeze)
+	}
+	head, err := m.stateManager.heads.Head(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting chain head: %w", err)
+	}
+	if h > head.Height() {
+		return nil, xerrors.Errorf("batch %d not applied yet: its block would be at height %d, head is at %d", sn, h, head.Height())
+	}
+	ts, err := m.lotusNode.ChainGetTipSetByHeight(ctx, h, head.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("error getting tipset at height %d: %w", h, err)
+	}
+	return &BatchBlock{SeqNr: sn, Epoch: epoch, Height: h, Block: ts.Blocks()[0].Cid()}, nil
+}
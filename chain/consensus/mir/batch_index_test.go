@@ -0,0 +1,52 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestBatchIndex(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+
+	_, _, err := batchHeight(ctx, ds, 0, 0)
+	require.ErrorIs(t, err, ErrBatchNotIndexed)
+
+	// The first batch of the genesis epoch becomes the block after the genesis.
+	genesis := membershipCheckpoint(0, nil)
+	require.NoError(t, putBatchIndexEntry(ctx, ds, genesis, 0))
+	epoch1 := membershipCheckpoint(1, nil)
+	epoch1.Sn = 8
+	require.NoError(t, putBatchIndexEntry(ctx, ds, epoch1, 9))
+
+	for _, tc := range []struct {
+		sn     uint64
+		epoch  uint64
+		height abi.ChainEpoch
+	}{
+		{0, 0, 1},
+		{7, 0, 8},
+		{8, 1, 9},
+		{20, 1, 21},
+	} {
+		epoch, h, err := batchHeight(ctx, ds, 1, tc.sn)
+		require.NoError(t, err)
+		require.Equal(t, tc.epoch, epoch)
+		require.Equal(t, tc.height, h)
+	}
+
+	// The epochs before a gap in the index can't be resolved.
+	epoch3 := membershipCheckpoint(3, nil)
+	epoch3.Sn = 24
+	require.NoError(t, putBatchIndexEntry(ctx, ds, epoch3, 25))
+	_, h, err := batchHeight(ctx, ds, 3, 30)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(31), h)
+	_, _, err = batchHeight(ctx, ds, 3, 20)
+	require.ErrorIs(t, err, ErrBatchNotIndexed)
+}
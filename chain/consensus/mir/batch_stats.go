@@ -0,0 +1,61 @@
+package mir
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+var (
+	// MaxBatchBytes is the size of a batch above which it gets close to what the transport carries comfortably.
+	MaxBatchBytes = 4 << 20
+	// BatchSaturationRatio is the fraction of the batch limits above which a batch is considered saturated.
+	BatchSaturationRatio = 0.9
+	// BatchSaturationAlertBatches is the number of consecutive saturated batches that raises a saturation alert.
+	BatchSaturationAlertBatches = 20
+)
+
+// batchStats records the size of the ordered batches and warns when they stay close to their limits,
+// which means the subnet is running at capacity and the limits should be raised or the load split.
+type batchStats struct {
+	id     string
+	maxTxs int
+
+	// Number of consecutive saturated batches.
+	saturated int
+}
+
+func newBatchStats(id string, maxTxs int) *batchStats {
+	return &batchStats{id: id, maxTxs: maxTxs}
+}
+
+// record records the size of a batch and reports whether it raised a saturation alert.
+func (s *batchStats) record(ctx context.Context, txs []*mirproto.Transaction) bool {
+	size := 0
+	for _, tx := range txs {
+		size += len(tx.Data)
+	}
+	stats.Record(ctx, metrics.MirBatchTransactions.M(int64(len(txs))), metrics.MirBatchBytes.M(int64(size)))
+
+	full := s.maxTxs > 0 && float64(len(txs)) >= BatchSaturationRatio*float64(s.maxTxs)
+	if !full && float64(size) < BatchSaturationRatio*float64(MaxBatchBytes) {
+		if s.saturated >= BatchSaturationAlertBatches {
+			log.With("validator", s.id).Infof("batches no longer saturated after %d batches", s.saturated)
+		}
+		s.saturated = 0
+		return false
+	}
+
+	s.saturated++
+	if s.saturated != BatchSaturationAlertBatches {
+		return false
+	}
+	stats.Record(ctx, metrics.MirBatchSaturationAlerts.M(1))
+	log.With("validator", s.id).Warnw("batches are saturated, consider raising the batch limits or splitting the load across subnets",
+		"batches", s.saturated, "txs", len(txs), "maxTxs", s.maxTxs, "bytes", size, "maxBytes", MaxBatchBytes)
+	return true
+}
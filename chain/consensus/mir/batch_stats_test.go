@@ -0,0 +1,35 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+)
+
+func TestBatchStatsSaturation(t *testing.T) {
+	ctx := context.Background()
+	s := newBatchStats("v", 10)
+
+	full := make([]*mirproto.Transaction, 9)
+	for i := range full {
+		full[i] = &mirproto.Transaction{Data: []byte{1}}
+	}
+
+	for i := 1; i < BatchSaturationAlertBatches; i++ {
+		require.False(t, s.record(ctx, full))
+	}
+	require.True(t, s.record(ctx, full))
+	// The alert is raised once per saturation period.
+	require.False(t, s.record(ctx, full))
+
+	require.False(t, s.record(ctx, full[:1]))
+	require.Equal(t, 0, s.saturated)
+
+	// Large transactions saturate the batch regardless of their number.
+	big := []*mirproto.Transaction{{Data: make([]byte, MaxBatchBytes)}}
+	require.False(t, s.record(ctx, big))
+	require.Equal(t, 1, s.saturated)
+}
@@ -0,0 +1,171 @@
+package mir
+
+import (
+	"context"
+	"sync"
+
+	"go.opencensus.io/stats"
+
+	"github.com/filecoin-project/mir/pkg/events"
+	"github.com/filecoin-project/mir/pkg/modules"
+	batchdbpbevents "github.com/filecoin-project/mir/pkg/pb/availabilitypb/batchdbpb/events"
+	batchdbpbtypes "github.com/filecoin-project/mir/pkg/pb/availabilitypb/batchdbpb/types"
+	"github.com/filecoin-project/mir/pkg/pb/eventpb"
+	tt "github.com/filecoin-project/mir/pkg/trantor/types"
+	t "github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+// batchDBModuleID is the ID of the batch store of the availability layer in Trantor.
+const batchDBModuleID = t.ModuleID("batchdb")
+
+var _ modules.PassiveModule = &batchStoreMonitor{}
+
+// The batch store of the availability layer keeps every batch ordered since the last stable checkpoint
+// in memory, and it is only pruned when Mir garbage-collects the epochs before a stable checkpoint.
+// If checkpoints don't become stable, e.g. because the validators lost their quorum, the store grows
+// without bound.
+
+// batchStoreMonitor wraps the batch store of the availability layer to keep track of its size,
+// and prunes it when the size exceeds the cap, even if no checkpoint became stable.
+type batchStoreMonitor struct {
+	modules.PassiveModule
+
+	ctx   context.Context
+	id    string
+	limit int64
+
+	lk sync.Mutex
+	// Size of the transactions and number of batches stored by retention index.
+	bytes   map[tt.RetentionIndex]int64
+	batches map[tt.RetentionIndex]int64
+	// Latest retention index for which the store exceeded the cap without anything to prune.
+	warned tt.RetentionIndex
+}
+
+func newBatchStoreMonitor(ctx context.Context, id string, store modules.PassiveModule, limit int64) *batchStoreMonitor {
+	return &batchStoreMonitor{
+		PassiveModule: store,
+		ctx:           ctx,
+		id:            id,
+		limit:         limit,
+		bytes:         make(map[tt.RetentionIndex]int64),
+		batches:       make(map[tt.RetentionIndex]int64),
+	}
+}
+
+func (b *batchStoreMonitor) ApplyEvents(eventList *events.EventList) (*events.EventList, error) {
+	iter := eventList.Iterator()
+	for event := iter.Next(); event != nil; event = iter.Next() {
+		e, ok := event.Type.(*eventpb.Event_BatchDb)
+		if !ok {
+			continue
+		}
+		switch ev := batchdbpbtypes.EventFromPb(e.BatchDb).Type.(type) {
+		case *batchdbpbtypes.Event_Store:
+			b.stored(ev.Store)
+		case *batchdbpbtypes.Event_GarbageCollect:
+			b.collected(ev.GarbageCollect.RetentionIndex)
+		}
+	}
+
+	out, err := b.PassiveModule.ApplyEvents(eventList)
+	if err != nil {
+		return nil, err
+	}
+	if idx, ok := b.overCap(); ok {
+		pruned, err := b.PassiveModule.ApplyEvents(events.ListOf(batchdbpbevents.GarbageCollect(batchDBModuleID, idx).Pb()))
+		if err != nil {
+			return nil, err
+		}
+		out.PushBackList(pruned)
+	}
+	b.record()
+	return out, nil
+}
+
+func (b *batchStoreMonitor) stored(s *batchdbpbtypes.StoreBatch) {
+	var size int64
+	for _, tx := range s.Txs {
+		size += int64(len(tx.Data))
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	b.bytes[s.RetentionIndex] += size
+	b.batches[s.RetentionIndex]++
+}
+
+// collected forgets the batches below the retention index, which Mir garbage-collects once
+// the checkpoint of the retention index is stable.
+func (b *batchStoreMonitor) collected(idx tt.RetentionIndex) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	for i := range b.bytes {
+		if i < idx {
+			delete(b.bytes, i)
+			delete(b.batches, i)
+		}
+	}
+}
+
+// overCap returns the retention index the store must be pruned to if it exceeds the cap.
+// The batches of the latest retention index are never pruned, as they may not be delivered yet.
+func (b *batchStoreMonitor) overCap() (tt.RetentionIndex, bool) {
+	if b.limit <= 0 {
+		return 0, false
+	}
+	size, latest := b.size()
+	if size <= b.limit {
+		return 0, false
+	}
+
+	b.lk.Lock()
+	pruned := b.bytes[latest] < size
+	warn := !pruned && b.warned != latest
+	if warn {
+		b.warned = latest
+	}
+	b.lk.Unlock()
+	if !pruned {
+		if warn {
+			log.With("validator", b.id).Warnf("batch store of %d bytes exceeds its cap of %d bytes, but only holds batches of the current retention index %d", size, b.limit, latest)
+		}
+		return 0, false
+	}
+
+	log.With("validator", b.id).Warnf("batch store of %d bytes exceeds its cap of %d bytes: pruning the batches before retention index %d without a stable checkpoint; "+
+		"validators that lag behind may be unable to fetch them from this validator", size, b.limit, latest)
+	stats.Record(b.ctx, metrics.MirBatchStorePrunes.M(1))
+	b.collected(latest)
+	return latest, true
+}
+
+// size returns the size of the transactions in the store and the latest retention index.
+func (b *batchStoreMonitor) size() (int64, tt.RetentionIndex) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	var (
+		size   int64
+		latest tt.RetentionIndex
+	)
+	for i, s := range b.bytes {
+		size += s
+		if i > latest {
+			latest = i
+		}
+	}
+	return size, latest
+}
+
+func (b *batchStoreMonitor) record() {
+	size, _ := b.size()
+	b.lk.Lock()
+	var n int64
+	for _, c := range b.batches {
+		n += c
+	}
+	b.lk.Unlock()
+	stats.Record(b.ctx, metrics.MirBatchStoreBytes.M(size), metrics.MirBatchStoreBatches.M(n))
+}
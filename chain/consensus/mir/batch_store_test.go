@@ -0,0 +1,55 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	batchdbpbtypes "github.com/filecoin-project/mir/pkg/pb/availabilitypb/batchdbpb/types"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	tt "github.com/filecoin-project/mir/pkg/trantor/types"
+)
+
+func TestBatchStoreMonitor(t *testing.T) {
+	b := newBatchStoreMonitor(context.Background(), "id1", nil, 100)
+	store := func(idx tt.RetentionIndex, size int) {
+		b.stored(&batchdbpbtypes.StoreBatch{
+			Txs:            []*mirproto.Transaction{{Data: make([]byte, size)}},
+			RetentionIndex: idx,
+		})
+	}
+
+	store(1, 40)
+	store(2, 40)
+	size, latest := b.size()
+	require.Equal(t, int64(80), size)
+	require.Equal(t, tt.RetentionIndex(2), latest)
+	_, ok := b.overCap()
+	require.False(t, ok)
+
+	// Mir garbage-collects the batches before a stable checkpoint.
+	b.collected(2)
+	size, _ = b.size()
+	require.Equal(t, int64(40), size)
+
+	// Above the cap, the batches before the latest retention index are pruned.
+	store(2, 40)
+	store(3, 40)
+	idx, ok := b.overCap()
+	require.True(t, ok)
+	require.Equal(t, tt.RetentionIndex(3), idx)
+	size, _ = b.size()
+	require.Equal(t, int64(40), size)
+
+	// The batches of the latest retention index are never pruned.
+	store(3, 100)
+	_, ok = b.overCap()
+	require.False(t, ok)
+
+	// No cap.
+	b = newBatchStoreMonitor(context.Background(), "id1", nil, 0)
+	store(1, 1000)
+	_, ok = b.overCap()
+	require.False(t, ok)
+}
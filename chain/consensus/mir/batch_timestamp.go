@@ -0,0 +1,239 @@
+package mir
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	"github.com/filecoin-project/mir/pkg/trantor/types"
+	t "github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/consensus"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+	ltypes "github.com/filecoin-project/lotus/chain/types"
+)
+
+// Mir doesn't agree on the time of the batches it orders, so blocks use their height as timestamp,
+// which every validator computes the same way. From the BatchTimestamps upgrade, every validator orders
+// its wall-clock time in timestamp transactions proposed with its batches, and the validators keep the
+// latest time ordered from every member. The timestamp of a block is the (f+1)-th latest of those times:
+// at least one correct validator reached it, so the f validators that may be faulty can't set it in the
+// future, and the blocks of all the validators are still identical but carry a real time.
+
+// NextTimestampTxNoKey is used to store the number of the next timestamp transaction proposed by the validator.
+var NextTimestampTxNoKey = datastore.NewKey("mir/next-timestamp-tx-number")
+
+// timestampClientSuffix is appended to the ID of a validator to get the ID of its timestamp client.
+const timestampClientSuffix = "/timestamps"
+
+// timestampClientID returns the ID of the Mir client used by the validator to propose timestamp transactions.
+func timestampClientID(validatorID string) types.ClientID {
+	return types.ClientID(validatorID + timestampClientSuffix)
+}
+
+// timestampClient assigns Mir transaction numbers to the timestamp transactions proposed by the validator.
+//
+// A single timestamp transaction is pending at a time, and it is proposed in every batch until it is
+// delivered, so the numbers delivered by Mir have no gaps. The number of the next transaction is only
+// persisted when a transaction is delivered: after a restart, the transaction that was pending is replaced
+// by one with the same number and the current time, and Mir delivers only one of them.
+type timestampClient struct {
+	ctx      context.Context
+	ds       db.DB
+	id       string
+	clientID types.ClientID
+
+	lk       sync.Mutex
+	nextTxNo uint64
+	pending  *mirproto.Transaction
+}
+
+func newTimestampClient(ctx context.Context, ds db.DB, id string) (*timestampClient, error) {
+	c := &timestampClient{
+		ctx:      ctx,
+		ds:       ds,
+		id:       id,
+		clientID: timestampClientID(id),
+	}
+	b, err := ds.Get(ctx, NextTimestampTxNoKey)
+	switch {
+	case errors.Is(err, datastore.ErrNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("validator %v failed to get the next timestamp tx number: %w", id, err)
+	default:
+		c.nextTxNo = binary.LittleEndian.Uint64(b)
+	}
+	return c, nil
+}
+
+// Propose returns the pending timestamp transaction, or a new one with the time now if there is none.
+func (c *timestampClient) Propose(now time.Time) *mirproto.Transaction {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if c.pending != nil {
+		return c.pending
+	}
+	data, err := encodeBatchTimestamp(now)
+	if err != nil {
+		log.With("validator", c.id).Errorf("failed to encode batch timestamp: %v", err)
+		return nil
+	}
+	c.pending = &mirproto.Transaction{
+		ClientId: c.clientID,
+		TxNo:     types.TxNo(c.nextTxNo),
+		Type:     TimestampTransaction,
+		Data:     data,
+	}
+	return c.pending
+}
+
+// Delivered marks the pending transaction as delivered, so a new one is proposed with the next batch.
+func (c *timestampClient) Delivered(tx *mirproto.Transaction) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if tx.TxNo.Pb() != c.nextTxNo {
+		return
+	}
+	c.nextTxNo++
+	c.pending = nil
+	rb := make([]byte, 8)
+	binary.LittleEndian.PutUint64(rb, c.nextTxNo)
+	if err := c.ds.Put(c.ctx, NextTimestampTxNoKey, rb); err != nil {
+		log.With("validator", c.id).Warnf("failed to store the next timestamp tx number: %v", err)
+	}
+}
+
+func encodeBatchTimestamp(tm time.Time) ([]byte, error) {
+	var b bytes.Buffer
+	if err := cbg.CborInt(tm.Unix()).MarshalCBOR(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func decodeBatchTimestamp(b []byte) (uint64, error) {
+	var ts cbg.CborInt
+	if err := ts.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return 0, xerrors.Errorf("invalid batch timestamp: %w", err)
+	}
+	if ts <= 0 {
+		return 0, xerrors.Errorf("invalid batch timestamp: %d", ts)
+	}
+	return uint64(ts), nil
+}
+
+// applyTimestampTx records the timestamp of the transaction as the latest one of the validator that sent it,
+// if it is a validator of the membership and the timestamp is later than the previous one.
+func (sm *StateManager) applyTimestampTx(tx *mirproto.Transaction) {
+	if sm.timestampClient != nil && tx.ClientId == sm.timestampClient.clientID {
+		sm.timestampClient.Delivered(tx)
+	}
+	if !strings.HasSuffix(string(tx.ClientId), timestampClientSuffix) {
+		log.With("validator", sm.id).Warnf("timestamp from client %s, which is not a timestamp client", tx.ClientId)
+		return
+	}
+	id := strings.TrimSuffix(string(tx.ClientId), timestampClientSuffix)
+	if _, found := sm.memberships[sm.currentEpoch].Nodes[t.NodeID(id)]; !found {
+		log.With("validator", sm.id).Warnf("timestamp from validator %s, which is not in the membership", id)
+		return
+	}
+	ts, err := decodeBatchTimestamp(tx.Data)
+	if err != nil {
+		log.With("validator", sm.id).Warnf("validator %s sent %v", id, err)
+		return
+	}
+	if ts > sm.timestamps[id] {
+		sm.timestamps[id] = ts
+	}
+}
+
+// memberTimestamps returns the latest timestamps of the validators of the current membership.
+func (sm *StateManager) memberTimestamps() []uint64 {
+	var ts []uint64
+	for id := range sm.memberships[sm.currentEpoch].Nodes {
+		if tm, ok := sm.timestamps[id.Pb()]; ok {
+			ts = append(ts, tm)
+		}
+	}
+	return ts
+}
+
+// pruneTimestamps removes the timestamps of the validators that are not in the current membership.
+func (sm *StateManager) pruneTimestamps() {
+	for id := range sm.timestamps {
+		if _, found := sm.memberships[sm.currentEpoch].Nodes[t.NodeID(id)]; !found {
+			delete(sm.timestamps, id)
+		}
+	}
+}
+
+// timestampRecords returns the latest timestamps of the validators, sorted by validator.
+func timestampRecords(timestamps map[string]uint64) []ValidatorTimestamp {
+	records := make([]ValidatorTimestamp, 0, len(timestamps))
+	for id, ts := range timestamps {
+		records = append(records, ValidatorTimestamp{Validator: id, Timestamp: ts})
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Validator < records[j].Validator })
+	return records
+}
+
+// timestampsFromRecords returns the latest timestamps of the validators recorded in a checkpoint.
+func timestampsFromRecords(records []ValidatorTimestamp) (map[string]uint64, error) {
+	timestamps := make(map[string]uint64, len(records))
+	for _, r := range records {
+		if _, found := timestamps[r.Validator]; found {
+			return nil, xerrors.Errorf("duplicated timestamp of validator %s", r.Validator)
+		}
+		timestamps[r.Validator] = r.Timestamp
+	}
+	return timestamps, nil
+}
+
+// blockTimestamp returns the timestamp of the block at height h on top of a parent with timestamp parent.
+// Without batch timestamps it is the height. Otherwise it is the (f+1)-th latest of the timestamps of the
+// n validators of the membership, and never earlier than the timestamp of the parent.
+func blockTimestamp(enabled bool, h abi.ChainEpoch, parent uint64, timestamps []uint64, n int) uint64 {
+	if !enabled {
+		return uint64(h)
+	}
+	sorted := append([]uint64(nil), timestamps...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+	if f := maxFaulty(n); len(sorted) > f && sorted[f] > parent {
+		return sorted[f]
+	}
+	return parent
+}
+
+// checkTimestamp checks the timestamp of a block. Without batch timestamps it must be the height of the
+// block. Otherwise, it must not be in the future, allowing for build.AllowableClockDriftSecs, nor earlier
+// than the timestamp of the parent, if not nil.
+func checkTimestamp(h *ltypes.BlockHeader, parent *uint64, batchTimestamps bool) error {
+	if !batchTimestamps {
+		if h.Timestamp != uint64(h.Height) {
+			return xerrors.Errorf("block timestamp %d is not the height %d", h.Timestamp, h.Height)
+		}
+		return nil
+	}
+	if parent != nil && h.Timestamp < *parent {
+		return xerrors.Errorf("block timestamp %d earlier than the parent timestamp %d", h.Timestamp, *parent)
+	}
+	if now := uint64(build.Clock.Now().Unix()); h.Timestamp > now+build.AllowableClockDriftSecs {
+		return xerrors.Errorf("block was from the future (now=%d, blk=%d): %w", now, h.Timestamp, consensus.ErrTemporal)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package mir
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestBatchTimestamps(t *testing.T) {
+	now := time.Now()
+	b, err := encodeBatchTimestamp(now)
+	require.NoError(t, err)
+	ts, err := decodeBatchTimestamp(b)
+	require.NoError(t, err)
+	require.Equal(t, uint64(now.Unix()), ts)
+
+	// Without batch timestamps, the height is the timestamp.
+	require.Equal(t, uint64(7), blockTimestamp(false, 7, 100, []uint64{200}, 4))
+	// The (f+1)-th latest timestamp of the validators is used, so a single validator
+	// can't move the time of the chain forward...
+	require.Equal(t, uint64(150), blockTimestamp(true, 7, 100, []uint64{150, 1 << 40, 120}, 4))
+	// ...nor stall it.
+	require.Equal(t, uint64(150), blockTimestamp(true, 7, 100, []uint64{150, 160, 0, 120}, 4))
+	// The timestamp of the parent is kept if there are not enough timestamps, or they are earlier.
+	require.Equal(t, uint64(100), blockTimestamp(true, 7, 100, []uint64{200}, 4))
+	require.Equal(t, uint64(100), blockTimestamp(true, 7, 100, []uint64{90, 80}, 4))
+	require.Equal(t, uint64(100), blockTimestamp(true, 7, 100, nil, 4))
+
+	parent := uint64(now.Unix())
+	// Without batch timestamps, the timestamp must be the height.
+	require.NoError(t, checkTimestamp(&types.BlockHeader{Height: 7, Timestamp: 7}, &parent, false))
+	require.Error(t, checkTimestamp(&types.BlockHeader{Height: 7, Timestamp: parent}, &parent, false))
+	// With them, it must be between the parent and the current time.
+	require.NoError(t, checkTimestamp(&types.BlockHeader{Height: 7, Timestamp: parent}, &parent, true))
+	require.Error(t, checkTimestamp(&types.BlockHeader{Height: 7, Timestamp: 7}, &parent, true))
+	require.Error(t, checkTimestamp(&types.BlockHeader{Height: 7, Timestamp: parent + 3600}, &parent, true))
+}
+
+func TestTimestampClient(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+	now := time.Now()
+
+	c, err := newTimestampClient(ctx, ds, "id1")
+	require.NoError(t, err)
+	tx := c.Propose(now)
+	require.Equal(t, timestampClientID("id1"), tx.ClientId)
+	require.Equal(t, uint64(0), tx.TxNo.Pb())
+	// The pending transaction is proposed until it is delivered.
+	require.Equal(t, tx, c.Propose(now.Add(time.Second)))
+	c.Delivered(tx)
+	next := c.Propose(now.Add(time.Second))
+	require.Equal(t, uint64(1), next.TxNo.Pb())
+
+	// After a restart, the numbering continues from the last delivered transaction.
+	c, err = newTimestampClient(ctx, ds, "id1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), c.Propose(now).TxNo.Pb())
+
+	records := timestampRecords(map[string]uint64{"id2": 2, "id1": 1})
+	require.Equal(t, []ValidatorTimestamp{{Validator: "id1", Timestamp: 1}, {Validator: "id2", Timestamp: 2}}, records)
+	timestamps, err := timestampsFromRecords(records)
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint64{"id1": 1, "id2": 2}, timestamps)
+	_, err = timestampsFromRecords(append(records, records[0]))
+	require.Error(t, err)
+}
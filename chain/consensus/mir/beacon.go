@@ -0,0 +1,91 @@
+package mir
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// Mir blocks carry no drand entries. When checkpoint randomness is enabled, validators include
+// in every block a beacon entry derived from the entry of the parent block and from the
+// checkpoint and certificate included in the block (if any). The entry of a block is a
+// deterministic function of the chain, so it can be verified by any node, and it is refreshed
+// with data that can't be predicted before the validators certify a new checkpoint.
+//
+// Note that only the checkpoints add unpredictable data: the entries of the blocks between two
+// checkpoints only depend on the previous entry and the height, so anyone who has seen the last
+// checkpoint can compute them in advance. Actors must not rely on this randomness for anything
+// that must stay unpredictable for longer than a checkpoint period.
+//
+// Whether a chain includes entries is decided by the first block after genesis, and enforced
+// on chain from then on: a block must include an entry if and only if its parent does.
+//
+// The entries are exposed to actors through the beacon randomness syscall.
+
+// NextBeaconEntry derives the beacon entry for a block at the given height.
+// prev is the latest beacon entry in the parent chain, or nil if there is none.
+func NextBeaconEntry(prev *types.BeaconEntry, height abi.ChainEpoch, ticket *types.Ticket, eproof *types.ElectionProof) types.BeaconEntry {
+	h := sha256.New()
+	if prev != nil {
+		h.Write(prev.Data)
+	}
+	if ticket != nil {
+		h.Write(ticket.VRFProof)
+	}
+	if eproof != nil {
+		h.Write(eproof.VRFProof)
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(height))
+	h.Write(b[:])
+
+	return types.BeaconEntry{
+		Round: uint64(height),
+		Data:  h.Sum(nil),
+	}
+}
+
+// latestBeaconEntry returns the last beacon entry of the block, or nil if it has none.
+func latestBeaconEntry(h *types.BlockHeader) *types.BeaconEntry {
+	if len(h.BeaconEntries) == 0 {
+		return nil
+	}
+	return &h.BeaconEntries[len(h.BeaconEntries)-1]
+}
+
+// includeBeaconEntry determines if the block at the given height on top of parent must include a beacon entry.
+// enabled is only taken into account for the first block after genesis.
+func includeBeaconEntry(parent *types.BlockHeader, height abi.ChainEpoch, enabled bool) bool {
+	if parent.Height == 0 && height == 1 {
+		return enabled
+	}
+	return len(parent.BeaconEntries) > 0
+}
+
+// verifyBeaconEntries checks that a block includes a beacon entry if and only if its parent does,
+// and that the entry has been derived from its parent and its checkpoint.
+func verifyBeaconEntries(h *types.BlockHeader, parent *types.BlockHeader) error {
+	if len(h.BeaconEntries) == 0 {
+		if len(parent.BeaconEntries) > 0 {
+			return xerrors.Errorf("missing beacon entry at height %d", h.Height)
+		}
+		return nil
+	}
+	if len(parent.BeaconEntries) == 0 && !(parent.Height == 0 && h.Height == 1) {
+		return xerrors.Errorf("beacon entries can only be enabled in the first block, got one at height %d", h.Height)
+	}
+	if len(h.BeaconEntries) != 1 {
+		return xerrors.Errorf("mir blocks include at most one beacon entry, got %d", len(h.BeaconEntries))
+	}
+	expected := NextBeaconEntry(latestBeaconEntry(parent), h.Height, h.Ticket, h.ElectionProof)
+	if e := h.BeaconEntries[0]; e.Round != expected.Round || !bytes.Equal(e.Data, expected.Data) {
+		return xerrors.Errorf("invalid beacon entry at height %d", h.Height)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestBeaconEntries(t *testing.T) {
+	genesis := &types.BlockHeader{Height: 0, Ticket: &types.Ticket{}, ElectionProof: &types.ElectionProof{}}
+
+	// Chains without beacon entries are valid.
+	h := &types.BlockHeader{Height: 1, Ticket: &types.Ticket{}, ElectionProof: &types.ElectionProof{}}
+	require.NoError(t, verifyBeaconEntries(h, genesis))
+
+	e := NextBeaconEntry(latestBeaconEntry(genesis), h.Height, h.Ticket, h.ElectionProof)
+	require.Equal(t, uint64(1), e.Round)
+	h.BeaconEntries = []types.BeaconEntry{e}
+	require.NoError(t, verifyBeaconEntries(h, genesis))
+
+	// The entry depends on the checkpoint included in the block.
+	withCheckpoint := NextBeaconEntry(nil, h.Height, &types.Ticket{VRFProof: []byte{1}}, &types.ElectionProof{VRFProof: []byte{2}})
+	require.NotEqual(t, e.Data, withCheckpoint.Data)
+	h.BeaconEntries = []types.BeaconEntry{withCheckpoint}
+	require.Error(t, verifyBeaconEntries(h, genesis))
+
+	// The entry depends on the entry of the parent.
+	child := &types.BlockHeader{Height: 2, Ticket: &types.Ticket{}, ElectionProof: &types.ElectionProof{}}
+	h.BeaconEntries = []types.BeaconEntry{e}
+	next := NextBeaconEntry(latestBeaconEntry(h), child.Height, child.Ticket, child.ElectionProof)
+	child.BeaconEntries = []types.BeaconEntry{next}
+	require.NoError(t, verifyBeaconEntries(child, h))
+
+	child.BeaconEntries = []types.BeaconEntry{next, next}
+	require.Error(t, verifyBeaconEntries(child, h))
+
+	// Once a chain includes entries, every block must include one.
+	child.BeaconEntries = nil
+	require.Error(t, verifyBeaconEntries(child, h))
+}
+
+func TestBeaconEntriesActivation(t *testing.T) {
+	genesis := &types.BlockHeader{Height: 0, Ticket: &types.Ticket{}, ElectionProof: &types.ElectionProof{}}
+	parent := &types.BlockHeader{Height: 1, Ticket: &types.Ticket{}, ElectionProof: &types.ElectionProof{}}
+
+	// The first block decides if the chain includes beacon entries.
+	require.True(t, includeBeaconEntry(genesis, 1, true))
+	require.False(t, includeBeaconEntry(genesis, 1, false))
+	require.False(t, includeBeaconEntry(parent, 2, true))
+
+	// Entries can't be enabled later on.
+	h := &types.BlockHeader{Height: 2, Ticket: &types.Ticket{}, ElectionProof: &types.ElectionProof{}}
+	h.BeaconEntries = []types.BeaconEntry{NextBeaconEntry(nil, h.Height, h.Ticket, h.ElectionProof)}
+	require.Error(t, verifyBeaconEntries(h, parent))
+
+	// Validators follow the chain regardless of their configuration.
+	parent.BeaconEntries = []types.BeaconEntry{NextBeaconEntry(nil, parent.Height, parent.Ticket, parent.ElectionProof)}
+	require.True(t, includeBeaconEntry(parent, 2, false))
+}
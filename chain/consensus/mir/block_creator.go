@@ -0,0 +1,87 @@
+package mir
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/v1api"
+	"github.com/filecoin-project/lotus/chain/consensus"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// BlockCreator creates the blocks of a validator from the templates built by its StateManager.
+type BlockCreator interface {
+	CreateBlock(ctx context.Context, bt *lapi.BlockTemplate) (*types.BlockMsg, error)
+}
+
+// CreateBlock creates a Mir block from a block template on top of the state of sm.
+// Mir blocks aren't signed, so unlike the consensus.Consensus API it doesn't take a wallet.
+func CreateBlock(ctx context.Context, sm *stmgr.StateManager, bt *lapi.BlockTemplate) (*types.FullBlock, error) {
+	pts, err := sm.ChainStore().LoadTipSet(ctx, bt.Parents)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load parent tipset: %w", err)
+	}
+
+	next, blsMessages, secpkMessages, err := consensus.CreateBlockHeader(ctx, sm, pts, bt)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to process messages from block template: %w", err)
+	}
+
+	return &types.FullBlock{
+		Header:        next,
+		BlsMessages:   blsMessages,
+		SecpkMessages: secpkMessages,
+	}, nil
+}
+
+// LocalBlockCreator creates blocks in the process of the full node, for validators that run in the
+// same process as their node, instead of calling MinerCreateBlock through its API.
+type LocalBlockCreator struct {
+	sm *stmgr.StateManager
+
+	// Timestamp, if set, overrides the timestamp of the block templates.
+	// Tests use it to create blocks with deterministic timestamps.
+	Timestamp func(abi.ChainEpoch) uint64
+}
+
+var _ BlockCreator = (*LocalBlockCreator)(nil)
+
+func NewLocalBlockCreator(sm *stmgr.StateManager) *LocalBlockCreator {
+	return &LocalBlockCreator{sm: sm}
+}
+
+func (c *LocalBlockCreator) CreateBlock(ctx context.Context, bt *lapi.BlockTemplate) (*types.BlockMsg, error) {
+	if c.Timestamp != nil {
+		t := *bt
+		t.Timestamp = c.Timestamp(bt.Epoch)
+		bt = &t
+	}
+
+	fblk, err := CreateBlock(ctx, c.sm, bt)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &types.BlockMsg{Header: fblk.Header}
+	for _, msg := range fblk.BlsMessages {
+		out.BlsMessages = append(out.BlsMessages, msg.Cid())
+	}
+	for _, msg := range fblk.SecpkMessages {
+		out.SecpkMessages = append(out.SecpkMessages, msg.Cid())
+	}
+	return out, nil
+}
+
+// apiBlockCreator creates blocks with the MinerCreateBlock API of the node of the validator.
+type apiBlockCreator struct {
+	api v1api.FullNode
+}
+
+func (c *apiBlockCreator) CreateBlock(ctx context.Context, bt *lapi.BlockTemplate) (*types.BlockMsg, error) {
+	return c.api.MinerCreateBlock(ctx, bt)
+}
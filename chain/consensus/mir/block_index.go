@@ -0,0 +1,65 @@
+package mir
+
+import (
+	"sync"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// blockIndex maps the heights of the blocks produced since the latest checkpoint to their CIDs.
+//
+// The index is maintained incrementally while the validator creates blocks, so the snapshot of a checkpoint
+// can be assembled without walking the chain through the API. The index is not persisted: after a restart
+// the heights that are missing are resolved from the chain.
+type blockIndex struct {
+	lk   sync.Mutex
+	cids map[abi.ChainEpoch]cid.Cid
+}
+
+func newBlockIndex() *blockIndex {
+	return &blockIndex{cids: make(map[abi.ChainEpoch]cid.Cid)}
+}
+
+func (i *blockIndex) add(h abi.ChainEpoch, c cid.Cid) {
+	i.lk.Lock()
+	defer i.lk.Unlock()
+	i.cids[h] = c
+}
+
+func (i *blockIndex) get(h abi.ChainEpoch) (cid.Cid, bool) {
+	i.lk.Lock()
+	defer i.lk.Unlock()
+	c, ok := i.cids[h]
+	return c, ok
+}
+
+// all returns a copy of the index.
+func (i *blockIndex) all() map[abi.ChainEpoch]cid.Cid {
+	i.lk.Lock()
+	defer i.lk.Unlock()
+	cids := make(map[abi.ChainEpoch]cid.Cid, len(i.cids))
+	for h, c := range i.cids {
+		cids[h] = c
+	}
+	return cids
+}
+
+// prune removes the blocks below height h.
+func (i *blockIndex) prune(h abi.ChainEpoch) {
+	i.lk.Lock()
+	defer i.lk.Unlock()
+	for k := range i.cids {
+		if k < h {
+			delete(i.cids, k)
+		}
+	}
+}
+
+// reset removes all the blocks, e.g. when the chain is restored from a checkpoint.
+func (i *blockIndex) reset() {
+	i.lk.Lock()
+	defer i.lk.Unlock()
+	i.cids = make(map[abi.ChainEpoch]cid.Cid)
+}
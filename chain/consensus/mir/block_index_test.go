@@ -0,0 +1,31 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	u "github.com/ipfs/go-ipfs-util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockIndex(t *testing.T) {
+	idx := newBlockIndex()
+	c1 := cid.NewCidV0(u.Hash([]byte("blk1")))
+	c2 := cid.NewCidV0(u.Hash([]byte("blk2")))
+
+	idx.add(1, c1)
+	idx.add(2, c2)
+	c, ok := idx.get(2)
+	require.True(t, ok)
+	require.Equal(t, c2, c)
+
+	idx.prune(2)
+	_, ok = idx.get(1)
+	require.False(t, ok)
+	_, ok = idx.get(2)
+	require.True(t, ok)
+
+	idx.reset()
+	_, ok = idx.get(2)
+	require.False(t, ok)
+}
@@ -0,0 +1,96 @@
+package mir
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api/v1api"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+const UnsubmittedBlocksDBPrefix = "mir/unsubmitted-blocks/"
+
+var (
+	// MaxBlockSubmitRetries is the number of times SyncSubmitBlock is retried when it fails.
+	MaxBlockSubmitRetries = 5
+	// BlockSubmitRetryBackoff is the time waited before the first retry; it doubles with every retry.
+	BlockSubmitRetryBackoff = 500 * time.Millisecond
+)
+
+func unsubmittedBlockKey(h abi.ChainEpoch) datastore.Key {
+	return datastore.NewKey(UnsubmittedBlocksDBPrefix + strconv.FormatInt(int64(h), 10))
+}
+
+// submitBlock submits the block to the node, retrying SyncSubmitBlock when it fails, e.g. during a hiccup
+// of the API. Retries are idempotent: a failed call may have reached the node anyway, so a block already
+// in the chain of the node is not submitted again. If all the attempts fail, the block is stored in ds under
+// UnsubmittedBlocksDBPrefix and its height, so it can be inspected, and the last error is returned.
+func submitBlock(ctx context.Context, api v1api.FullNode, ds db.DB, blk *types.BlockMsg) error {
+	c := blk.Header.Cid()
+	backoff := BlockSubmitRetryBackoff
+	err := api.SyncSubmitBlock(ctx, blk)
+	for i := 0; err != nil && i < MaxBlockSubmitRetries; i++ {
+		log.Warnf("failed to submit block %d (%s), retrying in %s: %v", blk.Header.Height, c, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		if inChain(ctx, api, blk.Header.Height, c) {
+			log.Infof("block %d (%s) was accepted by the node despite the error", blk.Header.Height, c)
+			return nil
+		}
+		err = api.SyncSubmitBlock(ctx, blk)
+	}
+	if err == nil {
+		return nil
+	}
+
+	b, serr := blk.Serialize()
+	if serr != nil {
+		return xerrors.Errorf("error serializing unsubmitted block: %v (submission error: %w)", serr, err)
+	}
+	if perr := ds.Put(ctx, unsubmittedBlockKey(blk.Header.Height), b); perr != nil {
+		log.Errorf("failed to store unsubmitted block %d: %v", blk.Header.Height, perr)
+	}
+	return xerrors.Errorf("giving up after %d retries: %w", MaxBlockSubmitRetries, err)
+}
+
+// inChain returns whether the block c is at height h in the chain of the node. The node only extends its
+// chain with blocks it validated, unlike its blockstore, which may have the header of a block that failed.
+func inChain(ctx context.Context, api v1api.FullNode, h abi.ChainEpoch, c cid.Cid) bool {
+	ts, err := api.ChainGetTipSetByHeight(ctx, h, types.EmptyTSK)
+	if err != nil || ts.Height() != h {
+		return false
+	}
+	for _, b := range ts.Cids() {
+		if b == c {
+			return true
+		}
+	}
+	return false
+}
+
+// UnsubmittedBlock returns the block at height h that the validator failed to submit to its node.
+func UnsubmittedBlock(ctx context.Context, ds db.DB, h abi.ChainEpoch) (*types.BlockMsg, error) {
+	b, err := ds.Get(ctx, unsubmittedBlockKey(h))
+	if err != nil {
+		return nil, err
+	}
+	var blk types.BlockMsg
+	if err := blk.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return nil, xerrors.Errorf("error decoding unsubmitted block %d: %w", h, err)
+	}
+	return &blk, nil
+}
@@ -0,0 +1,67 @@
+package mir
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api/mocks"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestSubmitBlock(t *testing.T) {
+	backoff := BlockSubmitRetryBackoff
+	t.Cleanup(func() { BlockSubmitRetryBackoff = backoff })
+	BlockSubmitRetryBackoff = time.Millisecond
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	node := mocks.NewMockFullNode(ctrl)
+	ds := datastore.NewMapDatastore()
+
+	parent := mock.TipSet(mock.MkBlock(nil, 1, 1))
+	ts := mock.TipSet(mock.MkBlock(parent, 1, 1))
+	blk := &types.BlockMsg{Header: ts.Blocks()[0]}
+	h := blk.Header.Height
+	errHiccup := errors.New("connection reset")
+
+	// A transient failure is retried.
+	gomock.InOrder(
+		node.EXPECT().SyncSubmitBlock(gomock.Any(), blk).Return(errHiccup),
+		node.EXPECT().ChainGetTipSetByHeight(gomock.Any(), h, types.EmptyTSK).Return(parent, nil),
+		node.EXPECT().SyncSubmitBlock(gomock.Any(), blk).Return(nil),
+	)
+	require.NoError(t, submitBlock(ctx, node, ds, blk))
+
+	// A block stored by the node but not in its chain is submitted again.
+	other := mock.TipSet(mock.MkBlock(parent, 1, 2))
+	gomock.InOrder(
+		node.EXPECT().SyncSubmitBlock(gomock.Any(), blk).Return(errHiccup),
+		node.EXPECT().ChainGetTipSetByHeight(gomock.Any(), h, types.EmptyTSK).Return(other, nil),
+		node.EXPECT().SyncSubmitBlock(gomock.Any(), blk).Return(nil),
+	)
+	require.NoError(t, submitBlock(ctx, node, ds, blk))
+
+	// A block accepted despite the error is not submitted again.
+	gomock.InOrder(
+		node.EXPECT().SyncSubmitBlock(gomock.Any(), blk).Return(errHiccup),
+		node.EXPECT().ChainGetTipSetByHeight(gomock.Any(), h, types.EmptyTSK).Return(ts, nil),
+	)
+	require.NoError(t, submitBlock(ctx, node, ds, blk))
+
+	// After the retries, the block is stored for inspection.
+	node.EXPECT().SyncSubmitBlock(gomock.Any(), blk).Return(errHiccup).Times(MaxBlockSubmitRetries + 1)
+	node.EXPECT().ChainGetTipSetByHeight(gomock.Any(), h, types.EmptyTSK).
+		Return(nil, errors.New("height above the head")).Times(MaxBlockSubmitRetries)
+	require.ErrorIs(t, submitBlock(ctx, node, ds, blk), errHiccup)
+
+	stored, err := UnsubmittedBlock(ctx, ds, blk.Header.Height)
+	require.NoError(t, err)
+	require.Equal(t, blk.Header.Cid(), stored.Header.Cid())
+}
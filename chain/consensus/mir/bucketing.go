@@ -0,0 +1,69 @@
+package mir
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// mempoolBuckets assigns the senders of mempool messages to validators, so that validators
+// selecting messages from the same mempool don't propose the same messages.
+//
+// The assignment is a deterministic function of the sender address, the validator set and
+// the round, so all validators with the same view agree on it without any communication.
+// The round is the Mir epoch being ordered, which is delimited in the ordered log and hence
+// the same for all the validators, regardless of how far their local chain has progressed.
+// All the messages of a sender are assigned to the same validator, which keeps the nonces of
+// the sender in order. The assignment rotates every round, so a faulty validator can only
+// delay the messages of the senders in its bucket until the next round.
+type mempoolBuckets struct {
+	self       string
+	validators []string
+}
+
+func newMempoolBuckets(self string, set *validator.Set) *mempoolBuckets {
+	b := mempoolBuckets{self: self}
+	for _, v := range set.Validators {
+		b.validators = append(b.validators, v.ID())
+	}
+	sort.Strings(b.validators)
+	return &b
+}
+
+// owns returns true if the sender is assigned to this validator in the round.
+// A validator that is not part of the validator set owns all the senders.
+func (b *mempoolBuckets) owns(sender address.Address, round uint64) bool {
+	n := uint64(len(b.validators))
+	if n <= 1 {
+		return true
+	}
+	self := sort.SearchStrings(b.validators, b.self)
+	if self == len(b.validators) || b.validators[self] != b.self {
+		return true
+	}
+
+	h := fnv.New64a()
+	h.Write(sender.Bytes()) // nolint
+	var r [8]byte
+	binary.BigEndian.PutUint64(r[:], round)
+	h.Write(r[:]) // nolint
+
+	return h.Sum64()%n == uint64(self)
+}
+
+// filter returns the messages whose senders are assigned to this validator in the round.
+func (b *mempoolBuckets) filter(msgs []*types.SignedMessage, round uint64) []*types.SignedMessage {
+	var owned []*types.SignedMessage
+	for _, msg := range msgs {
+		if b.owns(msg.Message.From, round) {
+			owned = append(owned, msg)
+		}
+	}
+	return owned
+}
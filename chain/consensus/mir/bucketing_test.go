@@ -0,0 +1,66 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func newTestBucketsValidatorSet(t *testing.T) *validator.Set {
+	s1 := "t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:10@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ"
+	v1, err := validator.NewValidatorFromString(s1)
+	require.NoError(t, err)
+
+	s2 := "t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq:10@/ip4/127.0.0.1/tcp/10001/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ"
+	v2, err := validator.NewValidatorFromString(s2)
+	require.NoError(t, err)
+
+	return validator.NewValidatorSet(0, []*validator.Validator{v1, v2})
+}
+
+func TestMempoolBucketsPartitionSenders(t *testing.T) {
+	set := newTestBucketsValidatorSet(t)
+	b1 := newMempoolBuckets(set.Validators[0].ID(), set)
+	b2 := newMempoolBuckets(set.Validators[1].ID(), set)
+
+	rotated := 0
+	for i := uint64(0); i < 100; i++ {
+		sender, err := address.NewIDAddress(1000 + i)
+		require.NoError(t, err)
+
+		for round := uint64(0); round < 4; round++ {
+			// Every sender is assigned to exactly one validator.
+			require.NotEqual(t, b1.owns(sender, round), b2.owns(sender, round))
+		}
+		if b1.owns(sender, 0) != b1.owns(sender, 1) {
+			rotated++
+		}
+	}
+	require.Greater(t, rotated, 0)
+}
+
+func TestMempoolBucketsOwnAllIfNotValidator(t *testing.T) {
+	set := newTestBucketsValidatorSet(t)
+	b := newMempoolBuckets("t1-not-a-validator", set)
+
+	msgs := []*types.SignedMessage{newTestSignedMessage(t, 1), newTestSignedMessage(t, 2)}
+	require.Equal(t, msgs, b.filter(msgs, 7))
+}
+
+func TestMempoolBucketsKeepSenderMessagesTogether(t *testing.T) {
+	set := newTestBucketsValidatorSet(t)
+	b1 := newMempoolBuckets(set.Validators[0].ID(), set)
+	b2 := newMempoolBuckets(set.Validators[1].ID(), set)
+
+	msgs := []*types.SignedMessage{newTestSignedMessage(t, 1), newTestSignedMessage(t, 2), newTestSignedMessage(t, 3)}
+	for round := uint64(0); round < 4; round++ {
+		owned1, owned2 := b1.filter(msgs, round), b2.filter(msgs, round)
+		require.Equal(t, len(msgs), len(owned1)+len(owned2))
+		require.True(t, len(owned1) == 0 || len(owned2) == 0)
+	}
+}
@@ -4,17 +4,21 @@ import (
 	"context"
 	"fmt"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 
+	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/query"
+	"go.opencensus.io/stats"
 
 	"github.com/filecoin-project/go-state-types/abi"
 
 	"github.com/filecoin-project/lotus/chain"
+	"github.com/filecoin-project/lotus/chain/consensus"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/metrics"
 )
 
 const (
@@ -27,52 +31,107 @@ var (
 	latestCheckKey = datastore.NewKey(CachePrefix + "latestCheck")
 )
 
-func blkCacheKey(e abi.ChainEpoch) datastore.Key {
-	return datastore.NewKey(BlkCachePrefix + strconv.FormatUint(uint64(e), 10))
-}
-
 func checkCacheKey(e abi.ChainEpoch) datastore.Key {
 	return datastore.NewKey(CheckCachePrefix + strconv.FormatUint(uint64(e), 10))
 }
 
-func heightFromBlkKey(k string) (abi.ChainEpoch, error) {
-	n, err := strconv.Atoi(strings.Split(k, BlkCachePrefix)[1])
-	if err != nil {
-		return -1, err
+// DefaultBlkCacheWindow bounds the unverified blocks kept by the block cache to a few MiB, while
+// covering two checkpoint periods of DefaultMaxCheckpointBlocks.
+const DefaultBlkCacheWindow = 8192
+
+func blkCacheWindow(cfg LearnerConfig) int {
+	if cfg.BlkCacheWindow > 0 {
+		return cfg.BlkCacheWindow
 	}
-	return abi.ChainEpoch(n), nil
+	return DefaultBlkCacheWindow
 }
 
+// mirCache keeps the blocks received by the daemon until they are verified by a checkpoint, and the
+// latest verified checkpoint.
+//
+// Unverified blocks are received from any peer, so they are only kept in memory, and only for the
+// heights in a window above the latest checkpoint: a peer flooding the daemon with blocks can't fill
+// the cache with heights the chain won't reach soon. The blocks of the heights below the window are
+// already covered by a checkpoint, and the blocks above it are received again once the chain gets
+// closer, so both are rejected without being cached. Every block covered by a checkpoint must be
+// either in the cache or in the chain, and the checkpoint is rejected otherwise.
+// Verified checkpoints are persisted in the datastore.
 type mirCache struct {
-	ds datastore.Batching
+	ds   datastore.Batching
+	blks *lru.Cache[abi.ChainEpoch, cid.Cid]
+	// Number of heights kept from the height of the latest checkpoint.
+	window abi.ChainEpoch
+	// Height of the latest checkpoint, where the window starts.
+	latestHeight atomic.Int64
+	// Returns the block at a height in the chain of the daemon, cid.Undef if there is none.
+	// Used to check the blocks validated before a restart, which are no longer in the cache.
+	onChain func(e abi.ChainEpoch) cid.Cid
 	// lock to avoid starting bad block
 	// marking processes in parallel
 	badBlkLk sync.Mutex
 	badBlk   *chain.BadBlockCache
+	// Called with the blocks marked as bad after a checkpoint is received, if any.
+	onUncovered func(snap *Checkpoint, blks []cid.Cid)
+	// Freeze height of the subnet in the latest checkpoint, zero if it isn't frozen.
+	freezeHeight atomic.Int64
 }
 
-func newDsBlkCache(ds datastore.Batching, bad *chain.BadBlockCache) *mirCache {
-	return &mirCache{ds: ds, badBlk: bad}
+func newDsBlkCache(ds datastore.Batching, bad *chain.BadBlockCache, cfg LearnerConfig) *mirCache {
+	return newBlkCache(ds, bad, blkCacheWindow(cfg))
 }
 
-func (c *mirCache) getBlk(e abi.ChainEpoch) (cid.Cid, error) {
-	v, err := c.ds.Get(context.Background(), blkCacheKey(e))
+func newBlkCache(ds datastore.Batching, bad *chain.BadBlockCache, window int) *mirCache {
+	// The window bounds the heights of the blocks, the LRU evicts the blocks left below the window.
+	blks, err := lru.New[abi.ChainEpoch, cid.Cid](window)
 	if err != nil {
-		if err == datastore.ErrNotFound {
-			return cid.Undef, nil
-		}
-		return cid.Undef, err
+		// Only fails with a non-positive size.
+		panic(err)
+	}
+	c := &mirCache{ds: ds, blks: blks, window: abi.ChainEpoch(window), badBlk: bad}
+	// Earlier versions persisted the unverified blocks, which are no longer read.
+	if err := c.purgeByPrefix(BlkCachePrefix); err != nil {
+		log.Warnf("error purging unverified blocks persisted in Mir cache: %s", err)
+	}
+	if latest, err := c.getLatestCheckpoint(); err != nil {
+		log.Warnf("error getting latest checkpoint from Mir cache: %s", err)
+	} else if latest != nil {
+		c.latestHeight.Store(int64(latest.Height))
+		c.freezeHeight.Store(int64(latest.Ext.FreezeHeight))
+	}
+	return c
+}
+
+// frozenAt returns the height at which the subnet was frozen according to the latest checkpoint,
+// or zero if it wasn't.
+func (c *mirCache) frozenAt() abi.ChainEpoch {
+	return abi.ChainEpoch(c.freezeHeight.Load())
+}
+
+// getBlk returns the unverified block at height e, cid.Undef if there is none.
+func (c *mirCache) getBlk(e abi.ChainEpoch) cid.Cid {
+	v, ok := c.blks.Get(e)
+	if !ok {
+		stats.Record(context.Background(), metrics.MirBlkCacheMisses.M(1))
+		return cid.Undef
 	}
-	_, one, err := cid.CidFromBytes(v)
-	return one, err
+	stats.Record(context.Background(), metrics.MirBlkCacheHits.M(1))
+	return v
 }
 
-func (c *mirCache) putBlk(e abi.ChainEpoch, v cid.Cid) error {
-	return c.ds.Put(context.Background(), blkCacheKey(e), v.Bytes())
+// putBlk adds the block at height e, unless there is already one, which is returned.
+func (c *mirCache) putBlk(e abi.ChainEpoch, v cid.Cid) cid.Cid {
+	prev, ok, evicted := c.blks.PeekOrAdd(e, v)
+	if evicted {
+		stats.Record(context.Background(), metrics.MirBlkCacheEvictions.M(1))
+	}
+	if ok {
+		return prev
+	}
+	return v
 }
 
-func (c *mirCache) rmBlk(e abi.ChainEpoch) error {
-	return c.ds.Delete(context.Background(), blkCacheKey(e))
+func (c *mirCache) rmBlk(e abi.ChainEpoch) {
+	c.blks.Remove(e)
 }
 
 func (c *mirCache) getCheck(e abi.ChainEpoch) (*Checkpoint, error) {
@@ -113,9 +172,7 @@ func (c *mirCache) rmCheck(e abi.ChainEpoch) error {
 // should consider performing this in the background to remove it
 // from the critical path.
 func (c *mirCache) purge() error {
-	if err := c.purgeByPrefix(BlkCachePrefix); err != nil {
-		return err
-	}
+	c.blks.Purge()
 	return c.purgeByPrefix(CheckCachePrefix)
 }
 
@@ -127,29 +184,26 @@ func (c *mirCache) purgeByPrefix(prefix string) error {
 		return fmt.Errorf("error performing cache query for purge prefix %s: %w", prefix, err)
 	}
 	for _, e := range entries {
-		return c.ds.Delete(context.Background(), datastore.NewKey(e.Key))
+		if err := c.ds.Delete(context.Background(), datastore.NewKey(e.Key)); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// this operation is potentially expensive according
-// to the underlying datastore and the size of the
-// datastore.
+// length returns the number of unverified blocks in the cache.
 func (c *mirCache) length() int {
-	q := query.Query{Prefix: BlkCachePrefix}
-	i := 0
-	qr, _ := c.ds.Query(context.Background(), q)
-	for r := range qr.Next() {
-		if r.Error != nil {
-			return -1
-		}
-		i++
-	}
-	return i
+	return c.blks.Len()
 }
 
 func (c *mirCache) getLatestCheckpoint() (*Checkpoint, error) {
-	b, err := c.ds.Get(context.Background(), latestCheckKey)
+	return LatestCheckpoint(context.Background(), c.ds)
+}
+
+// LatestCheckpoint returns the latest checkpoint verified by the daemon that keeps its Mir cache in ds,
+// or nil if there is none.
+func LatestCheckpoint(ctx context.Context, ds datastore.Read) (*Checkpoint, error) {
+	b, err := ds.Get(ctx, latestCheckKey)
 	if err != nil {
 		if err == datastore.ErrNotFound {
 			return nil, nil
@@ -166,7 +220,10 @@ func (c *mirCache) rcvCheckpoint(snap *Checkpoint) error {
 	if err != nil {
 		return err
 	}
+	// All the blocks are checked before any of them is removed from the cache, so a checkpoint
+	// rejected for a block that couldn't be checked yet finds the same blocks when it is received again.
 	i := snap.Height
+	var verified []abi.ChainEpoch
 	for _, k := range snap.BlockCids {
 		i--
 		// bypass genesis
@@ -174,25 +231,31 @@ func (c *mirCache) rcvCheckpoint(snap *Checkpoint) error {
 			continue
 		}
 		log.Debugf("Getting block from mir cache for epoch: %d", i)
-		v, err := c.getBlk(i)
-		if err != nil {
-			return fmt.Errorf("error getting value from datastore: %w", err)
+		v := c.getBlk(i)
+		if v != cid.Undef {
+			if v != k {
+				return fmt.Errorf("block verified in checkpoint not found in cache for epoch %d: %s v.s. %s", i, v, k)
+			}
+			verified = append(verified, i)
+			continue
+		}
+		// The blocks validated before a restart are no longer in the cache, but in the chain.
+		if c.onChain != nil {
+			v = c.onChain(i)
 		}
 		if v == cid.Undef {
-			// this usually happens when restarting a node, if the block is already on-chain
-			// but we receive the following checkpoint that wasn't received yet.
-			log.Warnf("missing unverified block for that height %d in cache. It may have been verified already", i)
-			continue
+			stats.Record(context.Background(), metrics.MirBlkCacheUnchecked.M(1))
+			return fmt.Errorf("block at height %d covered by the checkpoint at height %d was not received: %w",
+				i, snap.Height, consensus.ErrTemporal)
 		}
-		if v == k {
-			// delete from cache if verified by checkpoint
-			if err := c.rmBlk(i); err != nil {
-				return fmt.Errorf("error deleting value from datastore: %w", err)
-			}
-		} else {
-			return fmt.Errorf("block verified in checkpoint not found in cache for epoch %d: %s v.s. %s", i, v, k)
+		if v != k {
+			return fmt.Errorf("block verified in checkpoint not in the chain for epoch %d: %s v.s. %s", i, v, k)
 		}
 	}
+	// delete from cache the blocks verified by the checkpoint
+	for _, e := range verified {
+		c.rmBlk(e)
+	}
 
 	// verify that all block in range have been verified
 	// TODO: Mark as bad those that have not been verified and purge
@@ -200,6 +263,7 @@ func (c *mirCache) rcvCheckpoint(snap *Checkpoint) error {
 	if i != prev.Height {
 		log.Warnf("Checkpoint didn't verify the whole gap of blocks between checkpoints: %d, %d", i, prev.Height)
 	}
+	log.Debugf("checkpoint at height %d verified %d blocks", snap.Height, len(verified))
 
 	// update the latest checkpoint received.
 	if err := c.setLatestCheckpoint(snap); err != nil {
@@ -208,20 +272,27 @@ func (c *mirCache) rcvCheckpoint(snap *Checkpoint) error {
 
 	// mark bad blocks
 	// including it on a routine to take it out of the critical path.
-	go c.markBadBlks(snap.Height)
+	go func() {
+		bad := c.markBadBlks(snap.Height)
+		if len(bad) > 0 && c.onUncovered != nil {
+			c.onUncovered(snap, bad)
+		}
+	}()
 
 	return nil
 }
 
 func (c *mirCache) rcvBlock(b *types.BlockHeader) error {
-	if c, _ := c.getBlk(b.Height); c != cid.Undef {
-		// if someone is trying to push a new rcvBlock
-		if c != b.Cid() {
-			return fmt.Errorf("already seen a block for that height in cache: height=%d", b.Height)
-		}
-		return nil
+	if latest := abi.ChainEpoch(c.latestHeight.Load()); b.Height < latest || b.Height >= latest+c.window {
+		stats.Record(context.Background(), metrics.MirBlkCacheOutOfWindow.M(1))
+		return fmt.Errorf("block height %d out of the %d heights above the latest checkpoint at height %d: %w",
+			b.Height, c.window, latest, consensus.ErrTemporal)
 	}
-	return c.putBlk(b.Height, b.Cid())
+	// if someone is trying to push a new rcvBlock
+	if c.putBlk(b.Height, b.Cid()) != b.Cid() {
+		return fmt.Errorf("already seen a block for that height in cache: height=%d", b.Height)
+	}
+	return nil
 }
 
 // return previous checkpoint for checkpoint at epoch e.
@@ -241,6 +312,8 @@ func (c *mirCache) setLatestCheckpoint(snap *Checkpoint) error {
 	if err := c.ds.Put(context.Background(), latestCheckKey, b); err != nil {
 		return err
 	}
+	c.latestHeight.Store(int64(snap.Height))
+	c.freezeHeight.Store(int64(snap.Ext.FreezeHeight))
 	// garbage collect the previous checkpoint pointed by this one.
 	// Potentially not needed anymore if rcvCheckpoint was called.
 	return c.rmCheck(snap.Parent.Height)
@@ -248,34 +321,26 @@ func (c *mirCache) setLatestCheckpoint(snap *Checkpoint) error {
 
 // if a block with a height below a verify checkpoint hasn't been
 // removed from the cache is because it is bad (or outdated) and it should be marked
-// as such.
-func (c *mirCache) markBadBlks(height abi.ChainEpoch) {
+// as such. The blocks marked are returned and removed from the cache.
+func (c *mirCache) markBadBlks(height abi.ChainEpoch) []cid.Cid {
 	// sequentialize badblks marking
 	c.badBlkLk.Lock()
 	defer c.badBlkLk.Unlock()
 
-	q := query.Query{Prefix: BlkCachePrefix}
-	qr, _ := c.ds.Query(context.Background(), q)
-	for r := range qr.Next() {
-		if r.Error != nil {
-			log.Errorf("error marking bad blocks for height: %d: %w", height, r.Error)
-			return
-		}
-		h, err := heightFromBlkKey(r.Key)
-		if err != nil {
-			log.Errorf("error getting key height: %w", err)
+	var bad []cid.Cid
+	for _, h := range c.blks.Keys() {
+		if h >= height {
 			continue
 		}
-		if h < height {
-			// the cid for the badBlockReason should the cid for the tipset or block
-			// where it is verified.
-			_, vcid, err := cid.CidFromBytes(r.Value)
-			if err != nil {
-				log.Errorf("error getting cid for block from ds:  %w", err)
-				continue
-
-			}
-			c.badBlk.Add(vcid, chain.NewBadBlockReason([]cid.Cid{vcid}, "block not verified by mir checkpoint"))
+		vcid, ok := c.blks.Peek(h)
+		if !ok {
+			continue
 		}
+		// the cid for the badBlockReason should the cid for the tipset or block
+		// where it is verified.
+		c.badBlk.Add(vcid, chain.NewBadBlockReason([]cid.Cid{vcid}, "block not verified by mir checkpoint"))
+		c.blks.Remove(h)
+		bad = append(bad, vcid)
 	}
+	return bad
 }
@@ -1,6 +1,7 @@
 package mir
 
 import (
+	"context"
 	"testing"
 
 	"github.com/ipfs/go-cid"
@@ -8,26 +9,165 @@ import (
 	u "github.com/ipfs/go-ipfs-util"
 	"github.com/stretchr/testify/require"
 
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/builtin"
+
 	"github.com/filecoin-project/lotus/chain"
+	"github.com/filecoin-project/lotus/chain/consensus"
+	"github.com/filecoin-project/lotus/chain/types"
 )
 
 /*  */
 func TestCacheLen(t *testing.T) {
-	mc := newDsBlkCache(datastore.NewMapDatastore(), chain.NewBadBlockCache())
+	mc := newDsBlkCache(datastore.NewMapDatastore(), chain.NewBadBlockCache(), LearnerConfig{})
 	testCacheLen(t, mc)
 	testCacheLen(t, mc)
 }
 
 func testCacheLen(t *testing.T, c *mirCache) {
-	err := c.putBlk(10, cid.NewCidV0(u.Hash([]byte("req1"))))
-	require.NoError(t, err)
-	err = c.putBlk(11, cid.NewCidV0(u.Hash([]byte("req2"))))
-	require.NoError(t, err)
+	c.putBlk(10, cid.NewCidV0(u.Hash([]byte("req1"))))
+	c.putBlk(11, cid.NewCidV0(u.Hash([]byte("req2"))))
 	require.Equal(t, 2, c.length())
-	err = c.rmBlk(11)
-	require.NoError(t, err)
+	c.rmBlk(11)
 	require.Equal(t, 1, c.length())
-	err = c.purge()
+	err := c.purge()
 	require.NoError(t, err)
 	require.Equal(t, 0, c.length())
 }
+
+func TestCacheReportsUncoveredBlocks(t *testing.T) {
+	mc := newDsBlkCache(datastore.NewMapDatastore(), chain.NewBadBlockCache(), LearnerConfig{})
+	uncovered := make(chan []cid.Cid, 1)
+	mc.onUncovered = func(_ *Checkpoint, blks []cid.Cid) {
+		uncovered <- blks
+	}
+
+	stale := cid.NewCidV0(u.Hash([]byte("stale")))
+	c4 := cid.NewCidV0(u.Hash([]byte("blk4")))
+	c5 := cid.NewCidV0(u.Hash([]byte("blk5")))
+	mc.putBlk(2, stale)
+	mc.putBlk(4, c4)
+	mc.putBlk(5, c5)
+
+	snap := &Checkpoint{
+		Height:    6,
+		Parent:    ParentMeta{Height: 4, Cid: cid.NewCidV0(u.Hash([]byte("check4")))},
+		BlockCids: []cid.Cid{c5, c4},
+	}
+	require.NoError(t, mc.rcvCheckpoint(snap))
+
+	// The covered blocks are finalized, the stale one is reported.
+	require.Equal(t, []cid.Cid{stale}, <-uncovered)
+	require.Equal(t, 0, mc.length())
+	_, bad := mc.badBlk.Has(stale)
+	require.True(t, bad)
+}
+
+func TestCacheFreezeHeight(t *testing.T) {
+	ds := datastore.NewMapDatastore()
+	mc := newDsBlkCache(ds, chain.NewBadBlockCache(), LearnerConfig{})
+	require.Equal(t, abi.ChainEpoch(0), mc.frozenAt())
+
+	c4 := cid.NewCidV0(u.Hash([]byte("blk4")))
+	snap := &Checkpoint{
+		Height:    5,
+		Parent:    ParentMeta{Height: 4, Cid: cid.NewCidV0(u.Hash([]byte("check4")))},
+		BlockCids: []cid.Cid{c4},
+	}
+	snap.Ext.FreezeHeight = 8
+	mc.putBlk(4, c4)
+	require.NoError(t, mc.rcvCheckpoint(snap))
+	require.Equal(t, abi.ChainEpoch(8), mc.frozenAt())
+
+	// The freeze height is kept across restarts.
+	require.Equal(t, abi.ChainEpoch(8), newDsBlkCache(ds, chain.NewBadBlockCache(), LearnerConfig{}).frozenAt())
+}
+
+func TestCacheDropsPersistedBlocks(t *testing.T) {
+	ds := datastore.NewMapDatastore()
+	key := datastore.NewKey(BlkCachePrefix + "10")
+	require.NoError(t, ds.Put(context.Background(), key, cid.NewCidV0(u.Hash([]byte("req1"))).Bytes()))
+
+	mc := newDsBlkCache(ds, chain.NewBadBlockCache(), LearnerConfig{})
+	require.Equal(t, 0, mc.length())
+	_, err := ds.Get(context.Background(), key)
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+}
+
+func floodHeader(h abi.ChainEpoch, ts uint64) *types.BlockHeader {
+	empty := cid.NewCidV0(u.Hash([]byte("empty")))
+	return &types.BlockHeader{
+		Miner:                 builtin.SystemActorAddr,
+		Height:                h,
+		Timestamp:             ts,
+		ParentWeight:          big.Zero(),
+		ParentBaseFee:         big.Zero(),
+		ParentStateRoot:       empty,
+		ParentMessageReceipts: empty,
+		Messages:              empty,
+	}
+}
+
+// A byzantine peer flooding the daemon with blocks can't evict the blocks of the chain.
+func TestCacheBlockFlood(t *testing.T) {
+	const window = 16
+	mc := newBlkCache(datastore.NewMapDatastore(), chain.NewBadBlockCache(), window)
+
+	h1 := floodHeader(1, 0)
+	require.NoError(t, mc.rcvBlock(h1))
+	// Forged blocks for the heights ahead of the chain are dropped up front.
+	for h := abi.ChainEpoch(window); h <= 10*window; h++ {
+		require.ErrorIs(t, mc.rcvBlock(floodHeader(h, 666)), consensus.ErrTemporal)
+	}
+	require.Equal(t, 1, mc.length())
+	h2 := floodHeader(2, 0)
+	require.NoError(t, mc.rcvBlock(h2))
+
+	// The blocks competing with the ones in the cache are still rejected.
+	require.Error(t, mc.rcvBlock(floodHeader(2, 666)))
+
+	snap := &Checkpoint{
+		Height:    3,
+		BlockCids: []cid.Cid{h2.Cid(), h1.Cid()},
+	}
+	require.NoError(t, mc.rcvCheckpoint(snap))
+	require.Equal(t, 0, mc.length())
+
+	// The window moves with the latest checkpoint.
+	require.ErrorIs(t, mc.rcvBlock(floodHeader(2, 0)), consensus.ErrTemporal)
+	require.NoError(t, mc.rcvBlock(floodHeader(window, 0)))
+	require.ErrorIs(t, mc.rcvBlock(floodHeader(3+window, 0)), consensus.ErrTemporal)
+}
+
+// A checkpoint covering blocks that are neither received nor in the chain is rejected.
+func TestCacheUncheckedBlocks(t *testing.T) {
+	mc := newDsBlkCache(datastore.NewMapDatastore(), chain.NewBadBlockCache(), LearnerConfig{})
+	onChain := map[abi.ChainEpoch]cid.Cid{}
+	mc.onChain = func(e abi.ChainEpoch) cid.Cid {
+		if c, ok := onChain[e]; ok {
+			return c
+		}
+		return cid.Undef
+	}
+
+	h1, h2 := floodHeader(1, 0), floodHeader(2, 0)
+	require.NoError(t, mc.rcvBlock(h2))
+	snap := &Checkpoint{
+		Height:    3,
+		BlockCids: []cid.Cid{h2.Cid(), h1.Cid()},
+	}
+	require.ErrorIs(t, mc.rcvCheckpoint(snap), consensus.ErrTemporal)
+	require.Equal(t, 1, mc.length())
+
+	// A different block in the chain fails the checkpoint.
+	onChain[1] = floodHeader(1, 666).Cid()
+	err := mc.rcvCheckpoint(snap)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, consensus.ErrTemporal)
+
+	// The blocks validated before a restart are checked against the chain.
+	onChain[1] = h1.Cid()
+	require.NoError(t, mc.rcvCheckpoint(snap))
+	require.Equal(t, 0, mc.length())
+}
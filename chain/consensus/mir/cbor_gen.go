@@ -20,7 +20,7 @@ var _ = cid.Undef
 var _ = math.E
 var _ = sort.Sort
 
-var lengthBufCheckpoint = []byte{133}
+var lengthBufCheckpoint = []byte{134}
 
 func (t *Checkpoint) MarshalCBOR(w io.Writer) error {
 	if t == nil {
@@ -74,6 +74,11 @@ func (t *Checkpoint) MarshalCBOR(w io.Writer) error {
 	if err := t.Votes.MarshalCBOR(cw); err != nil {
 		return err
 	}
+
+	// t.SealedMsgs (mir.SealedMessageRecords) (struct)
+	if err := t.SealedMsgs.MarshalCBOR(cw); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -96,7 +101,7 @@ func (t *Checkpoint) UnmarshalCBOR(r io.Reader) (err error) {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 5 {
+	if extra != 6 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
@@ -184,6 +189,15 @@ func (t *Checkpoint) UnmarshalCBOR(r io.Reader) (err error) {
 			return xerrors.Errorf("unmarshaling t.Votes: %w", err)
 		}
 
+	}
+	// t.SealedMsgs (mir.SealedMessageRecords) (struct)
+
+	{
+
+		if err := t.SealedMsgs.UnmarshalCBOR(cr); err != nil {
+			return xerrors.Errorf("unmarshaling t.SealedMsgs: %w", err)
+		}
+
 	}
 	return nil
 }
@@ -560,3 +574,831 @@ func (t *VoteRecords) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
+
+var lengthBufSealedMessageRecords = []byte{129}
+
+func (t *SealedMessageRecords) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufSealedMessageRecords); err != nil {
+		return err
+	}
+
+	// t.Records ([]mir.SealedMessageRecord) (slice)
+	if len(t.Records) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Records was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Records))); err != nil {
+		return err
+	}
+	for _, v := range t.Records {
+		if err := v.MarshalCBOR(cw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *SealedMessageRecords) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = SealedMessageRecords{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Records ([]mir.SealedMessageRecord) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.Records: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Records = make([]SealedMessageRecord, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v SealedMessageRecord
+		if err := v.UnmarshalCBOR(cr); err != nil {
+			return err
+		}
+
+		t.Records[i] = v
+	}
+
+	return nil
+}
+
+var lengthBufSealedMessageRecord = []byte{130}
+
+func (t *SealedMessageRecord) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufSealedMessageRecord); err != nil {
+		return err
+	}
+
+	// t.Height (abi.ChainEpoch) (int64)
+	if t.Height >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Height)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.Height-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.Msg ([]uint8) (slice)
+	if len(t.Msg) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.Msg was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.Msg))); err != nil {
+		return err
+	}
+
+	if _, err := cw.Write(t.Msg[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *SealedMessageRecord) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = SealedMessageRecord{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 2 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Height (abi.ChainEpoch) (int64)
+	{
+		maj, extra, err := cr.ReadHeader()
+		var extraI int64
+		if err != nil {
+			return err
+		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative overflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
+
+		t.Height = abi.ChainEpoch(extraI)
+	}
+	// t.Msg ([]uint8) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.ByteArrayMaxLen {
+		return fmt.Errorf("t.Msg: byte array too large (%d)", extra)
+	}
+	if maj != cbg.MajByteString {
+		return fmt.Errorf("expected byte array")
+	}
+
+	if extra > 0 {
+		t.Msg = make([]uint8, extra)
+	}
+
+	if _, err := io.ReadFull(cr, t.Msg[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+var lengthBufCheckpointExtension = []byte{131}
+
+func (t *CheckpointExtension) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufCheckpointExtension); err != nil {
+		return err
+	}
+
+	// t.Timestamps ([]ValidatorTimestamp) (slice)
+	if len(t.Timestamps) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Timestamps was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Timestamps))); err != nil {
+		return err
+	}
+	for _, v := range t.Timestamps {
+		if err := v.MarshalCBOR(cw); err != nil {
+			return err
+		}
+	}
+
+	// t.FreezeHeight (abi.ChainEpoch) (int64)
+	if t.FreezeHeight >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.FreezeHeight)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.FreezeHeight-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.ShutdownVotes ([]ShutdownVote) (slice)
+	if len(t.ShutdownVotes) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.ShutdownVotes was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.ShutdownVotes))); err != nil {
+		return err
+	}
+	for _, v := range t.ShutdownVotes {
+		if err := v.MarshalCBOR(cw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *CheckpointExtension) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = CheckpointExtension{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 3 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Timestamps ([]ValidatorTimestamp) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.Timestamps: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Timestamps = make([]ValidatorTimestamp, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v ValidatorTimestamp
+		if err := v.UnmarshalCBOR(cr); err != nil {
+			return err
+		}
+
+		t.Timestamps[i] = v
+	}
+
+	// t.FreezeHeight (abi.ChainEpoch) (int64)
+	{
+		maj, extra, err := cr.ReadHeader()
+		var extraI int64
+		if err != nil {
+			return err
+		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative overflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
+
+		t.FreezeHeight = abi.ChainEpoch(extraI)
+	}
+	// t.ShutdownVotes ([]ShutdownVote) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.ShutdownVotes: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.ShutdownVotes = make([]ShutdownVote, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v ShutdownVote
+		if err := v.UnmarshalCBOR(cr); err != nil {
+			return err
+		}
+
+		t.ShutdownVotes[i] = v
+	}
+
+	return nil
+}
+
+var lengthBufValidatorTimestamp = []byte{130}
+
+func (t *ValidatorTimestamp) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufValidatorTimestamp); err != nil {
+		return err
+	}
+
+	// t.Validator (string) (string)
+	if len(t.Validator) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Validator was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Validator))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Validator)); err != nil {
+		return err
+	}
+
+	// t.Timestamp (uint64) (uint64)
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Timestamp)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *ValidatorTimestamp) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ValidatorTimestamp{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 2 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Validator (string) (string)
+
+	{
+		sval, err := cbg.ReadString(cr)
+		if err != nil {
+			return err
+		}
+
+		t.Validator = string(sval)
+	}
+	// t.Timestamp (uint64) (uint64)
+
+	{
+
+		maj, extra, err = cr.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Timestamp = uint64(extra)
+
+	}
+	return nil
+}
+
+var lengthBufShutdownVote = []byte{130}
+
+func (t *ShutdownVote) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufShutdownVote); err != nil {
+		return err
+	}
+
+	// t.Height (abi.ChainEpoch) (int64)
+	if t.Height >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Height)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.Height-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.Validator (string) (string)
+	if len(t.Validator) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Validator was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Validator))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Validator)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *ShutdownVote) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ShutdownVote{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 2 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Height (abi.ChainEpoch) (int64)
+	{
+		maj, extra, err := cr.ReadHeader()
+		var extraI int64
+		if err != nil {
+			return err
+		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative overflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
+
+		t.Height = abi.ChainEpoch(extraI)
+	}
+	// t.Validator (string) (string)
+
+	{
+		sval, err := cbg.ReadString(cr)
+		if err != nil {
+			return err
+		}
+
+		t.Validator = string(sval)
+	}
+	return nil
+}
+
+var lengthBufValidatorContribution = []byte{131}
+
+func (t *ValidatorContribution) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufValidatorContribution); err != nil {
+		return err
+	}
+
+	// t.Validator (string) (string)
+	if len(t.Validator) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Validator was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Validator))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Validator)); err != nil {
+		return err
+	}
+
+	// t.Transactions (uint64) (uint64)
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Transactions)); err != nil {
+		return err
+	}
+
+	// t.Bytes (uint64) (uint64)
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Bytes)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *ValidatorContribution) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ValidatorContribution{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 3 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Validator (string) (string)
+
+	{
+		sval, err := cbg.ReadString(cr)
+		if err != nil {
+			return err
+		}
+
+		t.Validator = string(sval)
+	}
+	// t.Transactions (uint64) (uint64)
+
+	{
+
+		maj, extra, err = cr.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Transactions = uint64(extra)
+
+	}
+	// t.Bytes (uint64) (uint64)
+
+	{
+
+		maj, extra, err = cr.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Bytes = uint64(extra)
+
+	}
+	return nil
+}
+
+var lengthBufEpochContributions = []byte{132}
+
+func (t *EpochContributions) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufEpochContributions); err != nil {
+		return err
+	}
+
+	// t.Epoch (uint64) (uint64)
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Epoch)); err != nil {
+		return err
+	}
+
+	// t.FirstHeight (abi.ChainEpoch) (int64)
+	if t.FirstHeight >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.FirstHeight)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.FirstHeight-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.LastHeight (abi.ChainEpoch) (int64)
+	if t.LastHeight >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.LastHeight)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.LastHeight-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.Validators ([]mir.ValidatorContribution) (slice)
+	if len(t.Validators) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Validators was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Validators))); err != nil {
+		return err
+	}
+	for _, v := range t.Validators {
+		if err := v.MarshalCBOR(cw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *EpochContributions) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = EpochContributions{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 4 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Epoch (uint64) (uint64)
+
+	{
+
+		maj, extra, err = cr.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Epoch = uint64(extra)
+
+	}
+	// t.FirstHeight (abi.ChainEpoch) (int64)
+	{
+		maj, extra, err := cr.ReadHeader()
+		var extraI int64
+		if err != nil {
+			return err
+		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative overflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
+
+		t.FirstHeight = abi.ChainEpoch(extraI)
+	}
+	// t.LastHeight (abi.ChainEpoch) (int64)
+	{
+		maj, extra, err := cr.ReadHeader()
+		var extraI int64
+		if err != nil {
+			return err
+		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative overflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
+
+		t.LastHeight = abi.ChainEpoch(extraI)
+	}
+	// t.Validators ([]mir.ValidatorContribution) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.Validators: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Validators = make([]ValidatorContribution, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v ValidatorContribution
+		if err := v.UnmarshalCBOR(cr); err != nil {
+			return err
+		}
+
+		t.Validators[i] = v
+	}
+
+	return nil
+}
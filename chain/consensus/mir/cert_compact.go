@@ -0,0 +1,106 @@
+package mir
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	t "github.com/filecoin-project/mir/pkg/types"
+	"github.com/filecoin-project/mir/pkg/util/membutil"
+
+	ltypes "github.com/filecoin-project/lotus/chain/types"
+)
+
+// The certificate of a checkpoint included in the election proof of a block holds the signatures of all
+// the validators that signed it, keyed by their IDs, so block headers grow linearly with the membership.
+// Validators sign with secp256k1 keys, whose signatures can't be aggregated, so the compact certificate
+// keeps only the signatures of a weak quorum of the membership that signed the checkpoint, which is what
+// the verification of the certificate requires, and identifies their signers by a bitmap over the membership.
+
+// compactCertVersion is the first byte of compact certificates. Certificates serialized by Mir are
+// CBOR maps, which never start with this byte.
+const compactCertVersion = 0x01
+
+// CompactCertAsElectionProof includes the compact certificate of a checkpoint in an election proof.
+func CompactCertAsElectionProof(ch *checkpoint.StableCheckpoint) (*ltypes.ElectionProof, error) {
+	ids := sortedMembers(ch.PreviousMembership())
+	cert := ch.Certificate()
+
+	b := []byte{compactCertVersion}
+	b = binary.AppendUvarint(b, uint64(len(ids)))
+	bitmap := make([]byte, (len(ids)+7)/8)
+	var (
+		signers []t.NodeID
+		sigs    []byte
+	)
+	for i, id := range ids {
+		sig, ok := cert[id]
+		if !ok {
+			continue
+		}
+		bitmap[i/8] |= 1 << (i % 8)
+		signers = append(signers, id)
+		sigs = binary.AppendUvarint(sigs, uint64(len(sig)))
+		sigs = append(sigs, sig...)
+		if membutil.HaveWeakQuorum(ch.PreviousMembership(), signers) {
+			break
+		}
+	}
+	if len(cert) > 0 && !membutil.HaveWeakQuorum(ch.PreviousMembership(), signers) {
+		return nil, xerrors.Errorf("checkpoint certificate without a weak quorum of the membership")
+	}
+	b = append(b, bitmap...)
+	return &ltypes.ElectionProof{WinCount: 0, VRFProof: append(b, sigs...)}, nil
+}
+
+// certFromCompact decodes a compact certificate of a checkpoint signed by the membership mb.
+func certFromCompact(b []byte, mb *mirproto.Membership) (*checkpoint.Certificate, error) {
+	ids := sortedMembers(mb)
+	b = b[1:]
+	n, l := binary.Uvarint(b)
+	if l <= 0 {
+		return nil, xerrors.Errorf("invalid membership size in compact certificate")
+	}
+	if n != uint64(len(ids)) {
+		return nil, xerrors.Errorf("compact certificate for a membership of %d validators, expected %d", n, len(ids))
+	}
+	b = b[l:]
+	size := (len(ids) + 7) / 8
+	if len(b) < size {
+		return nil, xerrors.Errorf("compact certificate too short")
+	}
+	bitmap, b := b[:size], b[size:]
+
+	cert := checkpoint.Certificate{}
+	for i, id := range ids {
+		if bitmap[i/8]&(1<<(i%8)) == 0 {
+			continue
+		}
+		sl, l := binary.Uvarint(b)
+		if l <= 0 || uint64(len(b)-l) < sl {
+			return nil, xerrors.Errorf("invalid signature of %s in compact certificate", id)
+		}
+		cert[id] = b[l : l+int(sl)]
+		b = b[l+int(sl):]
+	}
+	if len(b) != 0 {
+		return nil, xerrors.Errorf("%d trailing bytes in compact certificate", len(b))
+	}
+	return &cert, nil
+}
+
+// sortedMembers returns the IDs of the validators of a membership sorted in increasing order.
+func sortedMembers(mb *mirproto.Membership) []t.NodeID {
+	if mb == nil {
+		return nil
+	}
+	ids := make([]t.NodeID, 0, len(mb.Nodes))
+	for id := range mb.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
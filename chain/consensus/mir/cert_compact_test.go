@@ -0,0 +1,105 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	mirtypes "github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/headerext"
+	ltypes "github.com/filecoin-project/lotus/chain/types"
+)
+
+func certCheckpoint(ids []mirtypes.NodeID, cert checkpoint.Certificate) *checkpoint.StableCheckpoint {
+	mb := &mirproto.Membership{Nodes: map[mirtypes.NodeID]*mirproto.NodeIdentity{}}
+	for _, id := range ids {
+		mb.Nodes[id] = &mirproto.NodeIdentity{Id: id, Weight: "1"}
+	}
+	return &checkpoint.StableCheckpoint{
+		Sn: 10,
+		Snapshot: &mirproto.StateSnapshot{
+			EpochData: &mirproto.EpochData{
+				EpochConfig:        &mirproto.EpochConfig{EpochNr: 2, Memberships: []*mirproto.Membership{mb}},
+				ClientProgress:     &mirproto.ClientProgress{},
+				PreviousMembership: mb,
+			},
+		},
+		Cert: cert,
+	}
+}
+
+func TestCompactCert(t *testing.T) {
+	ids := []mirtypes.NodeID{"t1d", "t1b", "t1a", "t1c"}
+	cert := checkpoint.Certificate{}
+	for _, id := range ids {
+		cert[id] = []byte("sig-" + id)
+	}
+	ch := certCheckpoint(ids, cert)
+
+	// Mir certificates are still read.
+	ep, err := CertAsElectionProof(ch)
+	require.NoError(t, err)
+	got, err := CertFromElectionProof(ep, ch.PreviousMembership())
+	require.NoError(t, err)
+	require.Equal(t, cert, *got)
+
+	// The compact certificate only holds a weak quorum of signatures, of the lowest IDs.
+	cep, err := CompactCertAsElectionProof(ch)
+	require.NoError(t, err)
+	require.Less(t, len(cep.VRFProof), len(ep.VRFProof))
+	got, err = CertFromElectionProof(cep, ch.PreviousMembership())
+	require.NoError(t, err)
+	require.Equal(t, checkpoint.Certificate{"t1a": []byte("sig-t1a"), "t1b": []byte("sig-t1b")}, *got)
+
+	// A compact certificate can't be read with another membership.
+	other := certCheckpoint(ids[:3], nil)
+	_, err = CertFromElectionProof(cep, other.PreviousMembership())
+	require.Error(t, err)
+	_, err = CertFromElectionProof(&ltypes.ElectionProof{VRFProof: cep.VRFProof[:len(cep.VRFProof)-1]}, ch.PreviousMembership())
+	require.Error(t, err)
+
+	// A certificate without a weak quorum isn't compacted.
+	_, err = CompactCertAsElectionProof(certCheckpoint(ids, checkpoint.Certificate{"t1a": []byte("sig")}))
+	require.Error(t, err)
+}
+
+func TestTaggedHeaders(t *testing.T) {
+	ids := []mirtypes.NodeID{"t1a", "t1b", "t1c", "t1d"}
+	cert := checkpoint.Certificate{}
+	for _, id := range ids {
+		cert[id] = []byte("sig-" + id)
+	}
+	ch := certCheckpoint(ids, cert)
+
+	for _, compact := range []bool{false, true} {
+		ticket, err := CheckpointAsVRFProof(ch)
+		require.NoError(t, err)
+		var ep *ltypes.ElectionProof
+		if compact {
+			ep, err = CompactCertAsElectionProof(ch)
+		} else {
+			ep, err = CertAsElectionProof(ch)
+		}
+		require.NoError(t, err)
+		legacy, err := CertFromElectionProof(ep, ch.PreviousMembership())
+		require.NoError(t, err)
+
+		TagHeaderExtensions(ticket, ep, compact)
+		require.Equal(t, byte(headerext.Marker), ticket.VRFProof[0])
+		got, err := CheckpointFromVRFProof(ticket)
+		require.NoError(t, err)
+		require.Equal(t, ch.Sn, got.Sn)
+		gotCert, err := CertFromElectionProof(ep, ch.PreviousMembership())
+		require.NoError(t, err)
+		require.Equal(t, legacy, gotCert)
+
+		// The checkpoint and the certificate can't be swapped.
+		_, err = CheckpointFromVRFProof(&ltypes.Ticket{VRFProof: ep.VRFProof})
+		require.Error(t, err)
+		_, err = CertFromElectionProof(&ltypes.ElectionProof{VRFProof: ticket.VRFProof}, ch.PreviousMembership())
+		require.Error(t, err)
+	}
+}
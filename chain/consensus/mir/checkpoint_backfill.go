@@ -0,0 +1,108 @@
+package mir
+
+import (
+	"context"
+	"crypto"
+	"errors"
+
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api/v1api"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// CheckpointIndexBackfillKey marks the datastores whose checkpoint indices were backfilled.
+var CheckpointIndexBackfillKey = datastore.NewKey("mir/checkpoint-index-backfill")
+
+// BackfillCheckpointIndices indexes by height and by CID the checkpoints included in the chain of the node
+// that are missing from the datastore of the validator, so repos created before the indices existed serve
+// checkpoints by height like the rest. The chain is walked down from the head of the node, and the
+// checkpoints are verified against their certificate before being indexed.
+//
+// It only runs once per datastore, and returns the number of checkpoints indexed.
+func BackfillCheckpointIndices(ctx context.Context, ds db.DB, node v1api.FullNode) (int, error) {
+	_, err := ds.Get(ctx, CheckpointIndexBackfillKey)
+	if err == nil {
+		return 0, nil
+	}
+	if !errors.Is(err, datastore.ErrNotFound) {
+		return 0, xerrors.Errorf("error getting checkpoint index backfill marker: %w", err)
+	}
+
+	ts, err := node.ChainHead(ctx)
+	if err != nil {
+		return 0, xerrors.Errorf("error getting chain head: %w", err)
+	}
+	n := 0
+	for ts.Height() > 0 {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		indexed, err := backfillCheckpoint(ctx, ds, ts.Blocks()[0])
+		if err != nil {
+			return n, xerrors.Errorf("error indexing checkpoint of block at height %d: %w", ts.Height(), err)
+		}
+		if indexed {
+			n++
+		}
+		parent, err := node.ChainGetTipSet(ctx, ts.Parents())
+		if err != nil {
+			return n, xerrors.Errorf("error getting parent of tipset at height %d: %w", ts.Height(), err)
+		}
+		ts = parent
+	}
+
+	if err := ds.Put(ctx, CheckpointIndexBackfillKey, []byte{1}); err != nil {
+		return n, xerrors.Errorf("error persisting checkpoint index backfill marker: %w", err)
+	}
+	return n, nil
+}
+
+// backfillCheckpoint indexes the checkpoint included in a block, if any and if it isn't indexed yet.
+func backfillCheckpoint(ctx context.Context, ds db.DB, h *types.BlockHeader) (bool, error) {
+	if !hasCheckpoint(h) {
+		return false, nil
+	}
+	ch, err := CheckpointFromVRFProof(h.Ticket)
+	if err != nil {
+		return false, err
+	}
+	snap, err := UnwrapCheckpointSnapshot(ch)
+	if err != nil {
+		return false, xerrors.Errorf("error unwrapping checkpoint snapshot: %w", err)
+	}
+	_, err = ds.Get(ctx, HeightCheckIndexKey(snap.Height))
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, datastore.ErrNotFound) {
+		return false, err
+	}
+
+	cert, err := CertFromElectionProof(h.ElectionProof, ch.PreviousMembership())
+	if err != nil {
+		return false, err
+	}
+	ch = ch.AttachCert(cert)
+	if err := ch.VerifyCert(crypto.SHA256, CheckpointVerifier{}, ch.PreviousMembership()); err != nil {
+		return false, xerrors.Errorf("error verifying checkpoint certificate: %w", err)
+	}
+
+	b, err := ch.Serialize()
+	if err != nil {
+		return false, xerrors.Errorf("error serializing checkpoint: %w", err)
+	}
+	c, err := snap.Cid()
+	if err != nil {
+		return false, xerrors.Errorf("error computing cid for checkpoint: %w", err)
+	}
+	if err := ds.Put(ctx, HeightCheckIndexKey(snap.Height), b); err != nil {
+		return false, xerrors.Errorf("error persisting checkpoint at height %d: %w", snap.Height, err)
+	}
+	if err := ds.Put(ctx, CidCheckIndexKey(c), ch.Snapshot.AppData); err != nil {
+		return false, xerrors.Errorf("error persisting checkpoint %s: %w", c, err)
+	}
+	return true, nil
+}
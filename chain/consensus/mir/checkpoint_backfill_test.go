@@ -0,0 +1,53 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api/mocks"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestBackfillCheckpointIndices(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	node := mocks.NewMockFullNode(ctrl)
+	ds := datastore.NewMapDatastore()
+
+	var chain []*types.TipSet
+	var ts *types.TipSet
+	for i := 0; i <= 3; i++ {
+		b := mock.MkBlock(ts, 1, uint64(i))
+		b.Ticket.VRFProof, b.ElectionProof.VRFProof = nil, nil
+		ts = mock.TipSet(b)
+		chain = append(chain, ts)
+	}
+
+	node.EXPECT().ChainHead(gomock.Any()).Return(chain[3], nil)
+	for i := 3; i > 0; i-- {
+		node.EXPECT().ChainGetTipSet(gomock.Any(), chain[i].Parents()).Return(chain[i-1], nil)
+	}
+	n, err := BackfillCheckpointIndices(ctx, ds, node)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	// The backfill only runs once.
+	n, err = BackfillCheckpointIndices(ctx, ds, node)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	// Blocks with garbage in place of checkpoints stop the backfill, which runs again on the next start.
+	ds = datastore.NewMapDatastore()
+	head := mock.TipSet(mock.MkBlock(chain[3], 1, 4))
+	node.EXPECT().ChainHead(gomock.Any()).Return(head, nil)
+	_, err = BackfillCheckpointIndices(ctx, ds, node)
+	require.Error(t, err)
+	_, err = ds.Get(ctx, CheckpointIndexBackfillKey)
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+}
@@ -0,0 +1,215 @@
+package mir
+
+import (
+	"bufio"
+	"context"
+	"crypto"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+)
+
+// CheckpointExchangeProtocol is the libp2p protocol validators use to request checkpoints from each other,
+// e.g. to recover the checkpoint files of a validator that were lost.
+//
+// The request is the height of the checkpoint as an 8-byte big-endian integer, zero meaning the latest
+// checkpoint. The response is a status byte followed, if the checkpoint was found, by the length of the
+// serialized checkpoint as a uvarint and the serialized checkpoint. Responses don't need to be signed by
+// the peer: the certificate of the checkpoint is verified by the requester.
+const CheckpointExchangeProtocol = protocol.ID("/eudico/mir/checkpoint/1.0.0")
+
+const (
+	checkpointFound byte = iota
+	checkpointNotFound
+	checkpointError
+)
+
+var (
+	// CheckpointExchangeTimeout is the time a peer has to respond to a checkpoint request.
+	CheckpointExchangeTimeout = 30 * time.Second
+	// MaxExchangedCheckpointSize is the maximum size of a checkpoint accepted from a peer.
+	MaxExchangedCheckpointSize uint64 = 16 << 20
+
+	ErrCheckpointNotFound = errors.New("checkpoint not found")
+)
+
+// CheckpointFileName returns the name of the file the checkpoint at height h is persisted to.
+func CheckpointFileName(h abi.ChainEpoch) string {
+	return "checkpoint-" + h.String() + ".chkp"
+}
+
+// LatestCheckpointFile returns the path and height of the highest checkpoint persisted in checkpointRepo.
+// It returns an empty path if the repo has no checkpoint.
+func LatestCheckpointFile(checkpointRepo string) (string, abi.ChainEpoch, error) {
+	entries, err := os.ReadDir(checkpointRepo)
+	if os.IsNotExist(err) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, xerrors.Errorf("error reading checkpoint repo %s: %w", checkpointRepo, err)
+	}
+
+	var (
+		latest string
+		height abi.ChainEpoch
+	)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, "checkpoint-") || !strings.HasSuffix(name, ".chkp") {
+			continue
+		}
+		h, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, "checkpoint-"), ".chkp"), 10, 64)
+		if err != nil {
+			continue
+		}
+		if latest == "" || abi.ChainEpoch(h) > height {
+			latest, height = path.Join(checkpointRepo, name), abi.ChainEpoch(h)
+		}
+	}
+	return latest, height, nil
+}
+
+// ServeCheckpoints serves the checkpoints persisted in the datastore of the validator and in its checkpoint
+// repo, if any, to the peers of h.
+func ServeCheckpoints(h host.Host, ds db.DB, checkpointRepo string) {
+	h.SetStreamHandler(CheckpointExchangeProtocol, func(s network.Stream) {
+		defer s.Close() // nolint
+
+		_ = s.SetDeadline(time.Now().Add(CheckpointExchangeTimeout))
+		var req [8]byte
+		if _, err := io.ReadFull(s, req[:]); err != nil {
+			log.Debugf("failed to read checkpoint request from %s: %v", s.Conn().RemotePeer(), err)
+			_ = s.Reset()
+			return
+		}
+		height := abi.ChainEpoch(binary.BigEndian.Uint64(req[:]))
+
+		b, err := persistedCheckpoint(context.Background(), ds, checkpointRepo, height)
+		switch {
+		case errors.Is(err, ErrCheckpointNotFound):
+			_, _ = s.Write([]byte{checkpointNotFound})
+			return
+		case err != nil:
+			log.Warnf("failed to get checkpoint at height %d requested by %s: %v", height, s.Conn().RemotePeer(), err)
+			_, _ = s.Write([]byte{checkpointError})
+			return
+		}
+
+		w := bufio.NewWriter(s)
+		lb := make([]byte, binary.MaxVarintLen64)
+		_ = w.WriteByte(checkpointFound)
+		_, _ = w.Write(lb[:binary.PutUvarint(lb, uint64(len(b)))])
+		_, _ = w.Write(b)
+		if err := w.Flush(); err != nil {
+			log.Debugf("failed to send checkpoint at height %d to %s: %v", height, s.Conn().RemotePeer(), err)
+		}
+	})
+}
+
+// persistedCheckpoint returns the serialized checkpoint at height h, or the latest one if h is zero.
+func persistedCheckpoint(ctx context.Context, ds db.DB, checkpointRepo string, h abi.ChainEpoch) ([]byte, error) {
+	key := LatestCheckpointPbKey
+	if h > 0 {
+		key = HeightCheckIndexKey(h)
+	}
+	b, err := ds.Get(ctx, key)
+	if err == nil {
+		return b, nil
+	}
+	if !errors.Is(err, datastore.ErrNotFound) {
+		return nil, err
+	}
+	if h <= 0 || checkpointRepo == "" {
+		return nil, ErrCheckpointNotFound
+	}
+
+	b, err = ReadCheckpointFile(path.Join(checkpointRepo, CheckpointFileName(h)))
+	if os.IsNotExist(err) {
+		return nil, ErrCheckpointNotFound
+	}
+	return b, err
+}
+
+// FetchCheckpoint requests the checkpoint at height h, or the latest one if h is zero, from peer p.
+// The checkpoint is only returned if its certificate is valid.
+func FetchCheckpoint(ctx context.Context, h host.Host, p peer.AddrInfo, height abi.ChainEpoch) (*checkpoint.StableCheckpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, CheckpointExchangeTimeout)
+	defer cancel()
+
+	if err := h.Connect(ctx, p); err != nil {
+		return nil, xerrors.Errorf("error connecting to %s: %w", p.ID, err)
+	}
+	s, err := h.NewStream(ctx, p.ID, CheckpointExchangeProtocol)
+	if err != nil {
+		return nil, xerrors.Errorf("error opening stream to %s: %w", p.ID, err)
+	}
+	defer s.Close() // nolint
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.SetDeadline(deadline)
+	}
+
+	var req [8]byte
+	binary.BigEndian.PutUint64(req[:], uint64(height))
+	if _, err := s.Write(req[:]); err != nil {
+		return nil, xerrors.Errorf("error sending checkpoint request to %s: %w", p.ID, err)
+	}
+	_ = s.CloseWrite()
+
+	r := bufio.NewReader(s)
+	status, err := r.ReadByte()
+	if err != nil {
+		return nil, xerrors.Errorf("error reading response from %s: %w", p.ID, err)
+	}
+	switch status {
+	case checkpointFound:
+	case checkpointNotFound:
+		return nil, xerrors.Errorf("peer %s: %w", p.ID, ErrCheckpointNotFound)
+	default:
+		return nil, xerrors.Errorf("peer %s failed to get the checkpoint", p.ID)
+	}
+
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, xerrors.Errorf("error reading response from %s: %w", p.ID, err)
+	}
+	if n > MaxExchangedCheckpointSize {
+		return nil, xerrors.Errorf("checkpoint from %s too large: %d bytes", p.ID, n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, xerrors.Errorf("error reading checkpoint from %s: %w", p.ID, err)
+	}
+
+	ch, err := DeserializeCheckpoint(b)
+	if err != nil {
+		return nil, xerrors.Errorf("error deserializing checkpoint from %s: %w", p.ID, err)
+	}
+	if err := ch.VerifyCert(crypto.SHA256, CheckpointVerifier{}, ch.PreviousMembership()); err != nil {
+		return nil, xerrors.Errorf("invalid certificate of checkpoint from %s: %w", p.ID, err)
+	}
+	snap, err := UnwrapCheckpointSnapshot(ch)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting snapshot of checkpoint from %s: %w", p.ID, err)
+	}
+	if height > 0 && snap.Height != height {
+		return nil, xerrors.Errorf("peer %s sent checkpoint at height %d instead of %d", p.ID, snap.Height, height)
+	}
+	return ch, nil
+}
@@ -0,0 +1,78 @@
+package mir
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestPersistedCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+	repo := t.TempDir()
+
+	_, err := persistedCheckpoint(ctx, ds, repo, 0)
+	require.ErrorIs(t, err, ErrCheckpointNotFound)
+	_, err = persistedCheckpoint(ctx, ds, repo, 10)
+	require.ErrorIs(t, err, ErrCheckpointNotFound)
+
+	require.NoError(t, os.WriteFile(path.Join(repo, CheckpointFileName(10)), []byte{1}, 0600))
+	b, err := persistedCheckpoint(ctx, ds, repo, 10)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, b)
+
+	// The datastore takes precedence over the checkpoint repo.
+	require.NoError(t, ds.Put(ctx, HeightCheckIndexKey(10), []byte{2}))
+	b, err = persistedCheckpoint(ctx, ds, repo, 10)
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, b)
+}
+
+func TestLatestCheckpointFile(t *testing.T) {
+	repo := t.TempDir()
+
+	f, _, err := LatestCheckpointFile(repo)
+	require.NoError(t, err)
+	require.Empty(t, f)
+	f, _, err = LatestCheckpointFile(path.Join(repo, "missing"))
+	require.NoError(t, err)
+	require.Empty(t, f)
+
+	for _, h := range []abi.ChainEpoch{9, 100, 20} {
+		require.NoError(t, os.WriteFile(path.Join(repo, CheckpointFileName(h)), []byte{1}, 0600))
+	}
+	require.NoError(t, os.WriteFile(path.Join(repo, "checkpoint-latest.chkp"), []byte{1}, 0600))
+
+	f, h, err := LatestCheckpointFile(repo)
+	require.NoError(t, err)
+	require.Equal(t, path.Join(repo, CheckpointFileName(100)), f)
+	require.Equal(t, abi.ChainEpoch(100), h)
+}
+
+func TestFetchCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	mn, err := mocknet.FullMeshLinked(2)
+	require.NoError(t, err)
+	server, client := mn.Hosts()[0], mn.Hosts()[1]
+
+	ds := datastore.NewMapDatastore()
+	ServeCheckpoints(server, ds, "")
+	info := peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()}
+
+	_, err = FetchCheckpoint(ctx, client, info, 10)
+	require.ErrorIs(t, err, ErrCheckpointNotFound)
+
+	// Checkpoints that can't be verified are rejected.
+	require.NoError(t, ds.Put(ctx, HeightCheckIndexKey(10), []byte{1, 2, 3}))
+	_, err = FetchCheckpoint(ctx, client, info, 10)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrCheckpointNotFound)
+}
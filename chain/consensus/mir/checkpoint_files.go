@@ -0,0 +1,207 @@
+package mir
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// CompressedCheckpointSuffix is appended to the name of the checkpoint files compressed by the retention.
+const CompressedCheckpointSuffix = ".gz"
+
+// CheckpointFileRetention determines the checkpoint files kept in the checkpoint repo of the validator.
+// The zero value keeps all the files uncompressed. The latest checkpoint file is never removed nor compressed.
+type CheckpointFileRetention struct {
+	// Maximum number of checkpoint files.
+	MaxFiles int
+	// Maximum size in bytes of the checkpoint files.
+	MaxBytes int64
+	// Compress the checkpoint files with gzip, except the latest one.
+	Compress bool
+}
+
+func (r CheckpointFileRetention) enabled() bool {
+	return r.MaxFiles > 0 || r.MaxBytes > 0 || r.Compress
+}
+
+type checkpointFile struct {
+	name   string
+	height abi.ChainEpoch
+	size   int64
+}
+
+// checkpointFiles returns the checkpoint files in the repo, compressed or not, from the highest one.
+func checkpointFiles(checkpointRepo string) ([]checkpointFile, error) {
+	entries, err := os.ReadDir(checkpointRepo)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("error reading checkpoint repo %s: %w", checkpointRepo, err)
+	}
+
+	var files []checkpointFile
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), CompressedCheckpointSuffix)
+		if e.IsDir() || !strings.HasPrefix(name, "checkpoint-") || !strings.HasSuffix(name, ".chkp") {
+			continue
+		}
+		h, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, "checkpoint-"), ".chkp"), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, checkpointFile{name: e.Name(), height: abi.ChainEpoch(h), size: info.Size()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].height > files[j].height })
+	return files, nil
+}
+
+// ReadCheckpointFile reads a serialized checkpoint from a file. If the file is compressed, or was compressed
+// by the retention of the checkpoint repo after its path was taken, it is decompressed.
+func ReadCheckpointFile(p string) ([]byte, error) {
+	if !strings.HasSuffix(p, CompressedCheckpointSuffix) {
+		b, err := os.ReadFile(p)
+		if !os.IsNotExist(err) {
+			return b, err
+		}
+		if _, serr := os.Stat(p + CompressedCheckpointSuffix); serr != nil {
+			return nil, err
+		}
+		p += CompressedCheckpointSuffix
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, xerrors.Errorf("error decompressing checkpoint file %s: %w", p, err)
+	}
+	return io.ReadAll(zr)
+}
+
+// CleanCheckpointFiles compresses and removes the checkpoint files of the repo according to the retention,
+// and returns the number of files removed.
+func CleanCheckpointFiles(checkpointRepo string, r CheckpointFileRetention) (int, error) {
+	if !r.enabled() {
+		return 0, nil
+	}
+	files, err := checkpointFiles(checkpointRepo)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		removed int
+		total   int64
+	)
+	for i, f := range files {
+		p := path.Join(checkpointRepo, f.name)
+		if i > 0 && (r.MaxFiles > 0 && i >= r.MaxFiles || r.MaxBytes > 0 && total+f.size > r.MaxBytes) {
+			if err := os.Remove(p); err != nil {
+				return removed, xerrors.Errorf("error removing checkpoint file %s: %w", p, err)
+			}
+			removed++
+			continue
+		}
+		if i > 0 && r.Compress && !strings.HasSuffix(f.name, CompressedCheckpointSuffix) {
+			size, err := compressCheckpointFile(p)
+			if err != nil {
+				return removed, err
+			}
+			f.size = size
+		}
+		total += f.size
+	}
+	return removed, nil
+}
+
+// compressCheckpointFile replaces a checkpoint file with its compressed version, and returns its size.
+func compressCheckpointFile(p string) (int64, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return 0, err
+	}
+	tmp := p + CompressedCheckpointSuffix + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, xerrors.Errorf("error creating compressed checkpoint file: %w", err)
+	}
+	zw := gzip.NewWriter(f)
+	if _, err := zw.Write(b); err != nil {
+		_ = f.Close()
+		return 0, xerrors.Errorf("error compressing checkpoint file %s: %w", p, err)
+	}
+	if err := zw.Close(); err != nil {
+		_ = f.Close()
+		return 0, xerrors.Errorf("error compressing checkpoint file %s: %w", p, err)
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, p+CompressedCheckpointSuffix); err != nil {
+		return 0, err
+	}
+	if err := os.Remove(p); err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(p + CompressedCheckpointSuffix)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// syncCheckpointFile persists a serialized checkpoint in a file and syncs it to disk before returning.
+// The checkpoint is written to a temporary file that is renamed once synced, so a crash never leaves
+// a truncated checkpoint file behind.
+func syncCheckpointFile(b []byte, p string) error {
+	dir := path.Dir(p)
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return xerrors.Errorf("error creating directory for checkpoint persistence: %w", err)
+	}
+	tmp := p + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return xerrors.Errorf("error creating checkpoint file: %w", err)
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("error writing checkpoint file %s: %w", tmp, err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("error syncing checkpoint file %s: %w", tmp, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		return err
+	}
+
+	// The rename is only durable once the directory is synced.
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close() // nolint
+	if err := d.Sync(); err != nil {
+		return xerrors.Errorf("error syncing checkpoint repo %s: %w", dir, err)
+	}
+	return nil
+}
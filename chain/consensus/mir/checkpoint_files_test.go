@@ -0,0 +1,64 @@
+package mir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestCleanCheckpointFiles(t *testing.T) {
+	dir := t.TempDir()
+	for h := 1; h <= 5; h++ {
+		p := filepath.Join(dir, CheckpointFileName(abi.ChainEpoch(h)))
+		require.NoError(t, os.WriteFile(p, make([]byte, 100), 0644))
+	}
+
+	// The zero value keeps everything.
+	n, err := CleanCheckpointFiles(dir, CheckpointFileRetention{})
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	n, err = CleanCheckpointFiles(dir, CheckpointFileRetention{MaxFiles: 4, Compress: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	files, err := checkpointFiles(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+	require.Equal(t, CheckpointFileName(5), files[0].name)
+	require.Equal(t, CheckpointFileName(4)+CompressedCheckpointSuffix, files[1].name)
+
+	// Compressed files are read transparently, even through their uncompressed path.
+	b, err := ReadCheckpointFile(filepath.Join(dir, CheckpointFileName(4)))
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, 100), b)
+
+	// The latest file is kept even if it exceeds the size limit.
+	n, err = CleanCheckpointFiles(dir, CheckpointFileRetention{MaxBytes: 10})
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	path, h, err := LatestCheckpointFile(dir)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(5), h)
+	require.Equal(t, filepath.Join(dir, CheckpointFileName(5)), path)
+}
+
+func TestSyncCheckpointFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "checkpoints")
+	p := filepath.Join(dir, CheckpointFileName(3))
+
+	require.NoError(t, syncCheckpointFile([]byte("checkpoint"), p))
+	b, err := ReadCheckpointFile(p)
+	require.NoError(t, err)
+	require.Equal(t, []byte("checkpoint"), b)
+	_, err = os.Stat(p + ".tmp")
+	require.True(t, os.IsNotExist(err))
+
+	// Failing to persist the checkpoint is reported.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), nil, 0644))
+	require.Error(t, syncCheckpointFile([]byte("checkpoint"), filepath.Join(dir, "file", CheckpointFileName(4))))
+}
@@ -0,0 +1,111 @@
+package mir
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+)
+
+// CheckpointGCInterval is the interval between the removals of the checkpoints beyond the retention.
+var CheckpointGCInterval = 10 * time.Minute
+
+// CheckpointRetention determines which of the checkpoints indexed by height and by CID in the datastore
+// of the validator are kept. A checkpoint is removed once it is beyond all the limits that are set;
+// the zero value keeps all the checkpoints. The latest checkpoint is always kept.
+type CheckpointRetention struct {
+	// Number of latest checkpoints kept.
+	Checkpoints int
+	// Number of blocks below the latest checkpoint for which checkpoints are kept.
+	Epochs abi.ChainEpoch
+}
+
+func (r CheckpointRetention) enabled() bool {
+	return r.Checkpoints > 0 || r.Epochs > 0
+}
+
+// keep returns whether the i-th checkpoint from the latest one, at height h, is kept.
+func (r CheckpointRetention) keep(i int, latest, h abi.ChainEpoch) bool {
+	if !r.enabled() {
+		return true
+	}
+	return r.Checkpoints > 0 && i < r.Checkpoints || r.Epochs > 0 && latest-h <= r.Epochs
+}
+
+// GCCheckpoints removes from the datastore the checkpoints beyond the retention, and returns the number
+// of checkpoints removed. The checkpoints are walked back from the latest one until the first missing
+// checkpoint, so a run only goes through the checkpoints delivered since the previous one.
+func GCCheckpoints(ctx context.Context, ds db.DB, r CheckpointRetention) (int, error) {
+	if !r.enabled() {
+		return 0, nil
+	}
+	// Nothing to remove before the first checkpoint is delivered.
+	if _, err := ds.Get(ctx, LatestCheckpointPbKey); errors.Is(err, datastore.ErrNotFound) {
+		return 0, nil
+	}
+
+	var (
+		i, n   int
+		latest abi.ChainEpoch
+	)
+	err := walkCheckpoints(ctx, ds, func(_ *checkpoint.StableCheckpoint, snap *Checkpoint) (bool, error) {
+		if i == 0 {
+			latest = snap.Height
+		}
+		i++
+		if r.keep(i-1, latest, snap.Height) {
+			return true, nil
+		}
+
+		c, err := snap.Cid()
+		if err != nil {
+			return false, xerrors.Errorf("error computing cid for checkpoint at height %d: %w", snap.Height, err)
+		}
+		if err := ds.Delete(ctx, CidCheckIndexKey(c)); err != nil {
+			return false, xerrors.Errorf("error deleting checkpoint %s: %w", c, err)
+		}
+		if err := ds.Delete(ctx, HeightCheckIndexKey(snap.Height)); err != nil {
+			return false, xerrors.Errorf("error deleting checkpoint at height %d: %w", snap.Height, err)
+		}
+		n++
+		return ctx.Err() == nil, nil
+	})
+	return n, err
+}
+
+// gcCheckpoints periodically removes the checkpoints beyond the retention from the datastore and
+// the checkpoint repo until the context is done.
+func (m *Manager) gcCheckpoints(ctx context.Context) {
+	ticker := time.NewTicker(CheckpointGCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := GCCheckpoints(ctx, m.ds, m.checkpointRetention)
+			if err != nil {
+				log.With("validator", m.id).Warnf("failed to remove checkpoints beyond the retention: %v", err)
+			} else if n > 0 {
+				log.With("validator", m.id).Infof("removed %d checkpoints beyond the retention", n)
+			}
+
+			if m.checkpointRepo == "" {
+				continue
+			}
+			n, err = CleanCheckpointFiles(m.checkpointRepo, m.checkpointFileRetention)
+			if err != nil {
+				log.With("validator", m.id).Warnf("failed to clean checkpoint files: %v", err)
+			} else if n > 0 {
+				log.With("validator", m.id).Infof("removed %d checkpoint files beyond the retention", n)
+			}
+		}
+	}
+}
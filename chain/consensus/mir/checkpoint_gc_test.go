@@ -0,0 +1,40 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointRetention(t *testing.T) {
+	// The zero value keeps all the checkpoints.
+	require.True(t, CheckpointRetention{}.keep(100, 1000, 0))
+
+	r := CheckpointRetention{Checkpoints: 3}
+	require.True(t, r.keep(0, 100, 100))
+	require.True(t, r.keep(2, 100, 80))
+	require.False(t, r.keep(3, 100, 70))
+
+	r = CheckpointRetention{Epochs: 20}
+	require.True(t, r.keep(5, 100, 80))
+	require.False(t, r.keep(1, 100, 79))
+
+	// A checkpoint is removed once it is beyond both limits.
+	r = CheckpointRetention{Checkpoints: 3, Epochs: 20}
+	require.True(t, r.keep(5, 100, 85))
+	require.True(t, r.keep(1, 100, 50))
+	require.False(t, r.keep(3, 100, 70))
+}
+
+func TestGCCheckpointsWithoutCheckpoints(t *testing.T) {
+	ds := datastore.NewMapDatastore()
+	n, err := GCCheckpoints(context.Background(), ds, CheckpointRetention{})
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	n, err = GCCheckpoints(context.Background(), ds, CheckpointRetention{Checkpoints: 1})
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+}
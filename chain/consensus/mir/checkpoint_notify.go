@@ -0,0 +1,96 @@
+package mir
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+)
+
+// checkpointNotifyBuffer is the number of checkpoints buffered for a subscriber before it is dropped.
+const checkpointNotifyBuffer = 16
+
+// CheckpointNotification is a stable checkpoint delivered to the validator.
+type CheckpointNotification struct {
+	Epoch uint64
+	// Height of the checkpoint: the checkpoint commits the blocks below it.
+	Height abi.ChainEpoch
+	Cid    cid.Cid
+	// Checkpoint is the checkpoint without its certificate, as serialized by Mir.
+	Checkpoint []byte
+	// Cert is the certificate of the checkpoint, as serialized by Mir.
+	Cert []byte
+}
+
+func newCheckpointNotification(ch *checkpoint.StableCheckpoint, snap *Checkpoint, c cid.Cid) (*CheckpointNotification, error) {
+	b, err := ch.StripCert().Serialize()
+	if err != nil {
+		return nil, xerrors.Errorf("error serializing checkpoint: %w", err)
+	}
+	cert := ch.Certificate()
+	cb, err := cert.Serialize()
+	if err != nil {
+		return nil, xerrors.Errorf("error serializing checkpoint certificate: %w", err)
+	}
+	return &CheckpointNotification{
+		Epoch:      uint64(ch.Snapshot.EpochData.EpochConfig.EpochNr),
+		Height:     snap.Height,
+		Cid:        c,
+		Checkpoint: b,
+		Cert:       cb,
+	}, nil
+}
+
+// CheckpointNotifier streams the checkpoints delivered to the validator to its subscribers.
+// It outlives the Mir manager, so subscriptions survive the restarts of the manager.
+type CheckpointNotifier struct {
+	lk   sync.Mutex
+	subs map[chan *CheckpointNotification]struct{}
+}
+
+func NewCheckpointNotifier() *CheckpointNotifier {
+	return &CheckpointNotifier{subs: make(map[chan *CheckpointNotification]struct{})}
+}
+
+// Subscribe returns a channel receiving the checkpoints delivered from now on, until ctx is done.
+// The channel is closed if the subscriber falls behind, so it never misses a checkpoint silently.
+func (n *CheckpointNotifier) Subscribe(ctx context.Context) <-chan *CheckpointNotification {
+	ch := make(chan *CheckpointNotification, checkpointNotifyBuffer)
+	n.lk.Lock()
+	n.subs[ch] = struct{}{}
+	n.lk.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		n.unsubscribe(ch)
+	}()
+	return ch
+}
+
+func (n *CheckpointNotifier) unsubscribe(ch chan *CheckpointNotification) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	if _, ok := n.subs[ch]; ok {
+		delete(n.subs, ch)
+		close(ch)
+	}
+}
+
+// Notify sends the checkpoint to the subscribers.
+func (n *CheckpointNotifier) Notify(c *CheckpointNotification) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	for ch := range n.subs {
+		select {
+		case ch <- c:
+		default:
+			log.Warnf("checkpoint subscriber fell behind at height %d, dropping it", c.Height)
+			delete(n.subs, ch)
+			close(ch)
+		}
+	}
+}
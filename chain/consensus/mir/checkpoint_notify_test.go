@@ -0,0 +1,43 @@
+package mir
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestCheckpointNotifier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := NewCheckpointNotifier()
+
+	subCtx, unsubscribe := context.WithCancel(ctx)
+	follower := n.Subscribe(ctx)
+	leaving := n.Subscribe(subCtx)
+	slow := n.Subscribe(ctx)
+
+	n.Notify(&CheckpointNotification{Height: 10})
+	require.Equal(t, abi.ChainEpoch(10), (<-follower).Height)
+	require.Equal(t, abi.ChainEpoch(10), (<-leaving).Height)
+
+	unsubscribe()
+	require.Eventually(t, func() bool {
+		_, ok := <-leaving
+		return !ok
+	}, time.Second, time.Millisecond)
+
+	// A subscriber that falls behind is dropped instead of blocking the validator.
+	for i := 1; i <= checkpointNotifyBuffer; i++ {
+		n.Notify(&CheckpointNotification{Height: abi.ChainEpoch(10 + 10*i)})
+		require.Equal(t, abi.ChainEpoch(10+10*i), (<-follower).Height)
+	}
+	received := 0
+	for range slow {
+		received++
+	}
+	require.Equal(t, checkpointNotifyBuffer, received)
+}
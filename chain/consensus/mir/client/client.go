@@ -0,0 +1,269 @@
+// Package client implements helpers for applications that send messages to a Mir subnet and
+// follow them to finality, e.g. dapp backends.
+//
+// The package only depends on the JSON-RPC API of the nodes of the subnet, so it can be imported
+// without the dependencies of the node. A message is final once it is committed by a Mir checkpoint:
+// the checkpoint certificate signed by a quorum of validators is the proof of its inclusion.
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/compression"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/headerext"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// DefaultGatewayAddr is the address of the gateway actor of the subnets.
+var DefaultGatewayAddr, _ = address.NewIDAddress(64)
+
+// PollInterval is the period between the queries of the client while waiting for a message.
+var PollInterval = time.Second
+
+// FullNode is the subset of the API of a full node used by the client.
+type FullNode interface {
+	ChainHead(ctx context.Context) (*types.TipSet, error)
+	ChainGetTipSetByHeight(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error)
+	MpoolPush(ctx context.Context, msg *types.SignedMessage) (cid.Cid, error)
+	StateSearchMsg(ctx context.Context, from types.TipSetKey, msg cid.Cid, limit abi.ChainEpoch, allowReplaced bool) (*MsgLookup, error)
+	IPCGetGatewayMembership(ctx context.Context, gw address.Address, tsk types.TipSetKey) (*validator.Set, error)
+}
+
+// MsgLookup is the result of a message search, as returned by the full node.
+type MsgLookup struct {
+	Message cid.Cid
+	Receipt types.MessageReceipt
+	TipSet  types.TipSetKey
+	Height  abi.ChainEpoch
+}
+
+type fullNodeStruct struct {
+	Internal struct {
+		ChainHead               func(context.Context) (*types.TipSet, error)
+		ChainGetTipSetByHeight  func(context.Context, abi.ChainEpoch, types.TipSetKey) (*types.TipSet, error)
+		MpoolPush               func(context.Context, *types.SignedMessage) (cid.Cid, error)
+		StateSearchMsg          func(context.Context, types.TipSetKey, cid.Cid, abi.ChainEpoch, bool) (*MsgLookup, error)
+		IPCGetGatewayMembership func(context.Context, address.Address, types.TipSetKey) (*validator.Set, error)
+	}
+}
+
+func (s *fullNodeStruct) ChainHead(ctx context.Context) (*types.TipSet, error) {
+	return s.Internal.ChainHead(ctx)
+}
+
+func (s *fullNodeStruct) ChainGetTipSetByHeight(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	return s.Internal.ChainGetTipSetByHeight(ctx, h, tsk)
+}
+
+func (s *fullNodeStruct) MpoolPush(ctx context.Context, msg *types.SignedMessage) (cid.Cid, error) {
+	return s.Internal.MpoolPush(ctx, msg)
+}
+
+func (s *fullNodeStruct) StateSearchMsg(ctx context.Context, from types.TipSetKey, msg cid.Cid, limit abi.ChainEpoch, allowReplaced bool) (*MsgLookup, error) {
+	return s.Internal.StateSearchMsg(ctx, from, msg, limit, allowReplaced)
+}
+
+func (s *fullNodeStruct) IPCGetGatewayMembership(ctx context.Context, gw address.Address, tsk types.TipSetKey) (*validator.Set, error) {
+	return s.Internal.IPCGetGatewayMembership(ctx, gw, tsk)
+}
+
+// Client sends messages to a Mir subnet through one of its full nodes.
+type Client struct {
+	node    FullNode
+	gateway address.Address
+}
+
+// New returns a client using node, e.g. the API of a full node running in the same process.
+func New(node FullNode) *Client {
+	return &Client{
+		node:    node,
+		gateway: DefaultGatewayAddr,
+	}
+}
+
+// Dial returns a client connected to the JSON-RPC API of the full node at addr.
+func Dial(ctx context.Context, addr string, requestHeader http.Header) (*Client, jsonrpc.ClientCloser, error) {
+	var node fullNodeStruct
+	closer, err := jsonrpc.NewMergeClient(ctx, addr, "Filecoin", []interface{}{&node.Internal}, requestHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return New(&node), closer, nil
+}
+
+// Push pushes a signed message to the mempool of the node.
+func (c *Client) Push(ctx context.Context, msg *types.SignedMessage) (cid.Cid, error) {
+	return c.node.MpoolPush(ctx, msg)
+}
+
+// FinalizedMsg is a message committed by a Mir checkpoint.
+type FinalizedMsg struct {
+	Lookup *MsgLookup
+	// Proof is the checkpoint committing the block that includes the message.
+	Proof *CheckpointProof
+}
+
+// WaitFinal waits until the message is committed by a checkpoint.
+func (c *Client) WaitFinal(ctx context.Context, msg cid.Cid) (*FinalizedMsg, error) {
+	var lookup *MsgLookup
+	for {
+		var err error
+		lookup, err = c.node.StateSearchMsg(ctx, types.EmptyTSK, msg, -1, true)
+		if err != nil {
+			return nil, fmt.Errorf("error searching message %s: %w", msg, err)
+		}
+		if lookup != nil {
+			break
+		}
+		if err := wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		proof, err := c.CheckpointProof(ctx, lookup.Height)
+		if err != nil {
+			return nil, err
+		}
+		if proof != nil {
+			return &FinalizedMsg{Lookup: lookup, Proof: proof}, nil
+		}
+		if err := wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// CheckpointProof is a Mir checkpoint included in a block of the subnet.
+type CheckpointProof struct {
+	// Block is the block including the checkpoint.
+	Block *types.BlockHeader
+	// Height of the checkpoint: the checkpoint commits the blocks below it.
+	Height abi.ChainEpoch
+	// Checkpoint is the checkpoint without its certificate, as serialized by Mir.
+	Checkpoint []byte
+	// Cert is the certificate of the checkpoint, as serialized by Mir.
+	Cert []byte
+}
+
+// StableCheckpoint returns the checkpoint with its certificate, so it can be verified against
+// the membership of the epoch preceding the checkpoint.
+func (p *CheckpointProof) StableCheckpoint() (*checkpoint.StableCheckpoint, error) {
+	ch := &checkpoint.StableCheckpoint{}
+	if err := ch.Deserialize(p.Checkpoint); err != nil {
+		return nil, fmt.Errorf("error deserializing checkpoint: %w", err)
+	}
+	cert := &checkpoint.Certificate{}
+	if err := cert.Deserialize(p.Cert); err != nil {
+		return nil, fmt.Errorf("error deserializing checkpoint certificate: %w", err)
+	}
+	return ch.AttachCert(cert), nil
+}
+
+// CheckpointProof returns the first checkpoint in the chain committing the block at height h,
+// or nil if the block isn't committed by a checkpoint yet.
+func (c *Client) CheckpointProof(ctx context.Context, h abi.ChainEpoch) (*CheckpointProof, error) {
+	head, err := c.node.ChainHead(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting chain head: %w", err)
+	}
+	for i := h + 1; i <= head.Height(); i++ {
+		ts, err := c.node.ChainGetTipSetByHeight(ctx, i, head.Key())
+		if err != nil {
+			return nil, fmt.Errorf("error getting tipset at height %d: %w", i, err)
+		}
+		if ts.Height() != i {
+			continue
+		}
+		b := ts.Blocks()[0]
+		if b.ElectionProof == nil || b.ElectionProof.VRFProof == nil || b.Ticket == nil {
+			continue
+		}
+		_, chData, err := headerext.DecodeAs(b.Ticket.VRFProof, headerext.Checkpoint)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding checkpoint in block %d: %w", i, err)
+		}
+		height, err := checkpointHeight(chData)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding checkpoint in block %d: %w", i, err)
+		}
+		if height > h {
+			_, certData, err := headerext.DecodeAs(b.ElectionProof.VRFProof, headerext.Cert, headerext.CompactCert)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding checkpoint certificate in block %d: %w", i, err)
+			}
+			return &CheckpointProof{
+				Block:      b,
+				Height:     height,
+				Checkpoint: chData,
+				Cert:       certData,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Membership returns the validators of the subnet in the gateway actor at the chain head.
+func (c *Client) Membership(ctx context.Context) (*validator.Set, error) {
+	return c.node.IPCGetGatewayMembership(ctx, c.gateway, types.EmptyTSK)
+}
+
+// checkpointHeight returns the height of a serialized checkpoint.
+func checkpointHeight(b []byte) (abi.ChainEpoch, error) {
+	ch := &checkpoint.StableCheckpoint{}
+	if err := ch.Deserialize(b); err != nil {
+		return 0, err
+	}
+	return snapshotHeight(ch.Snapshot.AppData)
+}
+
+// snapshotHeight returns the height of the application data of a checkpoint, the CBOR tuple
+// of the checkpoint of the subnet starting with its height, compressed or not.
+func snapshotHeight(appData []byte) (abi.ChainEpoch, error) {
+	appData, err := compression.Decompress(appData)
+	if err != nil {
+		return 0, err
+	}
+	cr := cbg.NewCborReader(bytes.NewReader(appData))
+	maj, _, err := cr.ReadHeader()
+	if err != nil {
+		return 0, err
+	}
+	if maj != cbg.MajArray {
+		return 0, fmt.Errorf("checkpoint data should be a tuple")
+	}
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return 0, err
+	}
+	switch maj {
+	case cbg.MajUnsignedInt:
+		return abi.ChainEpoch(extra), nil
+	case cbg.MajNegativeInt:
+		return abi.ChainEpoch(-1 - int64(extra)), nil
+	default:
+		return 0, fmt.Errorf("wrong type for checkpoint height: %d", maj)
+	}
+}
+
+func wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(PollInterval):
+		return nil
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/compression"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestSnapshotHeight(t *testing.T) {
+	for _, h := range []abi.ChainEpoch{0, 1, 42, 1 << 40} {
+		ch := &mir.Checkpoint{Height: h, BlockCids: []cid.Cid{}}
+		b, err := ch.Bytes()
+		require.NoError(t, err)
+		got, err := snapshotHeight(b)
+		require.NoError(t, err)
+		require.Equal(t, h, got)
+
+		got, err = snapshotHeight(compression.Compress(b))
+		require.NoError(t, err)
+		require.Equal(t, h, got)
+	}
+
+	_, err := snapshotHeight([]byte{1})
+	require.Error(t, err)
+}
+
+type fakeNode struct {
+	chain      []*types.TipSet
+	lookup     *MsgLookup
+	membership *validator.Set
+}
+
+func (n *fakeNode) ChainHead(context.Context) (*types.TipSet, error) {
+	return n.chain[len(n.chain)-1], nil
+}
+
+func (n *fakeNode) ChainGetTipSetByHeight(_ context.Context, h abi.ChainEpoch, _ types.TipSetKey) (*types.TipSet, error) {
+	return n.chain[h], nil
+}
+
+func (n *fakeNode) MpoolPush(_ context.Context, msg *types.SignedMessage) (cid.Cid, error) {
+	return msg.Cid(), nil
+}
+
+func (n *fakeNode) StateSearchMsg(context.Context, types.TipSetKey, cid.Cid, abi.ChainEpoch, bool) (*MsgLookup, error) {
+	return n.lookup, nil
+}
+
+func (n *fakeNode) IPCGetGatewayMembership(_ context.Context, gw address.Address, _ types.TipSetKey) (*validator.Set, error) {
+	if gw != DefaultGatewayAddr {
+		return nil, nil
+	}
+	return n.membership, nil
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	PollInterval = 10 * time.Millisecond
+
+	node := &fakeNode{membership: validator.NewValidatorSet(3, nil)}
+	var ts *types.TipSet
+	for i := 0; i <= 3; i++ {
+		// Mir blocks without checkpoints have an empty election proof.
+		b := mock.MkBlock(ts, 1, 1)
+		b.ElectionProof = &types.ElectionProof{}
+		ts = mock.TipSet(b)
+		node.chain = append(node.chain, ts)
+	}
+	c := New(node)
+
+	set, err := c.Membership(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), set.ConfigurationNumber)
+
+	msg := &types.SignedMessage{Message: types.Message{To: DefaultGatewayAddr, From: DefaultGatewayAddr}}
+	mc, err := c.Push(ctx, msg)
+	require.NoError(t, err)
+
+	// Blocks without checkpoints don't finalize messages.
+	proof, err := c.CheckpointProof(ctx, 1)
+	require.NoError(t, err)
+	require.Nil(t, proof)
+
+	node.lookup = &MsgLookup{Message: mc, TipSet: node.chain[1].Key(), Height: 1}
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = c.WaitFinal(ctx, mc)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
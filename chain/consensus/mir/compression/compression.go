@@ -0,0 +1,58 @@
+// Package compression implements the compression of the checkpoints of a subnet.
+//
+// Checkpoints commit the CIDs of all the blocks since their parent, so with long checkpoint periods the
+// snapshots agreed on by Mir, and the stable checkpoints persisted and exchanged by the validators, grow
+// large. They are compressed as zstd frames. The frames are recognized by their magic number, which
+// neither a CBOR snapshot nor a protobuf stable checkpoint starts with, so the data serialized before
+// the compression is still read as is.
+package compression
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// MaxDecompressedSize is the maximum size of decompressed data, so a small frame received from a
+// peer can't exhaust the memory of the node.
+const MaxDecompressedSize = 256 << 20
+
+// magic is the magic number that starts every zstd frame.
+var magic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+var (
+	// The encoder is used with a single goroutine and without checksums, so the output only depends on
+	// the input: validators that compress the same snapshot must produce the same bytes.
+	encoder, _ = zstd.NewWriter(nil,
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithEncoderCRC(false),
+		zstd.WithEncoderLevel(zstd.SpeedDefault),
+	)
+	decoder, _ = zstd.NewReader(nil,
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderMaxMemory(MaxDecompressedSize),
+	)
+)
+
+// IsCompressed returns whether b is compressed.
+func IsCompressed(b []byte) bool {
+	return bytes.HasPrefix(b, magic)
+}
+
+// Compress returns b compressed. The output is deterministic for a given version of the encoder.
+func Compress(b []byte) []byte {
+	return encoder.EncodeAll(b, make([]byte, 0, len(b)/2))
+}
+
+// Decompress returns b decompressed, or b as is if it isn't compressed.
+func Decompress(b []byte) ([]byte, error) {
+	if !IsCompressed(b) {
+		return b, nil
+	}
+	out, err := decoder.DecodeAll(b, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing checkpoint: %w", err)
+	}
+	return out, nil
+}
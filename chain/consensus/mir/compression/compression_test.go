@@ -0,0 +1,34 @@
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	// CIDs of blocks share long prefixes, like the snapshots of the checkpoints.
+	data := bytes.Repeat([]byte("bafy2bzacea"), 1000)
+
+	b := Compress(data)
+	require.True(t, IsCompressed(b))
+	require.Less(t, len(b), len(data))
+	require.Equal(t, b, Compress(data))
+
+	out, err := Decompress(b)
+	require.NoError(t, err)
+	require.Equal(t, data, out)
+
+	// Uncompressed data is returned as is.
+	out, err = Decompress(data)
+	require.NoError(t, err)
+	require.Equal(t, data, out)
+	out, err = Decompress(nil)
+	require.NoError(t, err)
+	require.Nil(t, out)
+
+	// Corrupted frames are reported.
+	_, err = Decompress(b[:len(b)-2])
+	require.Error(t, err)
+}
@@ -7,10 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
+	"time"
 
+	"github.com/consensus-shipyard/go-ipc-types/validator"
 	"github.com/ipfs/go-datastore"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/mir/pkg/client"
 	"github.com/filecoin-project/mir/pkg/pb/trantorpb"
 	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
@@ -24,6 +28,8 @@ import (
 
 const (
 	ConfigurationTxDBPrefix = "mir/configuration/"
+	// ConfigurationTxTimeDBPrefix is used to store the time a configuration transaction was created.
+	ConfigurationTxTimeDBPrefix = "mir/configuration-time/"
 )
 
 var (
@@ -35,14 +41,43 @@ var (
 	NextAppliedConfigurationNumberKey = datastore.NewKey("mir/next-applied-config-number")
 	// ConfigurationVotesKey is used to store configuration votes.
 	ConfigurationVotesKey = datastore.NewKey("mir/reconfiguration-votes")
+	// LastValidatorSetKey is used to store the last validator set proposed by the validator.
+	LastValidatorSetKey = datastore.NewKey("mir/last-validator-set")
 )
 
+var (
+	// ErrInvalidVote is returned when a configuration vote or vote record is malformed.
+	ErrInvalidVote = errors.New("invalid configuration vote")
+	// ErrDuplicateVote is returned when a validator votes twice for the same configuration.
+	ErrDuplicateVote = errors.New("duplicate configuration vote")
+	// ErrCorruptedVotes is returned when the persisted configuration votes can't be restored.
+	ErrCorruptedVotes = errors.New("corrupted configuration votes")
+	// ErrNotPendingConfigurationTx is returned when cancelling a configuration transaction that is not pending.
+	ErrNotPendingConfigurationTx = errors.New("configuration transaction is not pending")
+)
+
+// PendingConfigurationRequest describes a configuration transaction created by the validator
+// that has not been applied yet.
+type PendingConfigurationRequest struct {
+	// TxNo is the number of the Mir configuration transaction.
+	TxNo uint64
+	// ConfigurationNumber is the number of the configuration the validator votes for.
+	ConfigurationNumber uint64
+	// SubmittedAt is the time the transaction was created. It is zero if unknown.
+	SubmittedAt time.Time
+	// Cancelled is true if the request was cancelled with CancelPending.
+	Cancelled bool
+	// FreezeHeight is the height the validator votes to freeze the subnet at, if it is a shutdown request.
+	FreezeHeight abi.ChainEpoch
+}
+
 var _ client.Client = &ConfigurationManager{}
 
 type ConfigurationManager struct {
 	ctx                  context.Context // Parent context
 	ds                   db.DB           // Persistent storage.
 	id                   string          // Manager ID.
+	lk                   sync.Mutex      // Protects the transaction numbers, as the validator admin API runs concurrently with Mir.
 	nextTxNo             uint64          // The number that will be used in the next Mir configuration transaction.
 	nextAppliedNo        uint64          // The number of the next configuration Mir transaction that will be applied.
 	initialConfiguration membership.Info // Initial membership information.
@@ -84,11 +119,14 @@ func NewConfigurationManagerWithMembershipInfo(ctx context.Context, ds db.DB, id
 // corresponding to the number of transactions previously created by this client.
 // Until Done is called with the returned transaction number,
 // the transaction will be pending, i.e., among the transactions returned by Pending.
-func (cm *ConfigurationManager) NewTX(_ uint64, data []byte) (*mirproto.Transaction, error) {
+func (cm *ConfigurationManager) NewTX(txType uint64, data []byte) (*mirproto.Transaction, error) {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
 	r := mirproto.Transaction{
 		ClientId: types.ClientID(cm.id),
 		TxNo:     types.TxNo(cm.nextTxNo),
-		Type:     ConfigurationTransaction,
+		Type:     txType,
 		Data:     data,
 	}
 
@@ -96,6 +134,7 @@ func (cm *ConfigurationManager) NewTX(_ uint64, data []byte) (*mirproto.Transact
 		log.With("validator", cm.id).Errorf("unable to store configuration tx: %v", err)
 		return nil, err
 	}
+	cm.storeTxTime(cm.nextTxNo, time.Now())
 
 	{
 		// If a transaction with number n was persisted and the node had crashed here
@@ -114,6 +153,9 @@ func (cm *ConfigurationManager) GetInitialMembershipInfo() membership.Info {
 
 // Done marks a configuration transaction as done. It will no longer be among the transactions returned by Pending.
 func (cm *ConfigurationManager) Done(txNo types.TxNo) error {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
 	cm.nextAppliedNo = txNo.Pb() + 1
 	cm.storeNextAppliedConfigurationNumber(cm.nextAppliedNo)
 	cm.removeTx(txNo.Pb())
@@ -123,6 +165,12 @@ func (cm *ConfigurationManager) Done(txNo types.TxNo) error {
 // Pending returns from the persistent storage all transactions previously returned by NewTX
 // that have not been applied yet.
 func (cm *ConfigurationManager) Pending() (txs []*mirproto.Transaction, err error) {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+	return cm.pending()
+}
+
+func (cm *ConfigurationManager) pending() (txs []*mirproto.Transaction, err error) {
 	for i := cm.nextAppliedNo; i < cm.nextTxNo; i++ {
 		tx, err := cm.getTx(i)
 		if err != nil {
@@ -133,6 +181,73 @@ func (cm *ConfigurationManager) Pending() (txs []*mirproto.Transaction, err erro
 	return txs, nil
 }
 
+// PendingRequests returns the configuration transactions that have not been applied yet,
+// together with the target configuration number and the time they were submitted.
+func (cm *ConfigurationManager) PendingRequests() ([]PendingConfigurationRequest, error) {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	txs, err := cm.pending()
+	if err != nil {
+		return nil, err
+	}
+
+	var reqs []PendingConfigurationRequest
+	for _, tx := range txs {
+		n := tx.TxNo.Pb()
+		r := PendingConfigurationRequest{
+			TxNo:        n,
+			SubmittedAt: cm.getTxTime(n),
+			Cancelled:   isCancelledConfigurationTx(tx),
+		}
+		switch {
+		case r.Cancelled:
+		case tx.Type == ShutdownTransaction:
+			if r.FreezeHeight, err = decodeShutdownRequest(tx.Data); err != nil {
+				return nil, fmt.Errorf("failed to decode shutdown tx %d: %w", n, err)
+			}
+		default:
+			var set validator.Set
+			if err := set.UnmarshalCBOR(bytes.NewReader(tx.Data)); err != nil {
+				return nil, fmt.Errorf("failed to decode configuration tx %d: %w", n, err)
+			}
+			r.ConfigurationNumber = set.ConfigurationNumber
+		}
+		reqs = append(reqs, r)
+	}
+	return reqs, nil
+}
+
+// CancelPending cancels a pending configuration transaction, so it is not resent to Mir.
+//
+// Mir delivers the transactions of a client in the order of their numbers, so the number of the
+// cancelled transaction can't be reused or skipped without blocking the next transactions.
+// Instead, the transaction is replaced by an empty one that is ignored when applied.
+// The cancellation is local: if the original transaction was already sent to the other
+// validators before the cancellation, it may still be ordered.
+func (cm *ConfigurationManager) CancelPending(txNo uint64) error {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	if txNo < cm.nextAppliedNo || txNo >= cm.nextTxNo {
+		return fmt.Errorf("configuration tx %d: %w", txNo, ErrNotPendingConfigurationTx)
+	}
+	tx, err := cm.getTx(txNo)
+	if err != nil {
+		return err
+	}
+	if isCancelledConfigurationTx(tx) {
+		return nil
+	}
+	tx.Data = nil
+	return cm.storeTx(tx, txNo)
+}
+
+// isCancelledConfigurationTx returns true if the configuration transaction was cancelled with CancelPending.
+func isCancelledConfigurationTx(tx *mirproto.Transaction) bool {
+	return len(tx.Data) == 0
+}
+
 // Sync ensures that the effects of all previous calls to NewTX and Done have been written to persistent storage.
 // We do not use this according to the used DB interface.
 func (cm *ConfigurationManager) Sync() error {
@@ -196,6 +311,22 @@ func (cm *ConfigurationManager) removeTx(n uint64) {
 	if err := cm.ds.Delete(cm.ctx, configurationIndexKey(n)); err != nil {
 		log.With("validator", cm.id).Warnf("failed to remove applied configuration tx %d: %v", n, err)
 	}
+	if err := cm.ds.Delete(cm.ctx, configurationTimeKey(n)); err != nil {
+		log.With("validator", cm.id).Warnf("failed to remove time of applied configuration tx %d: %v", n, err)
+	}
+}
+
+func (cm *ConfigurationManager) storeTxTime(n uint64, tm time.Time) {
+	cm.storeNumber(configurationTimeKey(n), uint64(tm.UnixNano()))
+}
+
+// getTxTime returns the time a configuration transaction was created, or zero time if it is unknown.
+func (cm *ConfigurationManager) getTxTime(n uint64) time.Time {
+	b, err := cm.ds.Get(cm.ctx, configurationTimeKey(n))
+	if err != nil || len(b) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.LittleEndian.Uint64(b)))
 }
 
 func (cm *ConfigurationManager) storeNextConfigurationNumber(n uint64) {
@@ -230,32 +361,62 @@ func (cm *ConfigurationManager) getAppliedConfigurationNumber() uint64 {
 	return binary.LittleEndian.Uint64(b)
 }
 
-func (cm *ConfigurationManager) GetConfigurationVotes() map[uint64]map[string]map[t.NodeID]struct{} {
-	votes := make(map[uint64]map[string]map[t.NodeID]struct{})
+// LoadVotes loads the configuration votes from the persistent storage.
+// If no votes have been stored yet, it returns an empty set of votes.
+// It returns ErrCorruptedVotes if the stored votes can't be decoded or are not valid.
+func (cm *ConfigurationManager) LoadVotes() (*ConfigurationVotes, error) {
 	b, err := cm.ds.Get(cm.ctx, ConfigurationVotesKey)
 	if errors.Is(err, datastore.ErrNotFound) {
 		log.With("validator", cm.id).Info("stored reconfiguration votes not found")
-		return votes
+		return NewConfigurationVotes(make(map[uint64]map[string]map[t.NodeID]struct{})), nil
 	}
 	if err != nil {
-		log.With("validator", cm.id).Warnf("failed to get reconfiguration votes: %v", err)
-		return votes
+		return nil, fmt.Errorf("failed to get reconfiguration votes: %w", err)
 	}
 
 	var r VoteRecords
 	if err := r.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
-		log.With("validator", cm.id).Warnf("failed to unmarshal reconfiguration votes: %v", err)
-		return votes
+		return nil, fmt.Errorf("failed to unmarshal reconfiguration votes: %v: %w", err, ErrCorruptedVotes)
+	}
+	if err := ValidateVoteRecords(r.Records); err != nil {
+		return nil, fmt.Errorf("invalid stored reconfiguration votes: %v: %w", err, ErrCorruptedVotes)
 	}
-	votes = GetConfigurationVotes(r.Records)
 
-	return votes
+	return NewConfigurationVotesFromRecords(r.Records), nil
 }
 
-func (cm *ConfigurationManager) StoreConfigurationVotes(votes map[uint64]map[string]map[t.NodeID]struct{}) error {
-	recs := StoreConfigurationVotes(votes)
-	r := VoteRecords{
-		Records: recs,
+// LoadLastValidatorSet returns the last validator set stored with StoreLastValidatorSet,
+// or nil if no set has been stored yet.
+func (cm *ConfigurationManager) LoadLastValidatorSet() (*validator.Set, error) {
+	b, err := cm.ds.Get(cm.ctx, LastValidatorSetKey)
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last validator set: %w", err)
+	}
+
+	var set validator.Set
+	if err := set.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal last validator set: %w", err)
+	}
+	return &set, nil
+}
+
+// StoreLastValidatorSet persists the last validator set proposed by the validator.
+func (cm *ConfigurationManager) StoreLastValidatorSet(set *validator.Set) error {
+	var b bytes.Buffer
+	if err := set.MarshalCBOR(&b); err != nil {
+		return fmt.Errorf("failed to marshal last validator set: %w", err)
+	}
+	return cm.ds.Put(cm.ctx, LastValidatorSetKey, b.Bytes())
+}
+
+// StoreVotes validates and persists the configuration votes.
+func (cm *ConfigurationManager) StoreVotes(votes *ConfigurationVotes) error {
+	r := votes.GetVoteRecords()
+	if err := ValidateVoteRecords(r.Records); err != nil {
+		return err
 	}
 
 	b := new(bytes.Buffer)
@@ -263,12 +424,24 @@ func (cm *ConfigurationManager) StoreConfigurationVotes(votes map[uint64]map[str
 		return err
 	}
 	if err := cm.ds.Put(cm.ctx, ConfigurationVotesKey, b.Bytes()); err != nil {
-		log.With("validator", cm.id).Warnf("failed to put reconfiguration votes: %v", err)
+		return fmt.Errorf("failed to put reconfiguration votes: %w", err)
 	}
-
 	return nil
 }
 
+// ApplyVote records the vote of validator v for the validator set with hash h and configuration number n,
+// and persists the updated votes.
+// It returns ErrInvalidVote if the vote is malformed and ErrDuplicateVote if the validator has already voted.
+func (cm *ConfigurationManager) ApplyVote(votes *ConfigurationVotes, n uint64, h string, v t.NodeID) error {
+	if h == "" || v == "" {
+		return fmt.Errorf("validator %s voted for configuration %d with hash %q: %w", v, n, h, ErrInvalidVote)
+	}
+	if err := votes.VoteForConfiguration(n, h, v); err != nil {
+		return err
+	}
+	return cm.StoreVotes(votes)
+}
+
 func (cm *ConfigurationManager) storeNumber(key datastore.Key, n uint64) {
 	rb := make([]byte, 8)
 	binary.LittleEndian.PutUint64(rb, n)
@@ -281,6 +454,10 @@ func configurationIndexKey(n uint64) datastore.Key {
 	return datastore.NewKey(ConfigurationTxDBPrefix + strconv.FormatUint(n, 10))
 }
 
+func configurationTimeKey(n uint64) datastore.Key {
+	return datastore.NewKey(ConfigurationTxTimeDBPrefix + strconv.FormatUint(n, 10))
+}
+
 func GetConfigurationVotes(vr []VoteRecord) map[uint64]map[string]map[t.NodeID]struct{} {
 	m := make(map[uint64]map[string]map[t.NodeID]struct{})
 	for _, v := range vr {
@@ -316,3 +493,35 @@ func StoreConfigurationVotes(votes map[uint64]map[string]map[t.NodeID]struct{})
 	}
 	return vs
 }
+
+// ValidateVoteRecords checks that vote records are well-formed: every record has a validator set hash,
+// there is a single record per configuration number and hash, and no validator appears twice in a record.
+func ValidateVoteRecords(vr []VoteRecord) error {
+	type recordKey struct {
+		n uint64
+		h string
+	}
+	seen := make(map[recordKey]struct{})
+	for _, r := range vr {
+		if r.ValSetHash == "" {
+			return fmt.Errorf("empty validator set hash for configuration %d: %w", r.ConfigurationNumber, ErrInvalidVote)
+		}
+		k := recordKey{r.ConfigurationNumber, r.ValSetHash}
+		if _, exist := seen[k]; exist {
+			return fmt.Errorf("several records for configuration %d: %w", r.ConfigurationNumber, ErrInvalidVote)
+		}
+		seen[k] = struct{}{}
+
+		voters := make(map[string]struct{})
+		for _, v := range r.VotedValidators {
+			if v.ID == "" {
+				return fmt.Errorf("empty validator ID in votes for configuration %d: %w", r.ConfigurationNumber, ErrInvalidVote)
+			}
+			if _, voted := voters[v.ID]; voted {
+				return fmt.Errorf("validator %s voted several times for configuration %d: %w", v.ID, r.ConfigurationNumber, ErrDuplicateVote)
+			}
+			voters[v.ID] = struct{}{}
+		}
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"testing"
 
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/ipfs/go-datastore"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
 
@@ -303,3 +305,126 @@ func TestConfigurationManagerRecoverData_WithZeroNonce0(t *testing.T) {
 	require.Equal(t, uint64(0), cm.nextAppliedNo)
 	require.Equal(t, 0, len(reqs))
 }
+
+func TestConfigurationManagerVotes(t *testing.T) {
+	cm, err := NewConfigurationManager(context.Background(), datastore.NewMapDatastore(), "id1")
+	require.NoError(t, err)
+
+	votes, err := cm.LoadVotes()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(votes.Votes()))
+
+	err = cm.ApplyVote(votes, 1, "hash1", "id1")
+	require.NoError(t, err)
+	err = cm.ApplyVote(votes, 1, "hash1", "id2")
+	require.NoError(t, err)
+	err = cm.ApplyVote(votes, 2, "hash2", "id1")
+	require.NoError(t, err)
+
+	err = cm.ApplyVote(votes, 1, "hash1", "id2")
+	require.ErrorIs(t, err, ErrDuplicateVote)
+	err = cm.ApplyVote(votes, 1, "", "id3")
+	require.ErrorIs(t, err, ErrInvalidVote)
+	err = cm.ApplyVote(votes, 1, "hash1", "")
+	require.ErrorIs(t, err, ErrInvalidVote)
+
+	restored, err := cm.LoadVotes()
+	require.NoError(t, err)
+	require.Equal(t, 2, restored.GetVotesForConfiguration(1, "hash1"))
+	require.Equal(t, 1, restored.GetVotesForConfiguration(2, "hash2"))
+	require.Equal(t, votes.GetVoteRecords(), restored.GetVoteRecords())
+}
+
+func TestConfigurationManagerCorruptedVotes(t *testing.T) {
+	ds := datastore.NewMapDatastore()
+	cm, err := NewConfigurationManager(context.Background(), ds, "id1")
+	require.NoError(t, err)
+
+	err = ds.Put(context.Background(), ConfigurationVotesKey, []byte{1, 2, 3})
+	require.NoError(t, err)
+	_, err = cm.LoadVotes()
+	require.ErrorIs(t, err, ErrCorruptedVotes)
+
+	r := VoteRecords{
+		Records: []VoteRecord{
+			{0, "hash", NewVotedValidators("id1", "id1")},
+		},
+	}
+	b := new(bytes.Buffer)
+	err = r.MarshalCBOR(b)
+	require.NoError(t, err)
+	err = ds.Put(context.Background(), ConfigurationVotesKey, b.Bytes())
+	require.NoError(t, err)
+	_, err = cm.LoadVotes()
+	require.ErrorIs(t, err, ErrCorruptedVotes)
+}
+
+func newTestConfigurationSet(t *testing.T, n uint64) *validator.Set {
+	v, err := validator.NewValidatorFromString(
+		"t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:10@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+	return validator.NewValidatorSet(n, []*validator.Validator{v})
+}
+
+func TestConfigurationManagerPendingRequests(t *testing.T) {
+	cm, err := NewConfigurationManager(context.Background(), datastore.NewMapDatastore(), "id1")
+	require.NoError(t, err)
+
+	for _, n := range []uint64{3, 4} {
+		set := newTestConfigurationSet(t, n)
+		var b bytes.Buffer
+		require.NoError(t, set.MarshalCBOR(&b))
+		_, err = cm.NewTX(ConfigurationTransaction, b.Bytes())
+		require.NoError(t, err)
+	}
+
+	reqs, err := cm.PendingRequests()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(reqs))
+	require.Equal(t, uint64(0), reqs[0].TxNo)
+	require.Equal(t, uint64(3), reqs[0].ConfigurationNumber)
+	require.Equal(t, uint64(4), reqs[1].ConfigurationNumber)
+	require.False(t, reqs[0].SubmittedAt.IsZero())
+	require.False(t, reqs[0].Cancelled)
+
+	// Cancelled transactions keep their number so that the next ones can be delivered.
+	err = cm.CancelPending(0)
+	require.NoError(t, err)
+	reqs, err = cm.PendingRequests()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(reqs))
+	require.True(t, reqs[0].Cancelled)
+	require.False(t, reqs[1].Cancelled)
+	require.Equal(t, uint64(2), cm.nextTxNo)
+
+	err = cm.CancelPending(2)
+	require.ErrorIs(t, err, ErrNotPendingConfigurationTx)
+
+	require.NoError(t, cm.Done(0))
+	err = cm.CancelPending(0)
+	require.ErrorIs(t, err, ErrNotPendingConfigurationTx)
+	reqs, err = cm.PendingRequests()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(reqs))
+	require.Equal(t, uint64(1), reqs[0].TxNo)
+}
+
+func TestValidateVoteRecords(t *testing.T) {
+	err := ValidateVoteRecords([]VoteRecord{
+		{0, "hash1", NewVotedValidators("id1", "id2")},
+		{0, "hash2", NewVotedValidators("id1")},
+	})
+	require.NoError(t, err)
+
+	err = ValidateVoteRecords([]VoteRecord{{0, "", NewVotedValidators("id1")}})
+	require.ErrorIs(t, err, ErrInvalidVote)
+
+	err = ValidateVoteRecords([]VoteRecord{
+		{0, "hash1", NewVotedValidators("id1")},
+		{0, "hash1", NewVotedValidators("id2")},
+	})
+	require.ErrorIs(t, err, ErrInvalidVote)
+
+	err = ValidateVoteRecords([]VoteRecord{{0, "hash1", NewVotedValidators("id1", "id1")}})
+	require.ErrorIs(t, err, ErrDuplicateVote)
+}
@@ -3,9 +3,11 @@ package mir
 import (
 	"time"
 
+	"github.com/libp2p/go-libp2p/core/host"
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/mir/pkg/checkpoint"
 
 	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
@@ -19,11 +21,24 @@ type BaseConfig struct {
 	Addr address.Address
 	// Persistent storage file path.
 	DatastorePath string
+	// Ephemeral is set if the datastore of the validator is kept in memory, in which case
+	// DatastorePath is empty and the validator restarts from the latest persisted checkpoint.
+	Ephemeral bool
 	// InitialCheckpoint from which to start the validator.
 	InitialCheckpoint *checkpoint.StableCheckpoint
 	// CheckpointRepo determines the path where Mir checkpoints
 	// will be (optionally) persisted.
 	CheckpointRepo string
+	// StrictCheckpointPersistence makes the validator stop producing blocks if a checkpoint can't be
+	// synced to CheckpointRepo, for deployments that recover from the checkpoint files.
+	StrictCheckpointPersistence bool
+	// CheckpointRetention determines the checkpoints kept in the datastore, which keeps all of them by default.
+	CheckpointRetention CheckpointRetention
+	// CheckpointFileRetention determines the checkpoint files kept in CheckpointRepo, which keeps all of them by default.
+	CheckpointFileRetention CheckpointFileRetention
+	// StateSnapshotRepo, if set, is the path where the validator exports the state of the chain at every
+	// checkpoint, to serve it to the validators that fast-sync.
+	StateSnapshotRepo string
 	// The name of the group of validators.
 	GroupName string
 	// The source of membership: file, chain, etc.
@@ -40,6 +55,9 @@ const (
 	DefaultMaxTransactionsInBatch       = 1024
 	DefaultPBFTViewChangeSNTimeout      = 6 * time.Second
 	DefaultPBFTViewChangeSegmentTimeout = 6 * time.Second
+	DefaultMpoolSelectRetries           = 3
+	DefaultRampUpEpochs                 = 3
+	DefaultMaxCheckpointBlocks          = 4096
 )
 
 type ConsensusConfig struct {
@@ -50,6 +68,40 @@ type ConsensusConfig struct {
 	MaxProposeDelay              time.Duration
 	PBFTViewChangeSNTimeout      time.Duration
 	PBFTViewChangeSegmentTimeout time.Duration
+	// CheckpointRandomness enables the inclusion in blocks of beacon entries derived from checkpoints.
+	// It is only taken into account for the first block of the chain; later blocks include entries if their parent does.
+	CheckpointRandomness bool
+	// CompressCheckpoints makes the validator compress the snapshots of its checkpoints and the stable
+	// checkpoints it persists. Mir agrees on the bytes of the snapshots, so all the validators must
+	// enable it.
+	CompressCheckpoints bool
+	// DisableMempoolBucketing makes the validator propose all the messages selected from the mempool,
+	// instead of only those of the senders assigned to it in the current segment.
+	DisableMempoolBucketing bool
+	// DisableParamsPresets keeps the default periods and timeouts of Mir in memberships of
+	// LargeMembershipSize validators or more, instead of scaling them to the size of the membership.
+	DisableParamsPresets bool
+	// MpoolSelectRetries is the number of times a failed selection of messages from the mempool is retried.
+	MpoolSelectRetries int
+	// QuietSelectionErrors logs the batches proposed without mempool messages because of a selection error
+	// at debug level instead of warning level.
+	QuietSelectionErrors bool
+	// RampUpEpochs is the number of epochs after a (re)start over which the size of the proposed batches
+	// grows up to MaxTransactionsInBatch. Zero disables the ramp-up.
+	RampUpEpochs int
+	// StallTimeout is the time without batches after which the chain is considered stalled.
+	// Zero means DefaultStallCadenceMultiple times MaxProposeDelay.
+	StallTimeout time.Duration
+	// BatchStoreCap is the size in bytes of the transactions the batch store of the availability layer
+	// keeps before it is pruned without waiting for a stable checkpoint. Zero means no cap.
+	BatchStoreCap int64
+	// MaxCheckpointBlocks is the maximum number of blocks committed by the checkpoints of the validator,
+	// which commit SegmentLength times the number of validators blocks. It can't be above the
+	// MaxCheckpointBlocks of the encoding. Zero means DefaultMaxCheckpointBlocks.
+	MaxCheckpointBlocks int
+	// ExecutionLag is the number of blocks ordered by Mir that can wait for the execution of the blocks
+	// before them. Zero creates the block of every batch before the next batch is delivered.
+	ExecutionLag int
 }
 
 // ---
@@ -60,6 +112,41 @@ type Config struct {
 	IPCAgent *rpc.Config
 
 	Consensus *ConsensusConfig
+
+	// OnBlock, if set, is called with the height of every block created by the validator.
+	// It is used to report the progress of the validator, e.g. to a service manager.
+	OnBlock func(height abi.ChainEpoch)
+
+	// OnBlockProvenance, if set, is called with the provenance of every block created by the validator.
+	// It is used by tests to check which validators proposed the batches of the blocks.
+	OnBlockProvenance func(BlockProvenance)
+
+	// OnCheckpoint, if set, is called with every stable checkpoint delivered to the validator.
+	// It is used to stream the checkpoints to bridges and auditors following the finality of the subnet.
+	OnCheckpoint func(*CheckpointNotification)
+
+	// OnMsgEvent, if set, is called when a message is proposed, ordered and included in a block by the validator.
+	// It is used to stream the lifecycle of their messages to the clients, e.g. with a MsgTracker.
+	OnMsgEvent func(*MsgEvent)
+
+	// TxSources are external sources of messages proposed by the validator besides its mempool.
+	TxSources []TxSource
+
+	// FastSyncHost, if set, is used to fetch from the other validators the state of the chain at the
+	// checkpoint the validator restores, instead of syncing the chain, when its head is far behind.
+	FastSyncHost host.Host
+
+	// BlockCreator, if set, creates the blocks of the validator instead of the MinerCreateBlock API
+	// of its node, e.g. a LocalBlockCreator if the validator runs in the process of the node.
+	BlockCreator BlockCreator
+}
+
+// maxCheckpointBlocks returns the maximum number of blocks committed by the checkpoints of the validator.
+func maxCheckpointBlocks(cfg *ConsensusConfig) int {
+	if cfg.MaxCheckpointBlocks > 0 {
+		return cfg.MaxCheckpointBlocks
+	}
+	return DefaultMaxCheckpointBlocks
 }
 
 func DefaultConsensusConfig() *ConsensusConfig {
@@ -70,6 +157,8 @@ func DefaultConsensusConfig() *ConsensusConfig {
 		MaxProposeDelay:              DefaultMaxBlockDelay,
 		PBFTViewChangeSNTimeout:      DefaultPBFTViewChangeSNTimeout,
 		PBFTViewChangeSegmentTimeout: DefaultPBFTViewChangeSegmentTimeout,
+		MpoolSelectRetries:           DefaultMpoolSelectRetries,
+		RampUpEpochs:                 DefaultRampUpEpochs,
 	}
 }
 
@@ -113,6 +202,8 @@ func NewConfig(
 		ConfigOffset:                 configOffset,
 		MaxProposeDelay:              maxBlockDelay,
 		MaxTransactionsInBatch:       DefaultMaxTransactionsInBatch,
+		MpoolSelectRetries:           DefaultMpoolSelectRetries,
+		RampUpEpochs:                 DefaultRampUpEpochs,
 		PBFTViewChangeSNTimeout:      max(maxBlockDelay+5*time.Second, 6*time.Second),
 		PBFTViewChangeSegmentTimeout: max((maxBlockDelay+2*time.Second)*time.Duration(segmentLength)+3*time.Second, 6*time.Second),
 	}
@@ -6,7 +6,6 @@ package mir
 import (
 	"context"
 	"crypto"
-	"fmt"
 
 	"github.com/ipfs/go-cid"
 	"golang.org/x/xerrors"
@@ -14,34 +13,48 @@ import (
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
 
 	lapi "github.com/filecoin-project/lotus/api"
 	bstore "github.com/filecoin-project/lotus/blockstore"
 	"github.com/filecoin-project/lotus/chain"
 	"github.com/filecoin-project/lotus/chain/actors/builtin"
-	"github.com/filecoin-project/lotus/chain/actors/builtin/reward"
 	"github.com/filecoin-project/lotus/chain/beacon"
 	"github.com/filecoin-project/lotus/chain/consensus"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/types"
-	"github.com/filecoin-project/lotus/chain/vm"
 	"github.com/filecoin-project/lotus/lib/async"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
 var _ consensus.Consensus = &Mir{}
 
-var RewardFunc = func(ctx context.Context, vmi vm.Interface, em stmgr.ExecMonitor,
-	epoch abi.ChainEpoch, ts *types.TipSet, params *reward.AwardBlockRewardParams) error {
-	// TODO: No RewardFunc implemented for mir yet
-	return nil
+// LearnerConfig configures how the daemon validates the blocks of a Mir subnet.
+type LearnerConfig struct {
+	// FastVerify enables the fast verification mode. The blocks between checkpoints are accepted
+	// provisionally, without checking their messages and state, and are finalized when the checkpoint
+	// that covers them is verified. If a provisional block in the head chain turns out not to be covered
+	// by the checkpoint, the head is rolled back to the last block committed by the checkpoint.
+	FastVerify bool
+	// BlkCacheWindow is the number of heights above the latest checkpoint whose blocks are kept until a
+	// checkpoint covers them, DefaultBlkCacheWindow if zero. It must span more than a checkpoint period.
+	BlkCacheWindow int
 }
 
 type Mir struct {
 	beacon  beacon.Schedule
 	sm      *stmgr.StateManager
 	genesis *types.TipSet
+	netName dtypes.NetworkName
 	cache   *mirCache
+	msgs    *msgIndex
+	// Memberships of the epochs certified by the checkpoints of the chain.
+	memberships db.DB
+
+	fastVerify bool
+	// Bounds the number of blocks validated concurrently.
+	validations *validationQueue
 }
 
 func NewConsensus(
@@ -50,42 +63,64 @@ func NewConsensus(
 	b beacon.Schedule,
 	g chain.Genesis,
 	badBlock *chain.BadBlockCache,
+	netName dtypes.NetworkName,
+	cfg LearnerConfig,
 ) (*Mir, error) {
-	return &Mir{
-		beacon:  b,
-		sm:      sm,
-		genesis: g,
-		cache:   newDsBlkCache(ds, badBlock),
-	}, nil
+	bft := &Mir{
+		beacon:      b,
+		sm:          sm,
+		genesis:     g,
+		netName:     netName,
+		cache:       newDsBlkCache(ds, badBlock, cfg),
+		msgs:        newMsgIndex(ds),
+		memberships: ds,
+		fastVerify:  cfg.FastVerify,
+		validations: newValidationQueue(maxConcurrentValidations()),
+	}
+	bft.cache.onChain = bft.headBlock
+	if bft.fastVerify {
+		log.Warn("MIR FAST VERIFICATION IS ENABLED: the messages and the state of the blocks are not checked, " +
+			"and the blocks are accepted provisionally until a checkpoint covers them")
+		bft.cache.onUncovered = bft.rollbackUncovered
+	}
+	// Mir blocks are not reorganized, so messages are searched through the index of the validated blocks.
+	sm.SetMsgIndex(bft.msgs)
+	return bft, nil
+}
+
+// upgrades returns the network upgrades of the subnet, which are read on every call because the daemon
+// may load the parameters of the subnet after the consensus is created.
+func (bft *Mir) upgrades() upgrades {
+	return newUpgrades(string(bft.netName))
 }
 
 // CreateBlock creates a Filecoin block from the block template provided by Mir.
+// The wallet isn't used, Mir blocks aren't signed.
 func (bft *Mir) CreateBlock(ctx context.Context, _ lapi.Wallet, bt *lapi.BlockTemplate) (*types.FullBlock, error) {
-	pts, err := bft.sm.ChainStore().LoadTipSet(ctx, bt.Parents)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load parent tipset: %w", err)
-	}
-
-	next, blsMessages, secpkMessages, err := consensus.CreateBlockHeader(ctx, bft.sm, pts, bt)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to process messages from block template: %w", err)
-	}
-
-	return &types.FullBlock{
-		Header:        next,
-		BlsMessages:   blsMessages,
-		SecpkMessages: secpkMessages,
-	}, nil
+	return CreateBlock(ctx, bft.sm, bt)
 }
 
+// ValidateBlockHeader performs the checks of the header of a block received through pubsub that don't need
+// its parents: the format of Mir blocks, the epoch and timestamp, and the certificate and linkage to the
+// previous checkpoint of the checkpoint included in the block, if any.
 func (bft *Mir) ValidateBlockHeader(_ context.Context, b *types.BlockHeader) (rejectReason string, err error) {
 	if b.IsValidated() {
 		return "", nil
 	}
 
+	if err := blockSanityChecks(b); err != nil {
+		return "sanity_check_failed", err
+	}
+	if b.Height <= bft.genesis.Height() {
+		return "invalid_height", xerrors.Errorf("block height %d not above genesis height %d", b.Height, bft.genesis.Height())
+	}
+	if err := checkTimestamp(b, nil, bft.upgrades().batchTimestamps(b.Height)); err != nil {
+		return "invalid_timestamp", err
+	}
+
 	// if there is a checkpoint, verify it before accepting the block.
 	if hasCheckpoint(b) {
-		if _, err := bft.verifyCheckpointInHeader(b); err != nil {
+		if _, _, err := bft.verifyCheckpointInHeader(b); err != nil {
 			log.Warnf("checkpoint validation failed in block: %s", err)
 			return "checkpoint_verification_failed", err
 		}
@@ -102,6 +137,12 @@ func (bft *Mir) ValidateBlock(ctx context.Context, b *types.FullBlock) (err erro
 		return xerrors.Errorf("incoming header failed basic sanity checks: %w", err)
 	}
 
+	release, err := bft.validations.acquire(ctx, b.Header.Height)
+	if err != nil {
+		return xerrors.Errorf("waiting for block validation slot: %w", err)
+	}
+	defer release()
+
 	h := b.Header
 
 	baseTs, err := bft.sm.ChainStore().LoadTipSet(ctx, types.NewTipSetKey(h.Parents...))
@@ -112,18 +153,16 @@ func (bft *Mir) ValidateBlock(ctx context.Context, b *types.FullBlock) (err erro
 		return xerrors.Errorf("block height not greater than parent height: %d != %d", h.Height, baseTs.Height())
 	}
 
-	// TODO: Include a block drift check when the batch timestamp is included in the block.
-	// Allow a small block drift
-	// now := uint64(build.Clock.Now().Unix())
-	// if h.Timestamp > now+build.AllowableClockDriftSecs {
-	// 	return xerrors.Errorf("block was from the future (now=%d, blk=%d): %w", now, h.Timestamp, consensus.ErrTemporal)
-	// }
-	// if h.Timestamp > now {
-	// 	log.Warn("got block from the future, but within threshold", h.Timestamp, build.Clock.Now().Unix())
-	// }
+	pts := baseTs.MinTimestamp()
+	if err := checkTimestamp(h, &pts, bft.upgrades().batchTimestamps(h.Height)); err != nil {
+		return err
+	}
+	if err := checkFreezeHeight(h, bft.cache.frozenAt()); err != nil {
+		return err
+	}
 
-	if h.Timestamp != uint64(h.Height) {
-		return xerrors.Errorf("Mir blocks should include the block height as timestamp (ts=%d, height=%d)", h.Timestamp, h.Height)
+	if err := verifyBeaconEntries(h, baseTs.Blocks()[0]); err != nil {
+		return xerrors.Errorf("beacon entries check failed: %w", err)
 	}
 
 	pweight, err := bft.sm.ChainStore().Weight(ctx, baseTs)
@@ -138,13 +177,23 @@ func (bft *Mir) ValidateBlock(ctx context.Context, b *types.FullBlock) (err erro
 
 	checkpointChk := async.Err(func() error {
 		if hasCheckpoint(h) {
-			ch, err := bft.verifyCheckpointInHeader(h)
+			stable, ch, err := bft.verifyCheckpointInHeader(h)
 			if err != nil {
 				return xerrors.Errorf("error verifying checkpoint: %w", err)
 			}
+			if err := checkFreezeHeight(h, ch.Ext.FreezeHeight); err != nil {
+				return err
+			}
 			if err := bft.cache.rcvCheckpoint(ch); err != nil {
 				return xerrors.Errorf("error verifying unverified blocks from checkpoint: %w", err)
 			}
+			// Daemons keep the memberships certified by the checkpoints of the chain too.
+			if err := putCheckpointMemberships(ctx, bft.memberships, stable, ch.Height); err != nil {
+				log.Warnf("error persisting memberships of checkpoint at height %d: %s", ch.Height, err)
+			}
+			if err := bft.msgs.finalize(ctx, ch.Height); err != nil {
+				log.Warnf("error updating message index finality to height %d: %s", ch.Height, err)
+			}
 		}
 
 		// the genesis block can be considered as verified already.
@@ -164,15 +213,26 @@ func (bft *Mir) ValidateBlock(ctx context.Context, b *types.FullBlock) (err erro
 		return nil
 	})
 
-	asyncChecks := append(
-		consensus.CommonBlkChecks(ctx, bft.sm, bft.sm.ChainStore(), b, baseTs),
-		checkpointChk,
-	)
+	asyncChecks := []async.ErrorFuture{checkpointChk}
+	if !bft.fastVerify {
+		asyncChecks = append(consensus.CommonBlkChecks(ctx, bft.sm, bft.sm.ChainStore(), b, baseTs), checkpointChk)
+	}
+	// In fast verification mode, the messages and the state of the block are committed by the checkpoint that covers it.
+	if err := consensus.RunAsyncChecks(ctx, asyncChecks); err != nil {
+		return err
+	}
 
-	return consensus.RunAsyncChecks(ctx, asyncChecks)
+	if err := bft.msgs.add(ctx, b); err != nil {
+		log.Warnf("error indexing messages of block at height %d: %s", h.Height, err)
+	}
+	return nil
 }
 
 func blockSanityChecks(h *types.BlockHeader) error {
+	if h.ElectionProof == nil || h.Ticket == nil {
+		return xerrors.Errorf("mir blocks have both a ticket and an election proof")
+	}
+
 	if h.ElectionProof.WinCount != 0 {
 		return xerrors.Errorf("mir expects a zero wincount")
 	}
@@ -212,53 +272,112 @@ func blockSanityChecks(h *types.BlockHeader) error {
 	return nil
 }
 
-func (bft *Mir) verifyCheckpointInHeader(h *types.BlockHeader) (*Checkpoint, error) {
+func (bft *Mir) verifyCheckpointInHeader(h *types.BlockHeader) (*checkpoint.StableCheckpoint, *Checkpoint, error) {
 	ch, err := CheckpointFromVRFProof(h.Ticket)
 	if err != nil {
-		return nil, xerrors.Errorf("error getting checkpoint from ticket: %w", err)
+		return nil, nil, xerrors.Errorf("error getting checkpoint from ticket: %w", err)
 	}
-	cert, err := CertFromElectionProof(h.ElectionProof)
+	cert, err := CertFromElectionProof(h.ElectionProof, ch.PreviousMembership())
 	if err != nil {
-		return nil, xerrors.Errorf("error getting checkpoint config from election proof: %w", err)
+		return nil, nil, xerrors.Errorf("error getting checkpoint config from election proof: %w", err)
 	}
 	ch = ch.AttachCert(cert)
 
+	// The range of blocks of the snapshot is validated when it is unwrapped.
 	snap, err := UnwrapCheckpointSnapshot(ch)
 	if err != nil {
-		return nil, xerrors.Errorf("error unwrapping checkpoint snapshot: %w", err)
+		return nil, nil, xerrors.Errorf("error unwrapping checkpoint snapshot: %w", err)
+	}
+	// A checkpoint can only be included once all the blocks it commits are in the chain.
+	if h.Height < snap.Height {
+		return nil, nil, xerrors.Errorf("block at height %d includes a checkpoint for height %d", h.Height, snap.Height)
 	}
 
 	// get the latest checkpoint in cache
 	prev, err := bft.cache.prevCheckpoint(snap)
 	if err != nil {
-		return nil, xerrors.Errorf("couldn't get previous checkpoint: %w", err)
+		return nil, nil, xerrors.Errorf("couldn't get previous checkpoint: %w", err)
 	}
 	// check that the block is in the right range.
 	if h.Height < prev.Height {
-		return nil, xerrors.Errorf("the height of the received block is over the latest checkpoint received")
+		return nil, nil, xerrors.Errorf("the height of the received block is over the latest checkpoint received")
 	}
 
+	// The membership a checkpoint was signed by is included in the checkpoint itself, so it is checked
+	// against the memberships certified by the previous checkpoints before verifying the signatures.
+	if err := checkCheckpointMemberships(context.TODO(), bft.memberships, ch); err != nil {
+		return nil, nil, xerrors.Errorf("error verifying checkpoint membership: %w", err)
+	}
 	// verify checkpoint signature
-	// TODO: Right now we assume static membership and the membership is received off-chain.
-	// Once we support reconfiguration (and especially if we track membership online), we should
-	// check that the membership expected for the checkpoint is correct as part of the verification.
-	// Here we are just getting the most recent membership according to the cert without additional
-	// checks. We should probably check if the membership included in the cert is the correct one.
 	if err := ch.VerifyCert(crypto.SHA256, CheckpointVerifier{}, ch.PreviousMembership()); err != nil {
-		return nil, xerrors.Errorf("error verifying checkpoint signature: %w", err)
+		return nil, nil, xerrors.Errorf("error verifying checkpoint signature: %w", err)
 	}
 	c, err := prev.Cid()
 	if err != nil {
-		return nil, xerrors.Errorf("error computing cid for latest checkpoint: %w", err)
+		return nil, nil, xerrors.Errorf("error computing cid for latest checkpoint: %w", err)
 	}
 	// if cid.Undef this is the first checkpoint, nothing to do here.
 	if c != cid.Undef {
 		if snap.Parent.Cid != c || snap.Parent.Height != prev.Height {
-			return nil, xerrors.Errorf("new checkpoint not pointing to the previous one: %s, %s", c, snap.Parent.Cid)
+			return nil, nil, xerrors.Errorf("new checkpoint not pointing to the previous one: %s, %s", c, snap.Parent.Cid)
 		}
 	}
 
-	return snap, nil
+	return ch, snap, nil
+}
+
+// headBlock returns the block at height e in the head chain, cid.Undef if there is none.
+func (bft *Mir) headBlock(e abi.ChainEpoch) cid.Cid {
+	cs := bft.sm.ChainStore()
+	head := cs.GetHeaviestTipSet()
+	if head == nil || e > head.Height() {
+		return cid.Undef
+	}
+	ts, err := cs.GetTipsetByHeight(context.TODO(), e, head, false)
+	if err != nil || ts.Height() != e {
+		return cid.Undef
+	}
+	return ts.Cids()[0]
+}
+
+// rollbackUncovered moves the head back to the last block committed by a verified checkpoint
+// if the head chain includes a provisional block that the checkpoint doesn't cover.
+func (bft *Mir) rollbackUncovered(snap *Checkpoint, uncovered []cid.Cid) {
+	ctx := context.TODO()
+	cs := bft.sm.ChainStore()
+	head := cs.GetHeaviestTipSet()
+
+	var bad *types.BlockHeader
+	for _, c := range uncovered {
+		blk, err := cs.GetBlock(ctx, c)
+		if err != nil || blk.Height > head.Height() {
+			continue
+		}
+		ts, err := cs.GetTipsetByHeight(ctx, blk.Height, head, false)
+		if err != nil {
+			log.Errorf("error getting tipset at height %d: %s", blk.Height, err)
+			continue
+		}
+		if ts.Contains(c) {
+			bad = blk
+			break
+		}
+	}
+	if bad == nil || len(snap.BlockCids) == 0 {
+		return
+	}
+
+	target, err := cs.LoadTipSet(ctx, types.NewTipSetKey(snap.BlockCids[0]))
+	if err != nil {
+		log.Errorf("error loading the last block committed by the checkpoint at height %d: %s", snap.Height, err)
+		return
+	}
+	if err := cs.SetHead(ctx, target); err != nil {
+		log.Errorf("error rolling back head to height %d: %s", target.Height(), err)
+		return
+	}
+	log.Warnf("rolled back head from %d to %d: provisional block %s at height %d not covered by the checkpoint at height %d",
+		head.Height(), target.Height(), bad.Cid(), bad.Height, snap.Height)
 }
 
 func hasCheckpoint(h *types.BlockHeader) bool {
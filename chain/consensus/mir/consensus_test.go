@@ -0,0 +1,80 @@
+package mir
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetparams"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestValidateBlockHeader(t *testing.T) {
+	ctx := context.Background()
+	genesis := mock.TipSet(mock.MkBlock(nil, 1, 0))
+	bft := &Mir{genesis: genesis}
+
+	header := func() *types.BlockHeader {
+		return &types.BlockHeader{
+			Miner:         builtin.SystemActorAddr,
+			Ticket:        &types.Ticket{},
+			ElectionProof: &types.ElectionProof{},
+			Parents:       genesis.Cids(),
+			ParentWeight:  types.NewInt(0),
+			Height:        1,
+			Timestamp:     1,
+			BLSAggregate:  &crypto.Signature{Type: crypto.SigTypeBLS},
+		}
+	}
+
+	_, err := bft.ValidateBlockHeader(ctx, header())
+	require.NoError(t, err)
+
+	// Before the BatchTimestamps upgrade, the timestamp must be the height.
+	h := header()
+	h.Timestamp = uint64(time.Now().Unix())
+	reason, err := bft.ValidateBlockHeader(ctx, h)
+	require.Error(t, err)
+	require.Equal(t, "invalid_timestamp", reason)
+
+	// After it, batch timestamps are wall-clock times.
+	activation := abi.ChainEpoch(0)
+	require.NoError(t, subnetparams.Set("/root/header-timestamps", subnetparams.Params{
+		Upgrades: &subnetparams.Upgrades{BatchTimestamps: &activation},
+	}))
+	upgraded := &Mir{genesis: genesis, netName: "/root/header-timestamps"}
+	h = header()
+	h.Timestamp = uint64(time.Now().Unix())
+	_, err = upgraded.ValidateBlockHeader(ctx, h)
+	require.NoError(t, err)
+	h = header()
+	h.Timestamp = uint64(time.Now().Add(time.Hour).Unix())
+	reason, err = upgraded.ValidateBlockHeader(ctx, h)
+	require.Error(t, err)
+	require.Equal(t, "invalid_timestamp", reason)
+
+	for name, tc := range map[string]struct {
+		malform func(h *types.BlockHeader)
+		reason  string
+	}{
+		"no election proof": {func(h *types.BlockHeader) { h.ElectionProof = nil }, "sanity_check_failed"},
+		"miner":             {func(h *types.BlockHeader) { h.Miner = builtin.CronActorAddr }, "sanity_check_failed"},
+		"signed":            {func(h *types.BlockHeader) { h.BlockSig = &crypto.Signature{} }, "sanity_check_failed"},
+		"genesis height":    {func(h *types.BlockHeader) { h.Height, h.Timestamp = 0, 0 }, "invalid_height"},
+		"timestamp":         {func(h *types.BlockHeader) { h.Timestamp = 2 }, "invalid_timestamp"},
+	} {
+		h := header()
+		tc.malform(h)
+		reason, err := bft.ValidateBlockHeader(ctx, h)
+		require.Error(t, err, name)
+		require.Equal(t, tc.reason, reason, name)
+		require.False(t, h.IsValidated(), name)
+	}
+}
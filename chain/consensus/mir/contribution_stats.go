@@ -0,0 +1,156 @@
+package mir
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+)
+
+// ContributionsDBPrefix is the prefix of the keys of the persisted contribution summaries, one per epoch.
+const ContributionsDBPrefix = "mir/contributions/"
+
+func ContributionsKey(epoch uint64) datastore.Key {
+	return datastore.NewKey(ContributionsDBPrefix + strconv.FormatUint(epoch, 10))
+}
+
+// ValidatorContribution is the amount of transactions a validator contributed to the ordered batches.
+type ValidatorContribution struct {
+	Validator    string
+	Transactions uint64
+	Bytes        uint64
+}
+
+// EpochContributions summarizes the contributions of the validators to the batches ordered in a Mir epoch.
+//
+// A validator only submits transactions to its own Mir node, so the client of a transaction is the validator
+// that made it available to the rest. The summary is the same in all the validators, as it only depends on
+// the ordered log, so it can be used as the basis of contribution-weighted rewards.
+type EpochContributions struct {
+	Epoch uint64
+	// Heights of the first and last blocks created from the batches of the epoch.
+	FirstHeight abi.ChainEpoch
+	LastHeight  abi.ChainEpoch
+	// Validators sorted by ID.
+	Validators []ValidatorContribution
+}
+
+// contributionStats keeps the summary of the epoch being ordered. The summary of an epoch is persisted
+// when the first batch of the next epoch is delivered. The batches of an epoch that wasn't persisted are
+// delivered again after a restart, as the validator is restored from the checkpoint at the start of the epoch.
+type contributionStats struct {
+	ds db.DB
+
+	lk      sync.Mutex
+	current *EpochContributions
+}
+
+func newContributionStats(ds db.DB) *contributionStats {
+	return &contributionStats{ds: ds}
+}
+
+// record adds the transport transactions of the batch delivered in epoch for the block at height h.
+func (s *contributionStats) record(ctx context.Context, epoch uint64, h abi.ChainEpoch, txs []*mirproto.Transaction) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.current == nil || s.current.Epoch != epoch {
+		if err := s.flush(ctx); err != nil {
+			return err
+		}
+		c, err := s.load(ctx, epoch)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			c = &EpochContributions{Epoch: epoch, FirstHeight: h}
+		}
+		s.current = c
+	}
+	if h <= s.current.LastHeight {
+		// Already counted before a restart.
+		return nil
+	}
+	s.current.LastHeight = h
+
+	for _, tx := range txs {
+		if tx.Type != TransportTransaction {
+			continue
+		}
+		id := string(tx.ClientId)
+		i := sort.Search(len(s.current.Validators), func(i int) bool {
+			return s.current.Validators[i].Validator >= id
+		})
+		if i == len(s.current.Validators) || s.current.Validators[i].Validator != id {
+			s.current.Validators = append(s.current.Validators, ValidatorContribution{})
+			copy(s.current.Validators[i+1:], s.current.Validators[i:])
+			s.current.Validators[i] = ValidatorContribution{Validator: id}
+		}
+		s.current.Validators[i].Transactions++
+		s.current.Validators[i].Bytes += uint64(len(tx.Data))
+	}
+	return nil
+}
+
+func (s *contributionStats) flush(ctx context.Context) error {
+	if s.current == nil {
+		return nil
+	}
+	buf := new(bytes.Buffer)
+	if err := s.current.MarshalCBOR(buf); err != nil {
+		return err
+	}
+	if err := s.ds.Put(ctx, ContributionsKey(s.current.Epoch), buf.Bytes()); err != nil {
+		return xerrors.Errorf("error persisting contributions of epoch %d: %w", s.current.Epoch, err)
+	}
+	return nil
+}
+
+func (s *contributionStats) load(ctx context.Context, epoch uint64) (*EpochContributions, error) {
+	b, err := s.ds.Get(ctx, ContributionsKey(epoch))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c EpochContributions
+	if err := c.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return nil, xerrors.Errorf("error decoding contributions of epoch %d: %w", epoch, err)
+	}
+	return &c, nil
+}
+
+// epochs returns the summaries of the epochs in [from, to], including the one being ordered.
+func (s *contributionStats) epochs(ctx context.Context, from, to uint64) ([]EpochContributions, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	var res []EpochContributions
+	for e := from; e <= to; e++ {
+		if s.current != nil && s.current.Epoch == e {
+			c := *s.current
+			c.Validators = append([]ValidatorContribution(nil), s.current.Validators...)
+			res = append(res, c)
+			continue
+		}
+		c, err := s.load(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			res = append(res, *c)
+		}
+	}
+	return res, nil
+}
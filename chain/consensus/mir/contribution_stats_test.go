@@ -0,0 +1,51 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+)
+
+func TestContributionStats(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+	s := newContributionStats(ds)
+
+	batch := []*mirproto.Transaction{
+		{ClientId: "id2", TxNo: 1, Type: TransportTransaction, Data: make([]byte, 10)},
+		{ClientId: "id1", TxNo: 1, Type: TransportTransaction, Data: make([]byte, 5)},
+		{ClientId: "id2", TxNo: 2, Type: TransportTransaction, Data: make([]byte, 10)},
+		{ClientId: "id3", TxNo: 1, Type: ConfigurationTransaction, Data: make([]byte, 100)},
+	}
+	require.NoError(t, s.record(ctx, 1, 10, batch))
+	require.NoError(t, s.record(ctx, 1, 11, batch[:1]))
+
+	epochs, err := s.epochs(ctx, 0, 1)
+	require.NoError(t, err)
+	require.Equal(t, []EpochContributions{{
+		Epoch:       1,
+		FirstHeight: 10,
+		LastHeight:  11,
+		Validators: []ValidatorContribution{
+			{Validator: "id1", Transactions: 1, Bytes: 5},
+			{Validator: "id2", Transactions: 3, Bytes: 30},
+		},
+	}}, epochs)
+
+	// The summary of an epoch is persisted once the next epoch starts.
+	require.NoError(t, s.record(ctx, 2, 12, batch[1:2]))
+	persisted, err := newContributionStats(ds).epochs(ctx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, epochs, persisted)
+
+	// Batches delivered again after a restart aren't counted twice.
+	s = newContributionStats(ds)
+	require.NoError(t, s.record(ctx, 1, 11, batch))
+	again, err := s.epochs(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, epochs, again)
+}
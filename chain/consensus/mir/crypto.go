@@ -11,6 +11,7 @@ import (
 	t "github.com/filecoin-project/mir/pkg/types"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/lightclient"
 
 	// Required for signature verification support
 	"github.com/filecoin-project/lotus/lib/sigs"
@@ -76,11 +77,8 @@ func verifySig(data [][]byte, sigBytes []byte, nodeID string) error {
 	return sigs.Verify(&sig, addr, hash(data))
 }
 
-type CheckpointVerifier struct{}
-
-func (CheckpointVerifier) Verify(data [][]byte, signature []byte, nodeID t.NodeID) error {
-	return verifySig(data, signature, nodeID.Pb())
-}
+// CheckpointVerifier verifies the signatures of checkpoint certificates. It is shared with light clients.
+type CheckpointVerifier = lightclient.SignatureVerifier
 
 func hash(data [][]byte) []byte {
 	h := sha256.New()
@@ -0,0 +1,64 @@
+package mir
+
+import (
+	"context"
+	"time"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+var (
+	// DaemonPingTimeout is the time the daemon has to answer a health check.
+	DaemonPingTimeout = 5 * time.Second
+	// DaemonRetryMinBackoff and DaemonRetryMaxBackoff bound the time between two health checks
+	// of a daemon that doesn't answer.
+	DaemonRetryMinBackoff = time.Second
+	DaemonRetryMaxBackoff = 30 * time.Second
+)
+
+// DaemonPinger is the part of the full node API used to check that the daemon is reachable.
+type DaemonPinger interface {
+	Version(ctx context.Context) (api.APIVersion, error)
+}
+
+// DaemonHealthy returns whether the daemon answers a health check within DaemonPingTimeout.
+func DaemonHealthy(ctx context.Context, d DaemonPinger) bool {
+	ctx, cancel := context.WithTimeout(ctx, DaemonPingTimeout)
+	defer cancel()
+	_, err := d.Version(ctx)
+	return err == nil
+}
+
+// WaitForDaemon blocks until the daemon answers a health check, backing off exponentially
+// between checks. It returns the error of ctx if it is done first.
+func WaitForDaemon(ctx context.Context, d DaemonPinger) error {
+	backoff := DaemonRetryMinBackoff
+	for !DaemonHealthy(ctx, d) {
+		log.Warnf("daemon unreachable, checking again in %s", backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > DaemonRetryMaxBackoff {
+			backoff = DaemonRetryMaxBackoff
+		}
+	}
+	return nil
+}
+
+// RunWithDaemon runs run once the daemon is reachable, and runs it again when it stops with an
+// error while the daemon is unreachable, e.g. because the daemon was restarted, once the daemon
+// is back. The remaining errors are returned, so they are handled by the failure policy of the validator.
+func RunWithDaemon(ctx context.Context, d DaemonPinger, run func(context.Context) error) error {
+	for {
+		if err := WaitForDaemon(ctx, d); err != nil {
+			return nil
+		}
+		err := run(ctx)
+		if err == nil || ctx.Err() != nil || DaemonHealthy(ctx, d) {
+			return err
+		}
+		log.Warnf("stopped while the daemon is unreachable: %v; restarting once it is back", err)
+	}
+}
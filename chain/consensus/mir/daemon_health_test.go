@@ -0,0 +1,69 @@
+package mir
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+// fakeDaemon fails the next down health checks.
+type fakeDaemon struct {
+	down  int
+	pings int
+}
+
+func (d *fakeDaemon) Version(context.Context) (api.APIVersion, error) {
+	d.pings++
+	if d.down > 0 {
+		d.down--
+		return api.APIVersion{}, errors.New("connection refused")
+	}
+	return api.APIVersion{}, nil
+}
+
+func TestRunWithDaemon(t *testing.T) {
+	DaemonRetryMinBackoff = time.Millisecond
+	DaemonRetryMaxBackoff = time.Millisecond
+	errFailed := errors.New("failed")
+
+	t.Run("restart after daemon restart", func(t *testing.T) {
+		d := &fakeDaemon{down: 2}
+		runs := 0
+		err := RunWithDaemon(context.Background(), d, func(context.Context) error {
+			runs++
+			if runs == 1 {
+				// The daemon goes down under the validator for a few health checks.
+				d.down = 3
+				return errFailed
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, runs)
+		require.Equal(t, 7, d.pings)
+	})
+
+	t.Run("failure with healthy daemon", func(t *testing.T) {
+		d := &fakeDaemon{}
+		runs := 0
+		err := RunWithDaemon(context.Background(), d, func(context.Context) error {
+			runs++
+			return errFailed
+		})
+		require.ErrorIs(t, err, errFailed)
+		require.Equal(t, 1, runs)
+	})
+
+	t.Run("wait for daemon", func(t *testing.T) {
+		d := &fakeDaemon{down: 1 << 30}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, WaitForDaemon(ctx, d), context.DeadlineExceeded)
+		require.Greater(t, d.pings, 1)
+	})
+}
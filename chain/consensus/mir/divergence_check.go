@@ -0,0 +1,117 @@
+package mir
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"go.opencensus.io/stats"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api/v1api"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+// DivergenceCheckInterval is the period of the check of the chain of the node against the latest checkpoint.
+var DivergenceCheckInterval = time.Minute
+
+// divergenceCheck periodically checks that the chain of the node includes the blocks committed
+// by the latest checkpoint certified by the validators.
+//
+// A validator whose chain diverges from the checkpoints keeps ordering batches, but the blocks it
+// creates are built on a different chain from the rest of validators. When the divergence is detected
+// the validator is flagged as unhealthy and the checkpoint is sent to Diverged, so the manager stops
+// proposing and restores the chain from the checkpoint.
+type divergenceCheck struct {
+	ctx   context.Context
+	api   v1api.FullNode
+	heads *headTracker
+	ds    db.DB
+	id    string
+
+	diverged chan *Checkpoint
+}
+
+func newDivergenceCheck(ctx context.Context, api v1api.FullNode, heads *headTracker, ds db.DB, id string) *divergenceCheck {
+	return &divergenceCheck{
+		ctx:      ctx,
+		api:      api,
+		heads:    heads,
+		ds:       ds,
+		id:       id,
+		diverged: make(chan *Checkpoint, 1),
+	}
+}
+
+// Diverged returns the channel where the checkpoint the chain diverges from is sent.
+func (d *divergenceCheck) Diverged() <-chan *Checkpoint {
+	return d.diverged
+}
+
+func (d *divergenceCheck) run() {
+	ticker := time.NewTicker(DivergenceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			ch, err := d.check()
+			if err != nil {
+				log.With("validator", d.id).Warnf("failed to check the chain against the latest checkpoint: %v", err)
+				continue
+			}
+			if ch != nil {
+				select {
+				case d.diverged <- ch:
+				default:
+				}
+				return
+			}
+		}
+	}
+}
+
+// check returns the latest checkpoint if the chain of the node doesn't include the blocks it commits.
+func (d *divergenceCheck) check() (*Checkpoint, error) {
+	b, err := d.ds.Get(d.ctx, LatestCheckpointKey)
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("error getting latest checkpoint: %w", err)
+	}
+	ch := &Checkpoint{}
+	if err := ch.FromBytes(b); err != nil {
+		return nil, xerrors.Errorf("error decoding latest checkpoint: %w", err)
+	}
+	if len(ch.BlockCids) == 0 {
+		return nil, nil
+	}
+
+	// The parents of a block commit to all its ancestors, so checking the last block of the checkpoint is enough.
+	h := ch.Height - 1
+	head, err := d.heads.Head(d.ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting chain head: %w", err)
+	}
+	if head.Height() < h {
+		// The node is still syncing the blocks of the checkpoint.
+		return nil, nil
+	}
+	ts, err := d.api.ChainGetTipSetByHeight(d.ctx, h, head.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("error getting tipset at height %d: %w", h, err)
+	}
+	if ts.Height() == h && ts.Contains(ch.BlockCids[0]) {
+		return nil, nil
+	}
+
+	stats.Record(d.ctx, metrics.MirChainDivergences.M(1))
+	log.With("validator", d.id).Errorf("validator unhealthy: chain at height %d is %s, the checkpoint at height %d commits %s",
+		h, ts.Key(), ch.Height, ch.BlockCids[0])
+	return ch, nil
+}
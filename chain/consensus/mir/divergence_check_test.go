@@ -0,0 +1,58 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api/mocks"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestDivergenceCheck(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	node := mocks.NewMockFullNode(ctrl)
+	ds := datastore.NewMapDatastore()
+
+	var chain []*types.TipSet
+	var ts *types.TipSet
+	for i := 0; i <= 5; i++ {
+		ts = mock.TipSet(mock.MkBlock(ts, 1, 1))
+		chain = append(chain, ts)
+	}
+	fork := mock.TipSet(mock.MkBlock(chain[2], 1, 2))
+
+	d := newDivergenceCheck(ctx, node, newHeadTracker(ctx, node, "validator"), ds, "validator")
+
+	// No checkpoint yet.
+	ch, err := d.check()
+	require.NoError(t, err)
+	require.Nil(t, ch)
+
+	snap := &Checkpoint{
+		Height:    chain[4].Height() + 1,
+		Parent:    ParentMeta{Height: chain[3].Height(), Cid: chain[3].Cids()[0]},
+		BlockCids: []cid.Cid{chain[4].Cids()[0], chain[3].Cids()[0]},
+	}
+	b, err := snap.Bytes()
+	require.NoError(t, err)
+	require.NoError(t, ds.Put(ctx, LatestCheckpointKey, b))
+
+	node.EXPECT().ChainHead(gomock.Any()).Return(chain[5], nil).Times(2)
+	node.EXPECT().ChainGetTipSetByHeight(gomock.Any(), chain[4].Height(), chain[5].Key()).Return(chain[4], nil)
+	ch, err = d.check()
+	require.NoError(t, err)
+	require.Nil(t, ch)
+
+	node.EXPECT().ChainGetTipSetByHeight(gomock.Any(), chain[4].Height(), chain[5].Key()).Return(fork, nil)
+	ch, err = d.check()
+	require.NoError(t, err)
+	require.Equal(t, snap.Height, ch.Height)
+}
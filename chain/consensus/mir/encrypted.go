@@ -0,0 +1,293 @@
+package mir
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// Encrypted transactions implement a commit-reveal scheme that hides the content of
+// a message until it has been ordered by Mir:
+//
+//  1. The client encrypts its signed message with a fresh symmetric key and submits
+//     the ciphertext, bound to the key through a commitment (the hash of the key).
+//  2. Validators order the ciphertext without being able to inspect it.
+//  3. Once the ciphertext has been included in a batch, the client submits the key.
+//     A key reveal is only accepted for ciphertexts ordered in a previous batch, and
+//     the decrypted message is executed in the block created for the reveal batch.
+
+const (
+	// EncryptionKeySize is the size of the symmetric key used to encrypt a message.
+	EncryptionKeySize = 32
+	// EncryptedTxRevealWindow is the number of blocks a ciphertext waits for its key
+	// to be revealed before being discarded.
+	EncryptedTxRevealWindow = 100
+)
+
+// Commitment binds a ciphertext to the key that decrypts it.
+type Commitment [sha256.Size]byte
+
+// EncryptedMessage is a signed message encrypted with AES-GCM.
+type EncryptedMessage struct {
+	Commitment Commitment
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// KeyReveal discloses the key used to encrypt an EncryptedMessage.
+type KeyReveal struct {
+	Key []byte
+}
+
+// EncryptMessage encrypts a signed message with a fresh key. The returned reveal must be
+// kept by the client and submitted once the encrypted message has been ordered.
+func EncryptMessage(msg *types.SignedMessage) (*EncryptedMessage, *KeyReveal, error) {
+	b, err := msg.Serialize()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to serialize message: %w", err)
+	}
+
+	key := make([]byte, EncryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, fmt.Errorf("unable to generate key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("unable to generate nonce: %w", err)
+	}
+
+	reveal := &KeyReveal{Key: key}
+	enc := &EncryptedMessage{
+		Commitment: reveal.Commitment(),
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, b, nil),
+	}
+	return enc, reveal, nil
+}
+
+// Decrypt decrypts the message with the revealed key.
+func (m *EncryptedMessage) Decrypt(r *KeyReveal) (*types.SignedMessage, error) {
+	if r.Commitment() != m.Commitment {
+		return nil, fmt.Errorf("revealed key doesn't match the commitment")
+	}
+	aead, err := newAEAD(r.Key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := aead.Open(nil, m.Nonce, m.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt message: %w", err)
+	}
+	return types.DecodeSignedMessage(b)
+}
+
+// Serialize encodes the message as commitment || nonce || ciphertext.
+func (m *EncryptedMessage) Serialize() ([]byte, error) {
+	var b bytes.Buffer
+	b.Write(m.Commitment[:])
+	b.Write(m.Nonce)
+	b.Write(m.Ciphertext)
+	return b.Bytes(), nil
+}
+
+func DecodeEncryptedMessage(b []byte) (*EncryptedMessage, error) {
+	// The nonce size of AES-GCM is fixed.
+	nonceSize := 12
+	if len(b) <= len(Commitment{})+nonceSize {
+		return nil, fmt.Errorf("encrypted message len %d is too small", len(b))
+	}
+	var m EncryptedMessage
+	n := copy(m.Commitment[:], b)
+	m.Nonce = append([]byte{}, b[n:n+nonceSize]...)
+	m.Ciphertext = append([]byte{}, b[n+nonceSize:]...)
+	return &m, nil
+}
+
+// Commitment returns the commitment of the revealed key.
+func (r *KeyReveal) Commitment() Commitment {
+	return sha256.Sum256(r.Key)
+}
+
+func (r *KeyReveal) Serialize() ([]byte, error) {
+	return append([]byte{}, r.Key...), nil
+}
+
+func DecodeKeyReveal(b []byte) (*KeyReveal, error) {
+	if len(b) != EncryptionKeySize {
+		return nil, fmt.Errorf("invalid key len %d", len(b))
+	}
+	return &KeyReveal{Key: append([]byte{}, b...)}, nil
+}
+
+// ValidateEncryptedTx checks that tx is a well-formed encrypted message or key reveal.
+func ValidateEncryptedTx(tx []byte) error {
+	msg, err := parseTx(tx)
+	if err != nil {
+		return err
+	}
+	switch msg.(type) {
+	case *EncryptedMessage, *KeyReveal:
+		return nil
+	default:
+		return fmt.Errorf("not an encrypted message or key reveal")
+	}
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+type sealedMessage struct {
+	msg    *EncryptedMessage
+	height abi.ChainEpoch
+}
+
+// sealedID identifies a ciphertext of a commitment by the hash of its nonce and ciphertext.
+type sealedID [sha256.Size]byte
+
+// sealedMessages keeps track of the ordered ciphertexts that are waiting for their keys.
+//
+// The commitment is public as soon as a ciphertext is ordered, so anyone can order other ciphertexts
+// with the same commitment. They are all kept, identified by their hash, and the reveal opens the one
+// that decrypts with the key: only the owner of the key can produce it, so a ciphertext ordered first
+// with a copied commitment can't take the place of the real one.
+type sealedMessages struct {
+	msgs map[Commitment]map[sealedID]sealedMessage
+}
+
+func newSealedMessages() *sealedMessages {
+	return &sealedMessages{msgs: make(map[Commitment]map[sealedID]sealedMessage)}
+}
+
+func sealedMessageID(m *EncryptedMessage) sealedID {
+	h := sha256.New()
+	h.Write(m.Nonce)
+	h.Write(m.Ciphertext)
+	var id sealedID
+	copy(id[:], h.Sum(nil))
+	return id
+}
+
+// add records a ciphertext ordered at the given height.
+// A ciphertext that is already pending keeps the height it was first ordered at.
+func (s *sealedMessages) add(m *EncryptedMessage, height abi.ChainEpoch) {
+	id := sealedMessageID(m)
+	pending, found := s.msgs[m.Commitment]
+	if !found {
+		pending = make(map[sealedID]sealedMessage)
+		s.msgs[m.Commitment] = pending
+	}
+	if _, found := pending[id]; !found {
+		pending[id] = sealedMessage{msg: m, height: height}
+	}
+}
+
+// open decrypts the ciphertext matching the reveal if it was ordered before the given height.
+// The ciphertexts with the commitment ordered before the height are tried in the order of their
+// hashes, and they are all discarded: once the key is public, none of them is sealed anymore.
+func (s *sealedMessages) open(r *KeyReveal, height abi.ChainEpoch) (*types.SignedMessage, error) {
+	c := r.Commitment()
+	ids := s.ids(c, height)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no encrypted message for commitment %x ordered before the current batch", c)
+	}
+
+	var err error
+	for _, id := range ids {
+		var msg *types.SignedMessage
+		if msg, err = s.msgs[c][id].msg.Decrypt(r); err == nil {
+			s.dropRevealed(c, ids)
+			return msg, nil
+		}
+	}
+	s.dropRevealed(c, ids)
+	return nil, err
+}
+
+// ids returns the hashes of the ciphertexts with the commitment ordered before the given height, sorted.
+func (s *sealedMessages) ids(c Commitment, height abi.ChainEpoch) []sealedID {
+	var ids []sealedID
+	for id, sealed := range s.msgs[c] {
+		if sealed.height < height {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+	return ids
+}
+
+// dropRevealed discards the ciphertexts of the commitment whose key has been revealed.
+func (s *sealedMessages) dropRevealed(c Commitment, ids []sealedID) {
+	for _, id := range ids {
+		delete(s.msgs[c], id)
+	}
+	if len(s.msgs[c]) == 0 {
+		delete(s.msgs, c)
+	}
+}
+
+// prune discards ciphertexts whose key was not revealed within the reveal window.
+func (s *sealedMessages) prune(height abi.ChainEpoch) {
+	for c, pending := range s.msgs {
+		for id, sealed := range pending {
+			if height-sealed.height > EncryptedTxRevealWindow {
+				delete(pending, id)
+			}
+		}
+		if len(pending) == 0 {
+			delete(s.msgs, c)
+		}
+	}
+}
+
+// records returns the sealed messages ordered by commitment and hash, so that all the validators
+// include the same records in their checkpoints.
+func (s *sealedMessages) records() (SealedMessageRecords, error) {
+	cs := make([]Commitment, 0, len(s.msgs))
+	for c := range s.msgs {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool { return bytes.Compare(cs[i][:], cs[j][:]) < 0 })
+
+	var r SealedMessageRecords
+	for _, c := range cs {
+		for _, id := range s.ids(c, math.MaxInt64) {
+			sealed := s.msgs[c][id]
+			b, err := sealed.msg.Serialize()
+			if err != nil {
+				return SealedMessageRecords{}, err
+			}
+			r.Records = append(r.Records, SealedMessageRecord{Height: sealed.height, Msg: b})
+		}
+	}
+	return r, nil
+}
+
+func newSealedMessagesFromRecords(r SealedMessageRecords) (*sealedMessages, error) {
+	s := newSealedMessages()
+	for _, rec := range r.Records {
+		m, err := DecodeEncryptedMessage(rec.Msg)
+		if err != nil {
+			return nil, err
+		}
+		s.add(m, rec.Height)
+	}
+	return s, nil
+}
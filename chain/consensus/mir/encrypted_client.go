@@ -0,0 +1,222 @@
+package mir
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/filecoin-project/mir/pkg/pb/trantorpb"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	"github.com/filecoin-project/mir/pkg/trantor/types"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/pool/encrypted"
+)
+
+const EncryptedTxDBPrefix = "mir/encrypted/"
+
+var (
+	// NextEncryptedTxNoKey is used to store the number of the next encrypted transaction proposed by the validator.
+	NextEncryptedTxNoKey = datastore.NewKey("mir/next-encrypted-tx-number")
+	// FirstPendingEncryptedTxNoKey is used to store the number of the oldest encrypted transaction
+	// proposed by the validator that has not been delivered yet.
+	FirstPendingEncryptedTxNoKey = datastore.NewKey("mir/first-pending-encrypted-tx-number")
+)
+
+// encryptedTxsClientID returns the ID of the Mir client used by the validator to propose encrypted transactions.
+func encryptedTxsClientID(validatorID string) types.ClientID {
+	return types.ClientID(validatorID + "/encrypted")
+}
+
+// encryptedTxsClient assigns Mir transaction numbers to the encrypted transactions proposed by the validator.
+//
+// All the encrypted transactions of the validator are proposed by a single Mir client, so the client
+// state tracked by Mir in checkpoints does not grow with the number of encrypted transactions.
+// Mir delivers the transactions of a client in order of their numbers, so a transaction that has been
+// assigned a number is persisted and proposed in every batch until it is delivered.
+//
+// The pool of the node keeps the encrypted transactions until they expire, so delivered transactions are
+// remembered for that time to avoid proposing them again. If the validator restarts, a delivered transaction
+// that is still in the pool may be proposed and delivered again: duplicated ciphertexts are ignored while they
+// wait for their key, and duplicated key reveals don't match any ciphertext.
+type encryptedTxsClient struct {
+	ctx      context.Context
+	ds       db.DB
+	id       string
+	clientID types.ClientID
+
+	lk           sync.Mutex
+	nextTxNo     uint64
+	firstPending uint64
+	pending      map[uint64]*mirproto.Transaction
+	pendingData  map[[sha256.Size]byte]struct{}
+	delivered    map[[sha256.Size]byte]time.Time
+}
+
+func newEncryptedTxsClient(ctx context.Context, ds db.DB, id string) (*encryptedTxsClient, error) {
+	c := &encryptedTxsClient{
+		ctx:         ctx,
+		ds:          ds,
+		id:          id,
+		clientID:    encryptedTxsClientID(id),
+		pending:     make(map[uint64]*mirproto.Transaction),
+		pendingData: make(map[[sha256.Size]byte]struct{}),
+		delivered:   make(map[[sha256.Size]byte]time.Time),
+	}
+	if err := c.recover(); err != nil {
+		return nil, fmt.Errorf("validator %v failed to recover encrypted txs: %w", id, err)
+	}
+	return c, nil
+}
+
+// Propose assigns numbers to the new encrypted transactions of the pool, and returns up to max transactions
+// that have not been delivered yet, in the order of their numbers.
+func (c *encryptedTxsClient) Propose(etxs [][]byte, max int) []*mirproto.Transaction {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	for h, tm := range c.delivered {
+		if time.Since(tm) > encrypted.DefaultTxTTL {
+			delete(c.delivered, h)
+		}
+	}
+
+	// Only assign numbers to the transactions that can be proposed in this batch,
+	// to avoid leaving gaps in the numbers delivered by Mir.
+	for _, data := range etxs {
+		if len(c.pending) >= max {
+			break
+		}
+		h := sha256.Sum256(data)
+		if _, found := c.pendingData[h]; found {
+			continue
+		}
+		if _, found := c.delivered[h]; found {
+			continue
+		}
+		tx := &mirproto.Transaction{
+			ClientId: c.clientID,
+			TxNo:     types.TxNo(c.nextTxNo),
+			Type:     TransportTransaction,
+			Data:     data,
+		}
+		if err := c.storeTx(tx); err != nil {
+			log.With("validator", c.id).Errorf("unable to store encrypted tx: %v", err)
+			break
+		}
+		c.pending[c.nextTxNo] = tx
+		c.pendingData[h] = struct{}{}
+		c.nextTxNo++
+		c.storeNumber(NextEncryptedTxNoKey, c.nextTxNo)
+	}
+
+	txNos := make([]uint64, 0, len(c.pending))
+	for n := range c.pending {
+		txNos = append(txNos, n)
+	}
+	sort.Slice(txNos, func(i, j int) bool { return txNos[i] < txNos[j] })
+	if len(txNos) > max {
+		txNos = txNos[:max]
+	}
+
+	txs := make([]*mirproto.Transaction, len(txNos))
+	for i, n := range txNos {
+		txs[i] = c.pending[n]
+	}
+	return txs
+}
+
+// Delivered marks a transaction of the client as delivered, so it is not proposed anymore.
+func (c *encryptedTxsClient) Delivered(tx *mirproto.Transaction) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	n := tx.TxNo.Pb()
+	if _, found := c.pending[n]; !found {
+		return
+	}
+	h := sha256.Sum256(tx.Data)
+	delete(c.pending, n)
+	delete(c.pendingData, h)
+	c.delivered[h] = time.Now()
+	if err := c.ds.Delete(c.ctx, encryptedTxKey(n)); err != nil {
+		log.With("validator", c.id).Warnf("failed to remove delivered encrypted tx %d: %v", n, err)
+	}
+
+	for c.firstPending < c.nextTxNo {
+		if _, found := c.pending[c.firstPending]; found {
+			break
+		}
+		c.firstPending++
+	}
+	c.storeNumber(FirstPendingEncryptedTxNoKey, c.firstPending)
+}
+
+// recover loads the transactions that were proposed and not delivered before the validator stopped.
+func (c *encryptedTxsClient) recover() error {
+	var err error
+	if c.nextTxNo, err = c.getNumber(NextEncryptedTxNoKey); err != nil {
+		return err
+	}
+	if c.firstPending, err = c.getNumber(FirstPendingEncryptedTxNoKey); err != nil {
+		return err
+	}
+	for n := c.firstPending; n < c.nextTxNo; n++ {
+		b, err := c.ds.Get(c.ctx, encryptedTxKey(n))
+		if errors.Is(err, datastore.ErrNotFound) {
+			// Delivered before the validator stopped.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var r trantorpb.Transaction
+		if err := proto.Unmarshal(b, &r); err != nil {
+			return err
+		}
+		tx := mirproto.TransactionFromPb(&r)
+		c.pending[n] = tx
+		c.pendingData[sha256.Sum256(tx.Data)] = struct{}{}
+	}
+	return nil
+}
+
+func (c *encryptedTxsClient) storeTx(tx *mirproto.Transaction) error {
+	v, err := proto.Marshal(tx.Pb())
+	if err != nil {
+		return err
+	}
+	return c.ds.Put(c.ctx, encryptedTxKey(tx.TxNo.Pb()), v)
+}
+
+func (c *encryptedTxsClient) storeNumber(key datastore.Key, n uint64) {
+	rb := make([]byte, 8)
+	binary.LittleEndian.PutUint64(rb, n)
+	if err := c.ds.Put(c.ctx, key, rb); err != nil {
+		log.With("validator", c.id).Warnf("failed to put encrypted tx number by %s: %v", key, err)
+	}
+}
+
+func (c *encryptedTxsClient) getNumber(key datastore.Key) (uint64, error) {
+	b, err := c.ds.Get(c.ctx, key)
+	if errors.Is(err, datastore.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func encryptedTxKey(n uint64) datastore.Key {
+	return datastore.NewKey(EncryptedTxDBPrefix + strconv.FormatUint(n, 10))
+}
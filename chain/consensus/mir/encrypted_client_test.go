@@ -0,0 +1,57 @@
+package mir
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mirkv "github.com/filecoin-project/lotus/chain/consensus/mir/db/kv"
+)
+
+func TestEncryptedTxsClient(t *testing.T) {
+	ctx := context.Background()
+	ds, err := mirkv.NewLevelDB(filepath.Join(t.TempDir(), "db"), false)
+	require.NoError(t, err)
+	defer ds.Close() // nolint
+
+	c, err := newEncryptedTxsClient(ctx, ds, "id1")
+	require.NoError(t, err)
+
+	etxs := [][]byte{[]byte("tx0"), []byte("tx1"), []byte("tx2")}
+
+	// Only max transactions get a number.
+	txs := c.Propose(etxs, 2)
+	require.Len(t, txs, 2)
+	require.Equal(t, encryptedTxsClientID("id1"), txs[0].ClientId)
+	require.Equal(t, uint64(0), txs[0].TxNo.Pb())
+	require.Equal(t, uint64(1), txs[1].TxNo.Pb())
+
+	// Transactions are proposed again until they are delivered.
+	txs = c.Propose(etxs, 2)
+	require.Len(t, txs, 2)
+	require.Equal(t, []byte("tx0"), txs[0].Data)
+
+	c.Delivered(txs[0])
+	txs = c.Propose(etxs, 2)
+	require.Len(t, txs, 2)
+	require.Equal(t, []byte("tx1"), txs[0].Data)
+	require.Equal(t, []byte("tx2"), txs[1].Data)
+	require.Equal(t, uint64(2), txs[1].TxNo.Pb())
+
+	// Pending transactions survive a restart.
+	c, err = newEncryptedTxsClient(ctx, ds, "id1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), c.firstPending)
+	require.Equal(t, uint64(3), c.nextTxNo)
+	txs = c.Propose(nil, 10)
+	require.Len(t, txs, 2)
+	require.Equal(t, []byte("tx1"), txs[0].Data)
+
+	c.Delivered(txs[1])
+	require.Equal(t, uint64(1), c.firstPending)
+	c.Delivered(txs[0])
+	require.Equal(t, uint64(3), c.firstPending)
+	require.Len(t, c.Propose(etxs[1:], 10), 0)
+}
@@ -0,0 +1,156 @@
+package mir
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func newTestSignedMessage(t testing.TB, nonce uint64) *types.SignedMessage {
+	from, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	to, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	return &types.SignedMessage{
+		Message: types.Message{
+			To:         to,
+			From:       from,
+			Nonce:      nonce,
+			Value:      big.NewInt(10),
+			GasLimit:   1000,
+			GasFeeCap:  big.Zero(),
+			GasPremium: big.Zero(),
+		},
+		Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1, Data: []byte("signature")},
+	}
+}
+
+func TestEncryptedMessage(t *testing.T) {
+	msg := newTestSignedMessage(t, 1)
+
+	enc, reveal, err := EncryptMessage(msg)
+	require.NoError(t, err)
+
+	b, err := MessageBytes(enc)
+	require.NoError(t, err)
+	require.NoError(t, ValidateEncryptedTx(b))
+	input, err := parseTx(b)
+	require.NoError(t, err)
+	enc = input.(*EncryptedMessage)
+
+	b, err = MessageBytes(reveal)
+	require.NoError(t, err)
+	require.NoError(t, ValidateEncryptedTx(b))
+	input, err = parseTx(b)
+	require.NoError(t, err)
+	reveal = input.(*KeyReveal)
+
+	decrypted, err := enc.Decrypt(reveal)
+	require.NoError(t, err)
+	require.Equal(t, msg.Cid(), decrypted.Cid())
+
+	_, otherReveal, err := EncryptMessage(msg)
+	require.NoError(t, err)
+	_, err = enc.Decrypt(otherReveal)
+	require.Error(t, err)
+
+	b, err = MessageBytes(msg)
+	require.NoError(t, err)
+	require.Error(t, ValidateEncryptedTx(b))
+}
+
+func TestSealedMessages(t *testing.T) {
+	s := newSealedMessages()
+
+	msg := newTestSignedMessage(t, 1)
+	enc, reveal, err := EncryptMessage(msg)
+	require.NoError(t, err)
+
+	// The key can't be revealed in the batch the ciphertext was ordered.
+	s.add(enc, 1)
+	_, err = s.open(reveal, 1)
+	require.Error(t, err)
+
+	decrypted, err := s.open(reveal, 2)
+	require.NoError(t, err)
+	require.Equal(t, msg.Cid(), decrypted.Cid())
+
+	// The ciphertext can only be opened once.
+	_, err = s.open(reveal, 3)
+	require.Error(t, err)
+
+	// Ciphertexts are discarded after the reveal window.
+	s.add(enc, 3)
+	s.prune(3 + EncryptedTxRevealWindow + 1)
+	_, err = s.open(reveal, 3+EncryptedTxRevealWindow+1)
+	require.Error(t, err)
+}
+
+func TestSealedMessagesSameCommitment(t *testing.T) {
+	s := newSealedMessages()
+
+	msg := newTestSignedMessage(t, 1)
+	enc, reveal, err := EncryptMessage(msg)
+	require.NoError(t, err)
+
+	// A ciphertext ordered first with a copied commitment doesn't take the place of the real one.
+	forged := &EncryptedMessage{Commitment: enc.Commitment, Nonce: enc.Nonce, Ciphertext: []byte("garbage")}
+	s.add(forged, 1)
+	s.add(enc, 2)
+	s.add(enc, 2)
+
+	r, err := s.records()
+	require.NoError(t, err)
+	require.Len(t, r.Records, 2)
+
+	decrypted, err := s.open(reveal, 3)
+	require.NoError(t, err)
+	require.Equal(t, msg.Cid(), decrypted.Cid())
+	_, err = s.open(reveal, 4)
+	require.Error(t, err)
+	require.Empty(t, s.msgs)
+
+	// The reveal discards the ciphertexts of the commitment even if none of them decrypts.
+	s.add(forged, 5)
+	_, err = s.open(reveal, 6)
+	require.Error(t, err)
+	require.Empty(t, s.msgs)
+}
+
+func TestSealedMessagesRecords(t *testing.T) {
+	s := newSealedMessages()
+
+	msg1, msg2 := newTestSignedMessage(t, 1), newTestSignedMessage(t, 2)
+	enc1, reveal1, err := EncryptMessage(msg1)
+	require.NoError(t, err)
+	enc2, _, err := EncryptMessage(msg2)
+	require.NoError(t, err)
+	s.add(enc1, 1)
+	s.add(enc2, 2)
+
+	r, err := s.records()
+	require.NoError(t, err)
+	require.Len(t, r.Records, 2)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.MarshalCBOR(&buf))
+	var decoded SealedMessageRecords
+	require.NoError(t, decoded.UnmarshalCBOR(&buf))
+	require.Equal(t, r, decoded)
+
+	// Messages restored from the records keep the height they were ordered at.
+	restored, err := newSealedMessagesFromRecords(decoded)
+	require.NoError(t, err)
+	_, err = restored.open(reveal1, 1)
+	require.Error(t, err)
+	decrypted, err := restored.open(reveal1, 2)
+	require.NoError(t, err)
+	require.Equal(t, msg1.Cid(), decrypted.Cid())
+}
@@ -0,0 +1,138 @@
+package mir
+
+import (
+	"sync"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// blockDraft is the content of a block ordered by Mir, before the block is created on top of its parent.
+//
+// Everything in the draft is derived from the batches ordered so far, so it is computed as soon as the
+// batch is delivered. Creating the block requires the state of its parent, and so executing the parent.
+type blockDraft struct {
+	l   *zap.SugaredLogger
+	txs []*mirproto.Transaction
+
+	height   abi.ChainEpoch
+	msgs     []*types.SignedMessage
+	ticket   *types.Ticket
+	eproof   *types.ElectionProof
+	votedSet *validator.Set
+
+	// Inputs of the timestamp of the block, taken when the batch was delivered.
+	batchTimestamps bool
+	timestamps      []uint64
+	validators      int
+}
+
+// executionPipeline creates the blocks of the batches delivered by Mir in its own goroutine, so Mir keeps
+// ordering up to ExecutionLag batches while the validator executes the blocks ordered before them.
+//
+// The blocks are the same as the ones created without a lag: the lag bounds how far ordering runs ahead of
+// execution, which absorbs bursts of expensive blocks without stalling the ordering of the next batches.
+type executionPipeline struct {
+	queue chan queuedDraft
+	// Drafts enqueued and not processed yet.
+	pending sync.WaitGroup
+
+	lk sync.Mutex
+	// Drafts enqueued before the last flush are dropped.
+	generation uint64
+	// The first error creating a block, returned with the next batch delivered by Mir.
+	err error
+}
+
+type queuedDraft struct {
+	*blockDraft
+	generation uint64
+}
+
+func newExecutionPipeline(lag int) *executionPipeline {
+	return &executionPipeline{queue: make(chan queuedDraft, lag)}
+}
+
+// current returns whether a draft enqueued in the given generation has to be executed.
+func (p *executionPipeline) current(generation uint64) bool {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	return generation == p.generation && p.err == nil
+}
+
+// Err returns the first error creating a block since the last flush, if any.
+func (p *executionPipeline) Err() error {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	return p.err
+}
+
+func (p *executionPipeline) fail(generation uint64, err error) {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	if generation == p.generation && p.err == nil {
+		p.err = err
+	}
+}
+
+// runExecution creates the blocks of the drafts in the order they are enqueued.
+func (sm *StateManager) runExecution() {
+	p := sm.execution
+	for {
+		select {
+		case <-sm.ctx.Done():
+			return
+		case d := <-p.queue:
+			if p.current(d.generation) {
+				if err := sm.createBlock(d.blockDraft); err != nil {
+					p.fail(d.generation, err)
+				}
+			}
+			p.pending.Done()
+		}
+	}
+}
+
+// executeBlock creates the block of the draft right away without an execution lag. Otherwise, it enqueues
+// the draft after the blocks waiting for execution, blocking while there are ExecutionLag of them, and
+// the error creating a block is returned with the batches delivered after it.
+func (sm *StateManager) executeBlock(d *blockDraft) error {
+	p := sm.execution
+	if p == nil {
+		return sm.createBlock(d)
+	}
+	p.lk.Lock()
+	generation, err := p.generation, p.err
+	p.lk.Unlock()
+	if err != nil {
+		return xerrors.Errorf("validator %v failed to create a previous block: %w", sm.id, err)
+	}
+
+	p.pending.Add(1)
+	select {
+	case p.queue <- queuedDraft{blockDraft: d, generation: generation}:
+	case <-sm.ctx.Done():
+		p.pending.Done()
+	}
+	return nil
+}
+
+// flushExecution drops the blocks waiting for execution, and waits for the block being created, if any.
+// It is called before restoring the state from a checkpoint, which replaces the state they were ordered in.
+func (sm *StateManager) flushExecution() {
+	p := sm.execution
+	if p == nil {
+		return
+	}
+	p.lk.Lock()
+	p.generation++
+	p.err = nil
+	p.lk.Unlock()
+	p.pending.Wait()
+}
@@ -0,0 +1,50 @@
+package mir
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api/mocks"
+)
+
+func TestExecutionLag(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	node := mocks.NewMockFullNode(ctrl)
+
+	sm := &StateManager{
+		ctx:       ctx,
+		id:        "id1",
+		api:       node,
+		execution: newExecutionPipeline(1),
+	}
+	go sm.runExecution()
+	draft := func(h abi.ChainEpoch) *blockDraft {
+		return &blockDraft{l: log.With("validator", sm.id), height: h}
+	}
+
+	// The block is created in the background, and its error is returned with the next batches,
+	// whose blocks are not created.
+	node.EXPECT().ChainGetTipSetByHeight(gomock.Any(), abi.ChainEpoch(1), gomock.Any()).Return(nil, errors.New("not executed"))
+	require.NoError(t, sm.executeBlock(draft(2)))
+	require.Eventually(t, func() bool {
+		return sm.executeBlock(draft(3)) != nil
+	}, time.Second, time.Millisecond)
+
+	// Restoring the state drops the queued blocks and the error.
+	sm.flushExecution()
+	node.EXPECT().ChainGetTipSetByHeight(gomock.Any(), abi.ChainEpoch(9), gomock.Any()).Return(nil, errors.New("not executed"))
+	require.NoError(t, sm.executeBlock(draft(10)))
+	require.Eventually(t, func() bool {
+		return sm.execution.Err() != nil
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,135 @@
+package mir
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+// FailurePolicy is what a validator does when one of its subsystems fails, e.g. when the Mir node stops
+// with an error or panics.
+type FailurePolicy string
+
+const (
+	// CrashOnFailure stops the validator with the error, so it is restarted by its supervisor, if any.
+	CrashOnFailure FailurePolicy = "crash"
+	// RestartOnFailure restarts the subsystem in the same process, up to MaxSubsystemRestarts times
+	// before crashing.
+	RestartOnFailure FailurePolicy = "restart"
+	// DegradeOnFailure keeps the validator process running without the failed subsystem, e.g. so the
+	// operator can still inspect it through the admin API.
+	DegradeOnFailure FailurePolicy = "degrade"
+
+	DefaultFailurePolicy = CrashOnFailure
+)
+
+var (
+	// MaxSubsystemRestarts is the number of consecutive restarts of a subsystem with RestartOnFailure.
+	MaxSubsystemRestarts = 5
+	// SubsystemRestartBackoff is the time waited before restarting a failed subsystem.
+	SubsystemRestartBackoff = 5 * time.Second
+	// SubsystemHealthyAfter is the time a subsystem has to run for its restarts to be forgotten.
+	SubsystemHealthyAfter = 10 * time.Minute
+)
+
+func ParseFailurePolicy(s string) (FailurePolicy, error) {
+	switch p := FailurePolicy(s); p {
+	case CrashOnFailure, RestartOnFailure, DegradeOnFailure:
+		return p, nil
+	case "":
+		return DefaultFailurePolicy, nil
+	default:
+		return "", xerrors.Errorf("unknown failure policy %q: expected %s, %s or %s", s,
+			CrashOnFailure, RestartOnFailure, DegradeOnFailure)
+	}
+}
+
+// SubsystemError is the error a subsystem of the validator stopped with.
+type SubsystemError struct {
+	Subsystem string
+	Err       error
+	// Stack is the stack of the goroutine, if the subsystem panicked.
+	Stack []byte
+}
+
+func (e *SubsystemError) Error() string {
+	if e.Stack != nil {
+		return fmt.Sprintf("%s panicked: %v", e.Subsystem, e.Err)
+	}
+	return fmt.Sprintf("%s failed: %v", e.Subsystem, e.Err)
+}
+
+func (e *SubsystemError) Unwrap() error {
+	return e.Err
+}
+
+// RunSubsystem runs the subsystem name until it stops, handling its failures according to policy.
+// Panics of run are converted into a SubsystemError. It returns nil if the subsystem stopped
+// without error or if the failure was handled, and the SubsystemError if the validator has to stop.
+func RunSubsystem(ctx context.Context, name string, policy FailurePolicy, run func(context.Context) error) error {
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Subsystem, name))
+
+	restarts := 0
+	for {
+		start := time.Now()
+		err := runRecovered(ctx, name, run)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			// The subsystem failed while the validator was stopping.
+			log.Debugf("%v after the validator stopped", err)
+			return nil
+		}
+		stats.Record(ctx, metrics.MirSubsystemFailures.M(1))
+
+		switch policy {
+		case RestartOnFailure:
+			if time.Since(start) >= SubsystemHealthyAfter {
+				restarts = 0
+			}
+			if restarts >= MaxSubsystemRestarts {
+				log.Errorf("%v: giving up after %d restarts", err, restarts)
+				return err
+			}
+			restarts++
+			log.Warnf("%v: restarting in %s (%d/%d)", err, SubsystemRestartBackoff, restarts, MaxSubsystemRestarts)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(SubsystemRestartBackoff):
+			}
+		case DegradeOnFailure:
+			log.Errorf("%v: the validator keeps running without it", err)
+			<-ctx.Done()
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+func runRecovered(ctx context.Context, name string, run func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			rerr, ok := r.(error)
+			if !ok {
+				rerr = xerrors.Errorf("%v", r)
+			}
+			se := &SubsystemError{Subsystem: name, Err: rerr, Stack: debug.Stack()}
+			log.Errorf("%v\n%s", se, se.Stack)
+			err = se
+		}
+	}()
+	if err := run(ctx); err != nil {
+		return &SubsystemError{Subsystem: name, Err: err}
+	}
+	return nil
+}
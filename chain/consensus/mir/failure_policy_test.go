@@ -0,0 +1,90 @@
+package mir
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSubsystem(t *testing.T) {
+	SubsystemRestartBackoff = time.Millisecond
+	errFailed := errors.New("failed")
+
+	t.Run("crash", func(t *testing.T) {
+		runs := 0
+		err := RunSubsystem(context.Background(), "test", CrashOnFailure, func(context.Context) error {
+			runs++
+			return errFailed
+		})
+		var se *SubsystemError
+		require.ErrorAs(t, err, &se)
+		require.Equal(t, "test", se.Subsystem)
+		require.ErrorIs(t, err, errFailed)
+		require.Equal(t, 1, runs)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		err := RunSubsystem(context.Background(), "test", CrashOnFailure, func(context.Context) error {
+			panic("boom")
+		})
+		var se *SubsystemError
+		require.ErrorAs(t, err, &se)
+		require.NotNil(t, se.Stack)
+		require.Contains(t, err.Error(), "boom")
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		runs := 0
+		err := RunSubsystem(context.Background(), "test", RestartOnFailure, func(context.Context) error {
+			runs++
+			if runs < 3 {
+				panic("boom")
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, runs)
+
+		runs = 0
+		err = RunSubsystem(context.Background(), "test", RestartOnFailure, func(context.Context) error {
+			runs++
+			return errFailed
+		})
+		require.ErrorIs(t, err, errFailed)
+		require.Equal(t, MaxSubsystemRestarts+1, runs)
+	})
+
+	t.Run("degrade", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- RunSubsystem(ctx, "test", DegradeOnFailure, func(context.Context) error {
+				return errFailed
+			})
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("degraded subsystem stopped the validator")
+		case <-time.After(50 * time.Millisecond):
+		}
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
+
+func TestParseFailurePolicy(t *testing.T) {
+	p, err := ParseFailurePolicy("")
+	require.NoError(t, err)
+	require.Equal(t, DefaultFailurePolicy, p)
+
+	p, err = ParseFailurePolicy("degrade")
+	require.NoError(t, err)
+	require.Equal(t, DegradeOnFailure, p)
+
+	_, err = ParseFailurePolicy("ignore")
+	require.Error(t, err)
+}
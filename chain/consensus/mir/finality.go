@@ -0,0 +1,38 @@
+package mir
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+)
+
+// FinalityCheckpoint returns the checkpoint that commits the block at height h, so external services can
+// check with the lightclient package that the block was finalized by the validator set, without running
+// a node of the subnet. The checkpoint is looked up among the checkpoints persisted by the validator.
+func (m *Manager) FinalityCheckpoint(ctx context.Context, h abi.ChainEpoch) (*CheckpointNotification, error) {
+	var n *CheckpointNotification
+	err := walkCheckpoints(ctx, m.ds, func(ch *checkpoint.StableCheckpoint, snap *Checkpoint) (bool, error) {
+		if snap.Height <= h {
+			return false, nil
+		}
+		if snap.Parent.Height > h {
+			return true, nil
+		}
+		c, err := snap.Cid()
+		if err != nil {
+			return false, xerrors.Errorf("error computing cid for checkpoint: %w", err)
+		}
+		n, err = newCheckpointNotification(ch, snap, c)
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, xerrors.Errorf("no checkpoint persisted by the validator commits height %d", h)
+	}
+	return n, nil
+}
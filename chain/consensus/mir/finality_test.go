@@ -0,0 +1,91 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	"github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/lightclient"
+)
+
+func persistFinalityCheckpoint(t *testing.T, ds datastore.Datastore, h, parentHeight abi.ChainEpoch, parent cid.Cid) cid.Cid {
+	var cids []cid.Cid
+	for i := h - 1; i >= parentHeight; i-- {
+		c, err := abi.CidBuilder.Sum([]byte{byte(i)})
+		require.NoError(t, err)
+		cids = append(cids, c)
+	}
+	snap := &Checkpoint{Height: h, BlockCids: cids, Parent: ParentMeta{Height: parentHeight, Cid: parent}}
+	appData, err := snap.Bytes()
+	require.NoError(t, err)
+
+	mb := weightedMembership(map[types.NodeID]string{"id1": "1"})
+	ch := &checkpoint.StableCheckpoint{
+		Sn: 10,
+		Snapshot: &mirproto.StateSnapshot{
+			AppData: appData,
+			EpochData: &mirproto.EpochData{
+				EpochConfig:        &mirproto.EpochConfig{EpochNr: 2, Memberships: []*mirproto.Membership{mb}},
+				ClientProgress:     &mirproto.ClientProgress{},
+				PreviousMembership: mb,
+			},
+		},
+		Cert: checkpoint.Certificate{"id1": []byte("sig")},
+	}
+	b, err := ch.Serialize()
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, ds.Put(ctx, HeightCheckIndexKey(h), b))
+	require.NoError(t, ds.Put(ctx, LatestCheckpointPbKey, b))
+
+	c, err := snap.Cid()
+	require.NoError(t, err)
+	return c
+}
+
+func TestFinalityCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+	m := &Manager{id: "id1", ds: ds}
+
+	genesis, err := abi.CidBuilder.Sum([]byte("genesis"))
+	require.NoError(t, err)
+	c10 := persistFinalityCheckpoint(t, ds, 10, 5, genesis)
+	c20 := persistFinalityCheckpoint(t, ds, 20, 10, c10)
+
+	n, err := m.FinalityCheckpoint(ctx, 15)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(20), n.Height)
+	require.Equal(t, c20, n.Cid)
+	n, err = m.FinalityCheckpoint(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(20), n.Height)
+	n, err = m.FinalityCheckpoint(ctx, 9)
+	require.NoError(t, err)
+	require.Equal(t, c10, n.Cid)
+
+	// The snapshot decoded by light clients matches the checkpoint.
+	ch := &checkpoint.StableCheckpoint{}
+	require.NoError(t, ch.Deserialize(n.Checkpoint))
+	snap, err := lightclient.DecodeSnapshot(ch.Snapshot.AppData)
+	require.NoError(t, err)
+	require.Equal(t, c10, snap.Cid)
+	require.Equal(t, genesis, snap.ParentCid)
+	c9, err := abi.CidBuilder.Sum([]byte{9})
+	require.NoError(t, err)
+	require.True(t, snap.Commits(9, c9))
+
+	// Heights that aren't finalized yet, or committed by checkpoints that aren't persisted.
+	_, err = m.FinalityCheckpoint(ctx, 20)
+	require.Error(t, err)
+	_, err = m.FinalityCheckpoint(ctx, 3)
+	require.Error(t, err)
+}
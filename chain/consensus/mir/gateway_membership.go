@@ -0,0 +1,67 @@
+package mir
+
+import (
+	"context"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"go.opencensus.io/stats"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api/v1api"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/chain/gen/genesis"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+// gatewayMembershipCheck verifies that the SetMembership messages included in the blocks created by
+// the validator are executed, i.e. that the membership in the gateway actor of the subnet becomes the
+// validator set voted in Mir.
+//
+// SetMembership messages are executed implicitly, with no gas and from the system actor, so a message
+// that doesn't change the gateway as expected goes unnoticed otherwise: the Mir membership and the
+// on-chain membership diverge silently.
+type gatewayMembershipCheck struct {
+	api v1api.FullNode
+	id  string
+
+	// Latest validator set included in a block and the height of the block, if not verified yet.
+	expected *validator.Set
+	height   abi.ChainEpoch
+}
+
+func newGatewayMembershipCheck(api v1api.FullNode, id string) *gatewayMembershipCheck {
+	return &gatewayMembershipCheck{
+		api: api,
+		id:  id,
+	}
+}
+
+// expect records that the block at height h includes a SetMembership message with set.
+func (c *gatewayMembershipCheck) expect(h abi.ChainEpoch, set *validator.Set) {
+	c.expected = set
+	c.height = h
+}
+
+// check verifies the pending validator set against the gateway state at ts, once ts is
+// above the block including the message. It returns false if the gateway doesn't have the expected membership.
+func (c *gatewayMembershipCheck) check(ctx context.Context, ts *types.TipSet) (bool, error) {
+	if c.expected == nil || ts.Height() <= c.height {
+		return true, nil
+	}
+	actual, err := c.api.IPCGetGatewayMembership(ctx, genesis.DefaultIPCGatewayAddr, ts.Key())
+	if err != nil {
+		return false, xerrors.Errorf("error getting gateway membership at %s: %w", ts.Key(), err)
+	}
+	expected, h := c.expected, c.height
+	c.expected = nil
+
+	if err := membership.VerifyGatewayMembership(expected, actual); err != nil {
+		stats.Record(ctx, metrics.MirGatewayMismatches.M(1))
+		log.With("validator", c.id).Errorf("SetMembership message at height %d not applied to the gateway: %v", h, err)
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,65 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api/mocks"
+	"github.com/filecoin-project/lotus/chain/gen/genesis"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestGatewayMembershipCheck(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	node := mocks.NewMockFullNode(ctrl)
+
+	var chain []*types.TipSet
+	var ts *types.TipSet
+	for i := 0; i <= 3; i++ {
+		ts = mock.TipSet(mock.MkBlock(ts, 1, 1))
+		chain = append(chain, ts)
+	}
+
+	v1, err := validator.NewValidatorFromString("t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+	v2, err := validator.NewValidatorFromString("t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq:2@/ip4/127.0.0.1/tcp/10001/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+	initial := validator.NewValidatorSet(0, []*validator.Validator{v1})
+	voted := validator.NewValidatorSet(1, []*validator.Validator{v1, v2})
+
+	c := newGatewayMembershipCheck(node, "validator")
+
+	// Nothing to check.
+	ok, err := c.check(ctx, chain[1])
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// The message isn't executed until the next tipset.
+	c.expect(chain[1].Height(), voted)
+	ok, err = c.check(ctx, chain[1])
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	node.EXPECT().IPCGetGatewayMembership(gomock.Any(), genesis.DefaultIPCGatewayAddr, chain[2].Key()).Return(voted, nil)
+	ok, err = c.check(ctx, chain[2])
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// A verified set isn't checked again.
+	ok, err = c.check(ctx, chain[3])
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	c.expect(chain[2].Height(), voted)
+	node.EXPECT().IPCGetGatewayMembership(gomock.Any(), genesis.DefaultIPCGatewayAddr, chain[3].Key()).Return(initial, nil)
+	ok, err = c.check(ctx, chain[3])
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -4,6 +4,7 @@ import (
 	gen "github.com/whyrusleeping/cbor-gen"
 
 	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/attestation"
 )
 
 func main() {
@@ -13,6 +14,18 @@ func main() {
 		mir.VoteRecord{},
 		mir.VotedValidator{},
 		mir.VoteRecords{},
+		mir.SealedMessageRecords{},
+		mir.SealedMessageRecord{},
+		mir.CheckpointExtension{},
+		mir.ValidatorTimestamp{},
+		mir.ShutdownVote{},
+		mir.ValidatorContribution{},
+		mir.EpochContributions{},
+	); err != nil {
+		panic(err)
+	}
+	if err := gen.WriteTupleEncodersToFile("./attestation/cbor_gen.go", "attestation",
+		attestation.Attestation{},
 	); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,141 @@
+package mir
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api/v1api"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// HeadResubscribeInterval is the time the head tracker waits before subscribing again to the head
+// changes of the daemon after the subscription fails or is closed.
+var HeadResubscribeInterval = time.Second
+
+// headTracker follows the chain head of the daemon through a single ChainNotify subscription shared by
+// the state manager, the manager and the watchdogs, instead of each of them polling ChainHead.
+//
+// Waiters block on a channel that is closed and replaced whenever the head changes, so any number of
+// them are woken up by a single head change, and a change between reading the head and waiting isn't
+// missed. Until the subscription delivers the current head, and while it is being restored, the head
+// is read from the daemon.
+type headTracker struct {
+	ctx context.Context
+	api v1api.FullNode
+	id  string
+	// Interval between attempts to subscribe.
+	resubscribe time.Duration
+
+	lk sync.Mutex
+	// Latest head notified, nil while not subscribed.
+	head *types.TipSet
+	// Closed when the head changes.
+	changed chan struct{}
+}
+
+func newHeadTracker(ctx context.Context, api v1api.FullNode, id string) *headTracker {
+	return &headTracker{
+		ctx:         ctx,
+		api:         api,
+		id:          id,
+		resubscribe: HeadResubscribeInterval,
+		changed:     make(chan struct{}),
+	}
+}
+
+// Head returns the head of the chain.
+func (t *headTracker) Head(ctx context.Context) (*types.TipSet, error) {
+	t.lk.Lock()
+	head := t.head
+	t.lk.Unlock()
+	if head != nil {
+		return head, nil
+	}
+	return t.api.ChainHead(ctx)
+}
+
+// WaitForHeight waits until the head of the chain reaches height h.
+func (t *headTracker) WaitForHeight(ctx context.Context, h abi.ChainEpoch) error {
+	for {
+		t.lk.Lock()
+		head, changed := t.head, t.changed
+		t.lk.Unlock()
+
+		// Not subscribed: the head is read again at the next change or attempt to subscribe.
+		var retry <-chan time.Time
+		if head == nil {
+			ts, err := t.api.ChainHead(ctx)
+			if err != nil {
+				return err
+			}
+			head = ts
+			retry = time.After(t.resubscribe)
+		}
+		if head.Height() >= h {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return xerrors.Errorf("context cancelled while waiting for height %v", h)
+		case <-changed:
+		case <-retry:
+		}
+	}
+}
+
+// setHead records a new head and wakes up the waiters.
+func (t *headTracker) setHead(ts *types.TipSet) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	t.head = ts
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+func (t *headTracker) run() {
+	for {
+		if err := t.follow(); err != nil {
+			log.With("validator", t.id).Warnf("head tracker: %v", err)
+		}
+		t.setHead(nil)
+
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-time.After(t.resubscribe):
+		}
+	}
+}
+
+// follow subscribes to the head changes of the daemon and records them until the subscription is closed.
+func (t *headTracker) follow() error {
+	notifs, err := t.api.ChainNotify(t.ctx)
+	if err != nil {
+		return xerrors.Errorf("failed to subscribe to head changes: %w", err)
+	}
+	for {
+		select {
+		case <-t.ctx.Done():
+			return nil
+		case changes, ok := <-notifs:
+			if !ok {
+				return xerrors.Errorf("head changes subscription closed")
+			}
+			var head *types.TipSet
+			for _, c := range changes {
+				if c.Type == store.HCCurrent || c.Type == store.HCApply {
+					head = c.Val
+				}
+			}
+			// With only reverts, the head is read from the daemon until the next apply.
+			t.setHead(head)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package mir
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/mocks"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestHeadTracker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	node := mocks.NewMockFullNode(ctrl)
+
+	var chain []*types.TipSet
+	var ts *types.TipSet
+	for i := 0; i <= 5; i++ {
+		ts = mock.TipSet(mock.MkBlock(ts, 1, 1))
+		chain = append(chain, ts)
+	}
+
+	heads := newHeadTracker(ctx, node, "validator")
+	heads.resubscribe = 10 * time.Millisecond
+
+	// Until subscribed, the head is read from the daemon.
+	node.EXPECT().ChainHead(gomock.Any()).Return(chain[0], nil)
+	head, err := heads.Head(ctx)
+	require.NoError(t, err)
+	require.Equal(t, chain[0], head)
+
+	notifs := make(chan []*api.HeadChange)
+	node.EXPECT().ChainNotify(gomock.Any()).Return((<-chan []*api.HeadChange)(notifs), nil)
+	go heads.run()
+	notifs <- []*api.HeadChange{{Type: store.HCCurrent, Val: chain[1]}}
+	require.Eventually(t, func() bool {
+		head, err := heads.Head(ctx)
+		return err == nil && head == chain[1]
+	}, time.Second, time.Millisecond)
+
+	// All the waiters are woken up by the head changes, without reading the head from the daemon.
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- heads.WaitForHeight(ctx, chain[3].Height())
+		}()
+	}
+	notifs <- []*api.HeadChange{{Type: store.HCApply, Val: chain[2]}}
+	notifs <- []*api.HeadChange{{Type: store.HCRevert, Val: chain[2]}, {Type: store.HCApply, Val: chain[3]}}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.NoError(t, heads.WaitForHeight(ctx, chain[2].Height()))
+
+	// A waiter gives up when its context is done.
+	wctx, wcancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer wcancel()
+	require.Error(t, heads.WaitForHeight(wctx, chain[5].Height()))
+
+	// When the subscription is closed, the head is read from the daemon until the tracker subscribes again.
+	resubscribed := make(chan struct{})
+	node.EXPECT().ChainNotify(gomock.Any()).DoAndReturn(func(context.Context) (<-chan []*api.HeadChange, error) {
+		close(resubscribed)
+		return make(chan []*api.HeadChange), nil
+	})
+	node.EXPECT().ChainHead(gomock.Any()).Return(chain[4], nil).AnyTimes()
+	close(notifs)
+	<-resubscribed
+	require.NoError(t, heads.WaitForHeight(ctx, chain[4].Height()))
+	head, err = heads.Head(ctx)
+	require.NoError(t, err)
+	require.Equal(t, chain[4], head)
+}
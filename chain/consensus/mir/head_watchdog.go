@@ -0,0 +1,185 @@
+package mir
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api/v1api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+var (
+	// HeadAdvanceTimeout is the time the chain head has to reach the blocks submitted by the validator.
+	HeadAdvanceTimeout = 30 * time.Second
+	// MaxBlockResubmissions is the number of times a block is submitted again if the chain head doesn't reach it.
+	MaxBlockResubmissions = 3
+
+	ErrStuckHead = errors.New("chain head doesn't advance to the submitted blocks")
+)
+
+// headWatchdog checks that the chain head reaches the blocks submitted by the validator.
+//
+// A successful SyncSubmitBlock doesn't guarantee that the block becomes the head of the chain,
+// e.g. if the syncer is wedged. If the head doesn't advance for HeadAdvanceTimeout, the watchdog
+// dumps the sync state and submits the latest block again. After MaxBlockResubmissions attempts
+// it reports ErrStuckHead, so the validator is stopped and restarts from the latest checkpoint
+// instead of ordering batches that never make it to the chain.
+type headWatchdog struct {
+	ctx   context.Context
+	api   v1api.FullNode
+	heads *headTracker
+	id    string
+
+	lk sync.Mutex
+	// Latest block submitted that the head hasn't reached yet.
+	pending *types.BlockMsg
+	// Height of the head in the previous check.
+	head abi.ChainEpoch
+	// Time of the last progress of the head, or of the last resubmission.
+	since   time.Time
+	retries int
+
+	stuck chan error
+}
+
+func newHeadWatchdog(ctx context.Context, api v1api.FullNode, heads *headTracker, id string) *headWatchdog {
+	return &headWatchdog{
+		ctx:   ctx,
+		api:   api,
+		heads: heads,
+		id:    id,
+		stuck: make(chan error, 1),
+	}
+}
+
+// submitted records a block submitted by the validator.
+func (w *headWatchdog) submitted(b *types.BlockMsg) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	if w.pending == nil {
+		w.since = time.Now()
+		w.retries = 0
+	}
+	w.pending = b
+}
+
+// pendingBlock returns the CID of the latest block submitted that the head hasn't reached yet, if any.
+func (w *headWatchdog) pendingBlock() *cid.Cid {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	if w.pending == nil {
+		return nil
+	}
+	c := w.pending.Header.Cid()
+	return &c
+}
+
+// reset forgets the submitted blocks, e.g. when the chain is restored from a checkpoint.
+func (w *headWatchdog) reset() {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	w.pending = nil
+}
+
+// Stuck returns the channel where ErrStuckHead is sent if the head doesn't advance.
+func (w *headWatchdog) Stuck() <-chan error {
+	return w.stuck
+}
+
+func (w *headWatchdog) run() {
+	ticker := time.NewTicker(HeadAdvanceTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.check(); err != nil {
+				select {
+				case w.stuck <- err:
+				default:
+				}
+				return
+			}
+		}
+	}
+}
+
+func (w *headWatchdog) check() error {
+	w.lk.Lock()
+	pending := w.pending
+	w.lk.Unlock()
+	if pending == nil {
+		return nil
+	}
+
+	head, err := w.heads.Head(w.ctx)
+	if err != nil {
+		log.With("validator", w.id).Warnf("watchdog failed to get chain head: %v", err)
+		return nil
+	}
+
+	w.lk.Lock()
+	switch {
+	case w.pending == nil:
+		w.lk.Unlock()
+		return nil
+	case head.Height() >= w.pending.Header.Height:
+		w.pending = nil
+		w.head = head.Height()
+		w.lk.Unlock()
+		return nil
+	case head.Height() > w.head:
+		w.head = head.Height()
+		w.since = time.Now()
+		w.lk.Unlock()
+		return nil
+	case time.Since(w.since) < HeadAdvanceTimeout:
+		w.lk.Unlock()
+		return nil
+	}
+	pending, stuckFor, retries := w.pending, time.Since(w.since), w.retries
+	w.retries++
+	w.since = time.Now()
+	w.lk.Unlock()
+
+	log.With("validator", w.id).Warnf("chain head stuck at %d for %v, latest submitted block at %d",
+		head.Height(), stuckFor, pending.Header.Height)
+	w.dumpSyncState()
+
+	if retries >= MaxBlockResubmissions {
+		return xerrors.Errorf("validator %v: head at %d after %d resubmissions of block %d: %w",
+			w.id, head.Height(), retries, pending.Header.Height, ErrStuckHead)
+	}
+	if err := w.api.SyncSubmitBlock(w.ctx, pending); err != nil {
+		log.With("validator", w.id).Warnf("failed to resubmit block %d: %v", pending.Header.Height, err)
+	}
+	return nil
+}
+
+func (w *headWatchdog) dumpSyncState() {
+	st, err := w.api.SyncState(w.ctx)
+	if err != nil {
+		log.With("validator", w.id).Warnf("failed to get sync state: %v", err)
+		return
+	}
+	for _, s := range st.ActiveSyncs {
+		var base, target abi.ChainEpoch
+		if s.Base != nil {
+			base = s.Base.Height()
+		}
+		if s.Target != nil {
+			target = s.Target.Height()
+		}
+		log.With("validator", w.id).Warnf("sync worker %d: stage %s, height %d, base %d, target %d, message: %s",
+			s.WorkerID, s.Stage, s.Height, base, target, s.Message)
+	}
+}
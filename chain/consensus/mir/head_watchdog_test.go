@@ -0,0 +1,50 @@
+package mir
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/mocks"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestHeadWatchdog(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	node := mocks.NewMockFullNode(ctrl)
+
+	var chain []*types.TipSet
+	var ts *types.TipSet
+	for i := 0; i <= 5; i++ {
+		ts = mock.TipSet(mock.MkBlock(ts, 1, 1))
+		chain = append(chain, ts)
+	}
+	blk := &types.BlockMsg{Header: chain[5].Blocks()[0]}
+
+	w := newHeadWatchdog(ctx, node, newHeadTracker(ctx, node, "validator"), "validator")
+	w.submitted(blk)
+
+	// The head is stuck below the submitted block: the block is resubmitted until the limit is reached.
+	node.EXPECT().ChainHead(gomock.Any()).Return(chain[3], nil).Times(MaxBlockResubmissions + 1)
+	node.EXPECT().SyncState(gomock.Any()).Return(&api.SyncState{}, nil).Times(MaxBlockResubmissions + 1)
+	node.EXPECT().SyncSubmitBlock(gomock.Any(), blk).Return(nil).Times(MaxBlockResubmissions)
+	w.head = 3
+	for i := 0; i < MaxBlockResubmissions; i++ {
+		w.since = time.Now().Add(-HeadAdvanceTimeout)
+		require.NoError(t, w.check())
+	}
+	w.since = time.Now().Add(-HeadAdvanceTimeout)
+	require.ErrorIs(t, w.check(), ErrStuckHead)
+
+	// The head reaches the submitted block.
+	node.EXPECT().ChainHead(gomock.Any()).Return(chain[5], nil)
+	require.NoError(t, w.check())
+	require.Nil(t, w.pending)
+}
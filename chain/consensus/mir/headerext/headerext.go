@@ -0,0 +1,93 @@
+// Package headerext implements the encoding of the Mir data carried in the block headers of a subnet.
+//
+// Mir blocks have no ticket nor election proof, so their VRFProof fields carry the checkpoint included
+// in the block and its certificate. The data is wrapped in an envelope that tags it with the version of
+// the encoding and the kind of data, so a header is self-describing and new kinds or encodings can be
+// added without guessing the format from the bytes. Headers created before the envelope carry the data
+// as is; they are still decoded as untagged data.
+package headerext
+
+import (
+	"fmt"
+)
+
+// Marker is the first byte of the tagged data. Checkpoints serialized by Mir are protobuf messages
+// starting with the tag of one of their first fields, and certificates are either CBOR maps or compact
+// certificates starting with their version, so none starts with this byte, which is a CBOR break.
+const Marker = 0xff
+
+// Version is the version of the envelope written by this node.
+const Version = 1
+
+// Kind is the kind of data in the envelope.
+type Kind byte
+
+const (
+	// Untagged is the data of the headers created before the envelope.
+	Untagged Kind = 0
+	// Checkpoint is a Mir checkpoint without certificate, carried in the ticket.
+	Checkpoint Kind = 1
+	// Cert is a checkpoint certificate serialized by Mir, carried in the election proof.
+	Cert Kind = 2
+	// CompactCert is a compact checkpoint certificate, carried in the election proof.
+	CompactCert Kind = 3
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Untagged:
+		return "untagged"
+	case Checkpoint:
+		return "checkpoint"
+	case Cert:
+		return "cert"
+	case CompactCert:
+		return "compact-cert"
+	default:
+		return fmt.Sprintf("kind-%d", byte(k))
+	}
+}
+
+// Encode wraps the data of a kind in the envelope.
+func Encode(k Kind, data []byte) []byte {
+	b := make([]byte, 0, len(data)+3)
+	b = append(b, Marker, Version, byte(k))
+	return append(b, data...)
+}
+
+// Decode returns the kind and the data in the envelope, or the data as is if it is untagged.
+func Decode(b []byte) (Kind, []byte, error) {
+	if len(b) == 0 || b[0] != Marker {
+		return Untagged, b, nil
+	}
+	if len(b) < 3 {
+		return 0, nil, fmt.Errorf("truncated header extension")
+	}
+	if b[1] != Version {
+		return 0, nil, fmt.Errorf("unsupported header extension version %d: the node may need an upgrade", b[1])
+	}
+	k := Kind(b[2])
+	switch k {
+	case Checkpoint, Cert, CompactCert:
+	default:
+		return 0, nil, fmt.Errorf("unknown header extension %s", k)
+	}
+	return k, b[3:], nil
+}
+
+// DecodeAs returns the data in the envelope if it is untagged or of one of the kinds.
+func DecodeAs(b []byte, kinds ...Kind) (Kind, []byte, error) {
+	k, data, err := Decode(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	if k == Untagged {
+		return k, data, nil
+	}
+	for _, want := range kinds {
+		if k == want {
+			return k, data, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("unexpected header extension %s, expected one of %v", k, kinds)
+}
@@ -0,0 +1,39 @@
+package headerext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderExt(t *testing.T) {
+	data := []byte{0x0a, 0x01, 0x02}
+	b := Encode(Checkpoint, data)
+	k, got, err := Decode(b)
+	require.NoError(t, err)
+	require.Equal(t, Checkpoint, k)
+	require.Equal(t, data, got)
+
+	// Untagged data is returned as is.
+	k, got, err = Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, Untagged, k)
+	require.Equal(t, data, got)
+	k, _, err = DecodeAs(data, Cert)
+	require.NoError(t, err)
+	require.Equal(t, Untagged, k)
+
+	_, _, err = DecodeAs(b, Cert, CompactCert)
+	require.Error(t, err)
+	k, _, err = DecodeAs(Encode(CompactCert, data), Cert, CompactCert)
+	require.NoError(t, err)
+	require.Equal(t, CompactCert, k)
+
+	// Newer versions and kinds are rejected rather than misread.
+	_, _, err = Decode([]byte{Marker, Version + 1, byte(Checkpoint)})
+	require.Error(t, err)
+	_, _, err = Decode([]byte{Marker, Version, 42})
+	require.Error(t, err)
+	_, _, err = Decode([]byte{Marker, Version})
+	require.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package mir
+
+import (
+	"context"
+	"crypto"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// VerifyImportedChain verifies the checkpoints included in a chain imported from a snapshot or a chain
+// export, and returns the highest tipset of the chain committed by a checkpoint with a valid certificate.
+// Only that tipset can be accepted as head: the blocks after it aren't covered by any certificate.
+//
+// Verification starts from the anchor checkpoint, e.g. a checkpoint file from the checkpoint repo of a
+// validator, and every later checkpoint of the chain must be certified by the membership committed by the
+// previous one, so a poisoned snapshot can't be used to bootstrap a node. Without an anchor, the first
+// checkpoint of the chain is used, and the chain is only verified to be consistent with its own history.
+func VerifyImportedChain(ctx context.Context, cs *store.ChainStore, head *types.TipSet, anchor *checkpoint.StableCheckpoint) (*types.TipSet, error) {
+	chain := &importedChain{cs: cs, head: head}
+	if anchor == nil {
+		var err error
+		anchor, err = chain.firstCheckpoint(ctx)
+		if err != nil {
+			return nil, err
+		}
+		log.Warnf("no trusted checkpoint to verify the imported chain against, using its first checkpoint")
+	}
+	if err := anchor.VerifyCert(crypto.SHA256, CheckpointVerifier{}, anchor.PreviousMembership()); err != nil {
+		return nil, xerrors.Errorf("invalid certificate of anchor checkpoint: %w", err)
+	}
+
+	// The light client verifies the checkpoints of any Mir chain, the imported one being served by the chain store.
+	lc, err := NewParentLightClient(ctx, chain, anchor)
+	if err != nil {
+		return nil, xerrors.Errorf("anchor checkpoint doesn't match the imported chain: %w", err)
+	}
+	if err := lc.Sync(ctx); err != nil {
+		return nil, xerrors.Errorf("error verifying checkpoints of the imported chain: %w", err)
+	}
+
+	header := lc.TrustedHeader()
+	ts, err := cs.GetTipsetByHeight(ctx, header.Height, head, false)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting imported tipset at height %d: %w", header.Height, err)
+	}
+	if !ts.Contains(header.Cid()) {
+		return nil, xerrors.Errorf("block %s committed by the latest checkpoint isn't in the imported chain", header.Cid())
+	}
+	return ts, nil
+}
+
+// importedChain serves an imported chain that isn't the head of the chain store yet.
+type importedChain struct {
+	cs   *store.ChainStore
+	head *types.TipSet
+}
+
+var _ ParentNode = (*importedChain)(nil)
+
+func (c *importedChain) ChainHead(context.Context) (*types.TipSet, error) {
+	return c.head, nil
+}
+
+func (c *importedChain) ChainGetTipSetByHeight(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	ts, err := c.cs.LoadTipSet(ctx, tsk)
+	if err != nil {
+		return nil, err
+	}
+	return c.cs.GetTipsetByHeight(ctx, h, ts, true)
+}
+
+func (c *importedChain) ChainReadObj(ctx context.Context, obj cid.Cid) ([]byte, error) {
+	b, err := c.cs.ChainBlockstore().Get(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return b.RawData(), nil
+}
+
+// firstCheckpoint returns the first checkpoint included in the imported chain.
+func (c *importedChain) firstCheckpoint(ctx context.Context) (*checkpoint.StableCheckpoint, error) {
+	for h := abi.ChainEpoch(1); h <= c.head.Height(); h++ {
+		ts, err := c.cs.GetTipsetByHeight(ctx, h, c.head, false)
+		if err != nil {
+			return nil, xerrors.Errorf("error getting imported tipset at height %d: %w", h, err)
+		}
+		if ts.Height() != h {
+			continue
+		}
+		if ch, ok, err := checkpointInHeader(ts.Blocks()[0]); ok || err != nil {
+			return ch, err
+		}
+	}
+	return nil, xerrors.Errorf("imported chain doesn't include any checkpoint up to height %d", c.head.Height())
+}
+
+// checkpointInHeader returns the checkpoint with its certificate included in a block, if any.
+func checkpointInHeader(h *types.BlockHeader) (*checkpoint.StableCheckpoint, bool, error) {
+	if h.ElectionProof == nil || h.ElectionProof.VRFProof == nil || h.Ticket == nil {
+		return nil, false, nil
+	}
+	ch, err := CheckpointFromVRFProof(h.Ticket)
+	if err != nil {
+		return nil, true, xerrors.Errorf("error getting checkpoint from ticket: %w", err)
+	}
+	cert, err := CertFromElectionProof(h.ElectionProof, ch.PreviousMembership())
+	if err != nil {
+		return nil, true, xerrors.Errorf("error getting checkpoint certificate from election proof: %w", err)
+	}
+	return ch.AttachCert(cert), true, nil
+}
@@ -0,0 +1,45 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func importChain(t *testing.T, ctx context.Context, withCheckpoints bool) (*store.ChainStore, *types.TipSet) {
+	bs := blockstore.NewMemory()
+	cs := store.NewChainStore(bs, bs, datastore.NewMapDatastore(), Weight, nil)
+	t.Cleanup(func() { cs.Close() }) // nolint
+
+	var ts *types.TipSet
+	for i := 0; i < 5; i++ {
+		b := mock.MkBlock(ts, 1, uint64(i))
+		if !withCheckpoints {
+			b.Ticket.VRFProof, b.ElectionProof.VRFProof = nil, nil
+		}
+		ts = mock.TipSet(b)
+		require.NoError(t, cs.PutTipSet(ctx, ts))
+	}
+	return cs, ts
+}
+
+func TestVerifyImportedChain(t *testing.T) {
+	ctx := context.Background()
+
+	// A chain without checkpoints isn't covered by any certificate.
+	cs, head := importChain(t, ctx, false)
+	_, err := VerifyImportedChain(ctx, cs, head, nil)
+	require.ErrorContains(t, err, "doesn't include any checkpoint")
+
+	// Blocks with garbage in place of checkpoints are rejected.
+	cs, head = importChain(t, ctx, true)
+	_, err = VerifyImportedChain(ctx, cs, head, nil)
+	require.Error(t, err)
+}
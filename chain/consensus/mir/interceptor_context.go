@@ -0,0 +1,170 @@
+package mir
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/pool/fifo"
+)
+
+// InterceptorContextFile is the file of the interceptor output directory of a validator where the snapshots
+// of its eudico-side state are appended, one JSON object per line, next to the Mir events.
+const InterceptorContextFile = "eudico-context.jsonl"
+
+// InterceptorContextInterval is the interval between the snapshots recorded with the Mir events.
+var InterceptorContextInterval = 5 * time.Second
+
+// InterceptorSnapshot is the state of a validator outside Mir at some point of a recording, so the replay
+// of the recorded Mir events can be matched with the requests and configuration changes that produced them.
+type InterceptorSnapshot struct {
+	// Time is the time of the snapshot in milliseconds since the recording started, like the time of the
+	// recorded Mir events.
+	Time     int64
+	WallTime time.Time
+	// Epoch is the Mir epoch the validator orders transactions in.
+	Epoch uint64
+	// CheckpointHeight is the height of the latest stable checkpoint delivered to the validator.
+	CheckpointHeight abi.ChainEpoch
+	// Pool is the state of the request pool.
+	Pool fifo.Snapshot
+	// Blocks maps the heights of the blocks created since the latest checkpoint to their CIDs.
+	Blocks map[abi.ChainEpoch]cid.Cid
+	// PendingBlock is the latest block submitted that the chain head hasn't reached yet, if any.
+	PendingBlock *cid.Cid `json:",omitempty"`
+	// PendingConfig are the configuration requests of the validator that haven't been applied yet.
+	PendingConfig []PendingConfigurationRequest
+}
+
+// contextRecorder writes snapshots to the InterceptorContextFile of a recording.
+type contextRecorder struct {
+	start time.Time
+
+	lk sync.Mutex
+	f  *os.File
+}
+
+// newContextRecorder creates a recorder for the recording in dir started at start.
+func newContextRecorder(dir string, start time.Time) (*contextRecorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	// Like the Mir event log, the file is overwritten when the validator restarts.
+	f, err := os.Create(path.Join(dir, InterceptorContextFile))
+	if err != nil {
+		return nil, err
+	}
+	return &contextRecorder{start: start, f: f}, nil
+}
+
+// record appends s, setting its time.
+func (r *contextRecorder) record(s *InterceptorSnapshot) error {
+	s.WallTime = time.Now()
+	s.Time = s.WallTime.Sub(r.start).Milliseconds()
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	if r.f == nil {
+		return os.ErrClosed
+	}
+	_, err = r.f.Write(append(b, '\n'))
+	return err
+}
+
+func (r *contextRecorder) close() error {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// interceptorSnapshot returns the current state of the validator outside Mir.
+func (m *Manager) interceptorSnapshot() *InterceptorSnapshot {
+	status := m.Status()
+	s := &InterceptorSnapshot{
+		Epoch:            status.Epoch,
+		CheckpointHeight: status.CheckpointHeight,
+		Pool:             m.txPool.Snapshot(),
+		Blocks:           m.stateManager.blocks.all(),
+		PendingBlock:     m.stateManager.watchdog.pendingBlock(),
+	}
+	reqs, err := m.confManager.PendingRequests()
+	if err != nil {
+		log.With("validator", m.id).Warnf("failed to get pending configuration requests: %v", err)
+	}
+	s.PendingConfig = reqs
+	return s
+}
+
+// recordContext records snapshots of the state of the validator every InterceptorContextInterval.
+func (m *Manager) recordContext(ctx context.Context) {
+	ticker := time.NewTicker(InterceptorContextInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.contextRecorder.record(m.interceptorSnapshot()); err != nil {
+			log.With("validator", m.id).Warnf("failed to record interceptor snapshot: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReadInterceptorContext reads the snapshots recorded in the interceptor output directory of a validator.
+func ReadInterceptorContext(dir string) ([]*InterceptorSnapshot, error) {
+	f, err := os.Open(path.Join(dir, InterceptorContextFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint
+	return readInterceptorContext(f)
+}
+
+func readInterceptorContext(r io.Reader) ([]*InterceptorSnapshot, error) {
+	var snaps []*InterceptorSnapshot
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 64<<20)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var s InterceptorSnapshot
+		if err := json.Unmarshal(sc.Bytes(), &s); err != nil {
+			// The last line may be truncated if the validator was killed while writing it.
+			return snaps, xerrors.Errorf("error decoding interceptor snapshot %d: %w", len(snaps), err)
+		}
+		snaps = append(snaps, &s)
+	}
+	return snaps, sc.Err()
+}
+
+// InterceptorContextAt returns the latest of the snapshots, sorted by time, recorded at or before the time t
+// of a Mir event, or nil if there is none.
+func InterceptorContextAt(snaps []*InterceptorSnapshot, t int64) *InterceptorSnapshot {
+	i := sort.Search(len(snaps), func(i int) bool { return snaps[i].Time > t })
+	if i == 0 {
+		return nil
+	}
+	return snaps[i-1]
+}
@@ -0,0 +1,63 @@
+package mir
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/pool/fifo"
+)
+
+func TestInterceptorContext(t *testing.T) {
+	dir := path.Join(t.TempDir(), "validator")
+	c, err := cid.Decode("bafy2bzacecnamqgqmifpluoeldx7zzglxcljo6oja4vrmtj7432rphldpdmm2")
+	require.NoError(t, err)
+
+	r, err := newContextRecorder(dir, time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	snaps := []*InterceptorSnapshot{
+		{Epoch: 1, Pool: fifo.Snapshot{InFlight: []fifo.InFlightTx{{Cid: c, ClientID: "client", Nonce: 2}}, Seen: map[string]uint64{"client": 2}}},
+		{Epoch: 2, Blocks: map[abi.ChainEpoch]cid.Cid{10: c}, PendingBlock: &c},
+		{Epoch: 2, PendingConfig: []PendingConfigurationRequest{{TxNo: 3, ConfigurationNumber: 4}}},
+	}
+	for _, s := range snaps {
+		require.NoError(t, r.record(s))
+	}
+	require.NoError(t, r.close())
+	require.ErrorIs(t, r.record(&InterceptorSnapshot{}), os.ErrClosed)
+
+	read, err := ReadInterceptorContext(dir)
+	require.NoError(t, err)
+	require.Len(t, read, len(snaps))
+	for i, s := range snaps {
+		require.GreaterOrEqual(t, read[i].Time, int64(1000))
+		require.True(t, s.WallTime.Equal(read[i].WallTime))
+		read[i].WallTime = s.WallTime
+		require.Equal(t, s, read[i])
+	}
+
+	// The snapshots before a truncated line are returned.
+	f, err := os.OpenFile(path.Join(dir, InterceptorContextFile), os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"Time":`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	read, err = ReadInterceptorContext(dir)
+	require.Error(t, err)
+	require.Len(t, read, len(snaps))
+}
+
+func TestInterceptorContextAt(t *testing.T) {
+	snaps := []*InterceptorSnapshot{{Time: 10}, {Time: 20}, {Time: 30}}
+	require.Nil(t, InterceptorContextAt(nil, 10))
+	require.Nil(t, InterceptorContextAt(snaps, 9))
+	require.Equal(t, snaps[0], InterceptorContextAt(snaps, 10))
+	require.Equal(t, snaps[1], InterceptorContextAt(snaps, 29))
+	require.Equal(t, snaps[2], InterceptorContextAt(snaps, 1000))
+}
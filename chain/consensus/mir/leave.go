@@ -0,0 +1,77 @@
+package mir
+
+import (
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"golang.org/x/xerrors"
+
+	t "github.com/filecoin-project/mir/pkg/types"
+)
+
+// LeaveStatus is the progress of the request of the validator to leave the validator set.
+type LeaveStatus struct {
+	TxNo uint64
+	// Ordered is true once the configuration transaction of the request has been ordered by Mir.
+	Ordered bool
+	// Left is true once the membership of the epoch being ordered doesn't include the validator,
+	// so it can stop without affecting the liveness of the subnet.
+	Left  bool
+	Epoch uint64
+}
+
+// validatorSetWithout returns the next configuration of the set without the validator.
+func validatorSetWithout(set *validator.Set, id string) (*validator.Set, error) {
+	var vals []*validator.Validator
+	found := false
+	for _, v := range set.Validators {
+		if v.ID() == id {
+			found = true
+			continue
+		}
+		vals = append(vals, v)
+	}
+	if !found {
+		return nil, xerrors.Errorf("validator %s not in validator set %d", id, set.ConfigurationNumber)
+	}
+	if len(vals) == 0 {
+		return nil, xerrors.Errorf("validator %s is the only validator of the subnet", id)
+	}
+	return validator.NewValidatorSet(set.ConfigurationNumber+1, vals), nil
+}
+
+// Leave submits a configuration request removing the validator from the last validator set it proposed.
+// The request is voted for like any other configuration, and the progress is reported by LeaveStatus.
+func (m *Manager) Leave() (uint64, error) {
+	set, err := validatorSetWithout(m.restoreLastValidatorSet(), m.id)
+	if err != nil {
+		return 0, err
+	}
+	txNo, err := m.SubmitConfigurationRequest(set)
+	if err != nil {
+		return 0, err
+	}
+	// A restarted validator must not propose the set including it again.
+	if err := m.confManager.StoreLastValidatorSet(set); err != nil {
+		log.With("validator", m.id).Warnf("failed to persist validator set %d: %v", set.ConfigurationNumber, err)
+	}
+	log.With("validator", m.id).Warnf("operator requested to leave the validator set: configuration %d, tx: %d",
+		set.ConfigurationNumber, txNo)
+	return txNo, nil
+}
+
+// LeaveStatus returns the progress of the request to leave the validator set submitted in txNo.
+func (m *Manager) LeaveStatus(txNo uint64) *LeaveStatus {
+	s := &LeaveStatus{
+		TxNo:    txNo,
+		Ordered: m.confManager.getAppliedConfigurationNumber() > txNo,
+	}
+
+	st := m.stateManager.status
+	st.lk.Lock()
+	defer st.lk.Unlock()
+	s.Epoch = uint64(st.currentEpoch)
+	if mb, ok := st.memberships[st.currentEpoch]; ok && s.Ordered {
+		_, member := mb.Nodes[t.NodeID(m.id)]
+		s.Left = !member
+	}
+	return s
+}
@@ -0,0 +1,28 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatorSetWithout(t *testing.T) {
+	const (
+		v1 = "t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ"
+		v2 = "t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq:1@/ip4/127.0.0.1/tcp/10001/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ"
+	)
+	set := testValidatorSet(t, 3, v1, v2)
+
+	next, err := validatorSetWithout(set, "t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq")
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), next.ConfigurationNumber)
+	require.Equal(t, 1, next.Size())
+	require.Equal(t, "t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy", next.Validators[0].ID())
+	require.Equal(t, 2, set.Size())
+
+	_, err = validatorSetWithout(next, "t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq")
+	require.Error(t, err)
+
+	_, err = validatorSetWithout(next, "t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy")
+	require.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package lightclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/compression"
+)
+
+// Snapshot is the part of the application snapshot of a checkpoint a light client relies on:
+// the range of blocks it commits and the checkpoint it follows.
+type Snapshot struct {
+	// Height of the checkpoint. The checkpoint commits the blocks from the height of its parent
+	// up to Height-1.
+	Height abi.ChainEpoch
+	// CIDs of the committed blocks in descending order of height, i.e. BlockCids[0] is at Height-1.
+	BlockCids []cid.Cid
+	// Height and CID of the parent checkpoint.
+	ParentHeight abi.ChainEpoch
+	ParentCid    cid.Cid
+	// CID of the snapshot, which the next checkpoint refers to as its parent.
+	Cid cid.Cid
+}
+
+// DecodeSnapshot decodes the application snapshot of a checkpoint, i.e. the AppData of its state snapshot.
+//
+// The snapshot is encoded as the Checkpoint type of the Mir consensus of Eudico, as a CBOR tuple starting
+// with the height, the block CIDs and the parent. Only those fields are decoded, so light clients don't
+// depend on the rest of the state of the validators. Compressed snapshots are decompressed first; the CID
+// is the one of the uncompressed snapshot.
+func DecodeSnapshot(b []byte) (*Snapshot, error) {
+	b, err := compression.Decompress(b)
+	if err != nil {
+		return nil, err
+	}
+	cr := cbg.NewCborReader(bytes.NewReader(b))
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return nil, err
+	}
+	if maj != cbg.MajArray || extra < 3 {
+		return nil, fmt.Errorf("checkpoint snapshot should be an array of at least 3 fields")
+	}
+
+	s := &Snapshot{}
+	if s.Height, err = readChainEpoch(cr); err != nil {
+		return nil, fmt.Errorf("error reading height: %w", err)
+	}
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return nil, err
+	}
+	if maj != cbg.MajArray || extra > cbg.MaxLength {
+		return nil, fmt.Errorf("invalid block cids")
+	}
+	for i := 0; i < int(extra); i++ {
+		c, err := cbg.ReadCid(cr)
+		if err != nil {
+			return nil, fmt.Errorf("error reading block cid: %w", err)
+		}
+		s.BlockCids = append(s.BlockCids, c)
+	}
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return nil, err
+	}
+	if maj != cbg.MajArray || extra != 2 {
+		return nil, fmt.Errorf("invalid parent")
+	}
+	if s.ParentHeight, err = readChainEpoch(cr); err != nil {
+		return nil, fmt.Errorf("error reading parent height: %w", err)
+	}
+	if s.ParentCid, err = cbg.ReadCid(cr); err != nil {
+		return nil, fmt.Errorf("error reading parent cid: %w", err)
+	}
+
+	// The snapshot of the genesis checkpoint is empty and has no CID.
+	if s.Height == 0 && len(s.BlockCids) == 0 && s.ParentHeight == 0 && s.ParentCid == cid.Undef {
+		return s, nil
+	}
+	// A checkpoint commits the blocks from the height of its parent, in descending order.
+	if s.Height <= s.ParentHeight {
+		return nil, fmt.Errorf("checkpoint height %d is not above the parent height %d", s.Height, s.ParentHeight)
+	}
+	if n := s.Height - s.ParentHeight; abi.ChainEpoch(len(s.BlockCids)) != n {
+		return nil, fmt.Errorf("checkpoint at height %d commits %d blocks, expected %d", s.Height, len(s.BlockCids), n)
+	}
+	h, err := multihash.Sum(b, abi.HashFunction, -1)
+	if err != nil {
+		return nil, err
+	}
+	s.Cid = cid.NewCidV1(abi.CidBuilder.GetCodec(), h)
+	return s, nil
+}
+
+// Commits returns whether the checkpoint commits the block with CID c at height h.
+func (s *Snapshot) Commits(h abi.ChainEpoch, c cid.Cid) bool {
+	i := int(s.Height - 1 - h)
+	return h < s.Height && i < len(s.BlockCids) && s.BlockCids[i] == c
+}
+
+func readChainEpoch(cr *cbg.CborReader) (abi.ChainEpoch, error) {
+	maj, extra, err := cr.ReadHeader()
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	v := int64(extra)
+	if v < 0 {
+		return 0, fmt.Errorf("int64 overflow")
+	}
+	switch maj {
+	case cbg.MajUnsignedInt:
+		return abi.ChainEpoch(v), nil
+	case cbg.MajNegativeInt:
+		return abi.ChainEpoch(-1 - v), nil
+	default:
+		return 0, fmt.Errorf("wrong type for int64 field: %d", maj)
+	}
+}
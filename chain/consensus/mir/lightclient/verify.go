@@ -0,0 +1,165 @@
+// Package lightclient verifies the checkpoints of a Mir subnet without running a node of the subnet.
+//
+// Every checkpoint of a Mir subnet is certified by a strong quorum of the validators of the epoch it
+// closes, and commits the CIDs of the blocks since the previous checkpoint. This is enough for bridges
+// and other external services to check that a block was finalized by a validator set, or to follow the
+// checkpoints of a subnet from a trusted one, using only the checkpoints served by any untrusted node.
+//
+// The package doesn't depend on the consensus of the subnet, so it can be imported without the native
+// dependencies of Lotus.
+package lightclient
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	filcrypto "github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	t "github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/lib/sigs"
+	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
+)
+
+// SignatureVerifier verifies the signatures of the validators of a subnet over checkpoints.
+// Validators sign the SHA256 hash of the concatenation of the data with their secp256k1 key,
+// and their node ID is their address.
+type SignatureVerifier struct{}
+
+func (SignatureVerifier) Verify(data [][]byte, sigBytes []byte, nodeID t.NodeID) error {
+	addr, err := address.NewFromString(nodeID.Pb())
+	if err != nil {
+		return err
+	}
+	var sig filcrypto.Signature
+	if err := sig.UnmarshalBinary(sigBytes); err != nil {
+		return err
+	}
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return sigs.Verify(&sig, addr, h.Sum(nil))
+}
+
+// VerifyCheckpoint verifies that the checkpoint is certified by the membership mb and returns its snapshot.
+func VerifyCheckpoint(ch *checkpoint.StableCheckpoint, mb *mirproto.Membership) (*Snapshot, error) {
+	if ch.Snapshot == nil {
+		return nil, fmt.Errorf("checkpoint without snapshot")
+	}
+	if mb == nil {
+		return nil, fmt.Errorf("empty membership")
+	}
+	// Mir weighs the signers of the certificate before checking that they belong to the membership,
+	// which panics on the certificates of another membership.
+	for id := range ch.Cert {
+		if _, ok := mb.Nodes[id]; !ok {
+			return nil, fmt.Errorf("signer %s of the checkpoint certificate not in membership", id)
+		}
+	}
+	if err := ch.VerifyCert(crypto.SHA256, SignatureVerifier{}, mb); err != nil {
+		return nil, fmt.Errorf("error verifying checkpoint certificate: %w", err)
+	}
+	snap, err := DecodeSnapshot(ch.Snapshot.AppData)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding checkpoint snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// VerifyFinality verifies that the block with CID c at the height h was finalized by the validator set,
+// i.e. that the checkpoint committing it is certified by the validator set.
+func VerifyFinality(ch *checkpoint.StableCheckpoint, set *validator.Set, h abi.ChainEpoch, c cid.Cid) error {
+	if set == nil {
+		return fmt.Errorf("empty validator set")
+	}
+	_, mb, err := membership.Membership(set.Validators)
+	if err != nil {
+		return fmt.Errorf("error resolving membership of the validator set: %w", err)
+	}
+	snap, err := VerifyCheckpoint(ch, mb)
+	if err != nil {
+		return err
+	}
+	if !snap.Commits(h, c) {
+		return fmt.Errorf("checkpoint at height %d doesn't commit block %s at height %d", snap.Height, c, h)
+	}
+	return nil
+}
+
+// VerifySuccessor verifies that the checkpoint next follows the trusted checkpoint and returns its snapshot.
+// The next checkpoint is certified by the membership of the epoch started by the trusted checkpoint,
+// not by the membership it claims.
+func VerifySuccessor(trusted *checkpoint.StableCheckpoint, next *checkpoint.StableCheckpoint) (*Snapshot, error) {
+	if trusted.Snapshot == nil {
+		return nil, fmt.Errorf("trusted checkpoint without snapshot")
+	}
+	prev, err := DecodeSnapshot(trusted.Snapshot.AppData)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding trusted checkpoint snapshot: %w", err)
+	}
+	mbs := trusted.Memberships()
+	if len(mbs) == 0 {
+		return nil, fmt.Errorf("trusted checkpoint at height %d without membership", prev.Height)
+	}
+	snap, err := VerifyCheckpoint(next, mbs[0])
+	if err != nil {
+		return nil, err
+	}
+	if snap.ParentCid != prev.Cid || snap.ParentHeight != prev.Height {
+		return nil, fmt.Errorf("checkpoint at height %d doesn't follow the trusted checkpoint at height %d", snap.Height, prev.Height)
+	}
+	return snap, nil
+}
+
+// Verifier follows the checkpoints of a subnet from a trusted anchor checkpoint.
+// It isn't safe for concurrent use.
+type Verifier struct {
+	trusted *checkpoint.StableCheckpoint
+	snap    *Snapshot
+}
+
+// NewVerifier creates a verifier trusting the anchor checkpoint, e.g. a checkpoint exported from
+// a validator of the subnet or committed in its parent.
+func NewVerifier(anchor *checkpoint.StableCheckpoint) (*Verifier, error) {
+	if anchor.Snapshot == nil {
+		return nil, fmt.Errorf("anchor checkpoint without snapshot")
+	}
+	snap, err := DecodeSnapshot(anchor.Snapshot.AppData)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding anchor checkpoint snapshot: %w", err)
+	}
+	return &Verifier{trusted: anchor, snap: snap}, nil
+}
+
+// Trusted returns the latest trusted checkpoint and its snapshot.
+func (v *Verifier) Trusted() (*checkpoint.StableCheckpoint, *Snapshot) {
+	return v.trusted, v.snap
+}
+
+// Membership returns the membership expected to certify the next checkpoint.
+func (v *Verifier) Membership() (*mirproto.Membership, error) {
+	mbs := v.trusted.Memberships()
+	if len(mbs) == 0 {
+		return nil, fmt.Errorf("trusted checkpoint at height %d without membership", v.snap.Height)
+	}
+	return mbs[0], nil
+}
+
+// Advance verifies that the checkpoint follows the latest trusted checkpoint and trusts it.
+func (v *Verifier) Advance(ch *checkpoint.StableCheckpoint) (*Snapshot, error) {
+	snap, err := VerifySuccessor(v.trusted, ch)
+	if err != nil {
+		return nil, err
+	}
+	v.trusted, v.snap = ch, snap
+	return snap, nil
+}
@@ -0,0 +1,222 @@
+package lightclient
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	filcrypto "github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	tt "github.com/filecoin-project/mir/pkg/trantor/types"
+	mirtypes "github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/compression"
+	"github.com/filecoin-project/lotus/lib/sigs"
+)
+
+type testValidator struct {
+	priv []byte
+	v    *validator.Validator
+}
+
+func newTestValidators(t *testing.T, n int) ([]testValidator, *validator.Set) {
+	var (
+		tvs []testValidator
+		vs  []*validator.Validator
+	)
+	for i := 0; i < n; i++ {
+		priv, err := sigs.Generate(filcrypto.SigTypeSecp256k1)
+		require.NoError(t, err)
+		pub, err := sigs.ToPublic(filcrypto.SigTypeSecp256k1, priv)
+		require.NoError(t, err)
+		addr, err := address.NewSecp256k1Address(pub)
+		require.NoError(t, err)
+		v, err := validator.NewValidatorFromString(fmt.Sprintf("%s:1@/ip4/127.0.0.1/tcp/%d", addr, 10000+i))
+		require.NoError(t, err)
+		tvs = append(tvs, testValidator{priv: priv, v: v})
+		vs = append(vs, v)
+	}
+	return tvs, validator.NewValidatorSet(0, vs)
+}
+
+func testMembership(set *validator.Set) *mirproto.Membership {
+	mb := &mirproto.Membership{Nodes: map[mirtypes.NodeID]*mirproto.NodeIdentity{}}
+	for _, v := range set.Validators {
+		id := mirtypes.NodeID(v.ID())
+		mb.Nodes[id] = &mirproto.NodeIdentity{Id: id, Addr: v.NetAddr, Weight: tt.VoteWeight(v.Weight.String())}
+	}
+	return mb
+}
+
+// encodeSnapshot encodes a snapshot like the Checkpoint type of the Mir consensus, with empty trailing fields.
+func encodeSnapshot(t *testing.T, h abi.ChainEpoch, cids []cid.Cid, parentHeight abi.ChainEpoch, parent cid.Cid) []byte {
+	buf := new(bytes.Buffer)
+	cw := cbg.NewCborWriter(buf)
+	require.NoError(t, cw.WriteMajorTypeHeader(cbg.MajArray, 6))
+	require.NoError(t, cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(h)))
+	require.NoError(t, cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(cids))))
+	for _, c := range cids {
+		require.NoError(t, cbg.WriteCid(cw, c))
+	}
+	require.NoError(t, cw.WriteMajorTypeHeader(cbg.MajArray, 2))
+	require.NoError(t, cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(parentHeight)))
+	require.NoError(t, cbg.WriteCid(cw, parent))
+	require.NoError(t, cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, 0))
+	require.NoError(t, cw.WriteMajorTypeHeader(cbg.MajArray, 0))
+	require.NoError(t, cw.WriteMajorTypeHeader(cbg.MajArray, 0))
+	return buf.Bytes()
+}
+
+func blockCid(t *testing.T, i int) cid.Cid {
+	h, err := multihash.Sum([]byte{byte(i)}, abi.HashFunction, -1)
+	require.NoError(t, err)
+	return cid.NewCidV1(abi.CidBuilder.GetCodec(), h)
+}
+
+// capturingVerifier records the data signed for a checkpoint certificate.
+type capturingVerifier struct {
+	data *[][]byte
+}
+
+func (v capturingVerifier) Verify(data [][]byte, _ []byte, _ mirtypes.NodeID) error {
+	*v.data = data
+	return nil
+}
+
+// signedCheckpoint creates a checkpoint signed by the validators, whose next epoch is run by next.
+func signedCheckpoint(t *testing.T, appData []byte, signers []testValidator, prev, next *mirproto.Membership) *checkpoint.StableCheckpoint {
+	ch := &checkpoint.StableCheckpoint{
+		Sn: 10,
+		Snapshot: &mirproto.StateSnapshot{
+			AppData: appData,
+			EpochData: &mirproto.EpochData{
+				EpochConfig:        &mirproto.EpochConfig{EpochNr: 2, Memberships: []*mirproto.Membership{next}},
+				ClientProgress:     &mirproto.ClientProgress{},
+				PreviousMembership: prev,
+			},
+		},
+		Cert: checkpoint.Certificate{},
+	}
+	for id := range prev.Nodes {
+		ch.Cert[id] = nil
+	}
+	var data [][]byte
+	require.NoError(t, ch.VerifyCert(crypto.SHA256, capturingVerifier{&data}, prev))
+
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	ch.Cert = checkpoint.Certificate{}
+	for _, s := range signers {
+		sig, err := sigs.Sign(filcrypto.SigTypeSecp256k1, s.priv, h.Sum(nil))
+		require.NoError(t, err)
+		b, err := sig.MarshalBinary()
+		require.NoError(t, err)
+		ch.Cert[mirtypes.NodeID(s.v.ID())] = b
+	}
+	return ch
+}
+
+func TestDecodeSnapshot(t *testing.T) {
+	b := encodeSnapshot(t, 12, []cid.Cid{blockCid(t, 11), blockCid(t, 10)}, 10, blockCid(t, 0))
+	snap, err := DecodeSnapshot(b)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(12), snap.Height)
+	require.Equal(t, abi.ChainEpoch(10), snap.ParentHeight)
+	require.Equal(t, blockCid(t, 0), snap.ParentCid)
+	h, err := multihash.Sum(b, abi.HashFunction, -1)
+	require.NoError(t, err)
+	require.Equal(t, cid.NewCidV1(abi.CidBuilder.GetCodec(), h), snap.Cid)
+
+	require.True(t, snap.Commits(11, blockCid(t, 11)))
+	require.True(t, snap.Commits(10, blockCid(t, 10)))
+	require.False(t, snap.Commits(10, blockCid(t, 11)))
+	require.False(t, snap.Commits(9, blockCid(t, 9)))
+	require.False(t, snap.Commits(12, blockCid(t, 11)))
+
+	// Compressed snapshots have the CID of the uncompressed snapshot.
+	compressed, err := DecodeSnapshot(compression.Compress(b))
+	require.NoError(t, err)
+	require.Equal(t, snap, compressed)
+
+	_, err = DecodeSnapshot(b[:len(b)/2])
+	require.Error(t, err)
+	// The checkpoint must commit every block since its parent.
+	_, err = DecodeSnapshot(encodeSnapshot(t, 12, []cid.Cid{blockCid(t, 11)}, 10, blockCid(t, 0)))
+	require.Error(t, err)
+}
+
+func TestVerifyFinality(t *testing.T) {
+	tvs, set := newTestValidators(t, 4)
+	mb := testMembership(set)
+	appData := encodeSnapshot(t, 12, []cid.Cid{blockCid(t, 11), blockCid(t, 10)}, 10, blockCid(t, 0))
+
+	ch := signedCheckpoint(t, appData, tvs[:2], mb, mb)
+	require.NoError(t, VerifyFinality(ch, set, 11, blockCid(t, 11)))
+	require.Error(t, VerifyFinality(ch, set, 11, blockCid(t, 10)))
+
+	// Not finalized by another validator set.
+	_, other := newTestValidators(t, 4)
+	require.Error(t, VerifyFinality(ch, other, 11, blockCid(t, 11)))
+
+	// Nor without a weak quorum of signatures.
+	ch = signedCheckpoint(t, appData, tvs[:1], mb, mb)
+	require.Error(t, VerifyFinality(ch, set, 11, blockCid(t, 11)))
+
+	// Signatures over another snapshot are rejected.
+	ch = signedCheckpoint(t, appData, tvs[:2], mb, mb)
+	ch.Snapshot.AppData = encodeSnapshot(t, 12, []cid.Cid{blockCid(t, 1), blockCid(t, 10)}, 10, blockCid(t, 0))
+	require.Error(t, VerifyFinality(ch, set, 11, blockCid(t, 1)))
+}
+
+func TestVerifier(t *testing.T) {
+	tvs, set := newTestValidators(t, 4)
+	mb := testMembership(set)
+	nextVs, nextSet := newTestValidators(t, 1)
+	nextMb := testMembership(nextSet)
+
+	anchorData := encodeSnapshot(t, 10, []cid.Cid{blockCid(t, 9)}, 9, blockCid(t, 0))
+	anchor := signedCheckpoint(t, anchorData, tvs, mb, mb)
+	v, err := NewVerifier(anchor)
+	require.NoError(t, err)
+	got, err := v.Membership()
+	require.NoError(t, err)
+	require.Equal(t, mb, got)
+	_, anchorSnap := v.Trusted()
+
+	// The next checkpoint must be certified by the membership committed by the anchor, and hand over
+	// to the new membership.
+	next := signedCheckpoint(t, encodeSnapshot(t, 12, []cid.Cid{blockCid(t, 11), blockCid(t, 10)}, 10, anchorSnap.Cid), tvs[:2], mb, nextMb)
+	snap, err := v.Advance(next)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(12), snap.Height)
+	got, err = v.Membership()
+	require.NoError(t, err)
+	require.Equal(t, nextMb, got)
+
+	// A checkpoint signed by the previous membership is rejected.
+	stale := signedCheckpoint(t, encodeSnapshot(t, 14, []cid.Cid{blockCid(t, 13), blockCid(t, 12)}, 12, snap.Cid), tvs[:2], mb, mb)
+	_, err = v.Advance(stale)
+	require.Error(t, err)
+
+	// So is a checkpoint that doesn't follow the trusted one.
+	fork := signedCheckpoint(t, encodeSnapshot(t, 14, []cid.Cid{blockCid(t, 13), blockCid(t, 12)}, 12, anchorSnap.Cid), nextVs, nextMb, nextMb)
+	_, err = v.Advance(fork)
+	require.Error(t, err)
+
+	ok := signedCheckpoint(t, encodeSnapshot(t, 14, []cid.Cid{blockCid(t, 13), blockCid(t, 12)}, 12, snap.Cid), nextVs, nextMb, nextMb)
+	_, err = v.Advance(ok)
+	require.NoError(t, err)
+}
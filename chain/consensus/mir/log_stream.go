@@ -0,0 +1,116 @@
+package mir
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"strings"
+
+	ipfslogging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+)
+
+// LogComponents are the loggers of the Mir subsystems of the validator.
+var LogComponents = []string{"mir-consensus", managerLoggerName, "mir-attestation"}
+
+// logStreamBuffer is the number of entries buffered for a slow reader before dropping them.
+const logStreamBuffer = 256
+
+// LogEntry is a log line of a Mir subsystem.
+type LogEntry struct {
+	Time      string
+	Level     string
+	Component string
+	Caller    string
+	Message   string
+	Fields    map[string]interface{} `json:",omitempty"`
+}
+
+// logComponent returns the logger of component, which can be given with or without the "mir-" prefix.
+func logComponent(component string) (string, error) {
+	for _, c := range LogComponents {
+		if component == c || "mir-"+component == c {
+			return c, nil
+		}
+	}
+	return "", xerrors.Errorf("unknown component %q, expected one of %s", component, strings.Join(LogComponents, ", "))
+}
+
+// TailLogs streams the log entries of the Mir subsystems at level or above until ctx is done.
+// If component isn't empty, only the entries of that subsystem are streamed. Entries below the
+// level configured for the subsystem are never logged, so they are not streamed either.
+//
+// Logging doesn't wait for the reader: the entries that don't fit in the buffer are dropped.
+func TailLogs(ctx context.Context, component, level string) (<-chan LogEntry, error) {
+	components := LogComponents
+	if component != "" {
+		c, err := logComponent(component)
+		if err != nil {
+			return nil, err
+		}
+		components = []string{c}
+	}
+	lvl, err := ipfslogging.LevelFromString(level)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid log level %q: %w", level, err)
+	}
+
+	r := ipfslogging.NewPipeReader(ipfslogging.PipeFormat(ipfslogging.JSONOutput), ipfslogging.PipeLevel(lvl))
+	go func() {
+		<-ctx.Done()
+		_ = r.Close()
+	}()
+
+	out := make(chan LogEntry, logStreamBuffer)
+	go func() {
+		defer close(out)
+		s := bufio.NewScanner(r)
+		s.Buffer(make([]byte, 64<<10), 1<<20)
+		for s.Scan() {
+			e, ok := parseLogEntry(s.Bytes(), components)
+			if !ok {
+				continue
+			}
+			// Not logging the drops, as they would be streamed too.
+			select {
+			case out <- e:
+			default:
+			}
+		}
+	}()
+	return out, nil
+}
+
+// parseLogEntry parses a JSON log line, returning false if it isn't from one of components.
+func parseLogEntry(line []byte, components []string) (LogEntry, bool) {
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return LogEntry{}, false
+	}
+	pop := func(k string) string {
+		v, _ := fields[k].(string)
+		delete(fields, k)
+		return v
+	}
+	e := LogEntry{
+		Time:      pop("ts"),
+		Level:     pop("level"),
+		Component: pop("logger"),
+		Caller:    pop("caller"),
+		Message:   pop("msg"),
+	}
+	found := false
+	for _, c := range components {
+		if e.Component == c {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return LogEntry{}, false
+	}
+	if len(fields) > 0 {
+		e.Fields = fields
+	}
+	return e, true
+}
@@ -0,0 +1,70 @@
+package mir
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ipfslogging "github.com/ipfs/go-log/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTailLogs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, ipfslogging.SetLogLevel("mir-consensus", "info"))
+	require.NoError(t, ipfslogging.SetLogLevel(managerLoggerName, "info"))
+
+	_, err := TailLogs(ctx, "pool", "info")
+	require.Error(t, err)
+	_, err = TailLogs(ctx, "consensus", "loud")
+	require.Error(t, err)
+
+	entries, err := TailLogs(ctx, "consensus", "warn")
+	require.NoError(t, err)
+
+	ipfslogging.Logger(managerLoggerName).Warnw("other component")
+	log.Infow("below level")
+	log.Warnw("streamed", "height", 7)
+
+	select {
+	case e := <-entries:
+		require.Equal(t, "mir-consensus", e.Component)
+		require.Equal(t, "warn", e.Level)
+		require.Equal(t, "streamed", e.Message)
+		require.Equal(t, float64(7), e.Fields["height"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("log entry not streamed")
+	}
+
+	cancel()
+	require.Eventually(t, func() bool {
+		select {
+		case _, ok := <-entries:
+			return !ok
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestParseLogEntry(t *testing.T) {
+	line := []byte(`{"level":"info","ts":"2023-04-01T10:00:00.000Z","logger":"mir-manager","caller":"mir/manager.go:10","msg":"started","nodeID":"t1"}`)
+
+	e, ok := parseLogEntry(line, LogComponents)
+	require.True(t, ok)
+	require.Equal(t, LogEntry{
+		Time:      "2023-04-01T10:00:00.000Z",
+		Level:     "info",
+		Component: "mir-manager",
+		Caller:    "mir/manager.go:10",
+		Message:   "started",
+		Fields:    map[string]interface{}{"nodeID": "t1"},
+	}, e)
+
+	_, ok = parseLogEntry(line, []string{"mir-consensus"})
+	require.False(t, ok)
+	_, ok = parseLogEntry([]byte("not json"), LogComponents)
+	require.False(t, ok)
+}
@@ -0,0 +1,33 @@
+package mir
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+
+	mirmembership "github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+)
+
+// updateMaintenance replaces the maintenance schedule of the validator with the one provided by
+// the membership, unless it would leave the validator set without enough active validators.
+func (m *Manager) updateMaintenance(info *mirmembership.Info) {
+	if err := info.Maintenance.Validate(info.ValidatorSet); err != nil {
+		log.With("validator", m.id).Errorf("refusing unsafe maintenance schedule: %v", err)
+		return
+	}
+	m.maintenance = info.Maintenance
+}
+
+// inMaintenance returns whether the validator is in one of its maintenance windows at height h.
+// During a window the validator keeps ordering the batches of the other validators but only proposes
+// configuration transactions, and it resumes proposing messages automatically when the window ends.
+func (m *Manager) inMaintenance(h abi.ChainEpoch) bool {
+	in := m.maintenance.InMaintenance(m.id, h)
+	if in != m.maintenanceActive {
+		if in {
+			log.With("validator", m.id).Infof("maintenance window started at height %d: pausing proposals", h)
+		} else {
+			log.With("validator", m.id).Infof("maintenance window ended at height %d: resuming proposals", h)
+		}
+		m.maintenanceActive = in
+	}
+	return in
+}
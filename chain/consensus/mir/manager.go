@@ -11,9 +11,12 @@ import (
 	"time"
 
 	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/ipfs/go-cid"
 	golog "github.com/ipfs/go-log/v2"
+	"go.opencensus.io/stats"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/mir"
 	"github.com/filecoin-project/mir/pkg/checkpoint"
@@ -21,6 +24,7 @@ import (
 	"github.com/filecoin-project/mir/pkg/eventlog"
 	"github.com/filecoin-project/mir/pkg/eventmangler"
 	"github.com/filecoin-project/mir/pkg/logging"
+	"github.com/filecoin-project/mir/pkg/modules"
 	"github.com/filecoin-project/mir/pkg/net"
 	mirlibp2p "github.com/filecoin-project/mir/pkg/net"
 	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
@@ -33,7 +37,9 @@ import (
 	mirmembership "github.com/filecoin-project/lotus/chain/consensus/mir/membership"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/pool"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/pool/fifo"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetparams"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/metrics"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
@@ -70,15 +76,55 @@ type Manager struct {
 	txPool          *fifo.Pool
 	net             net.Transport
 	interceptor     *eventlog.Recorder
+	contextRecorder *contextRecorder
 	readyForTxsChan chan chan []*mirproto.Transaction
 	stopped         bool
 	cryptoManager   *CryptoManager
 	confManager     *ConfigurationManager
 	stateManager    *StateManager
 
+	// Notifies the Serve loop that the pending configuration transactions have been changed through the admin API.
+	confUpdated chan struct{}
+
 	// Reconfiguration types.
 	initialValidatorSet *validator.Set
 	membership          mirmembership.Reader
+
+	// Maximum number of transactions proposed in a batch.
+	maxTxsInBatch int
+
+	// Client used to propose encrypted transactions, from the EncryptedTxs upgrade.
+	encryptedClient *encryptedTxsClient
+	// Client used to propose the timestamps of the validator.
+	timestampClient *timestampClient
+
+	// External sources of messages proposed in addition to the mempool ones.
+	txSources []TxSource
+
+	// Mempool bucketing support.
+	disableBucketing bool
+
+	// Retries of failed mempool selections, and whether the batches proposed without them are logged quietly.
+	mpoolSelectRetries   int
+	quietSelectionErrors bool
+
+	// Number of epochs over which the size of the proposed batches ramps up after a (re)start.
+	rampUpEpochs int
+
+	// Maintenance windows of the validators, and whether this validator is in one of them.
+	maintenance       *mirmembership.MaintenanceSchedule
+	maintenanceActive bool
+
+	// Effective configuration of the validator.
+	startupReport *StartupReport
+
+	// Retention of the checkpoints persisted in the datastore and in the checkpoint repo.
+	checkpointRetention     CheckpointRetention
+	checkpointRepo          string
+	checkpointFileRetention CheckpointFileRetention
+
+	// Called with the messages proposed by the validator.
+	onMsgEvent func(*MsgEvent)
 }
 
 func NewManager(ctx context.Context,
@@ -107,9 +153,15 @@ func NewManager(ctx context.Context,
 	if err := validateMembership(initialMembership); err != nil {
 		return nil, err
 	}
+	if err := validateCheckpointPeriod(cfg.Consensus, initialMembership); err != nil {
+		return nil, fmt.Errorf("validator %v refuses configuration: %w", id, err)
+	}
 	if err := validateMembershipInfo(membershipInfo); err != nil {
 		return nil, err
 	}
+	if err := membershipInfo.Maintenance.Validate(membershipInfo.ValidatorSet); err != nil {
+		return nil, fmt.Errorf("validator %v refuses unsafe maintenance schedule: %w", id, err)
+	}
 
 	e := membershipInfo.GenesisEpoch
 	initialValidatorSet := membershipInfo.ValidatorSet
@@ -134,26 +186,53 @@ func NewManager(ctx context.Context,
 	}
 
 	m := Manager{
-		ctx:                 ctx,
-		id:                  id,
-		ds:                  ds,
-		netName:             netName,
-		lotusNode:           node,
-		readyForTxsChan:     make(chan chan []*mirproto.Transaction),
-		txPool:              fifo.New(),
-		cryptoManager:       cryptoManager,
-		confManager:         confManager,
-		net:                 net,
-		initialValidatorSet: initialValidatorSet,
-		membership:          membership,
+		ctx:                     ctx,
+		id:                      id,
+		ds:                      ds,
+		netName:                 netName,
+		lotusNode:               node,
+		readyForTxsChan:         make(chan chan []*mirproto.Transaction),
+		confUpdated:             make(chan struct{}, 1),
+		txPool:                  fifo.New(),
+		cryptoManager:           cryptoManager,
+		confManager:             confManager,
+		net:                     net,
+		initialValidatorSet:     initialValidatorSet,
+		membership:              membership,
+		maxTxsInBatch:           cfg.Consensus.MaxTransactionsInBatch,
+		disableBucketing:        cfg.Consensus.DisableMempoolBucketing,
+		txSources:               cfg.TxSources,
+		mpoolSelectRetries:      cfg.Consensus.MpoolSelectRetries,
+		quietSelectionErrors:    cfg.Consensus.QuietSelectionErrors,
+		rampUpEpochs:            cfg.Consensus.RampUpEpochs,
+		maintenance:             membershipInfo.Maintenance,
+		checkpointRetention:     cfg.CheckpointRetention,
+		checkpointRepo:          cfg.CheckpointRepo,
+		checkpointFileRetention: cfg.CheckpointFileRetention,
+		onMsgEvent:              cfg.OnMsgEvent,
 	}
 	m.mirStopped = make(chan struct{})
 	m.mirCtx, m.mirCancel = context.WithCancel(context.Background())
 
+	m.encryptedClient, err = newEncryptedTxsClient(ctx, ds, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.timestampClient, err = newTimestampClient(ctx, ds, id)
+	if err != nil {
+		return nil, err
+	}
+
 	m.stateManager, err = NewStateManager(ctx, m.netName, initialMembership, abi.ChainEpoch(e), m.confManager, node, ds, m.txPool, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("validator %v failed to start mir state manager: %w", id, err)
 	}
+	m.stateManager.encryptedClient = m.encryptedClient
+	m.stateManager.timestampClient = m.timestampClient
+	if mm, ok := membership.(*MigratingMembership); ok {
+		mm.setEpochSource(func() uint64 { return uint64(m.stateManager.OrderedEpoch()) })
+	}
 
 	params := trantor.DefaultParams(initialMembership)
 	params.Iss.SegmentLength = cfg.Consensus.SegmentLength // Segment length determining the checkpoint period.
@@ -163,6 +242,9 @@ func NewManager(ctx context.Context,
 	params.Iss.PBFTViewChangeSegmentTimeout = cfg.Consensus.PBFTViewChangeSegmentTimeout
 	params.Mempool.MaxTransactionsInBatch = cfg.Consensus.MaxTransactionsInBatch
 	params.Mempool.TxFetcher = pool.NewFetcher(ctx, m.readyForTxsChan).Fetch
+	if !cfg.Consensus.DisableParamsPresets {
+		ScaleParams(&params, len(initialMembership.Nodes))
+	}
 
 	initCh := cfg.InitialCheckpoint
 	// if no initial checkpoint provided in config
@@ -173,6 +255,9 @@ func NewManager(ctx context.Context,
 		}
 	}
 
+	m.startupReport = newStartupReport(cfg, string(netName), membershipInfo, params, initCh, net)
+	m.startupReport.log()
+
 	smrSystem, err := trantor.New(
 		t.NodeID(id),
 		net,
@@ -187,6 +272,11 @@ func NewManager(ctx context.Context,
 	}
 
 	smrSystem = smrSystem.WithModule("hasher", mircrypto.NewHasher(crypto.SHA256)) // to use sha256 hash from cryptomodule.
+	if store, ok := smrSystem.Modules()[batchDBModuleID].(modules.PassiveModule); ok {
+		smrSystem = smrSystem.WithModule(batchDBModuleID, newBatchStoreMonitor(ctx, id, store, cfg.Consensus.BatchStoreCap))
+	} else {
+		log.With("validator", id).Warnf("batch store module %s not found, its usage is not reported", batchDBModuleID)
+	}
 
 	// -------------------------------------------------------------------------
 	// Mir's mangler support.
@@ -215,17 +305,21 @@ func NewManager(ctx context.Context,
 
 	// TODO: Persist in repo path?
 	var recorder *eventlog.Recorder
+	var recordingDir string
+	recordingStart := time.Now()
 	switch {
 	case os.Getenv(InterceptorOutputEnv) != "":
+		recordingDir = path.Join(os.Getenv(InterceptorOutputEnv), cfg.GroupName, id)
 		recorder, err = eventlog.NewRecorder(
 			t.NodeID(id),
-			path.Join(os.Getenv(InterceptorOutputEnv), cfg.GroupName, id),
+			recordingDir,
 			logging.Decorate(logger, "Interceptor: "),
 		)
 	case os.Getenv(InterceptorWithEventsOutputEnv) != "":
+		recordingDir = path.Join(os.Getenv(InterceptorWithEventsOutputEnv), cfg.GroupName, id)
 		recorder, err = eventlog.NewRecorder(
 			t.NodeID(id),
-			path.Join(os.Getenv(InterceptorWithEventsOutputEnv), cfg.GroupName, id),
+			recordingDir,
 			logging.Decorate(logger, "Interceptor: "),
 			eventlog.FileSplitterOpt(eventlog.EventLimitLogger(InterceptorEventsPerFile)),
 		)
@@ -236,6 +330,15 @@ func NewManager(ctx context.Context,
 	}
 	m.interceptor = recorder
 
+	// The state of the request pool and the pending configuration is recorded along with the events,
+	// so they can be replayed in context.
+	if recorder != nil {
+		m.contextRecorder, err = newContextRecorder(recordingDir, recordingStart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create interceptor context recorder: %w", err)
+		}
+	}
+
 	// -------------------------------------------------------------------------
 	// Mir node initialization.
 	nodeCfg := mir.DefaultNodeConfig().WithLogger(logger)
@@ -255,6 +358,9 @@ func (m *Manager) Serve(ctx context.Context) error {
 		Infof("Mir info:\n\tNetwork - %v\n\tValidator ID - %v\n\tMir peerID - %v\n\tValidators - %v",
 			m.netName, m.id, m.id, m.initialValidatorSet.GetValidators())
 
+	// Validate the backlog of the mempool before joining the ordering.
+	m.warmup(ctx)
+
 	go func() {
 		// Run Mir node until it stops.
 		// We pass a new cancellable context to Run() to be sure that if the Lotus context is closed then the Mir
@@ -266,6 +372,28 @@ func (m *Manager) Serve(ctx context.Context) error {
 	}()
 	defer m.stop()
 
+	// Repos created before the checkpoint indices existed have them populated from the chain once.
+	go func() {
+		n, err := BackfillCheckpointIndices(ctx, m.ds, m.lotusNode)
+		if err != nil {
+			log.With("validator", m.id).Warnf("failed to backfill checkpoint indices: %v", err)
+		} else if n > 0 {
+			log.With("validator", m.id).Infof("backfilled the indices of %d checkpoints from the chain", n)
+		}
+	}()
+
+	if m.checkpointRetention.enabled() || m.checkpointRepo != "" && m.checkpointFileRetention.enabled() {
+		gcCtx, cancelGC := context.WithCancel(ctx)
+		defer cancelGC()
+		go m.gcCheckpoints(gcCtx)
+	}
+
+	if m.contextRecorder != nil {
+		recCtx, cancelRec := context.WithCancel(ctx)
+		defer cancelRec()
+		go m.recordContext(recCtx)
+	}
+
 	reconfigure := time.NewTicker(ReconfigurationInterval)
 	defer reconfigure.Stop()
 
@@ -274,7 +402,9 @@ func (m *Manager) Serve(ctx context.Context) error {
 		return fmt.Errorf("validator %v failed to get pending confgiguration txs: %w", m.id, err)
 	}
 
-	lastValidatorSet := m.initialValidatorSet
+	lastValidatorSet := m.restoreLastValidatorSet()
+	buckets := newMempoolBuckets(m.id, lastValidatorSet)
+	ramp := newBatchRamp(m.maxTxsInBatch, m.rampUpEpochs)
 
 	for {
 		select {
@@ -285,6 +415,19 @@ func (m *Manager) Serve(ctx context.Context) error {
 		case <-m.mirStopped:
 			return fmt.Errorf("mir stopped with err %w", m.mirErr)
 
+		case err := <-m.stateManager.StuckHead():
+			// Stopping the validator makes it restart from the latest checkpoint.
+			return fmt.Errorf("validator %v stopped to recover from the latest checkpoint: %w", m.id, err)
+
+		case ch := <-m.stateManager.Diverged():
+			// Stop proposing batches on top of a chain the rest of validators don't have,
+			// and restore the chain certified by the checkpoint before restarting.
+			m.stop()
+			if err := m.stateManager.recoverFromCheckpoint(ch); err != nil {
+				log.With("validator", m.id).Errorf("failed to recover from the checkpoint at height %d: %v", ch.Height, err)
+			}
+			return fmt.Errorf("validator %v stopped: chain diverged from the checkpoint at height %d", m.id, ch.Height)
+
 		case <-reconfigure.C:
 			// Send a reconfiguration transaction if the validator set in the actor has been changed.
 			mInfo, err := m.membership.GetMembershipInfo()
@@ -292,8 +435,9 @@ func (m *Manager) Serve(ctx context.Context) error {
 				log.With("validator", m.id).Warnf("failed to get subnet validators: %v", err)
 				continue
 			}
+			m.updateMaintenance(mInfo)
 			newSet := mInfo.ValidatorSet
-			if lastValidatorSet.Equal(newSet) {
+			if !validatorSetChanged(lastValidatorSet, newSet) {
 				continue
 			}
 
@@ -302,32 +446,100 @@ func (m *Manager) Serve(ctx context.Context) error {
 					newSet.ConfigurationNumber, newSet.Size(), newSet.GetValidatorIDs())
 
 			lastValidatorSet = newSet
+			if err := m.confManager.StoreLastValidatorSet(newSet); err != nil {
+				log.With("validator", m.id).Warnf("failed to persist validator set %d: %v", newSet.ConfigurationNumber, err)
+			}
+			buckets = newMempoolBuckets(m.id, newSet)
 			r := m.createAndStoreConfigurationTx(newSet)
 			if r != nil {
 				configTxs = append(configTxs, r)
 			}
 
+		case <-m.confUpdated:
+			// Propose the submitted transactions and stop resending the cancelled ones.
+			txs, err := m.confManager.Pending()
+			if err != nil {
+				log.With("validator", m.id).Warnf("failed to reload pending configuration txs: %v", err)
+				continue
+			}
+			configTxs = txs
+
 		case mirChan := <-m.readyForTxsChan:
 			if ctx.Err() != nil {
 				log.With("validator", m.id).Info("Mir manager: context closed before calling ChainHead")
 				return nil
 			}
-			base, err := m.lotusNode.ChainHead(ctx)
+			base, err := m.stateManager.heads.Head(ctx)
 			if err != nil {
 				return xerrors.Errorf("validator %v failed to get chain head: %w", m.id, err)
 			}
+			// The timestamp of the validator is proposed with every batch, after the upgrade.
+			var timestampTx *mirproto.Transaction
+			if m.stateManager.upgrades.batchTimestamps(base.Height() + 1) {
+				timestampTx = m.timestampClient.Propose(time.Now())
+			}
+			// Configuration transactions are always proposed. Encrypted transactions can take up to
+			// half of the rest of the batch, and the messages of the external sources and the mempool
+			// fill the remaining space, in this order.
+			budget := ramp.limit(uint64(m.stateManager.OrderedEpoch())) - len(configTxs)
+			if m.inMaintenance(base.Height() + 1) {
+				budget = 0
+			}
+			var txs []*mirproto.Transaction
+
+			if m.stateManager.upgrades.encryptedTxs(base.Height()+1) && budget > 0 {
+				etxs, err := m.lotusNode.MirPendingEncryptedTxs(ctx, 0)
+				if err != nil {
+					log.With("validator", m.id).Errorw("failed to get pending encrypted txs", "error", err)
+				}
+				txs = m.encryptedClient.Propose(etxs, (budget+1)/2)
+				budget -= len(txs)
+			}
+
+			var external []*types.SignedMessage
+			if len(m.txSources) > 0 && budget > 0 {
+				external = selectFromSources(ctx, m.id, m.txSources, budget)
+				budget -= len(external)
+			}
+
 			log.With("validator", m.id).Debugf("selecting messages from mempool for base: %v", base.Key())
-			msgs, err := m.lotusNode.MpoolSelect(ctx, base.Key(), 1)
+			msgs, err := selectWithRetries(ctx, m.mpoolSelectRetries, func() ([]*types.SignedMessage, error) {
+				return m.lotusNode.MpoolSelect(ctx, base.Key(), 1)
+			})
 			if err != nil {
-				log.With("validator", m.id).With("epoch", base.Height()).
-					Errorw("failed to select messages from mempool", "error", err)
+				// The batch is still proposed so that the configuration and external transactions are ordered,
+				// but it is marked as lacking the mempool messages so the gaps in the cadence can be explained.
+				stats.Record(ctx, metrics.MirBatchesWithoutMempool.M(1))
+				l := log.With("validator", m.id, "epoch", base.Height(), "error", err)
+				if m.quietSelectionErrors {
+					l.Debug("proposing batch without mempool messages: selection failed")
+				} else {
+					l.Warn("proposing batch without mempool messages: selection failed")
+				}
+			}
+			if !m.disableBucketing {
+				// Only propose the messages of the senders assigned to this validator in the Mir epoch being ordered.
+				msgs = buckets.filter(msgs, uint64(m.stateManager.OrderedEpoch()))
+			}
+			if budget < 0 {
+				budget = 0
+			}
+			if len(msgs) > budget {
+				msgs = msgs[:budget]
 			}
+			msgs = limitGas(mergeMessages(external, msgs), subnetparams.BlockGasLimit(string(m.netName)))
 
-			txs := m.createTransportTxs(msgs)
+			txs = append(txs, m.createTransportTxs(msgs)...)
 
 			if len(configTxs) > 0 {
 				txs = append(txs, configTxs...)
 			}
+			if timestampTx != nil {
+				txs = append(txs, timestampTx)
+			}
+
+			log.With("validator", m.id, "epoch", m.stateManager.OrderedEpoch(), "batch", batchID(txs)).
+				Debugf("proposing batch over base %d: txs - %d", base.Height(), len(txs))
 
 			select {
 			case <-ctx.Done():
@@ -339,6 +551,97 @@ func (m *Manager) Serve(ctx context.Context) error {
 	}
 }
 
+// PendingConfigurationRequests returns the configuration transactions of the validator that have not been applied yet.
+func (m *Manager) PendingConfigurationRequests() ([]PendingConfigurationRequest, error) {
+	return m.confManager.PendingRequests()
+}
+
+// CancelConfigurationRequest cancels a pending configuration transaction of the validator,
+// so it is not proposed to Mir anymore.
+func (m *Manager) CancelConfigurationRequest(txNo uint64) error {
+	if err := m.confManager.CancelPending(txNo); err != nil {
+		return err
+	}
+	m.notifyConfUpdated()
+	return nil
+}
+
+// SubmitConfigurationRequest proposes a validator set to the rest of validators on behalf of the operator,
+// bypassing the membership source. It returns the number of the configuration transaction.
+func (m *Manager) SubmitConfigurationRequest(set *validator.Set) (uint64, error) {
+	if set == nil || set.Size() == 0 {
+		return 0, xerrors.New("empty validator set")
+	}
+	if _, _, err := mirmembership.Membership(set.GetValidators()); err != nil {
+		return 0, xerrors.Errorf("invalid validator set: %w", err)
+	}
+
+	r := m.createAndStoreConfigurationTx(set)
+	if r == nil {
+		return 0, xerrors.Errorf("validator %v failed to create configuration tx", m.id)
+	}
+	log.With("validator", m.id).
+		Infof("operator submitted validator set: number: %d, size: %d, tx: %d",
+			set.ConfigurationNumber, set.Size(), r.TxNo)
+
+	m.notifyConfUpdated()
+	return uint64(r.TxNo), nil
+}
+
+// RequestShutdown votes for freezing the subnet at height h. Once a strong quorum of the validators
+// vote for the same height, no blocks are created above it and the next checkpoint is the final one.
+func (m *Manager) RequestShutdown(h abi.ChainEpoch) (uint64, error) {
+	if h <= 0 {
+		return 0, xerrors.Errorf("invalid freeze height: %d", h)
+	}
+	if fh := m.stateManager.FreezeHeight(); fh > 0 {
+		return 0, xerrors.Errorf("subnet already frozen at height %d", fh)
+	}
+	data, err := encodeShutdownRequest(h)
+	if err != nil {
+		return 0, err
+	}
+	r, err := m.confManager.NewTX(ShutdownTransaction, data)
+	if err != nil {
+		return 0, xerrors.Errorf("validator %v failed to create shutdown tx: %w", m.id, err)
+	}
+	log.With("validator", m.id).Warnf("operator requested subnet shutdown at height %d, tx: %d", h, r.TxNo)
+
+	m.notifyConfUpdated()
+	return uint64(r.TxNo), nil
+}
+
+// NetDiagnostics returns the state of the connections of the validator to the rest of validators
+// of the membership. It requires the transport of the validator to be a DiagnosticTransport.
+func (m *Manager) NetDiagnostics() ([]PeerDiagnostics, error) {
+	d, ok := m.net.(*DiagnosticTransport)
+	if !ok {
+		return nil, xerrors.Errorf("validator %v: network diagnostics not available with transport %T", m.id, m.net)
+	}
+	return d.Diagnostics(), nil
+}
+
+// StartupReport returns the effective configuration the validator was started with.
+func (m *Manager) StartupReport() *StartupReport {
+	return m.startupReport
+}
+
+// ContributionStats returns the contributions of the validators to the batches of the epochs in [from, to],
+// including the epoch being ordered. Epochs above the one being ordered are ignored.
+func (m *Manager) ContributionStats(ctx context.Context, from, to uint64) ([]EpochContributions, error) {
+	if e := uint64(m.stateManager.OrderedEpoch()); to > e {
+		to = e
+	}
+	return m.stateManager.contributions.epochs(ctx, from, to)
+}
+
+func (m *Manager) notifyConfUpdated() {
+	select {
+	case m.confUpdated <- struct{}{}:
+	default:
+	}
+}
+
 // stop stops the manager and all its components.
 func (m *Manager) stop() {
 	log.With("validator", m.id).Infof("Mir manager stop() started")
@@ -362,6 +665,14 @@ func (m *Manager) stop() {
 			log.With("validator", m.id).Info("Interceptor stopped")
 		}
 	}
+	if m.contextRecorder != nil {
+		if err := m.contextRecorder.record(m.interceptorSnapshot()); err != nil {
+			log.With("validator", m.id).Warnf("failed to record interceptor snapshot: %v", err)
+		}
+		if err := m.contextRecorder.close(); err != nil {
+			log.With("validator", m.id).Errorf("Could not close interceptor context: %s", err)
+		}
+	}
 
 	m.net.Stop()
 	log.With("validator", m.id).Info("Network transport stopped")
@@ -379,13 +690,13 @@ func (m *Manager) initCheckpoint(params trantor.Params, height abi.ChainEpoch) (
 	return GetCheckpointByHeight(m.stateManager.ctx, m.ds, height, &params)
 }
 
-func (m *Manager) createTransportTxs(msgs []*types.SignedMessage) (txs []*mirproto.Transaction) {
-	txs = append(txs, m.batchSignedMessages(msgs)...)
-	return
+func (m *Manager) createTransportTxs(msgs []*types.SignedMessage) []*mirproto.Transaction {
+	return m.batchSignedMessages(msgs)
 }
 
 // batchPushSignedMessages pushes signed messages into the transactions pool and sends them to Mir.
-func (m *Manager) batchSignedMessages(msgs []*types.SignedMessage) (txs []*mirproto.Transaction) {
+func (m *Manager) batchSignedMessages(msgs []*types.SignedMessage) []*mirproto.Transaction {
+	txs := make([]*mirproto.Transaction, 0, len(msgs))
 	for _, msg := range msgs {
 		clientID := msg.Message.From.String()
 		nonce := msg.Message.Nonce
@@ -407,13 +718,87 @@ func (m *Manager) batchSignedMessages(msgs []*types.SignedMessage) (txs []*mirpr
 			Data:     data,
 		}
 
-		m.txPool.AddTx(msg.Cid(), r)
+		c := msg.Cid()
+		m.txPool.AddTx(c, r)
+		msgEvent(m.onMsgEvent, c, MsgProposed, 0)
 
 		txs = append(txs, r)
 	}
 	return txs
 }
 
+// mergeMessages appends the messages of b that are not in a to a.
+func mergeMessages(a, b []*types.SignedMessage) []*types.SignedMessage {
+	if len(a) == 0 {
+		return b
+	}
+	seen := make(map[cid.Cid]struct{}, len(a))
+	for _, msg := range a {
+		seen[msg.Cid()] = struct{}{}
+	}
+	for _, msg := range b {
+		if _, found := seen[msg.Cid()]; !found {
+			a = append(a, msg)
+		}
+	}
+	return a
+}
+
+// limitGas returns the longest prefix of msgs whose gas limits add up to at most limit, skipping the
+// messages that don't fit. Once a message is skipped, the later messages of its sender are skipped
+// too, so the nonces of the senders in the result have no gaps.
+func limitGas(msgs []*types.SignedMessage, limit int64) []*types.SignedMessage {
+	var (
+		gas     int64
+		skipped map[address.Address]struct{}
+	)
+	res := msgs[:0:0]
+	for _, msg := range msgs {
+		if _, ok := skipped[msg.Message.From]; ok {
+			continue
+		}
+		if gas+msg.Message.GasLimit > limit {
+			if skipped == nil {
+				skipped = make(map[address.Address]struct{})
+			}
+			skipped[msg.Message.From] = struct{}{}
+			continue
+		}
+		gas += msg.Message.GasLimit
+		res = append(res, msg)
+	}
+	return res
+}
+
+// restoreLastValidatorSet returns the validator set the validator proposed last before restarting,
+// so a set that was already proposed, and possibly applied, is not proposed again. If no set was
+// persisted, or the persisted set is outdated, the initial validator set is returned.
+func (m *Manager) restoreLastValidatorSet() *validator.Set {
+	set, err := m.confManager.LoadLastValidatorSet()
+	if err != nil {
+		log.With("validator", m.id).Warnf("failed to load last validator set: %v", err)
+		return m.initialValidatorSet
+	}
+	ch, err := m.stateManager.firstEpochCheckpoint()
+	if err != nil {
+		log.With("validator", m.id).Warnf("failed to get latest checkpoint: %v", err)
+		return m.initialValidatorSet
+	}
+	return reconcileValidatorSet(set, m.initialValidatorSet, ch.NextConfigNumber)
+}
+
+// reconcileValidatorSet returns the persisted validator set unless it is older than the initial set
+// or than the configuration number applied by the latest checkpoint.
+func reconcileValidatorSet(persisted, initial *validator.Set, applied uint64) *validator.Set {
+	if persisted == nil {
+		return initial
+	}
+	if persisted.ConfigurationNumber < applied || persisted.ConfigurationNumber < initial.ConfigurationNumber {
+		return initial
+	}
+	return persisted
+}
+
 func (m *Manager) createAndStoreConfigurationTx(set *validator.Set) *mirproto.Transaction {
 	var b bytes.Buffer
 	if err := set.MarshalCBOR(&b); err != nil {
@@ -556,5 +941,22 @@ func validateConfig(cfg *Config) error {
 	if cfg.Consensus.SegmentLength <= 0 {
 		return fmt.Errorf("segment length is not positive")
 	}
+	if cfg.Consensus.ExecutionLag < 0 {
+		return fmt.Errorf("execution lag is negative")
+	}
+	if cfg.Consensus.MaxCheckpointBlocks < 0 || cfg.Consensus.MaxCheckpointBlocks > MaxCheckpointBlocks {
+		return fmt.Errorf("max checkpoint blocks %d is not between 0 and %d", cfg.Consensus.MaxCheckpointBlocks, MaxCheckpointBlocks)
+	}
+	return nil
+}
+
+// validateCheckpointPeriod checks that the checkpoints of the membership, which commit SegmentLength
+// blocks per validator, don't commit more blocks than the maximum of the validator.
+func validateCheckpointPeriod(cfg *ConsensusConfig, m *mirproto.Membership) error {
+	period, limit := cfg.SegmentLength*len(m.Nodes), maxCheckpointBlocks(cfg)
+	if period > limit {
+		return fmt.Errorf("checkpoint period of %d blocks (SegmentLength %d, %d validators) above MaxCheckpointBlocks %d",
+			period, cfg.SegmentLength, len(m.Nodes), limit)
+	}
 	return nil
 }
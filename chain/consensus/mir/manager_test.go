@@ -6,10 +6,16 @@ import (
 	"time"
 
 	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/ipfs/go-datastore"
 	golog "github.com/ipfs/go-log/v2"
 	"github.com/stretchr/testify/require"
 
+	"github.com/filecoin-project/go-address"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	mirtypes "github.com/filecoin-project/mir/pkg/types"
+
 	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/chain/types"
 )
 
 type mockMembership struct {
@@ -47,3 +53,79 @@ func TestWaitForMembership(t *testing.T) {
 	require.NotNil(t, info)
 	require.NotNil(t, nodes)
 }
+
+func TestSubmitConfigurationRequest(t *testing.T) {
+	cm, err := NewConfigurationManager(context.Background(), datastore.NewMapDatastore(), "id1")
+	require.NoError(t, err)
+	m := &Manager{id: "id1", confManager: cm, confUpdated: make(chan struct{}, 1)}
+
+	_, err = m.SubmitConfigurationRequest(validator.NewValidatorSet(1, nil))
+	require.Error(t, err)
+
+	txNo, err := m.SubmitConfigurationRequest(newTestConfigurationSet(t, 1))
+	require.NoError(t, err)
+	require.Len(t, m.confUpdated, 1)
+
+	pending, err := cm.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, txNo, uint64(pending[0].TxNo))
+}
+
+func TestRestoreLastValidatorSet(t *testing.T) {
+	cm, err := NewConfigurationManager(context.Background(), datastore.NewMapDatastore(), "id1")
+	require.NoError(t, err)
+
+	set, err := cm.LoadLastValidatorSet()
+	require.NoError(t, err)
+	require.Nil(t, set)
+
+	initial := newTestConfigurationSet(t, 2)
+	require.Equal(t, initial, reconcileValidatorSet(nil, initial, 2))
+
+	require.NoError(t, cm.StoreLastValidatorSet(newTestConfigurationSet(t, 3)))
+	set, err = cm.LoadLastValidatorSet()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), set.ConfigurationNumber)
+
+	require.Equal(t, set, reconcileValidatorSet(set, initial, 3))
+	// A set older than the applied configuration or the initial set is outdated.
+	require.Equal(t, initial, reconcileValidatorSet(set, initial, 4))
+	require.Equal(t, initial, reconcileValidatorSet(set, newTestConfigurationSet(t, 5), 0))
+}
+
+func TestLimitGas(t *testing.T) {
+	a, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	b, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	msg := func(from address.Address, nonce uint64, gas int64) *types.SignedMessage {
+		return &types.SignedMessage{Message: types.Message{From: from, Nonce: nonce, GasLimit: gas}}
+	}
+
+	msgs := []*types.SignedMessage{msg(a, 0, 40), msg(b, 0, 50), msg(a, 1, 10), msg(b, 1, 10)}
+	require.Equal(t, msgs, limitGas(msgs, 110))
+
+	// Once a message of a sender doesn't fit, its later messages are dropped even if they fit.
+	limited := limitGas(msgs, 60)
+	require.Equal(t, []*types.SignedMessage{msgs[0], msgs[2]}, limited)
+
+	require.Empty(t, limitGas(msgs, 0))
+}
+
+func TestValidateCheckpointPeriod(t *testing.T) {
+	nodes := map[mirtypes.NodeID]*mirproto.NodeIdentity{"id1": {}, "id2": {}, "id3": {}, "id4": {}}
+	m := &mirproto.Membership{Nodes: nodes}
+
+	cfg := DefaultConsensusConfig()
+	require.NoError(t, validateCheckpointPeriod(cfg, m))
+	cfg.SegmentLength = DefaultMaxCheckpointBlocks/4 + 1
+	require.Error(t, validateCheckpointPeriod(cfg, m))
+
+	// Validators of chains with longer periods can raise the limit.
+	cfg.MaxCheckpointBlocks = 4 * cfg.SegmentLength
+	require.NoError(t, validateCheckpointPeriod(cfg, m))
+	require.NoError(t, validateConfig(&Config{BaseConfig: &BaseConfig{}, Consensus: cfg}))
+	cfg.MaxCheckpointBlocks = MaxCheckpointBlocks + 1
+	require.Error(t, validateConfig(&Config{BaseConfig: &BaseConfig{}, Consensus: cfg}))
+}
@@ -0,0 +1,334 @@
+package membership
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/lib/sigs"
+	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
+)
+
+// BootstrapInterval is the interval at which the validators republish their bootstrap messages,
+// so the validators that subscribed later get them too.
+var BootstrapInterval = 5 * time.Second
+
+// bootstrapDomain separates the signatures of bootstrap messages from other signatures of the key.
+const bootstrapDomain = "eudico/mir/bootstrap:"
+
+// BootstrapTopic returns the pubsub topic on which the validators of a new subnet bootstrap its
+// genesis membership.
+func BootstrapTopic(subnet string) string {
+	return "/mir/bootstrap/" + subnet
+}
+
+// BootstrapMsg is a message of the bootstrap of the genesis membership of a subnet, signed with the
+// wallet key of the validator. Without Genesis, it announces that the validator wants to join the
+// genesis membership. With Genesis, it states that the validator is ready to start with it.
+type BootstrapMsg struct {
+	Subnet    string
+	Validator *validator.Validator
+	Genesis   []*validator.Validator `json:",omitempty"`
+	Signature *crypto.Signature
+}
+
+// NewBootstrapMsg creates a bootstrap message of the validator, ready with the genesis membership
+// if it's not nil, signed by sign.
+func NewBootstrapMsg(subnet string, v *validator.Validator, genesis *validator.Set, sign func([]byte) (*crypto.Signature, error)) (*BootstrapMsg, error) {
+	m := &BootstrapMsg{Subnet: subnet, Validator: v}
+	if genesis != nil {
+		m.Genesis = genesis.Validators
+	}
+	b, err := m.signingBytes()
+	if err != nil {
+		return nil, err
+	}
+	m.Signature, err = sign(b)
+	if err != nil {
+		return nil, fmt.Errorf("error signing bootstrap message: %w", err)
+	}
+	return m, nil
+}
+
+func (m *BootstrapMsg) signingBytes() ([]byte, error) {
+	u := *m
+	u.Signature = nil
+	b, err := json.Marshal(&u)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(bootstrapDomain), b...), nil
+}
+
+// Verify checks that the message is signed by the validator it is from.
+func (m *BootstrapMsg) Verify() error {
+	if m.Validator == nil || m.Signature == nil {
+		return fmt.Errorf("incomplete bootstrap message")
+	}
+	b, err := m.signingBytes()
+	if err != nil {
+		return err
+	}
+	if err := sigs.Verify(m.Signature, m.Validator.Addr, b); err != nil {
+		return fmt.Errorf("invalid signature of validator %s: %w", m.Validator.ID(), err)
+	}
+	return nil
+}
+
+// GenesisBootstrap collects the bootstrap messages of the validators of a new subnet until they agree
+// on its genesis membership, without a pre-shared membership file.
+//
+// Once Quorum validators announced themselves, each validator picks as genesis membership the Quorum
+// validators with the lowest IDs, so all the validators that saw the same announcements pick the same
+// membership, and announces that it's ready with it. The genesis membership is agreed once all its
+// validators are ready with it, which also lets the validators that saw other announcements adopt it.
+type GenesisBootstrap struct {
+	subnet string
+	quorum int
+
+	lk     sync.Mutex
+	joined map[string]*validator.Validator
+	ready  map[string]*validator.Set
+}
+
+func NewGenesisBootstrap(subnet string, quorum int) *GenesisBootstrap {
+	return &GenesisBootstrap{
+		subnet: subnet,
+		quorum: quorum,
+		joined: make(map[string]*validator.Validator),
+		ready:  make(map[string]*validator.Set),
+	}
+}
+
+// Add verifies and records a bootstrap message.
+func (b *GenesisBootstrap) Add(m *BootstrapMsg) error {
+	if m.Subnet != b.subnet {
+		return fmt.Errorf("bootstrap message for subnet %s instead of %s", m.Subnet, b.subnet)
+	}
+	if err := m.Verify(); err != nil {
+		return err
+	}
+	v := *m.Validator
+	if v.Weight == nil {
+		return fmt.Errorf("validator %s without weight", v.ID())
+	}
+	a, err := NormalizeNetAddr(v.NetAddr)
+	if err != nil {
+		return fmt.Errorf("validator %s: %w", v.ID(), err)
+	}
+	v.NetAddr = a
+
+	var genesis *validator.Set
+	if len(m.Genesis) > 0 {
+		genesis = validator.NewValidatorSet(0, m.Genesis)
+		if err := NormalizeValidatorSet(genesis); err != nil {
+			return err
+		}
+		if !containsValidator(genesis, &v) {
+			return fmt.Errorf("validator %s is ready with a genesis membership without it", v.ID())
+		}
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	b.joined[v.ID()] = &v
+	if genesis != nil {
+		b.ready[v.ID()] = genesis
+	}
+	return nil
+}
+
+// Candidate returns the genesis membership picked from the validators announced so far, nil if
+// fewer than the quorum of validators announced themselves.
+func (b *GenesisBootstrap) Candidate() *validator.Set {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	if len(b.joined) < b.quorum {
+		return nil
+	}
+	ids := make([]string, 0, len(b.joined))
+	for id := range b.joined {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	vs := make([]*validator.Validator, 0, b.quorum)
+	for _, id := range ids[:b.quorum] {
+		vs = append(vs, b.joined[id])
+	}
+	return validator.NewValidatorSet(0, vs)
+}
+
+// Genesis returns the genesis membership all the validators of which are ready with it, if any.
+func (b *GenesisBootstrap) Genesis() (*validator.Set, bool) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	// Two memberships can only be agreed if they have no validator in common, in which case the
+	// one with the lowest hash is picked to remain deterministic.
+	var agreed *validator.Set
+	var agreedHash []byte
+	for _, set := range b.ready {
+		h, err := set.Hash()
+		if err != nil {
+			continue
+		}
+		if agreed != nil && bytes.Compare(h, agreedHash) >= 0 {
+			continue
+		}
+		if b.allReady(set, h) {
+			agreed, agreedHash = set, h
+		}
+	}
+	return agreed, agreed != nil
+}
+
+func (b *GenesisBootstrap) allReady(set *validator.Set, h []byte) bool {
+	for _, v := range set.Validators {
+		r, ok := b.ready[v.ID()]
+		if !ok {
+			return false
+		}
+		rh, err := r.Hash()
+		if err != nil || !bytes.Equal(rh, h) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsValidator(set *validator.Set, v *validator.Validator) bool {
+	for _, cur := range set.Validators {
+		if cur.Addr == v.Addr && cur.NetAddr == v.NetAddr && cur.Weight != nil && cur.Weight.Equals(*v.Weight) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bootstrap runs the bootstrap of the genesis membership of the subnet on its pubsub topic, announcing
+// the validator with self, until the genesis membership is agreed or ctx is done. The messages of
+// the validator are signed by sign; invalid messages of other validators are ignored.
+//
+// Once the genesis membership is agreed, the validator keeps publishing that it's ready with it
+// until ctx is done, so the validators that are still bootstrapping agree on it too.
+func Bootstrap(ctx context.Context, ps *pubsub.PubSub, subnet string, quorum int, self *validator.Validator,
+	sign func([]byte) (*crypto.Signature, error)) (*validator.Set, error) {
+	if quorum <= 0 {
+		return nil, fmt.Errorf("invalid genesis quorum %d", quorum)
+	}
+	topic, err := ps.Join(BootstrapTopic(subnet))
+	if err != nil {
+		return nil, fmt.Errorf("error joining bootstrap topic: %w", err)
+	}
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return nil, fmt.Errorf("error subscribing to bootstrap topic: %w", err)
+	}
+
+	b := NewGenesisBootstrap(subnet, quorum)
+	received := make(chan struct{}, 1)
+	go func() {
+		for {
+			msg, err := sub.Next(ctx)
+			if err != nil {
+				return
+			}
+			var m BootstrapMsg
+			if err := json.Unmarshal(msg.Data, &m); err != nil {
+				continue
+			}
+			if err := b.Add(&m); err != nil {
+				continue
+			}
+			select {
+			case received <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	publish := func(genesis *validator.Set) error {
+		m, err := NewBootstrapMsg(subnet, self, genesis, sign)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		// Our own messages are delivered to the subscription too.
+		return topic.Publish(ctx, data)
+	}
+
+	ticker := time.NewTicker(BootstrapInterval)
+	defer ticker.Stop()
+	var ready *validator.Set
+	var readyHash []byte
+	republish := true
+	for {
+		if genesis, ok := b.Genesis(); ok {
+			go func() {
+				defer topic.Close() // nolint
+				defer sub.Cancel()
+				if genesis.HasValidatorWithID(self.ID()) {
+					republishReady(ctx, ticker, genesis, publish)
+				}
+			}()
+			return genesis, nil
+		}
+
+		// A validator that is not in the membership picked from its announcements can't be ready.
+		c := b.Candidate()
+		if c == nil || !c.HasValidatorWithID(self.ID()) {
+			c = nil
+		}
+		var h []byte
+		if c != nil {
+			if h, err = c.Hash(); err != nil {
+				return nil, err
+			}
+		}
+		// Messages are only published when the candidate changes or periodically, not for every
+		// message received, so the validators don't echo each other.
+		if republish || !bytes.Equal(h, readyHash) {
+			ready, readyHash = c, h
+			if err := publish(ready); err != nil {
+				return nil, fmt.Errorf("error publishing bootstrap message: %w", err)
+			}
+		}
+
+		republish = false
+		select {
+		case <-ctx.Done():
+			sub.Cancel()
+			return nil, ctx.Err()
+		case <-received:
+		case <-ticker.C:
+			republish = true
+		}
+	}
+}
+
+// republishReady periodically publishes that the validator is ready with the agreed genesis
+// membership until ctx is done.
+func republishReady(ctx context.Context, ticker *time.Ticker, genesis *validator.Set, publish func(*validator.Set) error) {
+	for {
+		if err := publish(genesis); err != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package membership
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	filcrypto "github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/lib/sigs"
+)
+
+type bootstrapValidator struct {
+	v    *validator.Validator
+	sign func([]byte) (*filcrypto.Signature, error)
+}
+
+func newBootstrapValidators(t *testing.T, n int) []*bootstrapValidator {
+	vs := make([]*bootstrapValidator, n)
+	for i := range vs {
+		priv, err := sigs.Generate(filcrypto.SigTypeSecp256k1)
+		require.NoError(t, err)
+		pub, err := sigs.ToPublic(filcrypto.SigTypeSecp256k1, priv)
+		require.NoError(t, err)
+		addr, err := address.NewSecp256k1Address(pub)
+		require.NoError(t, err)
+		w := big.NewInt(1)
+		vs[i] = &bootstrapValidator{
+			v: validator.NewValidatorWithWeight(addr, fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", 10000+i), &w),
+			sign: func(b []byte) (*filcrypto.Signature, error) {
+				return sigs.Sign(filcrypto.SigTypeSecp256k1, priv, b)
+			},
+		}
+	}
+	return vs
+}
+
+func announce(t *testing.T, b *GenesisBootstrap, bv *bootstrapValidator, genesis *validator.Set) error {
+	m, err := NewBootstrapMsg("/root", bv.v, genesis, bv.sign)
+	require.NoError(t, err)
+	return b.Add(m)
+}
+
+func TestGenesisBootstrap(t *testing.T) {
+	vs := newBootstrapValidators(t, 4)
+	b := NewGenesisBootstrap("/root", 3)
+
+	require.NoError(t, announce(t, b, vs[0], nil))
+	require.NoError(t, announce(t, b, vs[1], nil))
+	require.Nil(t, b.Candidate())
+	require.NoError(t, announce(t, b, vs[2], nil))
+	require.NoError(t, announce(t, b, vs[3], nil))
+
+	genesis := b.Candidate()
+	require.NotNil(t, genesis)
+	require.Equal(t, 3, genesis.Size())
+	require.Equal(t, uint64(0), genesis.ConfigurationNumber)
+	// The candidate doesn't depend on the order of the announcements.
+	other := NewGenesisBootstrap("/root", 3)
+	for i := len(vs) - 1; i >= 0; i-- {
+		require.NoError(t, announce(t, other, vs[i], nil))
+	}
+	require.True(t, genesis.Equal(other.Candidate()))
+
+	// The genesis membership is agreed once all its validators are ready with it.
+	var members []*bootstrapValidator
+	for _, bv := range vs {
+		if genesis.HasValidatorWithID(bv.v.ID()) {
+			members = append(members, bv)
+		}
+	}
+	for _, bv := range members[:2] {
+		require.NoError(t, announce(t, b, bv, genesis))
+	}
+	_, ok := b.Genesis()
+	require.False(t, ok)
+	require.NoError(t, announce(t, b, members[2], genesis))
+	agreed, ok := b.Genesis()
+	require.True(t, ok)
+	require.True(t, genesis.Equal(agreed))
+
+	// A validator that didn't see the announcements adopts the agreed membership.
+	late := NewGenesisBootstrap("/root", 3)
+	for _, bv := range members {
+		require.NoError(t, announce(t, late, bv, genesis))
+	}
+	agreed, ok = late.Genesis()
+	require.True(t, ok)
+	require.True(t, genesis.Equal(agreed))
+}
+
+func TestGenesisBootstrapInvalidMsgs(t *testing.T) {
+	vs := newBootstrapValidators(t, 2)
+	b := NewGenesisBootstrap("/root", 2)
+
+	m, err := NewBootstrapMsg("/root/other", vs[0].v, nil, vs[0].sign)
+	require.NoError(t, err)
+	require.Error(t, b.Add(m))
+
+	// Signed by another validator.
+	m, err = NewBootstrapMsg("/root", vs[0].v, nil, vs[1].sign)
+	require.NoError(t, err)
+	require.Error(t, b.Add(m))
+
+	// Ready with a membership without the validator.
+	m, err = NewBootstrapMsg("/root", vs[0].v, validator.NewValidatorSet(0, []*validator.Validator{vs[1].v}), vs[0].sign)
+	require.NoError(t, err)
+	require.Error(t, b.Add(m))
+}
+
+func TestBootstrap(t *testing.T) {
+	interval := BootstrapInterval
+	BootstrapInterval = 100 * time.Millisecond
+	defer func() { BootstrapInterval = interval }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	const n = 3
+	vs := newBootstrapValidators(t, n)
+	mn, err := mocknet.FullMeshLinked(n)
+	require.NoError(t, err)
+	require.NoError(t, mn.ConnectAllButSelf())
+
+	results := make(chan *validator.Set, n)
+	errs := make(chan error, n)
+	for i, h := range mn.Hosts() {
+		ps, err := pubsub.NewGossipSub(ctx, h)
+		require.NoError(t, err)
+		go func(bv *bootstrapValidator) {
+			genesis, err := Bootstrap(ctx, ps, "/root", n, bv.v, bv.sign)
+			if err != nil {
+				errs <- err
+				return
+			}
+			results <- genesis
+		}(vs[i])
+	}
+
+	var first *validator.Set
+	for i := 0; i < n; i++ {
+		select {
+		case genesis := <-results:
+			require.Equal(t, n, genesis.Size())
+			if first == nil {
+				first = genesis
+			}
+			require.True(t, first.Equal(genesis))
+		case err := <-errs:
+			require.NoError(t, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package membership
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// MaintenanceFileSuffix is appended to the name of a membership file to get the file with
+// the maintenance schedule of its validators.
+const MaintenanceFileSuffix = ".maintenance"
+
+// MaintenanceWindow is a range of heights during which a validator doesn't propose messages,
+// e.g. because it is going to be restarted for an upgrade.
+type MaintenanceWindow struct {
+	Validator string
+	// Start is the first height of the window and End the first height after it.
+	Start abi.ChainEpoch
+	End   abi.ChainEpoch
+}
+
+// MaintenanceSchedule is the schedule of the maintenance windows of the validators of a subnet.
+// It is shared by all the validators, so every validator can check that the subnet keeps enough
+// active validators during every window.
+type MaintenanceSchedule struct {
+	Windows []MaintenanceWindow
+}
+
+// LoadMaintenanceSchedule reads the schedule from the JSON file at path.
+// A missing file means that no maintenance is scheduled.
+func LoadMaintenanceSchedule(path string) (*MaintenanceSchedule, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading maintenance schedule: %w", err)
+	}
+
+	var s MaintenanceSchedule
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("error parsing maintenance schedule in %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Validate checks that the windows are well-formed and belong to validators of the set, and that
+// no more than f validators of the set are in maintenance at the same height, so the subnet can
+// still make progress during every window.
+func (s *MaintenanceSchedule) Validate(set *validator.Set) error {
+	if s == nil {
+		return nil
+	}
+
+	members := make(map[string]struct{}, set.Size())
+	for _, v := range set.Validators {
+		members[v.ID()] = struct{}{}
+	}
+
+	type event struct {
+		h     abi.ChainEpoch
+		delta int
+	}
+	var events []event
+	for _, w := range s.Windows {
+		if w.End <= w.Start {
+			return fmt.Errorf("empty maintenance window [%d, %d) of %s", w.Start, w.End, w.Validator)
+		}
+		if _, ok := members[w.Validator]; !ok {
+			return fmt.Errorf("maintenance window of %s, which isn't in the validator set", w.Validator)
+		}
+		events = append(events, event{w.Start, 1}, event{w.End, -1})
+	}
+	// Windows are half-open, so a window ending at the height another one starts doesn't overlap with it.
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].h != events[j].h {
+			return events[i].h < events[j].h
+		}
+		return events[i].delta < events[j].delta
+	})
+
+	f := (set.Size() - 1) / 3
+	active := 0
+	for _, e := range events {
+		active += e.delta
+		if active > f {
+			return fmt.Errorf("%d validators in maintenance at height %d, at most %d of %d can be", active, e.h, f, set.Size())
+		}
+	}
+	return nil
+}
+
+// InMaintenance returns whether the validator is in a maintenance window at height h.
+func (s *MaintenanceSchedule) InMaintenance(id string, h abi.ChainEpoch) bool {
+	if s == nil {
+		return false
+	}
+	for _, w := range s.Windows {
+		if w.Validator == id && h >= w.Start && h < w.End {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package membership
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func newMaintenanceValidatorSet(t *testing.T) *validator.Set {
+	var vs []*validator.Validator
+	for _, s := range []string{
+		"t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ",
+		"t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq:1@/ip4/127.0.0.1/tcp/10001/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ",
+		"t1mkypjjgxbiuylzqmeudeootz7re5w4ub7npgzby:1@/ip4/127.0.0.1/tcp/10002/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ",
+		"t1usaabxww7e2z2xjbow4ngvqxwf5agdvumapjesq:1@/ip4/127.0.0.1/tcp/10003/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ",
+	} {
+		v, err := validator.NewValidatorFromString(s)
+		require.NoError(t, err)
+		vs = append(vs, v)
+	}
+	return validator.NewValidatorSet(0, vs)
+}
+
+func TestMaintenanceSchedule(t *testing.T) {
+	set := newMaintenanceValidatorSet(t)
+	a, b := set.Validators[0].ID(), set.Validators[1].ID()
+
+	path := filepath.Join(t.TempDir(), "mir.validators"+MaintenanceFileSuffix)
+	s, err := LoadMaintenanceSchedule(path)
+	require.NoError(t, err)
+	require.Nil(t, s)
+	require.NoError(t, s.Validate(set))
+	require.False(t, s.InMaintenance(a, 10))
+
+	// Back-to-back windows of different validators are safe with 4 validators.
+	require.NoError(t, os.WriteFile(path, []byte(`{"Windows": [
+		{"Validator": "`+a+`", "Start": 10, "End": 20},
+		{"Validator": "`+b+`", "Start": 20, "End": 30}
+	]}`), 0644))
+	s, err = LoadMaintenanceSchedule(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Validate(set))
+	require.False(t, s.InMaintenance(a, 9))
+	require.True(t, s.InMaintenance(a, 10))
+	require.True(t, s.InMaintenance(a, 19))
+	require.False(t, s.InMaintenance(a, 20))
+	require.True(t, s.InMaintenance(b, 20))
+
+	// Overlapping windows leave more than f validators out.
+	s.Windows = append(s.Windows, MaintenanceWindow{Validator: b, Start: 15, End: 16})
+	require.ErrorContains(t, s.Validate(set), "at height 15")
+
+	require.Error(t, (&MaintenanceSchedule{Windows: []MaintenanceWindow{{Validator: a, Start: 5, End: 5}}}).Validate(set))
+	require.Error(t, (&MaintenanceSchedule{Windows: []MaintenanceWindow{{Validator: "t01000", Start: 5, End: 6}}}).Validate(set))
+}
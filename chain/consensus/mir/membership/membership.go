@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/multiformats/go-multiaddr"
-
 	"github.com/consensus-shipyard/go-ipc-types/gateway"
 	"github.com/consensus-shipyard/go-ipc-types/sdk"
 	"github.com/consensus-shipyard/go-ipc-types/validator"
@@ -48,6 +46,8 @@ type Info struct {
 	MinValidators uint64
 	ValidatorSet  *validator.Set
 	GenesisEpoch  uint64
+	// Maintenance is the maintenance schedule of the validators, nil if the source doesn't provide one.
+	Maintenance *MaintenanceSchedule
 }
 
 type Reader interface {
@@ -66,15 +66,24 @@ func NewFileMembership(fileName string) FileMembership {
 	}
 }
 
-// GetMembershipInfo gets the membership config from a file.
+// GetMembershipInfo gets the membership config from a file, and the maintenance schedule
+// from the file with the same name and the MaintenanceFileSuffix, if any.
 func (f FileMembership) GetMembershipInfo() (*Info, error) {
 	vs, err := validator.NewValidatorSetFromFile(f.FileName)
 	if err != nil {
 		return nil, err
 	}
+	if err := NormalizeValidatorSet(vs); err != nil {
+		return nil, err
+	}
+	schedule, err := LoadMaintenanceSchedule(f.FileName + MaintenanceFileSuffix)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Info{
 		ValidatorSet: vs,
+		Maintenance:  schedule,
 	}, nil
 }
 
@@ -90,6 +99,9 @@ func (s StringMembership) GetMembershipInfo() (*Info, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := NormalizeValidatorSet(vs); err != nil {
+		return nil, err
+	}
 
 	return &Info{
 		ValidatorSet: vs,
@@ -107,6 +119,9 @@ func (e EnvMembership) GetMembershipInfo() (*Info, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := NormalizeValidatorSet(vs); err != nil {
+		return nil, err
+	}
 
 	return &Info{
 		ValidatorSet: vs,
@@ -147,6 +162,9 @@ func (c *OnChainMembership) GetMembershipInfo() (*Info, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := NormalizeValidatorSet(&resp.ValidatorSet); err != nil {
+		return nil, err
+	}
 	return &Info{
 		ValidatorSet:  &resp.ValidatorSet,
 		MinValidators: resp.MinValidators,
@@ -164,14 +182,14 @@ func Membership(validators []*validator.Validator) ([]t.NodeID, *mirproto.Member
 
 	for _, v := range validators {
 		id := t.NodeID(v.ID())
-		a, err := multiaddr.NewMultiaddr(v.NetAddr)
+		a, err := NormalizeNetAddr(v.NetAddr)
 		if err != nil {
 			return nil, nil, err
 		}
 		nodeIDs = append(nodeIDs, id)
 		nodeAddrs[id] = &mirproto.NodeIdentity{
 			Id:     id,
-			Addr:   a.String(),
+			Addr:   a,
 			Key:    nil,
 			Weight: tt.VoteWeight(v.Weight.String()),
 		}
@@ -184,9 +202,38 @@ func Membership(validators []*validator.Validator) ([]t.NodeID, *mirproto.Member
 	return nodeIDs, &membership, nil
 }
 
-// NewSetMembershipMsg creates a new message to update implicitly
-// the membership in the gateway actor of the subnet
-func NewSetMembershipMsg(gw address.Address, valSet *validator.Set) (*types.SignedMessage, error) {
+// ConfigMsgKind is the kind of an implicit configuration message.
+type ConfigMsgKind uint64
+
+const (
+	SetMembershipMsgKind ConfigMsgKind = iota
+	InitGenesisEpochMsgKind
+)
+
+// MaxConfigMsgsPerKind is the maximum number of configuration messages of the same kind in a block.
+const MaxConfigMsgsPerKind = 1 << 8
+
+// ConfigMsgNonce returns the nonce of the i-th configuration message of the given kind in the block at height h.
+//
+// Configuration messages are sent implicitly by the system actor, so their nonces aren't checked against
+// the state. They only make the configuration messages of a block different from each other, and different
+// from the messages of other blocks: messages with the same CID are executed only once in a tipset.
+func ConfigMsgNonce(h abi.ChainEpoch, kind ConfigMsgKind, i int) uint64 {
+	return uint64(h)<<16 | uint64(kind)<<8 | uint64(i)
+}
+
+// LegacyConfigMsgNonce returns the nonce of the configuration messages of the given kind in the blocks
+// that predate the nonces derived by ConfigMsgNonce: 0 for SetMembership and 1 for InitGenesisEpoch.
+func LegacyConfigMsgNonce(kind ConfigMsgKind) uint64 {
+	return uint64(kind)
+}
+
+// NewSetMembershipMsg creates the i-th message of the block at height h to update implicitly
+// the membership in the gateway actor of the subnet.
+func NewSetMembershipMsg(gw address.Address, valSet *validator.Set, h abi.ChainEpoch, i int) (*types.SignedMessage, error) {
+	if i >= MaxConfigMsgsPerKind {
+		return nil, fmt.Errorf("too many SetMembership messages in block %d", h)
+	}
 	params, err := actors.SerializeParams(valSet)
 	if err != nil {
 		return nil, err
@@ -200,14 +247,14 @@ func NewSetMembershipMsg(gw address.Address, valSet *validator.Set) (*types.Sign
 		GasFeeCap:  types.NewInt(0),
 		GasPremium: types.NewInt(0),
 		GasLimit:   build.BlockGasLimit, // Make super sure this is never too little
-		Nonce:      0,
+		Nonce:      ConfigMsgNonce(h, SetMembershipMsgKind, i),
 	}
 	return &types.SignedMessage{Message: msg, Signature: crypto.Signature{Type: crypto.SigTypeDelegated}}, nil
 }
 
-// NewInitGenesisEpochMsg creates a new config message to initialize
+// NewInitGenesisEpochMsg creates a new config message of the block at height h to initialize
 // implicitly the subnet and set the genesis epoch for it.
-func NewInitGenesisEpochMsg(gw address.Address, genesisEpoch abi.ChainEpoch) (*types.SignedMessage, error) {
+func NewInitGenesisEpochMsg(gw address.Address, genesisEpoch abi.ChainEpoch, h abi.ChainEpoch) (*types.SignedMessage, error) {
 	params, err := actors.SerializeParams(&gateway.InitGenesisEpochParams{GenesisEpoch: genesisEpoch})
 	if err != nil {
 		return nil, err
@@ -221,14 +268,37 @@ func NewInitGenesisEpochMsg(gw address.Address, genesisEpoch abi.ChainEpoch) (*t
 		GasFeeCap:  types.NewInt(0),
 		GasPremium: types.NewInt(0),
 		GasLimit:   build.BlockGasLimit, // Make super sure this is never too little
-		// the nonce must be different from other config messages for the case where
-		// all config messages are included in the same block, if not the one with the
-		// largest nonce will be discarded.
-		Nonce: 1,
+		Nonce:      ConfigMsgNonce(h, InitGenesisEpochMsgKind, 0),
 	}
 	return &types.SignedMessage{Message: msg, Signature: crypto.Signature{Type: crypto.SigTypeDelegated}}, nil
 }
 
+// CheckConfigMsgNonces checks that the configuration messages among the messages of the block
+// at height h have the nonces assigned by ConfigMsgNonce, in the order they appear in the block.
+func CheckConfigMsgNonces(gw address.Address, h abi.ChainEpoch, msgs []*types.Message) error {
+	counts := make(map[ConfigMsgKind]int)
+	for _, msg := range msgs {
+		var kind ConfigMsgKind
+		switch {
+		case IsSetMembershipConfigMsg(gw, msg):
+			kind = SetMembershipMsgKind
+		case IsInitGenesisEpochConfigMsg(gw, msg):
+			kind = InitGenesisEpochMsgKind
+		default:
+			continue
+		}
+		i := counts[kind]
+		if i >= MaxConfigMsgsPerKind {
+			return fmt.Errorf("too many configuration messages of kind %d", kind)
+		}
+		if exp := ConfigMsgNonce(h, kind, i); msg.Nonce != exp {
+			return fmt.Errorf("wrong nonce of configuration message %s (exp: %d, got: %d)", msg.Cid(), exp, msg.Nonce)
+		}
+		counts[kind]++
+	}
+	return nil
+}
+
 // IsConfigMsg determines if the message is an on-chain configuration message.
 func IsConfigMsg(gw address.Address, msg *types.Message) bool {
 	return IsSetMembershipConfigMsg(gw, msg) || IsInitGenesisEpochConfigMsg(gw, msg)
@@ -247,3 +317,19 @@ func IsInitGenesisEpochConfigMsg(gw address.Address, msg *types.Message) bool {
 		msg.From == builtin.SystemActorAddr &&
 		msg.Method == builtin.MustGenerateFRCMethodNum("InitGenesisEpoch")
 }
+
+// VerifyGatewayMembership checks that the membership read from the gateway actor is the
+// validator set set by a SetMembership message, i.e. that the message was executed.
+func VerifyGatewayMembership(expected, actual *validator.Set) error {
+	if actual == nil {
+		return fmt.Errorf("no membership in the gateway, expected configuration %d", expected.ConfigurationNumber)
+	}
+	if actual.ConfigurationNumber != expected.ConfigurationNumber {
+		return fmt.Errorf("gateway membership has configuration %d, expected %d",
+			actual.ConfigurationNumber, expected.ConfigurationNumber)
+	}
+	if !actual.Equal(expected) {
+		return fmt.Errorf("gateway membership %v doesn't match the voted validator set %v", actual, expected)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package membership
 
 import (
+	"bytes"
 	"os"
 	"testing"
 
@@ -8,6 +9,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/types"
 )
 
 func TestMembership(t *testing.T) {
@@ -70,7 +73,7 @@ func TestOnchainMembershipInfo(t *testing.T) {
 	require.Equal(t, 3, vs.Size())
 	require.Equal(t, uint64(0), vs.GetConfigurationNumber())
 
-	mb, err := NewSetMembershipMsg(gw, vs)
+	mb, err := NewSetMembershipMsg(gw, vs, 1, 0)
 	require.NoError(t, err)
 
 	require.True(t, IsConfigMsg(gw, &mb.Message))
@@ -107,3 +110,56 @@ func TestFileMembershipInfo(t *testing.T) {
 	require.Equal(t, uint64(0), info.ValidatorSet.ConfigurationNumber)
 	require.Equal(t, 3, len(info.ValidatorSet.Validators))
 }
+
+func TestVerifyGatewayMembership(t *testing.T) {
+	v1, err := validator.NewValidatorFromString("t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+	v2, err := validator.NewValidatorFromString("t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq:2@/ip4/127.0.0.1/tcp/10001/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+
+	gw, err := address.NewIDAddress(64)
+	require.NoError(t, err)
+
+	vs := validator.NewValidatorSet(1, []*validator.Validator{v1, v2})
+	mb, err := NewSetMembershipMsg(gw, vs, 1, 0)
+	require.NoError(t, err)
+
+	// The gateway is set to the params of the message when it is executed.
+	var executed validator.Set
+	require.NoError(t, executed.UnmarshalCBOR(bytes.NewReader(mb.Message.Params)))
+	require.NoError(t, VerifyGatewayMembership(vs, &executed))
+
+	require.Error(t, VerifyGatewayMembership(vs, nil))
+	require.Error(t, VerifyGatewayMembership(vs, validator.NewValidatorSet(0, []*validator.Validator{v1, v2})))
+	require.Error(t, VerifyGatewayMembership(vs, validator.NewValidatorSet(1, []*validator.Validator{v1})))
+}
+
+func TestConfigMsgNonces(t *testing.T) {
+	v1, err := validator.NewValidatorFromString("t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+	gw, err := address.NewIDAddress(64)
+	require.NoError(t, err)
+
+	vs := validator.NewValidatorSet(1, []*validator.Validator{v1})
+	m1, err := NewSetMembershipMsg(gw, vs, 7, 0)
+	require.NoError(t, err)
+	m2, err := NewSetMembershipMsg(gw, vs, 7, 1)
+	require.NoError(t, err)
+	initMsg, err := NewInitGenesisEpochMsg(gw, 10, 7)
+	require.NoError(t, err)
+
+	// Config messages with the same content in the same block are different messages.
+	require.NotEqual(t, m1.Message.Cid(), m2.Message.Cid())
+	require.NotEqual(t, m1.Message.Nonce, initMsg.Message.Nonce)
+	require.Equal(t, uint64(0), LegacyConfigMsgNonce(SetMembershipMsgKind))
+	require.Equal(t, uint64(1), LegacyConfigMsgNonce(InitGenesisEpochMsgKind))
+
+	other := &types.Message{To: gw, Nonce: 0}
+	msgs := []*types.Message{&initMsg.Message, other, &m1.Message, &m2.Message}
+	require.NoError(t, CheckConfigMsgNonces(gw, 7, msgs))
+	require.Error(t, CheckConfigMsgNonces(gw, 8, msgs))
+	require.Error(t, CheckConfigMsgNonces(gw, 7, []*types.Message{&m2.Message, &m1.Message}))
+
+	_, err = NewSetMembershipMsg(gw, vs, 7, MaxConfigMsgsPerKind)
+	require.Error(t, err)
+}
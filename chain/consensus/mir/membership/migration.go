@@ -0,0 +1,86 @@
+package membership
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+)
+
+// MigrationFileSuffix is appended to the name of a membership file to get the file with the plan
+// to migrate the membership of the subnet from the file to the on-chain membership.
+const MigrationFileSuffix = ".migration"
+
+// MigrationPlan is the plan to move a running subnet from the file membership to the on-chain one.
+// All the validators must use the same plan, so they all switch at the same Mir epoch.
+type MigrationPlan struct {
+	// SwitchEpoch is the first Mir epoch in which the validators read the on-chain membership.
+	SwitchEpoch uint64
+	// FallbackEpochs is the number of epochs after SwitchEpoch during which the membership file is
+	// still read if the on-chain membership can't be read.
+	FallbackEpochs uint64
+}
+
+// LoadMigrationPlan reads the plan from the JSON file at path.
+// A missing file means that no migration is planned.
+func LoadMigrationPlan(path string) (*MigrationPlan, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading migration plan %s: %w", path, err)
+	}
+	var p MigrationPlan
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("error parsing migration plan %s: %w", path, err)
+	}
+	if p.SwitchEpoch == 0 {
+		return nil, fmt.Errorf("migration plan %s without switch epoch", path)
+	}
+	return &p, nil
+}
+
+// Save writes the plan to the JSON file at path.
+func (p *MigrationPlan) Save(path string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// CheckMigration checks that the on-chain validator set can replace the validator set of the file:
+// both sets must have the same validators with the same addresses and weights, and the on-chain
+// configuration number must not be lower, or the switch would be seen as a reconfiguration.
+func CheckMigration(file, onchain *validator.Set) error {
+	if onchain == nil || onchain.Size() == 0 {
+		return fmt.Errorf("empty on-chain validator set")
+	}
+	if onchain.ConfigurationNumber < file.ConfigurationNumber {
+		return fmt.Errorf("on-chain configuration %d is older than the configuration %d of the file",
+			onchain.ConfigurationNumber, file.ConfigurationNumber)
+	}
+	if onchain.Size() != file.Size() {
+		return fmt.Errorf("on-chain validator set has %d validators, the file has %d", onchain.Size(), file.Size())
+	}
+	fv := make(map[string]*validator.Validator, file.Size())
+	for _, v := range file.GetValidators() {
+		fv[v.ID()] = v
+	}
+	for _, v := range onchain.GetValidators() {
+		f, ok := fv[v.ID()]
+		switch {
+		case !ok:
+			return fmt.Errorf("on-chain validator %s not in the file", v.ID())
+		case f.NetAddr != v.NetAddr:
+			return fmt.Errorf("validator %s has address %s on-chain and %s in the file", v.ID(), v.NetAddr, f.NetAddr)
+		case f.Weight.String() != v.Weight.String():
+			return fmt.Errorf("validator %s has weight %s on-chain and %s in the file", v.ID(), v.Weight, f.Weight)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package membership
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMigration(t *testing.T) {
+	file := newMaintenanceValidatorSet(t)
+
+	require.NoError(t, CheckMigration(file, validator.NewValidatorSet(2, file.Validators)))
+	require.Error(t, CheckMigration(validator.NewValidatorSet(3, file.Validators), validator.NewValidatorSet(2, file.Validators)))
+	require.Error(t, CheckMigration(file, validator.NewValidatorSet(0, file.Validators[1:])))
+	require.Error(t, CheckMigration(file, nil))
+
+	other, err := validator.NewValidatorFromString("t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:2@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+	reweighted := append([]*validator.Validator{other}, file.Validators[1:]...)
+	require.Error(t, CheckMigration(file, validator.NewValidatorSet(0, reweighted)))
+}
+
+func TestMigrationPlan(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "mir.validators"+MigrationFileSuffix)
+
+	plan, err := LoadMigrationPlan(p)
+	require.NoError(t, err)
+	require.Nil(t, plan)
+
+	require.NoError(t, (&MigrationPlan{SwitchEpoch: 12, FallbackEpochs: 4}).Save(p))
+	plan, err = LoadMigrationPlan(p)
+	require.NoError(t, err)
+	require.Equal(t, &MigrationPlan{SwitchEpoch: 12, FallbackEpochs: 4}, plan)
+
+	require.NoError(t, (&MigrationPlan{}).Save(p))
+	_, err = LoadMigrationPlan(p)
+	require.Error(t, err)
+}
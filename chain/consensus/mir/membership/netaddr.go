@@ -0,0 +1,149 @@
+package membership
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// NormalizeNetAddr returns the canonical multiaddr of the network address of a validator. Besides
+// multiaddrs, including DNS ones, it accepts the legacy host:port form, optionally followed by the
+// /p2p/<peer ID> of the validator, so every membership source and the transport see the same address
+// for a validator whatever the form it is written in.
+func NormalizeNetAddr(addr string) (string, error) {
+	s := strings.TrimSpace(addr)
+	if s == "" {
+		return "", fmt.Errorf("empty network address")
+	}
+	if !strings.HasPrefix(s, "/") {
+		legacy, err := legacyNetAddr(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid network address %q: %w", addr, err)
+		}
+		s = legacy
+	}
+
+	ma, err := multiaddr.NewMultiaddr(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid network address %q: %w", addr, err)
+	}
+	switch ma.Protocols()[0].Code {
+	case multiaddr.P_IP4, multiaddr.P_IP6, multiaddr.P_IP6ZONE, multiaddr.P_DNS, multiaddr.P_DNS4, multiaddr.P_DNS6:
+	default:
+		return "", fmt.Errorf("invalid network address %q: expected an IP or DNS address", addr)
+	}
+	if _, err := ma.ValueForProtocol(multiaddr.P_TCP); err != nil {
+		if _, err := ma.ValueForProtocol(multiaddr.P_UDP); err != nil {
+			return "", fmt.Errorf("invalid network address %q: no TCP or UDP port", addr)
+		}
+	}
+	return ma.String(), nil
+}
+
+// legacyNetAddr converts a host:port[/p2p/<peer ID>] address to a multiaddr string.
+func legacyNetAddr(s string) (string, error) {
+	hostPort, rest := s, ""
+	if i := strings.Index(s, "/"); i >= 0 {
+		hostPort, rest = s[:i], s[i:]
+	}
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return "", err
+	}
+
+	// IPv6 link-local addresses are scoped to an interface with a zone, e.g. [fe80::1%eth0]:10000.
+	zone := ""
+	if i := strings.LastIndex(host, "%"); i >= 0 {
+		host, zone = host[:i], host[i+1:]
+	}
+
+	proto := "dns"
+	if ip := net.ParseIP(host); ip != nil {
+		proto = "ip6"
+		if ip4 := ip.To4(); ip4 != nil && zone == "" {
+			// IPv4-mapped IPv6 addresses are dialed over IPv4.
+			proto, host = "ip4", ip4.String()
+		}
+	} else if zone != "" {
+		return "", fmt.Errorf("zone in non-IPv6 host %s", host)
+	}
+	if zone != "" {
+		return fmt.Sprintf("/ip6zone/%s/ip6/%s/tcp/%s%s", zone, host, port, rest), nil
+	}
+	return fmt.Sprintf("/%s/%s/tcp/%s%s", proto, host, port, rest), nil
+}
+
+// Preference classes of network addresses, from the most preferred.
+const (
+	netAddrRoutable = iota
+	netAddrLoopback
+	netAddrLinkLocal
+	netAddrInvalid
+)
+
+// netAddrRank returns the preference class of a network address, and whether it is an IPv6 one.
+func netAddrRank(addr string) (int, bool) {
+	ma, err := multiaddr.NewMultiaddr(addr)
+	if err != nil {
+		return netAddrInvalid, false
+	}
+	var (
+		class = netAddrRoutable
+		ip6   bool
+	)
+	multiaddr.ForEach(ma, func(c multiaddr.Component) bool {
+		switch c.Protocol().Code {
+		case multiaddr.P_IP6ZONE:
+			class = netAddrLinkLocal
+			return true
+		case multiaddr.P_DNS6:
+			ip6 = true
+		case multiaddr.P_IP4, multiaddr.P_IP6:
+			ip6 = c.Protocol().Code == multiaddr.P_IP6
+			ip := net.IP(c.RawValue())
+			switch {
+			case ip.IsLoopback():
+				class = netAddrLoopback
+			case ip.IsLinkLocalUnicast():
+				class = netAddrLinkLocal
+			}
+		}
+		return false
+	})
+	return class, ip6
+}
+
+// SortNetAddrs returns the network addresses of a validator sorted by preference to be dialed by its peers:
+// routable addresses first, then loopback and link-local ones. Within each class, IPv6 addresses come before
+// IPv4 ones if ipv6First is set, and after them otherwise. DNS addresses are sorted with the routable ones.
+func SortNetAddrs(addrs []string, ipv6First bool) []string {
+	sorted := append([]string(nil), addrs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ci, ip6i := netAddrRank(sorted[i])
+		cj, ip6j := netAddrRank(sorted[j])
+		if ci != cj {
+			return ci < cj
+		}
+		return ip6i != ip6j && ip6i == ipv6First
+	})
+	return sorted
+}
+
+// NormalizeValidatorSet normalizes the network addresses of the validators of the set.
+func NormalizeValidatorSet(set *validator.Set) error {
+	if set == nil {
+		return nil
+	}
+	for _, v := range set.Validators {
+		a, err := NormalizeNetAddr(v.NetAddr)
+		if err != nil {
+			return fmt.Errorf("validator %s: %w", v.ID(), err)
+		}
+		v.NetAddr = a
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package membership
+
+import (
+	"testing"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeNetAddr(t *testing.T) {
+	const id = "12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ"
+
+	for addr, exp := range map[string]string{
+		"/ip4/127.0.0.1/tcp/10000/p2p/" + id:    "/ip4/127.0.0.1/tcp/10000/p2p/" + id,
+		" /ip4/127.0.0.1/tcp/10000/ipfs/" + id:  "/ip4/127.0.0.1/tcp/10000/p2p/" + id,
+		"/dns4/validator.example.com/tcp/10000": "/dns4/validator.example.com/tcp/10000",
+		"/ip6/::1/udp/10000/quic":               "/ip6/::1/udp/10000/quic",
+		// Legacy host:port addresses.
+		"127.0.0.1:10000/p2p/" + id:             "/ip4/127.0.0.1/tcp/10000/p2p/" + id,
+		"[::1]:10000":                           "/ip6/::1/tcp/10000",
+		"[2001:db8::1]:10000/p2p/" + id:         "/ip6/2001:db8::1/tcp/10000/p2p/" + id,
+		"[fe80::1%eth0]:10000":                  "/ip6zone/eth0/ip6/fe80::1/tcp/10000",
+		"[::ffff:10.0.0.1]:10000":               "/ip4/10.0.0.1/tcp/10000",
+		"/ip6zone/eth0/ip6/fe80::1/tcp/10000":   "/ip6zone/eth0/ip6/fe80::1/tcp/10000",
+		"/dns6/validator.example.com/tcp/10000": "/dns6/validator.example.com/tcp/10000",
+		"validator.example.com:10000/p2p/" + id: "/dns/validator.example.com/tcp/10000/p2p/" + id,
+	} {
+		a, err := NormalizeNetAddr(addr)
+		require.NoError(t, err, addr)
+		require.Equal(t, exp, a, addr)
+	}
+
+	for _, addr := range []string{
+		"",
+		"127.0.0.1",
+		"/p2p/" + id,
+		"/ip4/127.0.0.1",
+		"/ip4/127.0.0.1/tcp/notaport",
+		"127.0.0.1:10000/garbage",
+		"[validator.example.com%eth0]:10000",
+	} {
+		_, err := NormalizeNetAddr(addr)
+		require.Error(t, err, addr)
+	}
+}
+
+func TestNormalizeValidatorSet(t *testing.T) {
+	v1, err := validator.NewValidatorFromString("t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip4/127.0.0.1/tcp/10000/ipfs/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+	v2, err := validator.NewValidatorFromString("t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq:2@/ip4/127.0.0.1/tcp/10001/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+	set := validator.NewValidatorSet(1, []*validator.Validator{v1, v2})
+
+	require.NoError(t, NormalizeValidatorSet(set))
+	require.Equal(t, "/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ", set.Validators[0].NetAddr)
+
+	// The membership of Mir uses the normalized addresses too.
+	_, mb, err := Membership(set.Validators)
+	require.NoError(t, err)
+	require.Equal(t, set.Validators[0].NetAddr, mb.Nodes["t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy"].Addr)
+
+	set.Validators[1].NetAddr = "/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ"
+	require.Error(t, NormalizeValidatorSet(set))
+}
+
+func TestNormalizeIPv6ValidatorSet(t *testing.T) {
+	v, err := validator.NewValidatorFromString("t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip6/::1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+	// Membership files may still hold the legacy form of IPv6 addresses.
+	v.NetAddr = "[2001:db8::1]:10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ"
+	set := validator.NewValidatorSet(1, []*validator.Validator{v})
+
+	require.NoError(t, NormalizeValidatorSet(set))
+	require.Equal(t, "/ip6/2001:db8::1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ", set.Validators[0].NetAddr)
+
+	_, mb, err := Membership(set.Validators)
+	require.NoError(t, err)
+	require.Equal(t, set.Validators[0].NetAddr, mb.Nodes["t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy"].Addr)
+}
+
+func TestSortNetAddrs(t *testing.T) {
+	addrs := []string{
+		"/ip6/::1/tcp/1",
+		"/ip4/127.0.0.1/tcp/1",
+		"/ip6/fe80::1/tcp/1",
+		"/ip4/10.0.0.1/tcp/1",
+		"/ip6/2001:db8::1/tcp/1",
+		"/dns4/validator.example.com/tcp/1",
+	}
+
+	require.Equal(t, []string{
+		"/ip6/2001:db8::1/tcp/1",
+		"/ip4/10.0.0.1/tcp/1",
+		"/dns4/validator.example.com/tcp/1",
+		"/ip6/::1/tcp/1",
+		"/ip4/127.0.0.1/tcp/1",
+		"/ip6/fe80::1/tcp/1",
+	}, SortNetAddrs(addrs, true))
+
+	require.Equal(t, []string{
+		"/ip4/10.0.0.1/tcp/1",
+		"/dns4/validator.example.com/tcp/1",
+		"/ip6/2001:db8::1/tcp/1",
+		"/ip4/127.0.0.1/tcp/1",
+		"/ip6/::1/tcp/1",
+		"/ip6/fe80::1/tcp/1",
+	}, SortNetAddrs(addrs, false))
+}
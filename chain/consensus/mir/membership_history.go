@@ -0,0 +1,194 @@
+package mir
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	trantor "github.com/filecoin-project/mir/pkg/trantor/types"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+)
+
+// ErrMembershipNotFound is returned when the membership of an epoch is not known by the validator.
+var ErrMembershipNotFound = errors.New("membership not found")
+
+// MembershipHistoryEntry is the membership of the epoch that starts with a checkpoint.
+type MembershipHistoryEntry struct {
+	Epoch uint64
+	// Height of the checkpoint the epoch starts with, zero if the epoch hasn't started yet.
+	Height     abi.ChainEpoch
+	Validators []MembershipHistoryValidator
+}
+
+type MembershipHistoryValidator struct {
+	ID     string
+	Addr   string
+	Weight string
+}
+
+func newMembershipHistoryEntry(epoch uint64, height abi.ChainEpoch, mb *mirproto.Membership) MembershipHistoryEntry {
+	entry := MembershipHistoryEntry{Epoch: epoch, Height: height}
+	for id, n := range mb.Nodes {
+		entry.Validators = append(entry.Validators, MembershipHistoryValidator{
+			ID:     id.Pb(),
+			Addr:   n.Addr,
+			Weight: string(n.Weight),
+		})
+	}
+	sort.Slice(entry.Validators, func(i, j int) bool { return entry.Validators[i].ID < entry.Validators[j].ID })
+	return entry
+}
+
+// walkCheckpoints calls fn with the checkpoints persisted by the validator, from the latest one back,
+// until fn returns false or the first missing checkpoint.
+func walkCheckpoints(ctx context.Context, ds db.DB, fn func(ch *checkpoint.StableCheckpoint, snap *Checkpoint) (bool, error)) error {
+	b, err := ds.Get(ctx, LatestCheckpointPbKey)
+	if errors.Is(err, datastore.ErrNotFound) {
+		return xerrors.Errorf("no checkpoint persisted by the validator")
+	}
+	if err != nil {
+		return xerrors.Errorf("error getting latest checkpoint: %w", err)
+	}
+
+	for {
+		ch, err := DeserializeCheckpoint(b)
+		if err != nil {
+			return xerrors.Errorf("error deserializing checkpoint: %w", err)
+		}
+		snap, err := UnwrapCheckpointSnapshot(ch)
+		if err != nil {
+			return xerrors.Errorf("error getting checkpoint snapshot: %w", err)
+		}
+		if next, err := fn(ch, snap); err != nil || !next {
+			return err
+		}
+
+		if snap.Parent.Height <= 1 {
+			// The parent is the genesis checkpoint.
+			return nil
+		}
+		b, err = ds.Get(ctx, HeightCheckIndexKey(snap.Parent.Height))
+		if errors.Is(err, datastore.ErrNotFound) {
+			log.Warnf("checkpoint at height %d not persisted, history starts at epoch %d",
+				snap.Parent.Height, ch.Snapshot.EpochData.EpochConfig.EpochNr)
+			return nil
+		}
+		if err != nil {
+			return xerrors.Errorf("error getting checkpoint at height %d: %w", snap.Parent.Height, err)
+		}
+	}
+}
+
+func checkpointMembership(ch *checkpoint.StableCheckpoint, height abi.ChainEpoch) (MembershipHistoryEntry, error) {
+	mbs := ch.Memberships()
+	if len(mbs) == 0 {
+		return MembershipHistoryEntry{}, xerrors.Errorf("checkpoint at height %d without membership", height)
+	}
+	return newMembershipHistoryEntry(uint64(ch.Snapshot.EpochData.EpochConfig.EpochNr), height, mbs[0]), nil
+}
+
+// MembershipHistory returns the memberships of the epochs in [from, to] in increasing order of epochs,
+// walking back the checkpoints persisted by the validator from the latest one. If to is zero the
+// history ends with the latest checkpoint.
+//
+// Only the checkpoints delivered to or imported by the validator are persisted, so the history
+// stops at the first missing checkpoint.
+func MembershipHistory(ctx context.Context, ds db.DB, from, to uint64) ([]MembershipHistoryEntry, error) {
+	var history []MembershipHistoryEntry
+	err := walkCheckpoints(ctx, ds, func(ch *checkpoint.StableCheckpoint, snap *Checkpoint) (bool, error) {
+		epoch := uint64(ch.Snapshot.EpochData.EpochConfig.EpochNr)
+		if epoch < from {
+			return false, nil
+		}
+		if to == 0 || epoch <= to {
+			entry, err := checkpointMembership(ch, snap.Height)
+			if err != nil {
+				return false, err
+			}
+			history = append(history, entry)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(history, func(i, j int) bool { return history[i].Epoch < history[j].Epoch })
+	return history, nil
+}
+
+// MembershipAt returns the membership of the Mir epoch, which is either the epoch being ordered,
+// one of the ConfigOffset following ones, or an epoch whose membership was persisted by the validator.
+// The memberships of the epochs before the validator persisted them are read from its checkpoints.
+func (m *Manager) MembershipAt(ctx context.Context, epoch uint64) (*MembershipHistoryEntry, error) {
+	st := m.stateManager.status
+	st.lk.Lock()
+	mb, ok := st.memberships[trantor.EpochNr(epoch)]
+	current, height := st.currentEpoch, st.checkpointHeight
+	st.lk.Unlock()
+
+	if ok {
+		if trantor.EpochNr(epoch) != current {
+			height = 0
+		}
+		entry := newMembershipHistoryEntry(epoch, height, mb)
+		return &entry, nil
+	}
+	entry, err := GetMembership(ctx, m.ds, epoch)
+	if !errors.Is(err, ErrMembershipNotFound) {
+		return entry, err
+	}
+	history, err := MembershipHistory(ctx, m.ds, epoch, epoch)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, xerrors.Errorf("epoch %d: %w", epoch, ErrMembershipNotFound)
+	}
+	return &history[0], nil
+}
+
+// MembershipAtHeight returns the membership of the Mir epoch the block at height h was ordered in.
+func (m *Manager) MembershipAtHeight(ctx context.Context, h abi.ChainEpoch) (*MembershipHistoryEntry, error) {
+	st := m.stateManager.status
+	st.lk.Lock()
+	mb, ok := st.memberships[st.currentEpoch]
+	current, height := st.currentEpoch, st.checkpointHeight
+	st.lk.Unlock()
+
+	if ok && h >= height {
+		entry := newMembershipHistoryEntry(uint64(current), height, mb)
+		return &entry, nil
+	}
+	entry, err := GetMembershipAtHeight(ctx, m.ds, uint64(current), h)
+	if !errors.Is(err, ErrMembershipNotFound) {
+		return entry, err
+	}
+
+	entry = nil
+	err = walkCheckpoints(ctx, m.ds, func(ch *checkpoint.StableCheckpoint, snap *Checkpoint) (bool, error) {
+		if snap.Height > h {
+			return true, nil
+		}
+		e, err := checkpointMembership(ch, snap.Height)
+		if err != nil {
+			return false, err
+		}
+		entry = &e
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, xerrors.Errorf("height %d: %w", h, ErrMembershipNotFound)
+	}
+	return entry, nil
+}
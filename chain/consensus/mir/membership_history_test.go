@@ -0,0 +1,52 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	trantor "github.com/filecoin-project/mir/pkg/trantor/types"
+	"github.com/filecoin-project/mir/pkg/types"
+)
+
+func TestMembershipAt(t *testing.T) {
+	ctx := context.Background()
+	sm := &StateManager{status: &statusTracker{}}
+	m := &Manager{id: "id1", stateManager: sm, ds: datastore.NewMapDatastore()}
+
+	cur := weightedMembership(map[types.NodeID]string{"id1": "1", "id2": "1"})
+	next := weightedMembership(map[types.NodeID]string{"id1": "1", "id2": "3"})
+	sm.status.setMemberships(4, map[trantor.EpochNr]*mirproto.Membership{4: cur, 5: cur, 6: next})
+	sm.status.setCheckpointHeight(40)
+
+	entry, err := m.MembershipAt(ctx, 4)
+	require.NoError(t, err)
+	require.Equal(t, &MembershipHistoryEntry{
+		Epoch:  4,
+		Height: 40,
+		Validators: []MembershipHistoryValidator{
+			{ID: "id1", Weight: "1"},
+			{ID: "id2", Weight: "1"},
+		},
+	}, entry)
+
+	// The epochs that haven't started yet have no height.
+	entry, err = m.MembershipAt(ctx, 6)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(0), entry.Height)
+	require.Equal(t, "3", entry.Validators[1].Weight)
+
+	entry, err = m.MembershipAtHeight(ctx, 45)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), entry.Epoch)
+
+	// Earlier epochs are read from the checkpoints persisted by the validator.
+	_, err = m.MembershipAt(ctx, 2)
+	require.Error(t, err)
+	_, err = m.MembershipAtHeight(ctx, 30)
+	require.Error(t, err)
+}
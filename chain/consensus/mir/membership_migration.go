@@ -0,0 +1,76 @@
+package mir
+
+import (
+	"sync"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+)
+
+var _ membership.Reader = &MigratingMembership{}
+
+// MigratingMembership is the membership of a subnet moving from the file membership to the on-chain
+// membership. The membership file is read until the switch epoch of the plan, and the on-chain
+// membership from then on. During the fallback epochs that follow the switch, the file is still
+// read if the on-chain membership can't be, so a failing IPC Agent doesn't stop reconfigurations
+// right after the switch.
+type MigratingMembership struct {
+	file    membership.Reader
+	onchain membership.Reader
+	plan    *membership.MigrationPlan
+
+	lk sync.Mutex
+	// epoch returns the Mir epoch being ordered, it is set by the manager.
+	epoch    func() uint64
+	switched bool
+}
+
+func NewMigratingMembership(file, onchain membership.Reader, plan *membership.MigrationPlan) *MigratingMembership {
+	return &MigratingMembership{
+		file:    file,
+		onchain: onchain,
+		plan:    plan,
+	}
+}
+
+func (mm *MigratingMembership) setEpochSource(epoch func() uint64) {
+	mm.lk.Lock()
+	defer mm.lk.Unlock()
+	mm.epoch = epoch
+}
+
+// GetMembershipInfo gets the membership from the source of the epoch being ordered.
+func (mm *MigratingMembership) GetMembershipInfo() (*membership.Info, error) {
+	mm.lk.Lock()
+	defer mm.lk.Unlock()
+
+	// Before the manager starts, the epoch is unknown and the initial membership is read from the file.
+	var epoch uint64
+	if mm.epoch != nil {
+		epoch = mm.epoch()
+	}
+	if epoch < mm.plan.SwitchEpoch {
+		return mm.file.GetMembershipInfo()
+	}
+
+	info, err := mm.onchain.GetMembershipInfo()
+	if err != nil {
+		if epoch < mm.plan.SwitchEpoch+mm.plan.FallbackEpochs {
+			log.Warnf("failed to read on-chain membership in epoch %d, falling back to the membership file: %v", epoch, err)
+			return mm.file.GetMembershipInfo()
+		}
+		return nil, xerrors.Errorf("error reading on-chain membership: %w", err)
+	}
+
+	if !mm.switched {
+		mm.switched = true
+		log.Infof("membership source switched to on-chain in epoch %d", epoch)
+		if fileInfo, err := mm.file.GetMembershipInfo(); err == nil {
+			if err := membership.CheckMigration(fileInfo.ValidatorSet, info.ValidatorSet); err != nil {
+				log.Warnf("on-chain membership differs from the membership file at the switch: %v", err)
+			}
+		}
+	}
+	return info, nil
+}
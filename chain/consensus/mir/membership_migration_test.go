@@ -0,0 +1,54 @@
+package mir
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+)
+
+type failingMembership struct{}
+
+func (failingMembership) GetMembershipInfo() (*membership.Info, error) {
+	return nil, errors.New("IPC Agent unavailable")
+}
+
+func TestMigratingMembership(t *testing.T) {
+	const v = "t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ"
+	file := membership.StringMembership("1;" + v)
+	onchain := membership.StringMembership("2;" + v)
+	plan := &membership.MigrationPlan{SwitchEpoch: 10, FallbackEpochs: 2}
+
+	var epoch uint64
+	mm := NewMigratingMembership(file, onchain, plan)
+
+	// Before the manager sets the epoch source, the file is read.
+	info, err := mm.GetMembershipInfo()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), info.ValidatorSet.ConfigurationNumber)
+
+	mm.setEpochSource(func() uint64 { return epoch })
+	epoch = 9
+	info, err = mm.GetMembershipInfo()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), info.ValidatorSet.ConfigurationNumber)
+
+	epoch = 10
+	info, err = mm.GetMembershipInfo()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), info.ValidatorSet.ConfigurationNumber)
+
+	// The file is the fallback during the fallback epochs only.
+	mm = NewMigratingMembership(file, failingMembership{}, plan)
+	mm.setEpochSource(func() uint64 { return epoch })
+	epoch = 11
+	info, err = mm.GetMembershipInfo()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), info.ValidatorSet.ConfigurationNumber)
+
+	epoch = 12
+	_, err = mm.GetMembershipInfo()
+	require.Error(t, err)
+}
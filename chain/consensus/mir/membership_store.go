@@ -0,0 +1,147 @@
+package mir
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	trantor "github.com/filecoin-project/mir/pkg/trantor/types"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+)
+
+// MembershipDBPrefix is the prefix of the keys of the persisted memberships, one per Mir epoch.
+const MembershipDBPrefix = "mir/memberships/"
+
+func MembershipKey(epoch uint64) datastore.Key {
+	return datastore.NewKey(MembershipDBPrefix + strconv.FormatUint(epoch, 10))
+}
+
+// PutMembership persists the membership of an epoch. The memberships of the epochs are known
+// ConfigOffset epochs before they start, so an entry without height doesn't override the height
+// of the checkpoint the epoch started with.
+func PutMembership(ctx context.Context, ds db.DB, entry MembershipHistoryEntry) error {
+	if entry.Height == 0 {
+		prev, err := GetMembership(ctx, ds, entry.Epoch)
+		if err != nil && !errors.Is(err, ErrMembershipNotFound) {
+			return err
+		}
+		if prev != nil {
+			entry.Height = prev.Height
+		}
+	}
+	b, err := json.Marshal(&entry)
+	if err != nil {
+		return err
+	}
+	if err := ds.Put(ctx, MembershipKey(entry.Epoch), b); err != nil {
+		return xerrors.Errorf("error persisting membership of epoch %d: %w", entry.Epoch, err)
+	}
+	return nil
+}
+
+// GetMembership returns the persisted membership of the epoch, or ErrMembershipNotFound.
+func GetMembership(ctx context.Context, ds db.DB, epoch uint64) (*MembershipHistoryEntry, error) {
+	b, err := ds.Get(ctx, MembershipKey(epoch))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil, xerrors.Errorf("epoch %d: %w", epoch, ErrMembershipNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry MembershipHistoryEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, xerrors.Errorf("error decoding membership of epoch %d: %w", epoch, err)
+	}
+	return &entry, nil
+}
+
+// GetMembershipAtHeight returns the persisted membership of the epoch the block at height h was
+// ordered in, looking back from the epoch latest, or ErrMembershipNotFound if the memberships
+// persisted down to that epoch have gaps.
+func GetMembershipAtHeight(ctx context.Context, ds db.DB, latest uint64, h abi.ChainEpoch) (*MembershipHistoryEntry, error) {
+	for e := latest; ; e-- {
+		entry, err := GetMembership(ctx, ds, e)
+		if err != nil {
+			return nil, err
+		}
+		// The epochs that haven't started yet have no height.
+		if entry.Height != 0 && entry.Height <= h {
+			return entry, nil
+		}
+		if e == 0 {
+			return nil, xerrors.Errorf("height %d: %w", h, ErrMembershipNotFound)
+		}
+	}
+}
+
+// putMemberships persists the memberships of the epoch and the following ones, the first of which
+// started with the checkpoint at height h, if known.
+func putMemberships(ctx context.Context, ds db.DB, epoch trantor.EpochNr, h abi.ChainEpoch, mbs []*mirproto.Membership) error {
+	for i, mb := range mbs {
+		var height abi.ChainEpoch
+		if i == 0 {
+			height = h
+		}
+		if err := PutMembership(ctx, ds, newMembershipHistoryEntry(uint64(epoch)+uint64(i), height, mb)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putCheckpointMemberships persists the memberships certified by a checkpoint at height h.
+func putCheckpointMemberships(ctx context.Context, ds db.DB, ch *checkpoint.StableCheckpoint, h abi.ChainEpoch) error {
+	return putMemberships(ctx, ds, ch.Snapshot.EpochData.EpochConfig.EpochNr, h, ch.Memberships())
+}
+
+// checkCheckpointMemberships checks that the membership that signed the certificate of a checkpoint,
+// i.e. the one of the epoch before the one it starts, and the memberships it certifies are the ones
+// persisted for their epochs. The epochs without persisted membership are trusted, which is the case
+// of the epochs before the first checkpoint verified by the node.
+func checkCheckpointMemberships(ctx context.Context, ds db.DB, ch *checkpoint.StableCheckpoint) error {
+	epoch := uint64(ch.Epoch())
+	if epoch > 0 {
+		if err := checkMembership(ctx, ds, epoch-1, ch.PreviousMembership()); err != nil {
+			return xerrors.Errorf("checkpoint certificate not signed by the membership of its epoch: %w", err)
+		}
+	}
+	for i, mb := range ch.Memberships() {
+		if err := checkMembership(ctx, ds, epoch+uint64(i), mb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkMembership(ctx context.Context, ds db.DB, epoch uint64, mb *mirproto.Membership) error {
+	if mb == nil {
+		return xerrors.Errorf("no membership for epoch %d", epoch)
+	}
+	expected, err := GetMembership(ctx, ds, epoch)
+	if errors.Is(err, ErrMembershipNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	actual := newMembershipHistoryEntry(epoch, 0, mb)
+	if len(actual.Validators) != len(expected.Validators) {
+		return xerrors.Errorf("membership of epoch %d has %d validators, expected %d",
+			epoch, len(actual.Validators), len(expected.Validators))
+	}
+	for i, v := range actual.Validators {
+		if v != expected.Validators[i] {
+			return xerrors.Errorf("membership of epoch %d has validator %s with weight %s, expected %s with weight %s",
+				epoch, v.ID, v.Weight, expected.Validators[i].ID, expected.Validators[i].Weight)
+		}
+	}
+	return nil
+}
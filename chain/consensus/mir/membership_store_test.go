@@ -0,0 +1,93 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	trantor "github.com/filecoin-project/mir/pkg/trantor/types"
+	"github.com/filecoin-project/mir/pkg/types"
+)
+
+func TestMembershipStore(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+
+	cur := weightedMembership(map[types.NodeID]string{"id1": "1", "id2": "1"})
+	next := weightedMembership(map[types.NodeID]string{"id1": "1", "id2": "3"})
+
+	_, err := GetMembership(ctx, ds, 4)
+	require.ErrorIs(t, err, ErrMembershipNotFound)
+
+	// The memberships of the following epochs are known before they start.
+	require.NoError(t, putMemberships(ctx, ds, 4, 40, []*mirproto.Membership{cur, cur, next}))
+	require.NoError(t, putMemberships(ctx, ds, 5, 0, []*mirproto.Membership{cur, next, next}))
+	entry, err := GetMembership(ctx, ds, 4)
+	require.NoError(t, err)
+	require.Equal(t, &MembershipHistoryEntry{
+		Epoch:  4,
+		Height: 40,
+		Validators: []MembershipHistoryValidator{
+			{ID: "id1", Weight: "1"},
+			{ID: "id2", Weight: "1"},
+		},
+	}, entry)
+	entry, err = GetMembership(ctx, ds, 6)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(0), entry.Height)
+	require.Equal(t, "3", entry.Validators[1].Weight)
+
+	// The height is set once the epoch starts, and kept when the membership is persisted again.
+	require.NoError(t, putMemberships(ctx, ds, 5, 50, []*mirproto.Membership{cur}))
+	require.NoError(t, putMemberships(ctx, ds, 5, 0, []*mirproto.Membership{cur, next, next}))
+	entry, err = GetMembership(ctx, ds, 5)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(50), entry.Height)
+
+	entry, err = GetMembershipAtHeight(ctx, ds, 7, 49)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), entry.Epoch)
+	entry, err = GetMembershipAtHeight(ctx, ds, 7, 60)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), entry.Epoch)
+	// The epochs before 4 weren't persisted.
+	_, err = GetMembershipAtHeight(ctx, ds, 7, 39)
+	require.ErrorIs(t, err, ErrMembershipNotFound)
+}
+
+func membershipCheckpoint(epoch trantor.EpochNr, prev *mirproto.Membership, mbs ...*mirproto.Membership) *checkpoint.StableCheckpoint {
+	return &checkpoint.StableCheckpoint{
+		Snapshot: &mirproto.StateSnapshot{
+			EpochData: &mirproto.EpochData{
+				EpochConfig:        &mirproto.EpochConfig{EpochNr: epoch, Memberships: mbs},
+				PreviousMembership: prev,
+			},
+		},
+	}
+}
+
+func TestCheckCheckpointMemberships(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+
+	cur := weightedMembership(map[types.NodeID]string{"id1": "1", "id2": "1"})
+	next := weightedMembership(map[types.NodeID]string{"id1": "1", "id2": "3"})
+	forged := weightedMembership(map[types.NodeID]string{"id3": "1", "id4": "1"})
+
+	// Nothing is known before the first checkpoint.
+	first := membershipCheckpoint(4, forged, cur, cur)
+	require.NoError(t, checkCheckpointMemberships(ctx, ds, first))
+	require.NoError(t, putCheckpointMemberships(ctx, ds, first, 40))
+
+	require.NoError(t, checkCheckpointMemberships(ctx, ds, membershipCheckpoint(5, cur, cur, next)))
+	// Signed by a stale or a future membership.
+	require.Error(t, checkCheckpointMemberships(ctx, ds, membershipCheckpoint(5, forged, cur, next)))
+	require.Error(t, checkCheckpointMemberships(ctx, ds, membershipCheckpoint(5, next, cur, next)))
+	// Certifying another membership for an epoch that is already fixed.
+	require.Error(t, checkCheckpointMemberships(ctx, ds, membershipCheckpoint(5, cur, next, next)))
+}
@@ -0,0 +1,43 @@
+package mir
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/stats"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+// MpoolSelectBackoff is the delay before the first retry of a failed mempool selection, doubled on every retry.
+var MpoolSelectBackoff = 50 * time.Millisecond
+
+// selectWithRetries calls selectFn until it succeeds, up to retries+1 times, backing off between attempts.
+// Every failure is recorded in the MirMpoolSelectFailures metric. The error of the last attempt is returned
+// if none succeeds.
+func selectWithRetries(
+	ctx context.Context,
+	retries int,
+	selectFn func() ([]*types.SignedMessage, error),
+) ([]*types.SignedMessage, error) {
+	backoff := MpoolSelectBackoff
+	for i := 0; ; i++ {
+		msgs, err := selectFn()
+		if err == nil {
+			return msgs, nil
+		}
+		stats.Record(ctx, metrics.MirMpoolSelectFailures.M(1))
+		if i >= retries {
+			return nil, err
+		}
+		log.Debugw("retrying mempool selection", "attempt", i+1, "backoff", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
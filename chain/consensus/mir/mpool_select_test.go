@@ -0,0 +1,48 @@
+package mir
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestSelectWithRetries(t *testing.T) {
+	MpoolSelectBackoff = time.Millisecond
+	ctx := context.Background()
+	errSelect := errors.New("select failed")
+
+	calls := 0
+	msgs, err := selectWithRetries(ctx, 3, func() ([]*types.SignedMessage, error) {
+		calls++
+		if calls < 3 {
+			return nil, errSelect
+		}
+		return []*types.SignedMessage{{}}, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 3, calls)
+
+	calls = 0
+	_, err = selectWithRetries(ctx, 2, func() ([]*types.SignedMessage, error) {
+		calls++
+		return nil, errSelect
+	})
+	require.ErrorIs(t, err, errSelect)
+	require.Equal(t, 3, calls)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	calls = 0
+	_, err = selectWithRetries(cctx, 5, func() ([]*types.SignedMessage, error) {
+		calls++
+		return nil, errSelect
+	})
+	require.ErrorIs(t, err, errSelect)
+	require.Equal(t, 1, calls)
+}
@@ -0,0 +1,197 @@
+package mir
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/exitcode"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// MsgStage is a stage of the lifecycle of a message sent to the subnet.
+type MsgStage string
+
+const (
+	// MsgAccepted is the message accepted to the mempool of the daemon of the validator.
+	MsgAccepted MsgStage = "accepted"
+	// MsgProposed is the message included by the validator in a batch proposed to Mir.
+	MsgProposed MsgStage = "proposed"
+	// MsgOrdered is the message in a batch ordered by Mir.
+	MsgOrdered MsgStage = "ordered"
+	// MsgIncluded is the message included in the block created from the ordered batch.
+	MsgIncluded MsgStage = "included"
+	// MsgExecuted is the message executed, with the exit code of its receipt.
+	MsgExecuted MsgStage = "executed"
+)
+
+// MsgEvent is a stage reached by a message.
+type MsgEvent struct {
+	Message cid.Cid
+	Stage   MsgStage
+	// Height of the block including the message once ordered, or of the tipset it was executed in.
+	Height abi.ChainEpoch `json:",omitempty"`
+	// Receipt of the message once executed.
+	ExitCode exitcode.ExitCode `json:",omitempty"`
+	GasUsed  int64             `json:",omitempty"`
+}
+
+// msgEventBuffer is the number of events buffered for a message, more than its stages as a message
+// can be proposed again, e.g. after the restart of the validator.
+const msgEventBuffer = 8
+
+// MsgTracker streams the lifecycle events of messages to their subscribers. Only the events of
+// the messages with subscribers are kept. Like the CheckpointNotifier, it outlives the Mir manager.
+type MsgTracker struct {
+	lk   sync.Mutex
+	subs map[cid.Cid]map[chan *MsgEvent]struct{}
+}
+
+func NewMsgTracker() *MsgTracker {
+	return &MsgTracker{subs: make(map[cid.Cid]map[chan *MsgEvent]struct{})}
+}
+
+// Track returns a channel receiving the events of the message from now on, until ctx is done.
+func (t *MsgTracker) Track(ctx context.Context, c cid.Cid) <-chan *MsgEvent {
+	ch := make(chan *MsgEvent, msgEventBuffer)
+	t.lk.Lock()
+	if t.subs[c] == nil {
+		t.subs[c] = make(map[chan *MsgEvent]struct{})
+	}
+	t.subs[c][ch] = struct{}{}
+	t.lk.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		t.lk.Lock()
+		defer t.lk.Unlock()
+		delete(t.subs[c], ch)
+		if len(t.subs[c]) == 0 {
+			delete(t.subs, c)
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Notify sends the event to the subscribers of its message. Events are dropped for the
+// subscribers that don't keep up rather than slowing down the validator.
+func (t *MsgTracker) Notify(e *MsgEvent) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	for ch := range t.subs[e.Message] {
+		select {
+		case ch <- e:
+		default:
+			log.Warnf("message %s subscriber fell behind, dropping %s event", e.Message, e.Stage)
+		}
+	}
+}
+
+// MsgWaiter is the API used to wait for the execution of a message.
+type MsgWaiter interface {
+	StateWaitMsg(ctx context.Context, c cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error)
+}
+
+// StreamMsgEvents streams the events of a message from now on until its execution, after which the
+// stream is closed. The stages up to its inclusion are reported by the tracker, the execution is
+// looked up in the chain, so it is reported even if the message was proposed by another validator
+// or reached the earlier stages before the stream started. The stream is also closed if ctx is done
+// or the execution of the message can't be waited for.
+func StreamMsgEvents(ctx context.Context, node MsgWaiter, tracker *MsgTracker, c cid.Cid, first ...*MsgEvent) <-chan *MsgEvent {
+	ctx, cancel := context.WithCancel(ctx)
+	events := tracker.Track(ctx, c)
+	out := make(chan *MsgEvent, msgEventBuffer)
+	for _, e := range first {
+		out <- e
+	}
+
+	executed := make(chan *MsgEvent, 1)
+	go func() {
+		lookup, err := node.StateWaitMsg(ctx, c, 0, api.LookbackNoLimit, true)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warnf("failed to wait for the execution of message %s: %v", c, err)
+			}
+			close(executed)
+			return
+		}
+		executed <- &MsgEvent{
+			Message:  c,
+			Stage:    MsgExecuted,
+			Height:   lookup.Height,
+			ExitCode: lookup.Receipt.ExitCode,
+			GasUsed:  lookup.Receipt.GasUsed,
+		}
+	}()
+
+	go func() {
+		defer close(out)
+		defer cancel()
+		send := func(e *MsgEvent) bool {
+			select {
+			case out <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok || !send(e) {
+					return
+				}
+			case e, ok := <-executed:
+				if !ok {
+					return
+				}
+				// The events of the earlier stages are sent first.
+			drain:
+				for {
+					select {
+					case p, ok := <-events:
+						if !ok {
+							break drain
+						}
+						if !send(p) {
+							return
+						}
+					default:
+						break drain
+					}
+				}
+				send(e)
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// PushMessage pushes a message to the mempool of the daemon of the validator, which signs it,
+// and streams its events until its execution, starting with its acceptance to the mempool.
+func (m *Manager) PushMessage(ctx context.Context, tracker *MsgTracker, msg *types.Message, spec *api.MessageSendSpec) (<-chan *MsgEvent, error) {
+	smsg, err := m.lotusNode.MpoolPushMessage(ctx, msg, spec)
+	if err != nil {
+		return nil, err
+	}
+	c := smsg.Cid()
+	return StreamMsgEvents(ctx, m.lotusNode, tracker, c, &MsgEvent{Message: c, Stage: MsgAccepted}), nil
+}
+
+// MsgEvents streams the events of a message sent to the subnet by other means until its execution.
+func (m *Manager) MsgEvents(ctx context.Context, tracker *MsgTracker, c cid.Cid) <-chan *MsgEvent {
+	return StreamMsgEvents(ctx, m.lotusNode, tracker, c)
+}
+
+// msgEvent reports a stage of a message, if the validator reports the events of messages.
+func msgEvent(fn func(*MsgEvent), c cid.Cid, stage MsgStage, h abi.ChainEpoch) {
+	if fn != nil {
+		fn(&MsgEvent{Message: c, Stage: stage, Height: h})
+	}
+}
@@ -0,0 +1,96 @@
+package mir
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/exitcode"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+type fakeMsgWaiter struct {
+	executed chan *api.MsgLookup
+}
+
+func (w *fakeMsgWaiter) StateWaitMsg(ctx context.Context, _ cid.Cid, _ uint64, _ abi.ChainEpoch, _ bool) (*api.MsgLookup, error) {
+	select {
+	case l := <-w.executed:
+		return l, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestMsgTracker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker := NewMsgTracker()
+	c1, err := abi.CidBuilder.Sum([]byte{1})
+	require.NoError(t, err)
+	c2, err := abi.CidBuilder.Sum([]byte{2})
+	require.NoError(t, err)
+
+	events := tracker.Track(ctx, c1)
+	tracker.Notify(&MsgEvent{Message: c2, Stage: MsgProposed})
+	tracker.Notify(&MsgEvent{Message: c1, Stage: MsgProposed})
+	e := <-events
+	require.Equal(t, c1, e.Message)
+	require.Equal(t, MsgProposed, e.Stage)
+
+	// Events are dropped rather than blocking the validator.
+	for i := 0; i < 2*msgEventBuffer; i++ {
+		tracker.Notify(&MsgEvent{Message: c1, Stage: MsgProposed})
+	}
+
+	cancel()
+	require.Eventually(t, func() bool {
+		tracker.lk.Lock()
+		defer tracker.lk.Unlock()
+		return len(tracker.subs) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStreamMsgEvents(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewMsgTracker()
+	waiter := &fakeMsgWaiter{executed: make(chan *api.MsgLookup, 1)}
+	c, err := abi.CidBuilder.Sum([]byte{1})
+	require.NoError(t, err)
+
+	events := StreamMsgEvents(ctx, waiter, tracker, c, &MsgEvent{Message: c, Stage: MsgAccepted})
+	require.Equal(t, MsgAccepted, (<-events).Stage)
+	require.Eventually(t, func() bool {
+		tracker.lk.Lock()
+		defer tracker.lk.Unlock()
+		return len(tracker.subs[c]) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	tracker.Notify(&MsgEvent{Message: c, Stage: MsgProposed})
+	tracker.Notify(&MsgEvent{Message: c, Stage: MsgOrdered, Height: 5})
+	tracker.Notify(&MsgEvent{Message: c, Stage: MsgIncluded, Height: 5})
+	waiter.executed <- &api.MsgLookup{Message: c, Height: 6, Receipt: types.MessageReceipt{ExitCode: exitcode.ErrInsufficientFunds, GasUsed: 10}}
+
+	var stages []MsgStage
+	var last *MsgEvent
+	for e := range events {
+		stages = append(stages, e.Stage)
+		last = e
+	}
+	require.Equal(t, []MsgStage{MsgProposed, MsgOrdered, MsgIncluded, MsgExecuted}, stages)
+	require.Equal(t, abi.ChainEpoch(6), last.Height)
+	require.Equal(t, exitcode.ErrInsufficientFunds, last.ExitCode)
+	require.Equal(t, int64(10), last.GasUsed)
+
+	// The subscription ends with the stream.
+	require.Eventually(t, func() bool {
+		tracker.lk.Lock()
+		defer tracker.lk.Unlock()
+		return len(tracker.subs) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,125 @@
+package mir
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+const MsgIndexPrefix = "mir-msg-index/"
+
+var (
+	msgIndexFinalKey = datastore.NewKey(MsgIndexPrefix + "final")
+
+	_ stmgr.MsgIndex = &msgIndex{}
+)
+
+func msgIndexKey(from address.Address, nonce uint64) datastore.Key {
+	return datastore.NewKey(MsgIndexPrefix + "msg/" + from.String() + "/" + strconv.FormatUint(nonce, 10))
+}
+
+type msgIndexEntry struct {
+	Msg    cid.Cid
+	Block  cid.Cid
+	Height abi.ChainEpoch
+}
+
+// msgIndex maps the sender and nonce of the messages of the validated blocks to the block including them,
+// so searching for a message doesn't walk back the chain. Mir blocks are not reorganized, except for the
+// provisional blocks of fast verification, so an entry only changes if its block is rolled back.
+//
+// The height of the latest verified checkpoint is kept as the finality marker: the entries at or below it
+// are committed by a checkpoint.
+type msgIndex struct {
+	ds datastore.Batching
+
+	lk    sync.Mutex
+	final abi.ChainEpoch
+}
+
+func newMsgIndex(ds datastore.Batching) *msgIndex {
+	i := &msgIndex{ds: ds}
+	if b, err := ds.Get(context.Background(), msgIndexFinalKey); err == nil {
+		h, err := strconv.ParseInt(string(b), 10, 64)
+		if err == nil {
+			i.final = abi.ChainEpoch(h)
+		}
+	}
+	return i
+}
+
+// add indexes the messages of a validated block.
+func (i *msgIndex) add(ctx context.Context, b *types.FullBlock) error {
+	batch, err := i.ds.Batch(ctx)
+	if err != nil {
+		return err
+	}
+	put := func(m *types.Message, c cid.Cid) error {
+		v, err := json.Marshal(&msgIndexEntry{Msg: c, Block: b.Cid(), Height: b.Header.Height})
+		if err != nil {
+			return err
+		}
+		return batch.Put(ctx, msgIndexKey(m.From, m.Nonce), v)
+	}
+	for _, m := range b.BlsMessages {
+		if err := put(m, m.Cid()); err != nil {
+			return err
+		}
+	}
+	for _, m := range b.SecpkMessages {
+		if err := put(m.VMMessage(), m.Cid()); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(ctx)
+}
+
+// finalize moves the finality marker to the height of a verified checkpoint.
+func (i *msgIndex) finalize(ctx context.Context, h abi.ChainEpoch) error {
+	i.lk.Lock()
+	defer i.lk.Unlock()
+	if h <= i.final {
+		return nil
+	}
+	if err := i.ds.Put(ctx, msgIndexFinalKey, []byte(strconv.FormatInt(int64(h), 10))); err != nil {
+		return err
+	}
+	i.final = h
+	return nil
+}
+
+// GetMsgInfo returns the block including the message of a sender with a nonce.
+func (i *msgIndex) GetMsgInfo(ctx context.Context, from address.Address, nonce uint64) (*stmgr.MsgInfo, error) {
+	v, err := i.ds.Get(ctx, msgIndexKey(from, nonce))
+	if err != nil {
+		if errors.Is(err, datastore.ErrNotFound) {
+			return nil, stmgr.ErrMsgNotIndexed
+		}
+		return nil, err
+	}
+	var e msgIndexEntry
+	if err := json.Unmarshal(v, &e); err != nil {
+		return nil, xerrors.Errorf("error decoding message index entry: %w", err)
+	}
+
+	i.lk.Lock()
+	defer i.lk.Unlock()
+	return &stmgr.MsgInfo{
+		Message: e.Msg,
+		Block:   e.Block,
+		Epoch:   e.Height,
+		Final:   e.Height <= i.final,
+	}, nil
+}
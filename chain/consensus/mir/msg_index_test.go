@@ -0,0 +1,49 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestMsgIndex(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+	idx := newMsgIndex(ds)
+
+	from, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	m0 := &types.Message{From: from, To: from, Nonce: 0}
+	m1 := &types.SignedMessage{Message: types.Message{From: from, To: from, Nonce: 1}}
+
+	h := mock.MkBlock(nil, 1, 1)
+	h.Height = 10
+	b := &types.FullBlock{Header: h, BlsMessages: []*types.Message{m0}, SecpkMessages: []*types.SignedMessage{m1}}
+	require.NoError(t, idx.add(ctx, b))
+
+	info, err := idx.GetMsgInfo(ctx, from, 1)
+	require.NoError(t, err)
+	require.Equal(t, m1.Cid(), info.Message)
+	require.Equal(t, b.Cid(), info.Block)
+	require.Equal(t, b.Header.Height, info.Epoch)
+	require.False(t, info.Final)
+
+	_, err = idx.GetMsgInfo(ctx, from, 2)
+	require.ErrorIs(t, err, stmgr.ErrMsgNotIndexed)
+
+	// The finality marker only moves forward, and survives restarts.
+	require.NoError(t, idx.finalize(ctx, 10))
+	require.NoError(t, idx.finalize(ctx, 5))
+	info, err = newMsgIndex(ds).GetMsgInfo(ctx, from, 0)
+	require.NoError(t, err)
+	require.Equal(t, m0.Cid(), info.Message)
+	require.True(t, info.Final)
+}
@@ -0,0 +1,217 @@
+package mir
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/filecoin-project/mir/pkg/events"
+	"github.com/filecoin-project/mir/pkg/net"
+	"github.com/filecoin-project/mir/pkg/pb/eventpb"
+	"github.com/filecoin-project/mir/pkg/pb/messagepb"
+	transportpbtypes "github.com/filecoin-project/mir/pkg/pb/transportpb/types"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	t "github.com/filecoin-project/mir/pkg/types"
+)
+
+// PeerDiagnostics is the state of the connection of the validator to another validator of the membership.
+type PeerDiagnostics struct {
+	ID            string
+	Addr          string
+	Connectedness string
+	// Estimate of the round-trip time to the peer, zero if unknown.
+	RTT          time.Duration
+	LastSent     time.Time
+	LastReceived time.Time
+	BytesOut     uint64
+	BytesIn      uint64
+}
+
+var _ net.Transport = &DiagnosticTransport{}
+
+// validatorConnTag protects the connections to the validators of the membership.
+const validatorConnTag = "mir-validator"
+
+// DiagnosticTransport wraps the transport of a validator to keep track of the traffic exchanged
+// with every validator of the membership, so operators can find the peers causing timeouts.
+type DiagnosticTransport struct {
+	net.Transport
+	h host.Host
+
+	lk    sync.Mutex
+	peers map[t.NodeID]*peerStats
+
+	startOut sync.Once
+	out      chan *events.EventList
+	stop     chan struct{}
+}
+
+type peerStats struct {
+	addr         string
+	pid          peer.ID
+	lastSent     time.Time
+	lastReceived time.Time
+	bytesOut     uint64
+	bytesIn      uint64
+}
+
+func NewDiagnosticTransport(tr net.Transport, h host.Host) *DiagnosticTransport {
+	return &DiagnosticTransport{
+		Transport: tr,
+		h:         h,
+		peers:     make(map[t.NodeID]*peerStats),
+		out:       make(chan *events.EventList),
+		stop:      make(chan struct{}),
+	}
+}
+
+func (d *DiagnosticTransport) Stop() {
+	d.Transport.Stop()
+	close(d.stop)
+}
+
+func (d *DiagnosticTransport) Send(dest t.NodeID, msg *messagepb.Message) error {
+	d.sent(dest, msg)
+	return d.Transport.Send(dest, msg)
+}
+
+func (d *DiagnosticTransport) Connect(nodes *mirproto.Membership) {
+	d.setMembership(nodes)
+	d.Transport.Connect(nodes)
+}
+
+func (d *DiagnosticTransport) CloseOldConnections(newNodes *mirproto.Membership) {
+	d.setMembership(newNodes)
+	d.Transport.CloseOldConnections(newNodes)
+}
+
+func (d *DiagnosticTransport) ApplyEvents(ctx context.Context, eventList *events.EventList) error {
+	iter := eventList.Iterator()
+	for event := iter.Next(); event != nil; event = iter.Next() {
+		e, ok := event.Type.(*eventpb.Event_Transport)
+		if !ok {
+			continue
+		}
+		if s, ok := transportpbtypes.EventFromPb(e.Transport).Type.(*transportpbtypes.Event_SendMessage); ok {
+			for _, dest := range s.SendMessage.Destinations {
+				d.sent(dest, s.SendMessage.Msg.Pb())
+			}
+		}
+	}
+	return d.Transport.ApplyEvents(ctx, eventList)
+}
+
+// EventsOut forwards the events of the wrapped transport, recording the messages received.
+func (d *DiagnosticTransport) EventsOut() <-chan *events.EventList {
+	d.startOut.Do(func() {
+		in := d.Transport.EventsOut()
+		go func() {
+			for {
+				select {
+				case <-d.stop:
+					return
+				case evts := <-in:
+					d.received(evts)
+					select {
+					case <-d.stop:
+						return
+					case d.out <- evts:
+					}
+				}
+			}
+		}()
+	})
+	return d.out
+}
+
+// Diagnostics returns the state of the connections to the validators of the latest membership.
+func (d *DiagnosticTransport) Diagnostics() []PeerDiagnostics {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	res := make([]PeerDiagnostics, 0, len(d.peers))
+	for id, p := range d.peers {
+		pd := PeerDiagnostics{
+			ID:            id.Pb(),
+			Addr:          p.addr,
+			Connectedness: "unknown",
+			LastSent:      p.lastSent,
+			LastReceived:  p.lastReceived,
+			BytesOut:      p.bytesOut,
+			BytesIn:       p.bytesIn,
+		}
+		if p.pid != "" {
+			pd.Connectedness = d.h.Network().Connectedness(p.pid).String()
+			pd.RTT = d.h.Peerstore().LatencyEWMA(p.pid)
+		}
+		res = append(res, pd)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// setMembership tracks the validators of a new membership. Their connections are protected from
+// the connection manager of the host, which would otherwise trim them in large memberships.
+func (d *DiagnosticTransport) setMembership(nodes *mirproto.Membership) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	peers := make(map[t.NodeID]*peerStats, len(nodes.Nodes))
+	for id, n := range nodes.Nodes {
+		p, ok := d.peers[id]
+		if !ok || p.addr != n.Addr {
+			p = &peerStats{addr: n.Addr}
+			if ma, err := multiaddr.NewMultiaddr(n.Addr); err == nil {
+				if info, err := peer.AddrInfoFromP2pAddr(ma); err == nil {
+					p.pid = info.ID
+				}
+			}
+		}
+		if p.pid != "" {
+			d.h.ConnManager().Protect(p.pid, validatorConnTag)
+		}
+		peers[id] = p
+	}
+	for id, p := range d.peers {
+		if n, ok := peers[id]; p.pid != "" && (!ok || n.pid != p.pid) {
+			d.h.ConnManager().Unprotect(p.pid, validatorConnTag)
+		}
+	}
+	d.peers = peers
+}
+
+func (d *DiagnosticTransport) sent(dest t.NodeID, msg *messagepb.Message) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if p, ok := d.peers[dest]; ok {
+		p.lastSent = time.Now()
+		p.bytesOut += uint64(proto.Size(msg))
+	}
+}
+
+func (d *DiagnosticTransport) received(evts *events.EventList) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	iter := evts.Iterator()
+	for event := iter.Next(); event != nil; event = iter.Next() {
+		e, ok := event.Type.(*eventpb.Event_Transport)
+		if !ok {
+			continue
+		}
+		r, ok := transportpbtypes.EventFromPb(e.Transport).Type.(*transportpbtypes.Event_MessageReceived)
+		if !ok {
+			continue
+		}
+		if p, ok := d.peers[r.MessageReceived.From]; ok {
+			p.lastReceived = time.Now()
+			p.bytesIn += uint64(proto.Size(r.MessageReceived.Msg.Pb()))
+		}
+	}
+}
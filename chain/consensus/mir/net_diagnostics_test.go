@@ -0,0 +1,38 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/mir/pkg/pb/messagepb"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	mirtypes "github.com/filecoin-project/mir/pkg/types"
+)
+
+func TestDiagnosticTransportMembership(t *testing.T) {
+	d := NewDiagnosticTransport(nil, nil)
+	d.setMembership(&mirproto.Membership{Nodes: map[mirtypes.NodeID]*mirproto.NodeIdentity{
+		"id1": {Id: "id1", Addr: "/ip4/127.0.0.1/tcp/10000"},
+		"id2": {Id: "id2", Addr: "/ip4/127.0.0.1/tcp/10001"},
+	}})
+
+	d.sent("id1", &messagepb.Message{DestModule: "iss"})
+	d.sent("id3", &messagepb.Message{DestModule: "iss"})
+
+	peers := d.Diagnostics()
+	require.Len(t, peers, 2)
+	require.Equal(t, "id1", peers[0].ID)
+	require.NotZero(t, peers[0].BytesOut)
+	require.False(t, peers[0].LastSent.IsZero())
+	require.Equal(t, "unknown", peers[0].Connectedness)
+	require.Zero(t, peers[1].BytesOut)
+
+	// The stats of the validators that stay in the membership are kept.
+	d.setMembership(&mirproto.Membership{Nodes: map[mirtypes.NodeID]*mirproto.NodeIdentity{
+		"id1": {Id: "id1", Addr: "/ip4/127.0.0.1/tcp/10000"},
+	}})
+	peers = d.Diagnostics()
+	require.Len(t, peers, 1)
+	require.NotZero(t, peers[0].BytesOut)
+}
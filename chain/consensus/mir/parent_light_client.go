@@ -0,0 +1,276 @@
+package mir
+
+import (
+	"context"
+	"sync"
+
+	"github.com/consensus-shipyard/go-ipc-types/sdk"
+	"github.com/consensus-shipyard/go-ipc-types/subnetactor"
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	block "github.com/ipfs/go-libipfs/blocks"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/lightclient"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/vm"
+)
+
+// ParentNode is the API of a full node of the parent chain used by the light client.
+// Its answers aren't trusted.
+type ParentNode interface {
+	ChainHead(ctx context.Context) (*types.TipSet, error)
+	ChainGetTipSetByHeight(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error)
+	ChainReadObj(ctx context.Context, c cid.Cid) ([]byte, error)
+}
+
+// ParentLightClient follows the parent chain of a subnet without trusting the node serving it.
+//
+// The parent of a Mir subnet is a Mir chain: its blocks regularly include a checkpoint certified by a
+// strong quorum of its validators that commits the CIDs of the blocks since the previous checkpoint.
+// Starting from a trusted checkpoint of the parent, every new checkpoint is verified against the membership
+// committed by the previous one, so the headers committed by the latest checkpoint can be trusted. The state
+// of the parent is read from the state root of the last committed block, and every IPLD block read from the
+// node is checked against its CID.
+type ParentLightClient struct {
+	node ParentNode
+
+	lk sync.Mutex
+	// Latest verified checkpoint of the parent and its snapshot.
+	trusted *checkpoint.StableCheckpoint
+	snap    *Checkpoint
+	// Last block committed by the latest verified checkpoint.
+	header *types.BlockHeader
+	// Height of the parent chain up to which checkpoints have been verified.
+	synced abi.ChainEpoch
+}
+
+// NewParentLightClient creates a light client trusting the anchor checkpoint of the parent,
+// e.g. a checkpoint exported from a validator of the parent.
+func NewParentLightClient(ctx context.Context, node ParentNode, anchor *checkpoint.StableCheckpoint) (*ParentLightClient, error) {
+	snap, err := UnwrapCheckpointSnapshot(anchor)
+	if err != nil {
+		return nil, xerrors.Errorf("error unwrapping anchor checkpoint: %w", err)
+	}
+	lc := &ParentLightClient{node: node}
+	header, err := lc.committedHeader(ctx, snap)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting header committed by the anchor checkpoint: %w", err)
+	}
+	lc.trusted, lc.snap, lc.header = anchor, snap, header
+	// A checkpoint is included in a block at its height or above.
+	lc.synced = snap.Height - 1
+	return lc, nil
+}
+
+// TrustedHeader returns the last block of the parent committed by the latest verified checkpoint.
+func (lc *ParentLightClient) TrustedHeader() *types.BlockHeader {
+	lc.lk.Lock()
+	defer lc.lk.Unlock()
+	return lc.header
+}
+
+// Sync verifies the checkpoints included in the parent chain since the latest verified one.
+func (lc *ParentLightClient) Sync(ctx context.Context) error {
+	lc.lk.Lock()
+	defer lc.lk.Unlock()
+
+	head, err := lc.node.ChainHead(ctx)
+	if err != nil {
+		return xerrors.Errorf("error getting parent chain head: %w", err)
+	}
+	for h := lc.synced + 1; h <= head.Height(); h++ {
+		ts, err := lc.node.ChainGetTipSetByHeight(ctx, h, head.Key())
+		if err != nil {
+			return xerrors.Errorf("error getting parent tipset at height %d: %w", h, err)
+		}
+		if ts.Height() == h {
+			b := ts.Blocks()[0]
+			if b.ElectionProof != nil && b.ElectionProof.VRFProof != nil && b.Ticket != nil {
+				if err := lc.verifyCheckpoint(ctx, b); err != nil {
+					return xerrors.Errorf("invalid checkpoint in parent block at height %d: %w", h, err)
+				}
+			}
+		}
+		lc.synced = h
+	}
+	return nil
+}
+
+// verifyCheckpoint verifies the checkpoint included in a block of the parent and trusts it if it
+// follows the latest verified checkpoint.
+func (lc *ParentLightClient) verifyCheckpoint(ctx context.Context, h *types.BlockHeader) error {
+	ch, _, err := checkpointInHeader(h)
+	if err != nil {
+		return err
+	}
+	snap, err := UnwrapCheckpointSnapshot(ch)
+	if err != nil {
+		return err
+	}
+	if snap.Height <= lc.snap.Height {
+		return nil
+	}
+
+	// The checkpoint is certified by the membership of the epoch started by the previous checkpoint,
+	// not by the membership it claims.
+	if _, err := lightclient.VerifySuccessor(lc.trusted, ch); err != nil {
+		return err
+	}
+
+	header, err := lc.committedHeader(ctx, snap)
+	if err != nil {
+		return err
+	}
+	lc.trusted, lc.snap, lc.header = ch, snap, header
+	return nil
+}
+
+// committedHeader returns the header of the last block committed by the checkpoint.
+func (lc *ParentLightClient) committedHeader(ctx context.Context, snap *Checkpoint) (*types.BlockHeader, error) {
+	if len(snap.BlockCids) == 0 {
+		return nil, xerrors.Errorf("checkpoint at height %d doesn't commit any block", snap.Height)
+	}
+	b, err := (&verifiedBlockstore{node: lc.node}).Get(ctx, snap.BlockCids[0])
+	if err != nil {
+		return nil, err
+	}
+	header, err := types.DecodeBlock(b.RawData())
+	if err != nil {
+		return nil, xerrors.Errorf("error decoding block header: %w", err)
+	}
+	if header.Height != snap.Height-1 {
+		return nil, xerrors.Errorf("checkpoint at height %d commits block at height %d", snap.Height, header.Height)
+	}
+	return header, nil
+}
+
+// ReadActorState reads the state of an actor of the parent at the state root of the last block committed
+// by the latest verified checkpoint.
+func (lc *ParentLightClient) ReadActorState(ctx context.Context, actor address.Address, out cbg.CBORUnmarshaler) error {
+	_, err := lc.readActorState(ctx, actor, out)
+	return err
+}
+
+func (lc *ParentLightClient) readActorState(ctx context.Context, actor address.Address, out cbg.CBORUnmarshaler) (*state.StateTree, error) {
+	root := lc.TrustedHeader().ParentStateRoot
+	cst := cbor.NewCborStore(&verifiedBlockstore{node: lc.node})
+	st, err := state.LoadStateTree(cst, root)
+	if err != nil {
+		return nil, xerrors.Errorf("error loading parent state tree: %w", err)
+	}
+	act, err := st.GetActor(actor)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting actor %s: %w", actor, err)
+	}
+	if err := cst.Get(ctx, act.Head, out); err != nil {
+		return nil, xerrors.Errorf("error getting state of actor %s: %w", actor, err)
+	}
+	return st, nil
+}
+
+// SubnetValidatorSet returns the validator set of the subnet in its subnet actor in the parent,
+// with the validators identified by their key addresses.
+func (lc *ParentLightClient) SubnetValidatorSet(ctx context.Context, sn sdk.SubnetID) (*validator.Set, error) {
+	var sa subnetactor.State
+	st, err := lc.readActorState(ctx, sn.Actor(), &sa)
+	if err != nil {
+		return nil, err
+	}
+	// Resolve on-chain IDs of validators to f1/f3 addresses
+	cst := cbor.NewCborStore(&verifiedBlockstore{node: lc.node})
+	for i, v := range sa.ValidatorSet.Validators {
+		sa.ValidatorSet.Validators[i].Addr, err = vm.ResolveToDeterministicAddr(st, cst, v.Addr)
+		if err != nil {
+			return nil, xerrors.Errorf("error resolving validator address %s: %w", v.Addr, err)
+		}
+	}
+	return validator.NewValidatorSet(sa.ValidatorSet.ConfigurationNumber, sa.ValidatorSet.Validators), nil
+}
+
+// verifiedBlockstore reads IPLD blocks from a node that isn't trusted.
+// Blocks are only returned if their data hashes to their CID.
+type verifiedBlockstore struct {
+	node ParentNode
+}
+
+var _ cbor.IpldBlockstore = (*verifiedBlockstore)(nil)
+
+func (bs *verifiedBlockstore) Get(ctx context.Context, c cid.Cid) (block.Block, error) {
+	b, err := bs.node.ChainReadObj(ctx, c)
+	if err != nil {
+		return nil, xerrors.Errorf("error reading %s: %w", c, err)
+	}
+	chk, err := c.Prefix().Sum(b)
+	if err != nil {
+		return nil, err
+	}
+	if !chk.Equals(c) {
+		return nil, xerrors.Errorf("data of %s doesn't match its cid", c)
+	}
+	return block.NewBlockWithCid(b, c)
+}
+
+func (bs *verifiedBlockstore) Put(context.Context, block.Block) error {
+	return xerrors.New("the parent state is read-only")
+}
+
+// VerifiedMembership is a membership reader whose validator sets are checked against the subnet actor
+// in the parent chain, read through the parent light client, e.g. to avoid trusting the IPC agent.
+type VerifiedMembership struct {
+	reader membership.Reader
+	lc     *ParentLightClient
+	subnet sdk.SubnetID
+}
+
+var _ membership.Reader = &VerifiedMembership{}
+
+func NewVerifiedMembership(reader membership.Reader, lc *ParentLightClient, subnet sdk.SubnetID) *VerifiedMembership {
+	return &VerifiedMembership{
+		reader: reader,
+		lc:     lc,
+		subnet: subnet,
+	}
+}
+
+// GetMembershipInfo returns the membership of the reader if its validator set is the one in the parent.
+func (m *VerifiedMembership) GetMembershipInfo() (*membership.Info, error) {
+	info, err := m.reader.GetMembershipInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if err := m.lc.Sync(ctx); err != nil {
+		return nil, xerrors.Errorf("error syncing parent light client: %w", err)
+	}
+	parentSet, err := m.lc.SubnetValidatorSet(ctx, m.subnet)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting validator set from the parent: %w", err)
+	}
+	if err := membership.NormalizeValidatorSet(parentSet); err != nil {
+		return nil, xerrors.Errorf("invalid validator set in the parent: %w", err)
+	}
+
+	got, err := ValidatorSetHash(info.ValidatorSet)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := ValidatorSetHash(parentSet)
+	if err != nil {
+		return nil, err
+	}
+	if got != exp {
+		return nil, xerrors.Errorf("validator set %v doesn't match the validator set %v of the subnet actor at parent height %d",
+			info.ValidatorSet, parentSet, m.lc.TrustedHeader().Height)
+	}
+	return info, nil
+}
@@ -0,0 +1,41 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api/mocks"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestVerifiedBlockstore(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	node := mocks.NewMockFullNode(ctrl)
+	bs := &verifiedBlockstore{node: node}
+
+	h := mock.MkBlock(nil, 1, 1)
+	b, err := h.Serialize()
+	require.NoError(t, err)
+
+	node.EXPECT().ChainReadObj(gomock.Any(), h.Cid()).Return(b, nil)
+	blk, err := bs.Get(ctx, h.Cid())
+	require.NoError(t, err)
+	got, err := types.DecodeBlock(blk.RawData())
+	require.NoError(t, err)
+	require.Equal(t, h.Cid(), got.Cid())
+
+	// A node serving another block for the CID is detected.
+	other, err := mock.MkBlock(nil, 2, 1).Serialize()
+	require.NoError(t, err)
+	node.EXPECT().ChainReadObj(gomock.Any(), h.Cid()).Return(other, nil)
+	_, err = bs.Get(ctx, h.Cid())
+	require.Error(t, err)
+
+	require.Error(t, bs.Put(ctx, blk))
+}
@@ -0,0 +1,99 @@
+package encrypted
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxPoolSize is the maximum number of encrypted transactions kept by the pool.
+	DefaultMaxPoolSize = 4096
+	// DefaultTxTTL is the time an encrypted transaction is kept in the pool.
+	DefaultTxTTL = 5 * time.Minute
+)
+
+type entry struct {
+	tx    []byte
+	added time.Time
+}
+
+// Pool keeps the encrypted transactions (ciphertexts and key reveals) submitted
+// by clients to the node, so the validator can propose them.
+//
+// Ciphertexts and keys are not included in blocks, so the node can't tell when a
+// transaction has been ordered. Transactions are kept in the pool until they expire,
+// and the validator, which sees the ordered transactions, is in charge of not
+// proposing them again once they have been delivered.
+type Pool struct {
+	lk      sync.Mutex
+	txs     []entry
+	seen    map[[sha256.Size]byte]struct{}
+	maxSize int
+	ttl     time.Duration
+}
+
+func New() *Pool {
+	return &Pool{
+		seen:    make(map[[sha256.Size]byte]struct{}),
+		maxSize: DefaultMaxPoolSize,
+		ttl:     DefaultTxTTL,
+	}
+}
+
+// Add adds a transaction to the pool. Transactions that are already in the pool are ignored.
+func (p *Pool) Add(tx []byte) error {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	p.prune()
+
+	h := sha256.Sum256(tx)
+	if _, found := p.seen[h]; found {
+		return nil
+	}
+	if len(p.txs) >= p.maxSize {
+		return fmt.Errorf("encrypted pool is full")
+	}
+	p.seen[h] = struct{}{}
+	p.txs = append(p.txs, entry{tx: tx, added: time.Now()})
+	return nil
+}
+
+// Pending returns up to max transactions in the order they were added, without removing them.
+// If max is not positive, all the transactions are returned.
+func (p *Pool) Pending(max int) [][]byte {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	p.prune()
+
+	if max <= 0 || max > len(p.txs) {
+		max = len(p.txs)
+	}
+	txs := make([][]byte, max)
+	for i := range txs {
+		txs[i] = p.txs[i].tx
+	}
+	return txs
+}
+
+// Len returns the number of transactions in the pool.
+func (p *Pool) Len() int {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	return len(p.txs)
+}
+
+// prune removes the expired transactions. The caller must hold the lock.
+func (p *Pool) prune() {
+	n := 0
+	for n < len(p.txs) && time.Since(p.txs[n].added) > p.ttl {
+		delete(p.seen, sha256.Sum256(p.txs[n].tx))
+		n++
+	}
+	if n > 0 {
+		p.txs = append([]entry{}, p.txs[n:]...)
+	}
+}
@@ -0,0 +1,45 @@
+package encrypted
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedPool(t *testing.T) {
+	p := New()
+
+	require.NoError(t, p.Add([]byte("tx1")))
+	require.NoError(t, p.Add([]byte("tx2")))
+	require.NoError(t, p.Add([]byte("tx1")))
+	require.NoError(t, p.Add([]byte("tx3")))
+	require.Equal(t, 3, p.Len())
+
+	// Pending transactions are not removed from the pool.
+	txs := p.Pending(2)
+	require.Equal(t, [][]byte{[]byte("tx1"), []byte("tx2")}, txs)
+	require.Equal(t, 3, p.Len())
+
+	txs = p.Pending(0)
+	require.Equal(t, [][]byte{[]byte("tx1"), []byte("tx2"), []byte("tx3")}, txs)
+
+	p.maxSize = 3
+	require.Error(t, p.Add([]byte("tx4")))
+}
+
+func TestEncryptedPoolExpiration(t *testing.T) {
+	p := New()
+	p.ttl = time.Hour
+
+	require.NoError(t, p.Add([]byte("tx1")))
+	require.NoError(t, p.Add([]byte("tx2")))
+	p.txs[0].added = time.Now().Add(-2 * time.Hour)
+
+	require.Equal(t, [][]byte{[]byte("tx2")}, p.Pending(0))
+	require.Equal(t, 1, p.Len())
+
+	// An expired transaction can be added again.
+	require.NoError(t, p.Add([]byte("tx1")))
+	require.Equal(t, [][]byte{[]byte("tx2"), []byte("tx1")}, p.Pending(0))
+}
@@ -0,0 +1,83 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+const (
+	// DefaultRequestTimeout is the maximum time to get the messages of a batch from the feed.
+	DefaultRequestTimeout = 2 * time.Second
+	// MaxResponseSize is the maximum size of a response of the feed.
+	MaxResponseSize = 32 << 20
+)
+
+// HTTPSource pulls signed messages from an external feed over HTTP.
+//
+// For every batch, the source sends a GET request to the URL of the feed with the maximum number
+// of messages in the "max" query parameter, authenticated with a bearer token. The feed responds
+// with a JSON array of signed messages.
+type HTTPSource struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewHTTPSource(feedURL, token string) (*HTTPSource, error) {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed URL %s: %w", feedURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported feed URL scheme: %s", u.Scheme)
+	}
+	return &HTTPSource{
+		url:    feedURL,
+		token:  token,
+		client: &http.Client{Timeout: DefaultRequestTimeout},
+	}, nil
+}
+
+func (s *HTTPSource) Name() string {
+	return s.url
+}
+
+func (s *HTTPSource) Select(ctx context.Context, max int) ([]*types.SignedMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	q.Set("max", strconv.Itoa(max))
+	req.URL.RawQuery = q.Encode()
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed responded with status %s", resp.Status)
+	}
+
+	var msgs []*types.SignedMessage
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxResponseSize)).Decode(&msgs); err != nil {
+		return nil, fmt.Errorf("failed to decode messages from feed: %w", err)
+	}
+	if len(msgs) > max {
+		msgs = msgs[:max]
+	}
+	return msgs, nil
+}
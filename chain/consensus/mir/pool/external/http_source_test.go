@@ -0,0 +1,50 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestHTTPSource(t *testing.T) {
+	from, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	var msgs []*types.SignedMessage
+	for i := uint64(0); i < 3; i++ {
+		msgs = append(msgs, &types.SignedMessage{Message: types.Message{From: from, To: from, Nonce: i}})
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "2", r.URL.Query().Get("max"))
+		// The feed may return more messages than requested.
+		require.NoError(t, json.NewEncoder(w).Encode(msgs))
+	}))
+	defer srv.Close()
+
+	s, err := NewHTTPSource(srv.URL, "secret")
+	require.NoError(t, err)
+	got, err := s.Select(context.Background(), 2)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, uint64(1), got[1].Message.Nonce)
+
+	s, err = NewHTTPSource(srv.URL, "wrong")
+	require.NoError(t, err)
+	_, err = s.Select(context.Background(), 2)
+	require.Error(t, err)
+
+	_, err = NewHTTPSource("ftp://feed", "")
+	require.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package fifo
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/ipfs/go-cid"
@@ -78,3 +79,33 @@ func (p *Pool) Purge() {
 	p.orderingClients = make(map[string]bool)
 	p.seen = make(map[string]uint64)
 }
+
+// InFlightTx is a transaction of the pool being ordered.
+type InFlightTx struct {
+	Cid      cid.Cid
+	ClientID string
+	// Nonce is the last nonce seen for the client.
+	Nonce uint64
+}
+
+// Snapshot is the state of the pool at some point in time.
+type Snapshot struct {
+	InFlight []InFlightTx
+	// Seen maps the clients to the last nonce seen for them.
+	Seen map[string]uint64
+}
+
+// Snapshot returns a copy of the state of the pool, with the transactions being ordered sorted by client.
+func (p *Pool) Snapshot() Snapshot {
+	p.lk.RLock()
+	defer p.lk.RUnlock()
+	s := Snapshot{Seen: make(map[string]uint64, len(p.seen))}
+	for c, clientID := range p.clientByCID {
+		s.InFlight = append(s.InFlight, InFlightTx{Cid: c, ClientID: clientID, Nonce: p.seen[clientID]})
+	}
+	sort.Slice(s.InFlight, func(i, j int) bool { return s.InFlight[i].ClientID < s.InFlight[j].ClientID })
+	for clientID, nonce := range p.seen {
+		s.Seen[clientID] = nonce
+	}
+	return s
+}
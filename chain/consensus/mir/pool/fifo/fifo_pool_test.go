@@ -32,3 +32,21 @@ func TestMirFIFOPool(t *testing.T) {
 	inProgress = p.DeleteTx(c2, 0)
 	require.Equal(t, false, inProgress)
 }
+
+func TestMirFIFOPoolSnapshot(t *testing.T) {
+	p := New()
+
+	c1 := cid.NewCidV0(u.Hash([]byte("req1")))
+	c2 := cid.NewCidV0(u.Hash([]byte("req2")))
+	p.AddTx(c1, &mirproto.Transaction{ClientId: "client2", TxNo: 3, Data: []byte{}})
+	p.AddTx(c2, &mirproto.Transaction{ClientId: "client1", TxNo: 5, Data: []byte{}})
+
+	s := p.Snapshot()
+	require.Equal(t, []InFlightTx{{Cid: c2, ClientID: "client1", Nonce: 5}, {Cid: c1, ClientID: "client2", Nonce: 3}}, s.InFlight)
+	require.Equal(t, map[string]uint64{"client1": 5, "client2": 3}, s.Seen)
+
+	// The snapshot isn't modified by the pool.
+	p.DeleteTx(c1, 3)
+	require.Len(t, s.InFlight, 2)
+	require.Len(t, p.Snapshot().InFlight, 1)
+}
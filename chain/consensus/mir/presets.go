@@ -0,0 +1,99 @@
+package mir
+
+import (
+	"time"
+
+	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
+
+	mirlibp2p "github.com/filecoin-project/mir/pkg/net/libp2p"
+	"github.com/filecoin-project/mir/pkg/trantor"
+)
+
+// LargeMembershipSize is the number of validators from which the parameters of the validator are scaled
+// to the size of the membership. The defaults of Trantor and of its transport are tuned for a few dozen
+// validators at most: every validator is connected to every other one, and the messages resent
+// periodically, e.g. checkpoints, are sent to all of them.
+const LargeMembershipSize = 32
+
+// DefaultMaxValidators is the default size of the largest membership the libp2p host of a validator
+// is sized for.
+const DefaultMaxValidators = 256
+
+// minMsgBufPerValidator is the minimum capacity of the buffer of the messages received ahead of time,
+// per validator. Trantor splits MsgBufCapacity among the validators.
+const minMsgBufPerValidator = 1 << 20
+
+// membershipScale is the factor by which the periods and timeouts that grow with the number of
+// validators are scaled for a membership of n validators.
+func membershipScale(n int) time.Duration {
+	if n < LargeMembershipSize {
+		return 1
+	}
+	return time.Duration((n + LargeMembershipSize - 1) / LargeMembershipSize)
+}
+
+// ScaleParams adjusts the local parameters of Trantor to a membership of n validators: the periods
+// at which messages are resent to all the validators and the view change timeouts grow with the
+// size of the membership, so large memberships don't flood the network nor change views while a
+// quorum is still being gathered. The parameters that all the validators must agree on, e.g.
+// SegmentLength, are left untouched.
+func ScaleParams(params *trantor.Params, n int) {
+	f := membershipScale(n)
+	if f == 1 {
+		return
+	}
+	iss := params.Iss
+	iss.CheckpointResendPeriod *= f
+	iss.PBFTDoneResendPeriod *= f
+	iss.PBFTViewChangeResendPeriod *= f
+	iss.CatchUpTimerPeriod *= f
+	iss.PBFTViewChangeSNTimeout *= f
+	iss.PBFTViewChangeSegmentTimeout *= f
+	if c := n * minMsgBufPerValidator; iss.MsgBufCapacity < c {
+		iss.MsgBufCapacity = c
+	}
+	params.Net = TransportParams(n)
+}
+
+// TransportParams returns the parameters of the libp2p transport of a validator in a membership of n
+// validators. In large memberships, the connections to unreachable validators are retried less often,
+// so a validator that is down isn't dialed by all the others every second, and writes are given more
+// time, as every validator writes to all the others.
+func TransportParams(n int) mirlibp2p.Params {
+	p := mirlibp2p.DefaultParams()
+	f := membershipScale(n)
+	p.ReconnectionPeriod *= f
+	p.StreamWriteTimeout *= f
+	return p
+}
+
+// ValidatorHostLimits returns the resource limits of the libp2p host of a validator that can be in
+// memberships of up to n validators. Every validator holds a connection and a stream to every other
+// one, and they all dial it at once when an epoch starts with new validators. The default limits of
+// libp2p are only raised.
+func ValidatorHostLimits(n int) rcmgr.ConcreteLimitConfig {
+	defaults := rcmgr.DefaultLimits.AutoScale()
+	current := defaults.ToPartialLimitConfig()
+	changes := rcmgr.PartialLimitConfig{}
+
+	raise := func(cur rcmgr.LimitVal, v int) rcmgr.LimitVal {
+		if cur == rcmgr.Unlimited || cur >= rcmgr.LimitVal(v) {
+			return rcmgr.DefaultLimit
+		}
+		return rcmgr.LimitVal(v)
+	}
+	// Up to two connections per validator, e.g. when two validators dial each other at the same time.
+	changes.System.ConnsInbound = raise(current.System.ConnsInbound, 2*n)
+	changes.System.ConnsOutbound = raise(current.System.ConnsOutbound, 2*n)
+	changes.System.Conns = raise(current.System.Conns, 4*n)
+	changes.System.StreamsInbound = raise(current.System.StreamsInbound, 4*n)
+	changes.System.StreamsOutbound = raise(current.System.StreamsOutbound, 4*n)
+	changes.System.Streams = raise(current.System.Streams, 8*n)
+	changes.System.FD = raise(current.System.FD, 4*n)
+	changes.Transient.ConnsInbound = raise(current.Transient.ConnsInbound, n)
+	changes.Transient.Conns = raise(current.Transient.Conns, 2*n)
+	changes.ProtocolDefault.StreamsInbound = raise(current.ProtocolDefault.StreamsInbound, 2*n)
+	changes.ProtocolDefault.StreamsOutbound = raise(current.ProtocolDefault.StreamsOutbound, 2*n)
+	changes.ProtocolDefault.Streams = raise(current.ProtocolDefault.Streams, 4*n)
+	return changes.Build(defaults)
+}
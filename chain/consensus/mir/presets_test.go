@@ -0,0 +1,41 @@
+package mir
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	mirlibp2p "github.com/filecoin-project/mir/pkg/net/libp2p"
+	"github.com/filecoin-project/mir/pkg/trantor"
+	"github.com/filecoin-project/mir/pkg/types"
+)
+
+func TestScaleParams(t *testing.T) {
+	// Small memberships keep the parameters of Trantor.
+	params := trantor.DefaultParams(weightedMembership(map[types.NodeID]string{"id1": "1"}))
+	params.Iss.AdjustSpeed(time.Second)
+	small := *params.Iss
+	ScaleParams(&params, LargeMembershipSize-1)
+	require.Equal(t, small, *params.Iss)
+	require.Equal(t, mirlibp2p.DefaultParams(), params.Net)
+
+	params = trantor.DefaultParams(weightedMembership(map[types.NodeID]string{"id1": "1"}))
+	params.Iss.AdjustSpeed(time.Second)
+	ScaleParams(&params, 4*LargeMembershipSize)
+	require.Equal(t, 4*time.Second, params.Iss.CheckpointResendPeriod)
+	require.Equal(t, 16*time.Second, params.Iss.PBFTViewChangeSNTimeout)
+	require.Equal(t, small.SegmentLength, params.Iss.SegmentLength)
+	require.Equal(t, small.ConfigOffset, params.Iss.ConfigOffset)
+	require.Equal(t, 4*LargeMembershipSize*minMsgBufPerValidator, params.Iss.MsgBufCapacity)
+	require.Equal(t, 4*mirlibp2p.DefaultParams().ReconnectionPeriod, params.Net.ReconnectionPeriod)
+}
+
+func TestValidatorHostLimits(t *testing.T) {
+	limits := ValidatorHostLimits(1000).ToPartialLimitConfig()
+	require.GreaterOrEqual(t, int(limits.System.ConnsInbound), 2000)
+	require.GreaterOrEqual(t, int(limits.Transient.ConnsInbound), 1000)
+
+	// The limits are never lowered.
+	require.Equal(t, ValidatorHostLimits(0), ValidatorHostLimits(1))
+}
@@ -0,0 +1,37 @@
+package mir
+
+import (
+	"sort"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+)
+
+// BlockProvenance describes the ordered batch a block was created from.
+type BlockProvenance struct {
+	Height abi.ChainEpoch
+	Block  cid.Cid
+	// Batch is the ID of the batch used in the logs of the validators.
+	Batch string
+	// Proposers are the sorted IDs of the validators whose transactions are included in the batch.
+	// A validator only submits transactions to its own Mir node, so its transactions are ordered
+	// in the batches it proposes as the leader of a segment.
+	Proposers []string
+}
+
+func batchProposers(txs []*mirproto.Transaction) []string {
+	seen := make(map[string]struct{})
+	var ids []string
+	for _, tx := range txs {
+		id := string(tx.ClientId)
+		if _, found := seen[id]; found {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,20 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+)
+
+func TestBatchProposers(t *testing.T) {
+	require.Empty(t, batchProposers(nil))
+
+	txs := []*mirproto.Transaction{
+		{ClientId: "id2", TxNo: 1},
+		{ClientId: "id1", TxNo: 1},
+		{ClientId: "id2", TxNo: 2},
+	}
+	require.Equal(t, []string{"id1", "id2"}, batchProposers(txs))
+}
@@ -0,0 +1,53 @@
+package mir
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// DefaultReadyCheckpointLag is the number of blocks the head of a ready validator can be behind
+// the latest stable checkpoint.
+const DefaultReadyCheckpointLag = abi.ChainEpoch(10)
+
+// Readiness tells orchestrators, e.g. through a Kubernetes readiness probe, whether the validator
+// takes part in the agreement, so rollouts wait for it before moving on to the next validator.
+type Readiness struct {
+	Ready bool
+	// Reason why the validator is not ready.
+	Reason string
+}
+
+// Readiness returns the readiness of the validator: its Mir node is running, the validator is in
+// the membership of the epoch being ordered, and the head of its daemon is at most maxLag blocks
+// behind the latest stable checkpoint. A validator of a frozen subnet is ready, as no blocks are expected.
+func (m *Manager) Readiness(ctx context.Context, maxLag abi.ChainEpoch) *Readiness {
+	s := m.Status()
+	switch s.State {
+	case MirRunning, MirIdle:
+	default:
+		return &Readiness{Reason: fmt.Sprintf("mir node %s", s.State)}
+	}
+
+	member := false
+	for _, id := range s.Membership {
+		if id == m.id {
+			member = true
+			break
+		}
+	}
+	if !member {
+		return &Readiness{Reason: fmt.Sprintf("validator not in the membership of epoch %d", s.Epoch)}
+	}
+
+	head, err := m.stateManager.heads.Head(ctx)
+	if err != nil {
+		return &Readiness{Reason: fmt.Sprintf("error getting chain head: %s", err)}
+	}
+	if head.Height()+maxLag < s.CheckpointHeight {
+		return &Readiness{Reason: fmt.Sprintf("head %d more than %d blocks behind the checkpoint at height %d",
+			head.Height(), maxLag, s.CheckpointHeight)}
+	}
+	return &Readiness{Ready: true}
+}
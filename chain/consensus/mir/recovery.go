@@ -0,0 +1,133 @@
+package mir
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	t "github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+)
+
+// RecoveryReport describes the checkpoint a stopped validator was recovered to by RecoverFromCheckpoint.
+type RecoveryReport struct {
+	Epoch  uint64
+	Height abi.ChainEpoch
+	// Hash of the serialized checkpoint, which must be the same for all the validators restarting the subnet.
+	Hash string
+	// ValidatorSet is the membership of the epoch of the checkpoint.
+	ValidatorSet *validator.Set
+	// Number of pending configuration transactions of the validator that were cancelled.
+	CancelledConfigurationTxs int
+}
+
+// RecoverFromCheckpoint prepares the datastore of a stopped validator to restart a subnet that lost its
+// liveness from the serialized checkpoint b, which all the validators of the subnet restart from:
+// the checkpoint becomes the latest checkpoint of the validator, and the configuration requests and
+// votes of the run being abandoned are dropped, as the votes of the checkpoint are restored instead.
+//
+// The configuration transactions are cancelled rather than deleted, so their numbers are not reused.
+func RecoverFromCheckpoint(ctx context.Context, ds db.DB, id string, b []byte) (*RecoveryReport, error) {
+	ch, err := DeserializeCheckpoint(b)
+	if err != nil {
+		return nil, xerrors.Errorf("error deserializing checkpoint: %w", err)
+	}
+	snap, err := UnwrapCheckpointSnapshot(ch)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting checkpoint snapshot: %w", err)
+	}
+	mbs := ch.Memberships()
+	if len(mbs) == 0 {
+		return nil, xerrors.Errorf("checkpoint at height %d without membership", snap.Height)
+	}
+	configNumber := snap.NextConfigNumber
+	if configNumber > 0 {
+		configNumber--
+	}
+	set, err := validatorSetFromMembership(configNumber, mbs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ds.Put(ctx, LatestCheckpointKey, ch.Snapshot.AppData); err != nil {
+		return nil, xerrors.Errorf("error persisting latest checkpoint: %w", err)
+	}
+	if err := ds.Put(ctx, LatestCheckpointPbKey, b); err != nil {
+		return nil, xerrors.Errorf("error persisting latest checkpoint: %w", err)
+	}
+	if err := ds.Put(ctx, HeightCheckIndexKey(snap.Height), b); err != nil {
+		return nil, xerrors.Errorf("error persisting checkpoint at height %d: %w", snap.Height, err)
+	}
+
+	cm, err := NewConfigurationManager(ctx, ds, id)
+	if err != nil {
+		return nil, xerrors.Errorf("error recovering configuration manager: %w", err)
+	}
+	reqs, err := cm.PendingRequests()
+	if err != nil {
+		return nil, err
+	}
+	cancelled := 0
+	for _, r := range reqs {
+		if r.Cancelled {
+			continue
+		}
+		if err := cm.CancelPending(r.TxNo); err != nil {
+			return nil, xerrors.Errorf("error cancelling configuration tx %d: %w", r.TxNo, err)
+		}
+		cancelled++
+	}
+	for _, k := range []datastore.Key{ConfigurationVotesKey, LastValidatorSetKey} {
+		if err := ds.Delete(ctx, k); err != nil && !errors.Is(err, datastore.ErrNotFound) {
+			return nil, xerrors.Errorf("error deleting %s: %w", k, err)
+		}
+	}
+
+	h := sha256.Sum256(b)
+	return &RecoveryReport{
+		Epoch:                     uint64(ch.Snapshot.EpochData.EpochConfig.EpochNr),
+		Height:                    snap.Height,
+		Hash:                      hex.EncodeToString(h[:]),
+		ValidatorSet:              set,
+		CancelledConfigurationTxs: cancelled,
+	}, nil
+}
+
+// validatorSetFromMembership returns the validator set of a Mir membership, sorted by validator ID.
+func validatorSetFromMembership(n uint64, mb *mirproto.Membership) (*validator.Set, error) {
+	ids := make([]string, 0, len(mb.Nodes))
+	for id := range mb.Nodes {
+		ids = append(ids, id.Pb())
+	}
+	sort.Strings(ids)
+
+	vals := make([]*validator.Validator, 0, len(ids))
+	for _, id := range ids {
+		node := mb.Nodes[t.NodeID(id)]
+		s := fmt.Sprintf("%s@%s", id, node.Addr)
+		if node.Weight != "" {
+			s = fmt.Sprintf("%s:%s@%s", id, node.Weight, node.Addr)
+		}
+		v, err := validator.NewValidatorFromString(s)
+		if err != nil {
+			return nil, xerrors.Errorf("error creating validator %s: %w", id, err)
+		}
+		vals = append(vals, v)
+	}
+	set := validator.NewValidatorSet(n, vals)
+	if err := membership.NormalizeValidatorSet(set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
@@ -0,0 +1,26 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+)
+
+func TestValidatorSetFromMembership(t *testing.T) {
+	set := testValidatorSet(t, 0,
+		"t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq:3@/ip4/127.0.0.1/tcp/10001/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ",
+		"t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ",
+	)
+	_, mb, err := membership.Membership(set.GetValidators())
+	require.NoError(t, err)
+
+	rebuilt, err := validatorSetFromMembership(4, mb)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), rebuilt.ConfigurationNumber)
+	require.Equal(t, 2, rebuilt.Size())
+	require.Equal(t, "t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq", rebuilt.Validators[0].ID())
+	require.Equal(t, "3", rebuilt.Validators[0].Weight.String())
+	require.Equal(t, "/ip4/127.0.0.1/tcp/10001/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ", rebuilt.Validators[0].NetAddr)
+}
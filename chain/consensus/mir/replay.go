@@ -0,0 +1,154 @@
+package mir
+
+import (
+	"context"
+	"crypto"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// ReplayReport summarizes the result of replaying a Mir chain.
+type ReplayReport struct {
+	Height      abi.ChainEpoch
+	Executed    int
+	Checkpoints int
+}
+
+// Replay re-executes the Mir chain ending at head, starting from genesis, and verifies that
+// the state root of every block matches the result of executing its parent, and that the
+// checkpoints included in the blocks form a valid chain of certified checkpoints.
+//
+// Replay doesn't need any connection to the network or to the validators: the blocks must
+// already be in the chainstore (e.g. imported from a chain export).
+func Replay(ctx context.Context, cs *store.ChainStore, sm *stmgr.StateManager, head *types.TipSet) (*ReplayReport, error) {
+	report := &ReplayReport{Height: head.Height()}
+
+	prev, err := cs.GetTipsetByHeight(ctx, 0, head, true)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting genesis: %w", err)
+	}
+	lastState := prev.ParentState()
+
+	v := checkpointChainVerifier{cs: cs, head: head, memberships: datastore.NewMapDatastore()}
+
+	for h := abi.ChainEpoch(1); h <= head.Height(); h++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		ts, err := cs.GetTipsetByHeight(ctx, h, head, true)
+		if err != nil {
+			return nil, xerrors.Errorf("error getting tipset at height %d: %w", h, err)
+		}
+		if ts.Height() != h {
+			return nil, xerrors.Errorf("missing block at height %d", h)
+		}
+		if ts.ParentState() != lastState {
+			return nil, xerrors.Errorf("state mismatch at height %d: block has %s, computed %s", h, ts.ParentState(), lastState)
+		}
+
+		for _, b := range ts.Blocks() {
+			if !hasCheckpoint(b) {
+				continue
+			}
+			if err := v.verify(ctx, b); err != nil {
+				return nil, xerrors.Errorf("invalid checkpoint in block at height %d: %w", h, err)
+			}
+			report.Checkpoints++
+		}
+
+		log.Debugf("replaying block at height %d", h)
+		lastState, err = sm.ExecutionTraceWithMonitor(ctx, ts, nil)
+		if err != nil {
+			return nil, xerrors.Errorf("error executing block at height %d: %w", h, err)
+		}
+		report.Executed++
+	}
+
+	return report, nil
+}
+
+// checkpointChainVerifier verifies that checkpoints are certified by the memberships certified by the
+// previous checkpoints, point to the previous checkpoint in the chain, and commit to the blocks actually
+// included in the chain.
+type checkpointChainVerifier struct {
+	cs   *store.ChainStore
+	head *types.TipSet
+	prev *Checkpoint
+	// Memberships of the epochs certified by the checkpoints verified so far.
+	memberships db.DB
+}
+
+func (v *checkpointChainVerifier) verify(ctx context.Context, h *types.BlockHeader) error {
+	ch, err := CheckpointFromVRFProof(h.Ticket)
+	if err != nil {
+		return err
+	}
+	cert, err := CertFromElectionProof(h.ElectionProof, ch.PreviousMembership())
+	if err != nil {
+		return err
+	}
+	ch = ch.AttachCert(cert)
+	// As in ValidateBlock, the membership included in the checkpoint is checked against the ones
+	// certified by the previous checkpoints before verifying the signatures.
+	if err := checkCheckpointMemberships(ctx, v.memberships, ch); err != nil {
+		return xerrors.Errorf("error verifying checkpoint membership: %w", err)
+	}
+	if err := ch.VerifyCert(crypto.SHA256, CheckpointVerifier{}, ch.PreviousMembership()); err != nil {
+		return xerrors.Errorf("error verifying checkpoint certificate: %w", err)
+	}
+
+	snap, err := UnwrapCheckpointSnapshot(ch)
+	if err != nil {
+		return xerrors.Errorf("error unwrapping checkpoint snapshot: %w", err)
+	}
+	if snap.Height > h.Height {
+		return xerrors.Errorf("checkpoint height %d is over the block height %d", snap.Height, h.Height)
+	}
+
+	if v.prev != nil {
+		c, err := v.prev.Cid()
+		if err != nil {
+			return xerrors.Errorf("error computing cid for previous checkpoint: %w", err)
+		}
+		if snap.Parent.Cid != c || snap.Parent.Height != v.prev.Height {
+			return xerrors.Errorf("checkpoint not pointing to the previous one: %s, %s", c, snap.Parent.Cid)
+		}
+	}
+
+	// Block CIDs are included in the snapshot in descending order of height.
+	for i, c := range snap.BlockCids {
+		height := snap.Height - 1 - abi.ChainEpoch(i)
+		ts, err := v.cs.GetTipsetByHeight(ctx, height, v.head, true)
+		if err != nil {
+			return xerrors.Errorf("error getting tipset at height %d: %w", height, err)
+		}
+		if !containsCid(ts.Cids(), c) {
+			return xerrors.Errorf("checkpoint commits to block %s at height %d not in the chain", c, height)
+		}
+	}
+
+	if err := putCheckpointMemberships(ctx, v.memberships, ch, snap.Height); err != nil {
+		return xerrors.Errorf("error storing checkpoint memberships: %w", err)
+	}
+	v.prev = snap
+	return nil
+}
+
+func containsCid(cids []cid.Cid, c cid.Cid) bool {
+	for _, x := range cids {
+		if x == c {
+			return true
+		}
+	}
+	return false
+}
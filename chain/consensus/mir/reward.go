@@ -0,0 +1,102 @@
+package mir
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/consensus-shipyard/go-ipc-types/gateway"
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/reward"
+	"github.com/filecoin-project/lotus/chain/consensus"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/vm"
+)
+
+// NewTipSetExecutor returns the executor of Mir tipsets, which rewards validators with RewardFunc.
+func NewTipSetExecutor() *consensus.TipSetExecutor {
+	return consensus.NewTipSetExecutorWithState(RewardFunc)
+}
+
+// RewardFunc pays the gas rewards of a block to the validator assigned to its epoch by blockMiner.
+// Mir blocks are not mined by a single validator, so the validators of the gateway membership
+// take turns to collect the rewards, which accrue to their account through an implicit transfer
+// from the reward actor. Without a membership, the rewards stay in the reward actor.
+func RewardFunc(ctx context.Context, sm *stmgr.StateManager, pstate cid.Cid, vmi vm.Interface, em stmgr.ExecMonitor,
+	epoch abi.ChainEpoch, ts *types.TipSet, params *reward.AwardBlockRewardParams) error {
+	if params.GasReward.IsZero() {
+		return nil
+	}
+
+	set, err := gatewayMembership(ctx, sm, pstate)
+	if err != nil {
+		return xerrors.Errorf("failed to get validators to reward: %w", err)
+	}
+	to := blockMiner(set, epoch)
+	if to == address.Undef {
+		return nil
+	}
+
+	rwMsg := &types.Message{
+		From:       reward.Address,
+		To:         to,
+		Nonce:      uint64(epoch),
+		Value:      params.GasReward,
+		GasFeeCap:  types.NewInt(0),
+		GasPremium: types.NewInt(0),
+		GasLimit:   1 << 30,
+		Method:     builtin.MethodSend,
+	}
+	ret, actErr := vmi.ApplyImplicitMessage(ctx, rwMsg)
+	if actErr != nil {
+		return xerrors.Errorf("failed to apply reward message: %w", actErr)
+	}
+	if em != nil {
+		if err := em.MessageApplied(ctx, ts, rwMsg.Cid(), rwMsg, ret, true); err != nil {
+			return xerrors.Errorf("callback failed on reward message: %w", err)
+		}
+	}
+
+	if ret.ExitCode != 0 {
+		return xerrors.Errorf("reward of %s failed (exit %d): %s", to, ret.ExitCode, ret.ActorErr)
+	}
+	return nil
+}
+
+// blockMiner returns the validator of the set that collects the rewards of the block at epoch.
+// Validators are assigned epochs in round-robin, in the order of the set, so every validator
+// is rewarded once every set.Size() epochs. It returns address.Undef for an empty set.
+func blockMiner(set *validator.Set, epoch abi.ChainEpoch) address.Address {
+	if set == nil || len(set.Validators) == 0 {
+		return address.Undef
+	}
+	n := abi.ChainEpoch(len(set.Validators))
+	return set.Validators[((epoch%n)+n)%n].Addr
+}
+
+// gatewayMembership returns the validator set stored in the gateway actor in state st.
+func gatewayMembership(ctx context.Context, sm *stmgr.StateManager, st cid.Cid) (*validator.Set, error) {
+	act, err := sm.LoadActorRaw(ctx, consensus.DefaultGatewayAddr, st)
+	if xerrors.Is(err, types.ErrActorNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("getting gateway actor: %w", err)
+	}
+	blk, err := sm.ChainStore().StateBlockstore().Get(ctx, act.Head)
+	if err != nil {
+		return nil, xerrors.Errorf("getting gateway actor head: %w", err)
+	}
+	var gst gateway.State
+	if err := gst.UnmarshalCBOR(bytes.NewReader(blk.RawData())); err != nil {
+		return nil, xerrors.Errorf("decoding gateway actor state: %w", err)
+	}
+	return &gst.Validators.Validators, nil
+}
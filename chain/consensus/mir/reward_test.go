@@ -0,0 +1,39 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestBlockMinerRoundRobin(t *testing.T) {
+	require.Equal(t, address.Undef, blockMiner(nil, 1))
+	require.Equal(t, address.Undef, blockMiner(validator.NewValidatorSet(0, nil), 1))
+
+	var vs []*validator.Validator
+	for _, s := range []string{
+		"t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ",
+		"t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq:1@/ip4/127.0.0.1/tcp/10001/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ",
+		"t1mkypjjgxbiuylzqmeudeootz7re5w4ub7npgzby:1@/ip4/127.0.0.1/tcp/10002/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ",
+	} {
+		v, err := validator.NewValidatorFromString(s)
+		require.NoError(t, err)
+		vs = append(vs, v)
+	}
+	set := validator.NewValidatorSet(0, vs)
+
+	rewarded := make(map[address.Address]int)
+	for h := abi.ChainEpoch(0); h < 30; h++ {
+		m := blockMiner(set, h)
+		require.Equal(t, set.Validators[h%3].Addr, m)
+		rewarded[m]++
+	}
+	require.Len(t, rewarded, 3)
+	for _, n := range rewarded {
+		require.Equal(t, 10, n)
+	}
+}
@@ -0,0 +1,55 @@
+package mir
+
+import (
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	trantor "github.com/filecoin-project/mir/pkg/trantor/types"
+)
+
+// A re-weighting is a configuration that keeps the validators and their addresses and only changes
+// their weights, e.g. to track the collateral of the validators of stake-proportional subnets.
+// It is voted for and applied like any other configuration: the votes are weighted by the weights of
+// the membership of the current epoch, so the new weights only count once the new membership is in
+// use, ConfigOffset epochs after the quorum is reached.
+
+// isReweighting returns whether next has the same validators as cur with different weights.
+func isReweighting(cur, next *mirproto.Membership) bool {
+	if cur == nil || next == nil || len(cur.Nodes) != len(next.Nodes) {
+		return false
+	}
+	changed := false
+	for id, n := range next.Nodes {
+		c, ok := cur.Nodes[id]
+		if !ok || c.Addr != n.Addr {
+			return false
+		}
+		if c.Weight != n.Weight {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// validatorSetChanged returns whether next is a new validator set with respect to last,
+// including sets that only change the weights of the validators.
+func validatorSetChanged(last, next *validator.Set) bool {
+	if last == nil || !last.Equal(next) {
+		return true
+	}
+	weights := make(map[string]string, last.Size())
+	for _, v := range last.GetValidators() {
+		weights[v.ID()] = v.Weight.String()
+	}
+	for _, v := range next.GetValidators() {
+		if w, ok := weights[v.ID()]; !ok || w != v.Weight.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// membershipEffectiveEpoch returns the first epoch using the membership agreed upon in the current epoch.
+func (sm *StateManager) membershipEffectiveEpoch() trantor.EpochNr {
+	return sm.currentEpoch + trantor.EpochNr(sm.configOffset) + 2
+}
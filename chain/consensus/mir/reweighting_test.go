@@ -0,0 +1,63 @@
+package mir
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/stretchr/testify/require"
+
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	tt "github.com/filecoin-project/mir/pkg/trantor/types"
+	"github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+)
+
+func testValidatorSet(t *testing.T, n uint64, validators ...string) *validator.Set {
+	var vs []*validator.Validator
+	for _, s := range validators {
+		v, err := validator.NewValidatorFromString(s)
+		require.NoError(t, err)
+		vs = append(vs, v)
+	}
+	return validator.NewValidatorSet(n, vs)
+}
+
+func TestReweighting(t *testing.T) {
+	const (
+		v1 = "t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:%s@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ"
+		v2 = "t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq:%s@/ip4/127.0.0.1/tcp/10001/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ"
+	)
+	weighted := func(n uint64, w1, w2 string) *validator.Set {
+		return testValidatorSet(t, n, fmt.Sprintf(v1, w1), fmt.Sprintf(v2, w2))
+	}
+	mb := func(set *validator.Set) *mirproto.Membership {
+		_, m, err := membership.Membership(set.GetValidators())
+		require.NoError(t, err)
+		return m
+	}
+
+	cur := weighted(1, "10", "10")
+	require.False(t, validatorSetChanged(cur, weighted(1, "10", "10")))
+	require.False(t, isReweighting(mb(cur), mb(weighted(1, "10", "10"))))
+
+	// Changing only the weights is a new configuration.
+	next := weighted(2, "10", "30")
+	require.True(t, validatorSetChanged(cur, next))
+	require.True(t, validatorSetChanged(cur, weighted(1, "10", "30")))
+	require.True(t, isReweighting(mb(cur), mb(next)))
+
+	// Adding or removing validators isn't a re-weighting.
+	require.True(t, validatorSetChanged(cur, testValidatorSet(t, 2, fmt.Sprintf(v1, "10"))))
+	require.False(t, isReweighting(mb(cur), mb(testValidatorSet(t, 2, fmt.Sprintf(v1, "30")))))
+
+	// Votes keep the weights of the current membership until the new one is in use.
+	weights, total := voteWeights(mb(cur))
+	require.False(t, hasWeightQuorum(votedWeight(weights, voters(types.NodeID(cur.GetValidators()[1].ID()))), total))
+	weights, total = voteWeights(mb(next))
+	require.True(t, hasWeightQuorum(votedWeight(weights, voters(types.NodeID(cur.GetValidators()[1].ID()))), total))
+
+	sm := &StateManager{currentEpoch: 5, configOffset: 2}
+	require.Equal(t, tt.EpochNr(9), sm.membershipEffectiveEpoch())
+}
@@ -0,0 +1,176 @@
+package mir
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/ipfs/go-datastore"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	trantor "github.com/filecoin-project/mir/pkg/trantor/types"
+	t "github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// FreezeHeightKey is used to store the height at which the subnet was frozen by a shutdown request.
+var FreezeHeightKey = datastore.NewKey("mir/freeze-height")
+
+// A subnet is shut down when a strong quorum of the validators vote for freezing it at the same height,
+// by sending shutdown transactions through the same path as configuration transactions. Once the votes
+// are ordered, every validator refuses to create blocks above the freeze height, so the first checkpoint
+// delivered after the freeze is the final checkpoint of the subnet, which can be exported and submitted
+// to the parent to release the remaining funds.
+//
+// The freeze height and the votes without a quorum yet are part of the checkpoints, so validators that
+// restore their state from a checkpoint keep them, and daemons reject the blocks above the freeze height
+// once they verify a checkpoint after the quorum.
+
+func encodeShutdownRequest(h abi.ChainEpoch) ([]byte, error) {
+	var b bytes.Buffer
+	if err := cbg.CborInt(h).MarshalCBOR(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func decodeShutdownRequest(b []byte) (abi.ChainEpoch, error) {
+	var h cbg.CborInt
+	if err := h.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return 0, xerrors.Errorf("invalid shutdown request: %w", err)
+	}
+	if h <= 0 {
+		return 0, xerrors.Errorf("invalid shutdown request: freeze height %d", h)
+	}
+	return abi.ChainEpoch(h), nil
+}
+
+// frozen returns true if the subnet was frozen below the next block.
+func (sm *StateManager) frozen() bool {
+	h := sm.FreezeHeight()
+	return h > 0 && sm.height >= h
+}
+
+// FreezeHeight returns the height at which the subnet was frozen, or zero if it wasn't.
+func (sm *StateManager) FreezeHeight() abi.ChainEpoch {
+	return abi.ChainEpoch(sm.freezeHeight.Load())
+}
+
+func (sm *StateManager) applyShutdownTx(tx *mirproto.Transaction) error {
+	if tx.ClientId == trantor.ClientID(sm.id) {
+		if err := sm.confManager.Done(tx.TxNo); err != nil {
+			log.With("validator", sm.id).Errorf("failed to mark shutdown tx as done: %v", err)
+		}
+	}
+	if isCancelledConfigurationTx(tx) {
+		return nil
+	}
+
+	h, err := decodeShutdownRequest(tx.Data)
+	if err != nil {
+		log.With("validator", sm.id).Errorf("validator %s sent %v", tx.ClientId, err)
+		return nil
+	}
+	voter := t.NodeID(tx.ClientId)
+	if _, found := sm.memberships[sm.currentEpoch].Nodes[voter]; !found {
+		log.With("validator", sm.id).Errorf("shutdown vote from validator %s, which is not in the membership", voter)
+		return nil
+	}
+	if fh := sm.FreezeHeight(); fh > 0 {
+		log.With("validator", sm.id).Infof("shutdown vote from %s ignored: subnet already frozen at %d", voter, fh)
+		return nil
+	}
+	if h <= sm.height {
+		log.With("validator", sm.id).Warnf("shutdown vote from %s ignored: freeze height %d already reached", voter, h)
+		return nil
+	}
+
+	if sm.shutdownVotes[h] == nil {
+		sm.shutdownVotes[h] = make(map[t.NodeID]struct{})
+	}
+	sm.shutdownVotes[h][voter] = struct{}{}
+	votes, nodes := len(sm.shutdownVotes[h]), len(sm.memberships[sm.currentEpoch].Nodes)
+	log.With("validator", sm.id).Warnf("shutdown vote from %s for height %d: votes %d, nodes %d", voter, h, votes, nodes)
+	if votes < strongQuorum(nodes) {
+		return nil
+	}
+
+	sm.shutdownVotes = make(map[abi.ChainEpoch]map[t.NodeID]struct{})
+	if err := sm.setFreezeHeight(h); err != nil {
+		return err
+	}
+	log.With("validator", sm.id).Warnf("subnet frozen: no blocks will be created above height %d", h)
+	return nil
+}
+
+// setFreezeHeight persists the freeze height, zero if the subnet isn't frozen.
+func (sm *StateManager) setFreezeHeight(h abi.ChainEpoch) error {
+	var err error
+	if h == 0 {
+		err = sm.ds.Delete(sm.ctx, FreezeHeightKey)
+	} else {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(h))
+		err = sm.ds.Put(sm.ctx, FreezeHeightKey, b)
+	}
+	if err != nil {
+		return xerrors.Errorf("validator %v failed to persist freeze height: %w", sm.id, err)
+	}
+	sm.freezeHeight.Store(int64(h))
+	return nil
+}
+
+// shutdownVoteRecords returns the shutdown votes, sorted by height and validator.
+func shutdownVoteRecords(votes map[abi.ChainEpoch]map[t.NodeID]struct{}) []ShutdownVote {
+	var records []ShutdownVote
+	for h, voters := range votes {
+		for v := range voters {
+			records = append(records, ShutdownVote{Height: h, Validator: v.Pb()})
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Height != records[j].Height {
+			return records[i].Height < records[j].Height
+		}
+		return records[i].Validator < records[j].Validator
+	})
+	return records
+}
+
+// shutdownVotesFromRecords returns the shutdown votes recorded in a checkpoint.
+func shutdownVotesFromRecords(records []ShutdownVote) map[abi.ChainEpoch]map[t.NodeID]struct{} {
+	votes := make(map[abi.ChainEpoch]map[t.NodeID]struct{})
+	for _, r := range records {
+		if votes[r.Height] == nil {
+			votes[r.Height] = make(map[t.NodeID]struct{})
+		}
+		votes[r.Height][t.NodeID(r.Validator)] = struct{}{}
+	}
+	return votes
+}
+
+// checkFreezeHeight checks that a block is not above the freeze height of the subnet, if it is frozen.
+func checkFreezeHeight(h *types.BlockHeader, freezeHeight abi.ChainEpoch) error {
+	if freezeHeight > 0 && h.Height > freezeHeight {
+		return xerrors.Errorf("block at height %d above the freeze height %d of the subnet", h.Height, freezeHeight)
+	}
+	return nil
+}
+
+func (sm *StateManager) loadFreezeHeight() (abi.ChainEpoch, error) {
+	b, err := sm.ds.Get(sm.ctx, FreezeHeightKey)
+	if xerrors.Is(err, datastore.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 8 {
+		return 0, xerrors.Errorf("invalid freeze height")
+	}
+	return abi.ChainEpoch(binary.BigEndian.Uint64(b)), nil
+}
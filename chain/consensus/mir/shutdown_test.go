@@ -0,0 +1,101 @@
+package mir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	u "github.com/ipfs/go-ipfs-util"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	trantor "github.com/filecoin-project/mir/pkg/trantor/types"
+	mirtypes "github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestShutdownRequestEncoding(t *testing.T) {
+	b, err := encodeShutdownRequest(42)
+	require.NoError(t, err)
+	h, err := decodeShutdownRequest(b)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(42), h)
+
+	b, err = encodeShutdownRequest(0)
+	require.NoError(t, err)
+	_, err = decodeShutdownRequest(b)
+	require.Error(t, err)
+}
+
+func TestApplyShutdownTx(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+	cm, err := NewConfigurationManager(ctx, ds, "id1")
+	require.NoError(t, err)
+
+	nodes := map[mirtypes.NodeID]*mirproto.NodeIdentity{"id1": {}, "id2": {}, "id3": {}, "id4": {}}
+	sm := &StateManager{
+		ctx:           ctx,
+		id:            "id1",
+		ds:            ds,
+		confManager:   cm,
+		height:        10,
+		memberships:   map[trantor.EpochNr]*mirproto.Membership{0: {Nodes: nodes}},
+		shutdownVotes: make(map[abi.ChainEpoch]map[mirtypes.NodeID]struct{}),
+	}
+
+	vote := func(from string, h abi.ChainEpoch) {
+		data, err := encodeShutdownRequest(h)
+		require.NoError(t, err)
+		tx := &mirproto.Transaction{ClientId: trantor.ClientID(from), Type: ShutdownTransaction, Data: data}
+		require.NoError(t, sm.applyShutdownTx(tx))
+	}
+
+	// Votes for a height already reached and from validators out of the membership are ignored.
+	vote("id2", 5)
+	vote("id5", 20)
+	vote("id2", 20)
+	vote("id3", 20)
+	vote("id3", 20)
+	require.Equal(t, abi.ChainEpoch(0), sm.FreezeHeight())
+	require.False(t, sm.frozen())
+
+	vote("id4", 20)
+	require.Equal(t, abi.ChainEpoch(20), sm.FreezeHeight())
+	require.False(t, sm.frozen())
+	sm.height = 20
+	require.True(t, sm.frozen())
+
+	h, err := sm.loadFreezeHeight()
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(20), h)
+
+	// Blocks above the freeze height are rejected.
+	require.NoError(t, checkFreezeHeight(&types.BlockHeader{Height: 20}, 20))
+	require.Error(t, checkFreezeHeight(&types.BlockHeader{Height: 21}, 20))
+	require.NoError(t, checkFreezeHeight(&types.BlockHeader{Height: 21}, 0))
+}
+
+func TestShutdownVoteRecords(t *testing.T) {
+	votes := map[abi.ChainEpoch]map[mirtypes.NodeID]struct{}{
+		30: {"id2": {}},
+		20: {"id3": {}, "id1": {}},
+	}
+	records := shutdownVoteRecords(votes)
+	require.Equal(t, []ShutdownVote{{20, "id1"}, {20, "id3"}, {30, "id2"}}, records)
+	require.Equal(t, votes, shutdownVotesFromRecords(records))
+
+	// The freeze height and the votes are restored from the checkpoints.
+	parent := ParentMeta{Height: 10, Cid: cid.NewCidV0(u.Hash([]byte("parent")))}
+	ch := &Checkpoint{Height: 11, Parent: parent, BlockCids: []cid.Cid{cid.NewCidV0(u.Hash([]byte("block")))}}
+	ch.Ext.FreezeHeight = 40
+	ch.Ext.ShutdownVotes = records
+	b, err := ch.Bytes()
+	require.NoError(t, err)
+	got := &Checkpoint{}
+	require.NoError(t, got.FromBytes(b))
+	require.Equal(t, ch.Ext, got.Ext)
+}
@@ -0,0 +1,173 @@
+//go:generate go run ./gen
+
+// Package solidity converts the certificates of Mir checkpoints into the ABI layout expected by
+// Solidity contracts verifying the finality of a subnet, e.g. FEVM contracts on the parent.
+//
+// The validators sign the SHA-256 hash of the checkpoint data with their secp256k1 Filecoin keys,
+// which sign the BLAKE2b-256 hash of the message. A contract verifies a certificate by recovering
+// the Ethereum address of each signer from the digest with ecrecover, and checking that the signers
+// hold a quorum of the weight of the membership it tracks for the subnet.
+package solidity
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/minio/blake2b-simd"
+
+	"github.com/filecoin-project/go-address"
+	gocrypto "github.com/filecoin-project/go-crypto"
+	filcrypto "github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+)
+
+// Signature is the signature of a validator in the layout of the CheckpointSignature struct.
+type Signature struct {
+	// Ethereum address of the validator, derived from the public key of its signature.
+	Signer [20]byte
+	R      [32]byte
+	S      [32]byte
+	// V is the recovery ID as expected by ecrecover, 27 or 28.
+	V uint8
+}
+
+// Cert is a checkpoint certificate in the layout of the SubnetCheckpointCert struct.
+type Cert struct {
+	Epoch        uint64
+	SeqNr        uint64
+	SnapshotHash [32]byte
+	// Message is the SHA-256 hash of SigData.
+	Message [32]byte
+	// Digest is the BLAKE2b-256 hash of Message, signed by the validators.
+	Digest [32]byte
+	// SigData is the checkpoint data signed by the validators: the epoch and the sequence number
+	// as little-endian uint64s, followed by the snapshot hash.
+	SigData []byte
+	// Signatures of the certificate, sorted by validator ID.
+	Signatures []Signature
+}
+
+// NewCert converts the certificate of a stable checkpoint. It fails if a signature of the certificate
+// is not a secp256k1 signature of the validator that produced it.
+func NewCert(ch *checkpoint.StableCheckpoint) (*Cert, error) {
+	sh, err := snapshotHash(ch.StateSnapshot())
+	if err != nil {
+		return nil, err
+	}
+	c := &Cert{
+		Epoch:        uint64(ch.Epoch()),
+		SeqNr:        uint64(ch.SeqNr()),
+		SnapshotHash: sh,
+		SigData:      checkpointSigData(ch.Epoch(), ch.SeqNr(), sh[:]),
+	}
+	c.Message = sha256.Sum256(c.SigData)
+	c.Digest = blake2b.Sum256(c.Message[:])
+
+	cert := ch.Certificate()
+	ids := make([]string, 0, len(cert))
+	sigs := make(map[string][]byte, len(cert))
+	for id, b := range cert {
+		ids = append(ids, id.Pb())
+		sigs[id.Pb()] = b
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		var sig filcrypto.Signature
+		if err := sig.UnmarshalBinary(sigs[id]); err != nil {
+			return nil, fmt.Errorf("error decoding signature of %s: %w", id, err)
+		}
+		s, err := newSignature(id, &sig, c.Digest)
+		if err != nil {
+			return nil, err
+		}
+		c.Signatures = append(c.Signatures, s)
+	}
+	return c, nil
+}
+
+func newSignature(id string, sig *filcrypto.Signature, digest [32]byte) (Signature, error) {
+	if sig.Type != filcrypto.SigTypeSecp256k1 || len(sig.Data) != 65 {
+		return Signature{}, fmt.Errorf("signature of %s is not a secp256k1 signature", id)
+	}
+	pubk, err := gocrypto.EcRecover(digest[:], sig.Data)
+	if err != nil {
+		return Signature{}, fmt.Errorf("error recovering public key of %s: %w", id, err)
+	}
+	addr, err := address.NewSecp256k1Address(pubk)
+	if err != nil {
+		return Signature{}, err
+	}
+	signer, err := address.NewFromString(id)
+	if err != nil {
+		return Signature{}, fmt.Errorf("invalid validator ID %s: %w", id, err)
+	}
+	if signer != addr {
+		return Signature{}, fmt.Errorf("signature of %s was produced by %s", id, addr)
+	}
+	eth, err := ethtypes.EthAddressFromPubKey(pubk)
+	if err != nil {
+		return Signature{}, err
+	}
+
+	var s Signature
+	copy(s.Signer[:], eth)
+	copy(s.R[:], sig.Data[:32])
+	copy(s.S[:], sig.Data[32:64])
+	s.V = sig.Data[64] + 27
+	return s, nil
+}
+
+// EncodeABI returns the certificate encoded as abi.encode(SubnetCheckpointCert), so a contract
+// can decode it with abi.decode(data, (SubnetCheckpointCert)).
+func (c *Cert) EncodeABI() []byte {
+	// Number of words of the head of the tuple: its static fields and the offsets of the dynamic ones.
+	const headWords = 7
+
+	head := make([]byte, 0, headWords*32)
+	head = append(head, uintWord(c.Epoch)...)
+	head = append(head, uintWord(c.SeqNr)...)
+	head = append(head, c.SnapshotHash[:]...)
+	head = append(head, c.Message[:]...)
+	head = append(head, c.Digest[:]...)
+
+	tail := encodeBytes(c.SigData)
+	head = append(head, uintWord(headWords*32)...)
+	head = append(head, uintWord(uint64(headWords*32+len(tail)))...)
+
+	tail = append(tail, uintWord(uint64(len(c.Signatures)))...)
+	for _, s := range c.Signatures {
+		tail = append(tail, leftPad(s.Signer[:])...)
+		tail = append(tail, s.R[:]...)
+		tail = append(tail, s.S[:]...)
+		tail = append(tail, uintWord(uint64(s.V))...)
+	}
+
+	// The certificate is a dynamic tuple, so its encoding starts with its offset.
+	out := uintWord(32)
+	out = append(out, head...)
+	return append(out, tail...)
+}
+
+func uintWord(v uint64) []byte {
+	w := make([]byte, 32)
+	binary.BigEndian.PutUint64(w[24:], v)
+	return w
+}
+
+func leftPad(b []byte) []byte {
+	w := make([]byte, 32)
+	copy(w[32-len(b):], b)
+	return w
+}
+
+// encodeBytes encodes a dynamic bytes value: its length followed by the data padded to a multiple of 32 bytes.
+func encodeBytes(b []byte) []byte {
+	out := uintWord(uint64(len(b)))
+	padded := make([]byte, (len(b)+31)/32*32)
+	copy(padded, b)
+	return append(out, padded...)
+}
@@ -0,0 +1,146 @@
+package solidity
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/minio/blake2b-simd"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	filcrypto "github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+	trantorpbtypes "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	tt "github.com/filecoin-project/mir/pkg/trantor/types"
+	mirtypes "github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	"github.com/filecoin-project/lotus/lib/sigs"
+	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
+)
+
+func TestNewSignature(t *testing.T) {
+	priv, err := sigs.Generate(filcrypto.SigTypeSecp256k1)
+	require.NoError(t, err)
+	pub, err := sigs.ToPublic(filcrypto.SigTypeSecp256k1, priv)
+	require.NoError(t, err)
+	addr, err := address.NewSecp256k1Address(pub)
+	require.NoError(t, err)
+
+	message := [32]byte{1, 2, 3}
+	sig, err := sigs.Sign(filcrypto.SigTypeSecp256k1, priv, message[:])
+	require.NoError(t, err)
+
+	s, err := newSignature(addr.String(), sig, blake2b.Sum256(message[:]))
+	require.NoError(t, err)
+	require.Contains(t, []uint8{27, 28}, s.V)
+	require.Equal(t, sig.Data[:32], s.R[:])
+	require.NotEqual(t, [20]byte{}, s.Signer)
+
+	// The signature of another validator is rejected.
+	other, err := address.NewSecp256k1Address(append([]byte{}, pub[:len(pub)-1]...))
+	require.NoError(t, err)
+	_, err = newSignature(other.String(), sig, blake2b.Sum256(message[:]))
+	require.Error(t, err)
+}
+
+// secpVerifier verifies the signatures of a checkpoint certificate like the Mir validators do.
+type secpVerifier struct{}
+
+func (secpVerifier) Verify(data [][]byte, b []byte, id mirtypes.NodeID) error {
+	addr, err := address.NewFromString(id.Pb())
+	if err != nil {
+		return err
+	}
+	var sig filcrypto.Signature
+	if err := sig.UnmarshalBinary(b); err != nil {
+		return err
+	}
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return sigs.Verify(&sig, addr, h.Sum(nil))
+}
+
+func TestNewCert(t *testing.T) {
+	priv, err := sigs.Generate(filcrypto.SigTypeSecp256k1)
+	require.NoError(t, err)
+	pub, err := sigs.ToPublic(filcrypto.SigTypeSecp256k1, priv)
+	require.NoError(t, err)
+	addr, err := address.NewSecp256k1Address(pub)
+	require.NoError(t, err)
+	id := mirtypes.NodeID(addr.String())
+
+	mb := &trantorpbtypes.Membership{Nodes: map[mirtypes.NodeID]*trantorpbtypes.NodeIdentity{
+		id: {Id: id, Addr: "/ip4/127.0.0.1/tcp/10000", Weight: "1"},
+	}}
+	ch := &checkpoint.StableCheckpoint{
+		Sn: 40,
+		Snapshot: &trantorpbtypes.StateSnapshot{
+			AppData: []byte("app data"),
+			EpochData: &trantorpbtypes.EpochData{
+				EpochConfig: &trantorpbtypes.EpochConfig{
+					EpochNr:     3,
+					FirstSn:     40,
+					Length:      10,
+					Memberships: []*trantorpbtypes.Membership{mb, mb},
+				},
+				ClientProgress: &trantorpbtypes.ClientProgress{Progress: map[tt.ClientID]*trantorpbtypes.DeliveredTXs{
+					"b": {LowWm: 2, Delivered: []uint64{4}},
+					"a": {LowWm: 7},
+				}},
+				LeaderPolicy:       []byte("policy"),
+				PreviousMembership: mb,
+			},
+		},
+	}
+
+	sh, err := snapshotHash(ch.StateSnapshot())
+	require.NoError(t, err)
+	msg := sha256.Sum256(checkpointSigData(ch.Epoch(), ch.SeqNr(), sh[:]))
+	sig, err := sigs.Sign(filcrypto.SigTypeSecp256k1, priv, msg[:])
+	require.NoError(t, err)
+	b, err := sig.MarshalBinary()
+	require.NoError(t, err)
+	ch.Cert = checkpoint.Certificate{id: b}
+
+	// The data serialized for the certificate must be the data Mir verifies the signatures over.
+	require.NoError(t, ch.VerifyCert(crypto.SHA256, secpVerifier{}, mb))
+
+	c, err := NewCert(ch)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), c.Epoch)
+	require.Equal(t, uint64(40), c.SeqNr)
+	require.Equal(t, msg, c.Message)
+	require.Len(t, c.Signatures, 1)
+	eth, err := ethtypes.EthAddressFromPubKey(pub)
+	require.NoError(t, err)
+	require.Equal(t, eth, c.Signatures[0].Signer[:])
+}
+
+func TestEncodeABI(t *testing.T) {
+	c := &Cert{
+		Epoch:      3,
+		SeqNr:      40,
+		SigData:    make([]byte, 33),
+		Signatures: []Signature{{Signer: [20]byte{0xaa}, V: 27}},
+	}
+	b := c.EncodeABI()
+
+	word := func(i int) []byte { return b[i*32 : (i+1)*32] }
+	// Offset of the tuple, then its head.
+	require.Equal(t, uintWord(32), word(0))
+	require.Equal(t, uintWord(3), word(1))
+	require.Equal(t, uintWord(40), word(2))
+	require.Len(t, Layout[1].Fields, 7)
+	require.Equal(t, uintWord(7*32), word(6))
+	// The 33 bytes of sigData take a length word and two data words.
+	require.Equal(t, uintWord(10*32), word(7))
+	require.Equal(t, uintWord(33), word(8))
+	require.Equal(t, uintWord(1), word(11))
+	require.Equal(t, byte(0xaa), word(12)[12])
+	require.Equal(t, uintWord(27), word(15))
+	require.Len(t, b, 16*32)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/solidity"
+)
+
+const output = "SubnetCheckpointCert.sol"
+
+var tmpl = template.Must(template.New("sol").Parse(`// Code generated by chain/consensus/mir/solidity/gen. DO NOT EDIT.
+// SPDX-License-Identifier: MIT OR Apache-2.0
+pragma solidity ^0.8.17;
+{{range .}}
+/// @notice {{.Doc}}
+struct {{.Name}} {
+{{- range .Fields}}
+{{- if .Doc}}
+    /// {{.Doc}}
+{{- end}}
+    {{.Type}} {{.Name}};
+{{- end}}
+}
+{{end}}
+/// @notice Verifier of the certificates exported with ` + "`eudico mir validator checkpoint export-cert`" + `,
+/// which are encoded as abi.encode(SubnetCheckpointCert).
+interface ISubnetCheckpointVerifier {
+    /// @notice Returns whether the signers of the certificate hold a quorum of the membership of the subnet.
+    function verify(SubnetCheckpointCert calldata cert) external view returns (bool);
+}
+`))
+
+func main() {
+	f, err := os.Create(output)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer f.Close() // nolint
+	if err := tmpl.Execute(f, solidity.Layout); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
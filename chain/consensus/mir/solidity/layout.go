@@ -0,0 +1,43 @@
+package solidity
+
+// Field is a field of a Solidity struct.
+type Field struct {
+	Name string
+	Type string
+	Doc  string
+}
+
+// Struct is a Solidity struct of the input layout of the verifiers.
+type Struct struct {
+	Name   string
+	Doc    string
+	Fields []Field
+}
+
+// Layout is the input layout expected by the Solidity verifiers of checkpoint certificates, in the order
+// the structs are declared. The encoding of EncodeABI follows it, and SubnetCheckpointCert.sol is generated from it.
+var Layout = []Struct{
+	{
+		Name: "CheckpointSignature",
+		Doc:  "Signature of a validator over the digest of a checkpoint.",
+		Fields: []Field{
+			{Name: "signer", Type: "address", Doc: "Ethereum address of the public key of the validator."},
+			{Name: "r", Type: "bytes32"},
+			{Name: "s", Type: "bytes32"},
+			{Name: "v", Type: "uint8", Doc: "Recovery ID as expected by ecrecover, 27 or 28."},
+		},
+	},
+	{
+		Name: "SubnetCheckpointCert",
+		Doc:  "Certificate of a stable checkpoint of a subnet, signed by a quorum of its validators.",
+		Fields: []Field{
+			{Name: "epoch", Type: "uint64", Doc: "Mir epoch of the checkpoint."},
+			{Name: "seqNr", Type: "uint64", Doc: "Sequence number of the first batch after the checkpoint."},
+			{Name: "snapshotHash", Type: "bytes32", Doc: "SHA-256 hash of the state snapshot of the checkpoint."},
+			{Name: "message", Type: "bytes32", Doc: "SHA-256 hash of sigData."},
+			{Name: "digest", Type: "bytes32", Doc: "BLAKE2b-256 hash of message, signed by the validators."},
+			{Name: "sigData", Type: "bytes", Doc: "Epoch and sequence number as little-endian uint64s, followed by snapshotHash."},
+			{Name: "signatures", Type: "CheckpointSignature[]", Doc: "Signatures sorted by validator ID."},
+		},
+	},
+}
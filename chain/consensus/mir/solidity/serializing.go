@@ -0,0 +1,61 @@
+package solidity
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	trantorpbtypes "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	"github.com/filecoin-project/mir/pkg/serializing"
+	tt "github.com/filecoin-project/mir/pkg/trantor/types"
+	"github.com/filecoin-project/mir/pkg/util/maputil"
+	"github.com/filecoin-project/mir/pkg/util/membutil"
+)
+
+// The data signed by the certificate of a checkpoint is serialized by unexported functions of the
+// checkpoint package of Mir, which are mirrored here. They must be kept in sync when Mir is upgraded:
+// TestNewCert fails if the certificate of a checkpoint doesn't verify against the data serialized here.
+
+// checkpointSigData returns the data signed by the validators: the epoch and the sequence number of the
+// checkpoint as little-endian uint64s, followed by the hash of its state snapshot.
+func checkpointSigData(epoch tt.EpochNr, seqNr tt.SeqNr, snapshotHash []byte) []byte {
+	data := append(epoch.Bytes(), seqNr.Bytes()...)
+	return append(data, snapshotHash...)
+}
+
+// snapshotHash returns the SHA-256 hash of a state snapshot, as computed by Mir.
+func snapshotHash(s *trantorpbtypes.StateSnapshot) ([32]byte, error) {
+	cfg := s.EpochData.EpochConfig
+	data := [][]byte{cfg.EpochNr.Bytes(), serializing.Uint64ToBytes(cfg.Length), cfg.FirstSn.Bytes()}
+	for _, m := range cfg.Memberships {
+		b, err := membutil.Serialize(m)
+		if err != nil {
+			return [32]byte{}, fmt.Errorf("error serializing membership: %w", err)
+		}
+		data = append(data, b)
+	}
+	data = append(data, s.EpochData.LeaderPolicy)
+	maputil.IterateSorted(s.EpochData.ClientProgress.Progress, func(id tt.ClientID, txs *trantorpbtypes.DeliveredTXs) bool {
+		data = append(data, []byte(id.Pb()), serializing.Uint64ToBytes(txs.LowWm))
+		for _, txNo := range txs.Delivered {
+			data = append(data, serializing.Uint64ToBytes(txNo))
+		}
+		return true
+	})
+	// The previous membership is empty in the initial checkpoint.
+	if prev := s.EpochData.PreviousMembership; prev != nil && len(prev.Nodes) != 0 {
+		b, err := membutil.Serialize(prev)
+		if err != nil {
+			return [32]byte{}, fmt.Errorf("error serializing previous membership: %w", err)
+		}
+		data = append(data, b)
+	}
+	data = append(data, s.AppData)
+
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
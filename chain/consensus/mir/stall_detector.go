@@ -0,0 +1,121 @@
+package mir
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+// DefaultStallCadenceMultiple is the number of MaxProposeDelay periods without batches after which
+// the chain is considered stalled, if the stall timeout isn't configured.
+const DefaultStallCadenceMultiple = 30
+
+// Mir proposes a batch at least every MaxProposeDelay, even if it is empty, so a validator that
+// doesn't get batches for many periods is not looking at an idle chain: either consensus is stalled,
+// e.g. because the validators lost their quorum, or the subnet was frozen on purpose by a shutdown vote.
+// The detector tells the two apart, so operators know whether to react during an incident.
+
+// stallDetector raises an alert when no batch is delivered to the validator for the stall timeout.
+type stallDetector struct {
+	ctx     context.Context
+	id      string
+	timeout time.Duration
+
+	lk sync.Mutex
+	// Time of the last batch delivered.
+	last time.Time
+	// Whether the subnet was frozen when the last batch was delivered.
+	frozen  bool
+	stalled bool
+}
+
+func newStallDetector(ctx context.Context, id string, timeout time.Duration) *stallDetector {
+	return &stallDetector{
+		ctx:     ctx,
+		id:      id,
+		timeout: timeout,
+		last:    time.Now(),
+	}
+}
+
+// stallTimeout returns the stall timeout of the configuration.
+func stallTimeout(cfg *ConsensusConfig) time.Duration {
+	if cfg.StallTimeout > 0 {
+		return cfg.StallTimeout
+	}
+	return DefaultStallCadenceMultiple * cfg.MaxProposeDelay
+}
+
+// delivered records a batch delivered to the validator, and whether the subnet is frozen.
+func (d *stallDetector) delivered(frozen bool) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if d.stalled {
+		log.With("validator", d.id).Infof("chain resumed: batch delivered after %s", time.Since(d.last).Truncate(time.Second))
+		stats.Record(d.ctx, metrics.MirChainStalled.M(0))
+	}
+	d.last = time.Now()
+	d.frozen = frozen
+	d.stalled = false
+}
+
+// reset restarts the timeout, e.g. after the state of the validator is restored from a checkpoint.
+func (d *stallDetector) reset() {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	d.last = time.Now()
+}
+
+// state returns MirStalled if the chain is stalled, MirIdle if no batch is expected, or an empty string.
+func (d *stallDetector) state() string {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	switch {
+	case d.frozen:
+		return MirIdle
+	case d.stalled:
+		return MirStalled
+	default:
+		return ""
+	}
+}
+
+// lastBatch returns the time of the last batch delivered to the validator.
+func (d *stallDetector) lastBatch() time.Time {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	return d.last
+}
+
+func (d *stallDetector) check(now time.Time) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if d.stalled || d.frozen || now.Sub(d.last) < d.timeout {
+		return
+	}
+	d.stalled = true
+	log.With("validator", d.id).Errorw("chain stalled: no batch delivered by Mir",
+		"since", d.last, "timeout", d.timeout)
+	stats.Record(d.ctx, metrics.MirChainStalls.M(1), metrics.MirChainStalled.M(1))
+}
+
+func (d *stallDetector) run() {
+	if d.timeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(d.timeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.check(now)
+		}
+	}
+}
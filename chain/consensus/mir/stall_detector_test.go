@@ -0,0 +1,33 @@
+package mir
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStallDetector(t *testing.T) {
+	d := newStallDetector(context.Background(), "id1", time.Minute)
+	start := d.lastBatch()
+
+	d.check(start.Add(30 * time.Second))
+	require.Equal(t, "", d.state())
+
+	d.check(start.Add(2 * time.Minute))
+	require.Equal(t, MirStalled, d.state())
+
+	// The chain resumes with the next batch.
+	d.delivered(false)
+	require.Equal(t, "", d.state())
+	require.True(t, d.lastBatch().After(start) || d.lastBatch().Equal(start))
+
+	// A frozen subnet is idle by design, not stalled.
+	d.delivered(true)
+	d.check(d.lastBatch().Add(time.Hour))
+	require.Equal(t, MirIdle, d.state())
+
+	require.Equal(t, time.Minute, stallTimeout(&ConsensusConfig{StallTimeout: time.Minute, MaxProposeDelay: time.Second}))
+	require.Equal(t, DefaultStallCadenceMultiple*time.Second, stallTimeout(&ConsensusConfig{MaxProposeDelay: time.Second}))
+}
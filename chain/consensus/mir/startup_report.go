@@ -0,0 +1,154 @@
+package mir
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+	"github.com/filecoin-project/mir/pkg/net"
+	"github.com/filecoin-project/mir/pkg/trantor"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetparams"
+)
+
+// Restore points of a validator.
+const (
+	RestoreFromGenesis           = "genesis"
+	RestoreFromLatestCheckpoint  = "latest checkpoint"
+	RestoreFromInitialCheckpoint = "initial checkpoint"
+)
+
+// StartupReport is the effective configuration of a validator when its Mir node is created.
+// It is logged at startup and served through the admin API, so a misconfigured deployment
+// can be diagnosed from the values actually used instead of the flags passed to the validator.
+type StartupReport struct {
+	Validator string
+	Network   string
+	StartedAt time.Time
+
+	// Membership the validator starts with.
+	MembershipSource    string
+	ConfigurationNumber uint64
+	Validators          int
+	ValidatorSetHash    string
+	MinValidators       uint64
+	GenesisEpoch        abi.ChainEpoch
+
+	// Checkpoint Mir is restored from.
+	RestoreFrom   string
+	RestoreHeight abi.ChainEpoch
+
+	DatastorePath     string
+	Ephemeral         bool
+	CheckpointRepo    string
+	StateSnapshotRepo string
+	FastSync          bool
+
+	// Libp2p identity and addresses of the Mir transport, if known.
+	PeerID         string
+	TransportAddrs []string
+
+	// Effective Trantor parameters.
+	SegmentLength                int
+	ConfigOffset                 int
+	MaxProposeDelay              time.Duration
+	PBFTViewChangeSNTimeout      time.Duration
+	PBFTViewChangeSegmentTimeout time.Duration
+	MaxTransactionsInBatch       int
+
+	// Subnet parameters.
+	BlockGasLimit int64
+	ParamsHash    string
+	Upgrades      subnetparams.Upgrades
+
+	CheckpointRandomness    bool
+	CompressCheckpoints     bool
+	StallTimeout            time.Duration
+	DisableMempoolBucketing bool
+	DisableParamsPresets    bool
+	MpoolSelectRetries      int
+	RampUpEpochs            int
+	ExecutionLag            int
+	MaxCheckpointBlocks     int
+	TxSources               int
+}
+
+func newStartupReport(
+	cfg *Config,
+	netName string,
+	info *membership.Info,
+	params trantor.Params,
+	initCh *checkpoint.StableCheckpoint,
+	net net.Transport,
+) *StartupReport {
+	r := &StartupReport{
+		Validator:                    cfg.Addr.String(),
+		Network:                      netName,
+		StartedAt:                    time.Now(),
+		MembershipSource:             cfg.MembershipSourceValue,
+		MinValidators:                info.MinValidators,
+		GenesisEpoch:                 abi.ChainEpoch(info.GenesisEpoch),
+		RestoreFrom:                  RestoreFromGenesis,
+		DatastorePath:                cfg.DatastorePath,
+		Ephemeral:                    cfg.Ephemeral,
+		CheckpointRepo:               cfg.CheckpointRepo,
+		StateSnapshotRepo:            cfg.StateSnapshotRepo,
+		FastSync:                     cfg.FastSyncHost != nil,
+		SegmentLength:                params.Iss.SegmentLength,
+		ConfigOffset:                 params.Iss.ConfigOffset,
+		MaxProposeDelay:              params.Iss.MaxProposeDelay,
+		PBFTViewChangeSNTimeout:      params.Iss.PBFTViewChangeSNTimeout,
+		PBFTViewChangeSegmentTimeout: params.Iss.PBFTViewChangeSegmentTimeout,
+		MaxTransactionsInBatch:       params.Mempool.MaxTransactionsInBatch,
+		CheckpointRandomness:         cfg.Consensus.CheckpointRandomness,
+		CompressCheckpoints:          cfg.Consensus.CompressCheckpoints,
+		StallTimeout:                 stallTimeout(cfg.Consensus),
+		DisableMempoolBucketing:      cfg.Consensus.DisableMempoolBucketing,
+		DisableParamsPresets:         cfg.Consensus.DisableParamsPresets,
+		MpoolSelectRetries:           cfg.Consensus.MpoolSelectRetries,
+		RampUpEpochs:                 cfg.Consensus.RampUpEpochs,
+		ExecutionLag:                 cfg.Consensus.ExecutionLag,
+		MaxCheckpointBlocks:          maxCheckpointBlocks(cfg.Consensus),
+		TxSources:                    len(cfg.TxSources),
+		BlockGasLimit:                subnetparams.BlockGasLimit(netName),
+		ParamsHash:                   subnetparams.Hash(netName),
+		Upgrades:                     subnetparams.GetUpgrades(netName),
+	}
+
+	if set := info.ValidatorSet; set != nil {
+		r.ConfigurationNumber = set.ConfigurationNumber
+		r.Validators = set.Size()
+		if h, err := ValidatorSetHash(set); err == nil {
+			r.ValidatorSetHash = h
+		}
+	}
+
+	if len(initCh.Snapshot.AppData) > 0 {
+		if snap, err := UnwrapCheckpointSnapshot(initCh); err == nil {
+			r.RestoreHeight = snap.Height
+		}
+		r.RestoreFrom = RestoreFromLatestCheckpoint
+		if cfg.InitialCheckpoint != nil {
+			r.RestoreFrom = RestoreFromInitialCheckpoint
+		}
+	}
+
+	if d, ok := net.(*DiagnosticTransport); ok {
+		r.PeerID = d.h.ID().String()
+		for _, a := range d.h.Addrs() {
+			r.TransportAddrs = append(r.TransportAddrs, a.String())
+		}
+	}
+	return r
+}
+
+func (r *StartupReport) log() {
+	b, err := json.Marshal(r)
+	if err != nil {
+		log.With("validator", r.Validator).Warnf("failed to encode startup report: %v", err)
+		return
+	}
+	log.With("validator", r.Validator).Infow("Mir startup report", "report", json.RawMessage(b))
+}
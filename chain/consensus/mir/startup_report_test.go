@@ -0,0 +1,52 @@
+package mir
+
+import (
+	"testing"
+	"time"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/mir/pkg/trantor"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+)
+
+func TestStartupReport(t *testing.T) {
+	v, err := validator.NewValidatorFromString("t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+	set := validator.NewValidatorSet(3, []*validator.Validator{v})
+	_, mb, err := membership.Membership(set.GetValidators())
+	require.NoError(t, err)
+
+	addr, err := address.NewFromString("t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy")
+	require.NoError(t, err)
+	cfg, err := NewConfig(addr, "/repo/mir.db", nil, "/repo/checkpoints", 2, 4, "3s", "", membership.FileSource)
+	require.NoError(t, err)
+
+	params := trantor.DefaultParams(mb)
+	params.Iss.SegmentLength = cfg.Consensus.SegmentLength
+	params.Iss.ConfigOffset = cfg.Consensus.ConfigOffset
+	params.Mempool.MaxTransactionsInBatch = cfg.Consensus.MaxTransactionsInBatch
+	genesis, err := trantor.GenesisCheckpoint([]byte{}, params)
+	require.NoError(t, err)
+
+	info := &membership.Info{ValidatorSet: set, MinValidators: 1, GenesisEpoch: 5}
+	r := newStartupReport(cfg, "/root", info, params, genesis, nil)
+
+	hash, err := ValidatorSetHash(set)
+	require.NoError(t, err)
+	require.Equal(t, addr.String(), r.Validator)
+	require.Equal(t, uint64(3), r.ConfigurationNumber)
+	require.Equal(t, 1, r.Validators)
+	require.Equal(t, hash, r.ValidatorSetHash)
+	require.EqualValues(t, 5, r.GenesisEpoch)
+	require.Equal(t, RestoreFromGenesis, r.RestoreFrom)
+	require.Equal(t, "/repo/mir.db", r.DatastorePath)
+	require.Equal(t, 2, r.SegmentLength)
+	require.Equal(t, 4, r.ConfigOffset)
+	require.Equal(t, DefaultMaxTransactionsInBatch, r.MaxTransactionsInBatch)
+	require.Empty(t, r.TransportAddrs)
+	require.WithinDuration(t, time.Now(), r.StartedAt, time.Minute)
+}
@@ -3,16 +3,22 @@ package mir
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
 	"path"
 	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/consensus-shipyard/go-ipc-types/sdk"
 	"github.com/consensus-shipyard/go-ipc-types/validator"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"go.opencensus.io/stats"
+	"go.uber.org/zap"
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-state-types/abi"
@@ -25,22 +31,36 @@ import (
 	lapi "github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/api/v1api"
 	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/compression"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/pool/fifo"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetcron"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetparams"
 	"github.com/filecoin-project/lotus/chain/gen/genesis"
+	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
 	ltypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/metrics"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
 var (
 	LatestCheckpointKey   = datastore.NewKey("mir/latest-check")
 	LatestCheckpointPbKey = datastore.NewKey("mir/latest-check-pb")
+	// SealedMessagesKey is used to store the ordered encrypted messages waiting for their keys.
+	SealedMessagesKey = datastore.NewKey("mir/sealed-messages")
 
 	PeerDiscoveryInterval   = 800 * time.Millisecond
 	PeerDiscoveryTimeout    = 3 * time.Minute
 	WaitForHeightMinTimeout = 30 * time.Second
+
+	// ExchangeSyncMinBackoff and ExchangeSyncMaxBackoff bound the time between two attempts to sync
+	// a checkpoint through the chain exchange, after syncing it from the connected peers failed.
+	ExchangeSyncMinBackoff = time.Second
+	ExchangeSyncMaxBackoff = time.Minute
+	// ExchangeSyncTimeout is the time after which syncing a checkpoint through the chain exchange is given up.
+	ExchangeSyncTimeout = 30 * time.Minute
 )
 
 type Message []byte
@@ -62,6 +82,8 @@ type StateManager struct {
 
 	// The current epoch number.
 	currentEpoch trantor.EpochNr
+	// Copy of currentEpoch that can be read by the manager while Mir runs.
+	orderedEpoch atomic.Uint64
 
 	// For each epoch number, stores the corresponding membership.
 	// It stores the current membership and the memberships of ConfigOffset following epochs.
@@ -86,6 +108,14 @@ type StateManager struct {
 	prevCheckpoint ParentMeta
 
 	checkpointRepo string // Path where checkpoints are (optionally) persisted
+	// Whether the checkpoints must be synced to the checkpoint repo before being delivered.
+	strictCheckpoints bool
+	// Path where the state of the chain at the checkpoints is (optionally) exported.
+	stateSnapshotRepo string
+	// Set while a state snapshot is being exported.
+	exportingState atomic.Bool
+	// Host used to fetch state snapshots when restoring the state, if fast sync is enabled.
+	fastSyncHost host.Host
 
 	// Channel to send checkpoints to assemble them in blocks.
 	nextCheckpointChan chan *checkpoint.StableCheckpoint
@@ -96,7 +126,65 @@ type StateManager struct {
 	// Mir chain height.
 	height abi.ChainEpoch
 
-	configOffset int
+	// CIDs of the blocks created since the previous checkpoint.
+	blocks *blockIndex
+
+	// Head of the chain, shared by the components of the validator that wait for or check it.
+	heads *headTracker
+	// Checks that the chain head reaches the submitted blocks.
+	watchdog *headWatchdog
+	// Checks that the chain includes the blocks committed by the latest checkpoint.
+	divergence *divergenceCheck
+	// Raises an alert if Mir doesn't deliver batches.
+	stalls *stallDetector
+	// Checks that the SetMembership messages are applied to the gateway actor.
+	gatewayMembership *gatewayMembershipCheck
+
+	// Size of the ordered batches.
+	batchStats *batchStats
+	// Transactions contributed by every validator to the batches of the epoch.
+	contributions *contributionStats
+	// State reported in the status of the validator.
+	status *statusTracker
+
+	configOffset  int
+	segmentLength int
+	// Maximum number of blocks committed by the checkpoints restored and delivered to the validator.
+	maxCheckpointBlocks int
+
+	// Height above which no blocks are created, set when a strong quorum votes for shutting the subnet down.
+	freezeHeight  atomic.Int64
+	shutdownVotes map[abi.ChainEpoch]map[t.NodeID]struct{}
+
+	// Ordered encrypted messages waiting for their keys to be revealed, from the EncryptedTxs upgrade.
+	sealedMsgs *sealedMessages
+	// Client used by the validator to propose encrypted transactions, if any.
+	encryptedClient *encryptedTxsClient
+
+	// Latest timestamp ordered from every validator, from the BatchTimestamps upgrade.
+	timestamps map[string]uint64
+	// Client used by the validator to propose its timestamps, if any.
+	timestampClient *timestampClient
+	// Creates the blocks behind the ordering of their batches, nil without an execution lag.
+	execution *executionPipeline
+
+	// Include beacon entries derived from checkpoints in blocks, if the chain doesn't include them already.
+	checkpointRandomness bool
+	// Consensus features activated by the flags of the validator or the network upgrades of the subnet.
+	upgrades upgrades
+	// Whether the snapshots and the persisted stable checkpoints are compressed.
+	compressCheckpoints bool
+
+	blockCreator BlockCreator
+
+	// Called with the height of every block created by the validator.
+	onBlock func(abi.ChainEpoch)
+	// Called with the provenance of every block created by the validator.
+	onBlockProvenance func(BlockProvenance)
+	// Called with every stable checkpoint delivered to the validator.
+	onCheckpoint func(*CheckpointNotification)
+	// Called with the messages ordered and included in blocks by the validator.
+	onMsgEvent func(*MsgEvent)
 }
 
 func NewStateManager(
@@ -110,6 +198,7 @@ func NewStateManager(
 	pool *fifo.Pool,
 	cfg *Config,
 ) (*StateManager, error) {
+	heads := newHeadTracker(ctx, api, cfg.Addr.String())
 	sm := StateManager{
 		ctx:                     ctx,
 		netName:                 netName,
@@ -123,10 +212,52 @@ func NewStateManager(
 		id:                      cfg.Addr.String(),
 		nextConfigurationNumber: 1,
 		checkpointRepo:          cfg.CheckpointRepo,
+		strictCheckpoints:       cfg.StrictCheckpointPersistence,
+		stateSnapshotRepo:       cfg.StateSnapshotRepo,
+		fastSyncHost:            cfg.FastSyncHost,
 		configOffset:            cfg.Consensus.ConfigOffset,
+		segmentLength:           cfg.Consensus.SegmentLength,
+		maxCheckpointBlocks:     maxCheckpointBlocks(cfg.Consensus),
+		sealedMsgs:              newSealedMessages(),
+		blocks:                  newBlockIndex(),
+		heads:                   heads,
+		watchdog:                newHeadWatchdog(ctx, api, heads, cfg.Addr.String()),
+		divergence:              newDivergenceCheck(ctx, api, heads, ds, cfg.Addr.String()),
+		stalls:                  newStallDetector(ctx, cfg.Addr.String(), stallTimeout(cfg.Consensus)),
+		gatewayMembership:       newGatewayMembershipCheck(api, cfg.Addr.String()),
+		batchStats:              newBatchStats(cfg.Addr.String(), cfg.Consensus.MaxTransactionsInBatch),
+		contributions:           newContributionStats(ds),
+		status:                  &statusTracker{},
+		shutdownVotes:           make(map[abi.ChainEpoch]map[t.NodeID]struct{}),
+		checkpointRandomness:    cfg.Consensus.CheckpointRandomness,
+		upgrades:                newUpgrades(string(netName)),
+		compressCheckpoints:     cfg.Consensus.CompressCheckpoints,
+		onBlock:                 cfg.OnBlock,
+		onBlockProvenance:       cfg.OnBlockProvenance,
+		onCheckpoint:            cfg.OnCheckpoint,
+		onMsgEvent:              cfg.OnMsgEvent,
+		blockCreator:            cfg.BlockCreator,
+	}
+	if sm.blockCreator == nil {
+		sm.blockCreator = &apiBlockCreator{api: api}
 	}
 
-	sm.configurationVotes = NewConfigurationVotes(sm.confManager.GetConfigurationVotes())
+	votes, err := sm.confManager.LoadVotes()
+	if err != nil {
+		return nil, xerrors.Errorf("validator %v failed to load configuration votes: %w", sm.id, err)
+	}
+	sm.configurationVotes = votes
+
+	freezeHeight, err := sm.loadFreezeHeight()
+	if err != nil {
+		return nil, xerrors.Errorf("validator %v failed to load freeze height: %w", sm.id, err)
+	}
+	sm.freezeHeight.Store(int64(freezeHeight))
+
+	sm.sealedMsgs, err = sm.loadSealedMessages()
+	if err != nil {
+		return nil, xerrors.Errorf("validator %v failed to load sealed messages: %w", sm.id, err)
+	}
 
 	// Initialize the membership for the first epoch and the ConfigOffset following ones (thus ConfigOffset+1).
 	// Note that sm.memberships[0] will almost immediately be overwritten by the first call to NewEpoch.
@@ -135,6 +266,7 @@ func NewStateManager(
 		sm.memberships[trantor.EpochNr(e)] = initialMembership
 	}
 	sm.nextNewMembership = initialMembership
+	sm.status.setMemberships(sm.currentEpoch, sm.memberships)
 
 	// Initialize manager checkpoint state with the corresponding latest checkpoint.
 	ch, err := sm.firstEpochCheckpoint()
@@ -146,6 +278,26 @@ func NewStateManager(
 		return nil, xerrors.Errorf("validator %v failed to get cid for checkpoint: %w", sm.id, err)
 	}
 	sm.prevCheckpoint = ParentMeta{Height: ch.Height, Cid: c}
+	sm.status.setCheckpointHeight(ch.Height)
+	sm.timestamps, err = timestampsFromRecords(ch.Ext.Timestamps)
+	if err != nil {
+		return nil, xerrors.Errorf("validator %v checkpoint contains invalid timestamps: %w", sm.id, err)
+	}
+	sm.shutdownVotes = shutdownVotesFromRecords(ch.Ext.ShutdownVotes)
+	if ch.Ext.FreezeHeight > sm.FreezeHeight() {
+		if err := sm.setFreezeHeight(ch.Ext.FreezeHeight); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Consensus.ExecutionLag > 0 {
+		sm.execution = newExecutionPipeline(cfg.Consensus.ExecutionLag)
+		go sm.runExecution()
+	}
+	go sm.heads.run()
+	go sm.watchdog.run()
+	go sm.divergence.run()
+	go sm.stalls.run()
 
 	return &sm, nil
 }
@@ -210,13 +362,60 @@ func (sm *StateManager) syncFromPeers(tsk types.TipSetKey) (err error) {
 	}
 }
 
+// syncFromExchange syncs the chain to the tipset tsk committed by a checkpoint through the chain exchange
+// of the daemon, which fetches the tipset and the chain before it from any of the peers it knows, and marks
+// the tipset as the checkpoint of the chain. Failed attempts are retried with exponential backoff until
+// ExchangeSyncTimeout.
+func (sm *StateManager) syncFromExchange(tsk types.TipSetKey) error {
+	log.With("validator", sm.id).Infof("syncFromExchange for TSK %s started", tsk)
+	defer log.With("validator", sm.id).Infof("syncFromExchange for TSK %s finished", tsk)
+
+	timeout := time.After(ExchangeSyncTimeout)
+	backoff := ExchangeSyncMinBackoff
+	for {
+		err := sm.api.SyncCheckpoint(sm.ctx, tsk)
+		if err == nil {
+			var ts *types.TipSet
+			if ts, err = sm.api.ChainGetTipSet(sm.ctx, tsk); err == nil {
+				err = sm.waitForHeightWithTimeout(WaitForHeightMinTimeout, ts.Height())
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		log.With("validator", sm.id).Warnf("syncFromExchange for TSK %s failed, retrying in %s: %v", tsk, backoff, err)
+
+		select {
+		case <-sm.ctx.Done():
+			return xerrors.Errorf("syncFromExchange context cancelled")
+		case <-timeout:
+			return xerrors.Errorf("syncing from the chain exchange timeout exceeded: %w", err)
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > ExchangeSyncMaxBackoff {
+			backoff = ExchangeSyncMaxBackoff
+		}
+	}
+}
+
+// syncToCheckpoint syncs the chain to the tipset tsk committed by a checkpoint from the connected peers,
+// falling back to the chain exchange if none of them serves it.
+func (sm *StateManager) syncToCheckpoint(tsk types.TipSetKey) error {
+	err := sm.syncFromPeers(tsk)
+	if err == nil || sm.ctx.Err() != nil {
+		return err
+	}
+	log.With("validator", sm.id).Warnf("failed to sync TSK %s from the connected peers, falling back to the chain exchange: %v", tsk, err)
+	return sm.syncFromExchange(tsk)
+}
+
 // RestoreState is called by Mir when the validator goes out-of-sync, and it requires
 // lotus to sync from the latest checkpoint. Mir provides lotus with the latest
 // checkpoint and from this:
 // - The latest membership and configuration for the consensus is recovered.
 // - We clean all previous outdated checkpoints and configurations we may have received while trying to sync.
 // - If there is a snapshot in the checkpoint, we poll our connections to sync
-// to the latest block determined by the checkpoint.
+// to the latest block determined by the checkpoint, falling back to the chain exchange.
 // - We deliver the checkpoint to the mining process, so it can be included in the next
 // block (Mir provides the latest checkpoint, which hasn't been included in a block yet)
 // - And we flag the mining process that we are synced, and it can start accepting new
@@ -224,18 +423,33 @@ func (sm *StateManager) syncFromPeers(tsk types.TipSetKey) (err error) {
 func (sm *StateManager) RestoreState(checkpoint *checkpoint.StableCheckpoint) error {
 	log.With("validator", sm.id).Infof("RestoreState for epoch %d started", sm.currentEpoch)
 	defer log.With("validator", sm.id).Infof("RestoreState for epoch %d finished", sm.currentEpoch)
+	sm.status.setSyncing(true)
+	defer sm.status.setSyncing(false)
+	// Restoring the state can take long, which doesn't mean that the chain is stalled.
+	defer sm.stalls.reset()
 	// release any previous checkpoint delivered and pending
 	// to sync, as we are syncing again. This prevents a deadlock.
 	sm.releaseNextCheckpointChan()
+	// The blocks ordered before the checkpoint are synced instead of created.
+	sm.flushExecution()
 
 	config := checkpoint.Snapshot.EpochData.EpochConfig
 	sm.currentEpoch = config.EpochNr
+	sm.orderedEpoch.Store(uint64(config.EpochNr))
 
 	// Sanity check.
 	if len(config.Memberships) != sm.configOffset+1 {
-		return fmt.Errorf("validator %v checkpoint contains %d memberships, expected %d (ConfigOffset=%d)",
+		return fmt.Errorf("validator %v checkpoint contains %d memberships, expected %d (ConfigOffset=%d): "+
+			"the ConfigOffset of the validator may differ from the rest of the membership",
 			sm.id, len(config.Memberships), sm.configOffset+1, sm.configOffset)
 	}
+	// Every validator leads a segment of the epoch, so the length of the epochs certified by the rest of
+	// validators reveals a different SegmentLength, which makes the validator fall out of sync repeatedly.
+	if n := len(config.Memberships[0].Nodes); sm.segmentLength > 0 && n > 0 && config.Length != uint64(sm.segmentLength*n) {
+		log.With("validator", sm.id).Warnf("epoch %d has length %d, expected %d for %d validators with SegmentLength=%d: "+
+			"the SegmentLength of the validator may differ from the rest of the membership",
+			config.EpochNr, config.Length, sm.segmentLength*n, n, sm.segmentLength)
+	}
 
 	// Set memberships for the current epoch and ConfigOffset following ones.
 	// Note that sm.memberships[i+sm.currentEpoch] will almost immediately be overwritten by the first call to NewEpoch.
@@ -246,6 +460,7 @@ func (sm *StateManager) RestoreState(checkpoint *checkpoint.StableCheckpoint) er
 
 	// The next membership is the last known membership. It may be replaced by another one during this epoch.
 	sm.nextNewMembership = sm.memberships[config.EpochNr+trantor.EpochNr(sm.configOffset)]
+	sm.status.setMemberships(sm.currentEpoch, sm.memberships)
 	log.With("validator", sm.id).Infof(
 		"RestoreState: next membership size is %d at epoch %d",
 		len(sm.nextNewMembership.Nodes), sm.currentEpoch)
@@ -259,6 +474,9 @@ func (sm *StateManager) RestoreState(checkpoint *checkpoint.StableCheckpoint) er
 		if err != nil {
 			return xerrors.Errorf("%v failed to unmarshal checkpoint: %w", sm.id, err)
 		}
+		if err := ch.validateWithLimit(sm.maxCheckpointBlocks); err != nil {
+			return xerrors.Errorf("%v refuses checkpoint: %w", sm.id, err)
+		}
 
 		chCID, err := ch.Cid()
 		if err != nil {
@@ -269,57 +487,120 @@ func (sm *StateManager) RestoreState(checkpoint *checkpoint.StableCheckpoint) er
 
 		// Restore the height, and configuration number and configuration votes.
 		sm.height = ch.Height - 1
+		sm.blocks.reset()
+		sm.watchdog.reset()
 		sm.nextConfigurationNumber = ch.NextConfigNumber
+		if err := ValidateVoteRecords(ch.Votes.Records); err != nil {
+			return xerrors.Errorf("%v checkpoint contains invalid configuration votes: %w", sm.id, err)
+		}
 		sm.configurationVotes = NewConfigurationVotesFromRecords(ch.Votes.Records)
 
+		// Restore the encrypted messages ordered before the checkpoint that are still waiting for their keys.
+		sealed, err := newSealedMessagesFromRecords(ch.SealedMsgs)
+		if err != nil {
+			return xerrors.Errorf("%v checkpoint contains invalid sealed messages: %w", sm.id, err)
+		}
+		sm.sealedMsgs = sealed
+		if err := sm.storeSealedMessages(); err != nil {
+			return xerrors.Errorf("%v failed to store sealed messages: %w", sm.id, err)
+		}
+
+		// Restore the latest timestamps of the validators.
+		sm.timestamps, err = timestampsFromRecords(ch.Ext.Timestamps)
+		if err != nil {
+			return xerrors.Errorf("%v checkpoint contains invalid timestamps: %w", sm.id, err)
+		}
+
+		// Restore the shutdown of the subnet.
+		sm.shutdownVotes = shutdownVotesFromRecords(ch.Ext.ShutdownVotes)
+		if err := sm.setFreezeHeight(ch.Ext.FreezeHeight); err != nil {
+			return err
+		}
+
 		// purge any state previous to the checkpoint
 		if err = sm.api.SyncPurgeForRecovery(sm.ctx, ch.Height); err != nil {
 			return xerrors.Errorf("%v couldn't purge state to recover from checkpoint: %w", sm.id, err)
 		}
 
-		if err = sm.syncFromPeers(types.NewTipSetKey(ch.BlockCids[0])); err != nil {
+		// Far behind the checkpoint, the state at the checkpoint is restored from a snapshot instead of
+		// executing all the blocks before it. The chain is still synced, to fetch any missing block.
+		if sm.fastSyncHost != nil {
+			if err := sm.fastSync(&ch); err != nil {
+				log.With("validator", sm.id).Warnf("fast sync failed, syncing the chain: %v", err)
+			}
+		}
+
+		if err = sm.syncToCheckpoint(types.NewTipSetKey(ch.BlockCids[0])); err != nil {
 			return xerrors.Errorf("%v couldn't sync from peers for checkpoint (%d, %v): %w", sm.id, ch.Height, chCID, err)
 		}
 	} else {
 		log.With("validator", sm.id).Infof("Snapshot len is zero")
 	}
 
+	if err := putCheckpointMemberships(sm.ctx, sm.ds, checkpoint, ch.Height); err != nil {
+		return xerrors.Errorf("%v failed to persist memberships: %w", sm.id, err)
+	}
+	if err := putBatchIndexEntry(sm.ctx, sm.ds, checkpoint, ch.Height); err != nil {
+		return xerrors.Errorf("%v failed to index batches: %w", sm.id, err)
+	}
+
 	return nil
 }
 
 // ApplyTXs applies transactions received from the availability layer to the app state
 // and creates a Lotus block from the delivered batch.
 func (sm *StateManager) ApplyTXs(txs []*mirproto.Transaction) error {
-	log.With("validator", sm.id).Info("ApplyTXs started")
-	defer log.With("validator", sm.id).Info("ApplyTXs finished")
-
 	var (
 		mirMsgs    []Message
 		valSetMsgs []*types.SignedMessage
-		err        error
+		// Validator set of the last SetMembership message of the block, if any.
+		votedSet *validator.Set
+		// Number of SetMembership messages in the block.
+		setMembershipMsgs int
+		err               error
 	)
 
+	sm.stalls.delivered(sm.frozen())
+	if sm.frozen() {
+		log.With("validator", sm.id).Debugf("subnet frozen at %d: batch of %d txs dropped", sm.FreezeHeight(), len(txs))
+		return nil
+	}
+
 	sm.height++
+	mirMsgs = make([]Message, 0, len(txs))
+
+	// All the log lines of the batch are tagged with the same fields, so a batch can be traced
+	// from its proposal by the manager to the submission of its block.
+	l := log.With("validator", sm.id, "epoch", sm.currentEpoch, "height", sm.height, "batch", batchID(txs))
+	l.Info("ApplyTXs started")
+	defer l.Info("ApplyTXs finished")
+
+	sm.batchStats.record(sm.ctx, txs)
+	if err := sm.contributions.record(sm.ctx, uint64(sm.currentEpoch), sm.height, txs); err != nil {
+		l.Warnf("failed to record validator contributions: %v", err)
+	}
 
 	// Include initial configuration and subnet initialization into the block 1.
 	if sm.height == 1 {
 		info := sm.confManager.GetInitialMembershipInfo()
-		initialConfigMsg, err := membership.NewSetMembershipMsg(genesis.DefaultIPCGatewayAddr, info.ValidatorSet)
+		initialConfigMsg, err := membership.NewSetMembershipMsg(genesis.DefaultIPCGatewayAddr, info.ValidatorSet, sm.height, setMembershipMsgs)
 		if err != nil {
 			return xerrors.Errorf("error setting initial on-chain membership: %w", err)
 		}
-		valSetMsgs = append(valSetMsgs, initialConfigMsg)
+		valSetMsgs = append(valSetMsgs, sm.configMsg(initialConfigMsg, membership.SetMembershipMsgKind))
+		votedSet = info.ValidatorSet
+		setMembershipMsgs++
 		// the rootnet doesn't need to be explicitly initialized.
 		sn, err := sdk.NewSubnetIDFromString(string(sm.netName))
 		if err != nil {
 			return xerrors.Errorf("error getting subnetID from network name: %w", err)
 		}
 		if !sn.IsRoot() {
-			initializeMsg, err := membership.NewInitGenesisEpochMsg(genesis.DefaultIPCGatewayAddr, sm.genesisEpoch)
+			initializeMsg, err := membership.NewInitGenesisEpochMsg(genesis.DefaultIPCGatewayAddr, sm.genesisEpoch, sm.height)
 			if err != nil {
 				return xerrors.Errorf("error initializing subnet: %w", err)
 			}
-			valSetMsgs = append(valSetMsgs, initializeMsg)
+			valSetMsgs = append(valSetMsgs, sm.configMsg(initializeMsg, membership.InitGenesisEpochMsgKind))
 		}
 	}
 
@@ -327,6 +608,9 @@ func (sm *StateManager) ApplyTXs(txs []*mirproto.Transaction) error {
 		switch tx.Type {
 		case TransportTransaction:
 			mirMsgs = append(mirMsgs, tx.Data)
+			if sm.encryptedClient != nil && tx.ClientId == sm.encryptedClient.clientID {
+				sm.encryptedClient.Delivered(tx)
+			}
 		case ConfigurationTransaction:
 			votedValSet, err := sm.applyConfigTx(tx)
 			if err != nil {
@@ -335,11 +619,21 @@ func (sm *StateManager) ApplyTXs(txs []*mirproto.Transaction) error {
 			if votedValSet != nil {
 				// FIXME: We should pick up the genesis address and not use the default one
 				// once we move into the user-defined gateway territory
-				reconfigMsg, err := membership.NewSetMembershipMsg(genesis.DefaultIPCGatewayAddr, votedValSet)
+				reconfigMsg, err := membership.NewSetMembershipMsg(genesis.DefaultIPCGatewayAddr, votedValSet, sm.height, setMembershipMsgs)
 				if err != nil {
 					return err
 				}
-				valSetMsgs = append(valSetMsgs, reconfigMsg)
+				valSetMsgs = append(valSetMsgs, sm.configMsg(reconfigMsg, membership.SetMembershipMsgKind))
+				votedSet = votedValSet
+				setMembershipMsgs++
+			}
+		case ShutdownTransaction:
+			if err := sm.applyShutdownTx(tx); err != nil {
+				return err
+			}
+		case TimestampTransaction:
+			if sm.upgrades.batchTimestamps(sm.height) {
+				sm.applyTimestampTx(tx)
 			}
 		}
 	}
@@ -348,21 +642,24 @@ func (sm *StateManager) ApplyTXs(txs []*mirproto.Transaction) error {
 		return nil
 	}
 
-	base, err := sm.api.ChainGetTipSetByHeight(sm.ctx, sm.height-1, types.EmptyTSK)
-	if err != nil {
-		return xerrors.Errorf("validator %v failed to get chain head: %w", sm.id, err)
+	msgs := sm.getSignedMessages(l, mirMsgs)
+	if sm.upgrades.encryptedTxs(sm.height) {
+		if err := sm.storeSealedMessages(); err != nil {
+			return xerrors.Errorf("validator %v failed to store sealed messages: %w", sm.id, err)
+		}
 	}
-	log.With("validator", sm.id).Debugf("Trying to mine new block over base: %s", base.Key())
-
-	msgs := sm.getSignedMessages(mirMsgs)
-	log.With("validator", sm.id).With("epoch", sm.currentEpoch).
-		With("height", sm.height).Infof("try to create a block: msgs - %d", len(msgs))
+	l.Infof("try to create a block: msgs - %d", len(msgs))
 
 	// include checkpoint in VRF proof field?
 	vrfCheckpoint := &ltypes.Ticket{VRFProof: nil}
 	eproofCheckpoint := &ltypes.ElectionProof{}
 	if ch := sm.pollCheckpoint(); ch != nil {
-		eproofCheckpoint, err = CertAsElectionProof(ch)
+		compact := sm.upgrades.compactCerts(sm.height)
+		if compact {
+			eproofCheckpoint, err = CompactCertAsElectionProof(ch)
+		} else {
+			eproofCheckpoint, err = CertAsElectionProof(ch)
+		}
 		if err != nil {
 			return xerrors.Errorf("validator %v failed to set eproof from checkpoint certificate: %w", sm.id, err)
 		}
@@ -371,46 +668,130 @@ func (sm *StateManager) ApplyTXs(txs []*mirproto.Transaction) error {
 		if err != nil {
 			return xerrors.Errorf("validator %v failed to set vrfproof from checkpoint: %w", sm.id, err)
 		}
-		log.With("validator", sm.id).Infof("Including Mir checkpoint for in block %d", sm.height)
+		if sm.upgrades.taggedHeaders(sm.height) {
+			TagHeaderExtensions(vrfCheckpoint, eproofCheckpoint, compact)
+		}
+		l.Infof("Including Mir checkpoint for in block %d", sm.height)
+
+		// Run the subnet cron jobs at the checkpoint boundary.
+		cronMsgs, err := subnetcron.Messages(string(sm.netName), sm.height)
+		if err != nil {
+			return xerrors.Errorf("validator %v failed to create subnet cron messages: %w", sm.id, err)
+		}
+		valSetMsgs = append(valSetMsgs, cronMsgs...)
 	}
 
 	// Include config messages into the block to update on-chain membership.
 	msgs = append(msgs, valSetMsgs...)
+	stats.Record(sm.ctx, metrics.MirBlockMessages.M(int64(len(msgs))))
+
+	return sm.executeBlock(&blockDraft{
+		l:               l,
+		txs:             txs,
+		height:          sm.height,
+		msgs:            msgs,
+		ticket:          vrfCheckpoint,
+		eproof:          eproofCheckpoint,
+		votedSet:        votedSet,
+		batchTimestamps: sm.upgrades.batchTimestamps(sm.height),
+		timestamps:      sm.memberTimestamps(),
+		validators:      len(sm.memberships[sm.currentEpoch].Nodes),
+	})
+}
+
+// createBlock creates the block of the draft on top of its parent, and submits it to the node.
+func (sm *StateManager) createBlock(d *blockDraft) error {
+	l := d.l
+	base, err := sm.api.ChainGetTipSetByHeight(sm.ctx, d.height-1, types.EmptyTSK)
+	if err != nil {
+		return xerrors.Errorf("validator %v failed to get chain head: %w", sm.id, err)
+	}
+	l.Debugf("Trying to mine new block over base: %s", base.Key())
 
-	bh, err := sm.api.MinerCreateBlock(sm.ctx, &lapi.BlockTemplate{
+	if _, err := sm.gatewayMembership.check(sm.ctx, base); err != nil {
+		l.Warnf("failed to check the gateway membership: %v", err)
+	}
+
+	var beaconValues []ltypes.BeaconEntry
+	if includeBeaconEntry(base.Blocks()[0], d.height, sm.checkpointRandomness) {
+		entry := NextBeaconEntry(latestBeaconEntry(base.Blocks()[0]), d.height, d.ticket, d.eproof)
+		beaconValues = append(beaconValues, entry)
+	}
+
+	bh, err := sm.blockCreator.CreateBlock(sm.ctx, &lapi.BlockTemplate{
 		// mir blocks are created by all miners. We use system actor as miner of the block
 		Miner:            builtin.SystemActorAddr,
 		Parents:          base.Key(),
-		BeaconValues:     nil,
-		Ticket:           vrfCheckpoint,
-		Eproof:           eproofCheckpoint,
-		Epoch:            sm.height,
-		Timestamp:        uint64(sm.height),
+		BeaconValues:     beaconValues,
+		Ticket:           d.ticket,
+		Eproof:           d.eproof,
+		Epoch:            d.height,
+		Timestamp:        blockTimestamp(d.batchTimestamps, d.height, base.MinTimestamp(), d.timestamps, d.validators),
 		WinningPoStProof: nil,
-		Messages:         msgs,
+		Messages:         d.msgs,
 	})
 	if err != nil {
 		return xerrors.Errorf("validator %v failed to create a block: %w", sm.id, err)
 	}
 	if bh == nil {
-		log.With("validator", sm.id).With("epoch", sm.height).Debug("created a nil block")
+		l.Debug("created a nil block")
 		return nil
 	}
 
-	err = sm.api.SyncSubmitBlock(sm.ctx, &types.BlockMsg{
+	blkMsg := &types.BlockMsg{
 		Header:        bh.Header,
 		BlsMessages:   bh.BlsMessages,
 		SecpkMessages: bh.SecpkMessages,
-	})
-	if err != nil {
+	}
+	if err := submitBlock(sm.ctx, sm.api, sm.ds, blkMsg); err != nil {
 		return xerrors.Errorf("validator %v unable to sync a block: %w", sm.id, err)
 	}
-	log.With("validator", sm.id).With("epoch", sm.currentEpoch).Infof("mined block %d : %v ", bh.Header.Height, bh.Header.Cid())
+	sm.watchdog.submitted(blkMsg)
+	l.Infof("mined block %d : %v ", bh.Header.Height, bh.Header.Cid())
+	sm.blocks.add(bh.Header.Height, bh.Header.Cid())
+	if sm.onMsgEvent != nil {
+		for _, msg := range d.msgs {
+			msgEvent(sm.onMsgEvent, msg.Cid(), MsgIncluded, bh.Header.Height)
+		}
+	}
+	if d.votedSet != nil {
+		sm.gatewayMembership.expect(bh.Header.Height, d.votedSet)
+	}
+	if sm.onBlock != nil {
+		sm.onBlock(bh.Header.Height)
+	}
+	if sm.onBlockProvenance != nil {
+		sm.onBlockProvenance(BlockProvenance{
+			Height:    bh.Header.Height,
+			Block:     bh.Header.Cid(),
+			Batch:     batchID(d.txs),
+			Proposers: batchProposers(d.txs),
+		})
+	}
 
 	return nil
 }
 
+// configMsg gives the configuration message of the given kind its legacy nonce in the blocks below the
+// ConfigMsgNonces upgrade, so they are the same as the blocks of the validators that predate it.
+func (sm *StateManager) configMsg(msg *ltypes.SignedMessage, kind membership.ConfigMsgKind) *ltypes.SignedMessage {
+	if !sm.upgrades.configMsgNonces(sm.height) {
+		msg.Message.Nonce = membership.LegacyConfigMsgNonce(kind)
+	}
+	return msg
+}
+
 func (sm *StateManager) applyConfigTx(tx *mirproto.Transaction) (*validator.Set, error) {
+	if isCancelledConfigurationTx(tx) {
+		// The transaction was cancelled by the validator that sent it, there is nothing to vote for.
+		if tx.ClientId == trantor.ClientID(sm.id) {
+			if err := sm.confManager.Done(tx.TxNo); err != nil {
+				log.With("validator", sm.id).Errorf("failed to mark cancelled config message as done: %v", err)
+			}
+		}
+		return nil, nil
+	}
+
 	var valSet validator.Set
 	if err := valSet.UnmarshalCBOR(bytes.NewReader(tx.Data)); err != nil {
 		return nil, err
@@ -451,6 +832,11 @@ func (sm *StateManager) updateNextMembership(set *validator.Set) error {
 	if err != nil {
 		return err
 	}
+	if isReweighting(sm.nextNewMembership, mbs) {
+		log.With("validator", sm.id).
+			Infof("updateNextMembership: configuration %d re-weights the validators from epoch %d",
+				set.ConfigurationNumber, sm.membershipEffectiveEpoch())
+	}
 	sm.nextNewMembership = mbs
 	log.With("validator", sm.id).
 		Infof("updateNextMembership: current epoch %d, config number %d, next membership size: %d",
@@ -472,34 +858,78 @@ func (sm *StateManager) processVote(votingValidator t.NodeID, set *validator.Set
 		return false, false, xerrors.Errorf("validator %s is not in the membership", votingValidator)
 	}
 
-	h, err := set.Hash()
+	canonical := sm.upgrades.canonicalSetHashes(sm.height)
+	h, err := voteKey(set, canonical)
 	if err != nil {
 		return false, false, err
 	}
+	// The votes applied before the upgrade are keyed by the legacy hash of the set.
+	if legacy, err := voteKey(set, false); canonical && err == nil &&
+		sm.configurationVotes.MigrateVotes(set.ConfigurationNumber, legacy, h) {
+		log.With("validator", sm.id).Infof("migrated votes for configuration %d to the canonical hash", set.ConfigurationNumber)
+	}
 
-	if err := sm.configurationVotes.VoteForConfiguration(set.ConfigurationNumber, string(h), votingValidator); err != nil {
+	err = sm.confManager.ApplyVote(sm.configurationVotes, set.ConfigurationNumber, h, votingValidator)
+	switch {
+	case errors.Is(err, ErrInvalidVote) || errors.Is(err, ErrDuplicateVote):
 		return false, false, err
+	case err != nil:
+		log.With("validator", sm.id).
+			Errorf("countVote: failed to store votes in epoch %d: %v", sm.currentEpoch, err)
 	}
-	if err := sm.confManager.StoreConfigurationVotes(sm.configurationVotes.Votes()); err != nil {
+
+	voters := sm.configurationVotes.Votes()[set.ConfigurationNumber][h]
+	if !sm.upgrades.weightedQuorums(sm.height) {
+		nodes := len(sm.memberships[sm.currentEpoch].Nodes)
 		log.With("validator", sm.id).
-			Error("countVote: failed to store votes in epoch %d: %w", sm.currentEpoch, err)
+			Infof("countVote: valset number %d, epoch %d: votes %d, nodes %d",
+				set.ConfigurationNumber, sm.currentEpoch, len(voters), nodes)
+		// Before the weighted quorums upgrade, f+1 votes are enough.
+		return len(voters) >= weakQuorum(nodes), len(voters) > weakQuorum(nodes), nil
 	}
 
-	votes := sm.configurationVotes.GetVotesForConfiguration(set.ConfigurationNumber, string(h))
-	nodes := len(sm.memberships[sm.currentEpoch].Nodes)
+	// Votes are weighted by the weights of the validators in the current membership.
+	weights, total := voteWeights(sm.memberships[sm.currentEpoch])
+	voted := votedWeight(weights, voters)
+	before := new(big.Int).Sub(voted, weights[votingValidator])
 	log.With("validator", sm.id).
-		Infof("countVote: valset number %d, epoch %d: votes %d, nodes %d",
-			set.ConfigurationNumber, sm.currentEpoch, votes, nodes)
+		Infof("countVote: valset number %d, epoch %d: votes %d, voted weight %s of %s",
+			set.ConfigurationNumber, sm.currentEpoch, len(voters), voted, total)
 
-	// We must have f+1 votes at least.
-	switch {
-	case votes == weakQuorum(nodes):
-		return true, false, nil
-	case votes > weakQuorum(nodes):
-		return true, true, nil
-	default:
-		return false, false, nil
+	// Validators holding more than 2/3 of the total weight must vote for the set.
+	// The voting is finished if the quorum was reached before this vote.
+	enough := hasWeightQuorum(voted, total)
+	return enough, enough && hasWeightQuorum(before, total), nil
+}
+
+// StuckHead returns a channel that receives an error if the chain head doesn't reach the blocks
+// submitted by the validator, even after resubmitting them.
+func (sm *StateManager) StuckHead() <-chan error {
+	return sm.watchdog.Stuck()
+}
+
+// Diverged returns a channel that receives the latest checkpoint if the chain of the node doesn't include
+// the blocks it commits.
+func (sm *StateManager) Diverged() <-chan *Checkpoint {
+	return sm.divergence.Diverged()
+}
+
+// recoverFromCheckpoint drops the chain of the node after the checkpoint and syncs the blocks
+// committed by the checkpoint from the peers.
+func (sm *StateManager) recoverFromCheckpoint(ch *Checkpoint) error {
+	if err := sm.api.SyncPurgeForRecovery(sm.ctx, ch.Height); err != nil {
+		return xerrors.Errorf("validator %v couldn't purge state to recover from checkpoint: %w", sm.id, err)
+	}
+	if err := sm.syncToCheckpoint(types.NewTipSetKey(ch.BlockCids[0])); err != nil {
+		return xerrors.Errorf("validator %v couldn't sync from peers for checkpoint at height %d: %w", sm.id, ch.Height, err)
 	}
+	return nil
+}
+
+// OrderedEpoch returns the number of the Mir epoch the validator is ordering transactions in.
+// All the validators agree on the epoch of a batch, as epochs are delimited in the ordered log.
+func (sm *StateManager) OrderedEpoch() trantor.EpochNr {
+	return trantor.EpochNr(sm.orderedEpoch.Load())
 }
 
 func (sm *StateManager) NewEpoch(nr trantor.EpochNr) (*mirproto.Membership, error) {
@@ -520,10 +950,21 @@ func (sm *StateManager) NewEpoch(nr trantor.EpochNr) (*mirproto.Membership, erro
 
 	// Update current epoch number.
 	sm.currentEpoch = nr
+	sm.orderedEpoch.Store(uint64(nr))
 
 	// Garbage-collect previous membership and old voting data.
 	// Note that at initialization and after state transfer, these entries do not exist.
 	delete(sm.memberships, sm.currentEpoch-1)
+	sm.status.setMemberships(sm.currentEpoch, sm.memberships)
+
+	// Persist the memberships known from now on, so they outlive the in-memory ones.
+	var mbs []*mirproto.Membership
+	for e := nr; sm.memberships[e] != nil; e++ {
+		mbs = append(mbs, sm.memberships[e])
+	}
+	if err := putMemberships(sm.ctx, sm.ds, nr, 0, mbs); err != nil {
+		return nil, xerrors.Errorf("validator %v failed to persist memberships: %w", sm.id, err)
+	}
 
 	log.With("validator", sm.id).
 		Debugf("New epoch result: current epoch %d, current membership size %d, next membership size: %d, height: %d",
@@ -548,6 +989,11 @@ func (sm *StateManager) Snapshot() ([]byte, error) {
 	nextHeight := sm.height + 1
 	log.With("validator", sm.id).Infof("Snapshot started: epoch - %d, height - %d", sm.currentEpoch, sm.height)
 
+	sealed, err := sm.sealedMsgs.records()
+	if err != nil {
+		return nil, xerrors.Errorf("snapshot: validator %v failed to get sealed messages: %w", sm.id, err)
+	}
+
 	// populating checkpoint template
 	ch := Checkpoint{
 		Height:           nextHeight,
@@ -555,7 +1001,13 @@ func (sm *StateManager) Snapshot() ([]byte, error) {
 		BlockCids:        make([]cid.Cid, 0),
 		NextConfigNumber: sm.nextConfigurationNumber,
 		Votes:            sm.configurationVotes.GetVoteRecords(),
+		SealedMsgs:       sealed,
 	}
+	// Only the timestamps of the current validators are kept.
+	sm.pruneTimestamps()
+	ch.Ext.Timestamps = timestampRecords(sm.timestamps)
+	ch.Ext.FreezeHeight = sm.FreezeHeight()
+	ch.Ext.ShutdownVotes = shutdownVoteRecords(sm.shutdownVotes)
 
 	// put blocks in descending order.
 	i := nextHeight - 1
@@ -567,14 +1019,12 @@ func (sm *StateManager) Snapshot() ([]byte, error) {
 	}
 
 	for i >= sm.prevCheckpoint.Height {
-		ts, err := sm.api.ChainGetTipSetByHeight(sm.ctx, i, types.EmptyTSK)
+		c, err := sm.blockCid(i)
 		if err != nil {
 			return nil, xerrors.Errorf("snapshot: validator %v failed to get tipset of height: %d: %w", sm.id, i, err)
 		}
-		// In Mir tipsets have a single block, so we can access directly the block for
-		// the tipset by accessing the first position.
-		ch.BlockCids = append(ch.BlockCids, ts.Blocks()[0].Cid())
-		log.With("validator", sm.id).Infof("Getting Cid for block height %d and cid %s to include in snapshot", i, ts.Blocks()[0].Cid())
+		ch.BlockCids = append(ch.BlockCids, c)
+		log.With("validator", sm.id).Debugf("Getting Cid for block height %d and cid %s to include in snapshot", i, c)
 		i--
 	}
 
@@ -582,10 +1032,30 @@ func (sm *StateManager) Snapshot() ([]byte, error) {
 	if err != nil {
 		return nil, xerrors.Errorf("snapshot: validator %v failed to serialize checkpoint: %w", sm.id, err)
 	}
+	if sm.compressCheckpoints {
+		b = compression.Compress(b)
+	}
 	log.With("validator", sm.id).Infof("Snapshot finished: epoch - %d, height - %d", sm.currentEpoch, sm.height)
 	return b, nil
 }
 
+// blockCid returns the CID of the block at height h, looking it up in the chain if the block
+// was not created by the validator since it started.
+func (sm *StateManager) blockCid(h abi.ChainEpoch) (cid.Cid, error) {
+	if c, ok := sm.blocks.get(h); ok {
+		return c, nil
+	}
+	ts, err := sm.api.ChainGetTipSetByHeight(sm.ctx, h, types.EmptyTSK)
+	if err != nil {
+		return cid.Undef, err
+	}
+	// In Mir tipsets have a single block, so we can access directly the block for
+	// the tipset by accessing the first position.
+	c := ts.Blocks()[0].Cid()
+	sm.blocks.add(h, c)
+	return c, nil
+}
+
 // Checkpoint is triggered by Mir when the committee agrees on the next checkpoint.
 // We persist the checkpoint locally so we can restore from it after a restart
 // or a crash and delivers it to the mining process to include it in the next block.
@@ -601,11 +1071,17 @@ func (sm *StateManager) Checkpoint(checkpoint *checkpoint.StableCheckpoint) erro
 	if err := ch.FromBytes(checkpoint.Snapshot.AppData); err != nil {
 		return xerrors.Errorf("validator %v failed to get checkpoint data from mir checkpoint: %w", sm.id, err)
 	}
+	if err := ch.validateWithLimit(sm.maxCheckpointBlocks); err != nil {
+		return xerrors.Errorf("validator %v refuses checkpoint: %w", sm.id, err)
+	}
 	log.With("validator", sm.id).Infof("Mir generated new checkpoint for height: %d", ch.Height)
 
 	if err := sm.deliverCheckpoint(checkpoint, ch); err != nil {
 		return xerrors.Errorf("validator %v failed to deliver checkpoint: %w", sm.id, err)
 	}
+	if sm.frozen() {
+		log.With("validator", sm.id).Warnf("final checkpoint of the frozen subnet delivered for height: %d", ch.Height)
+	}
 
 	// Reset fifo between checkpoints to avoid txs getting stuck.
 	// See https://github.com/consensus-shipyard/lotus/issues/28.
@@ -625,7 +1101,7 @@ func (sm *StateManager) deliverCheckpoint(checkpoint *checkpoint.StableCheckpoin
 	}
 
 	// persist the stable checkpoint to initialize mir from it if needed
-	b, err := checkpoint.Serialize()
+	b, err := serializeCheckpoint(checkpoint, sm.compressCheckpoints)
 	if err != nil {
 		return xerrors.Errorf("error marshaling stable checkpoint: %w", err)
 	}
@@ -645,6 +1121,15 @@ func (sm *StateManager) deliverCheckpoint(checkpoint *checkpoint.StableCheckpoin
 		return xerrors.Errorf("error computing cid for checkpoint: %w", err)
 	}
 	sm.prevCheckpoint = ParentMeta{Height: snapshot.Height, Cid: c}
+	sm.status.setCheckpointHeight(snapshot.Height)
+	if err := putCheckpointMemberships(sm.ctx, sm.ds, checkpoint, snapshot.Height); err != nil {
+		return err
+	}
+	if err := putBatchIndexEntry(sm.ctx, sm.ds, checkpoint, snapshot.Height); err != nil {
+		return err
+	}
+	// The blocks before the checkpoint won't be included in snapshots anymore.
+	sm.blocks.prune(snapshot.Height)
 
 	// store metadata for previous snapshot in datastore and manager to
 	// perform additional verifications
@@ -652,62 +1137,147 @@ func (sm *StateManager) deliverCheckpoint(checkpoint *checkpoint.StableCheckpoin
 		return xerrors.Errorf("error flushing latest checkpoint in datastore: %w", err)
 	}
 
-	// optionally persist the checkpoint in a file
-	// (this is a best-effort process, if it fails we shouldn't kill the process)
-	// in the future we could add a flag that makes persistence STRICT to notify
-	// that this process should fail if persisting to file fails.
+	// optionally persist the checkpoint in a file.
+	// Unless persistence is strict, this is a best-effort process taken out of the critical path,
+	// and the validator keeps running if it fails. With strict persistence, the checkpoint is
+	// synced to disk before it is delivered, and the validator stops producing blocks if it can't be.
 	if sm.checkpointRepo != "" {
-		// wrapping it in a routine to take it out of the critical path.
+		f := path.Join(sm.checkpointRepo, CheckpointFileName(snapshot.Height))
+		if sm.strictCheckpoints {
+			if err := syncCheckpointFile(b, f); err != nil {
+				return xerrors.Errorf("error persisting checkpoint for height %d in path %s: %w", snapshot.Height, f, err)
+			}
+		} else {
+			go func() {
+				if err := serializedCheckToFile(b, f); err != nil {
+					log.Errorf("error persisting checkpoint for height %d in path %s: %s", snapshot.Height, f, err)
+				}
+			}()
+		}
+	}
+
+	// Exporting the state takes long, so a checkpoint is skipped if the previous one is still being exported.
+	if sm.stateSnapshotRepo != "" && sm.exportingState.CompareAndSwap(false, true) {
 		go func() {
-			f := path.Join(sm.checkpointRepo, "checkpoint-"+snapshot.Height.String()+".chkp")
-			if err := serializedCheckToFile(b, f); err != nil {
-				log.Errorf("error persisting checkpoint for height %d in path %s: %s", snapshot.Height, f, err)
+			defer sm.exportingState.Store(false)
+			// The state can only be exported once the node has applied the blocks committed by the checkpoint.
+			if err := sm.heads.WaitForHeight(sm.ctx, snapshot.Height-1); err != nil {
+				return
+			}
+			if err := exportStateSnapshot(sm.ctx, sm.api, sm.stateSnapshotRepo, snapshot); err != nil {
+				log.With("validator", sm.id).Errorf("error exporting state snapshot for height %d: %v", snapshot.Height, err)
 			}
 		}()
 	}
 
+	if sm.onCheckpoint != nil {
+		n, err := newCheckpointNotification(checkpoint, snapshot, c)
+		if err != nil {
+			log.With("validator", sm.id).Errorf("failed to notify checkpoint for height %d: %v", snapshot.Height, err)
+		} else {
+			sm.onCheckpoint(n)
+		}
+	}
+
 	// Send the checkpoint to Lotus and handle it there
 	log.With("validator", sm.id).Debug("Sending checkpoint to mining process to include in block")
 	sm.nextCheckpointChan <- checkpoint
 	return nil
 }
 
-func (sm *StateManager) getSignedMessages(mirMsgs []Message) (msgs []*types.SignedMessage) {
-	log.With("validator", sm.id).With("epoch", sm.currentEpoch).
-		Infof("received a block with %d messages", len(mirMsgs))
+func (sm *StateManager) getSignedMessages(l *zap.SugaredLogger, mirMsgs []Message) (msgs []*types.SignedMessage) {
+	l.Infof("received a block with %d messages", len(mirMsgs))
+	msgs = make([]*types.SignedMessage, 0, len(mirMsgs))
 	for _, tx := range mirMsgs {
 		input, err := parseTx(tx)
 		if err != nil {
-			log.With("validator", sm.id).Error("unable to decode a message in Mir block:", err)
+			l.Error("unable to decode a message in Mir block:", err)
 			continue
 		}
 
 		switch msg := input.(type) {
 		case *types.SignedMessage:
 			// batch being processed, remove from mpool
-			found := sm.txPool.DeleteTx(msg.Cid(), msg.Message.Nonce)
+			c := msg.Cid()
+			found := sm.txPool.DeleteTx(c, msg.Message.Nonce)
 			if !found {
-				log.With("validator", sm.id).
-					Debugf("unable to find a message with %v hash in our local fifo.Pool", msg.Cid())
+				l.Debugf("unable to find a message with %v hash in our local fifo.Pool", msg.Cid())
 				// TODO: If we try to remove something from the pool, we should remember that
 				// we already tried to remove that to avoid adding as it may lead to a deadlock.
 				// FIFO should be updated because we don't have the support for in-flight supports.
 				// continue
 			}
 			msgs = append(msgs, msg)
-			log.With("validator", sm.id).Infof("got message: to=%s, nonce= %d", msg.Message.To, msg.Message.Nonce)
+			msgEvent(sm.onMsgEvent, c, MsgOrdered, sm.height)
+			l.Infof("got message: to=%s, nonce= %d", msg.Message.To, msg.Message.Nonce)
+		case *EncryptedMessage:
+			if !sm.upgrades.encryptedTxs(sm.height) {
+				l.Warn("encrypted message received before the EncryptedTxs upgrade")
+				continue
+			}
+			sm.sealedMsgs.add(msg, sm.height)
+		case *KeyReveal:
+			if !sm.upgrades.encryptedTxs(sm.height) {
+				l.Warn("key reveal received before the EncryptedTxs upgrade")
+				continue
+			}
+			decrypted, err := sm.sealedMsgs.open(msg, sm.height)
+			if err != nil {
+				l.Warnf("unable to open encrypted message: %v", err)
+				continue
+			}
+			msgs = append(msgs, decrypted)
+			l.Infof("got decrypted message: to=%s, nonce= %d", decrypted.Message.To, decrypted.Message.Nonce)
 		default:
-			log.With("validator", sm.id).Error("unknown message type in a block")
+			l.Error("unknown message type in a block")
 		}
 	}
 
+	if sm.upgrades.encryptedTxs(sm.height) {
+		sm.sealedMsgs.prune(sm.height)
+	}
+
 	sort.Slice(msgs, func(i, j int) bool {
 		return msgs[i].Message.Nonce < msgs[j].Message.Nonce
 	})
 
+	// The batch of a faulty validator, or the messages revealed in the same block, may not fit in the
+	// block. All the validators use the same limit, so they drop the same messages.
+	if limited := limitGas(msgs, subnetparams.BlockGasLimit(string(sm.netName))); len(limited) < len(msgs) {
+		l.Warnf("dropped %d messages exceeding the block gas limit", len(msgs)-len(limited))
+		msgs = limited
+	}
+
 	return
 }
 
+func (sm *StateManager) storeSealedMessages() error {
+	r, err := sm.sealedMsgs.records()
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := r.MarshalCBOR(buf); err != nil {
+		return err
+	}
+	return sm.ds.Put(sm.ctx, SealedMessagesKey, buf.Bytes())
+}
+
+func (sm *StateManager) loadSealedMessages() (*sealedMessages, error) {
+	b, err := sm.ds.Get(sm.ctx, SealedMessagesKey)
+	if errors.Is(err, datastore.ErrNotFound) {
+		return newSealedMessages(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r SealedMessageRecords
+	if err := r.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
+	return newSealedMessagesFromRecords(r)
+}
+
 func HeightCheckIndexKey(epoch abi.ChainEpoch) datastore.Key {
 	return datastore.NewKey(CheckpointDBKeyPrefix + epoch.String())
 }
@@ -728,6 +1298,12 @@ func weakQuorum(n int) int {
 	return maxFaulty(n) + 1
 }
 
+func strongQuorum(n int) int {
+	// assuming n > 3f:
+	//   return min q: 2q > n+f
+	return n - maxFaulty(n)
+}
+
 // pollCheckpoint listens to new available checkpoints to be
 // added in lotus blocks.
 func (sm *StateManager) pollCheckpoint() *checkpoint.StableCheckpoint {
@@ -757,7 +1333,7 @@ func (sm *StateManager) waitForHeightWithTimeout(timeout time.Duration, height a
 	log.With("validator", sm.id).Debugf("waitForHeight %v started", height)
 	defer log.With("validator", sm.id).Debugf("waitForHeight %v finished", height)
 
-	base, err := sm.api.ChainHead(sm.ctx)
+	base, err := sm.heads.Head(sm.ctx)
 	if err != nil {
 		return xerrors.Errorf("failed to get chain head: %w", err)
 	}
@@ -769,7 +1345,7 @@ func (sm *StateManager) waitForHeightWithTimeout(timeout time.Duration, height a
 	ctx, cancel := context.WithTimeout(sm.ctx, timeout)
 	defer cancel()
 
-	if err := WaitForHeight(ctx, height, sm.api); err != nil {
+	if err := sm.heads.WaitForHeight(ctx, height); err != nil {
 		return xerrors.Errorf("failed to wait for a block: %w", err)
 	}
 	return nil
@@ -779,7 +1355,7 @@ func (sm *StateManager) waitForHeight(height abi.ChainEpoch) error {
 	log.With("validator", sm.id).Infof("waitForHeight %v started", height)
 	defer log.With("validator", sm.id).Infof("waitForHeight %v finished", height)
 
-	if err := WaitForHeight(sm.ctx, height, sm.api); err != nil {
+	if err := sm.heads.WaitForHeight(sm.ctx, height); err != nil {
 		return xerrors.Errorf("failed to wait for a block: %w", err)
 	}
 	return nil
@@ -832,6 +1408,10 @@ func parseTx(tx []byte) (interface{}, error) {
 		msg, err = types.DecodeSignedMessage(tx[:ln-1])
 	case ConfigMessageType:
 		return nil, fmt.Errorf("config message is not supported")
+	case EncryptedMessageType:
+		msg, err = DecodeEncryptedMessage(tx[:ln-1])
+	case KeyRevealMessageType:
+		msg, err = DecodeKeyReveal(tx[:ln-1])
 	default:
 		err = fmt.Errorf("unknown message type %d", lastByte)
 	}
@@ -844,31 +1424,49 @@ func parseTx(tx []byte) (interface{}, error) {
 }
 
 // WaitForHeight waits for the syncer to see as the head of the chain the block for the height determined as an input.
+// It follows the head changes of the node with ChainNotify instead of polling ChainHead, and subscribes again if
+// the subscription is closed, until the head reaches the height or ctx is done.
 func WaitForHeight(ctx context.Context, height abi.ChainEpoch, api v1api.FullNode) error {
-	// get base to determine the gap to sync and configure timeout.
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	base, err := api.ChainHead(ctx)
-	if err != nil {
-		return err
-	}
-	head := base.Height()
+	// Cancelling the context closes the subscription.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	for {
-		if head >= height {
-			return nil
+		notifs, err := api.ChainNotify(ctx)
+		if err != nil {
+			return xerrors.Errorf("failed to subscribe to head changes: %w", err)
+		}
+		reached, err := waitForApply(ctx, notifs, height)
+		if reached || err != nil {
+			return err
 		}
 
 		select {
 		case <-ctx.Done():
 			return xerrors.Errorf("context cancelled while waiting for height %v", height)
-		default:
-			base, err := api.ChainHead(ctx)
-			if err != nil {
-				return err
+		case <-time.After(HeadResubscribeInterval):
+		}
+	}
+}
+
+// waitForApply waits for notifs to report a head at height or above. It returns false if notifs is closed first.
+func waitForApply(ctx context.Context, notifs <-chan []*lapi.HeadChange, height abi.ChainEpoch) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, xerrors.Errorf("context cancelled while waiting for height %v", height)
+		case changes, ok := <-notifs:
+			if !ok {
+				return false, nil
+			}
+			for _, c := range changes {
+				if (c.Type == store.HCCurrent || c.Type == store.HCApply) && c.Val.Height() >= height {
+					return true, nil
+				}
 			}
-			head = base.Height()
 		}
 	}
 }
@@ -0,0 +1,100 @@
+package mir
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	trantor "github.com/filecoin-project/mir/pkg/trantor/types"
+	mirtypes "github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/mocks"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestSyncToCheckpointFallback(t *testing.T) {
+	PeerDiscoveryTimeout = 10 * time.Millisecond
+	PeerDiscoveryInterval = time.Millisecond
+	ExchangeSyncMinBackoff = time.Millisecond
+	ExchangeSyncMaxBackoff = 2 * time.Millisecond
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	node := mocks.NewMockFullNode(ctrl)
+
+	ts := mock.TipSet(mock.MkBlock(nil, 1, 1))
+	nodes := map[mirtypes.NodeID]*mirproto.NodeIdentity{"id1": {}, "id2": {}}
+	sm := &StateManager{
+		ctx:         ctx,
+		id:          "id1",
+		api:         node,
+		heads:       newHeadTracker(ctx, node, "id1"),
+		memberships: map[trantor.EpochNr]*mirproto.Membership{0: {Nodes: nodes}},
+	}
+
+	// No peer is connected: the tipset is synced through the chain exchange once it succeeds.
+	node.EXPECT().NetPeers(gomock.Any()).Return([]peer.AddrInfo{}, nil).AnyTimes()
+	gomock.InOrder(
+		node.EXPECT().SyncCheckpoint(gomock.Any(), ts.Key()).Return(errors.New("no peers")).Times(2),
+		node.EXPECT().SyncCheckpoint(gomock.Any(), ts.Key()).Return(nil),
+	)
+	node.EXPECT().ChainGetTipSet(gomock.Any(), ts.Key()).Return(ts, nil)
+	node.EXPECT().ChainHead(gomock.Any()).Return(ts, nil).AnyTimes()
+	require.NoError(t, sm.syncToCheckpoint(ts.Key()))
+
+	// The chain exchange is given up after ExchangeSyncTimeout.
+	ExchangeSyncTimeout = 10 * time.Millisecond
+	node.EXPECT().SyncCheckpoint(gomock.Any(), types.EmptyTSK).Return(errors.New("not found")).MinTimes(1)
+	require.Error(t, sm.syncToCheckpoint(types.EmptyTSK))
+}
+
+func TestWaitForHeight(t *testing.T) {
+	HeadResubscribeInterval = time.Millisecond
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	node := mocks.NewMockFullNode(ctrl)
+
+	var chain []*types.TipSet
+	var ts *types.TipSet
+	for i := 0; i <= 3; i++ {
+		ts = mock.TipSet(mock.MkBlock(ts, 1, 1))
+		chain = append(chain, ts)
+	}
+
+	// The current head is already at the height.
+	notifs := make(chan []*api.HeadChange, 1)
+	notifs <- []*api.HeadChange{{Type: store.HCCurrent, Val: chain[2]}}
+	node.EXPECT().ChainNotify(gomock.Any()).Return((<-chan []*api.HeadChange)(notifs), nil)
+	require.NoError(t, WaitForHeight(ctx, chain[1].Height(), node))
+
+	// The height is reached after a revert, and after the subscription is closed and restored.
+	first := make(chan []*api.HeadChange, 3)
+	first <- []*api.HeadChange{{Type: store.HCCurrent, Val: chain[0]}}
+	first <- []*api.HeadChange{{Type: store.HCRevert, Val: chain[3]}, {Type: store.HCApply, Val: chain[1]}}
+	close(first)
+	second := make(chan []*api.HeadChange, 2)
+	second <- []*api.HeadChange{{Type: store.HCCurrent, Val: chain[1]}}
+	second <- []*api.HeadChange{{Type: store.HCApply, Val: chain[3]}}
+	gomock.InOrder(
+		node.EXPECT().ChainNotify(gomock.Any()).Return((<-chan []*api.HeadChange)(first), nil),
+		node.EXPECT().ChainNotify(gomock.Any()).Return((<-chan []*api.HeadChange)(second), nil),
+	)
+	require.NoError(t, WaitForHeight(ctx, chain[3].Height(), node))
+
+	// The wait is given up when the context is done.
+	wctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	node.EXPECT().ChainNotify(gomock.Any()).Return(make(<-chan []*api.HeadChange), nil)
+	require.Error(t, WaitForHeight(wctx, chain[3].Height()+1, node))
+}
@@ -0,0 +1,300 @@
+package mir
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ipld/go-car"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api/v1api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// StateSnapshotProtocol is the libp2p protocol validators use to request from each other the state of the
+// chain exported at a checkpoint, so a new validator can restore the state at the checkpoint instead of
+// syncing and executing all the blocks before it.
+//
+// The request is the height of the checkpoint as an 8-byte big-endian integer. The response is a status
+// byte followed, if the snapshot was found, by the CAR file of the snapshot. Responses don't need to be
+// trusted: the snapshot must be rooted at the last block committed by the checkpoint, whose certificate
+// is verified by the requester, and every block of the snapshot is checked against its CID.
+const StateSnapshotProtocol = protocol.ID("/eudico/mir/state-snapshot/1.0.0")
+
+var (
+	// StateSnapshotTimeout is the time a peer has to send a state snapshot.
+	StateSnapshotTimeout = 30 * time.Minute
+	// StateSnapshotRoots is the number of state roots before the checkpoint included in the state snapshots.
+	StateSnapshotRoots = abi.ChainEpoch(build.Finality)
+	// MaxStateSnapshots is the number of state snapshots kept in the state snapshot repo of the validator.
+	MaxStateSnapshots = 2
+	// FastSyncThreshold is the number of blocks the chain head must be behind a checkpoint for the
+	// validator to restore the state at the checkpoint from a state snapshot.
+	FastSyncThreshold = abi.ChainEpoch(build.Finality)
+
+	ErrStateSnapshotNotFound = errors.New("state snapshot not found")
+)
+
+// StateSnapshotFileName returns the name of the file the state snapshot of the checkpoint at height h is
+// exported to.
+func StateSnapshotFileName(h abi.ChainEpoch) string {
+	return "state-" + h.String() + ".car"
+}
+
+// stateSnapshotHeights returns the heights of the checkpoints of the state snapshots in the repo, from the highest one.
+func stateSnapshotHeights(repo string) ([]abi.ChainEpoch, error) {
+	entries, err := os.ReadDir(repo)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("error reading state snapshot repo %s: %w", repo, err)
+	}
+	var heights []abi.ChainEpoch
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, "state-") || !strings.HasSuffix(name, ".car") {
+			continue
+		}
+		h, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, "state-"), ".car"), 10, 64)
+		if err != nil {
+			continue
+		}
+		heights = append(heights, abi.ChainEpoch(h))
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] > heights[j] })
+	return heights, nil
+}
+
+// exportStateSnapshot exports the state of the chain at the last block committed by the checkpoint snap to
+// the repo, and removes the snapshots beyond MaxStateSnapshots.
+func exportStateSnapshot(ctx context.Context, api v1api.FullNode, repo string, snap *Checkpoint) error {
+	if len(snap.BlockCids) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(repo, 0770); err != nil {
+		return xerrors.Errorf("error creating state snapshot repo: %w", err)
+	}
+	p := path.Join(repo, StateSnapshotFileName(snap.Height))
+	tmp := p + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return xerrors.Errorf("error creating state snapshot file: %w", err)
+	}
+	defer os.Remove(tmp) // nolint
+
+	stream, err := api.ChainExport(ctx, StateSnapshotRoots, true, types.NewTipSetKey(snap.BlockCids[0]))
+	if err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("error exporting state at height %d: %w", snap.Height-1, err)
+	}
+	w := bufio.NewWriter(f)
+	var last bool
+	for b := range stream {
+		last = len(b) == 0
+		if _, err := w.Write(b); err != nil {
+			_ = f.Close()
+			return xerrors.Errorf("error writing state snapshot %s: %w", tmp, err)
+		}
+	}
+	if !last {
+		_ = f.Close()
+		return xerrors.Errorf("incomplete export of the state at height %d", snap.Height-1)
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("error writing state snapshot %s: %w", tmp, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		return err
+	}
+
+	heights, err := stateSnapshotHeights(repo)
+	if err != nil {
+		return err
+	}
+	for i := MaxStateSnapshots; i < len(heights); i++ {
+		if err := os.Remove(path.Join(repo, StateSnapshotFileName(heights[i]))); err != nil {
+			return xerrors.Errorf("error removing state snapshot at height %d: %w", heights[i], err)
+		}
+	}
+	return nil
+}
+
+// importStateSnapshot imports the state snapshot read from r into the blockstore of the node and sets the
+// last block committed by the checkpoint snap as the head of its chain. The snapshot must be rooted at that
+// block and its blocks must match their CIDs, so it can come from untrusted peers.
+func importStateSnapshot(ctx context.Context, api v1api.FullNode, r io.Reader, snap *Checkpoint) error {
+	cr, err := car.NewCarReader(r)
+	if err != nil {
+		return xerrors.Errorf("error reading state snapshot: %w", err)
+	}
+	if len(cr.Header.Roots) != 1 || cr.Header.Roots[0] != snap.BlockCids[0] {
+		return xerrors.Errorf("state snapshot rooted at %v instead of the block %s committed by the checkpoint",
+			cr.Header.Roots, snap.BlockCids[0])
+	}
+
+	n := 0
+	for {
+		b, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return xerrors.Errorf("error reading state snapshot: %w", err)
+		}
+		c, err := b.Cid().Prefix().Sum(b.RawData())
+		if err != nil {
+			return err
+		}
+		if !c.Equals(b.Cid()) {
+			return xerrors.Errorf("state snapshot block %s doesn't match its CID", b.Cid())
+		}
+		if err := api.ChainPutObj(ctx, b); err != nil {
+			return xerrors.Errorf("error importing block %s: %w", b.Cid(), err)
+		}
+		n++
+	}
+
+	tsk := types.NewTipSetKey(snap.BlockCids[0])
+	if err := api.ChainSetHead(ctx, tsk); err != nil {
+		return xerrors.Errorf("error setting the head to the checkpoint at height %d: %w", snap.Height, err)
+	}
+	log.Infof("imported state snapshot of the checkpoint at height %d: %d blocks", snap.Height, n)
+	return nil
+}
+
+// ServeStateSnapshots serves the state snapshots exported to the repo to the peers of h.
+func ServeStateSnapshots(h host.Host, repo string) {
+	h.SetStreamHandler(StateSnapshotProtocol, func(s network.Stream) {
+		defer s.Close() // nolint
+
+		_ = s.SetDeadline(time.Now().Add(StateSnapshotTimeout))
+		var req [8]byte
+		if _, err := io.ReadFull(s, req[:]); err != nil {
+			log.Debugf("failed to read state snapshot request from %s: %v", s.Conn().RemotePeer(), err)
+			_ = s.Reset()
+			return
+		}
+		height := abi.ChainEpoch(binary.BigEndian.Uint64(req[:]))
+
+		f, err := os.Open(path.Join(repo, StateSnapshotFileName(height)))
+		switch {
+		case os.IsNotExist(err):
+			_, _ = s.Write([]byte{checkpointNotFound})
+			return
+		case err != nil:
+			log.Warnf("failed to open state snapshot at height %d requested by %s: %v", height, s.Conn().RemotePeer(), err)
+			_, _ = s.Write([]byte{checkpointError})
+			return
+		}
+		defer f.Close() // nolint
+
+		w := bufio.NewWriter(s)
+		_ = w.WriteByte(checkpointFound)
+		if _, err := io.Copy(w, f); err != nil {
+			log.Debugf("failed to send state snapshot at height %d to %s: %v", height, s.Conn().RemotePeer(), err)
+			_ = s.Reset()
+			return
+		}
+		if err := w.Flush(); err != nil {
+			log.Debugf("failed to send state snapshot at height %d to %s: %v", height, s.Conn().RemotePeer(), err)
+		}
+	})
+}
+
+// FetchStateSnapshot requests the state snapshot of the checkpoint at height from peer p, and passes it to fn
+// as it is received.
+func FetchStateSnapshot(ctx context.Context, h host.Host, p peer.AddrInfo, height abi.ChainEpoch, fn func(io.Reader) error) error {
+	ctx, cancel := context.WithTimeout(ctx, StateSnapshotTimeout)
+	defer cancel()
+
+	if err := h.Connect(ctx, p); err != nil {
+		return xerrors.Errorf("error connecting to %s: %w", p.ID, err)
+	}
+	s, err := h.NewStream(ctx, p.ID, StateSnapshotProtocol)
+	if err != nil {
+		return xerrors.Errorf("error opening stream to %s: %w", p.ID, err)
+	}
+	defer s.Close() // nolint
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.SetDeadline(deadline)
+	}
+
+	var req [8]byte
+	binary.BigEndian.PutUint64(req[:], uint64(height))
+	if _, err := s.Write(req[:]); err != nil {
+		return xerrors.Errorf("error sending state snapshot request to %s: %w", p.ID, err)
+	}
+	_ = s.CloseWrite()
+
+	r := bufio.NewReader(s)
+	status, err := r.ReadByte()
+	if err != nil {
+		return xerrors.Errorf("error reading response from %s: %w", p.ID, err)
+	}
+	switch status {
+	case checkpointFound:
+	case checkpointNotFound:
+		return xerrors.Errorf("peer %s: %w", p.ID, ErrStateSnapshotNotFound)
+	default:
+		return xerrors.Errorf("peer %s failed to get the state snapshot", p.ID)
+	}
+	return fn(r)
+}
+
+// fastSync restores the state of the chain at the checkpoint snap from the state snapshots served by the
+// other validators of the current membership, if the chain head is more than FastSyncThreshold blocks
+// behind the checkpoint.
+func (sm *StateManager) fastSync(snap *Checkpoint) error {
+	if len(snap.BlockCids) == 0 {
+		return nil
+	}
+	head, err := sm.heads.Head(sm.ctx)
+	if err != nil {
+		return xerrors.Errorf("failed to get chain head: %w", err)
+	}
+	if head.Height()+FastSyncThreshold >= snap.Height-1 {
+		return nil
+	}
+
+	log.With("validator", sm.id).Infof("fast-syncing from head %d to the checkpoint at height %d", head.Height(), snap.Height)
+	for id, n := range sm.memberships[sm.currentEpoch].Nodes {
+		if id.Pb() == sm.id {
+			continue
+		}
+		p, err := peer.AddrInfoFromString(n.Addr)
+		if err != nil {
+			log.With("validator", sm.id).Warnf("invalid address of validator %s: %v", id, err)
+			continue
+		}
+		err = FetchStateSnapshot(sm.ctx, sm.fastSyncHost, *p, snap.Height, func(r io.Reader) error {
+			return importStateSnapshot(sm.ctx, sm.api, r, snap)
+		})
+		if err != nil {
+			log.With("validator", sm.id).Warnf("failed to fast-sync from %s: %v", id, err)
+			continue
+		}
+		return nil
+	}
+	return xerrors.Errorf("no validator sent the state snapshot of the checkpoint at height %d", snap.Height)
+}
@@ -0,0 +1,104 @@
+package mir
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-car"
+	"github.com/ipld/go-car/util"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api/mocks"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func testStateSnapshot(t *testing.T, root cid.Cid, blocks map[cid.Cid][]byte) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, car.WriteHeader(&car.CarHeader{Roots: []cid.Cid{root}, Version: 1}, &buf))
+	for c, data := range blocks {
+		require.NoError(t, util.LdWrite(&buf, c.Bytes(), data))
+	}
+	return buf.Bytes()
+}
+
+func testBlock(t *testing.T, data []byte) cid.Cid {
+	c, err := cid.V1Builder{Codec: cid.DagCBOR, MhType: multihash.BLAKE2B_MIN + 31}.Sum(data)
+	require.NoError(t, err)
+	return c
+}
+
+func TestImportStateSnapshot(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	node := mocks.NewMockFullNode(ctrl)
+
+	head, state := []byte{0x81, 0x01}, []byte{0x81, 0x02}
+	headCid, stateCid := testBlock(t, head), testBlock(t, state)
+	snap := &Checkpoint{Height: 10, BlockCids: []cid.Cid{headCid}}
+
+	// Snapshots of another block are rejected.
+	b := testStateSnapshot(t, stateCid, map[cid.Cid][]byte{stateCid: state})
+	require.Error(t, importStateSnapshot(ctx, node, bytes.NewReader(b), snap))
+
+	// Blocks that don't match their CID are rejected before the head is set.
+	node.EXPECT().ChainPutObj(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	b = testStateSnapshot(t, headCid, map[cid.Cid][]byte{stateCid: head})
+	require.Error(t, importStateSnapshot(ctx, node, bytes.NewReader(b), snap))
+
+	node.EXPECT().ChainSetHead(gomock.Any(), types.NewTipSetKey(headCid)).Return(nil)
+	b = testStateSnapshot(t, headCid, map[cid.Cid][]byte{headCid: head, stateCid: state})
+	require.NoError(t, importStateSnapshot(ctx, node, bytes.NewReader(b), snap))
+}
+
+func TestFetchStateSnapshot(t *testing.T) {
+	ctx := context.Background()
+	mn, err := mocknet.FullMeshLinked(2)
+	require.NoError(t, err)
+	server, client := mn.Hosts()[0], mn.Hosts()[1]
+
+	repo := t.TempDir()
+	ServeStateSnapshots(server, repo)
+	info := peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()}
+
+	err = FetchStateSnapshot(ctx, client, info, 10, func(io.Reader) error { return nil })
+	require.ErrorIs(t, err, ErrStateSnapshotNotFound)
+
+	b := testStateSnapshot(t, testBlock(t, []byte{0x80}), nil)
+	require.NoError(t, os.WriteFile(path.Join(repo, StateSnapshotFileName(10)), b, 0600))
+	var received []byte
+	err = FetchStateSnapshot(ctx, client, info, 10, func(r io.Reader) error {
+		received, err = io.ReadAll(r)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, b, received)
+}
+
+func TestStateSnapshotHeights(t *testing.T) {
+	repo := t.TempDir()
+
+	heights, err := stateSnapshotHeights(path.Join(repo, "missing"))
+	require.NoError(t, err)
+	require.Empty(t, heights)
+
+	for _, h := range []abi.ChainEpoch{9, 100, 20} {
+		require.NoError(t, os.WriteFile(path.Join(repo, StateSnapshotFileName(h)), []byte{1}, 0600))
+	}
+	require.NoError(t, os.WriteFile(path.Join(repo, StateSnapshotFileName(30)+".tmp"), []byte{1}, 0600))
+
+	heights, err = stateSnapshotHeights(repo)
+	require.NoError(t, err)
+	require.Equal(t, []abi.ChainEpoch{100, 20, 9}, heights)
+}
@@ -0,0 +1,111 @@
+package mir
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	trantor "github.com/filecoin-project/mir/pkg/trantor/types"
+)
+
+// States of the Mir node of a validator.
+const (
+	MirRunning = "running"
+	MirSyncing = "syncing"
+	MirStopped = "stopped"
+	// MirStalled is the state of a validator to which Mir delivers no batch for the stall timeout.
+	MirStalled = "stalled"
+	// MirIdle is the state of a validator of a frozen subnet, which creates no blocks by design.
+	MirIdle = "idle"
+)
+
+// Status is the current state of the Mir node of a validator.
+type Status struct {
+	Validator string
+	// MirRunning, MirSyncing while the validator restores the state of a checkpoint, MirStalled, MirIdle or MirStopped.
+	State string
+	// Time of the last batch delivered by Mir.
+	LastBatch time.Time
+	Epoch     uint64
+	// IDs of the validators of the membership of the current epoch.
+	Membership []string
+	// Height of the latest stable checkpoint delivered to the validator.
+	CheckpointHeight abi.ChainEpoch
+}
+
+// statusTracker keeps the parts of the state of the StateManager reported in the status, which can be
+// read while Mir runs.
+type statusTracker struct {
+	lk               sync.Mutex
+	syncing          bool
+	currentEpoch     trantor.EpochNr
+	memberships      map[trantor.EpochNr]*mirproto.Membership
+	checkpointHeight abi.ChainEpoch
+}
+
+func (s *statusTracker) setSyncing(syncing bool) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.syncing = syncing
+}
+
+// setMemberships sets the memberships of the current epoch and the following ones.
+// The memberships themselves are never modified, so only the map is copied.
+func (s *statusTracker) setMemberships(current trantor.EpochNr, mbs map[trantor.EpochNr]*mirproto.Membership) {
+	cp := make(map[trantor.EpochNr]*mirproto.Membership, len(mbs))
+	for e, mb := range mbs {
+		cp[e] = mb
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.currentEpoch = current
+	s.memberships = cp
+}
+
+func membershipIDs(mb *mirproto.Membership) []string {
+	if mb == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(mb.Nodes))
+	for id := range mb.Nodes {
+		ids = append(ids, id.Pb())
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *statusTracker) setCheckpointHeight(h abi.ChainEpoch) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.checkpointHeight = h
+}
+
+// Status returns the status of the Mir node of the validator.
+func (m *Manager) Status() *Status {
+	st := m.stateManager.status
+	st.lk.Lock()
+	defer st.lk.Unlock()
+
+	s := &Status{
+		Validator:        m.id,
+		State:            MirRunning,
+		Epoch:            uint64(m.stateManager.OrderedEpoch()),
+		Membership:       membershipIDs(st.memberships[st.currentEpoch]),
+		CheckpointHeight: st.checkpointHeight,
+		LastBatch:        m.stateManager.stalls.lastBatch(),
+	}
+	select {
+	case <-m.mirStopped:
+		s.State = MirStopped
+	default:
+		if st.syncing {
+			s.State = MirSyncing
+		} else if state := m.stateManager.stalls.state(); state != "" {
+			s.State = state
+		}
+	}
+	return s
+}
@@ -0,0 +1,46 @@
+package mir
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	trantor "github.com/filecoin-project/mir/pkg/trantor/types"
+	"github.com/filecoin-project/mir/pkg/types"
+)
+
+func TestManagerStatus(t *testing.T) {
+	sm := &StateManager{status: &statusTracker{}, stalls: newStallDetector(context.Background(), "id1", time.Minute)}
+	m := &Manager{id: "id1", stateManager: sm, mirStopped: make(chan struct{})}
+
+	sm.orderedEpoch.Store(3)
+	mb := &mirproto.Membership{Nodes: map[types.NodeID]*mirproto.NodeIdentity{"id2": {}, "id1": {}}}
+	sm.status.setMemberships(3, map[trantor.EpochNr]*mirproto.Membership{3: mb, 4: mb})
+	sm.status.setCheckpointHeight(16)
+
+	require.Equal(t, &Status{
+		Validator:        "id1",
+		State:            MirRunning,
+		Epoch:            3,
+		Membership:       []string{"id1", "id2"},
+		CheckpointHeight: abi.ChainEpoch(16),
+		LastBatch:        sm.stalls.lastBatch(),
+	}, m.Status())
+
+	sm.status.setSyncing(true)
+	require.Equal(t, MirSyncing, m.Status().State)
+	sm.status.setSyncing(false)
+	require.Equal(t, MirRunning, m.Status().State)
+
+	sm.stalls.check(time.Now().Add(time.Hour))
+	require.Equal(t, MirStalled, m.Status().State)
+	sm.stalls.delivered(false)
+	require.Equal(t, MirRunning, m.Status().State)
+
+	close(m.mirStopped)
+	require.Equal(t, MirStopped, m.Status().State)
+}
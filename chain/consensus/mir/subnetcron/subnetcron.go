@@ -0,0 +1,166 @@
+// Package subnetcron implements the registry of implicit messages executed by Mir subnets
+// at every checkpoint boundary, in addition to the builtin cron.
+//
+// Jobs are registered per subnet, either from an init function of the binary running the
+// subnet or from the JobsFile in the repo of the node. All the validators and full nodes of a
+// subnet must register the same jobs: validators include the messages of the jobs in the block
+// that contains a checkpoint, and full nodes execute them implicitly (without signature nor gas
+// payment) when they validate the block.
+package subnetcron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// JobsFile is the name of the file in the repo of the node with the jobs of the subnet.
+const JobsFile = "subnetcron.json"
+
+// FirstMsgNonce is the nonce of the first subnet cron message in a block.
+// The nonces of the membership configuration messages included in the same block are
+// derived from the height of the block, so they are always above the nonces of the jobs.
+const FirstMsgNonce = 2
+
+// Job is an implicit message executed at every checkpoint boundary.
+type Job struct {
+	// Name identifies the job. Jobs of a subnet are executed in the order of their names.
+	Name   string
+	To     address.Address
+	Method abi.MethodNum
+	// Params returns the serialized parameters of the message executed in the block at the given height.
+	// It must be deterministic.
+	Params func(height abi.ChainEpoch) ([]byte, error)
+}
+
+// JobConfig is the configuration of a job with constant parameters in the JobsFile.
+type JobConfig struct {
+	Name   string
+	To     address.Address
+	Method abi.MethodNum
+	Params []byte
+}
+
+var (
+	lk   sync.RWMutex
+	jobs = make(map[string][]Job)
+)
+
+// Register registers a job for the subnet with the given network name.
+func Register(subnet string, job Job) error {
+	if job.Name == "" {
+		return fmt.Errorf("subnet cron job without name")
+	}
+	if job.Params == nil {
+		return fmt.Errorf("subnet cron job %s without params", job.Name)
+	}
+
+	lk.Lock()
+	defer lk.Unlock()
+
+	for _, j := range jobs[subnet] {
+		if j.Name == job.Name {
+			return fmt.Errorf("subnet cron job %s already registered for %s", job.Name, subnet)
+		}
+	}
+	jobs[subnet] = append(jobs[subnet], job)
+	sort.Slice(jobs[subnet], func(i, j int) bool { return jobs[subnet][i].Name < jobs[subnet][j].Name })
+	return nil
+}
+
+// LoadJobs registers for the subnet the jobs configured in the file at path.
+// A missing file means that no job is configured.
+func LoadJobs(subnet, path string) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading subnet cron jobs: %w", err)
+	}
+
+	var cfgs []JobConfig
+	if err := json.Unmarshal(b, &cfgs); err != nil {
+		return fmt.Errorf("error parsing subnet cron jobs in %s: %w", path, err)
+	}
+	for _, c := range cfgs {
+		params := c.Params
+		err := Register(subnet, Job{
+			Name:   c.Name,
+			To:     c.To,
+			Method: c.Method,
+			Params: func(abi.ChainEpoch) ([]byte, error) { return params, nil },
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Jobs returns the jobs registered for the subnet.
+func Jobs(subnet string) []Job {
+	lk.RLock()
+	defer lk.RUnlock()
+	return append([]Job{}, jobs[subnet]...)
+}
+
+// Messages returns the implicit messages of the jobs registered for the subnet
+// to include in the block at the given height.
+func Messages(subnet string, height abi.ChainEpoch) ([]*types.SignedMessage, error) {
+	var msgs []*types.SignedMessage
+	for i, j := range Jobs(subnet) {
+		params, err := j.Params(height)
+		if err != nil {
+			return nil, fmt.Errorf("error getting params of subnet cron job %s: %w", j.Name, err)
+		}
+		msg := types.Message{
+			To:         j.To,
+			From:       builtin.SystemActorAddr,
+			Value:      abi.NewTokenAmount(0),
+			Method:     j.Method,
+			Params:     params,
+			GasFeeCap:  types.NewInt(0),
+			GasPremium: types.NewInt(0),
+			GasLimit:   build.BlockGasLimit,
+			Nonce:      uint64(FirstMsgNonce + i),
+		}
+		msgs = append(msgs, &types.SignedMessage{Message: msg, Signature: crypto.Signature{Type: crypto.SigTypeDelegated}})
+	}
+	return msgs, nil
+}
+
+// IsCronMsg determines if msg is the implicit message of one of the jobs registered for the subnet
+// that must be included in the block with header h. Subnet cron messages are only included in the
+// blocks carrying a Mir checkpoint, whose certificate is set as the election proof of the block.
+func IsCronMsg(subnet string, h *types.BlockHeader, msg *types.Message) bool {
+	if msg.From != builtin.SystemActorAddr {
+		return false
+	}
+	if h.ElectionProof == nil || h.ElectionProof.VRFProof == nil {
+		return false
+	}
+	msgs, err := Messages(subnet, h.Height)
+	if err != nil {
+		return false
+	}
+	c := msg.Cid()
+	for _, m := range msgs {
+		if m.Message.Cid() == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package subnetcron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func reset() {
+	lk.Lock()
+	defer lk.Unlock()
+	jobs = make(map[string][]Job)
+}
+
+func checkpointHeader(height abi.ChainEpoch) *types.BlockHeader {
+	return &types.BlockHeader{Height: height, ElectionProof: &types.ElectionProof{VRFProof: []byte{1}}}
+}
+
+func TestSubnetCronMessages(t *testing.T) {
+	t.Cleanup(reset)
+
+	to, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	params := func(h abi.ChainEpoch) ([]byte, error) {
+		return []byte{byte(h)}, nil
+	}
+
+	err = Register("/root/a", Job{Name: "rollup", To: to, Method: 3, Params: params})
+	require.NoError(t, err)
+	err = Register("/root/a", Job{Name: "fees", To: to, Method: 2, Params: params})
+	require.NoError(t, err)
+	err = Register("/root/a", Job{Name: "fees", To: to, Method: 2, Params: params})
+	require.Error(t, err)
+	err = Register("/root/a", Job{Name: "no-params", To: to, Method: 4})
+	require.Error(t, err)
+
+	msgs, err := Messages("/root/a", 7)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(msgs))
+
+	// Jobs are executed in the order of their names.
+	require.Equal(t, abi.MethodNum(2), msgs[0].Message.Method)
+	require.Equal(t, uint64(FirstMsgNonce), msgs[0].Message.Nonce)
+	require.Equal(t, abi.MethodNum(3), msgs[1].Message.Method)
+	require.Equal(t, uint64(FirstMsgNonce+1), msgs[1].Message.Nonce)
+	require.Equal(t, []byte{7}, msgs[1].Message.Params)
+	require.True(t, IsCronMsg("/root/a", checkpointHeader(7), &msgs[0].Message))
+
+	msgs, err = Messages("/root/b", 7)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(msgs))
+}
+
+func TestIsCronMsg(t *testing.T) {
+	t.Cleanup(reset)
+
+	to, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	params := func(h abi.ChainEpoch) ([]byte, error) {
+		return []byte{byte(h)}, nil
+	}
+	require.NoError(t, Register("/root/a", Job{Name: "fees", To: to, Method: 2, Params: params}))
+
+	msgs, err := Messages("/root/a", 7)
+	require.NoError(t, err)
+	msg := &msgs[0].Message
+
+	require.True(t, IsCronMsg("/root/a", checkpointHeader(7), msg))
+	// Jobs are scoped to the subnet that registered them.
+	require.False(t, IsCronMsg("/root/b", checkpointHeader(7), msg))
+	// Subnet cron messages are only included in blocks with a checkpoint.
+	require.False(t, IsCronMsg("/root/a", &types.BlockHeader{Height: 7, ElectionProof: &types.ElectionProof{}}, msg))
+	// The message must match the one expected for the height of the block.
+	require.False(t, IsCronMsg("/root/a", checkpointHeader(8), msg))
+
+	// Only messages sent by the system actor are subnet cron messages.
+	forged := *msg
+	forged.From = to
+	require.False(t, IsCronMsg("/root/a", checkpointHeader(7), &forged))
+	require.False(t, IsCronMsg("/root/a", checkpointHeader(7), &types.Message{From: builtin.SystemActorAddr, To: to, Method: 5}))
+}
+
+func TestLoadJobs(t *testing.T) {
+	t.Cleanup(reset)
+
+	dir := t.TempDir()
+	require.NoError(t, LoadJobs("/root/a", filepath.Join(dir, JobsFile)))
+	require.Len(t, Jobs("/root/a"), 0)
+
+	p := filepath.Join(dir, JobsFile)
+	cfg := `[{"Name": "fees", "To": "f01000", "Method": 2, "Params": "AQI="}]`
+	require.NoError(t, os.WriteFile(p, []byte(cfg), 0644))
+	require.NoError(t, LoadJobs("/root/a", p))
+
+	msgs, err := Messages("/root/a", 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, abi.MethodNum(2), msgs[0].Message.Method)
+	require.Equal(t, []byte{1, 2}, msgs[0].Message.Params)
+
+	require.NoError(t, os.WriteFile(p, []byte("{"), 0644))
+	require.Error(t, LoadJobs("/root/b", p))
+}
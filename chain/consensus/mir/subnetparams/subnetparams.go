@@ -0,0 +1,175 @@
+// Package subnetparams implements the registry of the consensus parameters that Mir subnets
+// can set independently of the parameters built into the binary.
+//
+// Parameters are set per subnet, either from an init function of the binary running the subnet
+// or from the ParamsFile in the repo of the node. All the validators and full nodes of a subnet
+// must use the same parameters, otherwise they disagree on the validity of the blocks. Validators
+// commit to the Hash of their parameters in their version attestations, so a mismatch can be
+// detected from any node of the subnet.
+package subnetparams
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/build"
+)
+
+// ParamsFile is the name of the file in the repo of the node with the parameters of the subnet.
+const ParamsFile = "subnetparams.json"
+
+// Params are the consensus parameters of a subnet.
+type Params struct {
+	// BlockGasLimit is the maximum sum of the gas limits of the messages in a block.
+	// Every single message is still bounded by build.BlockGasLimit.
+	BlockGasLimit int64
+	// Upgrades are the heights at which the subnet activates consensus features, if any.
+	Upgrades *Upgrades `json:",omitempty"`
+}
+
+// Upgrades are the activation heights of the consensus features adopted by a live subnet. A feature is
+// active in the blocks at or above its height, so all the validators switch to it at the same block
+// without restarting the subnet from genesis. Features without a height are never activated, and new
+// subnets enable them from genesis with a height of 0.
+type Upgrades struct {
+	// WeightedQuorums is the height from which reconfiguration votes are weighted by the weights of
+	// the validators. Below it, f+1 votes of the n validators are enough, whatever their weights.
+	WeightedQuorums *abi.ChainEpoch `json:",omitempty"`
+	// BatchTimestamps is the height from which the timestamps ordered in the batches are the block timestamps.
+	BatchTimestamps *abi.ChainEpoch `json:",omitempty"`
+	// TaggedHeaders is the height from which the checkpoints and their certificates are tagged in blocks.
+	TaggedHeaders *abi.ChainEpoch `json:",omitempty"`
+	// CompactCerts is the height from which the certificates of the checkpoints are compact in blocks.
+	CompactCerts *abi.ChainEpoch `json:",omitempty"`
+	// ConfigMsgNonces is the height from which the nonces of the configuration messages are derived from
+	// the height of the block and checked. Below it, they only depend on the kind of the message.
+	ConfigMsgNonces *abi.ChainEpoch `json:",omitempty"`
+	// EncryptedTxs is the height from which encrypted messages and their key reveals are ordered and executed.
+	// Below it, they are dropped from the batches.
+	EncryptedTxs *abi.ChainEpoch `json:",omitempty"`
+	// CanonicalSetHashes is the height from which configuration votes are counted by the canonical hash of
+	// the validator set. Below it, they are counted by the legacy hash of the set.
+	CanonicalSetHashes *abi.ChainEpoch `json:",omitempty"`
+}
+
+func (u *Upgrades) heights() map[string]*abi.ChainEpoch {
+	return map[string]*abi.ChainEpoch{
+		"WeightedQuorums":    u.WeightedQuorums,
+		"BatchTimestamps":    u.BatchTimestamps,
+		"TaggedHeaders":      u.TaggedHeaders,
+		"CompactCerts":       u.CompactCerts,
+		"ConfigMsgNonces":    u.ConfigMsgNonces,
+		"EncryptedTxs":       u.EncryptedTxs,
+		"CanonicalSetHashes": u.CanonicalSetHashes,
+	}
+}
+
+// Active returns whether a feature activated at the given height is active at height h.
+// Features without an activation height are never activated by an upgrade.
+func Active(activation *abi.ChainEpoch, h abi.ChainEpoch) bool {
+	return activation != nil && h >= *activation
+}
+
+// Default returns the parameters of a subnet that doesn't set them.
+func Default() Params {
+	return Params{
+		BlockGasLimit: build.BlockGasLimit,
+	}
+}
+
+var (
+	lk     sync.RWMutex
+	params = make(map[string]Params)
+)
+
+// Set sets the parameters of the subnet with the given network name.
+// Zero values are replaced with the default ones.
+func Set(subnet string, p Params) error {
+	if p.BlockGasLimit < 0 {
+		return fmt.Errorf("invalid block gas limit %d for %s", p.BlockGasLimit, subnet)
+	}
+	if p.BlockGasLimit == 0 {
+		p.BlockGasLimit = build.BlockGasLimit
+	}
+	if p.Upgrades != nil {
+		empty := true
+		for name, h := range p.Upgrades.heights() {
+			if h == nil {
+				continue
+			}
+			if *h < 0 {
+				return fmt.Errorf("invalid %s activation height %d for %s", name, *h, subnet)
+			}
+			empty = false
+		}
+		// Subnets without upgrades keep the hash of the default parameters.
+		if empty {
+			p.Upgrades = nil
+		} else {
+			u := *p.Upgrades
+			p.Upgrades = &u
+		}
+	}
+
+	lk.Lock()
+	defer lk.Unlock()
+	params[subnet] = p
+	return nil
+}
+
+// Load sets the parameters of the subnet from the file at path.
+// A missing file means that the subnet uses the default parameters.
+func Load(subnet, path string) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading subnet params: %w", err)
+	}
+
+	var p Params
+	if err := json.Unmarshal(b, &p); err != nil {
+		return fmt.Errorf("error parsing subnet params in %s: %w", path, err)
+	}
+	return Set(subnet, p)
+}
+
+// Get returns the parameters of the subnet.
+func Get(subnet string) Params {
+	lk.RLock()
+	defer lk.RUnlock()
+	if p, ok := params[subnet]; ok {
+		return p
+	}
+	return Default()
+}
+
+// BlockGasLimit returns the block gas limit of the subnet.
+func BlockGasLimit(subnet string) int64 {
+	return Get(subnet).BlockGasLimit
+}
+
+// GetUpgrades returns the activation heights of the upgrades of the subnet.
+func GetUpgrades(subnet string) Upgrades {
+	if u := Get(subnet).Upgrades; u != nil {
+		return *u
+	}
+	return Upgrades{}
+}
+
+// Hash returns the hex-encoded SHA-256 hash of the parameters of the subnet.
+func Hash(subnet string) string {
+	// Params only has fields that can always be encoded.
+	b, _ := json.Marshal(Get(subnet))
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,74 @@
+package subnetparams
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/build"
+)
+
+func reset() {
+	lk.Lock()
+	defer lk.Unlock()
+	params = make(map[string]Params)
+}
+
+func TestSubnetParams(t *testing.T) {
+	t.Cleanup(reset)
+
+	require.Equal(t, build.BlockGasLimit, BlockGasLimit("/root/a"))
+	defaultHash := Hash("/root/a")
+
+	path := filepath.Join(t.TempDir(), ParamsFile)
+	require.NoError(t, Load("/root/a", path))
+	require.Equal(t, defaultHash, Hash("/root/a"))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"BlockGasLimit": 1000000}`), 0644))
+	require.NoError(t, Load("/root/a", path))
+	require.Equal(t, int64(1000000), BlockGasLimit("/root/a"))
+	require.NotEqual(t, defaultHash, Hash("/root/a"))
+
+	// Other subnets keep the default parameters.
+	require.Equal(t, build.BlockGasLimit, BlockGasLimit("/root/b"))
+	require.Equal(t, defaultHash, Hash("/root/b"))
+
+	// Unset values are the default ones.
+	require.NoError(t, Set("/root/b", Params{}))
+	require.Equal(t, defaultHash, Hash("/root/b"))
+
+	require.Error(t, Set("/root/b", Params{BlockGasLimit: -1}))
+	require.NoError(t, os.WriteFile(path, []byte(`{"BlockGasLimit": "a lot"}`), 0644))
+	require.Error(t, Load("/root/a", path))
+}
+
+func TestUpgrades(t *testing.T) {
+	t.Cleanup(reset)
+
+	defaultHash := Hash("/root/a")
+	require.Equal(t, Upgrades{}, GetUpgrades("/root/a"))
+
+	// Subnets without upgrade heights keep the default parameters.
+	require.NoError(t, Set("/root/a", Params{Upgrades: &Upgrades{}}))
+	require.Equal(t, defaultHash, Hash("/root/a"))
+
+	path := filepath.Join(t.TempDir(), ParamsFile)
+	require.NoError(t, os.WriteFile(path, []byte(`{"Upgrades": {"BatchTimestamps": 100, "WeightedQuorums": 0}}`), 0644))
+	require.NoError(t, Load("/root/a", path))
+	require.NotEqual(t, defaultHash, Hash("/root/a"))
+	require.Equal(t, build.BlockGasLimit, BlockGasLimit("/root/a"))
+
+	u := GetUpgrades("/root/a")
+	require.False(t, Active(u.BatchTimestamps, 99))
+	require.True(t, Active(u.BatchTimestamps, 100))
+	require.True(t, Active(u.WeightedQuorums, 0))
+	require.False(t, Active(u.TaggedHeaders, 1000))
+	require.Nil(t, u.CompactCerts)
+
+	h := abi.ChainEpoch(-1)
+	require.Error(t, Set("/root/b", Params{Upgrades: &Upgrades{CompactCerts: &h}}))
+}
@@ -0,0 +1,82 @@
+package testing
+
+import (
+	"context"
+	"sync"
+
+	ds "github.com/ipfs/go-datastore"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+)
+
+var _ db.DB = (*DB)(nil)
+
+// DB is an in-memory db.DB. Like the LevelDB datastore of the validators, it returns
+// datastore.ErrNotFound for the keys that are missing, including when they are deleted.
+type DB struct {
+	Faults
+
+	lock sync.Mutex
+	db   map[ds.Key][]byte
+}
+
+func NewDB() *DB {
+	return &DB{
+		db: make(map[ds.Key][]byte),
+	}
+}
+
+func (kv *DB) Get(ctx context.Context, key ds.Key) (value []byte, err error) {
+	if err := kv.inject(OpGet); err != nil {
+		return nil, err
+	}
+	kv.lock.Lock()
+	defer kv.lock.Unlock()
+	v, ok := kv.db[key]
+	if !ok {
+		return nil, ds.ErrNotFound
+	}
+	return v, nil
+}
+
+func (kv *DB) Put(ctx context.Context, key ds.Key, value []byte) error {
+	if err := kv.inject(OpPut); err != nil {
+		return err
+	}
+	kv.lock.Lock()
+	defer kv.lock.Unlock()
+	kv.db[key] = value
+	return nil
+}
+
+func (kv *DB) Delete(ctx context.Context, key ds.Key) error {
+	if err := kv.inject(OpDelete); err != nil {
+		return err
+	}
+	kv.lock.Lock()
+	defer kv.lock.Unlock()
+	_, ok := kv.db[key]
+	if !ok {
+		return ds.ErrNotFound
+	}
+	delete(kv.db, key)
+	return nil
+}
+
+// Entries returns a copy of the keys and values in the datastore.
+func (kv *DB) Entries() map[ds.Key][]byte {
+	kv.lock.Lock()
+	defer kv.lock.Unlock()
+	m := make(map[ds.Key][]byte, len(kv.db))
+	for k, v := range kv.db {
+		m[k] = v
+	}
+	return m
+}
+
+// Len returns the number of keys in the datastore.
+func (kv *DB) Len() int {
+	kv.lock.Lock()
+	defer kv.lock.Unlock()
+	return len(kv.db)
+}
@@ -0,0 +1,69 @@
+// Package testing provides fakes of the interfaces of the Mir integration, so projects embedding it
+// can unit test their code without running validators. The fakes are safe for concurrent use, and
+// errors and latency can be injected in their methods to exercise failure handling.
+package testing
+
+import (
+	"sync"
+	"time"
+)
+
+// Names of the methods of the fakes, used to inject errors in them.
+const (
+	OpGet               = "Get"
+	OpPut               = "Put"
+	OpDelete            = "Delete"
+	OpGetMembershipInfo = "GetMembershipInfo"
+)
+
+// Faults injects errors and latency in the methods of a fake.
+type Faults struct {
+	lk      sync.Mutex
+	errs    map[string]error
+	latency time.Duration
+	calls   map[string]int
+}
+
+// FailWith makes the method op return err until it is called again with a nil error.
+func (f *Faults) FailWith(op string, err error) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	if f.errs == nil {
+		f.errs = make(map[string]error)
+	}
+	if err == nil {
+		delete(f.errs, op)
+		return
+	}
+	f.errs[op] = err
+}
+
+// SetLatency delays every call to the methods of the fake by d.
+func (f *Faults) SetLatency(d time.Duration) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.latency = d
+}
+
+// Calls returns the number of calls to the method op, including the failed ones.
+func (f *Faults) Calls(op string) int {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return f.calls[op]
+}
+
+// inject records a call to op, waits for the latency and returns the error injected in op, if any.
+func (f *Faults) inject(op string) error {
+	f.lk.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[op]++
+	latency, err := f.latency, f.errs[op]
+	f.lk.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	return err
+}
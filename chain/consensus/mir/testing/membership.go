@@ -0,0 +1,62 @@
+package testing
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+)
+
+var _ membership.Reader = (*Membership)(nil)
+
+// Membership is a membership.Reader returning the membership set with SetInfo or SetValidatorSet.
+// It returns an error until a membership is set.
+type Membership struct {
+	Faults
+
+	lk   sync.Mutex
+	info *membership.Info
+}
+
+// NewMembership returns a fake membership with the validator set, which may be nil.
+func NewMembership(set *validator.Set) *Membership {
+	m := &Membership{}
+	if set != nil {
+		m.SetValidatorSet(set)
+	}
+	return m
+}
+
+// SetInfo sets the membership returned by the reader.
+func (m *Membership) SetInfo(info *membership.Info) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.info = info
+}
+
+// SetValidatorSet sets the validator set returned by the reader, keeping the rest of the membership.
+func (m *Membership) SetValidatorSet(set *validator.Set) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if m.info == nil {
+		m.info = &membership.Info{}
+	}
+	info := *m.info
+	info.ValidatorSet = set
+	m.info = &info
+}
+
+func (m *Membership) GetMembershipInfo() (*membership.Info, error) {
+	if err := m.inject(OpGetMembershipInfo); err != nil {
+		return nil, err
+	}
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if m.info == nil {
+		return nil, fmt.Errorf("no validators")
+	}
+	info := *m.info
+	return &info, nil
+}
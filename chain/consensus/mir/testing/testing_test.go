@@ -0,0 +1,54 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB(t *testing.T) {
+	ctx := context.Background()
+	kv := NewDB()
+	k := ds.NewKey("k")
+
+	_, err := kv.Get(ctx, k)
+	require.ErrorIs(t, err, ds.ErrNotFound)
+	require.NoError(t, kv.Put(ctx, k, []byte("v")))
+	v, err := kv.Get(ctx, k)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v"), v)
+	require.Equal(t, 1, kv.Len())
+
+	errPut := errors.New("disk full")
+	kv.FailWith(OpPut, errPut)
+	require.ErrorIs(t, kv.Put(ctx, k, []byte("w")), errPut)
+	kv.FailWith(OpPut, nil)
+	require.NoError(t, kv.Put(ctx, k, []byte("w")))
+	require.Equal(t, 3, kv.Calls(OpPut))
+
+	require.NoError(t, kv.Delete(ctx, k))
+	require.ErrorIs(t, kv.Delete(ctx, k), ds.ErrNotFound)
+}
+
+func TestMembership(t *testing.T) {
+	m := NewMembership(nil)
+	_, err := m.GetMembershipInfo()
+	require.Error(t, err)
+
+	set := validator.NewValidatorSet(1, nil)
+	m.SetValidatorSet(set)
+	info, err := m.GetMembershipInfo()
+	require.NoError(t, err)
+	require.Equal(t, set, info.ValidatorSet)
+
+	m.SetLatency(10 * time.Millisecond)
+	start := time.Now()
+	_, err = m.GetMembershipInfo()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
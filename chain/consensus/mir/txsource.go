@@ -0,0 +1,41 @@
+package mir
+
+import (
+	"context"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// TxSource is a source of messages proposed by the validator in addition to the messages
+// of the mempool of its node, e.g. the feed of a shared sequencer or a relayer.
+//
+// The messages are ordered as any other message of the mempool: they must be signed by their
+// senders and have the nonces expected by the chain to be executed.
+type TxSource interface {
+	// Name identifies the source in logs.
+	Name() string
+	// Select returns up to max messages to be proposed in the next batch.
+	Select(ctx context.Context, max int) ([]*types.SignedMessage, error)
+}
+
+// selectFromSources returns up to max messages from the sources, in the order of the sources.
+// Sources that fail are skipped, so a faulty feed doesn't stop the validator from proposing
+// the messages of the mempool.
+func selectFromSources(ctx context.Context, id string, sources []TxSource, max int) []*types.SignedMessage {
+	var msgs []*types.SignedMessage
+	for _, s := range sources {
+		if len(msgs) >= max {
+			break
+		}
+		selected, err := s.Select(ctx, max-len(msgs))
+		if err != nil {
+			log.With("validator", id).Warnf("failed to select messages from source %s: %v", s.Name(), err)
+			continue
+		}
+		if len(selected) > max-len(msgs) {
+			selected = selected[:max-len(msgs)]
+		}
+		msgs = append(msgs, selected...)
+	}
+	return msgs
+}
@@ -0,0 +1,46 @@
+package mir
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+type testTxSource struct {
+	msgs []*types.SignedMessage
+	err  error
+}
+
+func (s *testTxSource) Name() string { return "test" }
+
+func (s *testTxSource) Select(_ context.Context, _ int) ([]*types.SignedMessage, error) {
+	return s.msgs, s.err
+}
+
+func TestSelectFromSources(t *testing.T) {
+	from, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	msg := func(nonce uint64) *types.SignedMessage {
+		return &types.SignedMessage{Message: types.Message{From: from, To: from, Nonce: nonce}}
+	}
+
+	sources := []TxSource{
+		&testTxSource{err: fmt.Errorf("feed down")},
+		&testTxSource{msgs: []*types.SignedMessage{msg(0), msg(1)}},
+		&testTxSource{msgs: []*types.SignedMessage{msg(2), msg(3)}},
+	}
+	msgs := selectFromSources(context.Background(), "validator", sources, 3)
+	require.Len(t, msgs, 3)
+	require.Equal(t, uint64(2), msgs[2].Message.Nonce)
+
+	// Messages received from a source and the mempool are proposed once.
+	merged := mergeMessages(msgs, []*types.SignedMessage{msg(1), msg(4)})
+	require.Len(t, merged, 4)
+	require.Equal(t, uint64(4), merged[3].Message.Nonce)
+}
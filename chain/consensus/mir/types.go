@@ -3,24 +3,33 @@ package mir
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/multiformats/go-multihash"
+	cbg "github.com/whyrusleeping/cbor-gen"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/mir/pkg/checkpoint"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
 	"github.com/filecoin-project/mir/pkg/trantor"
 	mir "github.com/filecoin-project/mir/pkg/types"
 
+	"github.com/filecoin-project/lotus/chain/consensus/mir/compression"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/headerext"
 	"github.com/filecoin-project/lotus/chain/types"
 	ltypes "github.com/filecoin-project/lotus/chain/types"
 )
@@ -28,6 +37,10 @@ import (
 const (
 	TransportTransaction     = 1
 	ConfigurationTransaction = 0
+	// ShutdownTransaction votes for freezing the subnet at a height.
+	ShutdownTransaction = 2
+	// TimestampTransaction carries the wall-clock time of the proposer of a batch.
+	TimestampTransaction = 3
 )
 
 type CtxCanceledWhileWaitingForBlockError struct {
@@ -82,30 +95,80 @@ type MirMessage interface {
 type MirMsgType int
 
 const (
-	ConfigMessageType = 0 // Mir specific config message
-	SignedMessageType = 1 // Lotus signed message
+	ConfigMessageType    = 0 // Mir specific config message
+	SignedMessageType    = 1 // Lotus signed message
+	EncryptedMessageType = 2 // Encrypted Lotus signed message
+	KeyRevealMessageType = 3 // Key of an encrypted message
 )
 
 func MsgType(m MirMessage) (MirMsgType, error) {
 	switch m.(type) {
 	case *types.SignedMessage:
 		return SignedMessageType, nil
+	case *EncryptedMessage:
+		return EncryptedMessageType, nil
+	case *KeyReveal:
+		return KeyRevealMessageType, nil
 	default:
 		return -1, fmt.Errorf("mir message type not implemented")
 
 	}
 }
 
+// maxPooledEncodeBuffer is the capacity above which encoding buffers are not returned to the pool,
+// so a single large message doesn't pin a large buffer.
+const maxPooledEncodeBuffer = 64 << 10
+
+// encodeBufferPool holds the buffers messages are encoded into before being copied to transactions.
+var encodeBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
 func MessageBytes(msg MirMessage) ([]byte, error) {
 	msgType, err := MsgType(msg)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get msgType %w", err)
 	}
-	msgBytes, err := msg.Serialize()
-	if err != nil {
+
+	m, ok := msg.(cbg.CBORMarshaler)
+	if !ok {
+		msgBytes, err := msg.Serialize()
+		if err != nil {
+			return nil, fmt.Errorf("unable to serialize message: %w", err)
+		}
+		return append(msgBytes, byte(msgType)), nil
+	}
+
+	// Encode into a pooled buffer and copy the result to a slice of the exact size, with room
+	// for the type byte, so each message only allocates the bytes kept by its transaction.
+	buf := encodeBufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledEncodeBuffer {
+			buf.Reset()
+			encodeBufferPool.Put(buf)
+		}
+	}()
+	if err := m.MarshalCBOR(buf); err != nil {
 		return nil, fmt.Errorf("unable to serialize message: %w", err)
 	}
-	return append(msgBytes, byte(msgType)), nil
+	b := make([]byte, buf.Len()+1)
+	copy(b, buf.Bytes())
+	b[len(b)-1] = byte(msgType)
+	return b, nil
+}
+
+// batchID returns a short identifier of a batch of transactions used to correlate the logs of the batch.
+// The proposal of a batch and its delivery by Mir get the same identifier on all validators.
+func batchID(txs []*mirproto.Transaction) string {
+	h := sha256.New()
+	b := make([]byte, 8)
+	for _, tx := range txs {
+		h.Write([]byte(tx.ClientId))
+		binary.BigEndian.PutUint64(b, tx.TxNo.Pb())
+		h.Write(b)
+		h.Write(tx.Data)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
 }
 
 type ParentMeta struct {
@@ -167,7 +230,7 @@ func (c *ConfigurationVotes) VoteForConfiguration(n uint64, h string, v mir.Node
 
 	// Prevent double voting.
 	if _, voted := c.votes[n][h][v]; voted {
-		return xerrors.Errorf("validator %s has already voted for configuration %d", v, n)
+		return xerrors.Errorf("validator %s has already voted for configuration %d: %w", v, n, ErrDuplicateVote)
 	}
 
 	c.votes[n][h][v] = struct{}{}
@@ -175,6 +238,24 @@ func (c *ConfigurationVotes) VoteForConfiguration(n uint64, h string, v mir.Node
 	return nil
 }
 
+// MigrateVotes moves the votes for configuration n from hash "from" to hash "to",
+// e.g. when the scheme used to hash validator sets changes while votes are in flight.
+// It returns whether there were votes to migrate.
+func (c *ConfigurationVotes) MigrateVotes(n uint64, from, to string) bool {
+	old, exist := c.votes[n][from]
+	if !exist || from == to {
+		return false
+	}
+	if _, exist := c.votes[n][to]; !exist {
+		c.votes[n][to] = make(map[mir.NodeID]struct{})
+	}
+	for v := range old {
+		c.votes[n][to][v] = struct{}{}
+	}
+	delete(c.votes[n], from)
+	return true
+}
+
 func (c *ConfigurationVotes) GetVotesForConfiguration(n uint64, h string) int {
 	return len(c.votes[n][h])
 }
@@ -195,11 +276,17 @@ func (c *ConfigurationVotes) Votes() map[uint64]map[string]map[mir.NodeID]struct
 	return c.votes
 }
 
+// MaxCheckpointBlocks is the maximum number of blocks that can be committed by a checkpoint, the maximum
+// length of the lists in the encoding of the snapshots. Checkpoints committing more blocks can't be encoded,
+// so every checkpoint of a chain can be decoded. The checkpoints of a validator are further bounded by its
+// ConsensusConfig.MaxCheckpointBlocks.
+const MaxCheckpointBlocks = cbg.MaxLength
+
 type Checkpoint struct {
 	// Height of the checkpoint
 	Height abi.ChainEpoch
-	// Cid of the blocks being committed in increasing order.
-	// (index 0 is the first block of the range)
+	// Cid of the blocks being committed in descending order of height.
+	// (index 0 is the last block of the range, at Height-1)
 	BlockCids []cid.Cid
 	// Parent checkpoint, i.e. metadata of previous checkpoint committed.
 	Parent ParentMeta
@@ -207,6 +294,51 @@ type Checkpoint struct {
 	NextConfigNumber uint64
 	// Reconfiguration votes.
 	Votes VoteRecords
+	// Ordered encrypted messages waiting for their keys to be revealed.
+	SealedMsgs SealedMessageRecords
+	// State added to the snapshots after the fields above, encoded after them.
+	Ext CheckpointExtension `cborgen:"ignore"`
+}
+
+// CheckpointExtension is the state restored from a checkpoint that was added after the original format of
+// the snapshots. It is encoded after the Checkpoint, and only if it isn't empty, so the snapshots of the
+// chains that don't use it are unchanged and the validators that predate it still agree on them.
+type CheckpointExtension struct {
+	// Latest batch timestamps ordered from the validators of the membership, sorted by validator.
+	Timestamps []ValidatorTimestamp
+	// Height above which no blocks are created, if a strong quorum voted for shutting the subnet down.
+	FreezeHeight abi.ChainEpoch
+	// Shutdown votes ordered without a quorum yet, sorted by height and validator.
+	ShutdownVotes []ShutdownVote
+}
+
+// ValidatorTimestamp is the latest batch timestamp ordered from a validator.
+type ValidatorTimestamp struct {
+	Validator string
+	Timestamp uint64
+}
+
+// ShutdownVote is the vote of a validator for freezing the subnet at a height.
+type ShutdownVote struct {
+	Height    abi.ChainEpoch
+	Validator string
+}
+
+func (e *CheckpointExtension) isEmpty() bool {
+	return len(e.Timestamps) == 0 && e.FreezeHeight == 0 && len(e.ShutdownVotes) == 0
+}
+
+// SealedMessageRecords are the encrypted messages that have been ordered and are waiting
+// for their keys. They are part of the state restored from a checkpoint, as the blocks
+// after the checkpoint include the decrypted messages.
+type SealedMessageRecords struct {
+	Records []SealedMessageRecord
+}
+
+// SealedMessageRecord is a serialized encrypted message ordered in the block at Height.
+type SealedMessageRecord struct {
+	Height abi.ChainEpoch
+	Msg    []byte
 }
 
 func (ch *Checkpoint) isEmpty() bool {
@@ -221,11 +353,59 @@ func (ch *Checkpoint) Bytes() ([]byte, error) {
 	if err := ch.MarshalCBOR(buf); err != nil {
 		return nil, err
 	}
+	if !ch.Ext.isEmpty() {
+		if err := ch.Ext.MarshalCBOR(buf); err != nil {
+			return nil, err
+		}
+	}
 	return buf.Bytes(), nil
 }
 
+// FromBytes decodes a checkpoint, compressed or not, and checks that it commits exactly the blocks
+// since its parent.
 func (ch *Checkpoint) FromBytes(b []byte) error {
-	return ch.UnmarshalCBOR(bytes.NewReader(b))
+	b, err := compression.Decompress(b)
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(b)
+	if err := ch.UnmarshalCBOR(r); err != nil {
+		return err
+	}
+	if r.Len() > 0 {
+		if err := ch.Ext.UnmarshalCBOR(r); err != nil {
+			return xerrors.Errorf("invalid checkpoint extension: %w", err)
+		}
+		if r.Len() > 0 {
+			return xerrors.Errorf("%d bytes after the checkpoint extension", r.Len())
+		}
+	}
+	return ch.validate()
+}
+
+// validate checks the range of blocks committed by the checkpoint.
+//
+// A checkpoint at height h with parent at height p commits the blocks p, ..., h-1,
+// so it must include h-p CIDs, and h-p must not be above MaxCheckpointBlocks.
+func (ch *Checkpoint) validate() error {
+	return ch.validateWithLimit(MaxCheckpointBlocks)
+}
+
+// validateWithLimit checks the range of blocks committed by the checkpoint, which must not be above max.
+func (ch *Checkpoint) validateWithLimit(max int) error {
+	if ch.isEmpty() {
+		return nil
+	}
+	if len(ch.BlockCids) > max {
+		return xerrors.Errorf("checkpoint commits %d blocks, the maximum is %d", len(ch.BlockCids), max)
+	}
+	if ch.Height <= ch.Parent.Height {
+		return xerrors.Errorf("checkpoint height %d is not above the parent height %d", ch.Height, ch.Parent.Height)
+	}
+	if n := ch.Height - ch.Parent.Height; abi.ChainEpoch(len(ch.BlockCids)) != n {
+		return xerrors.Errorf("checkpoint at height %d commits %d blocks, expected %d", ch.Height, len(ch.BlockCids), n)
+	}
+	return nil
 }
 
 func (ch *Checkpoint) Cid() (cid.Cid, error) {
@@ -269,23 +449,73 @@ func CheckpointAsVRFProof(ch *checkpoint.StableCheckpoint) (*ltypes.Ticket, erro
 	return &ltypes.Ticket{VRFProof: b}, nil
 }
 
+// TagHeaderExtensions wraps the checkpoint and the certificate of a block in the envelope of the
+// header extensions, so they are self-describing.
+func TagHeaderExtensions(t *ltypes.Ticket, ep *ltypes.ElectionProof, compact bool) {
+	t.VRFProof = headerext.Encode(headerext.Checkpoint, t.VRFProof)
+	if compact {
+		ep.VRFProof = headerext.Encode(headerext.CompactCert, ep.VRFProof)
+	} else {
+		ep.VRFProof = headerext.Encode(headerext.Cert, ep.VRFProof)
+	}
+}
+
+// CheckpointFromVRFProof returns the checkpoint included in a ticket, tagged or not.
 func CheckpointFromVRFProof(t *ltypes.Ticket) (*checkpoint.StableCheckpoint, error) {
+	_, b, err := headerext.DecodeAs(t.VRFProof, headerext.Checkpoint)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting checkpoint data from VRF Proof: %w", err)
+	}
 	ch := &checkpoint.StableCheckpoint{}
-	err := ch.Deserialize(t.VRFProof)
+	err = ch.Deserialize(b)
 	if err != nil {
 		return nil, xerrors.Errorf("error getting checkpoint data from VRF Proof: %w", err)
 	}
 	return ch, nil
 }
 
-func CertFromElectionProof(t *ltypes.ElectionProof) (*checkpoint.Certificate, error) {
+// CertFromElectionProof returns the certificate of a checkpoint signed by the membership mb included in an
+// election proof, either serialized by Mir or compact, tagged or not.
+func CertFromElectionProof(t *ltypes.ElectionProof, mb *mirproto.Membership) (*checkpoint.Certificate, error) {
+	k, b, err := headerext.DecodeAs(t.VRFProof, headerext.Cert, headerext.CompactCert)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting checkpoint certificate from ElectionProof: %w", err)
+	}
+	if k == headerext.CompactCert || (k == headerext.Untagged && len(b) > 0 && b[0] == compactCertVersion) {
+		return certFromCompact(b, mb)
+	}
 	cert := &checkpoint.Certificate{}
-	if err := cert.Deserialize(t.VRFProof); err != nil {
+	if err := cert.Deserialize(b); err != nil {
 		return nil, xerrors.Errorf("error getting checkpoint certificate from ElectionProof: %w", err)
 	}
 	return cert, nil
 }
 
+// CheckpointMembership returns the validators of the membership of the epoch that starts
+// with the checkpoint included in the block header. It returns nil if the block has no checkpoint.
+func CheckpointMembership(h *ltypes.BlockHeader) ([]address.Address, error) {
+	if !hasCheckpoint(h) {
+		return nil, nil
+	}
+	ch, err := CheckpointFromVRFProof(h.Ticket)
+	if err != nil {
+		return nil, err
+	}
+	mbs := ch.Memberships()
+	if len(mbs) == 0 {
+		return nil, xerrors.Errorf("checkpoint at height %d without membership", h.Height)
+	}
+	var validators []address.Address
+	for id := range mbs[0].Nodes {
+		addr, err := address.NewFromString(id.Pb())
+		if err != nil {
+			return nil, xerrors.Errorf("invalid validator ID %s in checkpoint membership: %w", id, err)
+		}
+		validators = append(validators, addr)
+	}
+	return validators, nil
+}
+
 func UnwrapCheckpointSnapshot(ch *checkpoint.StableCheckpoint) (*Checkpoint, error) {
 	snap := &Checkpoint{}
 	err := snap.FromBytes(ch.Snapshot.AppData)
@@ -322,9 +552,32 @@ func GetCheckpointByHeight(ctx context.Context, ds db.DB,
 		}
 	}
 
+	return DeserializeCheckpoint(b)
+}
+
+// DeserializeCheckpoint decodes a stable checkpoint persisted by a validator, compressed or not.
+func DeserializeCheckpoint(b []byte) (*checkpoint.StableCheckpoint, error) {
+	b, err := compression.Decompress(b)
+	if err != nil {
+		return nil, err
+	}
 	ch := &checkpoint.StableCheckpoint{}
-	err = ch.Deserialize(b)
-	return ch, err
+	if err := ch.Deserialize(b); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// serializeCheckpoint serializes a stable checkpoint to persist it, compressed if compress is set.
+func serializeCheckpoint(ch *checkpoint.StableCheckpoint, compress bool) ([]byte, error) {
+	b, err := ch.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	if compress {
+		b = compression.Compress(b)
+	}
+	return b, nil
 }
 
 // CheckpointToFile persist Mir stable checkpoint on a file.
@@ -336,6 +589,23 @@ func CheckpointToFile(ch *checkpoint.StableCheckpoint, path string) error {
 	return serializedCheckToFile(b, path)
 }
 
+// ExportCheckpoints writes the checkpoints persisted by the validator from height from on to dir,
+// one file per checkpoint named after its height, and returns the number of checkpoints written.
+func ExportCheckpoints(ctx context.Context, ds db.DB, dir string, from abi.ChainEpoch) (int, error) {
+	n := 0
+	err := walkCheckpoints(ctx, ds, func(ch *checkpoint.StableCheckpoint, snap *Checkpoint) (bool, error) {
+		if snap.Height < from {
+			return false, nil
+		}
+		if err := CheckpointToFile(ch, filepath.Join(dir, fmt.Sprintf("checkpoint-height-%d.chkp", snap.Height))); err != nil {
+			return false, err
+		}
+		n++
+		return true, nil
+	})
+	return n, err
+}
+
 func serializedCheckToFile(b []byte, path string) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0770); err != nil {
 		return fmt.Errorf("error creating directory for checkpoint persistence: %s", err)
@@ -0,0 +1,170 @@
+package mir
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	u "github.com/ipfs/go-ipfs-util"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/mir/pkg/checkpoint"
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	trantor "github.com/filecoin-project/mir/pkg/trantor/types"
+	mirtypes "github.com/filecoin-project/mir/pkg/types"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/compression"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestCheckpointFromBytes(t *testing.T) {
+	cids := func(n int) []cid.Cid {
+		var cs []cid.Cid
+		for i := 0; i < n; i++ {
+			cs = append(cs, cid.NewCidV0(u.Hash([]byte{byte(i), byte(i >> 8)})))
+		}
+		return cs
+	}
+	decode := func(ch *Checkpoint) error {
+		b, err := ch.Bytes()
+		require.NoError(t, err)
+		return new(Checkpoint).FromBytes(b)
+	}
+
+	parent := ParentMeta{Height: 10, Cid: cid.NewCidV0(u.Hash([]byte("parent")))}
+	require.NoError(t, decode(&Checkpoint{Height: 15, Parent: parent, BlockCids: cids(5)}))
+
+	// The checkpoint must commit all the blocks since the parent, and only them.
+	require.Error(t, decode(&Checkpoint{Height: 15, Parent: parent, BlockCids: cids(4)}))
+	require.Error(t, decode(&Checkpoint{Height: 15, Parent: parent, BlockCids: cids(6)}))
+	require.Error(t, decode(&Checkpoint{Height: 10, Parent: parent}))
+
+	// Checkpoints are decoded up to the limit of the encoding, and further limited by the validators.
+	long := &Checkpoint{Height: 10 + MaxCheckpointBlocks, Parent: parent, BlockCids: cids(MaxCheckpointBlocks)}
+	require.NoError(t, decode(long))
+	require.Error(t, long.validateWithLimit(DefaultMaxCheckpointBlocks))
+	require.NoError(t, (&Checkpoint{Height: 14, Parent: parent, BlockCids: cids(4)}).validateWithLimit(4))
+}
+
+func TestCheckpointExtension(t *testing.T) {
+	parent := ParentMeta{Height: 10, Cid: cid.NewCidV0(u.Hash([]byte("parent")))}
+	blocks := []cid.Cid{cid.NewCidV0(u.Hash([]byte("block")))}
+
+	// Checkpoints without extension keep the encoding they had before it.
+	ch := &Checkpoint{Height: 11, Parent: parent, BlockCids: blocks}
+	b, err := ch.Bytes()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, ch.MarshalCBOR(&buf))
+	require.Equal(t, buf.Bytes(), b)
+
+	ch.Ext.Timestamps = []ValidatorTimestamp{{Validator: "id1", Timestamp: 100}}
+	b, err = ch.Bytes()
+	require.NoError(t, err)
+	got := &Checkpoint{}
+	require.NoError(t, got.FromBytes(b))
+	require.Equal(t, ch.Ext, got.Ext)
+
+	// Trailing bytes after the extension are rejected.
+	require.Error(t, new(Checkpoint).FromBytes(append(b, 0)))
+}
+
+func TestCompressedCheckpoint(t *testing.T) {
+	var cids []cid.Cid
+	for i := 0; i < 1000; i++ {
+		cids = append(cids, cid.NewCidV0(u.Hash([]byte{byte(i), byte(i >> 8)})))
+	}
+	parent := ParentMeta{Height: 0, Cid: cid.NewCidV0(u.Hash([]byte("parent")))}
+	snap := &Checkpoint{Height: 1000, Parent: parent, BlockCids: cids}
+	b, err := snap.Bytes()
+	require.NoError(t, err)
+	compressed := compression.Compress(b)
+
+	// Compressed snapshots are decoded transparently, and their CID is the one of the uncompressed snapshot.
+	got := &Checkpoint{}
+	require.NoError(t, got.FromBytes(compressed))
+	require.Equal(t, snap.BlockCids, got.BlockCids)
+	c1, err := snap.Cid()
+	require.NoError(t, err)
+	c2, err := got.Cid()
+	require.NoError(t, err)
+	require.Equal(t, c1, c2)
+
+	ids := []mirtypes.NodeID{"t1a", "t1b"}
+	ch := certCheckpoint(ids, checkpoint.Certificate{"t1a": []byte("sig-a"), "t1b": []byte("sig-b")})
+	ch.Snapshot.AppData = compressed
+	for _, compress := range []bool{false, true} {
+		b, err := serializeCheckpoint(ch, compress)
+		require.NoError(t, err)
+		require.Equal(t, compress, compression.IsCompressed(b))
+		dec, err := DeserializeCheckpoint(b)
+		require.NoError(t, err)
+		require.Equal(t, ch.Snapshot.AppData, dec.Snapshot.AppData)
+		require.Equal(t, ch.Certificate(), dec.Certificate())
+	}
+}
+
+func TestBatchID(t *testing.T) {
+	tx := func(client string, no uint64, data string) *mirproto.Transaction {
+		return &mirproto.Transaction{ClientId: trantor.ClientID(client), TxNo: trantor.TxNo(no), Data: []byte(data)}
+	}
+	b1 := []*mirproto.Transaction{tx("a", 0, "x"), tx("b", 1, "y")}
+	b2 := []*mirproto.Transaction{tx("a", 0, "x"), tx("b", 1, "y")}
+	require.Equal(t, batchID(b1), batchID(b2))
+	require.NotEqual(t, batchID(b1), batchID(b1[:1]))
+	require.NotEqual(t, batchID(b1), batchID([]*mirproto.Transaction{tx("a", 1, "x"), tx("b", 1, "y")}))
+}
+
+func TestMessageBytesMatchesSerialize(t *testing.T) {
+	msg := newTestSignedMessage(t, 1)
+	expected, err := msg.Serialize()
+	require.NoError(t, err)
+
+	b, err := MessageBytes(msg)
+	require.NoError(t, err)
+	require.Equal(t, append(expected, SignedMessageType), b)
+
+	// The returned bytes don't alias the pooled buffer.
+	b2, err := MessageBytes(newTestSignedMessage(t, 2))
+	require.NoError(t, err)
+	require.Equal(t, append(expected, SignedMessageType), b)
+	require.NotEqual(t, b, b2)
+}
+
+// BenchmarkMessageBytes measures the encoding of the messages of a batch of 1k transactions.
+func BenchmarkMessageBytes(b *testing.B) {
+	msgs := make([]*types.SignedMessage, 1000)
+	for i := range msgs {
+		msgs[i] = newTestSignedMessage(b, uint64(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, msg := range msgs {
+			if _, err := MessageBytes(msg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkMessageBytesSerialize is the baseline of BenchmarkMessageBytes, encoding with Serialize.
+func BenchmarkMessageBytesSerialize(b *testing.B) {
+	msgs := make([]*types.SignedMessage, 1000)
+	for i := range msgs {
+		msgs[i] = newTestSignedMessage(b, uint64(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, msg := range msgs {
+			data, err := msg.Serialize()
+			if err != nil {
+				b.Fatal(err)
+			}
+			_ = append(data, SignedMessageType)
+		}
+	}
+}
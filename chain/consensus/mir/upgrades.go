@@ -0,0 +1,59 @@
+package mir
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetparams"
+)
+
+// upgrades tells which consensus features are active at a height, from the activation heights in the
+// parameters of the subnet. The parameters are shared by all the validators and daemons of the subnet,
+// and the heights are checked against the height of the block being created, validated or applied, which
+// they all agree on, so they switch at the same block. Features without an activation height keep the
+// behavior of the chains that predate them.
+type upgrades struct {
+	subnetparams.Upgrades
+}
+
+func newUpgrades(netName string) upgrades {
+	return upgrades{subnetparams.GetUpgrades(netName)}
+}
+
+// weightedQuorums returns whether reconfiguration votes are weighted at height h.
+// Below the upgrade, f+1 votes of the n validators are enough.
+func (u upgrades) weightedQuorums(h abi.ChainEpoch) bool {
+	return subnetparams.Active(u.WeightedQuorums, h)
+}
+
+// batchTimestamps returns whether the block at height h takes its timestamp from the batches.
+func (u upgrades) batchTimestamps(h abi.ChainEpoch) bool {
+	return subnetparams.Active(u.BatchTimestamps, h)
+}
+
+// taggedHeaders returns whether the block at height h includes its checkpoint tagged.
+func (u upgrades) taggedHeaders(h abi.ChainEpoch) bool {
+	return subnetparams.Active(u.TaggedHeaders, h)
+}
+
+// compactCerts returns whether the block at height h includes a compact checkpoint certificate.
+func (u upgrades) compactCerts(h abi.ChainEpoch) bool {
+	return subnetparams.Active(u.CompactCerts, h)
+}
+
+// configMsgNonces returns whether the configuration messages of the block at height h have the nonces
+// derived from the height.
+func (u upgrades) configMsgNonces(h abi.ChainEpoch) bool {
+	return subnetparams.Active(u.ConfigMsgNonces, h)
+}
+
+// encryptedTxs returns whether the encrypted messages and key reveals ordered in the batch of the block
+// at height h are applied.
+func (u upgrades) encryptedTxs(h abi.ChainEpoch) bool {
+	return subnetparams.Active(u.EncryptedTxs, h)
+}
+
+// canonicalSetHashes returns whether the configuration votes applied at height h are counted by the
+// canonical hash of the validator set.
+func (u upgrades) canonicalSetHashes(h abi.ChainEpoch) bool {
+	return subnetparams.Active(u.CanonicalSetHashes, h)
+}
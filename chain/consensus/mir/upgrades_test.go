@@ -0,0 +1,42 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetparams"
+)
+
+func TestUpgrades(t *testing.T) {
+	// Without upgrades, chains keep the legacy behavior.
+	u := newUpgrades("/root/upgrades")
+	require.False(t, u.weightedQuorums(1000))
+	require.False(t, u.batchTimestamps(1000))
+	require.False(t, u.taggedHeaders(1000))
+	require.False(t, u.compactCerts(1000))
+	require.False(t, u.encryptedTxs(1000))
+	require.False(t, u.canonicalSetHashes(1000))
+
+	quorums, timestamps, certs, encrypted := abi.ChainEpoch(10), abi.ChainEpoch(20), abi.ChainEpoch(0), abi.ChainEpoch(30)
+	hashes := abi.ChainEpoch(40)
+	require.NoError(t, subnetparams.Set("/root/upgrades", subnetparams.Params{
+		Upgrades: &subnetparams.Upgrades{
+			WeightedQuorums: &quorums, BatchTimestamps: &timestamps, CompactCerts: &certs, EncryptedTxs: &encrypted,
+			CanonicalSetHashes: &hashes,
+		},
+	}))
+	u = newUpgrades("/root/upgrades")
+	require.False(t, u.weightedQuorums(9))
+	require.True(t, u.weightedQuorums(10))
+	require.False(t, u.batchTimestamps(19))
+	require.True(t, u.batchTimestamps(20))
+	require.False(t, u.taggedHeaders(1000))
+	require.True(t, u.compactCerts(0))
+	require.False(t, u.encryptedTxs(29))
+	require.True(t, u.encryptedTxs(30))
+	require.False(t, u.canonicalSetHashes(39))
+	require.True(t, u.canonicalSetHashes(40))
+}
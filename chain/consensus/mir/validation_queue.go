@@ -0,0 +1,138 @@
+package mir
+
+import (
+	"container/heap"
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+// MaxConcurrentValidationsEnv sets the number of blocks validated concurrently, by default the number of CPUs.
+const MaxConcurrentValidationsEnv = "MIR_MAX_CONCURRENT_VALIDATIONS"
+
+func maxConcurrentValidations() int {
+	if n, err := strconv.Atoi(os.Getenv(MaxConcurrentValidationsEnv)); err == nil && n > 0 {
+		return n
+	}
+	return runtime.NumCPU()
+}
+
+// validationQueue bounds the number of blocks validated concurrently.
+//
+// When a learner catches up, the blocks of the chain selected by the syncer and the blocks received
+// through gossip compete for validation. The syncer validates the selected chain from its base upwards,
+// so the blocks waiting for a slot are served lowest height first: the blocks the syncer needs to make
+// progress aren't starved by the blocks received ahead of the chain.
+type validationQueue struct {
+	lk      sync.Mutex
+	limit   int
+	running int
+	seq     uint64
+	waiting validationHeap
+}
+
+func newValidationQueue(limit int) *validationQueue {
+	return &validationQueue{limit: limit}
+}
+
+// acquire waits for a validation slot for a block at height h. The returned function releases the slot.
+func (q *validationQueue) acquire(ctx context.Context, h abi.ChainEpoch) (func(), error) {
+	q.lk.Lock()
+	if q.running < q.limit {
+		q.running++
+		q.lk.Unlock()
+		return q.release, nil
+	}
+	w := &validationWaiter{height: h, seq: q.seq, ready: make(chan struct{})}
+	q.seq++
+	heap.Push(&q.waiting, w)
+	queued := len(q.waiting)
+	q.lk.Unlock()
+
+	stats.Record(ctx, metrics.MirValidationsQueued.M(int64(queued)))
+	start := time.Now()
+	defer func() {
+		stats.Record(ctx, metrics.MirValidationWait.M(metrics.SinceInMilliseconds(start)))
+	}()
+
+	select {
+	case <-w.ready:
+		return q.release, nil
+	case <-ctx.Done():
+		q.lk.Lock()
+		defer q.lk.Unlock()
+		if w.index >= 0 {
+			heap.Remove(&q.waiting, w.index)
+			return nil, ctx.Err()
+		}
+		// The slot was handed over while the context was being cancelled.
+		q.releaseLocked()
+		return nil, ctx.Err()
+	}
+}
+
+func (q *validationQueue) release() {
+	q.lk.Lock()
+	defer q.lk.Unlock()
+	q.releaseLocked()
+}
+
+func (q *validationQueue) releaseLocked() {
+	if len(q.waiting) == 0 {
+		q.running--
+		return
+	}
+	// The slot is handed over to the next block, so running doesn't change.
+	w := heap.Pop(&q.waiting).(*validationWaiter)
+	close(w.ready)
+}
+
+type validationWaiter struct {
+	height abi.ChainEpoch
+	seq    uint64
+	ready  chan struct{}
+	// index in the heap, -1 once the waiter got a slot.
+	index int
+}
+
+type validationHeap []*validationWaiter
+
+func (h validationHeap) Len() int { return len(h) }
+
+func (h validationHeap) Less(i, j int) bool {
+	if h[i].height != h[j].height {
+		return h[i].height < h[j].height
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h validationHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *validationHeap) Push(x interface{}) {
+	w := x.(*validationWaiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *validationHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*h = old[:n-1]
+	return w
+}
@@ -0,0 +1,64 @@
+package mir
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestValidationQueueOrder(t *testing.T) {
+	ctx := context.Background()
+	q := newValidationQueue(1)
+
+	release, err := q.acquire(ctx, 10)
+	require.NoError(t, err)
+
+	order := make(chan abi.ChainEpoch, 3)
+	for i, h := range []abi.ChainEpoch{5, 3, 4} {
+		i, h := i, h
+		go func() {
+			release, err := q.acquire(ctx, h)
+			if err != nil {
+				return
+			}
+			order <- h
+			release()
+		}()
+		require.Eventually(t, func() bool {
+			q.lk.Lock()
+			defer q.lk.Unlock()
+			return q.waiting.Len() == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	release()
+	require.Equal(t, abi.ChainEpoch(3), <-order)
+	require.Equal(t, abi.ChainEpoch(4), <-order)
+	require.Equal(t, abi.ChainEpoch(5), <-order)
+
+	q.lk.Lock()
+	require.Equal(t, 0, q.running)
+	q.lk.Unlock()
+}
+
+func TestValidationQueueCancel(t *testing.T) {
+	q := newValidationQueue(1)
+	release, err := q.acquire(context.Background(), 1)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = q.acquire(ctx, 2)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 0, q.waiting.Len())
+
+	release()
+	release, err = q.acquire(context.Background(), 3)
+	require.NoError(t, err)
+	release()
+	require.Equal(t, 0, q.running)
+}
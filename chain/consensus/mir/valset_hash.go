@@ -0,0 +1,67 @@
+package mir
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"golang.org/x/xerrors"
+)
+
+// ValidatorSetHashVersion is the version of the canonical validator set hash.
+// It is part of the hash, so hashes computed with different schemes never collide.
+const ValidatorSetHashVersion = 1
+
+const validatorSetHashDomain = "eudico/mir/validator-set"
+
+// ValidatorSetHash returns the canonical hash of a validator set. It identifies the set a validator
+// votes for in configuration transactions, and it can be used to compare the sets seen by different
+// validators.
+//
+// The hash is the hex-encoded SHA-256 digest of the following fields, prefixed by "v<version>:":
+//   - the domain tag "eudico/mir/validator-set" and the version, as a big-endian uint64;
+//   - the configuration number and the number of validators, as big-endian uint64s;
+//   - for every validator, in increasing order of ID: the ID, the network address and the weight
+//     in decimal, each of them prefixed by its length as a big-endian uint64.
+//
+// Two sets with the same validators hash to the same value whatever the order of the validators.
+func ValidatorSetHash(set *validator.Set) (string, error) {
+	if set == nil {
+		return "", xerrors.New("nil validator set")
+	}
+	vs := append([]*validator.Validator(nil), set.GetValidators()...)
+	sort.Slice(vs, func(i, j int) bool { return vs[i].ID() < vs[j].ID() })
+
+	h := sha256.New()
+	writeString := func(s string) {
+		_ = binary.Write(h, binary.BigEndian, uint64(len(s)))
+		_, _ = h.Write([]byte(s))
+	}
+	writeString(validatorSetHashDomain)
+	_ = binary.Write(h, binary.BigEndian, uint64(ValidatorSetHashVersion))
+	_ = binary.Write(h, binary.BigEndian, set.ConfigurationNumber)
+	_ = binary.Write(h, binary.BigEndian, uint64(len(vs)))
+	for _, v := range vs {
+		writeString(v.ID())
+		writeString(v.NetAddr)
+		writeString(v.Weight.String())
+	}
+	return fmt.Sprintf("v%d:%s", ValidatorSetHashVersion, hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// voteKey returns the key the votes for a validator set are counted by: the canonical hash of the set
+// from the CanonicalSetHashes upgrade, and the legacy hash of the set below it. The keys are part of the
+// votes recorded in the checkpoints, so all the validators switch at the same height.
+func voteKey(set *validator.Set, canonical bool) (string, error) {
+	if canonical {
+		return ValidatorSetHash(set)
+	}
+	h, err := set.Hash()
+	if err != nil {
+		return "", err
+	}
+	return string(h), nil
+}
@@ -0,0 +1,63 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/stretchr/testify/require"
+
+	mir "github.com/filecoin-project/mir/pkg/types"
+)
+
+func TestValidatorSetHash(t *testing.T) {
+	v1, err := validator.NewValidatorFromString("t1wpixt5mihkj75lfhrnaa6v56n27epvlgwparujy:1@/ip4/127.0.0.1/tcp/10000/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+	v2, err := validator.NewValidatorFromString("t12zjpclnis2uytmcydrx7i5jcbvehs5ut3x6mvvq:2@/ip4/127.0.0.1/tcp/10001/p2p/12D3KooWJhKBXvytYgPCAaiRtiNLJNSFG5jreKDu2jiVpJetzvVJ")
+	require.NoError(t, err)
+
+	h, err := ValidatorSetHash(validator.NewValidatorSet(1, []*validator.Validator{v1, v2}))
+	require.NoError(t, err)
+	require.Regexp(t, "^v1:[0-9a-f]{64}$", h)
+
+	// The order of the validators doesn't matter.
+	reordered, err := ValidatorSetHash(validator.NewValidatorSet(1, []*validator.Validator{v2, v1}))
+	require.NoError(t, err)
+	require.Equal(t, h, reordered)
+
+	other, err := ValidatorSetHash(validator.NewValidatorSet(2, []*validator.Validator{v1, v2}))
+	require.NoError(t, err)
+	require.NotEqual(t, h, other)
+
+	other, err = ValidatorSetHash(validator.NewValidatorSet(1, []*validator.Validator{v1}))
+	require.NoError(t, err)
+	require.NotEqual(t, h, other)
+
+	_, err = ValidatorSetHash(nil)
+	require.Error(t, err)
+
+	// Below the upgrade, votes are keyed by the legacy hash of the set.
+	set := validator.NewValidatorSet(1, []*validator.Validator{v1, v2})
+	key, err := voteKey(set, true)
+	require.NoError(t, err)
+	require.Equal(t, h, key)
+	legacy, err := set.Hash()
+	require.NoError(t, err)
+	key, err = voteKey(set, false)
+	require.NoError(t, err)
+	require.Equal(t, string(legacy), key)
+}
+
+func TestMigrateVotes(t *testing.T) {
+	votes := NewConfigurationVotes(map[uint64]map[string]map[mir.NodeID]struct{}{})
+	require.NoError(t, votes.VoteForConfiguration(1, "legacy", "id1"))
+	require.NoError(t, votes.VoteForConfiguration(1, "v1:new", "id2"))
+
+	require.True(t, votes.MigrateVotes(1, "legacy", "v1:new"))
+	require.Equal(t, 2, votes.GetVotesForConfiguration(1, "v1:new"))
+	require.Equal(t, 0, votes.GetVotesForConfiguration(1, "legacy"))
+	require.False(t, votes.MigrateVotes(1, "legacy", "v1:new"))
+	require.False(t, votes.MigrateVotes(2, "legacy", "v1:new"))
+
+	// Migrated votes are still counted once.
+	require.ErrorIs(t, votes.VoteForConfiguration(1, "v1:new", "id1"), ErrDuplicateVote)
+}
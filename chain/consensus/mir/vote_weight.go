@@ -0,0 +1,49 @@
+package mir
+
+import (
+	"math/big"
+
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	t "github.com/filecoin-project/mir/pkg/types"
+)
+
+// voteWeights returns the voting weight of every validator of the membership and the total weight.
+// The weight of a validator is the Weight of its entry in the validator set. If no validator
+// has a weight, e.g. with memberships from files that don't set it, every validator weighs one.
+func voteWeights(mb *mirproto.Membership) (map[t.NodeID]*big.Int, *big.Int) {
+	weights := make(map[t.NodeID]*big.Int, len(mb.Nodes))
+	total := new(big.Int)
+	for id, n := range mb.Nodes {
+		w, ok := new(big.Int).SetString(string(n.Weight), 10)
+		if !ok || w.Sign() < 0 {
+			w = new(big.Int)
+		}
+		weights[id] = w
+		total.Add(total, w)
+	}
+
+	if total.Sign() == 0 {
+		for id := range weights {
+			weights[id] = big.NewInt(1)
+		}
+		total.SetInt64(int64(len(weights)))
+	}
+	return weights, total
+}
+
+// votedWeight returns the sum of the weights of the voters. Voters not in weights don't count.
+func votedWeight(weights map[t.NodeID]*big.Int, voters map[t.NodeID]struct{}) *big.Int {
+	sum := new(big.Int)
+	for id := range voters {
+		if w, ok := weights[id]; ok {
+			sum.Add(sum, w)
+		}
+	}
+	return sum
+}
+
+// hasWeightQuorum returns whether the voted weight is more than two thirds of the total weight.
+func hasWeightQuorum(voted, total *big.Int) bool {
+	v := new(big.Int).Mul(voted, big.NewInt(3))
+	return v.Cmp(new(big.Int).Mul(total, big.NewInt(2))) > 0
+}
@@ -0,0 +1,48 @@
+package mir
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mirproto "github.com/filecoin-project/mir/pkg/pb/trantorpb/types"
+	tt "github.com/filecoin-project/mir/pkg/trantor/types"
+	"github.com/filecoin-project/mir/pkg/types"
+)
+
+func weightedMembership(weights map[types.NodeID]string) *mirproto.Membership {
+	mb := &mirproto.Membership{Nodes: make(map[types.NodeID]*mirproto.NodeIdentity)}
+	for id, w := range weights {
+		mb.Nodes[id] = &mirproto.NodeIdentity{Id: id, Weight: tt.VoteWeight(w)}
+	}
+	return mb
+}
+
+func voters(ids ...types.NodeID) map[types.NodeID]struct{} {
+	m := make(map[types.NodeID]struct{})
+	for _, id := range ids {
+		m[id] = struct{}{}
+	}
+	return m
+}
+
+func TestVoteWeights(t *testing.T) {
+	weights, total := voteWeights(weightedMembership(map[types.NodeID]string{"a": "60", "b": "20", "c": "10", "d": "10"}))
+	require.Equal(t, big.NewInt(100), total)
+
+	// A validator holding most of the stake can't reconfigure alone...
+	require.False(t, hasWeightQuorum(votedWeight(weights, voters("a")), total))
+	// ...but more than 2/3 of the weight is enough, whatever the number of validators holding it.
+	require.True(t, hasWeightQuorum(votedWeight(weights, voters("a", "c")), total))
+	require.False(t, hasWeightQuorum(votedWeight(weights, voters("b", "c", "d")), total))
+
+	// Votes of validators not in the membership don't count.
+	require.Equal(t, big.NewInt(60), votedWeight(weights, voters("a", "e")))
+
+	// Without weights, every validator weighs one.
+	weights, total = voteWeights(weightedMembership(map[types.NodeID]string{"a": "", "b": "0", "c": "", "d": ""}))
+	require.Equal(t, big.NewInt(4), total)
+	require.False(t, hasWeightQuorum(votedWeight(weights, voters("a", "b")), total))
+	require.True(t, hasWeightQuorum(votedWeight(weights, voters("a", "b", "c")), total))
+}
@@ -0,0 +1,71 @@
+package mir
+
+import (
+	"context"
+	"time"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// WarmupTimeout bounds the warmup of the validator before its Mir node starts.
+var WarmupTimeout = 30 * time.Second
+
+// warmup selects the messages of the mempool once before the validator joins the ordering.
+//
+// The selection validates the backlog against the state of the chain head, which loads the actors of the
+// senders into the caches of the node, so the first batches requested by Mir don't pay for it. The warmup
+// is best effort: the validator starts anyway if it fails or times out.
+func (m *Manager) warmup(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, WarmupTimeout)
+	defer cancel()
+
+	start := time.Now()
+	l := log.With("validator", m.id)
+	base, err := m.stateManager.heads.Head(ctx)
+	if err != nil {
+		l.Warnf("warmup skipped: failed to get chain head: %v", err)
+		return
+	}
+	msgs, err := selectWithRetries(ctx, m.mpoolSelectRetries, func() ([]*types.SignedMessage, error) {
+		return m.lotusNode.MpoolSelect(ctx, base.Key(), 1)
+	})
+	if err != nil {
+		l.Warnf("warmup skipped: failed to select mempool messages: %v", err)
+		return
+	}
+	l.Infow("warmup finished", "backlog", len(msgs), "height", base.Height(), "duration", time.Since(start))
+}
+
+// batchRamp limits the size of the batches proposed in the first epochs after the validator (re)starts,
+// so a large backlog doesn't make its first proposals huge and slow to disseminate while the rest of
+// validators are catching up with it. The limit grows linearly up to the configured batch size.
+type batchRamp struct {
+	max    int
+	epochs uint64
+
+	started bool
+	start   uint64
+}
+
+func newBatchRamp(max, epochs int) *batchRamp {
+	if epochs < 0 {
+		epochs = 0
+	}
+	return &batchRamp{max: max, epochs: uint64(epochs)}
+}
+
+// limit returns the number of transactions that can be proposed in a batch in epoch.
+// The ramp starts with the first batch proposed.
+func (r *batchRamp) limit(epoch uint64) int {
+	if !r.started {
+		r.started, r.start = true, epoch
+	}
+	if epoch < r.start || epoch-r.start >= r.epochs {
+		return r.max
+	}
+	l := r.max * int(epoch-r.start+1) / int(r.epochs+1)
+	if l < 1 {
+		l = 1
+	}
+	return l
+}
@@ -0,0 +1,24 @@
+package mir
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchRamp(t *testing.T) {
+	r := newBatchRamp(1000, 3)
+	// The ramp starts in the epoch of the first batch.
+	require.Equal(t, 250, r.limit(7))
+	require.Equal(t, 250, r.limit(7))
+	require.Equal(t, 500, r.limit(8))
+	require.Equal(t, 750, r.limit(9))
+	require.Equal(t, 1000, r.limit(10))
+	require.Equal(t, 1000, r.limit(100))
+
+	r = newBatchRamp(2, 3)
+	require.Equal(t, 1, r.limit(0))
+
+	r = newBatchRamp(1000, 0)
+	require.Equal(t, 1000, r.limit(0))
+}
@@ -472,11 +472,14 @@ func (c *client) RemovePeer(p peer.ID) {
 
 // getShuffledPeers returns a preference-sorted set of peers (by latency
 // and failure counting), shuffling the first few peers so we don't always
-// pick the same peer.
+// pick the same peer. Preferred peers are shuffled among themselves, so
+// they are still tried first.
 // FIXME: Consider merging with `shufflePrefix()s`.
 func (c *client) getShuffledPeers() []peer.ID {
 	peers := c.peerTracker.prefSortedPeers()
-	shufflePrefix(peers)
+	n := c.peerTracker.numPreferred(peers)
+	shufflePrefix(peers[:n])
+	shufflePrefix(peers[n:])
 	return peers
 }
 
@@ -0,0 +1,95 @@
+package exchange
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// ServeBandwidthEnv caps the bandwidth, in bytes per second, used by the node to serve the
+	// chain to the peers syncing from it, e.g. validators restored from a checkpoint catching up
+	// with tens of thousands of blocks. It is shared by all the requests; zero or unset is unlimited.
+	ServeBandwidthEnv = "LOTUS_CHAINXCHG_SERVE_BANDWIDTH"
+	// PreferredPeersEnv is a comma-separated list of peer IDs the node syncs from before any other
+	// peer, e.g. archive nodes and learners, to spare the upload of the validators.
+	PreferredPeersEnv = "LOTUS_CHAINXCHG_PREFERRED_PEERS"
+
+	serveChunkSize = 16 << 10
+)
+
+// serveLimiterFromEnv returns the limiter of the bandwidth used to serve responses, or nil if
+// the bandwidth isn't limited.
+func serveLimiterFromEnv() *rate.Limiter {
+	s := os.Getenv(ServeBandwidthEnv)
+	if s == "" {
+		return nil
+	}
+	bw, err := strconv.Atoi(s)
+	if err != nil || bw < 0 {
+		log.Errorf("invalid %s %q: serving without bandwidth limit", ServeBandwidthEnv, s)
+		return nil
+	}
+	if bw == 0 {
+		return nil
+	}
+	if bw < ReadResMinSpeed {
+		log.Warnf("%s of %d bytes/s is below the %d bytes/s peers expect: their requests will time out",
+			ServeBandwidthEnv, bw, ReadResMinSpeed)
+	}
+	burst := bw
+	if burst < serveChunkSize {
+		burst = serveChunkSize
+	}
+	return rate.NewLimiter(rate.Limit(bw), burst)
+}
+
+// pacedWriter writes to w no faster than allowed by the limiter.
+type pacedWriter struct {
+	ctx context.Context
+	w   io.Writer
+	l   *rate.Limiter
+}
+
+func (p *pacedWriter) Write(b []byte) (int, error) {
+	var n int
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > serveChunkSize {
+			chunk = chunk[:serveChunkSize]
+		}
+		if err := p.l.WaitN(p.ctx, len(chunk)); err != nil {
+			return n, err
+		}
+		w, err := p.w.Write(chunk)
+		n += w
+		if err != nil {
+			return n, err
+		}
+		b = b[len(chunk):]
+	}
+	return n, nil
+}
+
+// preferredPeersFromEnv returns the peers listed in PreferredPeersEnv.
+func preferredPeersFromEnv() map[peer.ID]struct{} {
+	s := os.Getenv(PreferredPeersEnv)
+	if s == "" {
+		return nil
+	}
+	peers := make(map[peer.ID]struct{})
+	for _, p := range strings.Split(s, ",") {
+		id, err := peer.Decode(strings.TrimSpace(p))
+		if err != nil {
+			log.Errorf("invalid peer %q in %s: %s", p, PreferredPeersEnv, err)
+			continue
+		}
+		peers[id] = struct{}{}
+	}
+	return peers
+}
@@ -28,14 +28,17 @@ type bsPeerTracker struct {
 
 	peers         map[peer.ID]*peerStats
 	avgGlobalTime time.Duration
+	// Peers tried before any other one.
+	preferred map[peer.ID]struct{}
 
 	pmgr *peermgr.PeerMgr
 }
 
 func newPeerTracker(lc fx.Lifecycle, h host.Host, pmgr *peermgr.PeerMgr) *bsPeerTracker {
 	bsPt := &bsPeerTracker{
-		peers: make(map[peer.ID]*peerStats),
-		pmgr:  pmgr,
+		peers:     make(map[peer.ID]*peerStats),
+		preferred: preferredPeersFromEnv(),
+		pmgr:      pmgr,
 	}
 
 	evtSub, err := h.EventBus().Subscribe(new(peermgr.FilPeerEvt))
@@ -94,6 +97,10 @@ func (bpt *bsPeerTracker) prefSortedPeers() []peer.ID {
 	// sort by 'expected cost' of requesting data from that peer
 	// additionally handle edge cases where not enough data is available
 	sort.Slice(out, func(i, j int) bool {
+		if pi, pj := bpt.isPreferred(out[i]), bpt.isPreferred(out[j]); pi != pj {
+			return pi
+		}
+
 		pi := bpt.peers[out[i]]
 		pj := bpt.peers[out[j]]
 
@@ -123,6 +130,20 @@ func (bpt *bsPeerTracker) prefSortedPeers() []peer.ID {
 	return out
 }
 
+func (bpt *bsPeerTracker) isPreferred(p peer.ID) bool {
+	_, ok := bpt.preferred[p]
+	return ok
+}
+
+// numPreferred returns the number of preferred peers at the start of peers sorted by prefSortedPeers.
+func (bpt *bsPeerTracker) numPreferred(peers []peer.ID) int {
+	n := 0
+	for n < len(peers) && bpt.isPreferred(peers[n]) {
+		n++
+	}
+	return n
+}
+
 const (
 	// xInvAlpha = (N+1)/2
 
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/ipfs/go-cid"
 	inet "github.com/libp2p/go-libp2p/core/network"
 	"go.opencensus.io/trace"
+	"golang.org/x/time/rate"
 	"golang.org/x/xerrors"
 
 	cborutil "github.com/filecoin-project/go-cbor-util"
@@ -21,6 +23,8 @@ import (
 // libp2p ChainExchange protocol.
 type server struct {
 	cs *store.ChainStore
+	// Limits the bandwidth used to write responses, if set.
+	limiter *rate.Limiter
 }
 
 var _ Server = (*server)(nil)
@@ -29,7 +33,8 @@ var _ Server = (*server)(nil)
 // for the libp2p ChainExchange protocol.
 func NewServer(cs *store.ChainStore) Server {
 	return &server{
-		cs: cs,
+		cs:      cs,
+		limiter: serveLimiterFromEnv(),
 	}
 }
 
@@ -55,7 +60,11 @@ func (s *server) HandleStream(stream inet.Stream) {
 	}
 
 	_ = stream.SetDeadline(time.Now().Add(WriteResDeadline))
-	buffered := bufio.NewWriter(stream)
+	var out io.Writer = stream
+	if s.limiter != nil {
+		out = &pacedWriter{ctx: ctx, w: stream, l: s.limiter}
+	}
+	buffered := bufio.NewWriter(out)
 	if err = cborutil.WriteCborRPC(buffered, resp); err == nil {
 		err = buffered.Flush()
 	}
@@ -36,6 +36,7 @@ import (
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/consensus"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetcron"
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
@@ -1297,7 +1298,7 @@ func (mp *MessagePool) HeadChange(ctx context.Context, revert []*types.TipSet, a
 			}
 
 			for _, msg := range smsgs {
-				if !membership.IsConfigMsg(consensus.DefaultGatewayAddr, &msg.Message) {
+				if !membership.IsConfigMsg(consensus.DefaultGatewayAddr, &msg.Message) && !subnetcron.IsCronMsg(string(mp.netName), b, &msg.Message) {
 					rm(msg.Message.From, msg.Message.Nonce)
 					maybeRepub(msg.Cid())
 				}
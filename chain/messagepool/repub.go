@@ -79,7 +79,7 @@ func (mp *MessagePool) republishPendingMessages(ctx context.Context) error {
 		return chains[i].Before(chains[j])
 	})
 
-	gasLimit := build.BlockGasLimit
+	gasLimit := mp.blockGasLimit()
 	minGas := int64(gasguess.MinGas)
 	var msgs []*types.SignedMessage
 loop:
@@ -15,6 +15,7 @@ import (
 	"github.com/filecoin-project/go-state-types/crypto"
 
 	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetparams"
 	"github.com/filecoin-project/lotus/chain/messagepool/gasguess"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/chain/vm"
@@ -24,6 +25,12 @@ var bigBlockGasLimit = big.NewInt(build.BlockGasLimit)
 
 const MaxBlocks = 15
 
+// blockGasLimit returns the gas limit of the blocks of the network of the pool,
+// which subnets can set independently of build.BlockGasLimit.
+func (mp *MessagePool) blockGasLimit() int64 {
+	return subnetparams.BlockGasLimit(string(mp.netName))
+}
+
 type msgChain struct {
 	msgs         []*types.SignedMessage
 	gasReward    *big.Int
@@ -258,7 +265,7 @@ func (mp *MessagePool) selectMessagesOptimal(ctx context.Context, curTs, ts *typ
 	nextChain := 0
 	partitions := make([][]*msgChain, MaxBlocks)
 	for i := 0; i < MaxBlocks && nextChain < len(chains); i++ {
-		gasLimit := build.BlockGasLimit
+		gasLimit := mp.blockGasLimit()
 		msgLimit := build.BlockMessageLimit
 		for nextChain < len(chains) {
 			chain := chains[nextChain]
@@ -590,7 +597,7 @@ func (mp *MessagePool) selectPriorityMessages(ctx context.Context, pending map[a
 	mpCfg := mp.getConfig()
 	result := &selectedMessages{
 		msgs:      make([]*types.SignedMessage, 0, mpCfg.SizeLimitLow),
-		gasLimit:  build.BlockGasLimit,
+		gasLimit:  mp.blockGasLimit(),
 		blsLimit:  cbg.MaxLength,
 		secpLimit: cbg.MaxLength,
 	}
@@ -812,7 +819,7 @@ func (mp *MessagePool) createMessageChains(actor address.Address, mset map[uint6
 		}
 
 		gasLimit += m.Message.GasLimit
-		if gasLimit > build.BlockGasLimit {
+		if gasLimit > mp.blockGasLimit() {
 			break
 		}
 
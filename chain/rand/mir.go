@@ -0,0 +1,80 @@
+package rand
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// mirBeaconLookback is the maximum number of tipsets to walk back looking for a beacon entry.
+const mirBeaconLookback = 20
+
+// mirRand implements the vm.Rand interface for Mir subnets.
+//
+// Beacon randomness is drawn from the beacon entries derived from checkpoints that Mir
+// validators include in blocks when checkpoint randomness is enabled. Chain randomness,
+// and beacon randomness for chains that don't include beacon entries, fall back to fakeRand.
+type mirRand struct {
+	fakeRand
+
+	cs   *store.ChainStore
+	blks []cid.Cid
+}
+
+func (mr *mirRand) GetBeaconRandomness(ctx context.Context, pers crypto.DomainSeparationTag, round abi.ChainEpoch, entropy []byte) ([]byte, error) {
+	if round < 0 {
+		return mr.fakeRand.GetBeaconRandomness(ctx, pers, round, entropy)
+	}
+
+	be, err := mr.beaconEntryForEpoch(ctx, round)
+	if err != nil {
+		return nil, err
+	}
+	if be == nil {
+		return mr.fakeRand.GetBeaconRandomness(ctx, pers, round, entropy)
+	}
+
+	return DrawRandomness(be.Data, pers, round, entropy)
+}
+
+// beaconEntryForEpoch returns the latest beacon entry included in the chain up to the given
+// epoch, or nil if no beacon entry is found within mirBeaconLookback tipsets.
+func (mr *mirRand) beaconEntryForEpoch(ctx context.Context, round abi.ChainEpoch) (*types.BeaconEntry, error) {
+	ts, err := mr.cs.LoadTipSet(ctx, types.NewTipSetKey(mr.blks...))
+	if err != nil {
+		return nil, err
+	}
+	if round > ts.Height() {
+		return nil, xerrors.Errorf("cannot draw randomness from the future")
+	}
+
+	randTs, err := mr.cs.GetTipsetByHeight(ctx, round, ts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < mirBeaconLookback; i++ {
+		cbe := randTs.Blocks()[0].BeaconEntries
+		if len(cbe) > 0 {
+			return &cbe[len(cbe)-1], nil
+		}
+		if randTs.Height() == 0 {
+			return nil, nil
+		}
+
+		next, err := mr.cs.LoadTipSet(ctx, randTs.Parents())
+		if err != nil {
+			return nil, xerrors.Errorf("failed to load parents when searching back for beacon entry: %w", err)
+		}
+		randTs = next
+	}
+
+	return nil, nil
+}
@@ -118,7 +118,7 @@ func NewStateRand(cs *store.ChainStore, blks []cid.Cid, b beacon.Schedule, netwo
 		log.Debug("=================================================================================")
 		log.Debug("DANGER ZONE! YOU ARE USING FAKE RANDOMNESS FOR YOUR VM AND PROOFS. USE WITH CARE!")
 		log.Debug("=================================================================================")
-		return &fakeRand{}
+		return &mirRand{cs: cs, blks: blks}
 	}
 
 	return &stateRand{
@@ -8,12 +8,30 @@ import (
 	"github.com/ipfs/go-cid"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
 )
 
+// ErrMsgNotIndexed is returned by a MsgIndex without an entry for a message.
+var ErrMsgNotIndexed = errors.New("message not indexed")
+
+// MsgInfo is the block in which a message was included, as recorded by a MsgIndex.
+type MsgInfo struct {
+	Message cid.Cid
+	Block   cid.Cid
+	Epoch   abi.ChainEpoch
+	// Final is set if the block can't be reverted anymore.
+	Final bool
+}
+
+// MsgIndex indexes the messages included in the chain by sender and nonce.
+type MsgIndex interface {
+	GetMsgInfo(ctx context.Context, from address.Address, nonce uint64) (*MsgInfo, error)
+}
+
 // WaitForMessage blocks until a message appears on chain. It looks backwards in the chain to see if this has already
 // happened, with an optional limit to how many epochs it will search. It guarantees that the message has been on
 // chain for at least confidence epochs without being reverted before returning.
@@ -169,6 +187,19 @@ func (sm *StateManager) searchBackForMsg(ctx context.Context, from *types.TipSet
 	limitHeight := from.Height() - limit
 	noLimit := limit == LookbackNoLimit
 
+	if sm.msgIndex != nil {
+		ts, r, foundMsg, err := sm.searchForIndexedMsg(ctx, from, m, allowReplaced)
+		switch {
+		case err == nil:
+			if ts == nil || !noLimit && ts.Height() <= limitHeight {
+				return nil, nil, cid.Undef, nil
+			}
+			return ts, r, foundMsg, nil
+		case !errors.Is(err, ErrMsgNotIndexed):
+			log.Debugf("falling back to chain walk to search for message %s: %s", m.Cid(), err)
+		}
+	}
+
 	cur := from
 	curActor, err := sm.LoadActor(ctx, m.VMMessage().From, cur)
 	if err != nil {
@@ -230,6 +261,51 @@ func (sm *StateManager) searchBackForMsg(ctx context.Context, from *types.TipSet
 	}
 }
 
+// searchForIndexedMsg looks up the block including a message in the message index, and returns the tipset
+// executing it if the block is in the chain of the given tipset. It returns a nil tipset if the message
+// isn't executed yet in that chain.
+func (sm *StateManager) searchForIndexedMsg(ctx context.Context, from *types.TipSet, m types.ChainMsg, allowReplaced bool) (*types.TipSet, *types.MessageReceipt, cid.Cid, error) {
+	vmm := m.VMMessage()
+	info, err := sm.msgIndex.GetMsgInfo(ctx, vmm.From, vmm.Nonce)
+	if err != nil {
+		return nil, nil, cid.Undef, err
+	}
+	if info.Message != m.Cid() && !allowReplaced {
+		return nil, nil, cid.Undef, nil
+	}
+	// The messages of a block are executed by its child.
+	if info.Epoch >= from.Height() {
+		return nil, nil, cid.Undef, nil
+	}
+
+	ts, err := sm.cs.GetTipsetByHeight(ctx, info.Epoch+1, from, false)
+	if err != nil {
+		return nil, nil, cid.Undef, xerrors.Errorf("loading tipset executing indexed message: %w", err)
+	}
+	included := false
+	for _, c := range ts.Parents().Cids() {
+		if c == info.Block {
+			included = true
+			break
+		}
+	}
+	if !included {
+		if info.Final {
+			log.Warnf("final block %s including message %s not in the chain at height %d", info.Block, info.Message, info.Epoch)
+		}
+		return nil, nil, cid.Undef, xerrors.Errorf("indexed block %s not in the chain", info.Block)
+	}
+
+	r, foundMsg, err := sm.tipsetExecutedMessage(ctx, ts, m.Cid(), vmm, allowReplaced)
+	if err != nil {
+		return nil, nil, cid.Undef, err
+	}
+	if r == nil {
+		return nil, nil, cid.Undef, xerrors.Errorf("indexed message %s not executed at height %d", info.Message, ts.Height())
+	}
+	return ts, r, foundMsg, nil
+}
+
 func (sm *StateManager) tipsetExecutedMessage(ctx context.Context, ts *types.TipSet, msg cid.Cid, vmm *types.Message, allowReplaced bool) (*types.MessageReceipt, cid.Cid, error) {
 	// The genesis block did not execute any messages
 	if ts.Height() == 0 {
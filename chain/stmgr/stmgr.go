@@ -136,6 +136,9 @@ type StateManager struct {
 	tsExec        Executor
 	tsExecMonitor ExecMonitor
 	beacon        beacon.Schedule
+
+	// Optional index of the messages by sender, used to search for messages without walking the chain.
+	msgIndex MsgIndex
 }
 
 // Caches a single state tree
@@ -211,6 +214,11 @@ func NewStateManagerWithUpgradeScheduleAndMonitor(cs *store.ChainStore, exec Exe
 	return sm, nil
 }
 
+// SetMsgIndex sets the index used to search for messages, e.g. by consensus without reorgs.
+func (sm *StateManager) SetMsgIndex(idx MsgIndex) {
+	sm.msgIndex = idx
+}
+
 func cidsToKey(cids []cid.Cid) string {
 	var out string
 	for _, c := range cids {
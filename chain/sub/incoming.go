@@ -285,6 +285,9 @@ func (bv *BlockValidator) Validate(ctx context.Context, pid peer.ID, msg *pubsub
 		}
 	} else {
 		recordFailure(ctx, metrics.BlockValidationFailure, what)
+		if res == pubsub.ValidationReject && pid != bv.self {
+			bv.flagPeer(pid)
+		}
 	}
 
 	return res
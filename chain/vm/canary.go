@@ -0,0 +1,184 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"go.opencensus.io/stats"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/network"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+// Canary execution runs every message a second time in a shadow FVM with the network version, and
+// optionally the actors bundle, of an upcoming upgrade. The shadow execution writes to memory only and
+// never affects the result returned to the node, so a validator can keep participating in the subnet
+// while it checks that the upgrade doesn't change the outcome of the blocks before the upgrade epoch.
+//
+// Divergences of receipts and state roots are logged and counted in metrics.VMCanaryDivergences.
+var (
+	canaryNetworkVersion, canaryEnabled = canaryVersionFromEnv(os.Getenv("LOTUS_FVM_CANARY_NETWORK_VERSION"))
+	canaryBundlePath                    = os.Getenv("LOTUS_FVM_CANARY_BUNDLE")
+)
+
+func canaryVersionFromEnv(v string) (network.Version, bool) {
+	if v == "" {
+		return 0, false
+	}
+	nv, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		log.Errorf("invalid canary network version %q: %s", v, err)
+		return 0, false
+	}
+	return network.Version(nv), true
+}
+
+type canaryFVM struct {
+	main   *FVM
+	canary *FVM
+	epoch  abi.ChainEpoch
+
+	lk sync.Mutex
+	// divergence describes the first message whose receipt diverged since the last flush.
+	divergence string
+}
+
+var _ Interface = (*canaryFVM)(nil)
+
+// NewCanaryFVM creates an FVM that shadow-executes the messages with network version nv and the actors
+// of the bundle at bundlePath, if any. The node keeps running with the main FVM if the canary can't
+// be created.
+func NewCanaryFVM(ctx context.Context, opts *VMOpts, chainID uint64, nv network.Version, bundlePath string) (Interface, error) {
+	main, err := NewFVM(ctx, opts, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	copts := *opts
+	copts.NetworkVersion = nv
+	canary, err := newOverlayFVM(ctx, &copts, chainID, bundlePath, opts.NetworkVersion, false)
+	if err != nil {
+		log.Errorf("failed to create canary FVM with network version %d: %s", nv, err)
+		return main, nil
+	}
+
+	return &canaryFVM{
+		main:   main,
+		canary: canary,
+		epoch:  opts.Epoch,
+	}, nil
+}
+
+func (vm *canaryFVM) ApplyMessage(ctx context.Context, cmsg types.ChainMsg) (ret *ApplyRet, err error) {
+	var (
+		wg        sync.WaitGroup
+		cret      *ApplyRet
+		canaryErr error
+	)
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		ret, err = vm.main.ApplyMessage(ctx, cmsg)
+	}()
+
+	go func() {
+		defer wg.Done()
+		cret, canaryErr = vm.canary.ApplyMessage(ctx, cmsg)
+	}()
+
+	wg.Wait()
+	if err == nil {
+		vm.compare(cmsg.VMMessage().Cid(), ret, cret, canaryErr)
+	}
+	return ret, err
+}
+
+func (vm *canaryFVM) ApplyImplicitMessage(ctx context.Context, msg *types.Message) (ret *ApplyRet, err error) {
+	var (
+		wg        sync.WaitGroup
+		cret      *ApplyRet
+		canaryErr error
+	)
+
+	// The FVM sets the gas limit of implicit messages, so each execution gets its own copy.
+	mcid := msg.Cid()
+	cmsg := *msg
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		ret, err = vm.main.ApplyImplicitMessage(ctx, msg)
+	}()
+
+	go func() {
+		defer wg.Done()
+		cret, canaryErr = vm.canary.ApplyImplicitMessage(ctx, &cmsg)
+	}()
+
+	wg.Wait()
+	if ret != nil {
+		vm.compare(mcid, ret, cret, canaryErr)
+	}
+	return ret, err
+}
+
+// compare records the divergence of the canary execution of the message, if it is the first one.
+func (vm *canaryFVM) compare(msg cid.Cid, ret, cret *ApplyRet, canaryErr error) {
+	var divergence string
+	switch {
+	case cret == nil:
+		divergence = fmt.Sprintf("canary execution failed: %s", canaryErr)
+	case ret.ExitCode != cret.ExitCode:
+		divergence = fmt.Sprintf("exit code %d vs %d", ret.ExitCode, cret.ExitCode)
+	case !bytes.Equal(ret.Return, cret.Return):
+		divergence = "different return values"
+	default:
+		return
+	}
+
+	vm.lk.Lock()
+	defer vm.lk.Unlock()
+	if vm.divergence == "" {
+		vm.divergence = fmt.Sprintf("message %s: %s", msg, divergence)
+	}
+}
+
+// Flush flushes the main FVM and reports a divergence if the canary state root is different.
+func (vm *canaryFVM) Flush(ctx context.Context) (cid.Cid, error) {
+	root, err := vm.main.Flush(ctx)
+	if err != nil {
+		return root, err
+	}
+
+	croot, cerr := vm.canary.Flush(ctx)
+
+	vm.lk.Lock()
+	divergence := vm.divergence
+	vm.divergence = ""
+	vm.lk.Unlock()
+
+	switch {
+	case cerr != nil:
+		log.Errorw("canary execution diverged", "epoch", vm.epoch, "error", cerr)
+	case root != croot:
+		log.Errorw("canary execution diverged", "epoch", vm.epoch, "root", root, "canaryRoot", croot,
+			"firstDivergence", divergence)
+	case divergence != "":
+		log.Warnw("canary receipts diverged with the same state root", "epoch", vm.epoch, "firstDivergence", divergence)
+	default:
+		return root, nil
+	}
+	stats.Record(ctx, metrics.VMCanaryDivergences.M(1))
+	return root, nil
+}
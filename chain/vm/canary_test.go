@@ -0,0 +1,45 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/go-state-types/network"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestCanaryVersionFromEnv(t *testing.T) {
+	_, ok := canaryVersionFromEnv("")
+	require.False(t, ok)
+	_, ok = canaryVersionFromEnv("next")
+	require.False(t, ok)
+	nv, ok := canaryVersionFromEnv("19")
+	require.True(t, ok)
+	require.Equal(t, network.Version19, nv)
+}
+
+func TestCanaryCompare(t *testing.T) {
+	ret := func(code exitcode.ExitCode, r []byte) *ApplyRet {
+		return &ApplyRet{MessageReceipt: types.MessageReceipt{ExitCode: code, Return: r}}
+	}
+	msg := (&types.Message{Nonce: 1}).Cid()
+
+	var vm canaryFVM
+	vm.compare(msg, ret(0, []byte{1}), ret(0, []byte{1}), nil)
+	require.Empty(t, vm.divergence)
+
+	// Only the first divergence is kept.
+	vm.compare(msg, ret(0, []byte{1}), ret(exitcode.ErrForbidden, nil), nil)
+	vm.compare(cid.Undef, ret(0, nil), nil, xerrors.New("boom"))
+	require.Contains(t, vm.divergence, msg.String())
+	require.Contains(t, vm.divergence, "exit code")
+
+	vm.divergence = ""
+	vm.compare(msg, ret(0, nil), nil, xerrors.New("boom"))
+	require.Contains(t, vm.divergence, "boom")
+}
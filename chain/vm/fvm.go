@@ -297,18 +297,30 @@ func NewFVM(ctx context.Context, opts *VMOpts, chainID uint64) (*FVM, error) {
 }
 
 func NewDebugFVM(ctx context.Context, opts *VMOpts) (*FVM, error) {
+	av, err := actorstypes.VersionForNetwork(opts.NetworkVersion)
+	if err != nil {
+		return nil, xerrors.Errorf("error determining actors version for network version %d: %w", opts.NetworkVersion, err)
+	}
+
+	return newOverlayFVM(ctx, opts, build.Eip155ChainId, os.Getenv(fmt.Sprintf("LOTUS_FVM_DEBUG_BUNDLE_V%d", av)), opts.NetworkVersion, true)
+}
+
+// newOverlayFVM creates an FVM whose writes go to an in-memory blockstore on top of the blockstore
+// of opts, so its execution doesn't affect the state of the node. If bundlePath is set, the builtin
+// actors of network version redirectFrom are redirected to the actors of the bundle.
+func newOverlayFVM(ctx context.Context, opts *VMOpts, chainID uint64, bundlePath string, redirectFrom network.Version, debug bool) (*FVM, error) {
 	baseBstore := opts.Bstore
 	overlayBstore := blockstore.NewMemorySync()
 	cborStore := cbor.NewCborStore(overlayBstore)
 	vmBstore := blockstore.NewTieredBstore(overlayBstore, baseBstore)
 
 	opts.Bstore = vmBstore
-	fvmOpts, err := defaultFVMOpts(ctx, opts, build.Eip155ChainId)
+	fvmOpts, err := defaultFVMOpts(ctx, opts, chainID)
 	if err != nil {
 		return nil, xerrors.Errorf("creating fvm opts: %w", err)
 	}
 
-	fvmOpts.Debug = true
+	fvmOpts.Debug = debug
 
 	putMapping := func(ar map[cid.Cid]cid.Cid) (cid.Cid, error) {
 		var mapping xMapping
@@ -330,18 +342,18 @@ func NewDebugFVM(ctx context.Context, opts *VMOpts) (*FVM, error) {
 		return mappingCid, nil
 	}
 
-	createMapping := func(debugBundlePath string) error {
-		mfCid, err := bundle.LoadBundleFromFile(ctx, overlayBstore, debugBundlePath)
+	createMapping := func(bundlePath string) error {
+		mfCid, err := bundle.LoadBundleFromFile(ctx, overlayBstore, bundlePath)
 		if err != nil {
-			return xerrors.Errorf("loading debug bundle: %w", err)
+			return xerrors.Errorf("loading bundle: %w", err)
 		}
 
 		mf, err := actors.LoadManifest(ctx, mfCid, adt.WrapStore(ctx, cborStore))
 		if err != nil {
-			return xerrors.Errorf("loading debug manifest: %w", err)
+			return xerrors.Errorf("loading manifest: %w", err)
 		}
 
-		av, err := actorstypes.VersionForNetwork(opts.NetworkVersion)
+		av, err := actorstypes.VersionForNetwork(redirectFrom)
 		if err != nil {
 			return xerrors.Errorf("getting actors version: %w", err)
 		}
@@ -375,15 +387,9 @@ func NewDebugFVM(ctx context.Context, opts *VMOpts) (*FVM, error) {
 		return nil
 	}
 
-	av, err := actorstypes.VersionForNetwork(opts.NetworkVersion)
-	if err != nil {
-		return nil, xerrors.Errorf("error determining actors version for network version %d: %w", opts.NetworkVersion, err)
-	}
-
-	debugBundlePath := os.Getenv(fmt.Sprintf("LOTUS_FVM_DEBUG_BUNDLE_V%d", av))
-	if debugBundlePath != "" {
-		if err := createMapping(debugBundlePath); err != nil {
-			log.Errorf("failed to create v%d debug mapping", av)
+	if bundlePath != "" {
+		if err := createMapping(bundlePath); err != nil {
+			log.Errorf("failed to create actors mapping for bundle %s: %s", bundlePath, err)
 		}
 	}
 
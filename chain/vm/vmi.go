@@ -38,6 +38,9 @@ func NewVM(ctx context.Context, opts *VMOpts, chainID uint64) (Interface, error)
 		if useFvmDebug {
 			return NewDualExecutionFVM(ctx, opts, chainID)
 		}
+		if canaryEnabled && canaryNetworkVersion > opts.NetworkVersion {
+			return NewCanaryFVM(ctx, opts, chainID, canaryNetworkVersion, canaryBundlePath)
+		}
 		return NewFVM(ctx, opts, chainID)
 	}
 
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"fmt"
 	"strings"
@@ -17,6 +18,7 @@ import (
 	"github.com/filecoin-project/lotus/chain/actors/builtin"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
 var sendCmd = &cli.Command{
@@ -88,7 +90,12 @@ var sendCmd = &cli.Command{
 		ctx := ReqContext(cctx)
 		var params SendParams
 
-		params.To, err = address.NewFromString(cctx.Args().Get(0))
+		// The node is only asked for its network name if an address has a subnet prefix.
+		nn := networkNameFunc(func(ctx context.Context) (dtypes.NetworkName, error) {
+			return srv.FullNodeAPI().StateNetworkName(ctx)
+		})
+
+		params.To, err = parseAddress(ctx, nn, cctx.Args().Get(0))
 		if err != nil {
 			return ShowHelp(cctx, fmt.Errorf("failed to parse target address: %w", err))
 		}
@@ -100,7 +107,7 @@ var sendCmd = &cli.Command{
 		params.Val = abi.TokenAmount(val)
 
 		if from := cctx.String("from"); from != "" {
-			addr, err := address.NewFromString(from)
+			addr, err := parseAddress(ctx, nn, from)
 			if err != nil {
 				return err
 			}
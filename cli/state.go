@@ -758,7 +758,7 @@ var StateGetActorCmd = &cli.Command{
 			return IncorrectNumArgs(cctx)
 		}
 
-		addr, err := address.NewFromString(cctx.Args().First())
+		addr, err := parseAddress(ctx, api, cctx.Args().First())
 		if err != nil {
 			return err
 		}
@@ -812,7 +812,7 @@ var StateLookupIDCmd = &cli.Command{
 			return IncorrectNumArgs(cctx)
 		}
 
-		addr, err := address.NewFromString(cctx.Args().First())
+		addr, err := parseAddress(ctx, api, cctx.Args().First())
 		if err != nil {
 			return err
 		}
@@ -833,7 +833,7 @@ var StateLookupIDCmd = &cli.Command{
 			return err
 		}
 
-		fmt.Printf("%s\n", a)
+		fmt.Printf("%s\n", addressFormatter(ctx, api)(a))
 
 		return nil
 	},
@@ -893,7 +893,7 @@ var StateReadStateCmd = &cli.Command{
 			return IncorrectNumArgs(cctx)
 		}
 
-		addr, err := address.NewFromString(cctx.Args().First())
+		addr, err := parseAddress(ctx, api, cctx.Args().First())
 		if err != nil {
 			return err
 		}
@@ -950,7 +950,7 @@ var StateListMessagesCmd = &cli.Command{
 
 		var toa, froma address.Address
 		if tos := cctx.String("to"); tos != "" {
-			a, err := address.NewFromString(tos)
+			a, err := parseAddress(ctx, api, tos)
 			if err != nil {
 				return fmt.Errorf("given 'to' address %q was invalid: %w", tos, err)
 			}
@@ -958,7 +958,7 @@ var StateListMessagesCmd = &cli.Command{
 		}
 
 		if froms := cctx.String("from"); froms != "" {
-			a, err := address.NewFromString(froms)
+			a, err := parseAddress(ctx, api, froms)
 			if err != nil {
 				return fmt.Errorf("given 'from' address %q was invalid: %w", froms, err)
 			}
@@ -1564,12 +1564,12 @@ var StateCallCmd = &cli.Command{
 
 		ctx := ReqContext(cctx)
 
-		toa, err := address.NewFromString(cctx.Args().First())
+		toa, err := parseAddress(ctx, api, cctx.Args().First())
 		if err != nil {
 			return fmt.Errorf("given 'to' address %q was invalid: %w", cctx.Args().First(), err)
 		}
 
-		froma, err := address.NewFromString(cctx.String("from"))
+		froma, err := parseAddress(ctx, api, cctx.String("from"))
 		if err != nil {
 			return fmt.Errorf("given 'from' address %q was invalid: %w", cctx.String("from"), err)
 		}
@@ -1802,7 +1802,7 @@ var stateMarketBalanceCmd = &cli.Command{
 			return err
 		}
 
-		addr, err := address.NewFromString(cctx.Args().First())
+		addr, err := parseAddress(ctx, api, cctx.Args().First())
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/consensus-shipyard/go-ipc-types/sdk"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+// subnetAddrSep separates the subnet ID from the address in the subnet-qualified form of an
+// address, e.g. /root/t01002:t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba.
+const subnetAddrSep = ":"
+
+type networkNamer interface {
+	StateNetworkName(context.Context) (dtypes.NetworkName, error)
+}
+
+type networkNameFunc func(context.Context) (dtypes.NetworkName, error)
+
+func (f networkNameFunc) StateNetworkName(ctx context.Context) (dtypes.NetworkName, error) {
+	return f(ctx)
+}
+
+// FormatSubnetAddress renders addr prefixed with the subnet ID of the network if the network
+// is a subnet, and the plain address otherwise.
+func FormatSubnetAddress(netName dtypes.NetworkName, addr address.Address) string {
+	sn, err := sdk.NewSubnetIDFromString(string(netName))
+	if err != nil {
+		return addr.String()
+	}
+	return sn.String() + subnetAddrSep + addr.String()
+}
+
+// ParseSubnetAddress parses a plain or subnet-qualified address. The subnet of a qualified
+// address must be the one the node runs, so funds are never sent to an address that looks the
+// same on another subnet.
+func ParseSubnetAddress(netName dtypes.NetworkName, s string) (address.Address, error) {
+	i := strings.LastIndex(s, subnetAddrSep)
+	if i < 0 {
+		return address.NewFromString(s)
+	}
+
+	sn, err := sdk.NewSubnetIDFromString(s[:i])
+	if err != nil {
+		return address.Undef, fmt.Errorf("invalid subnet in address %q: %w", s, err)
+	}
+	node, err := sdk.NewSubnetIDFromString(string(netName))
+	if err != nil {
+		return address.Undef, fmt.Errorf("address %q belongs to subnet %s, but the node doesn't run a subnet", s, sn)
+	}
+	if sn.String() != node.String() {
+		return address.Undef, fmt.Errorf("address %q belongs to subnet %s, but the node runs %s", s, sn, node)
+	}
+
+	return address.NewFromString(s[i+1:])
+}
+
+// parseAddress parses an address argument of a command. The network name is only queried
+// for subnet-qualified addresses.
+func parseAddress(ctx context.Context, api networkNamer, s string) (address.Address, error) {
+	if !strings.Contains(s, subnetAddrSep) {
+		return address.NewFromString(s)
+	}
+
+	nn, err := api.StateNetworkName(ctx)
+	if err != nil {
+		return address.Undef, fmt.Errorf("getting network name: %w", err)
+	}
+	return ParseSubnetAddress(nn, s)
+}
+
+// addressFormatter returns the function that renders addresses in the output of a command.
+// Addresses are rendered plain if the network name can't be retrieved.
+func addressFormatter(ctx context.Context, api networkNamer) func(address.Address) string {
+	nn, err := api.StateNetworkName(ctx)
+	if err != nil {
+		return address.Address.String
+	}
+	return func(addr address.Address) string {
+		return FormatSubnetAddress(nn, addr)
+	}
+}
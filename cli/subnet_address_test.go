@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+func TestSubnetAddress(t *testing.T) {
+	addr, err := address.NewFromString("t01234")
+	require.NoError(t, err)
+
+	subnet := dtypes.NetworkName("/root/t01002")
+
+	s := FormatSubnetAddress(subnet, addr)
+	require.Equal(t, "/root/t01002:t01234", s)
+	require.Equal(t, addr.String(), FormatSubnetAddress("localnet", addr))
+
+	parsed, err := ParseSubnetAddress(subnet, s)
+	require.NoError(t, err)
+	require.Equal(t, addr, parsed)
+
+	// Plain addresses are accepted on any network.
+	parsed, err = ParseSubnetAddress(subnet, "t01234")
+	require.NoError(t, err)
+	require.Equal(t, addr, parsed)
+
+	_, err = ParseSubnetAddress("/root/t01003", s)
+	require.ErrorContains(t, err, "belongs to subnet /root/t01002")
+	_, err = ParseSubnetAddress("localnet", s)
+	require.Error(t, err)
+	_, err = ParseSubnetAddress(subnet, "root:t01234")
+	require.Error(t, err)
+}
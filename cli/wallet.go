@@ -65,7 +65,7 @@ var walletNew = &cli.Command{
 			return err
 		}
 
-		afmt.Println(nk.String())
+		afmt.Println(addressFormatter(ctx, api)(nk))
 
 		return nil
 	},
@@ -100,6 +100,7 @@ var walletList = &cli.Command{
 		ctx := ReqContext(cctx)
 
 		afmt := NewAppFmt(cctx.App)
+		fmtAddr := addressFormatter(ctx, api)
 
 		addrs, err := api.WalletList(ctx)
 		if err != nil {
@@ -121,13 +122,13 @@ var walletList = &cli.Command{
 
 		for _, addr := range addrs {
 			if cctx.Bool("addr-only") {
-				afmt.Println(addr.String())
+				afmt.Println(fmtAddr(addr))
 			} else {
 				a, err := api.StateGetActor(ctx, addr, types.EmptyTSK)
 				if err != nil {
 					if !strings.Contains(err.Error(), "actor not found") {
 						tw.Write(map[string]interface{}{
-							"Address": addr,
+							"Address": fmtAddr(addr),
 							"Error":   err,
 						})
 						continue
@@ -139,7 +140,7 @@ var walletList = &cli.Command{
 				}
 
 				row := map[string]interface{}{
-					"Address": addr,
+					"Address": fmtAddr(addr),
 					"Balance": types.FIL(a.Balance),
 					"Nonce":   a.Nonce,
 				}
@@ -192,7 +193,7 @@ var walletBalance = &cli.Command{
 
 		var addr address.Address
 		if cctx.Args().First() != "" {
-			addr, err = address.NewFromString(cctx.Args().First())
+			addr, err = parseAddress(ctx, api, cctx.Args().First())
 		} else {
 			addr, err = api.WalletDefaultAddress(ctx)
 		}
@@ -233,7 +234,7 @@ var walletGetDefault = &cli.Command{
 			return err
 		}
 
-		afmt.Printf("%s\n", addr.String())
+		afmt.Printf("%s\n", addressFormatter(ctx, api)(addr))
 		return nil
 	},
 }
@@ -254,7 +255,7 @@ var walletSetDefault = &cli.Command{
 			return IncorrectNumArgs(cctx)
 		}
 
-		addr, err := address.NewFromString(cctx.Args().First())
+		addr, err := parseAddress(ctx, api, cctx.Args().First())
 		if err != nil {
 			return err
 		}
@@ -289,7 +290,7 @@ var walletExport = &cli.Command{
 			return IncorrectNumArgs(cctx)
 		}
 
-		addr, err := address.NewFromString(cctx.Args().First())
+		addr, err := parseAddress(ctx, api, cctx.Args().First())
 		if err != nil {
 			return err
 		}
@@ -428,7 +429,7 @@ var walletSign = &cli.Command{
 			return IncorrectNumArgs(cctx)
 		}
 
-		addr, err := address.NewFromString(cctx.Args().First())
+		addr, err := parseAddress(ctx, api, cctx.Args().First())
 
 		if err != nil {
 			return err
@@ -471,7 +472,7 @@ var walletVerify = &cli.Command{
 			return IncorrectNumArgs(cctx)
 		}
 
-		addr, err := address.NewFromString(cctx.Args().First())
+		addr, err := parseAddress(ctx, api, cctx.Args().First())
 
 		if err != nil {
 			return err
@@ -523,7 +524,7 @@ var walletDelete = &cli.Command{
 			return IncorrectNumArgs(cctx)
 		}
 
-		addr, err := address.NewFromString(cctx.Args().First())
+		addr, err := parseAddress(ctx, api, cctx.Args().First())
 		if err != nil {
 			return err
 		}
@@ -576,7 +577,7 @@ var walletMarketWithdraw = &cli.Command{
 
 		var wallet address.Address
 		if cctx.String("wallet") != "" {
-			wallet, err = address.NewFromString(cctx.String("wallet"))
+			wallet, err = parseAddress(ctx, api, cctx.String("wallet"))
 			if err != nil {
 				return xerrors.Errorf("parsing from address: %w", err)
 			}
@@ -589,7 +590,7 @@ var walletMarketWithdraw = &cli.Command{
 
 		addr := wallet
 		if cctx.String("address") != "" {
-			addr, err = address.NewFromString(cctx.String("address"))
+			addr, err = parseAddress(ctx, api, cctx.String("address"))
 			if err != nil {
 				return xerrors.Errorf("parsing market address: %w", err)
 			}
@@ -724,7 +725,7 @@ var walletMarketAdd = &cli.Command{
 		// Get from param
 		var from address.Address
 		if cctx.String("from") != "" {
-			from, err = address.NewFromString(cctx.String("from"))
+			from, err = parseAddress(ctx, api, cctx.String("from"))
 			if err != nil {
 				return xerrors.Errorf("parsing from address: %w", err)
 			}
@@ -738,7 +739,7 @@ var walletMarketAdd = &cli.Command{
 		// Get address param
 		addr := from
 		if cctx.String("address") != "" {
-			addr, err = address.NewFromString(cctx.String("address"))
+			addr, err = parseAddress(ctx, api, cctx.String("address"))
 			if err != nil {
 				return xerrors.Errorf("parsing market address: %w", err)
 			}
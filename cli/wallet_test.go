@@ -21,6 +21,7 @@ import (
 	"github.com/filecoin-project/lotus/api"
 	apitypes "github.com/filecoin-project/lotus/api/types"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
 func TestWalletNew(t *testing.T) {
@@ -35,11 +36,12 @@ func TestWalletNew(t *testing.T) {
 	assert.NoError(t, err)
 
 	mockApi.EXPECT().WalletNew(ctx, keyType).Return(address, nil)
+	mockApi.EXPECT().StateNetworkName(ctx).Return(dtypes.NetworkName("/root"), nil)
 
 	//stm: @CLI_WALLET_NEW_001
 	err = app.Run([]string{"wallet", "new"})
 	assert.NoError(t, err)
-	assert.Contains(t, buffer.String(), address.String())
+	assert.Contains(t, buffer.String(), "/root:"+address.String())
 }
 
 func TestWalletList(t *testing.T) {
@@ -67,6 +69,7 @@ func TestWalletList(t *testing.T) {
 		defer cancel()
 
 		gomock.InOrder(
+			mockApi.EXPECT().StateNetworkName(ctx).Return(dtypes.NetworkName("/root"), nil),
 			mockApi.EXPECT().WalletList(ctx).Return(addresses, nil),
 			mockApi.EXPECT().WalletDefaultAddress(ctx).Return(addr, nil),
 		)
@@ -85,6 +88,7 @@ func TestWalletList(t *testing.T) {
 		defer cancel()
 
 		gomock.InOrder(
+			mockApi.EXPECT().StateNetworkName(ctx).Return(dtypes.NetworkName("/root"), nil),
 			mockApi.EXPECT().WalletList(ctx).Return(addresses, nil),
 			mockApi.EXPECT().WalletDefaultAddress(ctx).Return(addr, nil),
 			mockApi.EXPECT().StateGetActor(ctx, addr, key).Return(&actor, nil),
@@ -109,6 +113,7 @@ func TestWalletList(t *testing.T) {
 		}
 
 		gomock.InOrder(
+			mockApi.EXPECT().StateNetworkName(ctx).Return(dtypes.NetworkName("/root"), nil),
 			mockApi.EXPECT().WalletList(ctx).Return(addresses, nil),
 			mockApi.EXPECT().WalletDefaultAddress(ctx).Return(addr, nil),
 			mockApi.EXPECT().StateGetActor(ctx, addr, key).Return(&actor, nil),
@@ -152,6 +157,7 @@ func TestWalletGetDefault(t *testing.T) {
 	assert.NoError(t, err)
 
 	mockApi.EXPECT().WalletDefaultAddress(ctx).Return(addr, nil)
+	mockApi.EXPECT().StateNetworkName(ctx).Return(dtypes.NetworkName("/root"), nil)
 
 	//stm: @CLI_WALLET_GET_DEFAULT_001
 	err = app.Run([]string{"wallet", "default"})
@@ -23,6 +23,7 @@ import (
 
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
 	lcli "github.com/filecoin-project/lotus/cli"
 	"github.com/filecoin-project/lotus/eudico-core/fxmodules"
 	"github.com/filecoin-project/lotus/eudico-core/global"
@@ -112,7 +113,7 @@ func daemonCmd(consensusAlgorithm global.ConsensusAlgorithm) *cli.Command {
 			},
 			&cli.StringFlag{
 				Name:  "profile",
-				Usage: "specify type of node",
+				Usage: "specify type of node (bootstrapper, rpc-provider)",
 			},
 			&cli.BoolFlag{
 				Name:  "manage-fdlimit",
@@ -143,6 +144,14 @@ func daemonCmd(consensusAlgorithm global.ConsensusAlgorithm) *cli.Command {
 				Name:  "mir-validator",
 				Usage: "start lotus in mir-validator mode",
 			},
+			&cli.BoolFlag{
+				Name:  "mir-fast-verify",
+				Usage: "accept the blocks of a Mir subnet without checking their messages and state until a checkpoint covers them",
+			},
+			&cli.IntFlag{
+				Name:  "mir-blk-cache-window",
+				Usage: "number of heights above the latest Mir checkpoint whose blocks are kept until a checkpoint covers them (default 8192)",
+			},
 		},
 		Action: eudicoDaemonAction(consensusAlgorithm),
 		Subcommands: []*cli.Command{
@@ -184,9 +193,15 @@ func eudicoDaemonAction(consensusAlgorithm global.ConsensusAlgorithm) func(*cli.
 		}
 
 		var isBootstrapper dtypes.Bootstrapper
+		var isRPCProvider bool
 		switch profile := cctx.String("profile"); profile {
 		case "bootstrapper":
 			isBootstrapper = true
+		case fxmodules.RPCProviderProfile:
+			if isLite || isMirValidator {
+				return xerrors.Errorf("%s profile can't be used in lite or mir-validator mode", fxmodules.RPCProviderProfile)
+			}
+			isRPCProvider = true
 		case "":
 			// do nothing
 		default:
@@ -278,6 +293,20 @@ func eudicoDaemonAction(consensusAlgorithm global.ConsensusAlgorithm) func(*cli.
 			liteModeDeps = fx.Provide(func() api.Gateway { return gapi })
 		}
 
+		learnerDeps := fx.Options()
+		if learnerCfg := (mir.LearnerConfig{
+			FastVerify:     cctx.Bool("mir-fast-verify"),
+			BlkCacheWindow: cctx.Int("mir-blk-cache-window"),
+		}); learnerCfg != (mir.LearnerConfig{}) {
+			if consensusAlgorithm != global.MirConsensus {
+				return xerrors.Errorf("'mir-fast-verify' and 'mir-blk-cache-window' require Mir consensus")
+			}
+			if learnerCfg.BlkCacheWindow < 0 {
+				return xerrors.Errorf("'mir-blk-cache-window' must not be negative")
+			}
+			learnerDeps = fx.Replace(learnerCfg)
+		}
+
 		// some libraries like ipfs/go-ds-measure and ipfs/go-ipfs-blockstore
 		// use ipfs/go-metrics-interface. This injects a Prometheus exporter
 		// for those. Metrics are exported to the default registry.
@@ -298,6 +327,9 @@ func eudicoDaemonAction(consensusAlgorithm global.ConsensusAlgorithm) func(*cli.
 		if !ok {
 			panic("invalid config from repo")
 		}
+		if isRPCProvider {
+			cfg = fxmodules.RPCProviderConfig(cfg)
+		}
 
 		fxProviders := fx.Options(
 			fxmodules.Fullnode(cctx.Bool("bootstrap"), isLite, cfg.Fevm),
@@ -305,19 +337,25 @@ func eudicoDaemonAction(consensusAlgorithm global.ConsensusAlgorithm) func(*cli.
 			fxmodules.Repository(lockedRepo, cfg),
 			fxmodules.Blockstore(cfg),
 			fxmodules.Consensus(consensusAlgorithm),
-			fxmodules.RpcServer(cctx, r, lockedRepo, cfg),
+			fxmodules.RpcServer(cctx, r, lockedRepo, cfg, isRPCProvider),
 			// misc providers
 			fx.Supply(isBootstrapper),
 			fx.Supply(dtypes.ShutdownChan(shutdownChan)),
 			genesis,
 			liteModeDeps,
+			learnerDeps,
 		)
 
+		invokes := fxmodules.Invokes(cfg, cctx.Bool("bootstrap"), isMirValidator)
+		if isRPCProvider {
+			invokes = fxmodules.RPCProviderInvokes(cfg)
+		}
+
 		var rpcStopper node.StopFunc
 		app := fx.New(
 			fxProviders,
 			fx.Populate(&rpcStopper),
-			fxmodules.Invokes(cfg, cctx.Bool("bootstrap"), isMirValidator),
+			invokes,
 			// Debugging of the dependency graph
 			fx.Invoke(
 				func(dotGraph fx.DotGraph) {
@@ -13,5 +13,7 @@ var mirCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		daemonCmd(global.MirConsensus),
 		mirvalidator.ValidatorCmd,
+		mirvalidator.StateCmd,
+		replayCmd,
 	},
 }
@@ -0,0 +1,474 @@
+package mirvalidator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/gbrlsnchs/jwt/v3"
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+const (
+	// AdminAddrPath is the file in the repo with the address of the admin API of the running validator.
+	AdminAddrPath = "mir.admin"
+	// AdminTokenPath is the file in the repo with the token used by the validator CLI, which has all the permissions.
+	AdminTokenPath = "mir.admin.token"
+	// AdminSecretPath is the file in the repo with the secret the tokens of the admin API are signed with.
+	AdminSecretPath = "mir.admin.secret"
+
+	adminNamespace = "MirValidator"
+
+	// PermMirRead allows inspecting the state of the validator.
+	PermMirRead auth.Permission = "mir-read"
+	// PermMirAdmin allows changing the state of the validator, e.g. submitting configuration requests.
+	PermMirAdmin auth.Permission = "mir-admin"
+)
+
+// AdminPermissions are the permissions of the admin API. A token with PermMirAdmin has to include
+// PermMirRead to call the read methods too.
+var AdminPermissions = []auth.Permission{PermMirRead, PermMirAdmin}
+
+type adminJwtPayload struct {
+	Allow []auth.Permission
+}
+
+// adminHandler is the admin API served by a running validator to the validator CLI
+// and to monitoring systems. Every method requires the token of the caller to include
+// PermMirRead or PermMirAdmin.
+type adminHandler struct {
+	m           *managerRef
+	checkpoints *mir.CheckpointNotifier
+	msgs        *mir.MsgTracker
+	// stop stops the Mir node of the validator.
+	stop context.CancelFunc
+}
+
+// managerRef is the Mir manager of the validator, replaced when the manager is restarted
+// after a failure.
+type managerRef struct {
+	lk sync.Mutex
+	m  *mir.Manager
+}
+
+func (r *managerRef) set(m *mir.Manager) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.m = m
+}
+
+func (r *managerRef) get() (*mir.Manager, error) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	if r.m == nil {
+		return nil, fmt.Errorf("mir manager not running")
+	}
+	return r.m, nil
+}
+
+func checkPerm(ctx context.Context, perm auth.Permission) error {
+	if !auth.HasPerm(ctx, nil, perm) {
+		return fmt.Errorf("missing permission: %s", perm)
+	}
+	return nil
+}
+
+func (h *adminHandler) PendingConfigurationRequests(ctx context.Context) ([]mir.PendingConfigurationRequest, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return nil, err
+	}
+	return m.PendingConfigurationRequests()
+}
+
+func (h *adminHandler) CancelConfigurationRequest(ctx context.Context, txNo uint64) error {
+	if err := checkPerm(ctx, PermMirAdmin); err != nil {
+		return err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return err
+	}
+	return m.CancelConfigurationRequest(txNo)
+}
+
+func (h *adminHandler) SubmitConfigurationRequest(ctx context.Context, set *validator.Set) (uint64, error) {
+	if err := checkPerm(ctx, PermMirAdmin); err != nil {
+		return 0, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return 0, err
+	}
+	return m.SubmitConfigurationRequest(set)
+}
+
+func (h *adminHandler) RequestShutdown(ctx context.Context, height abi.ChainEpoch) (uint64, error) {
+	if err := checkPerm(ctx, PermMirAdmin); err != nil {
+		return 0, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return 0, err
+	}
+	return m.RequestShutdown(height)
+}
+
+func (h *adminHandler) MirLeave(ctx context.Context) (uint64, error) {
+	if err := checkPerm(ctx, PermMirAdmin); err != nil {
+		return 0, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return 0, err
+	}
+	return m.Leave()
+}
+
+func (h *adminHandler) MirLeaveStatus(ctx context.Context, txNo uint64) (*mir.LeaveStatus, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return nil, err
+	}
+	return m.LeaveStatus(txNo), nil
+}
+
+// MirStop stops the Mir node of the validator, which exits once the node is stopped.
+func (h *adminHandler) MirStop(ctx context.Context) error {
+	if err := checkPerm(ctx, PermMirAdmin); err != nil {
+		return err
+	}
+	log.Warn("Operator requested to stop the validator")
+	h.stop()
+	return nil
+}
+
+func (h *adminHandler) MirNetDiagnostics(ctx context.Context) ([]mir.PeerDiagnostics, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return nil, err
+	}
+	return m.NetDiagnostics()
+}
+
+func (h *adminHandler) MirContributionStats(ctx context.Context, from, to uint64) ([]mir.EpochContributions, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return nil, err
+	}
+	return m.ContributionStats(ctx, from, to)
+}
+
+func (h *adminHandler) MirStartupReport(ctx context.Context) (*mir.StartupReport, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return nil, err
+	}
+	return m.StartupReport(), nil
+}
+
+// MirStatus returns the status of the Mir node of the validator, which is stopped while
+// the manager isn't running.
+func (h *adminHandler) MirStatus(ctx context.Context) (*mir.Status, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return &mir.Status{State: mir.MirStopped}, nil
+	}
+	return m.Status(), nil
+}
+
+func (h *adminHandler) MirMembershipAt(ctx context.Context, epoch uint64) (*mir.MembershipHistoryEntry, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return nil, err
+	}
+	return m.MembershipAt(ctx, epoch)
+}
+
+func (h *adminHandler) MirMembershipAtHeight(ctx context.Context, height abi.ChainEpoch) (*mir.MembershipHistoryEntry, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return nil, err
+	}
+	return m.MembershipAtHeight(ctx, height)
+}
+
+// MirGetBlockByBatch returns the block the batch ordered by Mir with sequence number sn became.
+func (h *adminHandler) MirGetBlockByBatch(ctx context.Context, sn uint64) (*mir.BatchBlock, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return nil, err
+	}
+	return m.BlockByBatch(ctx, sn)
+}
+
+// MirFinalityCheckpoint returns the checkpoint that commits the block at the height, which light
+// clients verify against the validator set.
+func (h *adminHandler) MirFinalityCheckpoint(ctx context.Context, height abi.ChainEpoch) (*mir.CheckpointNotification, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return nil, err
+	}
+	return m.FinalityCheckpoint(ctx, height)
+}
+
+// MirCheckpointNotify streams the stable checkpoints delivered to the validator from now on.
+// The stream is closed if the caller falls behind.
+func (h *adminHandler) MirCheckpointNotify(ctx context.Context) (<-chan *mir.CheckpointNotification, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	return h.checkpoints.Subscribe(ctx), nil
+}
+
+// MirPushMessage pushes a message signed by the wallet of the daemon of the validator, like MpoolPushMessage,
+// and streams its lifecycle until its execution: its acceptance to the mempool, its proposal by the validator,
+// its ordering by Mir, its inclusion in a block and its execution with the exit code of its receipt.
+func (h *adminHandler) MirPushMessage(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec) (<-chan *mir.MsgEvent, error) {
+	if err := checkPerm(ctx, PermMirAdmin); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return nil, err
+	}
+	return m.PushMessage(ctx, h.msgs, msg, spec)
+}
+
+// MirMsgEvents streams the lifecycle of a message pushed to the subnet by other means, from now on
+// until its execution.
+func (h *adminHandler) MirMsgEvents(ctx context.Context, c cid.Cid) (<-chan *mir.MsgEvent, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	m, err := h.m.get()
+	if err != nil {
+		return nil, err
+	}
+	return m.MsgEvents(ctx, h.msgs, c), nil
+}
+
+// MirLogs streams the logs of the Mir subsystems of the validator, which don't depend on
+// the manager, so they can be followed while the manager is restarted.
+func (h *adminHandler) MirLogs(ctx context.Context, component, level string) (<-chan mir.LogEntry, error) {
+	if err := checkPerm(ctx, PermMirRead); err != nil {
+		return nil, err
+	}
+	return mir.TailLogs(ctx, component, level)
+}
+
+// readyHandler serves the readiness of the validator, with a 503 status and the reason if it's not ready.
+func readyHandler(m *managerRef, maxLag abi.ChainEpoch) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mgr, err := m.get()
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = fmt.Fprintln(w, err)
+			return
+		}
+		rd := mgr.Readiness(r.Context(), maxLag)
+		if !rd.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = fmt.Fprintln(w, rd.Reason)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// adminClient is the client of the admin API of a running validator.
+type adminClient struct {
+	PendingConfigurationRequests func(ctx context.Context) ([]mir.PendingConfigurationRequest, error)
+	CancelConfigurationRequest   func(ctx context.Context, txNo uint64) error
+	SubmitConfigurationRequest   func(ctx context.Context, set *validator.Set) (uint64, error)
+	RequestShutdown              func(ctx context.Context, height abi.ChainEpoch) (uint64, error)
+	MirLeave                     func(ctx context.Context) (uint64, error)
+	MirLeaveStatus               func(ctx context.Context, txNo uint64) (*mir.LeaveStatus, error)
+	MirStop                      func(ctx context.Context) error
+	MirNetDiagnostics            func(ctx context.Context) ([]mir.PeerDiagnostics, error)
+	MirContributionStats         func(ctx context.Context, from, to uint64) ([]mir.EpochContributions, error)
+	MirStartupReport             func(ctx context.Context) (*mir.StartupReport, error)
+	MirStatus                    func(ctx context.Context) (*mir.Status, error)
+	MirMembershipAt              func(ctx context.Context, epoch uint64) (*mir.MembershipHistoryEntry, error)
+	MirMembershipAtHeight        func(ctx context.Context, height abi.ChainEpoch) (*mir.MembershipHistoryEntry, error)
+	MirGetBlockByBatch           func(ctx context.Context, sn uint64) (*mir.BatchBlock, error)
+	MirFinalityCheckpoint        func(ctx context.Context, height abi.ChainEpoch) (*mir.CheckpointNotification, error)
+	MirCheckpointNotify          func(ctx context.Context) (<-chan *mir.CheckpointNotification, error)
+	MirPushMessage               func(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec) (<-chan *mir.MsgEvent, error)
+	MirMsgEvents                 func(ctx context.Context, c cid.Cid) (<-chan *mir.MsgEvent, error)
+	MirLogs                      func(ctx context.Context, component, level string) (<-chan mir.LogEntry, error)
+}
+
+// adminSecret returns the secret the admin API tokens are signed with, generating it
+// the first time. The secret is kept across restarts, so the tokens minted for monitoring
+// systems remain valid.
+func adminSecret(repo string) ([]byte, error) {
+	p := filepath.Join(repo, AdminSecretPath)
+	b, err := os.ReadFile(p)
+	if err == nil {
+		return hex.DecodeString(strings.TrimSpace(string(b)))
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading admin secret: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("error generating admin secret: %w", err)
+	}
+	if err := os.WriteFile(p, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return nil, fmt.Errorf("error writing admin secret: %w", err)
+	}
+	return secret, nil
+}
+
+// newAdminToken returns a token of the admin API with the given permissions.
+func newAdminToken(secret []byte, perms []auth.Permission) (string, error) {
+	for _, p := range perms {
+		if p != PermMirRead && p != PermMirAdmin {
+			return "", fmt.Errorf("unknown permission: %s", p)
+		}
+	}
+	b, err := jwt.Sign(&adminJwtPayload{Allow: perms}, jwt.NewHS256(secret))
+	if err != nil {
+		return "", fmt.Errorf("error signing admin token: %w", err)
+	}
+	return string(b), nil
+}
+
+// serveAdminAPI serves the admin API of the validator on listenAddr until ctx is done.
+//
+// The address and a token with all the permissions are written to the repo, so only users
+// with access to the repo can manage the validator. Tokens with fewer permissions can be
+// minted with the auth command.
+func serveAdminAPI(ctx context.Context, repo, listenAddr string, m *managerRef, checkpoints *mir.CheckpointNotifier,
+	msgs *mir.MsgTracker, stop context.CancelFunc, readyLag abi.ChainEpoch) error {
+	secret, err := adminSecret(repo)
+	if err != nil {
+		return err
+	}
+	token, err := newAdminToken(secret, AdminPermissions)
+	if err != nil {
+		return err
+	}
+	alg := jwt.NewHS256(secret)
+
+	lst, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", listenAddr, err)
+	}
+
+	rpcServer := jsonrpc.NewServer()
+	rpcServer.Register(adminNamespace, &adminHandler{m: m, checkpoints: checkpoints, msgs: msgs, stop: stop})
+	mux := http.NewServeMux()
+	mux.Handle("/rpc/v0", &auth.Handler{
+		Verify: func(_ context.Context, token string) ([]auth.Permission, error) {
+			var payload adminJwtPayload
+			if _, err := jwt.Verify([]byte(token), alg, &payload); err != nil {
+				return nil, fmt.Errorf("JWT verification failed: %w", err)
+			}
+			return payload.Allow, nil
+		},
+		Next: rpcServer.ServeHTTP,
+	})
+	// The probes of orchestrators can't authenticate, and the readiness of the validator reveals nothing
+	// its status doesn't.
+	mux.Handle("/health/readyz", readyHandler(m, readyLag))
+	srv := &http.Server{Handler: mux}
+
+	addrFile, tokenFile := filepath.Join(repo, AdminAddrPath), filepath.Join(repo, AdminTokenPath)
+	if err := os.WriteFile(tokenFile, []byte(token), 0600); err != nil {
+		_ = lst.Close()
+		return fmt.Errorf("error writing admin token: %w", err)
+	}
+	if err := os.WriteFile(addrFile, []byte(lst.Addr().String()), 0644); err != nil {
+		_ = lst.Close()
+		return fmt.Errorf("error writing admin address: %w", err)
+	}
+
+	go func() {
+		if err := srv.Serve(lst); err != http.ErrServerClosed {
+			log.Warnf("validator admin API server failed: %s", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = os.Remove(addrFile)
+		_ = os.Remove(tokenFile)
+		if err := srv.Shutdown(context.Background()); err != nil {
+			log.Warnf("error shutting down validator admin API: %s", err)
+		}
+	}()
+
+	log.Infof("Validator admin API listening on %s", lst.Addr())
+	return nil
+}
+
+// newAdminClient connects to the admin API of the validator running with the given repo.
+func newAdminClient(ctx context.Context, repo string) (*adminClient, jsonrpc.ClientCloser, error) {
+	addr, err := os.ReadFile(filepath.Join(repo, AdminAddrPath))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading validator admin address (is the validator running?): %w", err)
+	}
+	token, err := os.ReadFile(filepath.Join(repo, AdminTokenPath))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading validator admin token: %w", err)
+	}
+
+	headers := http.Header{}
+	headers.Add("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+
+	var c adminClient
+	closer, err := jsonrpc.NewMergeClient(ctx, "ws://"+strings.TrimSpace(string(addr))+"/rpc/v0", adminNamespace,
+		[]interface{}{&c}, headers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error connecting to the validator admin API: %w", err)
+	}
+	return &c, closer, nil
+}
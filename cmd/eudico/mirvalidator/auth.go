@@ -0,0 +1,62 @@
+package mirvalidator
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+)
+
+var authCmd = &cli.Command{
+	Name:  "auth",
+	Usage: "Manage the tokens of the validator admin API",
+	Subcommands: []*cli.Command{
+		createAdminTokenCmd,
+	},
+}
+
+var createAdminTokenCmd = &cli.Command{
+	Name:  "create-token",
+	Usage: "Create a token of the validator admin API",
+	Description: `Tokens with the mir-read permission can inspect the validator, e.g. from monitoring systems.
+Tokens with the mir-admin permission can also change its state, e.g. submit configuration requests.
+The tokens are valid until the admin secret in the repo is removed.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "perm",
+			Usage: "permission to assign to the token, one of: mir-read, mir-admin",
+			Value: string(PermMirRead),
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		var perms []auth.Permission
+		switch auth.Permission(cctx.String("perm")) {
+		case PermMirRead:
+			perms = []auth.Permission{PermMirRead}
+		case PermMirAdmin:
+			perms = AdminPermissions
+		default:
+			return fmt.Errorf("unknown permission: %s", cctx.String("perm"))
+		}
+
+		secret, err := adminSecret(repoFlag)
+		if err != nil {
+			return err
+		}
+		token, err := newAdminToken(secret, perms)
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintln(cctx.App.Writer, token)
+		return nil
+	},
+}
@@ -0,0 +1,51 @@
+package mirvalidator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var blockByBatchCmd = &cli.Command{
+	Name:      "block-by-batch",
+	Usage:     "Show the block a batch ordered by Mir became on the validator",
+	ArgsUsage: "<sequence number>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 1 {
+			return lcli.IncorrectNumArgs(cctx)
+		}
+		sn, err := strconv.ParseUint(cctx.Args().First(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid sequence number: %w", err)
+		}
+
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		b, err := c.MirGetBlockByBatch(ctx, sn)
+		if err != nil {
+			return fmt.Errorf("error getting block of batch %d: %w", sn, err)
+		}
+		out, err := json.MarshalIndent(b, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(cctx.App.Writer, string(out))
+		return nil
+	},
+}
@@ -0,0 +1,72 @@
+package mirvalidator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/urfave/cli/v2"
+
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+)
+
+// bootstrapMembership bootstraps the genesis membership of a new subnet with the other prospective
+// validators when the membership file has no validator, and saves it to the membership file.
+func bootstrapMembership(ctx context.Context, cctx *cli.Context, nodeApi api.FullNode, h host.Host, netName, mf string) error {
+	quorum := cctx.Int("genesis-quorum")
+	if quorum == 0 {
+		return nil
+	}
+	set, err := validator.NewValidatorSetFromFile(mf)
+	if err != nil {
+		return fmt.Errorf("error reading membership file %s: %w", mf, err)
+	}
+	if set.Size() > 0 {
+		return nil
+	}
+
+	addr, netAddrs, err := localValidator(ctx, cctx, nodeApi)
+	if err != nil {
+		return err
+	}
+	self, err := validator.NewValidatorFromString(fmt.Sprintf("%s:%s@%s", addr, cctx.String("genesis-weight"), netAddrs[0]))
+	if err != nil {
+		return fmt.Errorf("error creating validator: %w", err)
+	}
+
+	ps, err := pubsub.NewGossipSub(ctx, h)
+	if err != nil {
+		return fmt.Errorf("error creating bootstrap pubsub: %w", err)
+	}
+	for _, s := range cctx.StringSlice("bootstrap-peer") {
+		pi, err := peer.AddrInfoFromString(s)
+		if err != nil {
+			return fmt.Errorf("invalid bootstrap peer %s: %w", s, err)
+		}
+		if err := h.Connect(ctx, *pi); err != nil {
+			log.Warnf("failed to connect to bootstrap peer %s: %v", s, err)
+		}
+	}
+
+	log.Infof("Bootstrapping the genesis membership of %s with %d validators", netName, quorum)
+	genesis, err := membership.Bootstrap(ctx, ps, netName, quorum, self, func(b []byte) (*crypto.Signature, error) {
+		return nodeApi.WalletSign(ctx, addr, b)
+	})
+	if err != nil {
+		return fmt.Errorf("error bootstrapping the genesis membership: %w", err)
+	}
+	if !genesis.HasValidatorWithID(self.ID()) {
+		return fmt.Errorf("validator %s is not in the genesis membership bootstrapped by the other validators: join the subnet instead", self.ID())
+	}
+	if err := genesis.Save(mf); err != nil {
+		return fmt.Errorf("error saving membership file %s: %w", mf, err)
+	}
+	log.Infof("Genesis membership of %d validators saved to %s", genesis.Size(), mf)
+	return nil
+}
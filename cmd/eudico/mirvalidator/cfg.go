@@ -12,6 +12,10 @@ import (
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
 	lcli "github.com/filecoin-project/lotus/cli"
 )
 
@@ -61,6 +65,9 @@ var addValidatorCmd = &cli.Command{
 		if err != nil {
 			return fmt.Errorf("error parsing validator from string: %s. Use the following format: <wallet id>@<multiaddr>", err)
 		}
+		if v.NetAddr, err = membership.NormalizeNetAddr(v.NetAddr); err != nil {
+			return err
+		}
 
 		if err := validator.AddValidatorToFile(membershipFile, v); err != nil {
 			return fmt.Errorf("failed to add validator to file %s: %w", membershipFile, err)
@@ -84,6 +91,10 @@ var validatorAddrCmd = &cli.Command{
 			Name:  "from",
 			Usage: "optionally specify the account used for the validator",
 		},
+		&cli.BoolFlag{
+			Name:  "ipv6-first",
+			Usage: "prefer the IPv6 addresses of the validator over its IPv4 ones",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		// check if repo initialized
@@ -102,40 +113,54 @@ var validatorAddrCmd = &cli.Command{
 		}
 		defer ncloser()
 
-		// validator identity.
-		validator, err := validatorIDFromFlag(context.Background(), cctx, nodeApi)
-		if err != nil {
-			return err
-		}
-
-		pk, err := lp2pID(cctx.String("repo"))
-		if err != nil {
-			return fmt.Errorf("error getting libp2p private key: %s", err)
-		}
-		pid, err := peer.IDFromPublicKey(pk.GetPublic())
-		if err != nil {
-			return fmt.Errorf("error generating ID from private key: %s", err)
-		}
-
-		// get multiaddr for host.
-		path := filepath.Join(cctx.String("repo"), MaddrPath)
-		bMaddr, err := os.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("error reading multiaddr from file: %w", err)
-		}
-		addrs, err := unmarshalMultiAddrSlice(bMaddr)
+		validator, addrs, err := localValidator(context.Background(), cctx, nodeApi)
 		if err != nil {
 			return err
 		}
 
 		for _, a := range addrs {
-			fmt.Printf("%s@%s/p2p/%s\n", validator, a, pid)
+			fmt.Printf("%s@%s\n", validator, a)
 		}
 
 		return nil
 	},
 }
 
+// localValidator returns the wallet address of the validator and its network addresses,
+// i.e. the multiaddrs the validator listens on followed by its libp2p peer ID, in order of preference.
+func localValidator(ctx context.Context, cctx *cli.Context, nodeApi api.FullNode) (address.Address, []string, error) {
+	validator, err := validatorIDFromFlag(ctx, cctx, nodeApi)
+	if err != nil {
+		return address.Undef, nil, err
+	}
+
+	pk, err := lp2pID(cctx.String("repo"))
+	if err != nil {
+		return address.Undef, nil, fmt.Errorf("error getting libp2p private key: %s", err)
+	}
+	pid, err := peer.IDFromPublicKey(pk.GetPublic())
+	if err != nil {
+		return address.Undef, nil, fmt.Errorf("error generating ID from private key: %s", err)
+	}
+
+	// get multiaddr for host.
+	path := filepath.Join(cctx.String("repo"), MaddrPath)
+	bMaddr, err := os.ReadFile(path)
+	if err != nil {
+		return address.Undef, nil, fmt.Errorf("error reading multiaddr from file: %w", err)
+	}
+	addrs, err := unmarshalMultiAddrSlice(bMaddr)
+	if err != nil {
+		return address.Undef, nil, err
+	}
+
+	netAddrs := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		netAddrs = append(netAddrs, fmt.Sprintf("%s/p2p/%s", a, pid))
+	}
+	return validator, membership.SortNetAddrs(netAddrs, cctx.Bool("ipv6-first")), nil
+}
+
 func cleanConfig(repo string) {
 	log.Infow("Cleaning mir config files from repo")
 	for _, s := range configFiles {
@@ -2,12 +2,15 @@ package mirvalidator
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	_ "net/http/pprof"
 	"os"
 	"path/filepath"
 
 	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/urfave/cli/v2"
 	"go.opencensus.io/tag"
 	"golang.org/x/xerrors"
@@ -18,6 +21,7 @@ import (
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/consensus/mir"
 	mirkv "github.com/filecoin-project/lotus/chain/consensus/mir/db/kv"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/solidity"
 	lcli "github.com/filecoin-project/lotus/cli"
 	"github.com/filecoin-project/lotus/metrics"
 )
@@ -28,17 +32,24 @@ var checkCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		importCheckCmd,
 		exportCheckCmd,
+		exportCertCmd,
+		fetchCheckCmd,
+		followCheckCmd,
+		finalityCheckCmd,
 	},
 }
 
 var importCheckCmd = &cli.Command{
-	Name:  "import",
-	Usage: "Imports checkpoint from file",
+	Name:      "import",
+	Usage:     "Imports checkpoints from files",
+	ArgsUsage: "[checkpoint files...]",
+	Description: `The checkpoints are persisted in the datastore of the validator, indexed by their height,
+so a validator can be bootstrapped from checkpoints archived out of band.`,
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:    "file",
 			Aliases: []string{"f"},
-			Usage:   "optionally specify the account used for the validator",
+			Usage:   "file with the checkpoint to import",
 		},
 	},
 	Action: func(cctx *cli.Context) error {
@@ -49,7 +60,13 @@ var importCheckCmd = &cli.Command{
 		)
 
 		repoFlag := cctx.String("repo")
-		fileFlag := cctx.String("file")
+		files := cctx.Args().Slice()
+		if f := cctx.String("file"); f != "" {
+			files = append(files, f)
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("expected the checkpoint files to import")
+		}
 
 		// check if validator has been initialized.
 		if err := initCheck(repoFlag); err != nil {
@@ -62,10 +79,16 @@ var importCheckCmd = &cli.Command{
 		if err != nil {
 			return fmt.Errorf("error initializing mir datastore: %s", err)
 		}
+		defer ds.Close() // nolint
 
-		_, err = checkpointFromFile(ctx, ds, fileFlag)
-		log.Infof("Import checkpoint from file %s", fileFlag)
-		return err
+		for _, f := range files {
+			ch, err := checkpointFromFile(ctx, ds, f)
+			if err != nil {
+				return fmt.Errorf("error importing checkpoint from file %s: %w", f, err)
+			}
+			log.Infof("Imported checkpoint of epoch %d from file %s", ch.Snapshot.EpochData.EpochConfig.EpochNr, f)
+		}
+		return nil
 	},
 }
 
@@ -75,13 +98,17 @@ var exportCheckCmd = &cli.Command{
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:  "output",
-			Usage: "optionally specify the output for the checkpoint",
+			Usage: "optionally specify the output for the checkpoint, a directory with --all",
 		},
 		&cli.IntFlag{
 			Name:  "height",
 			Usage: "optionally specify the height for the checkpoint to export. If not specified the latest one will be exported",
 			Value: 0,
 		},
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "export every checkpoint persisted by the validator from --height on",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx, _ := tag.New(lcli.DaemonContext(cctx),
@@ -103,13 +130,27 @@ var exportCheckCmd = &cli.Command{
 		if err != nil {
 			return fmt.Errorf("error initializing mir datastore: %s", err)
 		}
+		defer ds.Close() // nolint
 
 		height := abi.ChainEpoch(cctx.Int("height"))
+		if cctx.Bool("all") {
+			dir := cctx.String("output")
+			if dir == "" {
+				dir = "."
+			}
+			n, err := mir.ExportCheckpoints(ctx, ds, dir, height)
+			if err != nil {
+				return fmt.Errorf("error exporting checkpoints: %w", err)
+			}
+			log.Infof("Exported %d checkpoints to %s", n, dir)
+			return nil
+		}
+
 		ch, err := mir.GetCheckpointByHeight(ctx, ds, height, nil)
 		if err != nil {
 			return fmt.Errorf("error getting checkpoint by height: %s", err)
 		}
-		path := cctx.String("file")
+		path := cctx.String("output")
 		heightStr := height.String()
 		if path == "" {
 			if height == 0 {
@@ -122,13 +163,202 @@ var exportCheckCmd = &cli.Command{
 	},
 }
 
+var exportCertCmd = &cli.Command{
+	Name:  "export-cert",
+	Usage: "Exports the certificate of a checkpoint ABI-encoded for Solidity verifiers",
+	Description: `The certificate is written as the hex of abi.encode(SubnetCheckpointCert), with the layout of
+chain/consensus/mir/solidity/SubnetCheckpointCert.sol, so contracts on the parent or on other subnets
+can verify the finality of the subnet.`,
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "height",
+			Usage: "optionally specify the height of the checkpoint. If not specified the latest one is exported",
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "optionally specify the output file, instead of the standard output",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		ds, err := mirkv.NewLevelDB(filepath.Join(repoFlag, LevelDSPath), true)
+		if err != nil {
+			return fmt.Errorf("error initializing mir datastore: %s", err)
+		}
+		defer ds.Close() // nolint
+
+		ch, err := mir.GetCheckpointByHeight(ctx, ds, abi.ChainEpoch(cctx.Int("height")), nil)
+		if err != nil {
+			return fmt.Errorf("error getting checkpoint by height: %s", err)
+		}
+		cert, err := solidity.NewCert(ch)
+		if err != nil {
+			return fmt.Errorf("error converting checkpoint certificate: %w", err)
+		}
+
+		out := "0x" + hex.EncodeToString(cert.EncodeABI()) + "\n"
+		if path := cctx.String("output"); path != "" {
+			return os.WriteFile(path, []byte(out), 0644)
+		}
+		_, err = fmt.Fprint(cctx.App.Writer, out)
+		return err
+	},
+}
+
+var fetchCheckCmd = &cli.Command{
+	Name:  "fetch",
+	Usage: "Fetches a checkpoint from other validators and writes it to file",
+	Description: `The checkpoint is requested from the given validators in order until one of them sends
+a checkpoint with a valid certificate, so it can be fetched from untrusted peers. The validators
+are identified by their multiaddress, including their peer ID, like in the membership file.`,
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:     "peer",
+			Usage:    "multiaddress of a validator to request the checkpoint from",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  "height",
+			Usage: "optionally specify the height of the checkpoint to fetch. If not specified the latest one is fetched",
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "optionally specify the output for the checkpoint",
+		},
+		&cli.BoolFlag{
+			Name:  "import",
+			Usage: "also import the checkpoint into the datastore of the validator",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		var peers []peer.AddrInfo
+		for _, s := range cctx.StringSlice("peer") {
+			info, err := peer.AddrInfoFromString(s)
+			if err != nil {
+				return xerrors.Errorf("invalid peer %s: %w", s, err)
+			}
+			peers = append(peers, *info)
+		}
+
+		// A throwaway host is used, so the checkpoint can be fetched while the validator is running.
+		h, err := libp2p.New(libp2p.NoListenAddrs)
+		if err != nil {
+			return xerrors.Errorf("error creating libp2p host: %w", err)
+		}
+		defer h.Close() // nolint
+
+		height := abi.ChainEpoch(cctx.Int("height"))
+		var ch *checkpoint.StableCheckpoint
+		for _, p := range peers {
+			ch, err = mir.FetchCheckpoint(ctx, h, p, height)
+			if err == nil {
+				break
+			}
+			log.Warnf("failed to fetch checkpoint from %s: %s", p.ID, err)
+		}
+		if ch == nil {
+			return xerrors.Errorf("no validator sent the checkpoint")
+		}
+		snap, err := mir.UnwrapCheckpointSnapshot(ch)
+		if err != nil {
+			return err
+		}
+
+		path := cctx.String("output")
+		if path == "" {
+			path = "./" + mir.CheckpointFileName(snap.Height)
+		}
+		log.Infof("Writing checkpoint for height %d to file %s", snap.Height, path)
+		if err := mir.CheckpointToFile(ch, path); err != nil {
+			return err
+		}
+
+		if !cctx.Bool("import") {
+			return nil
+		}
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+		ds, err := mirkv.NewLevelDB(filepath.Join(repoFlag, LevelDSPath), false)
+		if err != nil {
+			return fmt.Errorf("error initializing mir datastore: %s", err)
+		}
+		defer ds.Close() // nolint
+		_, err = checkpointFromFile(ctx, ds, path)
+		return err
+	},
+}
+
+var finalityCheckCmd = &cli.Command{
+	Name:  "finality",
+	Usage: "Writes to file the checkpoint that finalized the block at a height",
+	Description: `The checkpoint is requested from the running validator. It can be verified against the validator
+set of the subnet with the lightclient package, without running a node of the subnet.`,
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:     "height",
+			Usage:    "height of the block",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "optionally specify the output for the checkpoint",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		n, err := c.MirFinalityCheckpoint(ctx, abi.ChainEpoch(cctx.Int64("height")))
+		if err != nil {
+			return fmt.Errorf("error getting finality checkpoint: %w", err)
+		}
+		ch := &checkpoint.StableCheckpoint{}
+		if err := ch.Deserialize(n.Checkpoint); err != nil {
+			return fmt.Errorf("error deserializing checkpoint: %w", err)
+		}
+		cert := checkpoint.Certificate{}
+		if err := cert.Deserialize(n.Cert); err != nil {
+			return fmt.Errorf("error deserializing checkpoint certificate: %w", err)
+		}
+		ch = ch.AttachCert(&cert)
+
+		path := cctx.String("output")
+		if path == "" {
+			path = "./" + mir.CheckpointFileName(n.Height)
+		}
+		log.Infof("Writing checkpoint for height %d to file %s", n.Height, path)
+		return mir.CheckpointToFile(ch, path)
+	},
+}
+
 func checkpointFromFile(ctx context.Context, ds datastore.Datastore, path string) (*checkpoint.StableCheckpoint, error) {
-	b, err := os.ReadFile(path)
+	b, err := mir.ReadCheckpointFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("error checkpoint from file: %s", err)
 	}
-	ch := &checkpoint.StableCheckpoint{}
-	err = ch.Deserialize(b)
+	ch, err := mir.DeserializeCheckpoint(b)
 	if err != nil {
 		return nil, fmt.Errorf("error deserializing checkpoint from file: %s", err)
 	}
@@ -0,0 +1,56 @@
+package mirvalidator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var followCheckCmd = &cli.Command{
+	Name:  "follow",
+	Usage: "Stream the stable checkpoints delivered to the running validator",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the checkpoints as JSON, including the checkpoint and its certificate",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		checkpoints, err := c.MirCheckpointNotify(ctx)
+		if err != nil {
+			return fmt.Errorf("error subscribing to checkpoints: %w", err)
+		}
+		for ch := range checkpoints {
+			if cctx.Bool("json") {
+				b, err := json.Marshal(ch)
+				if err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintln(cctx.App.Writer, string(b))
+				continue
+			}
+			_, _ = fmt.Fprintf(cctx.App.Writer, "epoch %d\theight %d\t%s\n", ch.Epoch, ch.Height, ch.Cid)
+		}
+		if ctx.Err() == nil {
+			return fmt.Errorf("checkpoint stream closed by the validator")
+		}
+		return nil
+	},
+}
@@ -0,0 +1,60 @@
+package mirvalidator
+
+import (
+	"fmt"
+	"math"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var contributionsCmd = &cli.Command{
+	Name:  "contributions",
+	Usage: "Show the transactions contributed by every validator to the batches ordered in each epoch",
+	Flags: []cli.Flag{
+		&cli.Uint64Flag{
+			Name:  "from",
+			Usage: "first epoch",
+		},
+		&cli.Uint64Flag{
+			Name:  "to",
+			Usage: "last epoch (defaults to the epoch being ordered)",
+			Value: math.MaxUint64,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		epochs, err := c.MirContributionStats(ctx, cctx.Uint64("from"), cctx.Uint64("to"))
+		if err != nil {
+			return fmt.Errorf("error getting contribution stats: %w", err)
+		}
+
+		tw := tabwriter.NewWriter(cctx.App.Writer, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Epoch\tHeights\tValidator\tTxs\tBytes\n")
+		for _, e := range epochs {
+			for _, v := range e.Validators {
+				_, _ = fmt.Fprintf(tw, "%d\t%d-%d\t%s\t%d\t%s\n", e.Epoch, e.FirstHeight, e.LastHeight, v.Validator,
+					v.Transactions, types.SizeStr(big.NewIntUnsigned(v.Bytes)))
+			}
+		}
+		return tw.Flush()
+	},
+}
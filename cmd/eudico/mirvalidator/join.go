@@ -0,0 +1,186 @@
+package mirvalidator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/consensus-shipyard/go-ipc-types/sdk"
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/chain/ipcagent/rpc"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+// joinSubnetMethod is the method of the IPC Agent that joins a subnet as a validator.
+const joinSubnetMethod = "ipc_joinSubnet"
+
+type joinSubnetParams struct {
+	Subnet           string  `json:"subnet"`
+	From             string  `json:"from,omitempty"`
+	Collateral       float64 `json:"collateral"`
+	ValidatorNetAddr string  `json:"validator_net_addr"`
+}
+
+var joinCmd = &cli.Command{
+	Name:  "join",
+	Usage: "Request to join the validator set of the subnet with the local wallet and libp2p identity",
+	Description: `With the file membership, the validator is added to the membership file of the repo with the
+   next configuration number; the current validators must add the same validator to their membership
+   files for the configuration to be agreed upon. With the onchain membership, the IPC Agent submits
+   the request to join the subnet with the collateral to the parent.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "default-key",
+			Value: true,
+			Usage: "use default wallet's key",
+		},
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "optionally specify the account used for the validator",
+		},
+		&cli.StringFlag{
+			Name:  "membership",
+			Usage: "membership type: onchain or file",
+			Value: "file",
+		},
+		&cli.StringFlag{
+			Name:  "membership-file",
+			Usage: "membership file the validator is added to",
+			Value: MembershipCfgPath,
+		},
+		&cli.StringFlag{
+			Name:  "net-addr",
+			Usage: "network address of the validator, by default the preferred address it listens on",
+		},
+		&cli.BoolFlag{
+			Name:  "ipv6-first",
+			Usage: "prefer the IPv6 addresses of the validator over its IPv4 ones",
+		},
+		&cli.StringFlag{
+			Name:  "weight",
+			Usage: "weight of the validator in the membership file",
+		},
+		&cli.Float64Flag{
+			Name:  "collateral",
+			Usage: "collateral in FIL put by the validator to join the subnet (onchain membership)",
+		},
+		&cli.StringFlag{
+			Name:  "ipcagent-url",
+			Usage: "The URL of IPC Agent interface",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+
+		// check if repo initialized
+		if err := repoInitialized(context.Background(), cctx); err != nil {
+			return err
+		}
+
+		// check if validator has been initialized.
+		if err := initCheck(cctx.String("repo")); err != nil {
+			return err
+		}
+
+		nodeApi, ncloser, err := lcli.GetFullNodeAPIV1(cctx)
+		if err != nil {
+			return xerrors.Errorf("getting full node api: %w", err)
+		}
+		defer ncloser()
+
+		addr, netAddrs, err := localValidator(ctx, cctx, nodeApi)
+		if err != nil {
+			return err
+		}
+		netAddr := cctx.String("net-addr")
+		if netAddr == "" {
+			if len(netAddrs) == 0 {
+				return fmt.Errorf("the validator doesn't listen on any address")
+			}
+			netAddr = netAddrs[0]
+		}
+		if netAddr, err = membership.NormalizeNetAddr(netAddr); err != nil {
+			return err
+		}
+
+		switch cctx.String("membership") {
+		case "file":
+			return joinWithFile(cctx, addr, netAddr)
+		case "onchain":
+			netName, err := nodeApi.StateNetworkName(ctx)
+			if err != nil {
+				return err
+			}
+			return joinOnChain(cctx, string(netName), addr, netAddr)
+		default:
+			return fmt.Errorf("membership type %s not supported", cctx.String("membership"))
+		}
+	},
+}
+
+// joinWithFile adds the validator to the membership file as the next configuration.
+func joinWithFile(cctx *cli.Context, addr address.Address, netAddr string) error {
+	s := fmt.Sprintf("%s@%s", addr, netAddr)
+	if w := cctx.String("weight"); w != "" {
+		s = fmt.Sprintf("%s:%s@%s", addr, w, netAddr)
+	}
+	v, err := validator.NewValidatorFromString(s)
+	if err != nil {
+		return fmt.Errorf("error creating validator %s: %w", s, err)
+	}
+
+	mf := filepath.Join(cctx.String("repo"), cctx.String("membership-file"))
+	set, err := validator.NewValidatorSetFromFile(mf)
+	if err != nil {
+		return fmt.Errorf("error reading membership file %s: %w", mf, err)
+	}
+	for _, cur := range set.Validators {
+		if cur.ID() == v.ID() {
+			return fmt.Errorf("validator %s is already in the membership file %s", v.ID(), mf)
+		}
+	}
+
+	vals := append(append([]*validator.Validator(nil), set.Validators...), v)
+	next := validator.NewValidatorSet(set.ConfigurationNumber+1, vals)
+	if err := next.Save(mf); err != nil {
+		return fmt.Errorf("error saving membership file %s: %w", mf, err)
+	}
+
+	_, _ = fmt.Fprintf(cctx.App.Writer, "Validator %s added to %s with configuration number %d.\n", s, mf, next.ConfigurationNumber)
+	_, _ = fmt.Fprintf(cctx.App.Writer, "The current validators must add it to their membership files too, e.g. with:\n")
+	_, _ = fmt.Fprintf(cctx.App.Writer, "  eudico mir validator config add-validator %s\n", s)
+	return nil
+}
+
+// joinOnChain requests the IPC Agent to join the subnet with the collateral.
+func joinOnChain(cctx *cli.Context, netName string, addr address.Address, netAddr string) error {
+	if cctx.Float64("collateral") <= 0 {
+		return fmt.Errorf("a collateral is required to join the subnet with the onchain membership")
+	}
+	sn, err := sdk.NewSubnetIDFromString(netName)
+	if err != nil {
+		return err
+	}
+
+	req := joinSubnetParams{
+		Subnet:           sn.String(),
+		From:             addr.String(),
+		Collateral:       cctx.Float64("collateral"),
+		ValidatorNetAddr: netAddr,
+	}
+	var resp interface{}
+	cl := rpc.NewJSONRPCClientWithConfig(rpc.NewConfig(cctx.String("ipcagent-url")))
+	if err := cl.SendRequest(joinSubnetMethod, &req, &resp); err != nil {
+		return fmt.Errorf("error joining subnet %s through the IPC Agent: %w", sn, err)
+	}
+
+	_, _ = fmt.Fprintf(cctx.App.Writer, "Request to join subnet %s as %s@%s submitted.\n", sn, addr, netAddr)
+	_, _ = fmt.Fprintf(cctx.App.Writer, "The validator is added to the membership once the request is committed in the parent.\n")
+	return nil
+}
@@ -0,0 +1,91 @@
+package mirvalidator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var leaveCmd = &cli.Command{
+	Name:  "leave",
+	Usage: "Leave the validator set of the subnet and stop the validator",
+	Description: `A configuration request removing the validator is sent to the rest of validators.
+The validator keeps running until the membership of the epoch being ordered doesn't include it,
+so leaving doesn't reduce the validators taking part in the current epoch, and it is stopped then.
+With the file membership, the rest of validators must remove the validator from their membership
+files too for the configuration to be agreed upon.`,
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "time to wait for the validator to be removed before giving up, zero to wait forever",
+		},
+		&cli.BoolFlag{
+			Name:  "no-stop",
+			Usage: "keep the validator running once it is removed from the validator set",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		txNo, err := c.MirLeave(ctx)
+		if err != nil {
+			return fmt.Errorf("error requesting to leave the validator set: %w", err)
+		}
+		log.Infof("Request to leave the validator set submitted in tx %d", txNo)
+
+		var deadline <-chan time.Time
+		if d := cctx.Duration("timeout"); d > 0 {
+			deadline = time.After(d)
+		}
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		ordered := false
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-deadline:
+				return fmt.Errorf("validator not removed after %s: the request in tx %d is still pending", cctx.Duration("timeout"), txNo)
+			case <-ticker.C:
+			}
+
+			s, err := c.MirLeaveStatus(ctx, txNo)
+			if err != nil {
+				return fmt.Errorf("error getting the status of the request: %w", err)
+			}
+			if s.Ordered && !ordered {
+				ordered = true
+				log.Infof("Request ordered, waiting for the configuration to take effect (epoch %d)", s.Epoch)
+			}
+			if s.Left {
+				log.Infof("Validator removed from the membership of epoch %d", s.Epoch)
+				break
+			}
+		}
+
+		if cctx.Bool("no-stop") {
+			return nil
+		}
+		if err := c.MirStop(ctx); err != nil {
+			return fmt.Errorf("error stopping the validator: %w", err)
+		}
+		log.Info("Validator stopped")
+		return nil
+	},
+}
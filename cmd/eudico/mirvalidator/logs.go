@@ -0,0 +1,87 @@
+package mirvalidator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var logsCmd = &cli.Command{
+	Name:  "logs",
+	Usage: "Follow the logs of the running validator",
+	Subcommands: []*cli.Command{
+		logsTailCmd,
+	},
+}
+
+var logsTailCmd = &cli.Command{
+	Name:  "tail",
+	Usage: "Stream the logs of the Mir subsystems of the running validator",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "component",
+			Usage: fmt.Sprintf("only stream the logs of a subsystem (%s)", strings.Join(mir.LogComponents, ", ")),
+		},
+		&cli.StringFlag{
+			Name:  "level",
+			Usage: "minimum level of the streamed logs, the level of the subsystem must allow it too",
+			Value: "info",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the log entries as JSON",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		entries, err := c.MirLogs(ctx, cctx.String("component"), cctx.String("level"))
+		if err != nil {
+			return fmt.Errorf("error streaming logs: %w", err)
+		}
+		for e := range entries {
+			if cctx.Bool("json") {
+				b, err := json.Marshal(e)
+				if err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintln(cctx.App.Writer, string(b))
+				continue
+			}
+			_, _ = fmt.Fprintln(cctx.App.Writer, formatLogEntry(e))
+		}
+		return nil
+	},
+}
+
+func formatLogEntry(e mir.LogEntry) string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "%s\t%s\t%s\t%s\t%s", e.Time, strings.ToUpper(e.Level), e.Component, e.Caller, e.Message)
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(&sb, " %s=%v", k, e.Fields[k])
+	}
+	return sb.String()
+}
@@ -0,0 +1,63 @@
+package mirvalidator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var membershipAtCmd = &cli.Command{
+	Name:  "membership-at",
+	Usage: "Show the validator set of a Mir epoch, or of the epoch a block was ordered in",
+	Flags: []cli.Flag{
+		&cli.Uint64Flag{
+			Name:  "epoch",
+			Usage: "Mir epoch",
+		},
+		&cli.Int64Flag{
+			Name:  "height",
+			Usage: "height of a block of the chain",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.IsSet("epoch") == cctx.IsSet("height") {
+			return fmt.Errorf("expected either --epoch or --height")
+		}
+
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		var entry *mir.MembershipHistoryEntry
+		if cctx.IsSet("epoch") {
+			entry, err = c.MirMembershipAt(ctx, cctx.Uint64("epoch"))
+		} else {
+			entry, err = c.MirMembershipAtHeight(ctx, abi.ChainEpoch(cctx.Int64("height")))
+		}
+		if err != nil {
+			return fmt.Errorf("error getting membership: %w", err)
+		}
+		b, err := json.MarshalIndent(entry, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(cctx.App.Writer, string(b))
+		return nil
+	},
+}
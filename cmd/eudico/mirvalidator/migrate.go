@@ -0,0 +1,137 @@
+package mirvalidator
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/consensus-shipyard/go-ipc-types/sdk"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/chain/ipcagent/rpc"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var migrateMembershipCmd = &cli.Command{
+	Name:  "migrate-membership",
+	Usage: "Move a running subnet from the file membership to the on-chain membership",
+	Description: `The on-chain validator set must match the membership file before the migration is planned.
+Every validator plans the migration with the same switch epoch and restarts, so they all read the
+on-chain membership from the same Mir epoch. The membership file is still used as a fallback for
+the fallback epochs that follow the switch.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "membership-file",
+			Usage: "membership file with configuration",
+			Value: MembershipCfgPath,
+		},
+		&cli.StringFlag{
+			Name:  "ipcagent-url",
+			Usage: "The URL of IPC Agent interface",
+		},
+	},
+	Subcommands: []*cli.Command{
+		migrateMembershipCheckCmd,
+		migrateMembershipPlanCmd,
+	},
+}
+
+var migrateMembershipCheckCmd = &cli.Command{
+	Name:  "check",
+	Usage: "Check that the on-chain validator set matches the membership file",
+	Action: func(cctx *cli.Context) error {
+		if err := checkMembershipMigration(cctx); err != nil {
+			return err
+		}
+		log.Info("On-chain validator set matches the membership file")
+		return nil
+	},
+}
+
+var migrateMembershipPlanCmd = &cli.Command{
+	Name:  "plan",
+	Usage: "Plan the switch to the on-chain membership, effective after restarting the validator",
+	Flags: []cli.Flag{
+		&cli.Uint64Flag{
+			Name:     "switch-epoch",
+			Usage:    "first Mir epoch in which the on-chain membership is read, the same for all the validators",
+			Required: true,
+		},
+		&cli.Uint64Flag{
+			Name:  "fallback-epochs",
+			Usage: "number of epochs after the switch during which the membership file is used if the on-chain membership can't be read",
+			Value: 10,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if err := checkMembershipMigration(cctx); err != nil {
+			return err
+		}
+
+		ctx := lcli.ReqContext(cctx)
+		// The switch must be ahead of the epoch being ordered if the validator is running.
+		if c, closer, err := newAdminClient(ctx, cctx.String("repo")); err == nil {
+			defer closer()
+			st, err := c.MirStatus(ctx)
+			if err != nil {
+				return fmt.Errorf("error getting validator status: %w", err)
+			}
+			if st.State != mir.MirStopped && cctx.Uint64("switch-epoch") <= st.Epoch {
+				return fmt.Errorf("switch epoch %d is not after the current epoch %d", cctx.Uint64("switch-epoch"), st.Epoch)
+			}
+		}
+
+		plan := &membership.MigrationPlan{
+			SwitchEpoch:    cctx.Uint64("switch-epoch"),
+			FallbackEpochs: cctx.Uint64("fallback-epochs"),
+		}
+		p := filepath.Join(cctx.String("repo"), cctx.String("membership-file")) + membership.MigrationFileSuffix
+		if err := plan.Save(p); err != nil {
+			return fmt.Errorf("error saving migration plan: %w", err)
+		}
+		log.Infof("Migration to the on-chain membership in epoch %d planned in %s, restart the validator to apply it", plan.SwitchEpoch, p)
+		return nil
+	},
+}
+
+// checkMembershipMigration checks that the on-chain validator set of the subnet can replace the membership file.
+func checkMembershipMigration(cctx *cli.Context) error {
+	// check if repo initialized
+	if err := repoInitialized(cctx.Context, cctx); err != nil {
+		return err
+	}
+
+	// check if validator has been initialized.
+	if err := initCheck(cctx.String("repo")); err != nil {
+		return err
+	}
+
+	nodeApi, ncloser, err := lcli.GetFullNodeAPIV1(cctx)
+	if err != nil {
+		return xerrors.Errorf("getting full node api: %w", err)
+	}
+	defer ncloser()
+
+	netName, err := nodeApi.StateNetworkName(cctx.Context)
+	if err != nil {
+		return err
+	}
+	sn, err := sdk.NewSubnetIDFromString(string(netName))
+	if err != nil {
+		return err
+	}
+
+	mf := filepath.Join(cctx.String("repo"), cctx.String("membership-file"))
+	fileInfo, err := membership.NewFileMembership(mf).GetMembershipInfo()
+	if err != nil {
+		return fmt.Errorf("error reading membership file %s: %w", mf, err)
+	}
+	cl := rpc.NewJSONRPCClientWithConfig(rpc.NewConfig(cctx.String("ipcagent-url")))
+	onchainInfo, err := membership.NewOnChainMembershipClient(cl, sn).GetMembershipInfo()
+	if err != nil {
+		return fmt.Errorf("error reading on-chain membership: %w", err)
+	}
+	return membership.CheckMigration(fileInfo.ValidatorSet, onchainInfo.ValidatorSet)
+}
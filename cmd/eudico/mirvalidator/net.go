@@ -0,0 +1,54 @@
+package mirvalidator
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var netDiagnosticsCmd = &cli.Command{
+	Name:  "net-diagnostics",
+	Usage: "Show the state of the connections to the validators of the current membership",
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		peers, err := c.MirNetDiagnostics(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting network diagnostics: %w", err)
+		}
+
+		since := func(t time.Time) string {
+			if t.IsZero() {
+				return "never"
+			}
+			return time.Since(t).Truncate(time.Millisecond).String()
+		}
+		tw := tabwriter.NewWriter(cctx.App.Writer, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Validator\tState\tRTT\tLast sent\tLast received\tOut\tIn\n")
+		for _, p := range peers {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", p.ID, p.Connectedness, p.RTT.Truncate(time.Microsecond),
+				since(p.LastSent), since(p.LastReceived),
+				types.SizeStr(big.NewIntUnsigned(p.BytesOut)), types.SizeStr(big.NewIntUnsigned(p.BytesIn)))
+		}
+		return tw.Flush()
+	},
+}
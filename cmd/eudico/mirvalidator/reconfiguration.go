@@ -0,0 +1,146 @@
+package mirvalidator
+
+import (
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/consensus-shipyard/go-ipc-types/validator"
+	"github.com/urfave/cli/v2"
+
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var reconfigurationCmd = &cli.Command{
+	Name:  "reconfiguration",
+	Usage: "Manage the configuration requests of the validator that have not been applied yet",
+	Description: `The commands are served by the running validator through its admin API.
+Cancelling a request only prevents the validator from resending it: if it was already sent
+to the rest of validators, it may still be ordered.`,
+	Subcommands: []*cli.Command{
+		listReconfigurationCmd,
+		cancelReconfigurationCmd,
+		submitReconfigurationCmd,
+	},
+}
+
+var listReconfigurationCmd = &cli.Command{
+	Name:  "list",
+	Usage: "List pending configuration requests",
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		reqs, err := c.PendingConfigurationRequests(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting pending configuration requests: %w", err)
+		}
+
+		tw := tabwriter.NewWriter(cctx.App.Writer, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "TxNo\tConfiguration\tSubmitted\tAge\tStatus\n")
+		for _, r := range reqs {
+			submitted, age := "unknown", "unknown"
+			if !r.SubmittedAt.IsZero() {
+				submitted = r.SubmittedAt.Format(time.RFC3339)
+				age = time.Since(r.SubmittedAt).Truncate(time.Second).String()
+			}
+			status, cfg := "pending", strconv.FormatUint(r.ConfigurationNumber, 10)
+			if r.FreezeHeight > 0 {
+				cfg = fmt.Sprintf("shutdown@%d", r.FreezeHeight)
+			}
+			if r.Cancelled {
+				status, cfg = "cancelled", "-"
+			}
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", r.TxNo, cfg, submitted, age, status)
+		}
+		return tw.Flush()
+	},
+}
+
+var cancelReconfigurationCmd = &cli.Command{
+	Name:      "cancel",
+	Usage:     "Cancel a pending configuration request",
+	ArgsUsage: "<tx number>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return fmt.Errorf("expected the number of the configuration tx as input")
+		}
+		txNo, err := strconv.ParseUint(cctx.Args().First(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("error parsing configuration tx number: %w", err)
+		}
+
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		if err := c.CancelConfigurationRequest(ctx, txNo); err != nil {
+			return err
+		}
+
+		log.Infof("Configuration tx %d cancelled", txNo)
+		return nil
+	},
+}
+
+var submitReconfigurationCmd = &cli.Command{
+	Name:      "submit",
+	Usage:     "Propose a validator set to the rest of validators, bypassing the membership source",
+	ArgsUsage: "<validator set file>",
+	Description: `The validator set file has the same format as the membership file of the validator.
+The request is proposed like the ones read from the membership source, so it is only applied
+if enough validators vote for the same validator set.`,
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return fmt.Errorf("expected the validator set file as input")
+		}
+		set, err := validator.NewValidatorSetFromFile(cctx.Args().First())
+		if err != nil {
+			return fmt.Errorf("error reading validator set: %w", err)
+		}
+
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		txNo, err := c.SubmitConfigurationRequest(ctx, set)
+		if err != nil {
+			return fmt.Errorf("error submitting configuration request: %w", err)
+		}
+
+		log.Infof("Configuration %d submitted in tx %d", set.ConfigurationNumber, txNo)
+		return nil
+	},
+}
@@ -0,0 +1,103 @@
+package mirvalidator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	mirkv "github.com/filecoin-project/lotus/chain/consensus/mir/db/kv"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var recoverCmd = &cli.Command{
+	Name:  "recover",
+	Usage: "Prepare a stopped validator to restart a subnet that lost its liveness from a checkpoint",
+	Description: `Every validator of the subnet is stopped and recovered from the same checkpoint file, e.g. the
+latest checkpoint exported by one of them. The checkpoint becomes the latest checkpoint of the
+validator, its pending configuration requests and votes are dropped, and the membership file is
+rebuilt from the membership of the epoch of the checkpoint. Once all the validators are recovered,
+they are restarted from the checkpoint with the run command printed at the end.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "from-checkpoint",
+			Usage:    "file with the checkpoint the subnet restarts from",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "membership-file",
+			Usage: "membership file rebuilt from the checkpoint",
+			Value: MembershipCfgPath,
+		},
+		&cli.BoolFlag{
+			Name:  "keep-membership-file",
+			Usage: "don't rebuild the membership file, e.g. with the on-chain membership",
+		},
+		&cli.BoolFlag{
+			Name:  "default-key",
+			Value: true,
+			Usage: "use default wallet's key",
+		},
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "optionally specify the account used for the validator",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(repoFlag, AdminAddrPath)); err == nil {
+			return fmt.Errorf("the validator is running: stop it before recovering it")
+		}
+
+		b, err := mir.ReadCheckpointFile(cctx.String("from-checkpoint"))
+		if err != nil {
+			return fmt.Errorf("error reading checkpoint: %w", err)
+		}
+
+		nodeApi, ncloser, err := lcli.GetFullNodeAPIV1(cctx)
+		if err != nil {
+			return fmt.Errorf("getting full node api: %w", err)
+		}
+		defer ncloser()
+		validatorID, err := validatorIDFromFlag(ctx, cctx, nodeApi)
+		if err != nil {
+			return err
+		}
+
+		ds, err := mirkv.NewLevelDB(filepath.Join(repoFlag, LevelDSPath), false)
+		if err != nil {
+			return fmt.Errorf("error initializing mir datastore: %s", err)
+		}
+		defer ds.Close() // nolint
+
+		r, err := mir.RecoverFromCheckpoint(ctx, ds, validatorID.String(), b)
+		if err != nil {
+			return fmt.Errorf("error recovering from checkpoint: %w", err)
+		}
+
+		if !cctx.Bool("keep-membership-file") {
+			mf := filepath.Join(repoFlag, cctx.String("membership-file"))
+			if err := r.ValidatorSet.Save(mf); err != nil {
+				return fmt.Errorf("error saving membership file %s: %w", mf, err)
+			}
+			log.Infof("Membership file %s rebuilt with configuration %d and %d validators",
+				mf, r.ValidatorSet.ConfigurationNumber, r.ValidatorSet.Size())
+		}
+
+		w := cctx.App.Writer
+		_, _ = fmt.Fprintf(w, "Recovered to the checkpoint of epoch %d at height %d\n", r.Epoch, r.Height)
+		_, _ = fmt.Fprintf(w, "Checkpoint hash: %s (must be the same on all the validators)\n", r.Hash)
+		_, _ = fmt.Fprintf(w, "Cancelled configuration requests: %d\n", r.CancelledConfigurationTxs)
+		_, _ = fmt.Fprintf(w, "Once all the validators are recovered, restart them with:\n")
+		_, _ = fmt.Fprintf(w, "  eudico mir validator run --init-checkpoint-height=%d\n", r.Height)
+		return nil
+	},
+}
@@ -0,0 +1,41 @@
+package mirvalidator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var startupReportCmd = &cli.Command{
+	Name:  "startup-report",
+	Usage: "Print the effective configuration the running validator was started with",
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		r, err := c.MirStartupReport(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting startup report: %w", err)
+		}
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(cctx.App.Writer, string(b))
+		return nil
+	},
+}
@@ -3,9 +3,13 @@ package mirvalidator
 import (
 	"context"
 	_ "net/http/pprof"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/consensus-shipyard/go-ipc-types/sdk"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
 	"github.com/urfave/cli/v2"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
@@ -13,19 +17,26 @@ import (
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-jsonrpc"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/mir/pkg/checkpoint"
 	mirlibp2p "github.com/filecoin-project/mir/pkg/net/libp2p"
 	t "github.com/filecoin-project/mir/pkg/types"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/client"
 	"github.com/filecoin-project/lotus/api/v0api"
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/attestation"
 	mirkv "github.com/filecoin-project/lotus/chain/consensus/mir/db/kv"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/pool/external"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetcron"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetparams"
 	"github.com/filecoin-project/lotus/chain/ipcagent/rpc"
 	lcli "github.com/filecoin-project/lotus/cli"
+	cliutil "github.com/filecoin-project/lotus/cli/util"
 	"github.com/filecoin-project/lotus/eudico-core/global"
 	"github.com/filecoin-project/lotus/lib/ulimit"
 	"github.com/filecoin-project/lotus/metrics"
@@ -54,13 +65,19 @@ var runCmd = &cli.Command{
 			Value: true,
 		},
 		&cli.IntFlag{
-			Name:  "init-height",
-			Usage: "checkpoint height from which to start the validator",
-			Value: 0,
+			Name:    "init-height",
+			Aliases: []string{"init-checkpoint-height"},
+			Usage:   "height of a checkpoint persisted by the validator from which to start Mir (see 'checkpoint import')",
+			Value:   0,
 		},
 		&cli.StringFlag{
-			Name:  "init-checkpoint",
-			Usage: "pass initial checkpoint as a file (it overwrites 'init-height' flag)",
+			Name:    "init-checkpoint",
+			Aliases: []string{"init-checkpoint-file"},
+			Usage:   "file with the checkpoint from which to start Mir",
+		},
+		&cli.BoolFlag{
+			Name:  "ephemeral",
+			Usage: "keep the Mir datastore in memory (for short-lived devnets and CI); after a restart the validator rejoins from the latest checkpoint in the checkpoints repo",
 		},
 		&cli.StringFlag{
 			Name:  "membership",
@@ -72,6 +89,19 @@ var runCmd = &cli.Command{
 			Usage: "membership file with configuration",
 			Value: MembershipCfgPath,
 		},
+		&cli.IntFlag{
+			Name:  "genesis-quorum",
+			Usage: "with an empty membership file, bootstrap the genesis membership with this number of validators announcing themselves",
+		},
+		&cli.StringFlag{
+			Name:  "genesis-weight",
+			Usage: "weight of the validator in the bootstrapped genesis membership",
+			Value: "1",
+		},
+		&cli.StringSliceFlag{
+			Name:  "bootstrap-peer",
+			Usage: "libp2p address of another prospective validator, used to bootstrap the genesis membership",
+		},
 		&cli.StringFlag{
 			Name:  "restore-configuration-number",
 			Usage: "use persisted configuration number",
@@ -93,11 +123,154 @@ var runCmd = &cli.Command{
 			Name:  "ipcagent-url",
 			Usage: "The URL of IPC Agent interface",
 		},
+		&cli.StringFlag{
+			Name:  "daemon-api",
+			Usage: "API info (token:multiaddr) of the full node the validator uses, e.g. a remote daemon; the connection is re-established automatically if it is lost",
+		},
+		&cli.StringFlag{
+			Name:  "parent-api",
+			Usage: "API info (token:multiaddr) of a full node of the parent used to verify the on-chain membership instead of trusting the IPC Agent",
+		},
+		&cli.StringFlag{
+			Name:  "parent-checkpoint",
+			Usage: "file with a trusted checkpoint of the parent the verification of the parent chain starts from (required with 'parent-api')",
+		},
+		&cli.BoolFlag{
+			Name:  "checkpoint-randomness",
+			Usage: "include beacon entries derived from checkpoints in blocks (applies when the subnet is bootstrapped; all the validators must enable it)",
+		},
+		&cli.BoolFlag{
+			Name:  "compress-checkpoints",
+			Usage: "compress the snapshots of the checkpoints and the persisted stable checkpoints (all the validators must enable it)",
+		},
+		&cli.DurationFlag{
+			Name:  "stall-timeout",
+			Usage: "time without batches after which the chain is reported as stalled (defaults to 30 times the max block delay)",
+		},
+		&cli.Int64Flag{
+			Name:  "batch-store-cap",
+			Usage: "size in bytes of the batch store of the availability layer above which it is pruned before the next stable checkpoint (0 for no cap)",
+		},
+		&cli.IntFlag{
+			Name:  "checkpoint-retention-count",
+			Usage: "number of latest checkpoints kept in the datastore (0 to keep all, unless limited by epochs)",
+		},
+		&cli.Int64Flag{
+			Name:  "checkpoint-retention-epochs",
+			Usage: "number of blocks below the latest checkpoint for which checkpoints are kept in the datastore (0 to keep all, unless limited by count)",
+		},
+		&cli.IntFlag{
+			Name:  "checkpoint-files-max",
+			Usage: "maximum number of checkpoint files kept in the checkpoints repo (0 for no limit)",
+		},
+		&cli.Int64Flag{
+			Name:  "checkpoint-files-max-size",
+			Usage: "maximum size in bytes of the checkpoint files kept in the checkpoints repo (0 for no limit)",
+		},
+		&cli.BoolFlag{
+			Name:  "checkpoint-files-compress",
+			Usage: "compress the checkpoint files of the checkpoints repo, except the latest one",
+		},
+		&cli.BoolFlag{
+			Name:  "checkpoint-files-strict",
+			Usage: "sync every checkpoint to the checkpoints repo before delivering it, and stop producing blocks if it fails",
+		},
+		&cli.StringFlag{
+			Name:  "state-snapshots-repo",
+			Usage: "path where the state of the chain is exported at every checkpoint, to serve it to the validators that fast-sync",
+		},
+		&cli.BoolFlag{
+			Name:  "fast-sync",
+			Usage: "restore the state at the checkpoint from the state snapshots of the other validators when the chain is far behind it, instead of syncing all the blocks",
+		},
+		&cli.BoolFlag{
+			Name:  "disable-mempool-bucketing",
+			Usage: "propose all the messages selected from the mempool instead of only those assigned to the validator",
+		},
+		&cli.BoolFlag{
+			Name:  "disable-params-presets",
+			Usage: "keep the default Mir timeouts and transport parameters in large memberships instead of scaling them to the number of validators",
+		},
+		&cli.IntFlag{
+			Name:  "max-validators",
+			Usage: "raise the connection limits of the libp2p host of the validator for memberships of up to this many validators (0 keeps the libp2p defaults)",
+			Value: mir.DefaultMaxValidators,
+		},
+		&cli.BoolFlag{
+			Name:  "daemonize",
+			Usage: "run the validator in the background",
+		},
+		&cli.StringFlag{
+			Name:  "daemon-log-file",
+			Usage: "file the output of the daemonized validator is appended to (defaults to validator.log in the repo)",
+		},
+		&cli.StringFlag{
+			Name:  "pidfile",
+			Usage: "write the PID of the validator to this file",
+		},
+		&cli.IntFlag{
+			Name:  "mpool-select-retries",
+			Usage: "number of times the selection of messages from the mempool is retried before proposing a batch without them",
+			Value: mir.DefaultMpoolSelectRetries,
+		},
+		&cli.IntFlag{
+			Name:  "ramp-up-epochs",
+			Usage: "number of epochs after a (re)start over which the size of the proposed batches grows to its limit (0 disables it)",
+			Value: mir.DefaultRampUpEpochs,
+		},
+		&cli.IntFlag{
+			Name:  "max-checkpoint-blocks",
+			Usage: "maximum number of blocks committed by a checkpoint, the segment length times the number of validators",
+			Value: mir.DefaultMaxCheckpointBlocks,
+		},
+		&cli.IntFlag{
+			Name:  "execution-lag",
+			Usage: "number of ordered blocks that can wait for the execution of the previous ones (0 executes every block before ordering the next)",
+		},
+		&cli.BoolFlag{
+			Name:  "quiet-selection-errors",
+			Usage: "don't warn about batches proposed without mempool messages because the selection failed",
+		},
+		&cli.StringSliceFlag{
+			Name:  "tx-feed",
+			Usage: "URL of an external feed of signed messages proposed in addition to the mempool ones",
+		},
+		&cli.StringFlag{
+			Name:  "tx-feed-token-file",
+			Usage: "file with the bearer token used to authenticate to the external feeds",
+		},
+		&cli.StringFlag{
+			Name:  "failure-policy",
+			Usage: "what to do when the Mir node fails: crash (stop the validator), restart (restart Mir from the latest checkpoint) or degrade (keep the validator running without Mir)",
+			Value: string(mir.DefaultFailurePolicy),
+		},
+		&cli.StringFlag{
+			Name:  "admin-listen",
+			Usage: "address the admin API used by the validator CLI listens on",
+			Value: "127.0.0.1:0",
+		},
+		&cli.Int64Flag{
+			Name:  "ready-checkpoint-lag",
+			Usage: "number of blocks the head can be behind the latest checkpoint for the validator to be reported as ready",
+			Value: int64(mir.DefaultReadyCheckpointLag),
+		},
 	},
 	Action: func(cctx *cli.Context) error {
+		if cctx.Bool("daemonize") && !isDaemonized() {
+			return daemonize(cctx)
+		}
+
 		api.RunningNodeType = api.NodeMiner
 		global.SetConsensusAlgorithm(global.MirConsensus)
 
+		if p := cctx.String("pidfile"); p != "" {
+			removePidFile, err := writePidFile(p)
+			if err != nil {
+				return err
+			}
+			defer removePidFile()
+		}
+
 		ctx, _ := tag.New(lcli.DaemonContext(cctx),
 			tag.Insert(metrics.Version, build.BuildVersion),
 			tag.Insert(metrics.Commit, build.CurrentCommit),
@@ -105,19 +278,22 @@ var runCmd = &cli.Command{
 		)
 		// Register all metric views
 		if err := view.Register(
-			metrics.MinerNodeViews...,
+			append(metrics.MinerNodeViews, metrics.MirValidatorViews...)...,
 		); err != nil {
 			log.Fatalf("Cannot register the view: %v", err)
 		}
 		// Set the metric to one so it is published to the exporter
 		stats.Record(ctx, metrics.LotusInfo.M(1))
 
-		nodeApi, ncloser, err := lcli.GetFullNodeAPIV1(cctx)
+		nodeApi, ncloser, err := fullNodeAPIFromFlags(ctx, cctx)
 		if err != nil {
 			return xerrors.Errorf("getting full node api: %w", err)
 		}
 		defer ncloser()
 
+		if err := mir.WaitForDaemon(ctx, nodeApi); err != nil {
+			return err
+		}
 		v, err := nodeApi.Version(ctx)
 		if err != nil {
 			return err
@@ -152,7 +328,7 @@ var runCmd = &cli.Command{
 			return err
 		}
 
-		h, err := getLibP2PHost(cctx.String("repo"))
+		h, err := getLibP2PHost(cctx.String("repo"), cctx.Int("max-validators"))
 		if err != nil {
 			return err
 		}
@@ -164,25 +340,60 @@ var runCmd = &cli.Command{
 
 		// Initialize Mir's DB.
 		dbPath := filepath.Join(cctx.String("repo"), LevelDSPath)
-		ds, err := mirkv.NewLevelDB(dbPath, false)
-		if err != nil {
-			return xerrors.Errorf("error initializing mir datastore: %w", err)
+		var ds datastore.Batching
+		if cctx.Bool("ephemeral") {
+			// Only the checkpoints written to the checkpoints repo survive the validator.
+			dbPath = ""
+			ds = dssync.MutexWrap(datastore.NewMapDatastore())
+			if cctx.String("checkpoints-repo") == "" {
+				log.Warn("ephemeral validator without checkpoints repo: it will rejoin from genesis after a restart")
+			}
+		} else {
+			ds, err = mirkv.NewLevelDB(dbPath, false)
+			if err != nil {
+				return xerrors.Errorf("error initializing mir datastore: %w", err)
+			}
 		}
 
 		// get initial checkpoint
+		if cctx.String("init-checkpoint") != "" && cctx.Int("init-height") != 0 {
+			return xerrors.Errorf("'init-checkpoint' and 'init-height' are mutually exclusive")
+		}
 		var initCh *checkpoint.StableCheckpoint
 		if cctx.String("init-checkpoint") != "" {
 			initCh, err = checkpointFromFile(ctx, ds, cctx.String("init-checkpoint"))
 			if err != nil {
 				return xerrors.Errorf("failed to get initial checkpoint from file: %s", err)
 			}
-			log.Info("Initializing mir validator from checkpoint provided in file: %s", cctx.String("init-checkpoint"))
+			log.Infof("Initializing mir validator from checkpoint of epoch %d provided in file: %s",
+				initCh.Snapshot.EpochData.EpochConfig.EpochNr, cctx.String("init-checkpoint"))
 		} else if cctx.Int("init-height") != 0 {
 			initCh, err = mir.GetCheckpointByHeight(ctx, ds, abi.ChainEpoch(cctx.Int("init-height")), nil)
 			if err != nil {
-				return xerrors.Errorf("failed to get initial checkpoint from file: %s", err)
+				return xerrors.Errorf("failed to get initial checkpoint at height %d: %w (import it with 'checkpoint import')",
+					cctx.Int("init-height"), err)
+			}
+			log.Infof("Initializing mir validator from checkpoint of epoch %d at height %d",
+				initCh.Snapshot.EpochData.EpochConfig.EpochNr, cctx.Int("init-height"))
+		} else if cctx.Bool("ephemeral") && cctx.String("checkpoints-repo") != "" {
+			// An ephemeral validator has no state after a restart, so it rejoins like a fresh
+			// validator from the latest checkpoint it persisted, if any.
+			f, h, err := mir.LatestCheckpointFile(cctx.String("checkpoints-repo"))
+			if err != nil {
+				return xerrors.Errorf("failed to find latest persisted checkpoint: %w", err)
+			}
+			if f != "" {
+				initCh, err = checkpointFromFile(ctx, ds, f)
+				if err != nil {
+					return xerrors.Errorf("failed to get latest persisted checkpoint: %w", err)
+				}
+				log.Infof("Rejoining from the latest persisted checkpoint at height %d", h)
 			}
-			log.Info("Initializing mir validator from checkpoint in height: %d", cctx.Int("init-height"))
+		}
+
+		failurePolicy, err := mir.ParseFailurePolicy(cctx.String("failure-policy"))
+		if err != nil {
+			return err
 		}
 
 		cfg, err := mir.NewConfig(
@@ -199,35 +410,229 @@ var runCmd = &cli.Command{
 		if err != nil {
 			return xerrors.Errorf("failed to get a config: %v", err)
 		}
+		cfg.Ephemeral = cctx.Bool("ephemeral")
+		cfg.CheckpointRetention = mir.CheckpointRetention{
+			Checkpoints: cctx.Int("checkpoint-retention-count"),
+			Epochs:      abi.ChainEpoch(cctx.Int64("checkpoint-retention-epochs")),
+		}
+		cfg.CheckpointFileRetention = mir.CheckpointFileRetention{
+			MaxFiles: cctx.Int("checkpoint-files-max"),
+			MaxBytes: cctx.Int64("checkpoint-files-max-size"),
+			Compress: cctx.Bool("checkpoint-files-compress"),
+		}
+		if cctx.Bool("checkpoint-files-strict") && cfg.CheckpointRepo == "" {
+			return xerrors.Errorf("'checkpoint-files-strict' requires 'checkpoints-repo'")
+		}
+		cfg.StrictCheckpointPersistence = cctx.Bool("checkpoint-files-strict")
+		cfg.StateSnapshotRepo = cctx.String("state-snapshots-repo")
+		if cctx.Bool("fast-sync") {
+			cfg.FastSyncHost = h
+		}
+		cfg.Consensus.CheckpointRandomness = cctx.Bool("checkpoint-randomness")
+		cfg.Consensus.CompressCheckpoints = cctx.Bool("compress-checkpoints")
+		cfg.Consensus.StallTimeout = cctx.Duration("stall-timeout")
+		cfg.Consensus.BatchStoreCap = cctx.Int64("batch-store-cap")
+		cfg.Consensus.DisableMempoolBucketing = cctx.Bool("disable-mempool-bucketing")
+		cfg.Consensus.DisableParamsPresets = cctx.Bool("disable-params-presets")
+		cfg.Consensus.MpoolSelectRetries = cctx.Int("mpool-select-retries")
+		cfg.Consensus.RampUpEpochs = cctx.Int("ramp-up-epochs")
+		cfg.Consensus.ExecutionLag = cctx.Int("execution-lag")
+		cfg.Consensus.MaxCheckpointBlocks = cctx.Int("max-checkpoint-blocks")
+		cfg.Consensus.QuietSelectionErrors = cctx.Bool("quiet-selection-errors")
+		cfg.TxSources, err = txSourcesFromFlags(cctx)
+		if err != nil {
+			return err
+		}
+
+		// Subnet cron jobs and parameters are configured in the repo shared with the daemon,
+		// so both include and accept the same messages.
+		netName, err := nodeApi.StateNetworkName(ctx)
+		if err != nil {
+			return xerrors.Errorf("error getting network name: %w", err)
+		}
+		if err := subnetcron.LoadJobs(string(netName), filepath.Join(cctx.String("repo"), subnetcron.JobsFile)); err != nil {
+			return xerrors.Errorf("error loading subnet cron jobs: %w", err)
+		}
+		if err := subnetparams.Load(string(netName), filepath.Join(cctx.String("repo"), subnetparams.ParamsFile)); err != nil {
+			return xerrors.Errorf("error loading subnet params: %w", err)
+		}
 
 		var mb membership.Reader
 		switch cfg.MembershipSourceValue {
 		case "file":
 			mf := filepath.Join(cctx.String("repo"), cctx.String("membership-file"))
+			if err := bootstrapMembership(ctx, cctx, nodeApi, h, string(netName), mf); err != nil {
+				return err
+			}
 			mb = membership.NewFileMembership(mf)
-		case "onchain":
-			cl := rpc.NewJSONRPCClientWithConfig(cfg.IPCConfig())
-			netName, err := nodeApi.StateNetworkName(ctx)
+			plan, err := membership.LoadMigrationPlan(mf + membership.MigrationFileSuffix)
 			if err != nil {
-				return xerrors.Errorf("error getting network name: %w", err)
+				return err
+			}
+			if plan != nil {
+				sn, err := sdk.NewSubnetIDFromString(string(netName))
+				if err != nil {
+					return err
+				}
+				onchain := membership.NewOnChainMembershipClient(rpc.NewJSONRPCClientWithConfig(cfg.IPCConfig()), sn)
+				mb = mir.NewMigratingMembership(mb, onchain, plan)
+				log.Infof("Migrating to the on-chain membership in epoch %d, with %d fallback epochs", plan.SwitchEpoch, plan.FallbackEpochs)
 			}
+		case "onchain":
+			cl := rpc.NewJSONRPCClientWithConfig(cfg.IPCConfig())
 			sn, err := sdk.NewSubnetIDFromString(string(netName))
 			if err != nil {
 				return err
 			}
 			mb = membership.NewOnChainMembershipClient(cl, sn)
+			if cctx.String("parent-api") != "" {
+				lc, closer, err := parentLightClientFromFlags(ctx, cctx)
+				if err != nil {
+					return xerrors.Errorf("error creating parent light client: %w", err)
+				}
+				defer closer()
+				mb = mir.NewVerifiedMembership(mb, lc, sn)
+				log.Infof("Verifying on-chain membership against the parent from height %d", lc.TrustedHeader().Height)
+			}
 		default:
 			return xerrors.Errorf("membership is currently only supported with file")
 		}
 
-		var netLogger = mir.NewLogger(validatorID.String())
-		netTransport := mirlibp2p.NewTransport(mirlibp2p.DefaultParams(), t.NodeID(validatorID.String()), h, netLogger)
+		// Serve the checkpoints of the validator to the validators that lost them.
+		mir.ServeCheckpoints(h, ds, cfg.CheckpointRepo)
+		if cfg.StateSnapshotRepo != "" {
+			mir.ServeStateSnapshots(h, cfg.StateSnapshotRepo)
+		}
+
+		notifier := &serviceNotifier{}
+		cfg.OnBlock = notifier.OnBlock
+		go notifier.Watchdog(ctx)
+		defer notifier.Stopping()
+
+		// Publish the version and subnet parameters of the validator, so the membership can be checked
+		// before an upgrade and validators with different parameters are detected.
+		go attestation.Run(ctx, nodeApi, validatorID, subnetparams.Hash(string(netName)))
+
+		checkpoints := mir.NewCheckpointNotifier()
+		cfg.OnCheckpoint = checkpoints.Notify
+		msgs := mir.NewMsgTracker()
+		cfg.OnMsgEvent = msgs.Notify
+
+		// The Mir node can be stopped through the admin API, e.g. once the validator left the validator set.
+		mirCtx, stopMir := context.WithCancel(ctx)
+		defer stopMir()
+
+		m := &managerRef{}
+		if err := serveAdminAPI(ctx, cctx.String("repo"), cctx.String("admin-listen"), m, checkpoints, msgs, stopMir,
+			abi.ChainEpoch(cctx.Int64("ready-checkpoint-lag"))); err != nil {
+			return xerrors.Errorf("failed to start the validator admin API: %w", err)
+		}
+
+		// A restarted manager recovers from the latest checkpoint, as if the validator was restarted.
+		// Failures caused by the daemon going away don't count as failures of the subsystem: the
+		// manager is restarted once the daemon is back.
+		return mir.RunSubsystem(mirCtx, "mir", failurePolicy, func(ctx context.Context) error {
+			return mir.RunWithDaemon(ctx, nodeApi, func(ctx context.Context) error {
+				var netLogger = mir.NewLogger(validatorID.String())
+				netTransport := mir.NewDiagnosticTransport(
+					mirlibp2p.NewTransport(transportParams(cfg, mb), t.NodeID(validatorID.String()), h, netLogger), h)
 
-		log.Infow("Starting mining with validator", "validator", validatorID)
-		return mir.Mine(ctx, netTransport, nodeApi, ds, mb, cfg)
+				mgr, err := mir.NewManager(ctx, netTransport, nodeApi, ds, mb, cfg)
+				if err != nil {
+					return xerrors.Errorf("%v failed to create manager: %w", validatorID, err)
+				}
+				m.set(mgr)
+				// The initial checkpoint only applies to the first manager: a restarted manager recovers
+				// from the latest checkpoint, which is ahead of it.
+				cfg.InitialCheckpoint = nil
+
+				log.Infow("Starting mining with validator", "validator", validatorID)
+				return mgr.Serve(ctx)
+			})
+		})
 	},
 }
 
+// transportParams returns the parameters of the transport of the validator, sized for the membership
+// it starts with. The transport keeps the default parameters if the membership can't be read yet.
+func transportParams(cfg *mir.Config, mb membership.Reader) mirlibp2p.Params {
+	if cfg.Consensus.DisableParamsPresets {
+		return mirlibp2p.DefaultParams()
+	}
+	info, err := mb.GetMembershipInfo()
+	if err != nil || info.ValidatorSet == nil {
+		return mirlibp2p.DefaultParams()
+	}
+	return mir.TransportParams(info.ValidatorSet.Size())
+}
+
+func txSourcesFromFlags(cctx *cli.Context) ([]mir.TxSource, error) {
+	var token string
+	if f := cctx.String("tx-feed-token-file"); f != "" {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, xerrors.Errorf("error reading tx feed token: %w", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	var sources []mir.TxSource
+	for _, u := range cctx.StringSlice("tx-feed") {
+		s, err := external.NewHTTPSource(u, token)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
+// fullNodeAPIFromFlags connects to the daemon in 'daemon-api', or to the one of the repo otherwise.
+// The connection to a daemon in 'daemon-api' is re-established with backoff when it is lost.
+func fullNodeAPIFromFlags(ctx context.Context, cctx *cli.Context) (api.FullNode, jsonrpc.ClientCloser, error) {
+	if cctx.String("daemon-api") == "" {
+		return lcli.GetFullNodeAPIV1(cctx)
+	}
+	ainfo := cliutil.ParseApiInfo(cctx.String("daemon-api"))
+	addr, err := ainfo.DialArgs("v1")
+	if err != nil {
+		return nil, nil, err
+	}
+	return client.NewFullNodeRPCV1(ctx, addr, ainfo.AuthHeader(),
+		jsonrpc.WithReconnectBackoff(mir.DaemonRetryMinBackoff, mir.DaemonRetryMaxBackoff))
+}
+
+// parentLightClientFromFlags connects to the full node of the parent and creates a light client
+// trusting the checkpoint of the parent in the file passed by the user.
+func parentLightClientFromFlags(ctx context.Context, cctx *cli.Context) (*mir.ParentLightClient, jsonrpc.ClientCloser, error) {
+	if cctx.String("parent-checkpoint") == "" {
+		return nil, nil, xerrors.Errorf("'parent-checkpoint' is required with 'parent-api'")
+	}
+	b, err := mir.ReadCheckpointFile(cctx.String("parent-checkpoint"))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("error reading parent checkpoint: %w", err)
+	}
+	anchor, err := mir.DeserializeCheckpoint(b)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("error deserializing parent checkpoint: %w", err)
+	}
+
+	ainfo := cliutil.ParseApiInfo(cctx.String("parent-api"))
+	addr, err := ainfo.DialArgs("v1")
+	if err != nil {
+		return nil, nil, err
+	}
+	parentApi, closer, err := client.NewFullNodeRPCV1(ctx, addr, ainfo.AuthHeader())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("error connecting to the parent: %w", err)
+	}
+	lc, err := mir.NewParentLightClient(ctx, parentApi, anchor)
+	if err != nil {
+		closer()
+		return nil, nil, err
+	}
+	return lc, closer, nil
+}
+
 func validatorIDFromFlag(ctx context.Context, cctx *cli.Context, nodeApi api.FullNode) (address.Address, error) {
 	var (
 		addr address.Address
@@ -0,0 +1,146 @@
+package mirvalidator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+const (
+	// daemonizedEnv is set in the environment of the validator process started by --daemonize.
+	daemonizedEnv = "EUDICO_MIR_VALIDATOR_DAEMONIZED"
+	// DaemonLogPath is the default file the output of a daemonized validator is written to.
+	DaemonLogPath = "validator.log"
+)
+
+// daemonize starts the validator in the background, detached from the terminal,
+// with the same command line. The output of the validator is appended to the log file.
+func daemonize(cctx *cli.Context) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return xerrors.Errorf("error getting executable path: %w", err)
+	}
+
+	logPath := cctx.String("daemon-log-file")
+	if logPath == "" {
+		logPath = filepath.Join(cctx.String("repo"), DaemonLogPath)
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return xerrors.Errorf("error opening daemon log file: %w", err)
+	}
+	defer logFile.Close() // nolint
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), daemonizedEnv+"=1")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return xerrors.Errorf("error starting validator in background: %w", err)
+	}
+
+	fmt.Fprintf(cctx.App.Writer, "Mir validator running in background with PID %d, logging to %s\n", cmd.Process.Pid, logPath) // nolint
+	return cmd.Process.Release()
+}
+
+// isDaemonized returns true if this is the process started by daemonize.
+func isDaemonized() bool {
+	return os.Getenv(daemonizedEnv) != ""
+}
+
+// writePidFile writes the PID of the process to the file and returns a function that removes it.
+// It fails if the file belongs to another running process.
+func writePidFile(path string) (func(), error) {
+	if b, err := os.ReadFile(path); err == nil {
+		if pid, err := strconv.Atoi(string(b)); err == nil && pid != os.Getpid() {
+			if p, err := os.FindProcess(pid); err == nil && p.Signal(syscall.Signal(0)) == nil {
+				return nil, xerrors.Errorf("pidfile %s belongs to running process %d", path, pid)
+			}
+		}
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return nil, xerrors.Errorf("error writing pidfile: %w", err)
+	}
+	return func() {
+		if err := os.Remove(path); err != nil {
+			log.Warnf("failed to remove pidfile %s: %v", path, err)
+		}
+	}, nil
+}
+
+// serviceNotifier reports the state of the validator to systemd (sd_notify).
+//
+// Readiness is notified once the validator has created its first block, i.e. when
+// the Mir node is participating in the ordering. If the watchdog is enabled, it is
+// pinged while the validator is starting, and afterwards only while the validator
+// keeps creating blocks, so a stalled validator is restarted by systemd.
+// All notifications are no-ops if the validator is not run by systemd.
+type serviceNotifier struct {
+	lk        sync.Mutex
+	ready     bool
+	lastBlock time.Time
+}
+
+// OnBlock is called with the height of every block created by the validator.
+func (n *serviceNotifier) OnBlock(height abi.ChainEpoch) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.lastBlock = time.Now()
+	if n.ready {
+		return
+	}
+	n.ready = true
+	if _, err := daemon.SdNotify(false, daemon.SdNotifyReady); err != nil {
+		log.Warnf("failed to notify readiness: %v", err)
+	}
+	log.Infof("Mir validator participating, created block %d", height)
+}
+
+// Watchdog pings the systemd watchdog until the context is closed.
+func (n *serviceNotifier) Watchdog(ctx context.Context) {
+	interval, err := daemon.SdWatchdogEnabled(false)
+	if err != nil || interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.lk.Lock()
+			alive := !n.ready || time.Since(n.lastBlock) < interval
+			n.lk.Unlock()
+			if !alive {
+				log.Warnf("no block created by the validator in %s, not pinging the watchdog", interval)
+				continue
+			}
+			if _, err := daemon.SdNotify(false, daemon.SdNotifyWatchdog); err != nil {
+				log.Warnf("failed to ping the watchdog: %v", err)
+			}
+		}
+	}
+}
+
+// Stopping notifies that the validator is shutting down.
+func (n *serviceNotifier) Stopping() {
+	if _, err := daemon.SdNotify(false, daemon.SdNotifyStopping); err != nil {
+		log.Warnf("failed to notify stopping: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package mirvalidator
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var shutdownCmd = &cli.Command{
+	Name:  "shutdown",
+	Usage: "Vote for freezing the subnet at a height",
+	Description: `The vote is sent to the rest of validators like a configuration request.
+Once a strong quorum of the validators vote for the same height, no blocks are created
+above it and the next checkpoint is the final checkpoint of the subnet. The vote can be
+cancelled with the reconfiguration cancel command until it is ordered.`,
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:     "height",
+			Usage:    "height of the last block of the subnet",
+			Required: true,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		txNo, err := c.RequestShutdown(ctx, abi.ChainEpoch(cctx.Int64("height")))
+		if err != nil {
+			return fmt.Errorf("error requesting shutdown: %w", err)
+		}
+
+		log.Infof("Shutdown at height %d requested in tx %d", cctx.Int64("height"), txNo)
+		return nil
+	},
+}
@@ -0,0 +1,117 @@
+package mirvalidator
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	mirkv "github.com/filecoin-project/lotus/chain/consensus/mir/db/kv"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var StateCmd = &cli.Command{
+	Name:  "state",
+	Usage: "Inspect the Mir state persisted by a validator",
+	Subcommands: []*cli.Command{
+		exportMembershipHistoryCmd,
+	},
+}
+
+var exportMembershipHistoryCmd = &cli.Command{
+	Name:  "export-membership-history",
+	Usage: "Export the validator set of every epoch from the checkpoints persisted by the validator",
+	Flags: []cli.Flag{
+		&cli.Uint64Flag{
+			Name:  "from",
+			Usage: "first Mir epoch to export",
+		},
+		&cli.Uint64Flag{
+			Name:  "to",
+			Usage: "last Mir epoch to export (defaults to the epoch of the latest checkpoint)",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output format: json, csv",
+			Value: "json",
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "file the history is written to (defaults to stdout)",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+		if cctx.IsSet("to") && cctx.Uint64("to") < cctx.Uint64("from") {
+			return xerrors.Errorf("invalid epoch range: %d-%d", cctx.Uint64("from"), cctx.Uint64("to"))
+		}
+
+		ds, err := mirkv.NewLevelDB(filepath.Join(repoFlag, LevelDSPath), true)
+		if err != nil {
+			return xerrors.Errorf("error initializing mir datastore: %w", err)
+		}
+		defer ds.Close() // nolint
+
+		to := uint64(0)
+		if cctx.IsSet("to") {
+			to = cctx.Uint64("to")
+		}
+		history, err := mir.MembershipHistory(ctx, ds, cctx.Uint64("from"), to)
+		if err != nil {
+			return err
+		}
+
+		w := cctx.App.Writer
+		if p := cctx.String("output"); p != "" {
+			f, err := os.Create(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close() // nolint
+			w = f
+		}
+
+		switch cctx.String("format") {
+		case "json":
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(history)
+		case "csv":
+			return writeMembershipHistoryCSV(w, history)
+		default:
+			return xerrors.Errorf("unknown format: %s", cctx.String("format"))
+		}
+	},
+}
+
+func writeMembershipHistoryCSV(w io.Writer, history []mir.MembershipHistoryEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"epoch", "height", "validator", "addr", "weight"}); err != nil {
+		return err
+	}
+	for _, e := range history {
+		for _, v := range e.Validators {
+			row := []string{strconv.FormatUint(e.Epoch, 10), e.Height.String(), v.ID, v.Addr, v.Weight}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("error writing csv: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package mirvalidator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var statusCmd = &cli.Command{
+	Name:  "status",
+	Usage: "Show the state, epoch, membership and latest checkpoint of the running validator",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the status as JSON",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+		repoFlag := cctx.String("repo")
+
+		// check if validator has been initialized.
+		if err := initCheck(repoFlag); err != nil {
+			return err
+		}
+
+		c, closer, err := newAdminClient(ctx, repoFlag)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		s, err := c.MirStatus(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting status: %w", err)
+		}
+		if cctx.Bool("json") {
+			b, err := json.MarshalIndent(s, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cctx.App.Writer, string(b))
+			return nil
+		}
+
+		w := cctx.App.Writer
+		_, _ = fmt.Fprintf(w, "Validator:         %s\n", s.Validator)
+		_, _ = fmt.Fprintf(w, "State:             %s\n", s.State)
+		_, _ = fmt.Fprintf(w, "Epoch:             %d\n", s.Epoch)
+		if !s.LastBatch.IsZero() {
+			_, _ = fmt.Fprintf(w, "Last batch:        %s ago\n", time.Since(s.LastBatch).Truncate(time.Second))
+		}
+		_, _ = fmt.Fprintf(w, "Checkpoint height: %d\n", s.CheckpointHeight)
+		_, _ = fmt.Fprintf(w, "Membership:        %s\n", strings.Join(s.Membership, ", "))
+		return nil
+	},
+}
@@ -13,10 +13,12 @@ import (
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/host"
+	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
 	"github.com/multiformats/go-multiaddr"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
 	lcli "github.com/filecoin-project/lotus/cli"
 )
 
@@ -177,7 +179,9 @@ func newLibP2PHost(dir string, tcpPort, quicPort int) (host.Host, error) {
 	)
 }
 
-func getLibP2PHost(dir string) (host.Host, error) {
+// getLibP2PHost returns the libp2p host of the validator, with resource limits for memberships of
+// up to maxValidators validators, or the default limits of libp2p if it is zero.
+func getLibP2PHost(dir string, maxValidators int) (host.Host, error) {
 	pk, err := lp2pID(dir)
 	if err != nil {
 		return nil, err
@@ -202,11 +206,19 @@ func getLibP2PHost(dir string) (host.Host, error) {
 	if err != nil {
 		return nil, err
 	}
-	return libp2p.New(
+	opts := []libp2p.Option{
 		libp2p.Identity(pk),
 		libp2p.DefaultTransports,
 		libp2p.ListenAddrs(addrs...),
-	)
+	}
+	if maxValidators > 0 {
+		rm, err := rcmgr.NewResourceManager(rcmgr.NewFixedLimiter(mir.ValidatorHostLimits(maxValidators)))
+		if err != nil {
+			return nil, fmt.Errorf("error creating libp2p resource manager: %w", err)
+		}
+		opts = append(opts, libp2p.ResourceManager(rm))
+	}
+	return libp2p.New(opts...)
 }
 
 func marshalMultiAddrSlice(ma []multiaddr.Multiaddr) ([]byte, error) {
@@ -21,5 +21,19 @@ var ValidatorCmd = &cli.Command{
 		runCmd,
 		cfgCmd,
 		checkCmd,
+		reconfigurationCmd,
+		joinCmd,
+		leaveCmd,
+		migrateMembershipCmd,
+		recoverCmd,
+		netDiagnosticsCmd,
+		contributionsCmd,
+		startupReportCmd,
+		statusCmd,
+		membershipAtCmd,
+		blockByBatchCmd,
+		logsCmd,
+		shutdownCmd,
+		authCmd,
 	},
 }
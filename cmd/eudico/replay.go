@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/mitchellh/go-homedir"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/consensus/filcns"
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/vm"
+	lcli "github.com/filecoin-project/lotus/cli"
+	"github.com/filecoin-project/lotus/eudico-core/global"
+	"github.com/filecoin-project/lotus/journal"
+	"github.com/filecoin-project/lotus/node/repo"
+	"github.com/filecoin-project/lotus/storage/sealer/ffiwrapper"
+)
+
+var replayCmd = &cli.Command{
+	Name:  "replay",
+	Usage: "Re-execute an ordered Mir chain locally and verify its state roots and checkpoints",
+	Description: `Replay re-executes the blocks of a Mir chain from genesis without joining the network,
+and verifies that every state root and checkpoint included in the chain is valid.
+The chain is read either from a chain export (--import-chain) or from the repo of
+a stopped node (--source-repo). The state computed during the replay is kept in memory.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "import-chain",
+			Usage: "replay the chain from a chain export file",
+		},
+		&cli.StringFlag{
+			Name:  "source-repo",
+			Usage: "replay the chain from the chainstore of a stopped node",
+		},
+		&cli.Int64Flag{
+			Name:  "height",
+			Usage: "replay the chain up to this height (defaults to the head of the chain)",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		global.SetConsensusAlgorithm(global.MirConsensus)
+		ctx := lcli.ReqContext(cctx)
+
+		if cctx.IsSet("import-chain") == cctx.IsSet("source-repo") {
+			return xerrors.Errorf("exactly one of --import-chain or --source-repo must be set")
+		}
+
+		var (
+			bs   blockstore.Blockstore
+			mds  datastore.Batching
+			head *types.TipSet
+			cs   *store.ChainStore
+		)
+
+		if cctx.IsSet("import-chain") {
+			fname, err := homedir.Expand(cctx.String("import-chain"))
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(fname)
+			if err != nil {
+				return err
+			}
+			defer f.Close() //nolint:errcheck
+
+			bs = blockstore.NewMemorySync()
+			mds = dssync.MutexWrap(datastore.NewMapDatastore())
+			cs = store.NewChainStore(bs, bs, mds, mir.Weight, journal.NilJournal())
+
+			log.Infof("importing chain from %s...", fname)
+			var r io.Reader = bufio.NewReaderSize(f, 1<<20)
+			if head, err = cs.Import(ctx, r); err != nil {
+				return xerrors.Errorf("importing chain failed: %w", err)
+			}
+		} else {
+			path, err := homedir.Expand(cctx.String("source-repo"))
+			if err != nil {
+				return err
+			}
+			r, err := repo.NewFS(path)
+			if err != nil {
+				return err
+			}
+			lr, err := r.Lock(repo.FullNode)
+			if err != nil {
+				return xerrors.Errorf("error locking source repo (is the node stopped?): %w", err)
+			}
+			defer lr.Close() //nolint:errcheck
+
+			src, err := lr.Blockstore(ctx, repo.UniversalBlockstore)
+			if err != nil {
+				return xerrors.Errorf("failed to open blockstore: %w", err)
+			}
+			srcMds, err := lr.Datastore(ctx, "/metadata")
+			if err != nil {
+				return err
+			}
+			// The state computed while replaying is written to memory and not to the source blockstore,
+			// and the metadata is copied so the chainstore and the state manager never write to the source.
+			bs = blockstore.NewTieredBstore(src, blockstore.NewMemorySync())
+			mds, err = copyDatastore(ctx, srcMds)
+			if err != nil {
+				return xerrors.Errorf("error copying metadata datastore: %w", err)
+			}
+			cs = store.NewChainStore(bs, bs, mds, mir.Weight, journal.NilJournal())
+			if err := cs.Load(ctx); err != nil {
+				return xerrors.Errorf("error loading chainstore: %w", err)
+			}
+			head = cs.GetHeaviestTipSet()
+		}
+		defer cs.Close() //nolint:errcheck
+
+		if h := cctx.Int64("height"); h > 0 {
+			if abi.ChainEpoch(h) > head.Height() {
+				return xerrors.Errorf("height %d is over the chain head %d", h, head.Height())
+			}
+			ts, err := cs.GetTipsetByHeight(ctx, abi.ChainEpoch(h), head, true)
+			if err != nil {
+				return err
+			}
+			head = ts
+		}
+
+		sm, err := stmgr.NewStateManager(cs, mir.NewTipSetExecutor(), vm.Syscalls(ffiwrapper.ProofVerifier), filcns.DefaultUpgradeSchedule(), nil, mds)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("replaying chain up to height %d", head.Height())
+		report, err := mir.Replay(ctx, cs, sm, head)
+		if err != nil {
+			return xerrors.Errorf("replay failed: %w", err)
+		}
+
+		afmt := lcli.NewAppFmt(cctx.App)
+		afmt.Printf("Replayed %d blocks up to height %d, verified %d checkpoints\n", report.Executed, report.Height, report.Checkpoints)
+		return nil
+	},
+}
+
+// copyDatastore copies the entries of a datastore into memory.
+func copyDatastore(ctx context.Context, src datastore.Read) (datastore.Batching, error) {
+	dst := dssync.MutexWrap(datastore.NewMapDatastore())
+	res, err := src.Query(ctx, query.Query{})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close() //nolint:errcheck
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		if err := dst.Put(ctx, datastore.NewKey(r.Key), r.Value); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/filecoin-project/go-jsonrpc"
 	"github.com/filecoin-project/go-paramfetch"
+	"github.com/filecoin-project/mir/pkg/checkpoint"
 
 	lapi "github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/build"
@@ -112,6 +113,10 @@ var DaemonCmd = &cli.Command{
 			Name:  "import-snapshot",
 			Usage: "import chain state from a given chain export file or url",
 		},
+		&cli.StringFlag{
+			Name:  "import-checkpoint",
+			Usage: "trusted Mir checkpoint file the checkpoints of the imported chain are verified against",
+		},
 		&cli.BoolFlag{
 			Name:  "halt-after-import",
 			Usage: "halt the process after importing chain from file",
@@ -273,7 +278,7 @@ var DaemonCmd = &cli.Command{
 				issnapshot = true
 			}
 
-			if err := ImportChain(ctx, r, chainfile, issnapshot); err != nil {
+			if err := ImportChain(ctx, r, chainfile, issnapshot, cctx.String("import-checkpoint")); err != nil {
 				return err
 			}
 			if cctx.Bool("halt-after-import") {
@@ -328,9 +333,10 @@ var DaemonCmd = &cli.Command{
 			genesis,
 			liteModeDeps,
 
+			node.Override(new(mir.LearnerConfig), mir.LearnerConfig{}),
 			node.Override(new(consensus.Consensus), mir.NewConsensus),
 			node.Override(new(store.WeightFunc), mir.Weight),
-			node.Override(new(stmgr.Executor), consensus.NewTipSetExecutor(mir.RewardFunc)),
+			node.Override(new(stmgr.Executor), mir.NewTipSetExecutor()),
 
 			node.ApplyIf(func(s *node.Settings) bool { return cctx.IsSet("api") },
 				node.Override(node.SetApiEndpointKey, func(lr repo.LockedRepo) error {
@@ -432,7 +438,22 @@ func importKey(ctx context.Context, api lapi.FullNode, f string) error {
 	return nil
 }
 
-func ImportChain(ctx context.Context, r repo.Repo, fname string, snapshot bool) (err error) {
+// ImportChain imports a chain export or snapshot of a Mir chain. The imported head is only accepted
+// up to the last block committed by a checkpoint with a valid certificate, verified against the
+// checkpoint at anchorPath if given.
+func ImportChain(ctx context.Context, r repo.Repo, fname string, snapshot bool, anchorPath string) (err error) {
+	var anchor *checkpoint.StableCheckpoint
+	if anchorPath != "" {
+		b, err := os.ReadFile(anchorPath)
+		if err != nil {
+			return xerrors.Errorf("reading checkpoint: %w", err)
+		}
+		anchor = &checkpoint.StableCheckpoint{}
+		if err := anchor.Deserialize(b); err != nil {
+			return xerrors.Errorf("deserializing checkpoint: %w", err)
+		}
+	}
+
 	var rd io.Reader
 	var l int64
 	if strings.HasPrefix(fname, "http://") || strings.HasPrefix(fname, "https://") {
@@ -529,6 +550,16 @@ func ImportChain(ctx context.Context, r repo.Repo, fname string, snapshot bool)
 		return xerrors.Errorf("importing chain failed: %w", err)
 	}
 
+	log.Infof("verifying checkpoints of imported chain...")
+	covered, err := mir.VerifyImportedChain(ctx, cst, ts, anchor)
+	if err != nil {
+		return xerrors.Errorf("checkpoint verification failed: %w", err)
+	}
+	if covered.Height() < ts.Height() {
+		log.Warnf("dropping imported blocks after height %d not committed by a checkpoint", covered.Height())
+		ts = covered
+	}
+
 	if err := cst.FlushValidationCache(ctx); err != nil {
 		return xerrors.Errorf("flushing validation cache failed: %w", err)
 	}
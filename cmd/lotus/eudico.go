@@ -156,7 +156,7 @@ func eudicoDaemonAction(cctx *cli.Context) error {
 			issnapshot = true
 		}
 
-		if err := ImportChain(ctx, r, chainfile, issnapshot); err != nil {
+		if err := ImportChain(ctx, r, chainfile, issnapshot, cctx.String("import-checkpoint")); err != nil {
 			return err
 		}
 		if cctx.Bool("halt-after-import") {
@@ -218,7 +218,7 @@ func eudicoDaemonAction(cctx *cli.Context) error {
 		fxmodules.Repository(lockedRepo, cfg),
 		fxmodules.Blockstore(cfg),
 		fxmodules.Consensus(global.MirConsensus),
-		fxmodules.RpcServer(cctx, r, lockedRepo, cfg),
+		fxmodules.RpcServer(cctx, r, lockedRepo, cfg, false),
 		// misc providers
 		fx.Supply(isBootstrapper),
 		fx.Supply(dtypes.ShutdownChan(shutdownChan)),
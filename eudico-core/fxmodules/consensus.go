@@ -1,7 +1,10 @@
 package fxmodules
 
 import (
+	"errors"
+
 	"go.uber.org/fx"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/lotus/chain/consensus"
 	"github.com/filecoin-project/lotus/chain/consensus/filcns"
@@ -12,6 +15,9 @@ import (
 	"github.com/filecoin-project/lotus/eudico-core/global"
 )
 
+// ErrUnsupportedConsensus is the error the node fails to start with if the consensus algorithm isn't supported.
+var ErrUnsupportedConsensus = errors.New("unsupported consensus algorithm")
+
 func Consensus(algorithm global.ConsensusAlgorithm) fx.Option {
 	module := fxCase(algorithm,
 		map[global.ConsensusAlgorithm]fx.Option{
@@ -20,7 +26,7 @@ func Consensus(algorithm global.ConsensusAlgorithm) fx.Option {
 			global.TSPoWConsensus:    tspowModule,
 		})
 	if module == nil {
-		panic("Unsupported consensus algorithm")
+		return fx.Error(xerrors.Errorf("%w: %v", ErrUnsupportedConsensus, algorithm))
 	}
 	global.SetConsensusAlgorithm(algorithm)
 	return module
@@ -33,9 +39,10 @@ var filecoinExpectedConsensusModule = fx.Module("filecoinExpectedConsensus",
 )
 
 var mirConsensusModule = fx.Module("mirConsensus",
+	fx.Supply(mir.LearnerConfig{}),
 	fx.Provide(fx.Annotate(mir.NewConsensus, fx.As(new(consensus.Consensus)))),
 	fx.Supply(store.WeightFunc(mir.Weight)),
-	fx.Supply(fx.Annotate(consensus.NewTipSetExecutor(mir.RewardFunc), fx.As(new(stmgr.Executor)))),
+	fx.Supply(fx.Annotate(mir.NewTipSetExecutor(), fx.As(new(stmgr.Executor)))),
 )
 
 var tspowModule = fx.Module("tspowModule",
@@ -0,0 +1,15 @@
+package fxmodules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+
+	"github.com/filecoin-project/lotus/eudico-core/global"
+)
+
+func TestUnsupportedConsensus(t *testing.T) {
+	app := fx.New(Consensus(global.ConsensusAlgorithm(-1)), fx.NopLogger)
+	require.ErrorIs(t, app.Err(), ErrUnsupportedConsensus)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/pool/encrypted"
 	"github.com/filecoin-project/lotus/chain/exchange"
 	"github.com/filecoin-project/lotus/chain/market"
 	"github.com/filecoin-project/lotus/chain/messagepool"
@@ -137,6 +138,8 @@ func Fullnode(isBootstrap bool, isLite bool, fevmCfg config.FevmConfig) fx.Optio
 			storageadapter.NewClientNodeAdapter,
 			retrievaladapter.NewAPIBlockstoreAdapter,
 			full.NewGasPriceCache,
+			encrypted.New,
+			modules.MirAttestations,
 		),
 
 		// Defaults
@@ -18,6 +18,8 @@ func Invokes(cfg *config.FullNode, isBootstrap bool, isMirValidator bool) fx.Opt
 			lp2p.PstoreAddSelfKeys,                                 // 3 libp2p
 			lp2p.StartListening(cfg.Common.Libp2p.ListenAddresses), // 4 common config
 			modules.DoSetGenesis,                                   // 6
+			modules.RegisterSubnetCronJobs,                         // 6 subnet cron
+			modules.RegisterSubnetParams,                           // 6 subnet params
 			modules.RunHello,                                       // 7
 			modules.RunChainExchange,                               // 8
 			modules.HandleIncomingMessages,                         // 12
@@ -0,0 +1,106 @@
+package fxmodules
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"go.uber.org/fx"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-jsonrpc"
+
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	"github.com/filecoin-project/lotus/node"
+	"github.com/filecoin-project/lotus/node/config"
+	"github.com/filecoin-project/lotus/node/impl"
+	"github.com/filecoin-project/lotus/node/impl/full"
+	"github.com/filecoin-project/lotus/node/modules"
+	"github.com/filecoin-project/lotus/node/modules/lp2p"
+)
+
+// RPCProviderProfile is the daemon profile used to deploy read-only RPC nodes for a subnet.
+const RPCProviderProfile = "rpc-provider"
+
+// RPCProviderConfig adapts the node configuration to the RPC provider profile.
+// RPC providers always serve the Eth JSON-RPC API.
+func RPCProviderConfig(cfg *config.FullNode) *config.FullNode {
+	cfg.Fevm.EnableEthRPC = true
+	return cfg
+}
+
+// RPCProviderInvokes returns the invokes for a node deployed with the RPC provider profile.
+//
+// The node keeps the chainstore in sync as a learner (it receives blocks from the validators
+// through pubsub) and serves the chain and Eth APIs, but it doesn't subscribe to the messages
+// topic, relay indexer messages or run the markets and payment channel services. This keeps
+// the attack surface and the resource usage of RPC farms to a minimum.
+func RPCProviderInvokes(cfg *config.FullNode) fx.Option {
+	return fx.Module("rpcProviderInvokes",
+		fx.Invoke(
+			modules.MemoryWatchdog,
+			modules.CheckFdLimit(build.DefaultFDLimit),
+			lp2p.PstoreAddSelfKeys,
+			lp2p.StartListening(cfg.Common.Libp2p.ListenAddresses),
+			modules.DoSetGenesis,
+			modules.RegisterSubnetCronJobs,
+			modules.RegisterSubnetParams,
+			modules.RunHello,
+			modules.RunChainExchange,
+			modules.HandleIncomingBlocks,
+		),
+		fxOptional(cfg.Fevm.EnableEthRPC, fx.Invoke(modules.EnableStoringEvents)),
+	)
+}
+
+// ErrReadOnlyNode is returned by the methods that publish messages in nodes with the RPC provider profile.
+var ErrReadOnlyNode = xerrors.New("method not available in read-only RPC provider nodes")
+
+// readOnlyEthModule disables the Eth methods that publish messages, as they only require the read permission.
+type readOnlyEthModule struct {
+	full.EthModuleAPI
+}
+
+func (readOnlyEthModule) EthSendRawTransaction(context.Context, ethtypes.EthBytes) (ethtypes.EthHash, error) {
+	return ethtypes.EmptyEthHash, ErrReadOnlyNode
+}
+
+// newReadOnlyFullNodeHandler returns the API handler of a node with the RPC provider profile.
+//
+// Every request is served with the default read permission, whatever token it carries, so
+// write, sign and admin methods (including MpoolPush and the wallet) are rejected, and the read
+// methods that publish messages are disabled.
+func newReadOnlyFullNodeHandler(api lapi.FullNode, serverOptions []jsonrpc.ServerOption, importedKey *types.KeyInfo) (http.Handler, error) {
+	if importedKey != nil {
+		return nil, xerrors.Errorf("keys can't be imported in read-only RPC provider nodes")
+	}
+	fn, ok := api.(*impl.FullNodeAPI)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected full node API type %T", api)
+	}
+	ro := *fn
+	ro.EthModuleAPI = readOnlyEthModule{EthModuleAPI: fn.EthModuleAPI}
+
+	h, err := node.FullNodeHandler(&ro, true, serverOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return readOnlyHandler(h), nil
+}
+
+// readOnlyHandler drops the auth token of the requests, so they get the default permissions.
+func readOnlyHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.Clone(r.Context())
+		r.Header.Del("Authorization")
+		q := r.URL.Query()
+		q.Del("token")
+		r.URL.RawQuery = q.Encode()
+		// Don't let the auth handler parse a token from the body.
+		r.Form, r.PostForm = url.Values{}, url.Values{}
+		h.ServeHTTP(w, r)
+	})
+}
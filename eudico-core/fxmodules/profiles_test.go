@@ -0,0 +1,64 @@
+package fxmodules
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+
+	"github.com/filecoin-project/go-jsonrpc"
+
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/client"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	"github.com/filecoin-project/lotus/node/impl"
+	"github.com/filecoin-project/lotus/node/impl/full"
+)
+
+func TestRPCProviderHandler(t *testing.T) {
+	fn := &impl.FullNodeAPI{}
+	fn.EthModuleAPI = &full.EthModuleDummy{}
+
+	var h http.Handler
+	app := fxtest.New(t,
+		fx.Provide(func() lapi.FullNode { return fn }),
+		fx.Supply(newServerOptions(0)),
+		fx.Provide(func() *types.KeyInfo { return nil }),
+		fxEitherOr(true, fx.Provide(newReadOnlyFullNodeHandler), fx.Provide(newFullNodeHandler)),
+		fx.Populate(&h),
+	)
+	app.RequireStart()
+	defer app.RequireStop()
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	ctx := context.Background()
+	headers := http.Header{}
+	// Tokens are ignored, even if they would grant admin permissions.
+	headers.Add("Authorization", "Bearer admin-token")
+	api, closer, err := client.NewFullNodeRPCV1(ctx, "ws://"+strings.TrimPrefix(srv.URL, "http://")+"/rpc/v1", headers)
+	require.NoError(t, err)
+	defer closer()
+
+	_, err = api.MpoolPush(ctx, &types.SignedMessage{})
+	require.ErrorContains(t, err, "missing permission")
+
+	_, err = api.EthSendRawTransaction(ctx, ethtypes.EthBytes{})
+	require.ErrorContains(t, err, ErrReadOnlyNode.Error())
+
+	// Read methods are served.
+	_, err = api.EthChainId(ctx)
+	require.ErrorContains(t, err, full.ErrModuleDisabled.Error())
+}
+
+func TestRPCProviderHandlerRejectsImportedKey(t *testing.T) {
+	_, err := newReadOnlyFullNodeHandler(&impl.FullNodeAPI{}, []jsonrpc.ServerOption{}, &types.KeyInfo{})
+	require.Error(t, err)
+}
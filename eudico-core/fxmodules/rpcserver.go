@@ -25,15 +25,15 @@ import (
 	"github.com/filecoin-project/lotus/node/repo"
 )
 
-func RpcServer(cctx *cli.Context, r *repo.FsRepo, lr repo.LockedRepo, cfg *config.FullNode) fx.Option {
+func RpcServer(cctx *cli.Context, r *repo.FsRepo, lr repo.LockedRepo, cfg *config.FullNode, readOnly bool) fx.Option {
 	if cctx.IsSet("api") {
 		cfg.API.ListenAddress = "/ip4/127.0.0.1/tcp/" + cctx.String("api")
 	}
 
 	return fx.Module("startup",
+		fxEitherOr(readOnly, fx.Provide(newReadOnlyFullNodeHandler), fx.Provide(newFullNodeHandler)),
 		fx.Provide(
 			startRPCServer,
-			newFullNodeHandler,
 			func(api impl.FullNodeAPI, lr repo.LockedRepo, e dtypes.APIEndpoint) lapi.FullNode {
 				lr.SetAPIEndpoint(e) // nolint
 				return &api
@@ -23,3 +23,21 @@ func SetConsensusAlgorithm(algorithm ConsensusAlgorithm) {
 func IsConsensusAlgorithm(algorithm ConsensusAlgorithm) bool {
 	return injectedConsensusAlgorithm == algorithm
 }
+
+// ConsensusAlgorithmInUse returns the consensus algorithm injected in the node.
+func ConsensusAlgorithmInUse() ConsensusAlgorithm {
+	return injectedConsensusAlgorithm
+}
+
+func (a ConsensusAlgorithm) String() string {
+	switch a {
+	case ExpectedConsensus:
+		return "filcns"
+	case MirConsensus:
+		return "mir"
+	case TSPoWConsensus:
+		return "tspow"
+	default:
+		return "none"
+	}
+}
@@ -151,6 +151,8 @@ type Ensemble struct {
 		miners   []genesis.Miner
 		accounts []genesis.Actor
 	}
+	// mirHistoryDBs are the databases of the validators that mined the history of the chain.
+	mirHistoryDBs map[string]*TestDB
 }
 
 // NewEnsemble instantiates a new blank Ensemble.
@@ -1140,6 +1142,10 @@ func (n *Ensemble) BeginMirMiningWithTestAndConsensusConfigs(
 	mirConsensusConfig *mir.ConsensusConfig,
 	faultyValidators ...*TestValidator,
 ) {
+	if n.options.mirHistory > 0 && !n.bootstrapped {
+		n.populateMirHistory(ctx, append(validators, faultyValidators...), testConfig, mirConsensusConfig)
+	}
+
 	for i, v := range append(validators, faultyValidators...) {
 		i := i
 		v := v
@@ -1150,6 +1156,9 @@ func (n *Ensemble) BeginMirMiningWithTestAndConsensusConfigs(
 			if ok {
 				tdb = v
 			}
+		} else if hdb, ok := n.mirHistoryDBs[v.mirAddr.String()]; ok {
+			// Restart the validator from the state it mined the history with.
+			tdb = hdb
 		} else {
 			tdb = NewTestDB()
 		}
@@ -1165,8 +1174,13 @@ func (n *Ensemble) BeginMirMiningWithTestAndConsensusConfigs(
 		require.NoError(n.t, err)
 		v.mirValidator = nv
 
+		consensusConfig := mirConsensusConfig
+		if c, ok := testConfig.ConsensusConfigs[v.mirAddr.String()]; ok {
+			consensusConfig = c
+		}
+
 		g.Go(func() error {
-			err = nv.MineBlocks(ctx, mirConsensusConfig)
+			err = nv.MineBlocks(ctx, consensusConfig)
 			if xerrors.Is(mapi.ErrStopped, err) { // nolint
 				return nil
 			}
@@ -27,10 +27,17 @@ type ensembleOpts struct {
 	upgradeSchedule stmgr.UpgradeSchedule
 
 	consensus global.ConsensusAlgorithm
+
+	// mirHistory is the number of blocks mined before the Mir validators of the test start.
+	mirHistory int
+
+	// mirListenAddr is the multiaddr the libp2p hosts of the Mir validators listen on.
+	mirListenAddr string
 }
 
 var DefaultEnsembleOpts = ensembleOpts{
-	pastOffset: 10000000 * time.Second, // time sufficiently in the past to trigger catch-up mining.
+	pastOffset:    10000000 * time.Second, // time sufficiently in the past to trigger catch-up mining.
+	mirListenAddr: "/ip4/0.0.0.0/tcp/0",
 	upgradeSchedule: stmgr.UpgradeSchedule{stmgr.Upgrade{
 		Height:  -1,
 		Network: build.TestNetworkVersion,
@@ -45,6 +52,15 @@ func MirConsensus() EnsembleOpt {
 	}
 }
 
+// MirIPv6 makes the Mir validators of the ensemble listen on the IPv6 loopback only,
+// so their membership holds IPv6 addresses.
+func MirIPv6() EnsembleOpt {
+	return func(opts *ensembleOpts) error {
+		opts.mirListenAddr = "/ip6/::1/tcp/0"
+		return nil
+	}
+}
+
 // MockProofs activates mock proofs for the entire ensemble.
 func MockProofs() EnsembleOpt {
 	return func(opts *ensembleOpts) error {
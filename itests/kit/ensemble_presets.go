@@ -51,7 +51,7 @@ func EnsembleWithMirValidators(t *testing.T, n int, opts ...interface{}) ([]*Tes
 	ens.Start()
 
 	for i := 0; i < n; i++ {
-		validators = append(validators, NewTestValidator(t, nodes[i], *miners[i]))
+		validators = append(validators, NewTestValidatorWithListenAddr(t, nodes[i], *miners[i], ens.options.mirListenAddr))
 	}
 
 	require.Equal(t, n, len(nodes))
@@ -105,7 +105,7 @@ func EnsembleMirNodesWithByzantineTwins(t *testing.T, n int, opts ...interface{}
 	ens.Start()
 
 	for i := 0; i < n; i++ {
-		validators = append(validators, NewTestValidator(t, nodes[i], *miners[i]))
+		validators = append(validators, NewTestValidatorWithListenAddr(t, nodes[i], *miners[i], ens.options.mirListenAddr))
 	}
 
 	for i := 0; i < f; i++ {
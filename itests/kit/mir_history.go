@@ -0,0 +1,103 @@
+package kit
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	mapi "github.com/filecoin-project/mir"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+var (
+	// MirHistoryProposeDelay is the maximum propose delay of the validators while they produce the history.
+	MirHistoryProposeDelay = 50 * time.Millisecond
+	// MirHistoryTimeout bounds the time spent producing the history of the chain.
+	MirHistoryTimeout = 5 * time.Minute
+)
+
+// MirHistory makes the ensemble produce the given number of blocks of history, with messages, before the
+// Mir validators of the test start mining.
+//
+// Mir can only start from genesis or from a checkpoint, so the history is produced by the same validators
+// with a short propose delay. They are then stopped and restarted from their state when the test begins
+// mining, which saves tests of joins at a height, learner sync or checkpoint restores from producing
+// filler blocks at the pace of the test configuration.
+func MirHistory(blocks int) EnsembleOpt {
+	return func(opts *ensembleOpts) error {
+		opts.mirHistory = blocks
+		return nil
+	}
+}
+
+// populateMirHistory mines the history of the chain with the validators and keeps their databases,
+// so they are restarted from them.
+func (n *Ensemble) populateMirHistory(
+	ctx context.Context,
+	validators []*TestValidator,
+	testConfig *MirTestConfig,
+	consensusConfig *mir.ConsensusConfig,
+) {
+	ctx, cancel := context.WithTimeout(ctx, MirHistoryTimeout)
+	defer cancel()
+
+	cfg := *testConfig
+	if cfg.MembershipString == "" {
+		cfg.MembershipString = n.fixedMirMembership(validators...)
+	}
+	hc := mir.DefaultConsensusConfig()
+	if consensusConfig != nil {
+		c := *consensusConfig
+		hc = &c
+	}
+	hc.MaxProposeDelay = MirHistoryProposeDelay
+
+	g, gctx := errgroup.WithContext(ctx)
+	n.mirHistoryDBs = make(map[string]*TestDB, len(validators))
+	for _, v := range validators {
+		tdb := NewTestDB()
+		mv, err := NewMirValidator(n.t, v, tdb, &cfg)
+		require.NoError(n.t, err)
+		v.mirValidator = mv
+		n.mirHistoryDBs[v.mirAddr.String()] = tdb
+
+		g.Go(func() error {
+			err := mv.MineBlocks(gctx, hc)
+			if xerrors.Is(mapi.ErrStopped, err) || gctx.Err() != nil { // nolint
+				return nil
+			}
+			return err
+		})
+	}
+
+	node := validators[0].FullNode
+	from, err := node.WalletDefaultAddress(ctx)
+	require.NoError(n.t, err)
+	height := abi.ChainEpoch(n.options.mirHistory)
+	for {
+		head, err := ChainHeadWithCtx(gctx, node)
+		require.NoError(n.t, err)
+		if head.Height() >= height {
+			break
+		}
+		_, err = node.MpoolPushMessage(gctx, &types.Message{
+			From:  from,
+			To:    from,
+			Value: abi.NewTokenAmount(1),
+		}, nil)
+		require.NoError(n.t, err)
+		require.NoError(n.t, mir.WaitForHeight(gctx, head.Height()+1, node))
+	}
+
+	for _, v := range validators {
+		require.NoError(n.t, mir.WaitForHeight(gctx, height, v.FullNode))
+		v.mirValidator.stop()
+	}
+	require.NoError(n.t, g.Wait())
+}
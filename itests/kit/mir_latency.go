@@ -0,0 +1,136 @@
+package kit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// LatencyReportDirEnv is the environment variable used to specify the directory where
+// message inclusion latency reports are written. Reports are not written if it is not set.
+const LatencyReportDirEnv = "MIR_LATENCY_REPORT_DIR"
+
+// LatencyReport is the machine-readable summary of the message inclusion latencies
+// measured in a test scenario.
+type LatencyReport struct {
+	Scenario string        `json:"scenario"`
+	Samples  int           `json:"samples"`
+	Min      time.Duration `json:"min_ns"`
+	P50      time.Duration `json:"p50_ns"`
+	P95      time.Duration `json:"p95_ns"`
+	P99      time.Duration `json:"p99_ns"`
+	Max      time.Duration `json:"max_ns"`
+}
+
+// LatencyRecorder measures the time between pushing a message to the mempool with MpoolPushMessage
+// and the message being available in the chain of the given nodes (MirNodesWaitForMsg).
+type LatencyRecorder struct {
+	scenario string
+
+	lk      sync.Mutex
+	samples []time.Duration
+}
+
+func NewLatencyRecorder(scenario string) *LatencyRecorder {
+	return &LatencyRecorder{scenario: scenario}
+}
+
+// PushAndWait pushes the message through the sender node and waits until it has been included
+// in the chain of all nodes, recording the latency.
+func (r *LatencyRecorder) PushAndWait(ctx context.Context, sender *TestFullNode, msg *types.Message, nodes ...*TestFullNode) (*types.SignedMessage, error) {
+	start := time.Now()
+	smsg, err := sender.MpoolPushMessage(ctx, msg, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := MirNodesWaitForMsg(ctx, smsg.Cid(), nodes...); err != nil {
+		return nil, err
+	}
+	r.Record(time.Since(start))
+	return smsg, nil
+}
+
+// Record adds a latency sample.
+func (r *LatencyRecorder) Record(d time.Duration) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.samples = append(r.samples, d)
+}
+
+// Report computes the latency summary of the recorded samples.
+func (r *LatencyRecorder) Report() LatencyReport {
+	r.lk.Lock()
+	samples := append([]time.Duration{}, r.samples...)
+	r.lk.Unlock()
+
+	rep := LatencyReport{Scenario: r.scenario, Samples: len(samples)}
+	if len(samples) == 0 {
+		return rep
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	rep.Min = samples[0]
+	rep.P50 = percentile(samples, 50)
+	rep.P95 = percentile(samples, 95)
+	rep.P99 = percentile(samples, 99)
+	rep.Max = samples[len(samples)-1]
+	return rep
+}
+
+// RequireP95 writes the latency report and fails the test if the p95 latency is over the bound.
+func (r *LatencyRecorder) RequireP95(t *testing.T, bound time.Duration) {
+	rep := r.WriteReport(t)
+	require.Greater(t, rep.Samples, 0, "no latency samples recorded for %s", r.scenario)
+	require.LessOrEqualf(t, rep.P95, bound, "p95 message inclusion latency for %s is over the SLO", r.scenario)
+}
+
+// WriteReport logs the latency report and, if LatencyReportDirEnv is set, writes it as JSON to
+// <dir>/<scenario>.json so results can be collected and compared across runs. Characters of the
+// scenario that are not valid in file names, like the "/" of subtests, are replaced by "_".
+func (r *LatencyRecorder) WriteReport(t *testing.T) LatencyReport {
+	rep := r.Report()
+	t.Logf("message inclusion latency for %s: samples=%d p50=%s p95=%s p99=%s max=%s",
+		rep.Scenario, rep.Samples, rep.P50, rep.P95, rep.P99, rep.Max)
+
+	dir := os.Getenv(LatencyReportDirEnv)
+	if dir == "" {
+		return rep
+	}
+	b, err := json.MarshalIndent(rep, "", "  ")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, reportFileName(r.scenario)), b, 0644))
+	return rep
+}
+
+// reportFileName returns the name of the file of the report of a scenario.
+func reportFileName(scenario string) string {
+	name := strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.':
+			return c
+		default:
+			return '_'
+		}
+	}, scenario)
+	return name + ".json"
+}
+
+// percentile returns the nearest-rank percentile of sorted samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,74 @@
+package kit
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+)
+
+// provenanceRecorder records the provenance of the blocks created by a validator.
+type provenanceRecorder struct {
+	lk     sync.Mutex
+	blocks map[abi.ChainEpoch]mir.BlockProvenance
+}
+
+func newProvenanceRecorder() *provenanceRecorder {
+	return &provenanceRecorder{blocks: make(map[abi.ChainEpoch]mir.BlockProvenance)}
+}
+
+func (r *provenanceRecorder) record(p mir.BlockProvenance) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.blocks[p.Height] = p
+}
+
+func (r *provenanceRecorder) get(h abi.ChainEpoch) (mir.BlockProvenance, bool) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	p, ok := r.blocks[h]
+	return p, ok
+}
+
+// BlockProvenance returns the provenance of the block at height h created by the validator.
+func (tv *TestValidator) BlockProvenance(h abi.ChainEpoch) (mir.BlockProvenance, bool) {
+	return tv.mirValidator.provenance.get(h)
+}
+
+// ProposerCounts returns the number of blocks in [from, to] that include transactions of every validator,
+// according to the blocks created by v.
+func ProposerCounts(t *testing.T, v *TestValidator, from, to abi.ChainEpoch) map[string]int {
+	counts := make(map[string]int)
+	for h := from; h <= to; h++ {
+		p, ok := v.BlockProvenance(h)
+		require.True(t, ok, "validator %s didn't create block %d", v.GetMirID(), h)
+		for _, id := range p.Proposers {
+			counts[id]++
+		}
+	}
+	return counts
+}
+
+// RequireBlockProposedBy checks that the block at height h includes transactions proposed by the validator
+// with the given ID, according to the blocks created by every validator.
+func RequireBlockProposedBy(t *testing.T, h abi.ChainEpoch, proposer string, validators ...*TestValidator) {
+	for _, v := range validators {
+		p, ok := v.BlockProvenance(h)
+		require.True(t, ok, "validator %s didn't create block %d", v.GetMirID(), h)
+		require.Contains(t, p.Proposers, proposer, "block %d created by %s", h, v.GetMirID())
+	}
+}
+
+// RequireLeadershipRotation checks that every one of the proposers proposed some of the blocks in [from, to],
+// according to the blocks created by v, so no validator is starved.
+func RequireLeadershipRotation(t *testing.T, v *TestValidator, from, to abi.ChainEpoch, proposers ...*TestValidator) {
+	counts := ProposerCounts(t, v, from, to)
+	for _, p := range proposers {
+		require.NotZero(t, counts[p.GetMirID()], "validator %s proposed none of the blocks in [%d, %d]: %v",
+			p.GetMirID(), from, to, counts)
+	}
+}
@@ -106,12 +106,61 @@ func WaitForMessageWithAvailable(ctx context.Context, n api.FullNode, c cid.Cid,
 
 func MirNodesWaitForMembershipMsg(ctx context.Context, expected *validator.Set, nodes ...*TestFullNode) error {
 	for _, node := range nodes {
-		gw, err := node.IPCReadGatewayState(ctx, genesis.DefaultIPCGatewayAddr, types.TipSetKey{})
+		set, err := node.IPCGetGatewayMembership(ctx, genesis.DefaultIPCGatewayAddr, types.TipSetKey{})
 		if err != nil {
 			return err
 		}
-		if !gw.Validators.Validators.Equal(expected) {
-			return fmt.Errorf("expected %v, got %v", expected, gw.Validators.Validators)
+		if !set.Equal(expected) {
+			return fmt.Errorf("expected %v, got %v", expected, set)
+		}
+	}
+	return nil
+}
+
+// MirNodesCheckMembershipMsgsApplied checks that every SetMembership message in the chain of the nodes
+// set the membership of the gateway to the validator set in the message.
+func MirNodesCheckMembershipMsgsApplied(ctx context.Context, nodes ...*TestFullNode) error {
+	for _, node := range nodes {
+		head, err := node.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+		// The message included at height h is executed in the state of the tipset at height h+1.
+		for h := abi.ChainEpoch(1); h < head.Height(); h++ {
+			ts, err := node.ChainGetTipSetByHeight(ctx, h, head.Key())
+			if err != nil {
+				return err
+			}
+			msgs, err := node.ChainGetBlockMessages(ctx, ts.Cids()[0])
+			if err != nil {
+				return err
+			}
+
+			var expected *validator.Set
+			for _, msg := range msgs.SecpkMessages {
+				if !membership.IsSetMembershipConfigMsg(consensus.DefaultGatewayAddr, &msg.Message) {
+					continue
+				}
+				expected = new(validator.Set)
+				if err := expected.UnmarshalCBOR(bytes.NewReader(msg.Message.Params)); err != nil {
+					return err
+				}
+			}
+			if expected == nil {
+				continue
+			}
+
+			next, err := node.ChainGetTipSetByHeight(ctx, h+1, head.Key())
+			if err != nil {
+				return err
+			}
+			actual, err := node.IPCGetGatewayMembership(ctx, genesis.DefaultIPCGatewayAddr, next.Key())
+			if err != nil {
+				return err
+			}
+			if err := membership.VerifyGatewayMembership(expected, actual); err != nil {
+				return fmt.Errorf("SetMembership message at height %d: %w", h, err)
+			}
 		}
 	}
 	return nil
@@ -200,15 +249,6 @@ func RandomDelay(seconds int) {
 	time.Sleep(time.Duration(rand.Intn(seconds)) * time.Second)
 }
 
-var _ membership.Reader = &fakeMembership{}
-
-type fakeMembership struct {
-}
-
-func (f fakeMembership) GetMembershipInfo() (*membership.Info, error) {
-	return nil, fmt.Errorf("no validators")
-}
-
 func ChainHeadWithCtx(ctx context.Context, api v1api.FullNode) (*types.TipSet, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -0,0 +1,133 @@
+package kit
+
+import (
+	"fmt"
+	"time"
+
+	mirtypes "github.com/filecoin-project/mir/pkg/types"
+)
+
+// unreachable is the latency between nodes with no path between them.
+const unreachable = time.Duration(1<<63 - 1)
+
+// Topology describes how the nodes of an ensemble are interconnected and the latency
+// of the links between them. Nodes are identified by their index in the ensemble,
+// i.e. the i-th full node and the validator running on top of it.
+//
+// Full nodes are only connected to their neighbours, so that blocks and messages are
+// gossiped through the topology. Mir validators are always connected to each other,
+// and the latency between two of them is the latency of the shortest path in the topology.
+type Topology struct {
+	Name string
+	// Link returns whether nodes i and j are neighbours and the latency of the link.
+	Link func(i, j int) (bool, time.Duration)
+}
+
+// FullMeshTopology connects every node to the rest with the same latency.
+func FullMeshTopology(latency time.Duration) *Topology {
+	return &Topology{
+		Name: "full-mesh",
+		Link: func(i, j int) (bool, time.Duration) {
+			return true, latency
+		},
+	}
+}
+
+// TwoRegionsTopology splits the nodes into two regions of the given size. Nodes in the same
+// region are connected with intraLatency, and every node is connected to the nodes
+// of the other region with interLatency.
+func TwoRegionsTopology(regionSize int, intraLatency, interLatency time.Duration) *Topology {
+	return &Topology{
+		Name: fmt.Sprintf("two-regions-%d", regionSize),
+		Link: func(i, j int) (bool, time.Duration) {
+			if (i < regionSize) == (j < regionSize) {
+				return true, intraLatency
+			}
+			return true, interLatency
+		},
+	}
+}
+
+// StarTopology connects every node only to the hub node.
+func StarTopology(hub int, latency time.Duration) *Topology {
+	return &Topology{
+		Name: "star",
+		Link: func(i, j int) (bool, time.Duration) {
+			return i == hub || j == hub, latency
+		},
+	}
+}
+
+// ChainTopology connects every node i to nodes i-1 and i+1.
+func ChainTopology(latency time.Duration) *Topology {
+	return &Topology{
+		Name: "chain",
+		Link: func(i, j int) (bool, time.Duration) {
+			return i-j == 1 || j-i == 1, latency
+		},
+	}
+}
+
+// latencies returns the latency of the shortest path between every pair of the n nodes.
+func (tp *Topology) latencies(n int) [][]time.Duration {
+	d := make([][]time.Duration, n)
+	for i := range d {
+		d[i] = make([]time.Duration, n)
+		for j := range d[i] {
+			if i == j {
+				continue
+			}
+			d[i][j] = unreachable
+			if ok, l := tp.Link(i, j); ok {
+				d[i][j] = l
+			}
+		}
+	}
+
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if d[i][k] == unreachable || d[k][j] == unreachable {
+					continue
+				}
+				if d[i][k]+d[k][j] < d[i][j] {
+					d[i][j] = d[i][k] + d[k][j]
+				}
+			}
+		}
+	}
+	return d
+}
+
+// InterconnectFullNodesWithTopology connects the full nodes that are neighbours in the topology.
+func (n *Ensemble) InterconnectFullNodesWithTopology(tp *Topology) *Ensemble {
+	for i, from := range n.active.fullnodes {
+		for j := i + 1; j < len(n.active.fullnodes); j++ {
+			if ok, _ := tp.Link(i, j); ok {
+				n.Connect(from, n.active.fullnodes[j])
+			}
+		}
+	}
+	return n
+}
+
+// ApplyMirTopology sets the latencies between Mir validators according to the topology.
+// Validators must be mining with the mocked transport.
+func (n *Ensemble) ApplyMirTopology(tp *Topology, validators ...*TestValidator) {
+	d := tp.latencies(len(validators))
+	for i, from := range validators {
+		if from.mirValidator == nil || from.mirValidator.mockedNet == nil {
+			n.t.Fatalf("validator %s is not using the mocked transport", from.mirAddr)
+		}
+		for j, to := range validators {
+			if i == j {
+				continue
+			}
+			if d[i][j] == unreachable {
+				n.t.Fatalf("validators %d and %d are not connected in topology %s", i, j, tp.Name)
+			}
+			from.mirValidator.mockedNet.SetLinkDelay(mirtypes.NodeID(to.mirAddr.String()), d[i][j])
+		}
+	}
+	n.t.Logf(">>> applied topology %s to %d validators", tp.Name, len(validators))
+}
@@ -3,6 +3,9 @@ package kit
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/host"
 
@@ -29,6 +32,7 @@ func NewTransport(params libp2p.Params, ownID t.NodeID, h host.Host, logger logg
 		transportChan:  tr.EventsOut(),
 		controlledChan: make(chan *events.EventList),
 		stop:           make(chan struct{}),
+		links:          make(map[t.NodeID]*delayedLink),
 	}
 }
 
@@ -39,7 +43,10 @@ type MockedTransport struct {
 	logger         logging.Logger
 	transportChan  <-chan *events.EventList
 	controlledChan chan *events.EventList
-	disconnected   bool
+	disconnected   atomic.Bool
+
+	linksLk sync.Mutex
+	links   map[t.NodeID]*delayedLink
 }
 
 func (m *MockedTransport) Start() error {
@@ -52,12 +59,12 @@ func (m *MockedTransport) Disable() {
 	for _, c := range conns {
 		_ = c.Close() // nolint
 	}
-	m.disconnected = true
+	m.disconnected.Store(true)
 }
 
 // Enable enables the transport after calling Disable.
 func (m *MockedTransport) Enable() {
-	m.disconnected = false
+	m.disconnected.Store(false)
 	err := m.Start()
 	if err != nil {
 		panic(err)
@@ -69,11 +76,67 @@ func (m *MockedTransport) Stop() {
 	close(m.stop)
 }
 
+// SetLinkDelay delays all the messages sent to dest by d.
+// Messages to the same destination are delivered in the order they were sent.
+func (m *MockedTransport) SetLinkDelay(dest t.NodeID, d time.Duration) {
+	m.linksLk.Lock()
+	defer m.linksLk.Unlock()
+
+	l, ok := m.links[dest]
+	if !ok {
+		l = &delayedLink{queue: make(chan delayedMessage, 1024)}
+		m.links[dest] = l
+		go m.deliver(dest, l)
+	}
+	l.setDelay(d)
+}
+
 func (m *MockedTransport) Send(dest t.NodeID, msg *messagepb.Message) error {
-	if m.disconnected {
+	if m.disconnected.Load() {
 		return nil // fmt.Errorf("no connection")
 	}
-	return m.transport.Send(dest, msg)
+
+	m.linksLk.Lock()
+	l, ok := m.links[dest]
+	m.linksLk.Unlock()
+	if !ok {
+		return m.transport.Send(dest, msg)
+	}
+	d := l.getDelay()
+	if d == 0 {
+		return m.transport.Send(dest, msg)
+	}
+
+	// Mir must not be blocked by a slow link, so messages that don't fit in the queue are dropped
+	// as they would be by a congested network.
+	select {
+	case l.queue <- delayedMessage{msg: msg, at: time.Now().Add(d)}:
+	default:
+		m.logger.Log(logging.LevelWarn, "Dropped a delayed message: link queue is full", "dest", dest)
+	}
+	return nil
+}
+
+// deliver sends the messages queued for dest once their delay has elapsed.
+func (m *MockedTransport) deliver(dest t.NodeID, l *delayedLink) {
+	for {
+		select {
+		case <-m.stop:
+			return
+		case dm := <-l.queue:
+			select {
+			case <-m.stop:
+				return
+			case <-time.After(time.Until(dm.at)):
+			}
+			if m.disconnected.Load() {
+				continue
+			}
+			if err := m.transport.Send(dest, dm.msg); err != nil {
+				m.logger.Log(logging.LevelWarn, "Failed to send a delayed message", "dest", dest, "err", err)
+			}
+		}
+	}
 }
 
 func (m *MockedTransport) Connect(nodes *trantorpbtypes.Membership) {
@@ -124,7 +187,7 @@ func (m *MockedTransport) EventsOut() <-chan *events.EventList {
 			case <-m.stop:
 				return
 			case msg := <-m.transportChan:
-				if !m.disconnected {
+				if !m.disconnected.Load() {
 					m.controlledChan <- msg
 				}
 			}
@@ -134,3 +197,27 @@ func (m *MockedTransport) EventsOut() <-chan *events.EventList {
 
 	return m.controlledChan
 }
+
+type delayedMessage struct {
+	msg *messagepb.Message
+	at  time.Time
+}
+
+// delayedLink is the queue of messages sent to a destination with a simulated latency.
+type delayedLink struct {
+	lk    sync.Mutex
+	delay time.Duration
+	queue chan delayedMessage
+}
+
+func (l *delayedLink) setDelay(d time.Duration) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	l.delay = d
+}
+
+func (l *delayedLink) getDelay() time.Duration {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	return l.delay
+}
@@ -0,0 +1,100 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// TwinSchedule controls when the twins of byzantine validators equivocate. The twins start proposing
+// once the chain reaches Diverge, and they crash once it reaches Crash, if it isn't zero, so crash
+// and equivocation faults can be combined at specific heights.
+type TwinSchedule struct {
+	Diverge abi.ChainEpoch
+	Crash   abi.ChainEpoch
+}
+
+// BeginMirTwins starts the twins following the schedule, using the height of the chain of observer.
+// The membership is the one of members, which must include the validators the twins are twins of.
+func (n *Ensemble) BeginMirTwins(
+	ctx context.Context,
+	g *errgroup.Group,
+	schedule TwinSchedule,
+	observer *TestFullNode,
+	members []*TestValidator,
+	twins ...*TestValidator,
+) {
+	g.Go(func() error {
+		if err := mir.WaitForHeight(ctx, schedule.Diverge, observer); err != nil {
+			return nil
+		}
+		cfg := DefaultMirTestConfig()
+		cfg.MembershipString = n.fixedMirMembership(members...)
+		n.BeginMirMiningWithConfig(ctx, g, twins, cfg)
+		if schedule.Crash == 0 {
+			return nil
+		}
+
+		started := make([]*MirValidator, len(twins))
+		for i, tw := range twins {
+			started[i] = tw.mirValidator
+		}
+		if err := mir.WaitForHeight(ctx, schedule.Crash, observer); err != nil {
+			return nil
+		}
+		for _, v := range started {
+			v.stop()
+		}
+		return nil
+	})
+}
+
+// CheckSafety checks that the nodes have the same tipset at every height up to the lowest of their heads.
+// Mir blocks are final once they are ordered, so different tipsets at the same height in the chains of
+// honest nodes are a safety violation, whether or not the nodes are still making progress.
+func CheckSafety(ctx context.Context, nodes ...*TestFullNode) error {
+	if len(nodes) < 2 {
+		return nil
+	}
+	heads := make([]*types.TipSet, len(nodes))
+	to := abi.ChainEpoch(-1)
+	for i, n := range nodes {
+		head, err := ChainHeadWithCtx(ctx, n)
+		if err != nil {
+			return err
+		}
+		heads[i] = head
+		if to < 0 || head.Height() < to {
+			to = head.Height()
+		}
+	}
+
+	var conflicts []string
+	for h := abi.ChainEpoch(1); h <= to; h++ {
+		var base *types.TipSet
+		for i, n := range nodes {
+			ts, err := n.ChainGetTipSetByHeight(ctx, h, heads[i].Key())
+			if err != nil {
+				return fmt.Errorf("getting tipset at height %d from node %d: %w", h, i, err)
+			}
+			if base == nil {
+				base = ts
+				continue
+			}
+			if !ts.Equals(base) {
+				conflicts = append(conflicts, fmt.Sprintf("height %d: node 0 has %s, node %d has %s", h, base.Key(), i, ts.Key()))
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("safety violated at %d heights:\n%s", len(conflicts), strings.Join(conflicts, "\n"))
+	}
+	return nil
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/filecoin-project/lotus/chain/consensus/mir"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	mirtesting "github.com/filecoin-project/lotus/chain/consensus/mir/testing"
 )
 
 type MirTestConfig struct {
@@ -29,6 +30,12 @@ type MirTestConfig struct {
 	MembershipFilename string
 	Databases          map[string]*TestDB
 	MockedTransport    bool
+	// MembershipSize sizes the transport of the validators for a membership of that many validators,
+	// as the validator command does for the membership it starts with.
+	MembershipSize int
+	// ConsensusConfigs overrides the consensus config of the validators with the given addresses,
+	// e.g. to test validators with inconsistent parameters.
+	ConsensusConfigs map[string]*mir.ConsensusConfig
 }
 
 func DefaultMirTestConfig() *MirTestConfig {
@@ -63,23 +70,25 @@ type MirValidator struct {
 	membershipString string
 	membership       membership.Reader
 	config           *MirTestConfig
+	provenance       *provenanceRecorder
 }
 
 func NewMirValidator(t *testing.T, miner *TestValidator, db *TestDB, cfg *MirTestConfig) (*MirValidator, error) {
 	v := MirValidator{
-		t:         t,
-		miner:     miner,
-		privKey:   miner.mirPrivKey,
-		host:      miner.mirHost,
-		addr:      miner.mirAddr,
-		multiAddr: miner.mirMultiAddr,
-		db:        db,
-		config:    cfg,
+		t:          t,
+		miner:      miner,
+		privKey:    miner.mirPrivKey,
+		host:       miner.mirHost,
+		addr:       miner.mirAddr,
+		multiAddr:  miner.mirMultiAddr,
+		db:         db,
+		config:     cfg,
+		provenance: newProvenanceRecorder(),
 	}
 
 	switch cfg.MembershipType {
 	case membership.FakeSource:
-		v.membership = fakeMembership{}
+		v.membership = mirtesting.NewMembership(nil)
 	case membership.StringSource:
 		if cfg.MembershipString == "" {
 			return nil, fmt.Errorf("empty membership string")
@@ -100,11 +109,12 @@ func NewMirValidator(t *testing.T, miner *TestValidator, db *TestDB, cfg *MirTes
 	}
 
 	var netLogger = mir.NewLogger(v.addr.String())
+	netParams := mir.TransportParams(cfg.MembershipSize)
 	if cfg.MockedTransport {
-		v.mockedNet = NewTransport(mirlibp2p.DefaultParams(), mirtypes.NodeID(v.addr.String()), v.host, netLogger)
+		v.mockedNet = NewTransport(netParams, mirtypes.NodeID(v.addr.String()), v.host, netLogger)
 		v.net = v.mockedNet
 	} else {
-		v.net = mirlibp2p.NewTransport(mirlibp2p.DefaultParams(), mirtypes.NodeID(v.addr.String()), v.host, netLogger)
+		v.net = mirlibp2p.NewTransport(netParams, mirtypes.NodeID(v.addr.String()), v.host, netLogger)
 	}
 
 	return &v, nil
@@ -116,6 +126,7 @@ func (v *MirValidator) MineBlocks(ctx context.Context, mirConfig *mir.ConsensusC
 			Addr:      v.addr,
 			GroupName: v.t.Name(),
 		},
+		OnBlockProvenance: v.provenance.record,
 	}
 
 	if mirConfig != nil {
@@ -131,7 +142,7 @@ func (v *MirValidator) MineBlocks(ctx context.Context, mirConfig *mir.ConsensusC
 }
 
 func (v *MirValidator) GetRawDB() map[datastore.Key][]byte {
-	return v.db.db
+	return v.db.Entries()
 }
 
 func (v *MirValidator) GetDB() db.DB {
@@ -9,11 +9,13 @@ import (
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/host"
+	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
 	"github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-address"
 
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
 	"github.com/filecoin-project/lotus/chain/consensus/mir/db"
 	"github.com/filecoin-project/lotus/chain/types"
 )
@@ -29,16 +31,25 @@ type TestValidator struct {
 }
 
 func NewTestValidator(t *testing.T, full *TestFullNode, miner TestMiner) *TestValidator {
+	return NewTestValidatorWithListenAddr(t, full, miner, DefaultEnsembleOpts.mirListenAddr)
+}
+
+// NewTestValidatorWithListenAddr creates a validator whose libp2p host listens on listenAddr.
+func NewTestValidatorWithListenAddr(t *testing.T, full *TestFullNode, miner TestMiner, listenAddr string) *TestValidator {
 	addr, err := full.WalletNew(context.Background(), types.KTSecp256k1)
 	require.NoError(t, err)
 
 	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
 	require.NoError(t, err)
 
+	// All the validators of large memberships dial each other at once.
+	rm, err := rcmgr.NewResourceManager(rcmgr.NewFixedLimiter(mir.ValidatorHostLimits(mir.DefaultMaxValidators)))
+	require.NoError(t, err)
 	h, err := libp2p.New(
 		libp2p.Identity(priv),
 		libp2p.DefaultTransports,
-		libp2p.ListenAddrStrings("/ip4/0.0.0.0/tcp/0"),
+		libp2p.ListenAddrStrings(listenAddr),
+		libp2p.ResourceManager(rm),
 	)
 	require.NoError(t, err)
 
@@ -56,7 +67,7 @@ func (tv *TestValidator) GetMirID() string {
 }
 
 func (tv *TestValidator) GetRawDB() map[datastore.Key][]byte {
-	return tv.mirValidator.db.db
+	return tv.mirValidator.db.Entries()
 }
 
 func (tv *TestValidator) GetDB() db.DB {
@@ -8,7 +8,6 @@ package itests
 // and nodes[MirFaultyValidatorNumber:] are honest nodes.
 
 import (
-	"bytes"
 	"context"
 	"encoding/binary"
 	"math"
@@ -30,6 +29,7 @@ import (
 	"github.com/filecoin-project/lotus/api/client"
 	"github.com/filecoin-project/lotus/chain/consensus/mir"
 	mb "github.com/filecoin-project/lotus/chain/consensus/mir/membership"
+	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/itests/kit"
 )
@@ -42,6 +42,9 @@ const (
 	MirLearnersNumber        = MirFaultyValidatorNumber + 1
 	TestedBlockNumber        = 10
 	MaxDelay                 = 15
+
+	MessageLatencySamples      = 20
+	MessageInclusionLatencySLO = 30 * time.Second
 )
 
 func setupMangler(t *testing.T) {
@@ -185,6 +188,9 @@ func TestMirReconfiguration_AddAndRemoveOneValidator(t *testing.T) {
 
 	err = kit.MirNodesWaitForMembershipMsg(ctx, membership, nodes...)
 	require.NoError(t, err)
+
+	err = kit.MirNodesCheckMembershipMsgsApplied(ctx, nodes...)
+	require.NoError(t, err)
 }
 
 // TestMirReconfigurationOnChain_RunSubnet tests that the membership can be received using a stub JSON RPC client.
@@ -338,6 +344,9 @@ func TestMirReconfiguration_MembershipMessagesSent(t *testing.T) {
 	err = kit.MirNodesWaitForMembershipMsg(ctx, membership, nodes...)
 	require.NoError(t, err)
 
+	err = kit.MirNodesCheckMembershipMsgsApplied(ctx, nodes...)
+	require.NoError(t, err)
+
 }
 
 // TestMirReconfiguration_AddOneValidatorWithConfigurationRecovery tests that the reconfiguration mechanism operates normally
@@ -378,18 +387,11 @@ func TestMirReconfiguration_AddOneValidatorWithConfigurationRecovery(t *testing.
 		require.NoError(t, err)
 
 		// -- store fake votes
-		r := mir.VoteRecords{
-			Records: []mir.VoteRecord{
-				{
-					ConfigurationNumber: 0, ValSetHash: "hash", VotedValidators: []mir.VotedValidator{{ID: "id1"}},
-				},
-			},
-		}
-
-		br := new(bytes.Buffer)
-		err = r.MarshalCBOR(br)
+		cm, err := mir.NewConfigurationManager(ctx, db, m.GetMirID())
+		require.NoError(t, err)
+		votes, err := cm.LoadVotes()
 		require.NoError(t, err)
-		err = db.Put(ctx, mir.ConfigurationVotesKey, br.Bytes())
+		err = cm.ApplyVote(votes, 0, "hash", "id1")
 		require.NoError(t, err)
 
 		dbs[m.GetMirID()] = db
@@ -431,19 +433,18 @@ func TestMirReconfiguration_AddOneValidatorWithConfigurationRecovery(t *testing.
 		require.NoError(t, err)
 		require.Equal(t, recoveredRequestNonce, binary.LittleEndian.Uint64(nonce))
 
-		b, err := db.Get(ctx, mir.ConfigurationVotesKey)
+		cm, err := mir.NewConfigurationManager(ctx, db, m.GetMirID())
 		require.NoError(t, err)
-		var r mir.VoteRecords
-		err = r.UnmarshalCBOR(bytes.NewReader(b))
+		votes, err := cm.LoadVotes()
 		require.NoError(t, err)
 
+		r := votes.GetVoteRecords()
 		require.Equal(t, 1, len(r.Records))
 		for _, v := range r.Records {
 			require.Equal(t, uint64(0), v.ConfigurationNumber)
 			require.Equal(t, "hash", v.ValSetHash)
 		}
-		votes := mir.GetConfigurationVotes(r.Records)
-		require.Equal(t, 1, len(votes))
+		require.Equal(t, 1, len(votes.Votes()))
 	}
 
 	var newConfigNumber uint64 = 1
@@ -482,16 +483,14 @@ func TestMirReconfiguration_AddOneValidatorWithConfigurationRecovery(t *testing.
 		require.NoError(t, err)
 		require.Equal(t, uint64(1)+recoveredRequestNonce, binary.LittleEndian.Uint64(nonce))
 
-		b, err := db.Get(ctx, mir.ConfigurationVotesKey)
+		cm, err := mir.NewConfigurationManager(ctx, db, m.GetMirID())
 		require.NoError(t, err)
-		var r mir.VoteRecords
-		err = r.UnmarshalCBOR(bytes.NewReader(b))
+		votes, err := cm.LoadVotes()
 		require.NoError(t, err)
-		for _, v := range r.Records {
+		for _, v := range votes.GetVoteRecords().Records {
 			require.Equal(t, newConfigNumber, v.ConfigurationNumber)
 		}
-		votes := mir.GetConfigurationVotes(r.Records)
-		require.Greater(t, MirTotalValidatorNumber, len(votes))
+		require.Greater(t, MirTotalValidatorNumber, len(votes.Votes()))
 	}
 }
 
@@ -707,6 +706,72 @@ func TestMirReconfiguration_AddValidatorsOneByOne(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// TestMirReconfiguration_LargeMembership tests that a membership of more than a hundred validators, with
+// the parameters of the validators scaled to its size, orders blocks and converges after validators are added.
+// The test is scaled down in time: the validators propose fast, and the chain only advances long enough
+// for the new membership to take over.
+func TestMirReconfiguration_LargeMembership(t *testing.T) {
+	initialValidatorNumber := 100
+	addedValidatorNumber := 4
+
+	membershipFileName := kit.TempFileName("membership")
+	t.Cleanup(func() {
+		err := os.Remove(membershipFileName)
+		require.NoError(t, err)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g, ctx := errgroup.WithContext(ctx)
+
+	defer func() {
+		t.Logf("[*] defer: cancelling %s context", t.Name())
+		cancel()
+		err := g.Wait()
+		require.NoError(t, err)
+		t.Logf("[*] defer: system %s stopped", t.Name())
+	}()
+
+	nodes, validators, ens := kit.EnsembleWithMirValidators(t, initialValidatorNumber+addedValidatorNumber)
+	ens.SaveValidatorSetToFile(0, membershipFileName, validators[:initialValidatorNumber]...)
+
+	testConfig := &kit.MirTestConfig{
+		MembershipType:     mb.FileSource,
+		MembershipFileName: membershipFileName,
+		MockedTransport:    true,
+		MembershipSize:     initialValidatorNumber + addedValidatorNumber,
+	}
+	consensusConfig := kit.DefaultConsensusTestConfig()
+	consensusConfig.MaxProposeDelay = 500 * time.Millisecond
+	// Every validator leads a segment, so the epochs are as long as the membership.
+	epochLength := consensusConfig.SegmentLength * initialValidatorNumber
+
+	ens.InterconnectFullNodes().BeginMirMiningWithTestAndConsensusConfigs(ctx, g, validators[:initialValidatorNumber],
+		testConfig, consensusConfig)
+
+	t.Log(">>> initial advancing chain")
+	err := kit.AdvanceChain(ctx, epochLength, nodes[:initialValidatorNumber]...)
+	require.NoError(t, err)
+	t.Log(">>> initial check")
+	err = kit.CheckNodesInSync(ctx, 0, nodes[0], nodes[1:initialValidatorNumber]...)
+	require.NoError(t, err)
+
+	t.Log(">>> new validators have been added to the membership")
+	ens.SaveValidatorSetToFile(1, membershipFileName, validators...)
+	membership, err := validator.NewValidatorSetFromFile(membershipFileName)
+	require.NoError(t, err)
+	require.Equal(t, initialValidatorNumber+addedValidatorNumber, membership.Size())
+	ens.InterconnectFullNodes().BeginMirMiningWithTestAndConsensusConfigs(ctx, g, validators[initialValidatorNumber:],
+		testConfig, consensusConfig)
+
+	// The new membership takes over ConfigOffset epochs after the epoch it is voted in.
+	t.Log(">>> final advancing chain")
+	err = kit.AdvanceChain(ctx, (consensusConfig.ConfigOffset+2)*epochLength, nodes...)
+	require.NoError(t, err)
+	t.Log(">>> final check")
+	err = kit.CheckNodesInSync(ctx, 0, nodes[0], nodes[1:]...)
+	require.NoError(t, err)
+}
+
 // TestMirReconfiguration_NewNodeFailsToJoin tests that the reconfiguration mechanism operates normally
 // if a new validator cannot join the network.
 // In this test we don't stop the faulty validator explicitly, instead, we don't spawn it.
@@ -908,6 +973,37 @@ func TestMirSmoke_3NodesMine(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// TestMirSmoke_IPv6 tests that validators reachable over IPv6 only mine.
+func TestMirSmoke_IPv6(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g, ctx := errgroup.WithContext(ctx)
+
+	defer func() {
+		t.Logf("[*] defer: cancelling %s context", t.Name())
+		cancel()
+		err := g.Wait()
+		require.NoError(t, err)
+		t.Logf("[*] defer: system %s stopped", t.Name())
+	}()
+
+	nodes, validators, ens := kit.EnsembleWithMirValidators(t, 3, kit.MirIPv6())
+
+	cfg := kit.DefaultMirTestConfig()
+	cfg.MembershipString = ens.FixedMirMembershipWithWeights(kit.DefaultTestValidatorWeight, validators...)
+	require.Contains(t, cfg.MembershipString, "/ip6/::1/")
+	require.NotContains(t, cfg.MembershipString, "/ip4/")
+
+	ens.InterconnectFullNodes().BeginMirMiningWithTestAndConsensusConfigs(ctx, g, validators,
+		cfg,
+		kit.DefaultConsensusTestConfig(),
+	)
+
+	err := kit.AdvanceChain(ctx, 30, nodes...)
+	require.NoError(t, err)
+	err = kit.CheckNodesInSync(ctx, 0, nodes[0], nodes[1:]...)
+	require.NoError(t, err)
+}
+
 // TestMirSmoke_MembershipWithZeroWeights tests that nodes with zero weights do not work.
 // The membership with 0 weights is considered as incorrect.
 func TestMirSmoke_MembershipWithZeroWeights(t *testing.T) {
@@ -1005,6 +1101,38 @@ func TestMirWithMangler_WhenLearnersJoin(t *testing.T) {
 	TestMirBasic_WhenLearnersJoin(t)
 }
 
+// TestMirBasic_LearnersSyncHistory tests that learners joining a subnet sync the history
+// mined before the validators of the test started.
+func TestMirBasic_LearnersSyncHistory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g, ctx := errgroup.WithContext(ctx)
+
+	defer func() {
+		t.Logf("[*] defer: cancelling %s context", t.Name())
+		cancel()
+		err := g.Wait()
+		require.NoError(t, err)
+		t.Logf("[*] defer: system %s stopped", t.Name())
+	}()
+
+	const historyBlocks = 5 * TestedBlockNumber
+
+	nodes, validators, ens := kit.EnsembleWithMirValidators(t, MirTotalValidatorNumber, kit.MirHistory(historyBlocks))
+	ens.InterconnectFullNodes().BeginMirMining(ctx, g, validators...)
+
+	head, err := nodes[0].ChainHead(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, head.Height(), abi.ChainEpoch(historyBlocks))
+
+	var learner kit.TestFullNode
+	ens.FullNode(&learner, kit.LearnerNode()).Start().InterconnectFullNodes()
+
+	err = kit.AdvanceChain(ctx, TestedBlockNumber, append(nodes, &learner)...)
+	require.NoError(t, err)
+	err = kit.CheckNodesInSync(ctx, 0, nodes[0], append(nodes[1:], &learner)...)
+	require.NoError(t, err)
+}
+
 // TestMirSmoke_GenesisBlocksOfValidatorsAndLearners tests that genesis for validators and learners are correct.
 func TestMirSmoke_GenesisBlocksOfValidatorsAndLearners(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -1229,6 +1357,127 @@ func TestMirBasic_AllNodesMiningWithMessaging(t *testing.T) {
 	}
 }
 
+// TestMirBasic_MessageInclusionLatency checks that the p95 latency between pushing a message
+// to the mempool and its inclusion in the chain of all the nodes is within the SLO.
+func TestMirBasic_MessageInclusionLatency(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g, ctx := errgroup.WithContext(ctx)
+
+	defer func() {
+		t.Logf("[*] defer: cancelling %s context", t.Name())
+		cancel()
+		err := g.Wait()
+		require.NoError(t, err)
+		t.Logf("[*] defer: system %s stopped", t.Name())
+	}()
+
+	nodes, validators, ens := kit.EnsembleWithMirValidators(t, MirTotalValidatorNumber)
+	ens.InterconnectFullNodes().BeginMirMining(ctx, g, validators...)
+
+	err := kit.AdvanceChain(ctx, TestedBlockNumber, nodes...)
+	require.NoError(t, err)
+
+	recorder := kit.NewLatencyRecorder(t.Name())
+	for i := 0; i < MessageLatencySamples; i++ {
+		j := i % len(nodes)
+		src, err := nodes[j].WalletDefaultAddress(ctx)
+		require.NoError(t, err)
+
+		dst, err := nodes[(j+1)%len(nodes)].WalletDefaultAddress(ctx)
+		require.NoError(t, err)
+
+		_, err = recorder.PushAndWait(ctx, nodes[j], &types.Message{
+			From:  src,
+			To:    dst,
+			Value: big.Zero(),
+		}, nodes...)
+		require.NoError(t, err)
+	}
+
+	recorder.RequireP95(t, MessageInclusionLatencySLO)
+}
+
+// TestMirBasic_WANTopologies tests that validators with the default consensus parameters
+// keep making progress and stay in sync when they are deployed in WAN-like topologies.
+func TestMirBasic_WANTopologies(t *testing.T) {
+	for _, tp := range []*kit.Topology{
+		kit.TwoRegionsTopology(MirTotalValidatorNumber/2, 10*time.Millisecond, 150*time.Millisecond),
+		kit.StarTopology(0, 50*time.Millisecond),
+		kit.ChainTopology(50 * time.Millisecond),
+	} {
+		tp := tp
+		t.Run(tp.Name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			g, ctx := errgroup.WithContext(ctx)
+
+			defer func() {
+				t.Logf("[*] defer: cancelling %s context", t.Name())
+				cancel()
+				err := g.Wait()
+				require.NoError(t, err)
+				t.Logf("[*] defer: system %s stopped", t.Name())
+			}()
+
+			nodes, validators, ens := kit.EnsembleWithMirValidators(t, MirTotalValidatorNumber)
+
+			cfg := kit.DefaultMirTestConfig()
+			cfg.MockedTransport = true
+
+			ens.InterconnectFullNodesWithTopology(tp).BeginMirMiningWithConfig(ctx, g, validators, cfg)
+			ens.ApplyMirTopology(tp, validators...)
+
+			err := kit.AdvanceChain(ctx, TestedBlockNumber, nodes...)
+			require.NoError(t, err)
+			err = kit.CheckNodesInSync(ctx, 0, nodes[0], nodes[1:]...)
+			require.NoError(t, err)
+		})
+	}
+}
+
+// TestMirBasic_ChainGetPath tests that the path between two tipsets of the Mir chain contains
+// only applies when walking forward and only reverts when walking back.
+func TestMirBasic_ChainGetPath(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g, ctx := errgroup.WithContext(ctx)
+
+	defer func() {
+		t.Logf("[*] defer: cancelling %s context", t.Name())
+		cancel()
+		err := g.Wait()
+		require.NoError(t, err)
+		t.Logf("[*] defer: system %s stopped", t.Name())
+	}()
+
+	nodes, validators, ens := kit.EnsembleWithMirValidators(t, MirTotalValidatorNumber)
+	ens.InterconnectFullNodes().BeginMirMining(ctx, g, validators...)
+
+	err := kit.AdvanceChain(ctx, TestedBlockNumber, nodes...)
+	require.NoError(t, err)
+
+	head, err := nodes[0].ChainHead(ctx)
+	require.NoError(t, err)
+	from, err := nodes[0].ChainGetTipSetByHeight(ctx, head.Height()-TestedBlockNumber/2, head.Key())
+	require.NoError(t, err)
+	n := int(head.Height() - from.Height())
+
+	path, err := nodes[0].ChainGetPath(ctx, from.Key(), head.Key())
+	require.NoError(t, err)
+	require.Equal(t, n, len(path))
+	for i, hc := range path {
+		require.Equal(t, store.HCApply, hc.Type)
+		require.Equal(t, from.Height()+abi.ChainEpoch(i+1), hc.Val.Height())
+	}
+	require.True(t, path[n-1].Val.Equals(head))
+
+	path, err = nodes[0].ChainGetPath(ctx, head.Key(), from.Key())
+	require.NoError(t, err)
+	require.Equal(t, n, len(path))
+	for i, hc := range path {
+		require.Equal(t, store.HCRevert, hc.Type)
+		require.Equal(t, head.Height()-abi.ChainEpoch(i), hc.Val.Height())
+	}
+}
+
 // TestMirBasic_BlocksContainSortedMessages tests that the messages nonces in the ordered blocks increase.
 func TestMirBasic_BlocksContainSortedMessages(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -1376,6 +1625,66 @@ func TestMirBasic_MiningWithOneByzantineNode(t *testing.T) {
 	require.NoError(t, err)
 	err = kit.CheckNodesInSync(ctx, 0, nodes[0], nodes[1:]...)
 	require.NoError(t, err)
+	err = kit.CheckSafety(ctx, nodes[:3]...)
+	require.NoError(t, err)
+}
+
+// TestMirBasic_TwinDivergesAndCrashes tests that the honest validators keep a single chain when the twin
+// of a byzantine validator starts equivocating at a given height and crashes later.
+func TestMirBasic_TwinDivergesAndCrashes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g, ctx := errgroup.WithContext(ctx)
+
+	defer func() {
+		t.Logf("[*] defer: cancelling %s context", t.Name())
+		cancel()
+		err := g.Wait()
+		require.NoError(t, err)
+		t.Logf("[*] defer: system %s stopped", t.Name())
+	}()
+
+	nodes, _, validators, twinValidators, ens := kit.EnsembleMirNodesWithByzantineTwins(t, 4)
+	ens.InterconnectFullNodes()
+
+	ens.BeginMirMining(ctx, g, validators...)
+	ens.BeginMirTwins(ctx, g, kit.TwinSchedule{Diverge: TestedBlockNumber, Crash: 3 * TestedBlockNumber},
+		nodes[0], validators, twinValidators...)
+
+	err := kit.AdvanceChain(ctx, 5*TestedBlockNumber, nodes...)
+	require.NoError(t, err)
+	err = kit.CheckSafety(ctx, nodes[:3]...)
+	require.NoError(t, err)
+}
+
+// TestMirBasic_ValidatorWithDifferentProposeDelay tests that the membership keeps mining
+// when a validator is configured with different batch parameters from the rest.
+func TestMirBasic_ValidatorWithDifferentProposeDelay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g, ctx := errgroup.WithContext(ctx)
+
+	defer func() {
+		t.Logf("[*] defer: cancelling %s context", t.Name())
+		cancel()
+		err := g.Wait()
+		require.NoError(t, err)
+		t.Logf("[*] defer: system %s stopped", t.Name())
+	}()
+
+	nodes, validators, ens := kit.EnsembleWithMirValidators(t, MirTotalValidatorNumber)
+
+	misconfigured := kit.DefaultConsensusTestConfig()
+	misconfigured.MaxProposeDelay = time.Second
+	misconfigured.MaxTransactionsInBatch = 16
+	testConfig := kit.DefaultMirTestConfig()
+	testConfig.ConsensusConfigs = map[string]*mir.ConsensusConfig{
+		validators[0].GetMirID(): misconfigured,
+	}
+	ens.InterconnectFullNodes().BeginMirMiningWithTestAndConsensusConfigs(ctx, g, validators, testConfig, kit.DefaultConsensusTestConfig())
+
+	err := kit.AdvanceChain(ctx, TestedBlockNumber, nodes...)
+	require.NoError(t, err)
+	err = kit.CheckNodesInSync(ctx, 0, nodes[0], nodes[1:]...)
+	require.NoError(t, err)
 }
 
 // TestMirWithMangler_AllNodesMiningWithMessaging runs TestMir_AllNodesMiningWithMessaging with mangler.
@@ -1725,3 +2034,59 @@ func TestMirBasic_FNodesHaveLongPeriodNoNetworkAccessButDoNotCrash(t *testing.T)
 	}
 	require.NoError(t, err)
 }
+
+// TestMirBasic_BlockProvenance tests that all the validators attribute the blocks
+// including the messages to the same proposers.
+func TestMirBasic_BlockProvenance(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g, ctx := errgroup.WithContext(ctx)
+
+	defer func() {
+		t.Logf("[*] defer: cancelling %s context", t.Name())
+		cancel()
+		err := g.Wait()
+		require.NoError(t, err)
+		t.Logf("[*] defer: system %s stopped", t.Name())
+	}()
+
+	nodes, validators, ens := kit.EnsembleWithMirValidators(t, MirTotalValidatorNumber)
+	ens.InterconnectFullNodes().BeginMirMining(ctx, g, validators...)
+
+	err := kit.AdvanceChain(ctx, TestedBlockNumber, nodes...)
+	require.NoError(t, err)
+
+	var cids []cid.Cid
+	for i := range nodes {
+		src, err := nodes[i].WalletDefaultAddress(ctx)
+		require.NoError(t, err)
+		dst, err := nodes[(i+1)%len(nodes)].WalletDefaultAddress(ctx)
+		require.NoError(t, err)
+
+		smsg, err := nodes[i].MpoolPushMessage(ctx, &types.Message{
+			From:  src,
+			To:    dst,
+			Value: big.Zero(),
+		}, nil)
+		require.NoError(t, err)
+		cids = append(cids, smsg.Cid())
+	}
+
+	for _, c := range cids {
+		err = kit.MirNodesWaitForMsg(ctx, c, nodes[0])
+		require.NoError(t, err)
+
+		lookup, err := nodes[0].StateSearchMsg(ctx, types.EmptyTSK, c, -1, true)
+		require.NoError(t, err)
+		require.NotNil(t, lookup)
+		execTs, err := nodes[0].ChainGetTipSet(ctx, lookup.TipSet)
+		require.NoError(t, err)
+		inclTs, err := nodes[0].ChainGetTipSet(ctx, execTs.Parents())
+		require.NoError(t, err)
+
+		p, ok := validators[0].BlockProvenance(inclTs.Height())
+		require.True(t, ok)
+		require.NotEmpty(t, p.Proposers)
+		require.Equal(t, inclTs.Cids()[0], p.Block)
+		kit.RequireBlockProposedBy(t, inclTs.Height(), p.Proposers[0], validators...)
+	}
+}
@@ -23,6 +23,8 @@ var workMillisecondsDistribution = view.Distribution(
 )
 
 var queueSizeDistribution = view.Distribution(0, 1, 2, 3, 5, 7, 10, 15, 25, 35, 50, 70, 90, 130, 200, 300, 500, 1000, 2000, 5000, 10000)
+var batchSizeDistribution = view.Distribution(0, 1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2000, 4000, 8000, 16000)
+var batchBytesDistribution = view.Distribution(0, 1<<10, 4<<10, 16<<10, 64<<10, 256<<10, 512<<10, 1<<20, 2<<20, 4<<20, 8<<20, 16<<20)
 
 // Global Tags
 var (
@@ -33,6 +35,7 @@ var (
 	PeerID, _      = tag.NewKey("peer_id")
 	MinerID, _     = tag.NewKey("miner_id")
 	FailureType, _ = tag.NewKey("failure_type")
+	Subsystem, _   = tag.NewKey("subsystem")
 
 	// chain
 	Local, _        = tag.NewKey("local")
@@ -121,6 +124,7 @@ var (
 	VMApplyFlush                        = stats.Float64("vm/applyblocks_flush", "Time spent flushing vm state", stats.UnitMilliseconds)
 	VMSends                             = stats.Int64("vm/sends", "Counter for sends processed by the VM", stats.UnitDimensionless)
 	VMApplied                           = stats.Int64("vm/applied", "Counter for messages (including internal messages) processed by the VM", stats.UnitDimensionless)
+	VMCanaryDivergences                 = stats.Int64("vm/canary_divergences", "Counter for blocks whose canary execution diverged from the main one", stats.UnitDimensionless)
 
 	// miner
 	WorkerCallsStarted           = stats.Int64("sealing/worker_calls_started", "Counter of started worker tasks", stats.UnitDimensionless)
@@ -182,6 +186,29 @@ var (
 
 	// gateway rate limit
 	RateLimitCount = stats.Int64("ratelimit/limited", "rate limited connections", stats.UnitDimensionless)
+
+	// mir
+	MirMpoolSelectFailures   = stats.Int64("mir/mpool_select_failures", "Number of failed mempool selections of the Mir validator", stats.UnitDimensionless)
+	MirBatchesWithoutMempool = stats.Int64("mir/batches_without_mempool", "Number of batches proposed without mempool messages because the selection failed", stats.UnitDimensionless)
+	MirBatchTransactions     = stats.Int64("mir/batch_transactions", "Number of transactions in the batches ordered by Mir", stats.UnitDimensionless)
+	MirBatchBytes            = stats.Int64("mir/batch_bytes", "Total size of the transactions in the batches ordered by Mir", stats.UnitBytes)
+	MirBlockMessages         = stats.Int64("mir/block_messages", "Number of messages in the blocks created by the Mir validator", stats.UnitDimensionless)
+	MirBatchSaturationAlerts = stats.Int64("mir/batch_saturation_alerts", "Number of times the batches stayed close to their limits for too long", stats.UnitDimensionless)
+	MirChainDivergences      = stats.Int64("mir/chain_divergences", "Number of times the chain of the validator diverged from the latest checkpoint", stats.UnitDimensionless)
+	MirValidationsQueued     = stats.Int64("mir/validations_queued", "Number of blocks waiting for a validation slot when a block is queued", stats.UnitDimensionless)
+	MirValidationWait        = stats.Float64("mir/validation_wait_ms", "Time blocks wait for a validation slot", stats.UnitMilliseconds)
+	MirGatewayMismatches     = stats.Int64("mir/gateway_membership_mismatches", "Number of times the membership in the gateway actor didn't match the validator set voted in Mir", stats.UnitDimensionless)
+	MirSubsystemFailures     = stats.Int64("mir/subsystem_failures", "Number of times a subsystem of the Mir validator stopped with an error or panicked", stats.UnitDimensionless)
+	MirChainStalls           = stats.Int64("mir/chain_stalls", "Number of times Mir delivered no batch to the validator for longer than the stall timeout", stats.UnitDimensionless)
+	MirChainStalled          = stats.Int64("mir/chain_stalled", "Whether Mir has delivered no batch to the validator for longer than the stall timeout", stats.UnitDimensionless)
+	MirBatchStoreBytes       = stats.Int64("mir/batch_store_bytes", "Size of the transactions kept in the batch store of the Mir availability layer", stats.UnitBytes)
+	MirBatchStoreBatches     = stats.Int64("mir/batch_store_batches", "Number of batches kept in the batch store of the Mir availability layer", stats.UnitDimensionless)
+	MirBatchStorePrunes      = stats.Int64("mir/batch_store_emergency_prunes", "Number of times the batch store was pruned because it exceeded its cap", stats.UnitDimensionless)
+	MirBlkCacheHits          = stats.Int64("mir/blk_cache_hits", "Number of blocks covered by a checkpoint found in the cache of unverified blocks", stats.UnitDimensionless)
+	MirBlkCacheMisses        = stats.Int64("mir/blk_cache_misses", "Number of blocks covered by a checkpoint missing from the cache of unverified blocks", stats.UnitDimensionless)
+	MirBlkCacheEvictions     = stats.Int64("mir/blk_cache_evictions", "Number of unverified blocks evicted from the full block cache", stats.UnitDimensionless)
+	MirBlkCacheOutOfWindow   = stats.Int64("mir/blk_cache_out_of_window", "Number of blocks rejected for a height out of the window above the latest checkpoint", stats.UnitDimensionless)
+	MirBlkCacheUnchecked     = stats.Int64("mir/blk_cache_unchecked", "Number of blocks covered by a checkpoint that were neither received nor in the chain", stats.UnitDimensionless)
 )
 
 var (
@@ -363,6 +390,10 @@ var (
 		Measure:     VMApplied,
 		Aggregation: view.LastValue(),
 	}
+	VMCanaryDivergencesView = &view.View{
+		Measure:     VMCanaryDivergences,
+		Aggregation: view.Count(),
+	}
 
 	// miner
 	WorkerCallsStartedView = &view.View{
@@ -643,6 +674,91 @@ var (
 		Measure:     RateLimitCount,
 		Aggregation: view.Count(),
 	}
+	MirMpoolSelectFailuresView = &view.View{
+		Measure:     MirMpoolSelectFailures,
+		Aggregation: view.Count(),
+	}
+	MirBatchesWithoutMempoolView = &view.View{
+		Measure:     MirBatchesWithoutMempool,
+		Aggregation: view.Count(),
+	}
+	MirBatchTransactionsView = &view.View{
+		Measure:     MirBatchTransactions,
+		Aggregation: batchSizeDistribution,
+	}
+	MirBatchBytesView = &view.View{
+		Measure:     MirBatchBytes,
+		Aggregation: batchBytesDistribution,
+	}
+	MirBlockMessagesView = &view.View{
+		Measure:     MirBlockMessages,
+		Aggregation: batchSizeDistribution,
+	}
+	MirBatchSaturationAlertsView = &view.View{
+		Measure:     MirBatchSaturationAlerts,
+		Aggregation: view.Count(),
+	}
+	MirChainDivergencesView = &view.View{
+		Measure:     MirChainDivergences,
+		Aggregation: view.Count(),
+	}
+	MirValidationsQueuedView = &view.View{
+		Measure:     MirValidationsQueued,
+		Aggregation: queueSizeDistribution,
+	}
+	MirValidationWaitView = &view.View{
+		Measure:     MirValidationWait,
+		Aggregation: defaultMillisecondsDistribution,
+	}
+	MirGatewayMismatchesView = &view.View{
+		Measure:     MirGatewayMismatches,
+		Aggregation: view.Count(),
+	}
+	MirSubsystemFailuresView = &view.View{
+		Measure:     MirSubsystemFailures,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{Subsystem},
+	}
+	MirChainStallsView = &view.View{
+		Measure:     MirChainStalls,
+		Aggregation: view.Count(),
+	}
+	MirChainStalledView = &view.View{
+		Measure:     MirChainStalled,
+		Aggregation: view.LastValue(),
+	}
+	MirBatchStoreBytesView = &view.View{
+		Measure:     MirBatchStoreBytes,
+		Aggregation: view.LastValue(),
+	}
+	MirBatchStoreBatchesView = &view.View{
+		Measure:     MirBatchStoreBatches,
+		Aggregation: view.LastValue(),
+	}
+	MirBatchStorePrunesView = &view.View{
+		Measure:     MirBatchStorePrunes,
+		Aggregation: view.Count(),
+	}
+	MirBlkCacheHitsView = &view.View{
+		Measure:     MirBlkCacheHits,
+		Aggregation: view.Count(),
+	}
+	MirBlkCacheMissesView = &view.View{
+		Measure:     MirBlkCacheMisses,
+		Aggregation: view.Count(),
+	}
+	MirBlkCacheEvictionsView = &view.View{
+		Measure:     MirBlkCacheEvictions,
+		Aggregation: view.Count(),
+	}
+	MirBlkCacheOutOfWindowView = &view.View{
+		Measure:     MirBlkCacheOutOfWindow,
+		Aggregation: view.Count(),
+	}
+	MirBlkCacheUncheckedView = &view.View{
+		Measure:     MirBlkCacheUnchecked,
+		Aggregation: view.Count(),
+	}
 )
 
 // DefaultViews is an array of OpenCensus views for metric gathering purposes
@@ -727,6 +843,14 @@ var ChainNodeViews = append([]*view.View{
 	VMApplyFlushView,
 	VMSendsView,
 	VMAppliedView,
+	VMCanaryDivergencesView,
+	MirValidationsQueuedView,
+	MirValidationWaitView,
+	MirBlkCacheHitsView,
+	MirBlkCacheMissesView,
+	MirBlkCacheEvictionsView,
+	MirBlkCacheOutOfWindowView,
+	MirBlkCacheUncheckedView,
 }, DefaultViews...)
 
 var MinerNodeViews = append([]*view.View{
@@ -764,6 +888,24 @@ var MinerNodeViews = append([]*view.View{
 	DagStorePRSeekForwardBytesView,
 }, DefaultViews...)
 
+// MirValidatorViews are the views of a Mir validator process, on top of MinerNodeViews.
+var MirValidatorViews = []*view.View{
+	MirMpoolSelectFailuresView,
+	MirBatchesWithoutMempoolView,
+	MirBatchTransactionsView,
+	MirBatchBytesView,
+	MirBlockMessagesView,
+	MirBatchSaturationAlertsView,
+	MirChainDivergencesView,
+	MirGatewayMismatchesView,
+	MirSubsystemFailuresView,
+	MirChainStallsView,
+	MirChainStalledView,
+	MirBatchStoreBytesView,
+	MirBatchStoreBatchesView,
+	MirBatchStorePrunesView,
+}
+
 var GatewayNodeViews = append([]*view.View{
 	RateLimitedView,
 }, ChainNodeViews...)
@@ -96,6 +96,8 @@ const (
 
 	// filecoin
 	SetGenesisKey
+	RegisterSubnetCronJobsKey
+	RegisterSubnetParamsKey
 
 	RunHelloKey
 	RunChainExchangeKey
@@ -17,6 +17,8 @@ import (
 	"github.com/filecoin-project/lotus/chain/beacon"
 	"github.com/filecoin-project/lotus/chain/consensus"
 	"github.com/filecoin-project/lotus/chain/consensus/filcns"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/attestation"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/pool/encrypted"
 	"github.com/filecoin-project/lotus/chain/events"
 	"github.com/filecoin-project/lotus/chain/exchange"
 	"github.com/filecoin-project/lotus/chain/gen/slashfilter"
@@ -60,6 +62,8 @@ var ChainNode = Options(
 	Override(new(modules.Genesis), modules.ErrorGenesis),
 	Override(new(dtypes.AfterGenesisSet), modules.SetGenesis),
 	Override(SetGenesisKey, modules.DoSetGenesis),
+	Override(RegisterSubnetCronJobsKey, modules.RegisterSubnetCronJobs),
+	Override(RegisterSubnetParamsKey, modules.RegisterSubnetParams),
 	Override(new(beacon.Schedule), modules.RandomSchedule),
 
 	// Network bootstrap
@@ -141,6 +145,8 @@ var ChainNode = Options(
 	Override(HandleMigrateClientFundsKey, modules.HandleMigrateClientFunds),
 
 	Override(new(*full.GasPriceCache), full.NewGasPriceCache),
+	Override(new(*encrypted.Pool), encrypted.New),
+	Override(new(*attestation.Store), modules.MirAttestations),
 
 	Override(RelayIndexerMessagesKey, modules.RelayIndexerMessages),
 
@@ -3,12 +3,16 @@ package node
 import (
 	"context"
 	"net/http"
+	"os"
+	"strconv"
 	"sync/atomic"
 	"time"
 
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p/core/network"
 
+	"github.com/filecoin-project/go-state-types/abi"
+
 	lapi "github.com/filecoin-project/lotus/api"
 )
 
@@ -116,3 +120,56 @@ func NewReadyHandler(api lapi.FullNode) *HealthHandler {
 	}()
 	return &h
 }
+
+// LearnerReadyLagEnv is the number of blocks a learner of a Mir subnet can be behind the head
+// observed from the validators to be ready. It defaults to DefaultLearnerReadyLag.
+const LearnerReadyLagEnv = "EUDICO_LEARNER_READY_LAG"
+
+const DefaultLearnerReadyLag = abi.ChainEpoch(10)
+
+// NewLearnerReadyHandler reports a learner of a Mir subnet as ready once its head is at most
+// lag blocks behind the highest head it observed from its peers. Unlike NewReadyHandler, it doesn't
+// rely on the expected height of the chain, as Mir subnets have no fixed block time, so a syncing
+// learner isn't reported as ready and a learner of an idle subnet isn't reported as lagging.
+func NewLearnerReadyHandler(api lapi.FullNode, lag abi.ChainEpoch) *HealthHandler {
+	ctx := context.Background()
+	h := HealthHandler{}
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		for range ticker.C {
+			head, err := api.ChainHead(ctx)
+			if err != nil {
+				h.SetHealthy(false)
+				continue
+			}
+			state, err := api.SyncState(ctx)
+			if err != nil {
+				h.SetHealthy(false)
+				continue
+			}
+			var observed abi.ChainEpoch
+			for _, s := range state.ActiveSyncs {
+				if s.Target != nil && s.Target.Height() > observed {
+					observed = s.Target.Height()
+				}
+			}
+			// A learner that hasn't observed any head from its peers can't tell whether it's lagging.
+			h.SetHealthy(observed > 0 && head.Height()+lag >= observed)
+		}
+	}()
+	return &h
+}
+
+// learnerReadyLagFromEnv returns the lag of LearnerReadyLagEnv, or DefaultLearnerReadyLag.
+func learnerReadyLagFromEnv() abi.ChainEpoch {
+	s := os.Getenv(LearnerReadyLagEnv)
+	if s == "" {
+		return DefaultLearnerReadyLag
+	}
+	lag, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || lag < 0 {
+		healthlog.Errorf("invalid %s %q: using %d", LearnerReadyLagEnv, s, DefaultLearnerReadyLag)
+		return DefaultLearnerReadyLag
+	}
+	return abi.ChainEpoch(lag)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/filecoin-project/lotus/api"
 	apitypes "github.com/filecoin-project/lotus/api/types"
 	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/eudico-core/global"
 	"github.com/filecoin-project/lotus/journal/alerting"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
@@ -67,9 +68,20 @@ func (a *CommonAPI) Version(context.Context) (api.APIVersion, error) {
 		APIVersion: v,
 
 		BlockDelay: build.BlockDelaySecs,
+		Consensus:  ConsensusInfo(),
 	}, nil
 }
 
+// ConsensusInfo returns the properties of the consensus run by the node.
+func ConsensusInfo() api.ConsensusInfo {
+	cns := global.ConsensusAlgorithmInUse()
+	return api.ConsensusInfo{
+		Type:               cns.String(),
+		InstantFinality:    cns == global.MirConsensus,
+		SingleBlockTipSets: cns == global.MirConsensus,
+	}
+}
+
 func (a *CommonAPI) LogList(context.Context) ([]string, error) {
 	return logging.GetSubsystems(), nil
 }
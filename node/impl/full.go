@@ -9,11 +9,14 @@ import (
 
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/build"
+	mirconsensus "github.com/filecoin-project/lotus/chain/consensus/mir"
+	"github.com/filecoin-project/lotus/eudico-core/global"
 	"github.com/filecoin-project/lotus/node/impl/client"
 	"github.com/filecoin-project/lotus/node/impl/common"
 	"github.com/filecoin-project/lotus/node/impl/full"
 	"github.com/filecoin-project/lotus/node/impl/ipc"
 	"github.com/filecoin-project/lotus/node/impl/market"
+	"github.com/filecoin-project/lotus/node/impl/mir"
 	"github.com/filecoin-project/lotus/node/impl/net"
 	"github.com/filecoin-project/lotus/node/impl/paych"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
@@ -38,6 +41,7 @@ type FullNodeAPI struct {
 	full.RaftAPI
 	full.EthAPI
 	ipc.IPCAPI
+	mir.MirAPI
 
 	DS          dtypes.MetadataDS
 	NetworkName dtypes.NetworkName
@@ -118,6 +122,17 @@ func (n *FullNodeAPI) NodeStatus(ctx context.Context, inclChainStatus bool) (sta
 
 	}
 
+	status.ConsensusStatus.Info = common.ConsensusInfo()
+	if global.IsConsensusAlgorithm(global.MirConsensus) {
+		ch, err := mirconsensus.LatestCheckpoint(ctx, n.DS)
+		if err != nil {
+			return status, err
+		}
+		if ch != nil {
+			status.ConsensusStatus.CheckpointPeriod = ch.Height - ch.Parent.Height
+		}
+	}
+
 	return status, nil
 }
 
@@ -0,0 +1,83 @@
+package full
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/eudico-core/global"
+)
+
+// getPath returns the path between two tipsets. For Mir, it uses mirGetPath
+// instead of looking for the common ancestor of two branches.
+func getPath(ctx context.Context, cs *store.ChainStore, from, to types.TipSetKey) ([]*api.HeadChange, error) {
+	if global.IsConsensusAlgorithm(global.MirConsensus) {
+		return mirGetPath(ctx, cs, from, to)
+	}
+	return cs.GetPath(ctx, from, to)
+}
+
+// mirGetPath returns the path between two tipsets of a Mir chain.
+//
+// Blocks ordered by Mir are final, so in the common case one tipset is an ancestor of the
+// other one, and the path consists only of reverts (walking back) or only of applies (walking
+// forward). In that case, the tipsets of the path are taken from the chain index instead of
+// walking back parent by parent looking for a common ancestor. Otherwise, for instance for
+// tipsets received from a misbehaving peer, it falls back to the generic path with lotus
+// semantics (reverts followed by applies).
+func mirGetPath(ctx context.Context, cs *store.ChainStore, from, to types.TipSetKey) ([]*api.HeadChange, error) {
+	fts, err := cs.LoadTipSet(ctx, from)
+	if err != nil {
+		return nil, xerrors.Errorf("loading from tipset %s: %w", from, err)
+	}
+	tts, err := cs.LoadTipSet(ctx, to)
+	if err != nil {
+		return nil, xerrors.Errorf("loading to tipset %s: %w", to, err)
+	}
+	if fts.Equals(tts) {
+		return []*api.HeadChange{}, nil
+	}
+
+	revert := fts.Height() > tts.Height()
+	low, high := fts, tts
+	if revert {
+		low, high = tts, fts
+	}
+
+	anc, err := cs.GetTipsetByHeight(ctx, low.Height(), high, true)
+	if err != nil {
+		return nil, xerrors.Errorf("error getting tipset at height %d: %w", low.Height(), err)
+	}
+	if !anc.Equals(low) {
+		return cs.GetPath(ctx, from, to)
+	}
+
+	// Tipsets from low (excluded) to high (included), in ascending order of height.
+	var branch []*types.TipSet
+	prev := low
+	for h := low.Height() + 1; h <= high.Height(); h++ {
+		ts, err := cs.GetTipsetByHeight(ctx, h, high, true)
+		if err != nil {
+			return nil, xerrors.Errorf("error getting tipset at height %d: %w", h, err)
+		}
+		// Null rounds resolve to the previous tipset.
+		if ts.Equals(prev) {
+			continue
+		}
+		branch = append(branch, ts)
+		prev = ts
+	}
+
+	path := make([]*api.HeadChange, len(branch))
+	for i, ts := range branch {
+		if revert {
+			path[len(branch)-1-i] = &api.HeadChange{Type: store.HCRevert, Val: ts}
+		} else {
+			path[i] = &api.HeadChange{Type: store.HCApply, Val: ts}
+		}
+	}
+	return path, nil
+}
@@ -0,0 +1,81 @@
+package full
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/consensus/filcns"
+	"github.com/filecoin-project/lotus/chain/gen"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestMirGetPath(t *testing.T) {
+	ctx := context.Background()
+
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+	gencar, err := cg.GenesisCar()
+	require.NoError(t, err)
+
+	nbs := blockstore.NewMemorySync()
+	cs := store.NewChainStore(nbs, nbs, syncds.MutexWrap(datastore.NewMapDatastore()), filcns.Weight, nil)
+	defer cs.Close() //nolint:errcheck
+	_, err = cs.Import(ctx, bytes.NewReader(gencar))
+	require.NoError(t, err)
+
+	genTs := mock.TipSet(cg.Genesis())
+	require.NoError(t, cs.PutTipSet(ctx, genTs))
+	require.NoError(t, cs.SetGenesis(ctx, cg.Genesis()))
+
+	extend := func(from *types.TipSet, n int, ticket uint64) []*types.TipSet {
+		var chain []*types.TipSet
+		cur := from
+		for i := 0; i < n; i++ {
+			cur = mock.TipSet(mock.MkBlock(cur, 1, ticket))
+			require.NoError(t, cs.PutTipSet(ctx, cur))
+			chain = append(chain, cur)
+		}
+		return chain
+	}
+
+	main := extend(genTs, 10, 1)
+	from, to := main[2], main[7]
+
+	path, err := mirGetPath(ctx, cs, from.Key(), to.Key())
+	require.NoError(t, err)
+	require.Len(t, path, 5)
+	for i, hc := range path {
+		require.Equal(t, store.HCApply, hc.Type)
+		require.True(t, hc.Val.Equals(main[3+i]))
+	}
+
+	path, err = mirGetPath(ctx, cs, to.Key(), from.Key())
+	require.NoError(t, err)
+	require.Len(t, path, 5)
+	for i, hc := range path {
+		require.Equal(t, store.HCRevert, hc.Type)
+		require.True(t, hc.Val.Equals(main[7-i]))
+	}
+
+	path, err = mirGetPath(ctx, cs, from.Key(), from.Key())
+	require.NoError(t, err)
+	require.Len(t, path, 0)
+
+	// Tipsets in different chains get the same path as in lotus.
+	fork := extend(main[4], 3, 2)
+	path, err = mirGetPath(ctx, cs, to.Key(), fork[2].Key())
+	require.NoError(t, err)
+	expected, err := cs.GetPath(ctx, to.Key(), fork[2].Key())
+	require.NoError(t, err)
+	require.Equal(t, expected, path)
+	require.Equal(t, store.HCRevert, path[0].Type)
+	require.Equal(t, store.HCApply, path[len(path)-1].Type)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/consensus-shipyard/go-ipc-types/gateway"
 	"github.com/consensus-shipyard/go-ipc-types/sdk"
 	"github.com/consensus-shipyard/go-ipc-types/subnetactor"
+	"github.com/consensus-shipyard/go-ipc-types/validator"
 	"github.com/ipfs/go-cid"
 	cbg "github.com/whyrusleeping/cbor-gen"
 	"go.uber.org/fx"
@@ -375,6 +376,18 @@ func (a *IPCAPI) IPCGetGenesisEpochForSubnet(ctx context.Context, gatewayAddr ad
 	return subnet.GenesisEpoch, nil
 }
 
+// IPCGetGatewayMembership returns the membership in the gateway actor of the current subnet at a tipset.
+//
+// The state of a tipset results from executing the messages of its parents, so the membership
+// set by a SetMembership message included at height h is returned from the tipset at height h+1.
+func (a *IPCAPI) IPCGetGatewayMembership(ctx context.Context, gatewayAddr address.Address, tsk types.TipSetKey) (*validator.Set, error) {
+	st, err := a.IPCReadGatewayState(ctx, gatewayAddr, tsk)
+	if err != nil {
+		return nil, err
+	}
+	return &st.Validators.Validators, nil
+}
+
 // readActorState reads the state of a specific actor at a specefic epoch determined by the tipset key.
 //
 // The function accepts the address actor and the tipSetKet from which to read the state as an input, along
@@ -0,0 +1,63 @@
+package mir
+
+import (
+	"context"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"go.uber.org/fx"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/attestation"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/pool/encrypted"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetparams"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+type MirAPI struct {
+	fx.In
+
+	EncryptedPool     *encrypted.Pool
+	Attestations      *attestation.Store
+	AttestationPubSub *pubsub.PubSub
+	NetName           dtypes.NetworkName
+}
+
+func (a *MirAPI) MirPushEncryptedTx(ctx context.Context, tx []byte) error {
+	if err := mir.ValidateEncryptedTx(tx); err != nil {
+		return xerrors.Errorf("invalid encrypted tx: %w", err)
+	}
+	return a.EncryptedPool.Add(tx)
+}
+
+func (a *MirAPI) MirPendingEncryptedTxs(ctx context.Context, max int) ([][]byte, error) {
+	return a.EncryptedPool.Pending(max), nil
+}
+
+func (a *MirAPI) MirPublishVersionAttestation(ctx context.Context, att *api.MirVersionAttestation) error {
+	at, err := attestation.FromAPI(att)
+	if err != nil {
+		return err
+	}
+	if _, err := a.Attestations.Add(at); err != nil {
+		return xerrors.Errorf("invalid version attestation: %w", err)
+	}
+	b, err := at.Bytes()
+	if err != nil {
+		return xerrors.Errorf("serializing version attestation failed: %w", err)
+	}
+	return a.AttestationPubSub.Publish(build.MirAttestationsTopic(a.NetName), b) //nolint:staticcheck
+}
+
+func (a *MirAPI) MirMembershipVersions(ctx context.Context) ([]api.MirMembershipEntry, error) {
+	entries := a.Attestations.Membership()
+	paramsHash := subnetparams.Hash(string(a.NetName))
+	for i, e := range entries {
+		if e.Attestation != nil {
+			entries[i].ParamsMismatch = e.Attestation.ParamsHash != paramsHash
+		}
+	}
+	return entries, nil
+}
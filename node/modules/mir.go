@@ -0,0 +1,139 @@
+package modules
+
+import (
+	"context"
+	"path/filepath"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.uber.org/fx"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/consensus/mir"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/attestation"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetcron"
+	"github.com/filecoin-project/lotus/chain/consensus/mir/subnetparams"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/eudico-core/global"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+	"github.com/filecoin-project/lotus/node/modules/helpers"
+	"github.com/filecoin-project/lotus/node/repo"
+)
+
+// RegisterSubnetCronJobs registers the subnet cron jobs configured in the repo of the node,
+// so the node accepts and executes them in the blocks with a Mir checkpoint.
+func RegisterSubnetCronJobs(lr repo.LockedRepo, nn dtypes.NetworkName) error {
+	return subnetcron.LoadJobs(string(nn), filepath.Join(lr.Path(), subnetcron.JobsFile))
+}
+
+// RegisterSubnetParams sets the subnet parameters configured in the repo of the node,
+// e.g. the block gas limit used to select and validate the messages of blocks.
+func RegisterSubnetParams(lr repo.LockedRepo, nn dtypes.NetworkName) error {
+	return subnetparams.Load(string(nn), filepath.Join(lr.Path(), subnetparams.ParamsFile))
+}
+
+// MirAttestations creates the store of the version attestations of Mir validators.
+// The store tracks the membership of the latest checkpoint in the chain and is kept
+// updated with the attestations gossiped by the members.
+func MirAttestations(mctx helpers.MetricsCtx, lc fx.Lifecycle, ps *pubsub.PubSub, cs *store.ChainStore, nn dtypes.NetworkName) (*attestation.Store, error) {
+	atts := attestation.NewStore()
+	if !global.IsConsensusAlgorithm(global.MirConsensus) {
+		return atts, nil
+	}
+
+	ctx := helpers.LifecycleCtx(mctx, lc)
+	topic := build.MirAttestationsTopic(nn)
+
+	validate := func(ctx context.Context, _ peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+		var a attestation.Attestation
+		if err := a.FromBytes(msg.Data); err != nil {
+			return pubsub.ValidationReject
+		}
+		if err := atts.Validate(&a); err != nil {
+			log.Debugf("invalid version attestation: %v", err)
+			return pubsub.ValidationReject
+		}
+		msg.ValidatorData = &a
+		return pubsub.ValidationAccept
+	}
+	if err := ps.RegisterTopicValidator(topic, validate); err != nil {
+		return nil, xerrors.Errorf("failed to register validator for topic %s: %w", topic, err)
+	}
+
+	sub, err := ps.Subscribe(topic) //nolint
+	if err != nil {
+		return nil, xerrors.Errorf("failed to subscribe to topic %s: %w", topic, err)
+	}
+
+	go trackMirMembership(ctx, cs, atts)
+
+	go func() {
+		defer sub.Cancel()
+		for {
+			msg, err := sub.Next(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Errorf("error from version attestation subscription: %v", err)
+				}
+				return
+			}
+			a := msg.ValidatorData.(*attestation.Attestation)
+			if _, err := atts.Add(a); err != nil {
+				log.Debugf("failed to store version attestation: %v", err)
+				continue
+			}
+			if a.ParamsHash != subnetparams.Hash(string(nn)) {
+				log.Warnf("validator %s uses different subnet params: hash %s", a.Validator, a.ParamsHash)
+			}
+		}
+	}()
+
+	return atts, nil
+}
+
+// trackMirMembership sets the membership of the store from the checkpoints in the chain.
+func trackMirMembership(ctx context.Context, cs *store.ChainStore, atts *attestation.Store) {
+	for changes := range cs.SubHeadChanges(ctx) {
+		for _, hc := range changes {
+			var (
+				validators []address.Address
+				err        error
+			)
+			switch hc.Type {
+			case store.HCCurrent:
+				validators, err = latestCheckpointMembership(ctx, cs, hc.Val)
+			case store.HCApply:
+				validators, err = mir.CheckpointMembership(hc.Val.Blocks()[0])
+			default:
+				continue
+			}
+			if err != nil {
+				log.Errorf("error getting Mir membership at height %d: %v", hc.Val.Height(), err)
+				continue
+			}
+			if validators != nil {
+				atts.SetMembership(validators)
+			}
+		}
+	}
+}
+
+// latestCheckpointMembership returns the membership of the latest checkpoint
+// included in the chain up to the tipset.
+func latestCheckpointMembership(ctx context.Context, cs *store.ChainStore, ts *types.TipSet) ([]address.Address, error) {
+	for ts.Height() > 0 {
+		validators, err := mir.CheckpointMembership(ts.Blocks()[0])
+		if err != nil || validators != nil {
+			return validators, err
+		}
+		ts, err = cs.LoadTipSet(ctx, ts.Parents())
+		if err != nil {
+			return nil, xerrors.Errorf("error loading parent tipset: %w", err)
+		}
+	}
+	return nil, nil
+}
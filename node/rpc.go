@@ -133,6 +133,7 @@ func FullNodeHandler(a v1api.FullNode, permissioned bool, opts ...jsonrpc.Server
 	}))
 	m.Handle("/health/livez", NewLiveHandler(a))
 	m.Handle("/health/readyz", NewReadyHandler(a))
+	m.Handle("/health/learner/readyz", NewLearnerReadyHandler(a, learnerReadyLagFromEnv()))
 	m.PathPrefix("/").Handler(http.DefaultServeMux) // pprof
 
 	return m, nil